@@ -0,0 +1,334 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/avalarin/livlog/backend/internal/config"
+	"github.com/avalarin/livlog/backend/internal/repository"
+	"github.com/avalarin/livlog/backend/internal/seed"
+	"github.com/avalarin/livlog/backend/internal/service"
+)
+
+const exportPageSize = 500
+
+func runMigrate(ctx context.Context, a *app, args []string) error {
+	fs := flag.NewFlagSet("migrate", flag.ContinueOnError)
+	down := fs.Int("down", 0, "roll back this many migrations instead of applying")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	if *down > 0 {
+		if err := repository.RollbackMigrations(&a.cfg.Database, a.migrationsPath, *down, a.log); err != nil {
+			return err
+		}
+		fmt.Printf("rolled back %d migration(s)\n", *down)
+		return nil
+	}
+
+	if err := repository.RunMigrations(&a.cfg.Database, a.migrationsPath, a.log); err != nil {
+		return err
+	}
+
+	db, err := a.connect(ctx)
+	if err != nil {
+		return err
+	}
+	version, dirty, err := db.MigrationVersion(ctx)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("schema at version %d (dirty: %t)\n", version, dirty)
+	return nil
+}
+
+func runCreateAdmin(ctx context.Context, a *app, args []string) error {
+	fs := flag.NewFlagSet("create-admin", flag.ContinueOnError)
+	email := fs.String("email", "", "admin email; they sign in with an email code")
+	name := fs.String("name", "", "display name for a new user")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if *email == "" {
+		return errors.New("-email is required")
+	}
+
+	userRepo, err := a.userRepo(ctx)
+	if err != nil {
+		return err
+	}
+
+	*email = service.NormalizeEmail(*email, a.cfg.Auth.StripEmailPlusTags)
+	user, err := userRepo.GetUserByEmail(ctx, *email)
+	if errors.Is(err, repository.ErrUserNotFound) {
+		user, err = userRepo.CreateUserWithProvider(ctx, *email, *name, true, false, "email", *email)
+	}
+	if err != nil {
+		return err
+	}
+
+	if err := userRepo.SetUserRole(ctx, user.ID, repository.UserRoleAdmin); err != nil {
+		return err
+	}
+
+	fmt.Printf("user %s (%s) is now an admin\n", user.ID, *email)
+	return nil
+}
+
+func runGenerateJWTKeys(ctx context.Context, a *app, args []string) error {
+	fs := flag.NewFlagSet("generate-jwt-keys", flag.ContinueOnError)
+	force := fs.Bool("force", false, "overwrite existing keys; tokens signed with them stop working")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	privatePath, publicPath := a.cfg.JWT.PrivateKeyPath, a.cfg.JWT.PublicKeyPath
+	if !*force {
+		for _, path := range []string{privatePath, publicPath} {
+			if _, err := os.Stat(path); err == nil {
+				return fmt.Errorf("%s already exists, use -force to replace it", path)
+			}
+		}
+	}
+
+	if err := service.GenerateJWTKeys(privatePath, publicPath); err != nil {
+		return err
+	}
+
+	fmt.Printf("wrote %s and %s\n", privatePath, publicPath)
+	return nil
+}
+
+type userExport struct {
+	ExportedAt  time.Time                `json:"exported_at"`
+	User        *repository.User         `json:"user"`
+	Collections []*repository.Collection `json:"collections"`
+	Types       []*repository.EntryType  `json:"types"`
+	Entries     []entryExport            `json:"entries"`
+}
+
+type entryExport struct {
+	*repository.Entry
+	Images []imageExport `json:"images,omitempty"`
+}
+
+type imageExport struct {
+	ID       uuid.UUID `json:"id"`
+	IsCover  bool      `json:"is_cover"`
+	Position int       `json:"position"`
+	Data     []byte    `json:"data"` // base64 in JSON
+}
+
+func runExportUser(ctx context.Context, a *app, args []string) error {
+	fs := flag.NewFlagSet("export-user", flag.ContinueOnError)
+	userRef := fs.String("user", "", "user email or id")
+	out := fs.String("out", "", "output file (default stdout)")
+	withImages := fs.Bool("images", true, "include image data")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	db, err := a.connect(ctx)
+	if err != nil {
+		return err
+	}
+	userRepo, err := a.userRepo(ctx)
+	if err != nil {
+		return err
+	}
+	user, err := resolveUser(ctx, userRepo, *userRef)
+	if err != nil {
+		return err
+	}
+
+	collections, err := repository.NewCollectionRepository(db.Pool).GetCollectionsByUserID(ctx, user.ID)
+	if err != nil {
+		return err
+	}
+
+	allTypes, err := repository.NewTypeRepository(db.Pool).GetAllTypes(ctx, user.ID)
+	if err != nil {
+		return err
+	}
+	types := []*repository.EntryType{}
+	for _, t := range allTypes {
+		if t.UserID != nil { // system types are not the user's data
+			types = append(types, t)
+		}
+	}
+
+	entryRepo := repository.NewEntryRepository(db.Pool)
+	entries := []entryExport{}
+	for offset := 0; ; offset += exportPageSize {
+		page, err := entryRepo.GetEntriesByUserID(ctx, user.ID, nil, exportPageSize, offset)
+		if err != nil {
+			return err
+		}
+
+		for _, e := range page {
+			export := entryExport{Entry: e}
+			if *withImages {
+				images, err := entryRepo.GetEntryImages(ctx, e.ID)
+				if err != nil {
+					return err
+				}
+				for _, img := range images {
+					export.Images = append(export.Images, imageExport{
+						ID:       img.ID,
+						IsCover:  img.IsCover,
+						Position: img.Position,
+						Data:     img.ImageData,
+					})
+				}
+			}
+			entries = append(entries, export)
+		}
+
+		if len(page) < exportPageSize {
+			break
+		}
+	}
+
+	var w io.Writer = os.Stdout
+	if *out != "" {
+		f, err := os.OpenFile(*out, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0o600)
+		if err != nil {
+			return fmt.Errorf("failed to create output file: %w", err)
+		}
+		defer f.Close()
+		w = f
+	}
+
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(userExport{
+		ExportedAt:  time.Now().UTC(),
+		User:        user,
+		Collections: collections,
+		Types:       types,
+		Entries:     entries,
+	})
+}
+
+func runResetAIQuota(ctx context.Context, a *app, args []string) error {
+	fs := flag.NewFlagSet("reset-ai-quota", flag.ContinueOnError)
+	userRef := fs.String("user", "", "user email or id")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	db, err := a.connect(ctx)
+	if err != nil {
+		return err
+	}
+	userRepo, err := a.userRepo(ctx)
+	if err != nil {
+		return err
+	}
+	user, err := resolveUser(ctx, userRepo, *userRef)
+	if err != nil {
+		return err
+	}
+
+	if err := repository.NewAISearchUsageRepository(db.Pool).ResetUsage(ctx, user.ID); err != nil {
+		return err
+	}
+
+	fmt.Printf("reset AI search usage for user %s\n", user.ID)
+	return nil
+}
+
+func runSeedDemoData(ctx context.Context, a *app, args []string) error {
+	fs := flag.NewFlagSet("seed-demo-data", flag.ContinueOnError)
+	email := fs.String("email", seed.DefaultDemoEmail, "demo user email; they sign in with an email code")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	db, err := a.connect(ctx)
+	if err != nil {
+		return err
+	}
+	userRepo, err := a.userRepo(ctx)
+	if err != nil {
+		return err
+	}
+
+	entryRepo := repository.NewEntryRepository(db.Pool)
+	collectionRepo := repository.NewCollectionRepository(db.Pool)
+	typeRepo := repository.NewTypeRepository(db.Pool)
+
+	// Demo entries reference the seed images, which the server normally upserts on startup
+	if err := entryRepo.UpsertSeedImages(ctx, seed.Images); err != nil {
+		return err
+	}
+
+	// Quotas are for app users; the demo account gets its full data set
+	seeder := seed.NewDemoSeeder(
+		userRepo,
+		service.NewCollectionService(collectionRepo, typeRepo, config.QuotasConfig{}, service.RandomIDs),
+		service.NewTypeService(typeRepo),
+		service.NewEntryService(entryRepo, collectionRepo, typeRepo, repository.NewWorkspaceRepository(db.Pool), userRepo, config.QuotasConfig{}, 0, service.RandomIDs),
+	)
+	user, err := seeder.Seed(ctx, *email, time.Now())
+	if errors.Is(err, seed.ErrDemoUserExists) {
+		return fmt.Errorf("%s (%s) already has data, delete it first to reseed", *email, user.ID)
+	}
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("seeded demo data for user %s (%s)\n", user.ID, *email)
+	return nil
+}
+
+func runRotateEncryptionKey(ctx context.Context, a *app, args []string) error {
+	fs := flag.NewFlagSet("rotate-encryption-key", flag.ContinueOnError)
+	newDataKey := fs.Bool("new-data-key", false, "replace the data key and re-encrypt everything with the new one")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if !a.cfg.Encryption.Enabled() {
+		return errors.New("encryption.key is not set")
+	}
+
+	db, err := a.connect(ctx)
+	if err != nil {
+		return err
+	}
+	encryptionService, err := service.NewEncryptionService(a.cfg.Encryption,
+		repository.NewEncryptionKeyRepository(db.Pool),
+		repository.NewUserRepository(db.Pool),
+		repository.NewVerificationCodeRepository(db.Pool),
+		repository.NewEntryRepository(db.Pool))
+	if err != nil {
+		return err
+	}
+
+	result, err := encryptionService.Rotate(ctx, *newDataKey)
+	if result != nil {
+		fmt.Printf("re-wrapped %d keys (new data key: %t); encrypted %d emails, %d sign-in identities, %d device infos, %d IP addresses, %d verification codes, %d entry devices\n",
+			result.RewrappedKeys, result.NewDataKey, result.Emails, result.Identities, result.DeviceInfo, result.IPAddresses, result.Codes, result.EntryDevices)
+	}
+	return err
+}
+
+// resolveUser finds a user by id or, failing to parse one, by email.
+func resolveUser(ctx context.Context, userRepo *repository.UserRepository, ref string) (*repository.User, error) {
+	if ref == "" {
+		return nil, errors.New("-user is required")
+	}
+	if id, err := uuid.Parse(ref); err == nil {
+		return userRepo.GetUserByID(ctx, id)
+	}
+	return userRepo.GetUserByEmail(ctx, ref)
+}
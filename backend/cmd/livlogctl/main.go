@@ -0,0 +1,149 @@
+// Command livlogctl runs operational tasks against a livlog deployment using
+// the server's configuration file and environment.
+//
+//	livlogctl [-config path] [-migrations dir] <command> [flags]
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"sort"
+	"syscall"
+
+	"go.uber.org/zap"
+
+	"github.com/avalarin/livlog/backend/internal/config"
+	"github.com/avalarin/livlog/backend/internal/logger"
+	"github.com/avalarin/livlog/backend/internal/repository"
+	"github.com/avalarin/livlog/backend/internal/service"
+)
+
+type command struct {
+	usage string
+	run   func(ctx context.Context, app *app, args []string) error
+}
+
+var commands = map[string]command{
+	"migrate":               {"migrate [-down N]  apply pending migrations, or roll back N", runMigrate},
+	"create-admin":          {"create-admin -email E [-name N]  create a user or promote an existing one to admin", runCreateAdmin},
+	"generate-jwt-keys":     {"generate-jwt-keys [-force]  write a new RSA key pair to jwt.private_key_path/public_key_path", runGenerateJWTKeys},
+	"export-user":           {"export-user -user EMAIL|ID [-out FILE] [-images]  export a user's data as JSON", runExportUser},
+	"reset-ai-quota":        {"reset-ai-quota -user EMAIL|ID  clear a user's AI search usage", runResetAIQuota},
+	"seed-demo-data":        {"seed-demo-data [-email E]  create a demo user with two years of sample entries", runSeedDemoData},
+	"rotate-encryption-key": {"rotate-encryption-key [-new-data-key]  re-wrap keys with encryption.key and encrypt what isn't yet", runRotateEncryptionKey},
+}
+
+// app holds what commands share. The database is connected on first use, so
+// commands that don't need it work without one.
+type app struct {
+	cfg            *config.Config
+	log            *zap.Logger
+	migrationsPath string
+	db             *repository.DB
+}
+
+func (a *app) connect(ctx context.Context) (*repository.DB, error) {
+	if a.db == nil {
+		db, err := repository.NewDB(ctx, &a.cfg.Database, a.log)
+		if err != nil {
+			return nil, err
+		}
+		a.db = db
+	}
+	return a.db, nil
+}
+
+// userRepo returns a user repository that reads and writes emails the way
+// the server does, encrypted when encryption is enabled.
+func (a *app) userRepo(ctx context.Context) (*repository.UserRepository, error) {
+	db, err := a.connect(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	userRepo := repository.NewUserRepository(db.Pool)
+	if a.cfg.Encryption.Enabled() {
+		encryptionService, err := service.NewEncryptionService(a.cfg.Encryption,
+			repository.NewEncryptionKeyRepository(db.Pool), userRepo, repository.NewVerificationCodeRepository(db.Pool),
+			repository.NewEntryRepository(db.Pool))
+		if err == nil {
+			_, err = encryptionService.Enable(ctx)
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	return userRepo, nil
+}
+
+func main() {
+	configPath := flag.String("config", "", "path to config file")
+	migrationsPath := flag.String("migrations", "migrations", "path to migrations directory")
+	flag.Usage = usage
+	flag.Parse()
+
+	if flag.NArg() == 0 {
+		usage()
+		os.Exit(2)
+	}
+	cmd, ok := commands[flag.Arg(0)]
+	if !ok {
+		fmt.Fprintf(os.Stderr, "unknown command %q\n\n", flag.Arg(0))
+		usage()
+		os.Exit(2)
+	}
+
+	cfg, err := config.Load(*configPath)
+	if err == nil {
+		err = cfg.Validate()
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+
+	// Keep the output to what the command prints unless asked otherwise
+	level := cfg.Logging.Level
+	if level == "" {
+		level = "warn"
+	}
+	log, _, err := logger.New(cfg.Logging.Format, level)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "failed to initialize logger:", err)
+		os.Exit(1)
+	}
+	defer func() {
+		_ = log.Sync()
+	}()
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	a := &app{cfg: cfg, log: log, migrationsPath: *migrationsPath}
+	err = cmd.run(ctx, a, flag.Args()[1:])
+	if a.db != nil {
+		a.db.Close()
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%s: %v\n", flag.Arg(0), err)
+		os.Exit(1)
+	}
+}
+
+func usage() {
+	fmt.Fprintln(os.Stderr, "usage: livlogctl [-config path] [-migrations dir] <command> [flags]")
+	fmt.Fprintln(os.Stderr)
+	fmt.Fprintln(os.Stderr, "commands:")
+
+	names := make([]string, 0, len(commands))
+	for name := range commands {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		fmt.Fprintf(os.Stderr, "  %s\n", commands[name].usage)
+	}
+}
@@ -0,0 +1,209 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os/exec"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/avalarin/livlog/backend/internal/backup"
+	"github.com/avalarin/livlog/backend/internal/config"
+	"github.com/avalarin/livlog/backend/internal/handler"
+	"github.com/avalarin/livlog/backend/internal/repository"
+	"github.com/avalarin/livlog/backend/internal/service"
+)
+
+// checkTimeout bounds each check except the database connection, which
+// retries for database.connect_timeout like the server does.
+const checkTimeout = 10 * time.Second
+
+// checkResult is one line of the --check report. Status is "ok", "fail" or
+// "skipped" (not configured, or a check it depends on failed).
+type checkResult struct {
+	Name       string `json:"name"`
+	Status     string `json:"status"`
+	Detail     string `json:"detail,omitempty"`
+	Error      string `json:"error,omitempty"`
+	DurationMS int64  `json:"duration_ms"`
+}
+
+type checkReport struct {
+	Status  string        `json:"status"` // "ok" unless a check failed
+	Version string        `json:"version"`
+	Checks  []checkResult `json:"checks"`
+}
+
+type checker struct {
+	report checkReport
+}
+
+// run records the outcome of fn; the detail it returns is kept on success.
+func (c *checker) run(name string, timeout time.Duration, fn func(ctx context.Context) (string, error)) bool {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	start := time.Now()
+	detail, err := fn(ctx)
+	result := checkResult{Name: name, Status: "ok", Detail: detail, DurationMS: time.Since(start).Milliseconds()}
+	if err != nil {
+		result.Status, result.Detail, result.Error = "fail", "", err.Error()
+		c.report.Status = "fail"
+	}
+	c.report.Checks = append(c.report.Checks, result)
+	return err == nil
+}
+
+func (c *checker) skip(name, reason string) {
+	c.report.Checks = append(c.report.Checks, checkResult{Name: name, Status: "skipped", Detail: reason})
+}
+
+// runCheck loads the config, then checks everything the server needs to
+// start: JWT keys, the database and its replicas, the schema version and the
+// configured providers. It writes a JSON report to out and returns the exit
+// code, 1 if any check failed.
+func runCheck(configPath, migrationsPath string, out io.Writer) int {
+	c := &checker{report: checkReport{Status: "ok", Version: handler.Version}}
+	defer func() {
+		enc := json.NewEncoder(out)
+		enc.SetIndent("", "  ")
+		_ = enc.Encode(c.report)
+	}()
+
+	var cfg *config.Config
+	ok := c.run("config", checkTimeout, func(ctx context.Context) (string, error) {
+		var err error
+		if cfg, err = config.Load(configPath); err != nil {
+			return "", err
+		}
+		return "", cfg.Validate()
+	})
+	if !ok {
+		for _, name := range []string{"jwt_keys", "database", "migrations", "openrouter", "backup_store"} {
+			c.skip(name, "config failed")
+		}
+		return 1
+	}
+
+	// Logs would corrupt the report
+	log := zap.NewNop()
+
+	c.run("jwt_keys", checkTimeout, func(ctx context.Context) (string, error) {
+		jwtService, err := service.NewJWTService(
+			cfg.JWT.PrivateKeyPath,
+			cfg.JWT.PublicKeyPath,
+			cfg.JWT.AccessTokenLifetime,
+			cfg.JWT.RefreshTokenLifetime,
+			cfg.JWT.Issuer,
+			cfg.JWT.Audience,
+			service.SystemClock,
+		)
+		if err != nil {
+			return "", err
+		}
+
+		// A token signed with the private key must verify with the public one
+		token, err := jwtService.GenerateAccessToken("check", "")
+		if err != nil {
+			return "", err
+		}
+		if _, err := jwtService.ValidateAccessToken(token); err != nil {
+			return "", fmt.Errorf("public key does not match the private key: %w", err)
+		}
+		return "", nil
+	})
+
+	var db *repository.DB
+	ok = c.run("database", cfg.Database.ConnectTimeout+checkTimeout, func(ctx context.Context) (string, error) {
+		var err error
+		if db, err = repository.NewDB(ctx, &cfg.Database, log); err != nil {
+			return "", err
+		}
+		latency, err := db.Ping(ctx)
+		return fmt.Sprintf("ping %s", latency.Round(time.Millisecond)), err
+	})
+	if db != nil {
+		defer db.Close()
+	}
+
+	for i, dsn := range cfg.Database.Replicas {
+		c.run(fmt.Sprintf("database_replica_%d", i+1), cfg.Database.ConnectTimeout+checkTimeout, func(ctx context.Context) (string, error) {
+			replica, err := repository.NewReplicaDB(ctx, dsn, &cfg.Database, log)
+			if err != nil {
+				return "", err
+			}
+			defer replica.Close()
+
+			// The lag is measured against the primary
+			if db == nil {
+				return "lag not checked", nil
+			}
+			router := repository.NewReplicaRouter(db, []*repository.DB{replica}, cfg.Database.MaxReplicaLag, log)
+			if err := router.CheckLag(ctx); err != nil {
+				return "", err
+			}
+			if router.Read() == db.Pool {
+				return "", fmt.Errorf("lags more than %s behind the primary, or is not a replica", cfg.Database.MaxReplicaLag)
+			}
+			return "", nil
+		})
+	}
+
+	if ok {
+		c.run("migrations", checkTimeout, func(ctx context.Context) (string, error) {
+			latest, err := repository.LatestMigrationVersion(migrationsPath)
+			if err != nil {
+				return "", err
+			}
+			version, dirty, err := db.MigrationVersion(ctx)
+			if err != nil {
+				return "", err
+			}
+			if dirty {
+				return "", fmt.Errorf("version %d is dirty; fix the schema and force the version before deploying", version)
+			}
+			if version < latest {
+				return fmt.Sprintf("at version %d, %d pending migrations are applied on startup", version, latest-version), nil
+			}
+			return fmt.Sprintf("at version %d", version), nil
+		})
+	} else {
+		c.skip("migrations", "database failed")
+	}
+
+	if cfg.OpenRouter.Enabled() {
+		c.run("openrouter", checkTimeout, func(ctx context.Context) (string, error) {
+			aiSearchService, err := service.NewAISearchService(cfg, nil, nil, nil, nil, service.SystemClock, service.RandomIDs, log)
+			if err != nil {
+				return "", err
+			}
+			return "", aiSearchService.Ping(ctx)
+		})
+	} else {
+		c.skip("openrouter", "openrouter.api_key is not set")
+	}
+
+	if cfg.Backup.Enabled() {
+		c.run("backup_store", checkTimeout, func(ctx context.Context) (string, error) {
+			if _, err := exec.LookPath(cfg.Backup.PGDumpPath); err != nil {
+				return "", fmt.Errorf("pg_dump not found: %w", err)
+			}
+			// Any answer about a made-up key shows the store is reachable
+			if _, err := backup.NewStore(cfg.Backup).Stat(ctx, "livlog-check.dump"); err != nil && !errors.Is(err, backup.ErrNotFound) {
+				return "", err
+			}
+			return "", nil
+		})
+	} else {
+		c.skip("backup_store", "backup.dir and backup.s3_bucket are not set")
+	}
+
+	if c.report.Status != "ok" {
+		return 1
+	}
+	return 0
+}
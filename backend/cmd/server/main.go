@@ -2,10 +2,14 @@ package main
 
 import (
 	"context"
+	"errors"
 	"flag"
+	"fmt"
+	"net"
 	"net/http"
 	"os"
 	"os/signal"
+	"sync"
 	"syscall"
 	"time"
 
@@ -13,29 +17,46 @@ import (
 	chimw "github.com/go-chi/chi/v5/middleware"
 	"github.com/prometheus/client_golang/prometheus/promhttp"
 	"go.uber.org/zap"
+	"google.golang.org/grpc"
 
+	"github.com/avalarin/livlog/backend/internal/apiv1"
+	"github.com/avalarin/livlog/backend/internal/backup"
 	"github.com/avalarin/livlog/backend/internal/config"
+	"github.com/avalarin/livlog/backend/internal/errortracking"
+	"github.com/avalarin/livlog/backend/internal/grpcserver"
 	"github.com/avalarin/livlog/backend/internal/handler"
+	"github.com/avalarin/livlog/backend/internal/jobs"
 	"github.com/avalarin/livlog/backend/internal/logger"
 	"github.com/avalarin/livlog/backend/internal/middleware"
 	"github.com/avalarin/livlog/backend/internal/repository"
 	"github.com/avalarin/livlog/backend/internal/seed"
 	"github.com/avalarin/livlog/backend/internal/service"
+	"github.com/avalarin/livlog/backend/internal/tracing"
 )
 
 func main() {
 	configPath := flag.String("config", "", "path to config file")
 	migrationsPath := flag.String("migrations", "migrations", "path to migrations directory")
+	seedDemo := flag.Bool("seed-demo", false, "create the demo user and its data on startup if missing")
+	check := flag.Bool("check", false, "check config, JWT keys, database, migrations and providers, print a JSON report and exit")
 	flag.Parse()
 
+	if *check {
+		os.Exit(runCheck(*configPath, *migrationsPath, os.Stdout))
+	}
+
 	// Load configuration
 	cfg, err := config.Load(*configPath)
 	if err != nil {
 		panic("failed to load config: " + err.Error())
 	}
+	if err := cfg.Validate(); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
 
 	// Initialize logger
-	log, err := logger.New(cfg.Logging.Format)
+	log, logLevel, err := logger.New(cfg.Logging.Format, cfg.Logging.Level)
 	if err != nil {
 		panic("failed to initialize logger: " + err.Error())
 	}
@@ -48,27 +69,110 @@ func main() {
 		zap.String("address", cfg.Server.Address()),
 	)
 
-	// Run migrations
-	log.Info("running database migrations")
-	if err := repository.RunMigrations(&cfg.Database, *migrationsPath, log); err != nil {
-		log.Fatal("failed to run migrations", zap.Error(err))
+	// Initialize tracing
+	shutdownTracing, err := tracing.Setup(context.Background(), &cfg.Tracing, handler.Version)
+	if err != nil {
+		log.Fatal("failed to initialize tracing", zap.Error(err))
+	}
+	defer func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		if err := shutdownTracing(ctx); err != nil {
+			log.Error("failed to shutdown tracing", zap.Error(err))
+		}
+	}()
+
+	// Initialize error tracking; nil when errortracking.dsn is not set
+	tracker, err := errortracking.New(&cfg.ErrorTracking, handler.Version, log)
+	if err != nil {
+		log.Fatal("failed to initialize error tracking", zap.Error(err))
 	}
+	defer func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		if err := tracker.Close(ctx); err != nil {
+			log.Error("failed to flush error tracking", zap.Error(err))
+		}
+	}()
+
+	// The lifecycle context is cancelled on SIGINT/SIGTERM. Startup and all
+	// background work derive from it, so shutdown stops them before the
+	// database pool closes.
+	ctx, stopSignals := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stopSignals()
 
 	// Connect to database
-	ctx := context.Background()
 	db, err := repository.NewDB(ctx, &cfg.Database, log)
 	if err != nil {
 		log.Fatal("failed to connect to database", zap.Error(err))
 	}
 	defer db.Close()
 
+	// Run migrations once the database is reachable
+	log.Info("running database migrations")
+	if err := repository.RunMigrations(&cfg.Database, *migrationsPath, log); err != nil {
+		log.Fatal("failed to run migrations", zap.Error(err))
+	}
+	migrationVersion, err := repository.LatestMigrationVersion(*migrationsPath)
+	if err != nil {
+		log.Fatal("failed to read migrations", zap.Error(err))
+	}
+
+	// Listings, search, stats and sync pulls read from replicas when there are any
+	var replicas []*repository.DB
+	var replicaRouter *repository.ReplicaRouter
+	if len(cfg.Database.Replicas) > 0 {
+		for _, dsn := range cfg.Database.Replicas {
+			replica, err := repository.NewReplicaDB(ctx, dsn, &cfg.Database, log)
+			if err != nil {
+				log.Fatal("failed to connect to database replica", zap.Error(err))
+			}
+			defer replica.Close()
+			replicas = append(replicas, replica)
+		}
+		replicaRouter = repository.NewReplicaRouter(db, replicas, cfg.Database.MaxReplicaLag, log)
+		if err := replicaRouter.CheckLag(ctx); err != nil {
+			log.Fatal("failed to check database replicas", zap.Error(err))
+		}
+	}
+
 	// Initialize repositories
 	userRepo := repository.NewUserRepository(db.Pool)
 	codeRepo := repository.NewVerificationCodeRepository(db.Pool)
 	collectionRepo := repository.NewCollectionRepository(db.Pool)
 	entryRepo := repository.NewEntryRepository(db.Pool)
+	entryRepo.UseTrigramSearch(cfg.Database.TrigramSearch)
 	typeRepo := repository.NewTypeRepository(db.Pool)
 	aiSearchUsageRepo := repository.NewAISearchUsageRepository(db.Pool)
+	syncRepo := repository.NewSyncRepository(db.Pool)
+	webhookRepo := repository.NewWebhookRepository(db.Pool)
+	notificationRepo := repository.NewNotificationRepository(db.Pool)
+	statsRepo := repository.NewStatsRepository(db.Pool)
+	outboxRepo := repository.NewOutboxRepository(db.Pool)
+	exportRepo := repository.NewExportRepository(db.Pool)
+	backupRepo := repository.NewBackupRepository(db.Pool)
+	profileRepo := repository.NewProfileRepository(db.Pool)
+	channelRepo := repository.NewNotificationChannelRepository(db.Pool)
+	activityRepo := repository.NewActivityRepository(db.Pool)
+	workspaceRepo := repository.NewWorkspaceRepository(db.Pool)
+	usageRepo := repository.NewUsageRepository(db.Pool)
+	if cfg.Encryption.Enabled() {
+		encryptionService, err := service.NewEncryptionService(cfg.Encryption,
+			repository.NewEncryptionKeyRepository(db.Pool), userRepo, codeRepo, entryRepo)
+		if err == nil {
+			_, err = encryptionService.Enable(ctx)
+		}
+		if err != nil {
+			log.Fatal("failed to enable encryption", zap.Error(err))
+		}
+	}
+	if replicaRouter != nil {
+		entryRepo.UseReplicas(replicaRouter)
+		collectionRepo.UseReplicas(replicaRouter)
+		typeRepo.UseReplicas(replicaRouter)
+		statsRepo.UseReplicas(replicaRouter)
+		syncRepo.UseReplicas(replicaRouter)
+	}
 
 	// Seed cover images with fixed UUIDs
 	log.Info("seeding cover images")
@@ -77,6 +181,8 @@ func main() {
 	}
 
 	// Initialize services
+	clock, ids := service.SystemClock, service.RandomIDs
+	service.UsePageSizes(cfg.API)
 	appleVerifier := service.NewAppleVerifier(cfg.Apple.BundleID)
 	jwtService, err := service.NewJWTService(
 		cfg.JWT.PrivateKeyPath,
@@ -85,103 +191,410 @@ func main() {
 		cfg.JWT.RefreshTokenLifetime,
 		cfg.JWT.Issuer,
 		cfg.JWT.Audience,
+		clock,
 	)
 	if err != nil {
 		log.Fatal("failed to initialize JWT service", zap.Error(err))
 	}
 
-	authService := service.NewAuthService(userRepo, appleVerifier, jwtService)
+	// Without a mail server codes aren't sent and emails can't be verified again
+	mailer, err := service.NewMailer(cfg.Mail)
+	if err != nil {
+		log.Fatal("failed to initialize mailer", zap.Error(err))
+	}
+
+	authService := service.NewAuthService(userRepo, appleVerifier, jwtService, mailer, clock)
 
 	// Initialize rate limiter for email auth (60 second window)
-	rateLimiter := service.NewRateLimiter(60 * time.Second)
+	rateLimiter := service.NewRateLimiter(60*time.Second, clock)
+
+	// Per-user limits for expensive routes
+	limiters := routeLimiters{
+		search: service.NewWindowLimiter(cfg.RateLimit.Search.Requests, cfg.RateLimit.Search.Period, clock),
+		bulk:   service.NewWindowLimiter(cfg.RateLimit.Bulk.Requests, cfg.RateLimit.Bulk.Period, clock),
+	}
 
 	// Initialize email auth service
-	emailAuthService := service.NewEmailAuthService(userRepo, codeRepo, jwtService, rateLimiter)
+	emailAuthService := service.NewEmailAuthService(userRepo, codeRepo, jwtService, rateLimiter, mailer, cfg.Auth.StripEmailPlusTags, clock)
 
 	// Initialize collection, entry, and type services
-	collectionService := service.NewCollectionService(collectionRepo)
-	entryService := service.NewEntryService(entryRepo, collectionRepo, typeRepo)
+	collectionService := service.NewCollectionService(collectionRepo, typeRepo, cfg.Quotas, ids)
+	webhookService := service.NewWebhookService(webhookRepo, clock, log)
+	channelService := service.NewChannelService(channelRepo, cfg.Channels, log)
+	notificationService := service.NewNotificationService(notificationRepo, channelService)
+	statsService := service.NewStatsService(statsRepo, entryRepo, clock)
+	booksService := service.NewBooksService(cfg.Books, log)
+	retentionService := service.NewRetentionService(userRepo, cfg.Retention, clock, log)
+	outboxService := service.NewOutboxService(outboxRepo, entryRepo)
+	entryService := service.NewEntryService(entryRepo, collectionRepo, typeRepo, workspaceRepo, userRepo, cfg.Quotas, cfg.Entries.ConcurrentUpdateWindow, ids)
+	exportService := service.NewExportService(exportRepo, entryRepo, collectionRepo, typeRepo, notificationService, clock, log)
+	var backupService *service.BackupService
+	if cfg.Backup.Enabled() {
+		backupService = service.NewBackupService(backupRepo, backup.NewStore(cfg.Backup), cfg.Database, cfg.Backup, log)
+	} else {
+		log.Warn("backup.dir and backup.s3_bucket are not set, database backups are disabled")
+	}
 	typeService := service.NewTypeService(typeRepo)
+	profileService := service.NewProfileService(profileRepo, collectionRepo, entryRepo)
+	activityService := service.NewActivityService(activityRepo)
+	usageService := service.NewUsageService(usageRepo, userRepo, clock)
+	workspaceService := service.NewWorkspaceService(workspaceRepo, collectionRepo, typeRepo, entryRepo, cfg.Workspaces, clock)
+	syncService := service.NewSyncService(syncRepo, entryRepo, collectionRepo, entryService, collectionService)
+	changeFeed := service.NewChangeFeed(syncRepo, log)
+	importService := service.NewImportService(entryService, collectionService, typeService, entryRepo, collectionRepo, typeRepo, cfg.Quotas)
+
+	if *seedDemo {
+		seeder := seed.NewDemoSeeder(userRepo, collectionService, typeService, entryService)
+		user, err := seeder.Seed(ctx, seed.DefaultDemoEmail, clock.Now())
+		switch {
+		case errors.Is(err, seed.ErrDemoUserExists):
+			log.Info("demo data already present", zap.String("user_id", user.ID.String()))
+		case err != nil:
+			log.Fatal("failed to seed demo data", zap.Error(err))
+		default:
+			log.Info("seeded demo data", zap.String("user_id", user.ID.String()), zap.String("email", seed.DefaultDemoEmail))
+		}
+	}
 
 	// Initialize AI search service
-	aiSearchService, err := service.NewAISearchService(cfg, aiSearchUsageRepo, userRepo, log)
-	if err != nil {
-		log.Fatal("failed to initialize AI search service", zap.Error(err))
+	var aiSearchService *service.AISearchService
+	if cfg.OpenRouter.Enabled() {
+		aiSearchService, err = service.NewAISearchService(cfg, aiSearchUsageRepo, userRepo, booksService, notificationService, clock, ids, log)
+		if err != nil {
+			log.Fatal("failed to initialize AI search service", zap.Error(err))
+		}
+	} else {
+		log.Warn("openrouter.api_key is not set, AI search is disabled")
 	}
 
+	rateLimitService := service.NewRateLimitService(userRepo, aiSearchUsageRepo, aiSearchService, rateLimiter, map[string]*service.WindowLimiter{
+		"search": limiters.search,
+		"bulk":   limiters.bulk,
+	}, clock)
+
 	// Initialize handlers
-	healthHandler := handler.NewHealthHandler(db)
-	authHandler := handler.NewAuthHandler(authService, emailAuthService)
+	healthHandler := handler.NewHealthHandler(db, migrationVersion)
+	for i, replica := range replicas {
+		healthHandler.AddCheck(handler.HealthCheck{
+			Name:     fmt.Sprintf("database_replica_%d", i+1),
+			Optional: true,
+			Check: func(ctx context.Context) error {
+				_, err := replica.Ping(ctx)
+				return err
+			},
+		})
+	}
+	if aiSearchService != nil {
+		healthHandler.AddCheck(handler.HealthCheck{
+			Name:     "openrouter",
+			Optional: true,
+			Check:    aiSearchService.Ping,
+		})
+	}
+	authHandler := handler.NewAuthHandler(authService, emailAuthService, aiSearchService)
 	collectionHandler := handler.NewCollectionHandler(collectionService)
 	entryHandler := handler.NewEntryHandler(entryService)
 	typeHandler := handler.NewTypeHandler(typeService)
 	aiSearchHandler := handler.NewAISearchHandler(aiSearchService)
+	syncHandler := handler.NewSyncHandler(syncService, changeFeed)
+	webhookHandler := handler.NewWebhookHandler(webhookService)
+	notificationHandler := handler.NewNotificationHandler(notificationService, channelService)
+	statsHandler := handler.NewStatsHandler(statsService)
+	adminHandler := handler.NewAdminHandler(statsService, backupService, rateLimitService)
+	lookupHandler := handler.NewLookupHandler(booksService)
+	exportHandler := handler.NewExportHandler(exportService)
+	jobHandler := handler.NewJobHandler(exportService, jwtService)
+	importHandler := handler.NewImportHandler(importService)
+	profileHandler := handler.NewProfileHandler(profileService)
+	activityHandler := handler.NewActivityHandler(activityService)
+	usageHandler := handler.NewUsageHandler(usageService)
+	workspaceHandler := handler.NewWorkspaceHandler(workspaceService)
+	bootstrapHandler := handler.NewBootstrapHandler(
+		service.NewBootstrapService(authService, collectionService, typeService, profileService, cfg.Quotas),
+		aiSearchService,
+	)
+	openAPIHandler, err := handler.NewOpenAPIHandler()
+	if err != nil {
+		log.Fatal("failed to initialize openapi handler", zap.Error(err))
+	}
+	if cfg.CDN.Enabled() {
+		apiv1.UseCDN(cfg.CDN.BaseURL)
+		log.Info("serving image URLs through CDN", zap.String("base_url", cfg.CDN.BaseURL))
+	}
 
 	// Setup router
 	r := chi.NewRouter()
 
 	// Global middleware
-	r.Use(chimw.RequestID)
+	r.Use(middleware.Tracing)
+	r.Use(middleware.RequestID)
 	r.Use(chimw.RealIP)
 	r.Use(middleware.Logging(log))
+	r.Use(middleware.PayloadLogging(cfg.Logging.Payloads))
 	r.Use(middleware.Metrics)
-	r.Use(chimw.Recoverer)
-
-	// Metrics endpoint (no /api/v1 prefix)
-	r.Handle("/metrics", promhttp.Handler())
-
-	// API v1 routes
-	r.Route("/api/v1", func(r chi.Router) {
-		// Public routes
-		r.Get("/health", healthHandler.Health)
-		r.Post("/auth/apple", authHandler.AppleAuth)
-		r.Post("/auth/email/send-code", authHandler.SendVerificationCode)
-		r.Post("/auth/email/resend-code", authHandler.ResendVerificationCode)
-		r.Post("/auth/email/verify", authHandler.VerifyEmailCode)
-		r.Post("/auth/refresh", authHandler.RefreshToken)
-		entryHandler.RegisterPublicRoutes(r)
-
-		// Protected routes
-		r.Group(func(r chi.Router) {
-			r.Use(middleware.AuthMiddleware(jwtService))
-
-			r.Get("/auth/me", authHandler.GetMe)
-			r.Post("/auth/logout", authHandler.Logout)
-			r.Delete("/auth/account", authHandler.DeleteAccount)
-
-			// Collections, entries, and types endpoints
-			collectionHandler.RegisterRoutes(r)
-			entryHandler.RegisterRoutes(r)
-			typeHandler.RegisterRoutes(r)
-
-			// AI search endpoint
-			aiSearchHandler.RegisterRoutes(r)
+	r.Use(middleware.Recoverer(tracker))
+
+	// Metrics and probe endpoints (no /api/v1 prefix). With metrics.port set,
+	// /metrics moves to its own listener below.
+	metricsHandler := middleware.MetricsAccess(cfg.Metrics)(promhttp.Handler())
+	if cfg.Metrics.Port == 0 {
+		r.Handle("/metrics", metricsHandler)
+	}
+	healthHandler.RegisterProbeRoutes(r)
+
+	// API versions are mounted side by side with the same routes. Handlers map
+	// responses whose shape changed with respondWithVersionedJSON; set
+	// Deprecated/Sunset on a version once clients should move off it.
+	apiVersions := []middleware.APIVersion{
+		{Number: 1},
+		{Number: 2},
+	}
+	for _, version := range apiVersions {
+		r.Route(version.Prefix(), func(r chi.Router) {
+			r.Use(middleware.Versioned(version))
+			r.Use(middleware.CORS(cfg.CORS))
+
+			// Public routes
+			r.Group(func(r chi.Router) {
+				r.Use(middleware.MaxBodySize(cfg.Limits.MaxBodyBytes))
+				r.Use(middleware.Timeout(cfg.Limits.RequestTimeout))
+
+				r.Get("/health", healthHandler.Health)
+				r.Post("/auth/apple", authHandler.AppleAuth)
+				r.Post("/auth/email/send-code", authHandler.SendVerificationCode)
+				r.Post("/auth/email/resend-code", authHandler.ResendVerificationCode)
+				r.Post("/auth/email/verify", authHandler.VerifyEmailCode)
+				r.Post("/auth/refresh", authHandler.RefreshToken)
+				if cfg.Auth.IntrospectionToken != "" {
+					r.With(middleware.ServiceAuth(cfg.Auth.IntrospectionToken)).Post("/auth/introspect", authHandler.Introspect)
+				}
+				// Images, which only the CDN may fetch once it has an origin secret
+				r.Group(func(r chi.Router) {
+					if cfg.CDN.OriginAuthSecret != "" {
+						r.Use(middleware.OriginAuth(cfg.CDN.OriginAuthHeader, cfg.CDN.OriginAuthSecret))
+					}
+					entryHandler.RegisterPublicRoutes(r)
+					collectionHandler.RegisterPublicRoutes(r)
+				})
+				jobHandler.RegisterPublicRoutes(r)
+				profileHandler.RegisterPublicRoutes(r)
+				openAPIHandler.RegisterRoutes(r)
+			})
+
+			// Protected routes
+			r.Group(func(r chi.Router) {
+				r.Use(middleware.AuthMiddleware(jwtService))
+				r.Use(middleware.CountCalls(usageService.RecordCall))
+				r.Use(middleware.ChangeSeq(syncService.ChangeSeq))
+
+				r.Group(func(r chi.Router) {
+					r.Use(middleware.MaxBodySize(cfg.Limits.MaxBodyBytes))
+					r.Use(middleware.Timeout(cfg.Limits.RequestTimeout))
+
+					r.Get("/auth/me", authHandler.GetMe)
+					r.Get("/auth/me/overview", authHandler.GetMeOverview)
+					usageHandler.RegisterRoutes(r)
+					r.Post("/auth/logout", authHandler.Logout)
+					r.Delete("/auth/account", authHandler.DeleteAccount)
+
+					// Everything the app loads on launch, in one request
+					bootstrapHandler.RegisterRoutes(r)
+
+					// Collections and types endpoints
+					collectionHandler.RegisterRoutes(r)
+					typeHandler.RegisterRoutes(r)
+
+					// Recaps
+					statsHandler.RegisterRoutes(r)
+
+					// Barcode lookups
+					lookupHandler.RegisterRoutes(r)
+
+					// Internal dashboard
+					r.Group(func(r chi.Router) {
+						r.Use(middleware.RequireRole(userRepo, repository.UserRoleAdmin))
+						adminHandler.RegisterRoutes(r)
+					})
+
+					// Webhook management
+					webhookHandler.RegisterRoutes(r)
+
+					// In-app notification inbox
+					notificationHandler.RegisterRoutes(r)
+
+					// Change history of entries and collections
+					activityHandler.RegisterRoutes(r)
+
+					// Generated exports, also available as jobs
+					exportHandler.RegisterRoutes(r)
+					jobHandler.RegisterRoutes(r)
+
+					// Public profile settings
+					profileHandler.RegisterRoutes(r)
+
+					// Shared workspaces, soft-launched behind workspaces.enabled
+					if cfg.Workspaces.Enabled {
+						workspaceHandler.RegisterRoutes(r)
+					}
+
+					// Expensive routes get per-user budgets
+					r.Group(func(r chi.Router) {
+						r.Use(middleware.RateLimit("search", limiters.search))
+						entryHandler.RegisterSearchRoutes(r)
+					})
+					r.Group(func(r chi.Router) {
+						r.Use(middleware.RateLimit("bulk", limiters.bulk))
+						entryHandler.RegisterBulkRoutes(r)
+					})
+				})
+
+				// Entries and sync push carry base64 images, imports carry
+				// whole exports
+				r.Group(func(r chi.Router) {
+					r.Use(middleware.MaxBodySize(cfg.Limits.MaxUploadBodyBytes))
+					r.Use(middleware.Timeout(cfg.Limits.UploadTimeout))
+
+					entryHandler.RegisterRoutes(r)
+					syncHandler.RegisterRoutes(r)
+					importHandler.RegisterRoutes(r)
+				})
+
+				// AI search waits on the model provider
+				r.Group(func(r chi.Router) {
+					r.Use(middleware.MaxBodySize(cfg.Limits.MaxBodyBytes))
+					r.Use(middleware.Timeout(cfg.Limits.AISearchTimeout))
+
+					aiSearchHandler.RegisterRoutes(r)
+				})
+
+				// Change stream stays open; no timeout
+				syncHandler.RegisterStreamRoutes(r)
+			})
 		})
+	}
+
+	// Register background jobs
+	jobRunner := jobs.NewRunner(log)
+	jobRunner.OnFailure(func(job string, err error) {
+		tracker.Capture(errortracking.Event{Err: err, Tags: map[string]string{"job": job}})
+	})
+	if replicaRouter != nil {
+		jobRunner.Register(jobs.Job{
+			Name:     "replica_lag_check",
+			Interval: 5 * time.Second,
+			Run:      replicaRouter.CheckLag,
+		})
+	}
+	jobRunner.Register(jobs.Job{
+		Name:     "rate_limiter_cleanup",
+		Interval: 5 * time.Minute,
+		Run: func(ctx context.Context) error {
+			rateLimiter.Cleanup()
+			limiters.search.Cleanup()
+			limiters.bulk.Cleanup()
+			return nil
+		},
+	})
+	jobRunner.Register(jobs.Job{
+		// Renews Apple's Sign in with Apple keys before the cached ones expire
+		Name:     "apple_keys_refresh",
+		Interval: 6 * time.Hour,
+		Timeout:  30 * time.Second,
+		Retries:  2,
+		Run:      appleVerifier.RefreshKeys,
 	})
+	jobRunner.Register(jobs.Job{
+		Name:     "verification_code_cleanup",
+		Interval: 5 * time.Minute,
+		Timeout:  time.Minute,
+		Retries:  2,
+		Cleanup: func(ctx context.Context) (int64, error) {
+			// Remove expired verification codes older than 24 hours
+			return codeRepo.CleanupExpiredCodes(ctx, 24*time.Hour)
+		},
+	})
+	jobRunner.Register(jobs.Job{
+		// Publishes entry/collection changes to the change feed and webhook queue
+		Name:     "outbox_relay",
+		Interval: time.Second,
+		Timeout:  30 * time.Second,
+		Run:      outboxService.Relay,
+	})
+	jobRunner.Register(jobs.Job{
+		Name:     "webhook_dispatch",
+		Interval: 5 * time.Second,
+		Timeout:  2 * time.Minute,
+		Run:      webhookService.DispatchDue,
+	})
+	jobRunner.Register(jobs.Job{
+		Name:     "webhook_delivery_cleanup",
+		Interval: time.Hour,
+		Timeout:  5 * time.Minute,
+		Retries:  2,
+		Cleanup:  webhookService.CleanupDeliveries,
+	})
+	jobRunner.Register(jobs.Job{
+		Name:     "notification_cleanup",
+		Interval: 24 * time.Hour,
+		Timeout:  5 * time.Minute,
+		Retries:  2,
+		Cleanup:  notificationService.CleanupRead,
+	})
+	jobRunner.Register(jobs.Job{
+		Name:     "activity_cleanup",
+		Interval: 24 * time.Hour,
+		Timeout:  5 * time.Minute,
+		Retries:  2,
+		Cleanup:  activityService.CleanupOld,
+	})
+	jobRunner.Register(jobs.Job{
+		// Writes the API calls counted since the last run
+		Name:     "usage_flush",
+		Interval: time.Minute,
+		Timeout:  30 * time.Second,
+		Run:      usageService.Flush,
+	})
+	jobRunner.Register(jobs.Job{
+		Name:     "usage_cleanup",
+		Interval: 24 * time.Hour,
+		Timeout:  5 * time.Minute,
+		Retries:  2,
+		Cleanup:  usageService.CleanupOld,
+	})
+	jobRunner.Register(jobs.Job{
+		// Renders queued exports (PDF and JSON jobs)
+		Name:     "export_worker",
+		Interval: 5 * time.Second,
+		Timeout:  15 * time.Minute,
+		Run:      exportService.ProcessPending,
+	})
+	jobRunner.Register(jobs.Job{
+		Name:     "export_cleanup",
+		Interval: time.Hour,
+		Timeout:  5 * time.Minute,
+		Retries:  2,
+		Cleanup:  exportService.CleanupFinished,
+	})
+	if cfg.Retention.DeletedUsers > 0 {
+		jobRunner.Register(jobs.Job{
+			// Hard-deletes accounts past the retention period
+			Name:     "deleted_user_purge",
+			Interval: cfg.Retention.PurgeInterval,
+			Timeout:  10 * time.Minute,
+			Retries:  2,
+			Run:      retentionService.PurgeDeletedUsers,
+		})
+	}
 
-	// Start cleanup goroutine for expired verification codes and rate limiter
-	go func() {
-		ticker := time.NewTicker(5 * time.Minute)
-		defer ticker.Stop()
-
-		for {
-			select {
-			case <-ticker.C:
-				// Cleanup rate limiter
-				rateLimiter.Cleanup()
-
-				// Cleanup expired verification codes (older than 24 hours)
-				deleted, err := codeRepo.CleanupExpiredCodes(ctx, 24*time.Hour)
-				if err != nil {
-					log.Error("failed to cleanup verification codes", zap.Error(err))
-				} else if deleted > 0 {
-					log.Info("cleaned up verification codes", zap.Int64("deleted", deleted))
-				}
-			case <-ctx.Done():
-				return
-			}
-		}
-	}()
+	if backupService != nil {
+		jobRunner.Register(jobs.Job{
+			// Queues scheduled backups, takes queued ones and deletes expired ones
+			Name:     "database_backup",
+			Interval: time.Minute,
+			Timeout:  cfg.Backup.Timeout + 5*time.Minute,
+			Run:      backupService.RunDue,
+		})
+	}
 
 	// Create HTTP server
 	server := &http.Server{
@@ -192,6 +605,27 @@ func main() {
 		IdleTimeout:  60 * time.Second,
 	}
 
+	// Start the change feed; end open streams on shutdown so it doesn't wait on them
+	var workers sync.WaitGroup
+	workers.Add(1)
+	go func() {
+		defer workers.Done()
+		changeFeed.Run(ctx)
+	}()
+	server.RegisterOnShutdown(changeFeed.Close)
+
+	jobRunner.Start(ctx)
+
+	// Reload safe-to-change settings on SIGHUP
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	defer signal.Stop(hup)
+	go func() {
+		for range hup {
+			reloadConfig(*configPath, cfg, logLevel, aiSearchService, limiters, log)
+		}
+	}()
+
 	// Start server in goroutine
 	go func() {
 		log.Info("http server listening", zap.String("address", cfg.Server.Address()))
@@ -200,10 +634,77 @@ func main() {
 		}
 	}()
 
-	// Wait for interrupt signal
-	quit := make(chan os.Signal, 1)
-	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
-	<-quit
+	// Start gRPC server on its own listener
+	var grpcServer *grpc.Server
+	if cfg.GRPC.Enabled {
+		grpcServer = grpcserver.New(grpcserver.Services{
+			JWT:        jwtService,
+			Auth:       authService,
+			EmailAuth:  emailAuthService,
+			Collection: collectionService,
+			Entry:      entryService,
+			Type:       typeService,
+			Usage:      usageService,
+			Sync:       syncService,
+			Limiters:   map[string]service.Limiter{"search": limiters.search},
+		})
+
+		lis, err := net.Listen("tcp", cfg.GRPC.Address())
+		if err != nil {
+			log.Fatal("failed to listen for grpc", zap.Error(err))
+		}
+
+		go func() {
+			log.Info("grpc server listening", zap.String("address", cfg.GRPC.Address()))
+			if err := grpcServer.Serve(lis); err != nil {
+				log.Error("grpc server failed", zap.Error(err))
+			}
+		}()
+	}
+
+	// Start debug server (pprof, expvar) on its own listener
+	var debugServer *http.Server
+	if cfg.Debug.Enabled {
+		debugServer = &http.Server{
+			Addr:        cfg.Debug.Address(),
+			Handler:     handler.NewDebugRouter(db),
+			ReadTimeout: 15 * time.Second,
+			// No WriteTimeout: CPU profiles and traces stream for ?seconds=N
+			IdleTimeout: 60 * time.Second,
+		}
+
+		go func() {
+			log.Info("debug server listening", zap.String("address", cfg.Debug.Address()))
+			if err := debugServer.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+				log.Error("debug server failed", zap.Error(err))
+			}
+		}()
+	}
+
+	// Start metrics server on its own listener
+	var metricsServer *http.Server
+	if cfg.Metrics.Port != 0 {
+		metricsMux := http.NewServeMux()
+		metricsMux.Handle("/metrics", metricsHandler)
+		metricsServer = &http.Server{
+			Addr:         cfg.Metrics.Address(),
+			Handler:      metricsMux,
+			ReadTimeout:  15 * time.Second,
+			WriteTimeout: 15 * time.Second,
+			IdleTimeout:  60 * time.Second,
+		}
+
+		go func() {
+			log.Info("metrics server listening", zap.String("address", cfg.Metrics.Address()))
+			if err := metricsServer.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+				log.Error("metrics server failed", zap.Error(err))
+			}
+		}()
+	}
+
+	// Wait for interrupt signal; a second one kills the process
+	<-ctx.Done()
+	stopSignals()
 
 	log.Info("shutting down server...")
 
@@ -215,5 +716,49 @@ func main() {
 		log.Error("server forced to shutdown", zap.Error(err))
 	}
 
+	if grpcServer != nil {
+		grpcServer.GracefulStop()
+	}
+
+	if debugServer != nil {
+		if err := debugServer.Shutdown(shutdownCtx); err != nil {
+			log.Error("debug server forced to shutdown", zap.Error(err))
+		}
+	}
+
+	if metricsServer != nil {
+		if err := metricsServer.Shutdown(shutdownCtx); err != nil {
+			log.Error("metrics server forced to shutdown", zap.Error(err))
+		}
+	}
+
+	// Let in-flight jobs and the change feed finish before the database pool closes
+	if err := jobRunner.Stop(shutdownCtx); err != nil {
+		log.Error("background jobs forced to stop", zap.Error(err))
+	}
+	if err := waitGroup(shutdownCtx, &workers); err != nil {
+		log.Error("background workers forced to stop", zap.Error(err))
+	}
+	// Write the calls counted since the last usage_flush run
+	if err := usageService.Flush(shutdownCtx); err != nil {
+		log.Error("failed to flush usage", zap.Error(err))
+	}
+
 	log.Info("server stopped")
 }
+
+// waitGroup waits for wg, or returns an error once ctx ends.
+func waitGroup(ctx context.Context, wg *sync.WaitGroup) error {
+	done := make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return fmt.Errorf("workers did not stop: %w", ctx.Err())
+	}
+}
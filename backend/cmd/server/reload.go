@@ -0,0 +1,64 @@
+package main
+
+import (
+	"github.com/avalarin/livlog/backend/internal/config"
+	"github.com/avalarin/livlog/backend/internal/logger"
+	"github.com/avalarin/livlog/backend/internal/service"
+	"go.uber.org/zap"
+)
+
+// routeLimiters are the per-user limiters of expensive routes, see
+// config.RateLimitConfig.
+type routeLimiters struct {
+	search *service.WindowLimiter
+	bulk   *service.WindowLimiter
+}
+
+// reloadConfig re-reads the configuration and applies the settings that are
+// safe to change at runtime: the log level, rate limits and the AI model. Everything else needs a restart. An invalid configuration is
+// rejected as a whole and the running settings are kept.
+func reloadConfig(
+	configPath string,
+	current *config.Config,
+	logLevel zap.AtomicLevel,
+	aiSearchService *service.AISearchService,
+	limiters routeLimiters,
+	log *zap.Logger,
+) {
+	cfg, err := config.Load(configPath)
+	if err == nil {
+		err = cfg.Validate()
+	}
+	if err != nil {
+		log.Error("config reload failed, keeping current settings", zap.Error(err))
+		return
+	}
+
+	if err := logger.SetLevel(logLevel, current.Logging.Format, cfg.Logging.Level); err != nil {
+		log.Error("config reload failed, keeping current settings", zap.Error(err))
+		return
+	}
+
+	limiters.search.SetLimit(cfg.RateLimit.Search.Requests, cfg.RateLimit.Search.Period)
+	limiters.bulk.SetLimit(cfg.RateLimit.Bulk.Requests, cfg.RateLimit.Bulk.Period)
+
+	if aiSearchService != nil {
+		if err := aiSearchService.Reload(cfg); err != nil {
+			log.Error("failed to reload AI search settings", zap.Error(err))
+		}
+	} else if cfg.OpenRouter.Enabled() {
+		log.Warn("openrouter.api_key was set, restart the server to enable AI search")
+	}
+
+	log.Info("configuration reloaded",
+		zap.String("log_level", logLevel.String()),
+		zap.String("ai_model", cfg.OpenRouter.Model),
+		zap.Any("ai_routing", cfg.OpenRouter.Routing),
+		zap.Int("ai_search_basic_limit", cfg.RateLimit.AISearchBasicLimit),
+		zap.Int("ai_search_pro_limit", cfg.RateLimit.AISearchProLimit),
+		zap.Int("ai_search_unlimited_limit", cfg.RateLimit.AISearchUnlimitedLimit),
+		zap.String("ai_search_period", cfg.RateLimit.AISearchPeriod),
+		zap.Int("search_limit", cfg.RateLimit.Search.Requests),
+		zap.Int("bulk_limit", cfg.RateLimit.Bulk.Requests),
+	)
+}
@@ -0,0 +1,56 @@
+// Package apitime formats and parses the timestamps of the HTTP API. v1
+// responses keep the layout handlers used to write by hand; from v2 on every
+// timestamp is RFC 3339 in UTC with microseconds, the precision PostgreSQL
+// stores. Input accepts both, so clients can switch at their own pace.
+package apitime
+
+import (
+	"time"
+)
+
+// Layout is how timestamps are written in responses.
+type Layout string
+
+const (
+	// Legacy is the v1 layout: whole seconds, in the offset the time came
+	// with.
+	Legacy Layout = "2006-01-02T15:04:05Z07:00"
+	// UTC is the layout from v2 on: UTC, with fixed-width microseconds, so
+	// timestamps sort as strings.
+	UTC Layout = "2006-01-02T15:04:05.000000Z07:00"
+)
+
+// ForVersion returns the layout of an API version.
+func ForVersion(version int) Layout {
+	if version < 2 {
+		return Legacy
+	}
+	return UTC
+}
+
+// Format writes t in the layout.
+func (l Layout) Format(t time.Time) string {
+	if l == UTC {
+		t = t.UTC()
+	}
+	return t.Format(string(l))
+}
+
+// FormatPtr writes t in the layout, or returns nil for a nil t.
+func (l Layout) FormatPtr(t *time.Time) *string {
+	if t == nil {
+		return nil
+	}
+	s := l.Format(*t)
+	return &s
+}
+
+// Parse reads an RFC 3339 timestamp in either layout, or with any other
+// offset or precision, and returns it in UTC.
+func Parse(s string) (time.Time, error) {
+	t, err := time.Parse(time.RFC3339Nano, s)
+	if err != nil {
+		return time.Time{}, err
+	}
+	return t.UTC(), nil
+}
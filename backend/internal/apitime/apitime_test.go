@@ -0,0 +1,57 @@
+package apitime
+
+import (
+	"testing"
+	"time"
+)
+
+func TestLayoutFormat(t *testing.T) {
+	ts := time.Date(2025, 1, 20, 12, 30, 45, 123456789, time.FixedZone("MSK", 3*60*60))
+
+	tests := []struct {
+		layout Layout
+		want   string
+	}{
+		{Legacy, "2025-01-20T12:30:45+03:00"},
+		{UTC, "2025-01-20T09:30:45.123456Z"},
+	}
+
+	for _, tt := range tests {
+		if got := tt.layout.Format(ts); got != tt.want {
+			t.Errorf("%s: got %s, want %s", tt.layout, got, tt.want)
+		}
+	}
+
+	if got := UTC.FormatPtr(nil); got != nil {
+		t.Errorf("FormatPtr(nil) = %q, want nil", *got)
+	}
+}
+
+func TestForVersion(t *testing.T) {
+	if ForVersion(1) != Legacy || ForVersion(2) != UTC || ForVersion(3) != UTC {
+		t.Error("expected Legacy for v1 and UTC from v2 on")
+	}
+}
+
+func TestParse_AcceptsBothLayouts(t *testing.T) {
+	want := time.Date(2025, 1, 20, 9, 30, 45, 0, time.UTC)
+
+	for _, s := range []string{
+		"2025-01-20T12:30:45+03:00",
+		"2025-01-20T09:30:45Z",
+		"2025-01-20T09:30:45.000000Z",
+	} {
+		got, err := Parse(s)
+		if err != nil {
+			t.Errorf("Parse(%q): %v", s, err)
+			continue
+		}
+		if !got.Equal(want) || got.Location() != time.UTC {
+			t.Errorf("Parse(%q) = %v, want %v in UTC", s, got, want)
+		}
+	}
+
+	if _, err := Parse("2025-01-20"); err == nil {
+		t.Error("expected an error for a date without time")
+	}
+}
@@ -0,0 +1,62 @@
+// Package apiv1 holds the response models of the HTTP API and the mappers
+// that build them from repository types. Handlers only choose what to
+// render; how an entry, collection or type looks on the wire is decided
+// here, once, so every endpoint that returns one returns the same shape.
+// Golden files in testdata pin that shape.
+package apiv1
+
+import (
+	"strings"
+
+	"github.com/google/uuid"
+)
+
+// DateLayout is the wire format for calendar dates (entries, filters).
+const DateLayout = "2006-01-02"
+
+// imageBaseURL prefixes image URLs in responses; empty keeps them relative
+// to the API base, like the image routes themselves. See UseCDN.
+var imageBaseURL string
+
+// UseCDN makes image URLs in responses point at a CDN that pulls from this
+// server, e.g. https://cdn.livlog.app/api/v1. Call it once at startup.
+func UseCDN(baseURL string) {
+	imageBaseURL = strings.TrimSuffix(baseURL, "/")
+}
+
+// imageURL returns the URL of the image served at path. A content hash is
+// appended as ?v=, so the URL changes whenever the image does and can be
+// cached for good.
+func imageURL(path, hash string) string {
+	url := imageBaseURL + path
+	if hash != "" {
+		url += "?v=" + hash[:min(len(hash), 12)]
+	}
+	return url
+}
+
+// EntryImageURL returns the URL of an entry image.
+func EntryImageURL(id uuid.UUID, hash string) string {
+	return imageURL("/images/"+id.String(), hash)
+}
+
+// CollectionIconURL returns the URL of an uploaded collection icon.
+func CollectionIconURL(id uuid.UUID, hash string) string {
+	return imageURL("/collections/icons/"+id.String(), hash)
+}
+
+func uuidStrings(ids []uuid.UUID) []string {
+	result := make([]string, len(ids))
+	for i, id := range ids {
+		result[i] = id.String()
+	}
+	return result
+}
+
+func optionalUUID(id *uuid.UUID) *string {
+	if id == nil {
+		return nil
+	}
+	s := id.String()
+	return &s
+}
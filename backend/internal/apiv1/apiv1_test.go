@@ -0,0 +1,182 @@
+package apiv1
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/avalarin/livlog/backend/internal/apitime"
+	"github.com/avalarin/livlog/backend/internal/repository"
+	"github.com/google/uuid"
+)
+
+var update = flag.Bool("update", false, "rewrite the golden files in testdata")
+
+// assertGolden compares v, as JSON, with testdata/name.golden.
+func assertGolden(t *testing.T, name string, v interface{}) {
+	t.Helper()
+	got, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		t.Fatalf("marshal: %v", err)
+	}
+	got = append(got, '\n')
+
+	path := filepath.Join("testdata", name+".golden")
+	if *update {
+		if err := os.WriteFile(path, got, 0o644); err != nil {
+			t.Fatal(err)
+		}
+		return
+	}
+	want, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("read golden file (run with -update to create it): %v", err)
+	}
+	if !bytes.Equal(got, want) {
+		t.Errorf("%s differs from %s:\n%s", name, path, got)
+	}
+}
+
+var (
+	createdAt = time.Date(2025, 1, 20, 13, 0, 0, 500000000, time.FixedZone("CET", 60*60))
+	updatedAt = time.Date(2025, 1, 21, 9, 30, 0, 0, time.UTC)
+)
+
+func testEntry() *repository.Entry {
+	collectionID := uuid.MustParse("0b6d8a5e-3c1f-4f5e-9a7b-2c3d4e5f6a7b")
+	return &repository.Entry{
+		ID:               uuid.MustParse("7c9e6679-7425-40de-944b-e07fc1f90ae7"),
+		CollectionID:     &collectionID,
+		Title:            "Dune",
+		OriginalTitle:    "Dune",
+		Language:         "en",
+		Description:      "Spice",
+		Score:            3,
+		Priority:         1,
+		Visibility:       "private",
+		Date:             time.Date(2025, 1, 15, 0, 0, 0, 0, time.UTC),
+		AdditionalFields: map[string]string{"Author": "Frank Herbert"},
+		CreatedAt:        createdAt,
+		UpdatedAt:        updatedAt,
+	}
+}
+
+func testImages() []repository.ImageMeta {
+	return []repository.ImageMeta{
+		{ID: uuid.MustParse("5f0c1c9e-8a43-4b8e-9d7a-1f2b3c4d5e6f"), IsCover: true, Hash: "0cc175b9c0f1b6a831c399e269772661"},
+		{ID: uuid.MustParse("9b2e4c7d-1a3f-4e5d-8c6b-7a9f0e1d2c3b"), Position: 1},
+	}
+}
+
+func TestGolden(t *testing.T) {
+	workspaceID := uuid.MustParse("3fa85f64-5717-4562-b3fc-2c963f66afa6")
+	iconID := uuid.MustParse("c56a4180-65aa-42ec-a945-5fd21dec0538")
+	slug := "k3v9x2"
+	history := []repository.ScoreChange{
+		{Score: 2, RatedAt: time.Date(2021, 3, 4, 20, 15, 0, 0, time.UTC)},
+		{Score: 3, RatedAt: time.Date(2025, 1, 18, 15, 30, 0, 0, time.UTC)},
+	}
+
+	tests := []struct {
+		name string
+		v    interface{}
+	}{
+		{"entry_v1", MapEntry(testEntry(), testImages(), apitime.Legacy)},
+		{"entry_v2", MapEntry(testEntry(), testImages(), apitime.UTC)},
+		{"entry_detail", MapEntryDetail(testEntry(), nil, history, apitime.Legacy)},
+		{"collection", MapCollection(&repository.Collection{
+			ID:             uuid.MustParse("0b6d8a5e-3c1f-4f5e-9a7b-2c3d4e5f6a7b"),
+			Name:           "Books",
+			IconImageID:    &iconID,
+			IconImageHash:  "92eb5ffee6ae2fec3ad71c777531578f",
+			WorkspaceID:    &workspaceID,
+			AllowedTypeIDs: []uuid.UUID{uuid.MustParse("6ba7b810-9dad-11d1-80b4-00c04fd430c8")},
+			ShareSlug:      &slug,
+			EntryCount:     12,
+			CreatedAt:      createdAt,
+			UpdatedAt:      updatedAt,
+		}, apitime.Legacy)},
+		{"collection_minimal", MapCollection(&repository.Collection{
+			ID:        uuid.MustParse("0b6d8a5e-3c1f-4f5e-9a7b-2c3d4e5f6a7b"),
+			Name:      "Movies",
+			Icon:      "🎬",
+			CreatedAt: createdAt,
+			UpdatedAt: updatedAt,
+		}, apitime.Legacy)},
+		{"type", MapType(&repository.EntryType{
+			ID:         uuid.MustParse("6ba7b810-9dad-11d1-80b4-00c04fd430c8"),
+			Name:       "Book",
+			Icon:       "📚",
+			Fields:     []repository.FieldDefinition{{Key: "Author", Label: "Author", Type: "string"}},
+			ScoreScale: repository.ScoreScale{Kind: repository.ScoreScaleStars, Max: 5},
+			CreatedAt:  createdAt,
+			UpdatedAt:  updatedAt,
+		}, apitime.Legacy)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assertGolden(t, tt.name, tt.v)
+		})
+	}
+}
+
+func TestMapEntryDetail(t *testing.T) {
+	// An entry with no recorded scores gets an empty list, not null
+	body, _ := json.Marshal(MapEntryDetail(testEntry(), nil, nil, apitime.Legacy))
+	var got map[string]json.RawMessage
+	if err := json.Unmarshal(body, &got); err != nil {
+		t.Fatalf("unmarshal: %v", err)
+	}
+	if string(got["score_history"]) != "[]" || string(got["images"]) != "[]" {
+		t.Errorf("response = %s", body)
+	}
+}
+
+func TestImageURLs(t *testing.T) {
+	imageID := uuid.MustParse("5f0c1c9e-8a43-4b8e-9d7a-1f2b3c4d5e6f")
+
+	// Without a CDN, URLs are relative to the API base
+	resp := MapEntry(testEntry(), testImages(), apitime.Legacy)
+	if want := "/images/5f0c1c9e-8a43-4b8e-9d7a-1f2b3c4d5e6f?v=0cc175b9c0f1"; resp.Images[0].URL != want {
+		t.Errorf("url = %q, want %q", resp.Images[0].URL, want)
+	}
+
+	UseCDN("https://cdn.example.com/api/v1/")
+	t.Cleanup(func() { UseCDN("") })
+
+	resp = MapEntry(testEntry(), testImages(), apitime.Legacy)
+	if want := "https://cdn.example.com/api/v1/images/5f0c1c9e-8a43-4b8e-9d7a-1f2b3c4d5e6f?v=0cc175b9c0f1"; resp.Images[0].URL != want {
+		t.Errorf("url = %q, want %q", resp.Images[0].URL, want)
+	}
+	if got, want := CollectionIconURL(imageID, ""), "https://cdn.example.com/api/v1/collections/icons/"+imageID.String(); got != want {
+		t.Errorf("icon url = %q, want %q", got, want)
+	}
+}
+
+func TestSelectEntryFields(t *testing.T) {
+	cover := uuid.New()
+	entry := &repository.EntryWithImages{Entry: testEntry(), CoverImageID: &cover}
+
+	got := SelectEntryFields(entry, repository.EntryFields{"id": true, "title": true, "date": true, "cover": true}, apitime.Legacy)
+
+	if len(got) != 4 {
+		t.Errorf("expected 4 fields, got %v", got)
+	}
+	if got["title"] != "Dune" {
+		t.Errorf("expected title Dune, got %v", got["title"])
+	}
+	if got["date"] != "2025-01-15" {
+		t.Errorf("expected date in wire format, got %v", got["date"])
+	}
+	if c, ok := got["cover"].(*string); !ok || *c != cover.String() {
+		t.Errorf("expected cover %s, got %v", cover, got["cover"])
+	}
+	if _, ok := got["description"]; ok {
+		t.Error("expected description to be left out")
+	}
+}
@@ -0,0 +1,43 @@
+package apiv1
+
+import (
+	"github.com/avalarin/livlog/backend/internal/apitime"
+	"github.com/avalarin/livlog/backend/internal/repository"
+)
+
+type Collection struct {
+	ID             string   `json:"id"`
+	Name           string   `json:"name"`
+	Icon           string   `json:"icon"`
+	IconImageID    *string  `json:"icon_image_id,omitempty"`
+	IconURL        *string  `json:"icon_url,omitempty"`
+	WorkspaceID    *string  `json:"workspace_id,omitempty"`
+	AllowedTypeIDs []string `json:"allowed_type_ids"` // empty accepts any type
+	ShareSlug      *string  `json:"share_slug,omitempty"`
+	EntryCount     int      `json:"entry_count"`
+	CreatedAt      string   `json:"created_at"`
+	UpdatedAt      string   `json:"updated_at"`
+}
+
+func MapCollection(c *repository.Collection, layout apitime.Layout) Collection {
+	resp := Collection{
+		ID:             c.ID.String(),
+		Name:           c.Name,
+		Icon:           c.Icon,
+		WorkspaceID:    optionalUUID(c.WorkspaceID),
+		AllowedTypeIDs: uuidStrings(c.AllowedTypeIDs),
+		EntryCount:     c.EntryCount,
+		CreatedAt:      layout.Format(c.CreatedAt),
+		UpdatedAt:      layout.Format(c.UpdatedAt),
+	}
+	if c.ShareSlug != nil {
+		slug := *c.ShareSlug
+		resp.ShareSlug = &slug
+	}
+	if c.IconImageID != nil {
+		url := CollectionIconURL(*c.IconImageID, c.IconImageHash)
+		resp.IconImageID = optionalUUID(c.IconImageID)
+		resp.IconURL = &url
+	}
+	return resp
+}
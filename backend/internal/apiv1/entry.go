@@ -0,0 +1,118 @@
+package apiv1
+
+import (
+	"github.com/avalarin/livlog/backend/internal/apitime"
+	"github.com/avalarin/livlog/backend/internal/repository"
+)
+
+type ImageMeta struct {
+	ID       string `json:"id"`
+	IsCover  bool   `json:"is_cover"`
+	Position int    `json:"position"`
+	URL      string `json:"url"`
+}
+
+type Entry struct {
+	ID               string            `json:"id"`
+	CollectionID     *string           `json:"collection_id,omitempty"`
+	TypeID           *string           `json:"type_id,omitempty"`
+	Title            string            `json:"title"`
+	OriginalTitle    string            `json:"original_title"`
+	Language         string            `json:"language"`
+	Description      string            `json:"description"`
+	Score            int               `json:"score"`
+	Priority         int               `json:"priority"`
+	Visibility       string            `json:"visibility"`
+	Date             string            `json:"date"`
+	AdditionalFields map[string]string `json:"additional_fields"`
+	Images           []ImageMeta       `json:"images"`
+	CreatedAt        string            `json:"created_at"`
+	UpdatedAt        string            `json:"updated_at"`
+}
+
+// EntryDetail is GET /entries/{id}: the entry with every score it has had,
+// oldest first.
+type EntryDetail struct {
+	Entry
+	ScoreHistory []ScoreChange `json:"score_history"`
+}
+
+type ScoreChange struct {
+	Score   int    `json:"score"`
+	RatedAt string `json:"rated_at"`
+}
+
+func MapEntry(e *repository.Entry, imageMetas []repository.ImageMeta, layout apitime.Layout) Entry {
+	images := make([]ImageMeta, len(imageMetas))
+	for i, m := range imageMetas {
+		images[i] = ImageMeta{
+			ID:       m.ID.String(),
+			IsCover:  m.IsCover,
+			Position: m.Position,
+			URL:      EntryImageURL(m.ID, m.Hash),
+		}
+	}
+
+	return Entry{
+		ID:               e.ID.String(),
+		CollectionID:     optionalUUID(e.CollectionID),
+		TypeID:           optionalUUID(e.TypeID),
+		Title:            e.Title,
+		OriginalTitle:    e.OriginalTitle,
+		Language:         e.Language,
+		Description:      e.Description,
+		Score:            e.Score,
+		Priority:         e.Priority,
+		Visibility:       e.Visibility,
+		Date:             e.Date.Format(DateLayout),
+		AdditionalFields: e.AdditionalFields,
+		Images:           images,
+		CreatedAt:        layout.Format(e.CreatedAt),
+		UpdatedAt:        layout.Format(e.UpdatedAt),
+	}
+}
+
+func MapEntryDetail(e *repository.Entry, imageMetas []repository.ImageMeta, history []repository.ScoreChange, layout apitime.Layout) EntryDetail {
+	resp := EntryDetail{
+		Entry:        MapEntry(e, imageMetas, layout),
+		ScoreHistory: make([]ScoreChange, len(history)),
+	}
+	for i, c := range history {
+		resp.ScoreHistory[i] = ScoreChange{
+			Score:   c.Score,
+			RatedAt: layout.Format(c.RatedAt),
+		}
+	}
+	return resp
+}
+
+// SelectEntryFields renders an entry with only the requested fields, in the
+// same format as Entry. "cover" is the cover image id or null.
+func SelectEntryFields(e *repository.EntryWithImages, fields repository.EntryFields, layout apitime.Layout) map[string]interface{} {
+	full := MapEntry(e.Entry, e.Images, layout)
+
+	values := map[string]interface{}{
+		"id":                full.ID,
+		"collection_id":     full.CollectionID,
+		"type_id":           full.TypeID,
+		"title":             full.Title,
+		"original_title":    full.OriginalTitle,
+		"language":          full.Language,
+		"description":       full.Description,
+		"score":             full.Score,
+		"priority":          full.Priority,
+		"visibility":        full.Visibility,
+		"date":              full.Date,
+		"additional_fields": full.AdditionalFields,
+		"images":            full.Images,
+		"cover":             optionalUUID(e.CoverImageID),
+		"created_at":        full.CreatedAt,
+		"updated_at":        full.UpdatedAt,
+	}
+
+	response := make(map[string]interface{}, len(fields))
+	for name := range fields {
+		response[name] = values[name]
+	}
+	return response
+}
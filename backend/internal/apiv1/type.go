@@ -0,0 +1,34 @@
+package apiv1
+
+import (
+	"github.com/avalarin/livlog/backend/internal/apitime"
+	"github.com/avalarin/livlog/backend/internal/repository"
+)
+
+type Type struct {
+	ID          string                       `json:"id"`
+	Name        string                       `json:"name"`
+	Icon        string                       `json:"icon"`
+	WorkspaceID *string                      `json:"workspace_id,omitempty"`
+	Fields      []repository.FieldDefinition `json:"fields"`
+	ScoreScale  repository.ScoreScale        `json:"score_scale"`
+	CreatedAt   string                       `json:"created_at"`
+	UpdatedAt   string                       `json:"updated_at"`
+}
+
+func MapType(t *repository.EntryType, layout apitime.Layout) Type {
+	fields := t.Fields
+	if fields == nil {
+		fields = []repository.FieldDefinition{}
+	}
+	return Type{
+		ID:          t.ID.String(),
+		Name:        t.Name,
+		Icon:        t.Icon,
+		WorkspaceID: optionalUUID(t.WorkspaceID),
+		Fields:      fields,
+		ScoreScale:  t.ScoreScale,
+		CreatedAt:   layout.Format(t.CreatedAt),
+		UpdatedAt:   layout.Format(t.UpdatedAt),
+	}
+}
@@ -0,0 +1,303 @@
+// Package apperror defines the typed errors returned by the HTTP API and the
+// JSON envelope they are rendered into. Every error code maps to exactly one
+// HTTP status, so clients can branch on the stable code alone.
+package apperror
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/go-chi/chi/v5/middleware"
+
+	"github.com/avalarin/livlog/backend/internal/i18n"
+)
+
+// Code is a stable, machine-readable error identifier.
+type Code string
+
+const (
+	CodeBadRequest        Code = "BAD_REQUEST"
+	CodeUnauthorized      Code = "UNAUTHORIZED"
+	CodeForbidden         Code = "FORBIDDEN"
+	CodeNotFound          Code = "NOT_FOUND"
+	CodeConflict          Code = "CONFLICT"
+	CodeValidation        Code = "VALIDATION_ERROR"
+	CodeRateLimitExceeded Code = "RATE_LIMIT_EXCEEDED"
+	CodeInternal          Code = "INTERNAL_ERROR"
+	CodeUnavailable       Code = "SERVICE_UNAVAILABLE"
+	CodePayloadTooLarge   Code = "PAYLOAD_TOO_LARGE"
+	CodeTimeout           Code = "TIMEOUT"
+
+	// Auth
+	CodeInvalidAppleToken       Code = "INVALID_APPLE_TOKEN"
+	CodeInvalidRefreshToken     Code = "INVALID_REFRESH_TOKEN"
+	CodeInvalidEmail            Code = "INVALID_EMAIL"
+	CodeInvalidVerificationCode Code = "INVALID_VERIFICATION_CODE"
+	CodeUserNotFound            Code = "USER_NOT_FOUND"
+	CodeAIConsentRequired       Code = "AI_CONSENT_REQUIRED"
+	CodeAccountPendingDeletion  Code = "ACCOUNT_PENDING_DELETION"
+	// CodeEmailVerificationRequired: the action needs the user's email
+	// verified again, after it changed or lost its verification.
+	CodeEmailVerificationRequired Code = "EMAIL_VERIFICATION_REQUIRED"
+
+	// Content
+	CodeEntryNotFound             Code = "ENTRY_NOT_FOUND"
+	CodeCollectionNotFound        Code = "COLLECTION_NOT_FOUND"
+	CodeTypeNotFound              Code = "TYPE_NOT_FOUND"
+	CodeImageNotFound             Code = "IMAGE_NOT_FOUND"
+	CodeCollectionsAlreadyCreated Code = "COLLECTIONS_ALREADY_CREATED"
+	CodeQuotaExceeded             Code = "QUOTA_EXCEEDED"
+	CodeBookNotFound              Code = "BOOK_NOT_FOUND"
+
+	// Webhooks
+	CodeWebhookNotFound     Code = "WEBHOOK_NOT_FOUND"
+	CodeWebhookLimitReached Code = "WEBHOOK_LIMIT_REACHED"
+
+	// Notifications
+	CodeNotificationNotFound  Code = "NOTIFICATION_NOT_FOUND"
+	CodeChannelNotFound       Code = "CHANNEL_NOT_FOUND"
+	CodeChannelLimitReached   Code = "CHANNEL_LIMIT_REACHED"
+	CodeChannelDeliveryFailed Code = "CHANNEL_DELIVERY_FAILED"
+
+	// Exports
+	CodeExportNotFound Code = "EXPORT_NOT_FOUND"
+	CodeExportNotReady Code = "EXPORT_NOT_READY"
+
+	// Jobs
+	CodeInvalidDownloadLink Code = "INVALID_DOWNLOAD_LINK"
+
+	// Profiles
+	CodeProfileNotFound Code = "PROFILE_NOT_FOUND"
+	CodeHandleTaken     Code = "HANDLE_TAKEN"
+	CodeShareSlugTaken  Code = "SHARE_SLUG_TAKEN"
+
+	// Workspaces
+	CodeWorkspaceNotFound       Code = "WORKSPACE_NOT_FOUND"
+	CodeWorkspaceMemberNotFound Code = "WORKSPACE_MEMBER_NOT_FOUND"
+	CodeInvitationNotFound      Code = "INVITATION_NOT_FOUND"
+	CodeAlreadyWorkspaceMember  Code = "ALREADY_WORKSPACE_MEMBER"
+	CodeWorkspaceFull           Code = "WORKSPACE_FULL"
+)
+
+var statuses = map[Code]int{
+	CodeBadRequest:        http.StatusBadRequest,
+	CodeUnauthorized:      http.StatusUnauthorized,
+	CodeForbidden:         http.StatusForbidden,
+	CodeNotFound:          http.StatusNotFound,
+	CodeConflict:          http.StatusConflict,
+	CodeValidation:        http.StatusUnprocessableEntity,
+	CodeRateLimitExceeded: http.StatusTooManyRequests,
+	CodeInternal:          http.StatusInternalServerError,
+	CodeUnavailable:       http.StatusServiceUnavailable,
+	CodePayloadTooLarge:   http.StatusRequestEntityTooLarge,
+	CodeTimeout:           http.StatusGatewayTimeout,
+
+	CodeInvalidAppleToken:         http.StatusUnauthorized,
+	CodeInvalidRefreshToken:       http.StatusUnauthorized,
+	CodeInvalidEmail:              http.StatusBadRequest,
+	CodeInvalidVerificationCode:   http.StatusUnauthorized,
+	CodeUserNotFound:              http.StatusNotFound,
+	CodeAIConsentRequired:         http.StatusForbidden,
+	CodeAccountPendingDeletion:    http.StatusConflict,
+	CodeEmailVerificationRequired: http.StatusForbidden,
+
+	CodeEntryNotFound:             http.StatusNotFound,
+	CodeCollectionNotFound:        http.StatusNotFound,
+	CodeTypeNotFound:              http.StatusNotFound,
+	CodeImageNotFound:             http.StatusNotFound,
+	CodeCollectionsAlreadyCreated: http.StatusConflict,
+	CodeQuotaExceeded:             http.StatusConflict,
+	CodeBookNotFound:              http.StatusNotFound,
+
+	CodeWebhookNotFound:     http.StatusNotFound,
+	CodeWebhookLimitReached: http.StatusConflict,
+
+	CodeNotificationNotFound:  http.StatusNotFound,
+	CodeChannelNotFound:       http.StatusNotFound,
+	CodeChannelLimitReached:   http.StatusConflict,
+	CodeChannelDeliveryFailed: http.StatusBadGateway,
+
+	CodeExportNotFound: http.StatusNotFound,
+	CodeExportNotReady: http.StatusConflict,
+
+	CodeInvalidDownloadLink: http.StatusUnauthorized,
+
+	CodeProfileNotFound: http.StatusNotFound,
+	CodeHandleTaken:     http.StatusConflict,
+	CodeShareSlugTaken:  http.StatusConflict,
+
+	CodeWorkspaceNotFound:       http.StatusNotFound,
+	CodeWorkspaceMemberNotFound: http.StatusNotFound,
+	CodeInvitationNotFound:      http.StatusNotFound,
+	CodeAlreadyWorkspaceMember:  http.StatusConflict,
+	CodeWorkspaceFull:           http.StatusConflict,
+}
+
+// HTTPStatus returns the HTTP status code for the error code.
+// Unknown codes are treated as internal errors.
+func (c Code) HTTPStatus() int {
+	if status, ok := statuses[c]; ok {
+		return status
+	}
+	return http.StatusInternalServerError
+}
+
+// Error is an API error. Message and Details are sent to the client;
+// the wrapped cause is kept for logging only.
+type Error struct {
+	Code    Code
+	Message string
+	Details map[string]interface{}
+	Err     error
+
+	// RateLimit, set by RateLimited, is rendered as headers by Write.
+	RateLimit *RateLimit
+}
+
+func (e *Error) Error() string {
+	if e.Err != nil {
+		return string(e.Code) + ": " + e.Message + ": " + e.Err.Error()
+	}
+	return string(e.Code) + ": " + e.Message
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// Status returns the HTTP status code for the error.
+func (e *Error) Status() int {
+	return e.Code.HTTPStatus()
+}
+
+// WithDetails attaches client-visible details and returns the same error.
+func (e *Error) WithDetails(details map[string]interface{}) *Error {
+	e.Details = details
+	return e
+}
+
+// New creates an error with the given code and message.
+func New(code Code, message string) *Error {
+	return &Error{Code: code, Message: message}
+}
+
+// Wrap creates an error with the given code and message around a cause.
+func Wrap(err error, code Code, message string) *Error {
+	return &Error{Code: code, Message: message, Err: err}
+}
+
+func BadRequest(message string, err error) *Error {
+	return Wrap(err, CodeBadRequest, message)
+}
+
+func Unauthorized(message string, err error) *Error {
+	return Wrap(err, CodeUnauthorized, message)
+}
+
+func Validation(message string, err error) *Error {
+	return Wrap(err, CodeValidation, message)
+}
+
+func Internal(message string, err error) *Error {
+	return Wrap(err, CodeInternal, message)
+}
+
+// RateLimit is a request budget as reported to clients. A zero Limit leaves
+// out the RateLimit-Limit and RateLimit-Remaining headers.
+type RateLimit struct {
+	Limit     int
+	Remaining int
+	Reset     time.Duration // until the budget is refilled
+}
+
+// ResetSeconds is Reset in whole seconds, rounded up and at least 1.
+func (l RateLimit) ResetSeconds() int {
+	seconds := int((l.Reset + time.Second - 1) / time.Second)
+	if seconds < 1 {
+		return 1
+	}
+	return seconds
+}
+
+// SetHeaders sets the RateLimit-Limit, RateLimit-Remaining and RateLimit-Reset
+// headers.
+func (l RateLimit) SetHeaders(h http.Header) {
+	if l.Limit > 0 {
+		h.Set("RateLimit-Limit", strconv.Itoa(l.Limit))
+		h.Set("RateLimit-Remaining", strconv.Itoa(l.Remaining))
+	}
+	h.Set("RateLimit-Reset", strconv.Itoa(l.ResetSeconds()))
+}
+
+// RateLimited creates a RATE_LIMIT_EXCEEDED error for an exhausted budget.
+// Write adds Retry-After and the RateLimit-* headers, and details carry
+// retry_after (and limit, if known) so every 429 has the same shape.
+func RateLimited(message string, err error, limit RateLimit) *Error {
+	details := map[string]interface{}{"retry_after": limit.ResetSeconds()}
+	if limit.Limit > 0 {
+		details["limit"] = limit.Limit
+	}
+	return &Error{
+		Code:      CodeRateLimitExceeded,
+		Message:   message,
+		Details:   details,
+		Err:       err,
+		RateLimit: &limit,
+	}
+}
+
+// As returns the *Error in err's chain, if any.
+func As(err error) (*Error, bool) {
+	var appErr *Error
+	if errors.As(err, &appErr) {
+		return appErr, true
+	}
+	return nil, false
+}
+
+// Response is the JSON envelope for all error responses.
+type Response struct {
+	Error Body `json:"error"`
+}
+
+type Body struct {
+	Code    Code   `json:"code"`
+	Message string `json:"message"`
+	// LocalizedMessage is a user-facing text for Code in the language
+	// negotiated from Accept-Language; Message is meant for developers.
+	LocalizedMessage string                 `json:"localized_message,omitempty"`
+	Details          map[string]interface{} `json:"details,omitempty"`
+	RequestID        string                 `json:"request_id,omitempty"`
+}
+
+// NewResponse builds the envelope for err as a response to r, with r's
+// request id and a message in the language r accepts.
+func NewResponse(r *http.Request, err *Error) Response {
+	lang := i18n.Negotiate(r.Header.Get("Accept-Language"))
+	return Response{
+		Error: Body{
+			Code:             err.Code,
+			Message:          err.Message,
+			LocalizedMessage: messages.Lookup(lang, string(err.Code)),
+			Details:          err.Details,
+			RequestID:        middleware.GetReqID(r.Context()),
+		},
+	}
+}
+
+// Write renders err as the JSON error envelope with its mapped HTTP status.
+func Write(w http.ResponseWriter, r *http.Request, err *Error) {
+	if err.RateLimit != nil {
+		err.RateLimit.SetHeaders(w.Header())
+		w.Header().Set("Retry-After", strconv.Itoa(err.RateLimit.ResetSeconds()))
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Content-Language", i18n.Negotiate(r.Header.Get("Accept-Language")))
+	w.Header().Add("Vary", "Accept-Language")
+	w.WriteHeader(err.Status())
+
+	_ = json.NewEncoder(w).Encode(NewResponse(r, err))
+}
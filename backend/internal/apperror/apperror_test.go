@@ -0,0 +1,153 @@
+package apperror
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5/middleware"
+
+	"github.com/avalarin/livlog/backend/internal/i18n"
+)
+
+func TestCode_HTTPStatus(t *testing.T) {
+	tests := []struct {
+		code Code
+		want int
+	}{
+		{CodeBadRequest, http.StatusBadRequest},
+		{CodeValidation, http.StatusUnprocessableEntity},
+		{CodeEntryNotFound, http.StatusNotFound},
+		{CodeInvalidRefreshToken, http.StatusUnauthorized},
+		{CodeAIConsentRequired, http.StatusForbidden},
+		{CodeEmailVerificationRequired, http.StatusForbidden},
+		{CodeRateLimitExceeded, http.StatusTooManyRequests},
+		{Code("SOMETHING_NEW"), http.StatusInternalServerError},
+	}
+
+	for _, tt := range tests {
+		if got := tt.code.HTTPStatus(); got != tt.want {
+			t.Errorf("%s: expected status %d, got %d", tt.code, tt.want, got)
+		}
+	}
+}
+
+func TestAs_Unwrap(t *testing.T) {
+	cause := errors.New("boom")
+	err := fmt.Errorf("handler: %w", Internal("Failed", cause))
+
+	appErr, ok := As(err)
+	if !ok {
+		t.Fatal("expected *Error in chain")
+	}
+	if appErr.Code != CodeInternal {
+		t.Errorf("expected code %s, got %s", CodeInternal, appErr.Code)
+	}
+	if !errors.Is(err, cause) {
+		t.Error("expected cause to be reachable via errors.Is")
+	}
+}
+
+func TestWrite(t *testing.T) {
+	rec := httptest.NewRecorder()
+	err := Wrap(errors.New("internal detail"), CodeRateLimitExceeded, "Slow down").
+		WithDetails(map[string]interface{}{"retry_after": 60})
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req = req.WithContext(context.WithValue(req.Context(), middleware.RequestIDKey, "req-1"))
+
+	Write(rec, req, err)
+
+	if rec.Code != http.StatusTooManyRequests {
+		t.Errorf("expected status 429, got %d", rec.Code)
+	}
+	if ct := rec.Header().Get("Content-Type"); ct != "application/json" {
+		t.Errorf("expected application/json, got %s", ct)
+	}
+
+	var resp Response
+	if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+		t.Fatalf("failed to decode body: %v", err)
+	}
+	if resp.Error.Code != CodeRateLimitExceeded || resp.Error.Message != "Slow down" || resp.Error.RequestID != "req-1" {
+		t.Errorf("unexpected envelope: %+v", resp.Error)
+	}
+	if resp.Error.Details["retry_after"] != float64(60) {
+		t.Errorf("expected retry_after 60, got %v", resp.Error.Details["retry_after"])
+	}
+}
+
+func TestWrite_RateLimited(t *testing.T) {
+	rec := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodPost, "/auth/email/resend-code", nil)
+
+	Write(rec, req, RateLimited("Slow down", nil, RateLimit{Limit: 1, Reset: 41500 * time.Millisecond}))
+
+	if rec.Code != http.StatusTooManyRequests {
+		t.Errorf("expected status 429, got %d", rec.Code)
+	}
+	for header, want := range map[string]string{
+		"Retry-After":         "42",
+		"RateLimit-Reset":     "42",
+		"RateLimit-Limit":     "1",
+		"RateLimit-Remaining": "0",
+	} {
+		if got := rec.Header().Get(header); got != want {
+			t.Errorf("%s = %q, want %q", header, got, want)
+		}
+	}
+
+	var resp Response
+	if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+		t.Fatalf("failed to decode body: %v", err)
+	}
+	if resp.Error.Details["retry_after"] != float64(42) || resp.Error.Details["limit"] != float64(1) {
+		t.Errorf("unexpected details: %v", resp.Error.Details)
+	}
+}
+
+func TestRateLimit_ResetSeconds(t *testing.T) {
+	for reset, want := range map[time.Duration]int{0: 1, time.Second: 1, 1001 * time.Millisecond: 2, 24 * time.Hour: 86400} {
+		if got := (RateLimit{Reset: reset}).ResetSeconds(); got != want {
+			t.Errorf("ResetSeconds(%v) = %d, want %d", reset, got, want)
+		}
+	}
+}
+
+func TestWrite_LocalizedMessage(t *testing.T) {
+	rec := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set("Accept-Language", "ru-RU,ru;q=0.9,en;q=0.8")
+
+	Write(rec, req, New(CodeEntryNotFound, "Entry not found"))
+
+	if lang := rec.Header().Get("Content-Language"); lang != "ru" {
+		t.Errorf("expected Content-Language ru, got %q", lang)
+	}
+
+	var resp Response
+	if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+		t.Fatalf("failed to decode body: %v", err)
+	}
+	if resp.Error.Message != "Entry not found" {
+		t.Errorf("expected developer message to be kept, got %q", resp.Error.Message)
+	}
+	if want := messages[i18n.Russian][string(CodeEntryNotFound)]; resp.Error.LocalizedMessage != want {
+		t.Errorf("expected localized message %q, got %q", want, resp.Error.LocalizedMessage)
+	}
+}
+
+func TestMessages_CoverAllCodes(t *testing.T) {
+	for code := range statuses {
+		for _, lang := range []string{i18n.English, i18n.Russian} {
+			if _, ok := messages[lang][string(code)]; !ok {
+				t.Errorf("%s has no %s message", code, lang)
+			}
+		}
+	}
+}
@@ -0,0 +1,114 @@
+package apperror
+
+import "github.com/avalarin/livlog/backend/internal/i18n"
+
+// messages are the user-facing texts sent as localized_message, one per code.
+// Every code needs at least an English text; see TestMessages_CoverAllCodes.
+var messages = i18n.Catalog{
+	i18n.English: {
+		string(CodeBadRequest):        "The request could not be processed.",
+		string(CodeUnauthorized):      "Please sign in again.",
+		string(CodeForbidden):         "You don't have access to this.",
+		string(CodeNotFound):          "Nothing was found.",
+		string(CodeConflict):          "This conflicts with existing data.",
+		string(CodeValidation):        "Some fields are invalid. Please check them and try again.",
+		string(CodeRateLimitExceeded): "Too many attempts. Please try again later.",
+		string(CodeInternal):          "Something went wrong on our side. Please try again.",
+		string(CodeUnavailable):       "This feature is temporarily unavailable.",
+		string(CodePayloadTooLarge):   "The upload is too large.",
+		string(CodeTimeout):           "The server took too long to respond. Please try again.",
+
+		string(CodeInvalidAppleToken):         "Sign in with Apple failed. Please try again.",
+		string(CodeInvalidRefreshToken):       "Your session has expired. Please sign in again.",
+		string(CodeInvalidEmail):              "Please enter a valid email address.",
+		string(CodeInvalidVerificationCode):   "The code is invalid or has expired.",
+		string(CodeUserNotFound):              "The account was not found.",
+		string(CodeAIConsentRequired):         "Allow AI features to send your data to our AI provider to use this.",
+		string(CodeAccountPendingDeletion):    "This account was deleted and is being removed. You can sign up again once it's gone.",
+		string(CodeEmailVerificationRequired): "Please confirm your email address first. We'll send you a code.",
+
+		string(CodeEntryNotFound):             "The entry was not found. It may have been deleted.",
+		string(CodeCollectionNotFound):        "The collection was not found. It may have been deleted.",
+		string(CodeTypeNotFound):              "The entry type was not found.",
+		string(CodeImageNotFound):             "The image was not found.",
+		string(CodeCollectionsAlreadyCreated): "Your collections have already been created.",
+		string(CodeQuotaExceeded):             "You have reached your storage limit.",
+		string(CodeBookNotFound):              "We couldn't find a book with this barcode.",
+
+		string(CodeWebhookNotFound):     "The webhook was not found.",
+		string(CodeWebhookLimitReached): "You have reached the maximum number of webhooks.",
+
+		string(CodeNotificationNotFound):  "The notification was not found.",
+		string(CodeChannelNotFound):       "The notification channel was not found.",
+		string(CodeChannelLimitReached):   "You have reached the maximum number of notification channels.",
+		string(CodeChannelDeliveryFailed): "The message could not be delivered. Check the channel settings.",
+
+		string(CodeExportNotFound): "The export was not found. It may have expired.",
+		string(CodeExportNotReady): "The export is not ready yet.",
+
+		string(CodeInvalidDownloadLink): "The download link is invalid or has expired. Open the job again for a new one.",
+
+		string(CodeProfileNotFound): "The profile was not found.",
+		string(CodeHandleTaken):     "This handle is already taken.",
+		string(CodeShareSlugTaken):  "This link is already taken. Try another one.",
+
+		string(CodeWorkspaceNotFound):       "The workspace was not found. You may have left it, or it was deleted.",
+		string(CodeWorkspaceMemberNotFound): "This person is not a member of the workspace.",
+		string(CodeInvitationNotFound):      "The invitation is invalid or has expired. Ask for a new one.",
+		string(CodeAlreadyWorkspaceMember):  "You are already a member of this workspace.",
+		string(CodeWorkspaceFull):           "The workspace has reached its member limit.",
+	},
+	i18n.Russian: {
+		string(CodeBadRequest):        "Не удалось обработать запрос.",
+		string(CodeUnauthorized):      "Пожалуйста, войдите снова.",
+		string(CodeForbidden):         "У вас нет доступа.",
+		string(CodeNotFound):          "Ничего не найдено.",
+		string(CodeConflict):          "Конфликт с существующими данными.",
+		string(CodeValidation):        "Некоторые поля заполнены неверно. Проверьте их и попробуйте снова.",
+		string(CodeRateLimitExceeded): "Слишком много попыток. Попробуйте позже.",
+		string(CodeInternal):          "Что-то пошло не так. Попробуйте снова.",
+		string(CodeUnavailable):       "Функция временно недоступна.",
+		string(CodePayloadTooLarge):   "Слишком большой файл.",
+		string(CodeTimeout):           "Сервер слишком долго не отвечал. Попробуйте снова.",
+
+		string(CodeInvalidAppleToken):         "Не удалось войти через Apple. Попробуйте снова.",
+		string(CodeInvalidRefreshToken):       "Сессия истекла. Пожалуйста, войдите снова.",
+		string(CodeInvalidEmail):              "Введите корректный адрес электронной почты.",
+		string(CodeInvalidVerificationCode):   "Код неверный или устарел.",
+		string(CodeUserNotFound):              "Аккаунт не найден.",
+		string(CodeAIConsentRequired):         "Чтобы пользоваться этой функцией, разрешите передавать ваши данные нашему поставщику ИИ.",
+		string(CodeAccountPendingDeletion):    "Этот аккаунт удалён и скоро будет стёрт. После этого вы сможете зарегистрироваться снова.",
+		string(CodeEmailVerificationRequired): "Сначала подтвердите адрес электронной почты. Мы отправим вам код.",
+
+		string(CodeEntryNotFound):             "Запись не найдена. Возможно, она была удалена.",
+		string(CodeCollectionNotFound):        "Коллекция не найдена. Возможно, она была удалена.",
+		string(CodeTypeNotFound):              "Тип записи не найден.",
+		string(CodeImageNotFound):             "Изображение не найдено.",
+		string(CodeCollectionsAlreadyCreated): "Ваши коллекции уже созданы.",
+		string(CodeQuotaExceeded):             "Достигнут лимит хранилища.",
+		string(CodeBookNotFound):              "Не удалось найти книгу по этому штрихкоду.",
+
+		string(CodeWebhookNotFound):     "Вебхук не найден.",
+		string(CodeWebhookLimitReached): "Достигнуто максимальное количество вебхуков.",
+
+		string(CodeNotificationNotFound):  "Уведомление не найдено.",
+		string(CodeChannelNotFound):       "Канал уведомлений не найден.",
+		string(CodeChannelLimitReached):   "Достигнуто максимальное число каналов уведомлений.",
+		string(CodeChannelDeliveryFailed): "Не удалось доставить сообщение. Проверьте настройки канала.",
+
+		string(CodeExportNotFound): "Экспорт не найден. Возможно, срок его хранения истёк.",
+		string(CodeExportNotReady): "Экспорт ещё не готов.",
+
+		string(CodeInvalidDownloadLink): "Ссылка для скачивания недействительна или устарела. Откройте задачу снова, чтобы получить новую.",
+
+		string(CodeProfileNotFound): "Профиль не найден.",
+		string(CodeHandleTaken):     "Это имя пользователя уже занято.",
+		string(CodeShareSlugTaken):  "Эта ссылка уже занята. Попробуйте другую.",
+
+		string(CodeWorkspaceNotFound):       "Пространство не найдено. Возможно, вы его покинули или оно было удалено.",
+		string(CodeWorkspaceMemberNotFound): "Этот человек не участник пространства.",
+		string(CodeInvitationNotFound):      "Приглашение недействительно или устарело. Попросите новое.",
+		string(CodeAlreadyWorkspaceMember):  "Вы уже участник этого пространства.",
+		string(CodeWorkspaceFull):           "В пространстве уже максимальное число участников.",
+	},
+}
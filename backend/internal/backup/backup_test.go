@@ -0,0 +1,130 @@
+package backup
+
+import (
+	"context"
+	"encoding/hex"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestDirStore(t *testing.T) {
+	ctx := context.Background()
+	store := NewDirStore(t.TempDir())
+
+	data := "archive"
+	if err := store.Put(ctx, "livlog.dump", strings.NewReader(data), int64(len(data)), hexSHA256([]byte(data))); err != nil {
+		t.Fatalf("Put: %v", err)
+	}
+
+	size, err := store.Stat(ctx, "livlog.dump")
+	if err != nil || size != int64(len(data)) {
+		t.Fatalf("Stat = %d, %v; want %d", size, err, len(data))
+	}
+
+	if err := store.Delete(ctx, "livlog.dump"); err != nil {
+		t.Fatalf("Delete: %v", err)
+	}
+	if _, err := store.Stat(ctx, "livlog.dump"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Stat after Delete = %v, want ErrNotFound", err)
+	}
+	if err := store.Delete(ctx, "livlog.dump"); err != nil {
+		t.Errorf("Delete of a missing archive: %v", err)
+	}
+}
+
+func TestDirStore_ShortBody(t *testing.T) {
+	store := NewDirStore(t.TempDir())
+
+	err := store.Put(context.Background(), "livlog.dump", strings.NewReader("abc"), 10, "")
+	if err == nil {
+		t.Fatal("expected an error for a truncated body")
+	}
+	if _, err := store.Stat(context.Background(), "livlog.dump"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("a truncated archive was stored: %v", err)
+	}
+}
+
+func TestS3Store(t *testing.T) {
+	objects := map[string]string{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		auth := r.Header.Get("Authorization")
+		if !strings.HasPrefix(auth, "AWS4-HMAC-SHA256 Credential=AKID/20261016/eu-central-1/s3/aws4_request, SignedHeaders=host;x-amz-content-sha256;x-amz-date, Signature=") {
+			t.Errorf("unexpected Authorization %q", auth)
+		}
+		if got := r.Header.Get("X-Amz-Date"); got != "20261016T020000Z" {
+			t.Errorf("X-Amz-Date = %q", got)
+		}
+
+		switch r.Method {
+		case http.MethodPut:
+			body, _ := io.ReadAll(r.Body)
+			if got := r.Header.Get("X-Amz-Content-Sha256"); got != hexSHA256(body) {
+				t.Errorf("X-Amz-Content-Sha256 = %q", got)
+			}
+			objects[r.URL.Path] = string(body)
+		case http.MethodHead:
+			body, ok := objects[r.URL.Path]
+			if !ok {
+				w.WriteHeader(http.StatusNotFound)
+				return
+			}
+			w.Header().Set("Content-Length", strconv.Itoa(len(body)))
+		case http.MethodDelete:
+			delete(objects, r.URL.Path)
+			w.WriteHeader(http.StatusNoContent)
+		}
+	}))
+	defer server.Close()
+
+	store := NewS3Store(S3Options{
+		Endpoint:  server.URL + "/",
+		Region:    "eu-central-1",
+		Bucket:    "livlog",
+		Prefix:    "backups/",
+		AccessKey: "AKID",
+		SecretKey: "secret",
+	})
+	store.now = func() time.Time { return time.Date(2026, 10, 16, 2, 0, 0, 0, time.UTC) }
+
+	ctx := context.Background()
+	data := "archive"
+	if err := store.Put(ctx, "livlog.dump", strings.NewReader(data), int64(len(data)), hexSHA256([]byte(data))); err != nil {
+		t.Fatalf("Put: %v", err)
+	}
+	if _, ok := objects["/livlog/backups/livlog.dump"]; !ok {
+		t.Fatalf("object stored at unexpected path: %v", objects)
+	}
+
+	size, err := store.Stat(ctx, "livlog.dump")
+	if err != nil || size != int64(len(data)) {
+		t.Fatalf("Stat = %d, %v; want %d", size, err, len(data))
+	}
+
+	if err := store.Delete(ctx, "livlog.dump"); err != nil {
+		t.Fatalf("Delete: %v", err)
+	}
+	if _, err := store.Stat(ctx, "livlog.dump"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Stat after Delete = %v, want ErrNotFound", err)
+	}
+}
+
+func TestSigningKey(t *testing.T) {
+	// Example from the AWS Signature Version 4 documentation
+	key := signingKey("wJalrXUtnFEMI/K7MDENG+bPxRfiCYEXAMPLEKEY", "20120215", "us-east-1", "iam")
+	want := "f4780e2d9f65fa895f9c67b32ce1baf0b0d8a43505a000a1a9e090d414db404d"
+	if got := hex.EncodeToString(key); got != want {
+		t.Errorf("signingKey = %s, want %s", got, want)
+	}
+}
+
+func TestURIEncodePath(t *testing.T) {
+	if got := uriEncodePath("backups/livlog 2026+x.dump"); got != "backups/livlog%202026%2Bx.dump" {
+		t.Errorf("uriEncodePath = %q", got)
+	}
+}
@@ -0,0 +1,73 @@
+package backup
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+)
+
+// DirStore keeps archives as files in a directory, e.g. a mounted volume.
+type DirStore struct {
+	dir string
+}
+
+func NewDirStore(dir string) *DirStore {
+	return &DirStore{dir: dir}
+}
+
+func (s *DirStore) path(key string) string {
+	return filepath.Join(s.dir, filepath.Base(key))
+}
+
+// Put writes the archive to a temporary file first, so a failed backup never
+// leaves a truncated archive under key.
+func (s *DirStore) Put(ctx context.Context, key string, body io.Reader, size int64, sha256 string) error {
+	if err := os.MkdirAll(s.dir, 0o750); err != nil {
+		return fmt.Errorf("failed to create backup directory: %w", err)
+	}
+
+	tmp, err := os.CreateTemp(s.dir, ".tmp-*")
+	if err != nil {
+		return fmt.Errorf("failed to create backup file: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	written, err := io.Copy(tmp, body)
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return fmt.Errorf("failed to write backup file: %w", err)
+	}
+	if written != size {
+		return fmt.Errorf("wrote %d bytes of a %d byte backup", written, size)
+	}
+
+	if err := os.Rename(tmp.Name(), s.path(key)); err != nil {
+		return fmt.Errorf("failed to store backup file: %w", err)
+	}
+	return nil
+}
+
+func (s *DirStore) Stat(ctx context.Context, key string) (int64, error) {
+	info, err := os.Stat(s.path(key))
+	if errors.Is(err, fs.ErrNotExist) {
+		return 0, ErrNotFound
+	}
+	if err != nil {
+		return 0, fmt.Errorf("failed to stat backup file: %w", err)
+	}
+	return info.Size(), nil
+}
+
+func (s *DirStore) Delete(ctx context.Context, key string) error {
+	err := os.Remove(s.path(key))
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return fmt.Errorf("failed to delete backup file: %w", err)
+	}
+	return nil
+}
@@ -2,7 +2,9 @@ package config
 
 import (
 	"fmt"
+	"os"
 	"strings"
+	"time"
 
 	"github.com/spf13/viper"
 )
@@ -13,8 +15,28 @@ type Config struct {
 	Logging    LoggingConfig    `mapstructure:"logging"`
 	JWT        JWTConfig        `mapstructure:"jwt"`
 	Apple      AppleConfig      `mapstructure:"apple"`
+	Auth       AuthConfig       `mapstructure:"auth"`
 	OpenRouter OpenRouterConfig `mapstructure:"openrouter"`
 	RateLimit  RateLimitConfig  `mapstructure:"ratelimit"`
+	Tracing    TracingConfig    `mapstructure:"tracing"`
+	Debug      DebugConfig      `mapstructure:"debug"`
+	GRPC       GRPCConfig       `mapstructure:"grpc"`
+	CORS       CORSConfig       `mapstructure:"cors"`
+	Limits     LimitsConfig     `mapstructure:"limits"`
+	API        APIConfig        `mapstructure:"api"`
+
+	ErrorTracking ErrorTrackingConfig `mapstructure:"errortracking"`
+	Metrics       MetricsConfig       `mapstructure:"metrics"`
+	Quotas        QuotasConfig        `mapstructure:"quotas"`
+	Entries       EntriesConfig       `mapstructure:"entries"`
+	Retention     RetentionConfig     `mapstructure:"retention"`
+	Books         BooksConfig         `mapstructure:"books"`
+	Channels      ChannelsConfig      `mapstructure:"channels"`
+	Mail          MailConfig          `mapstructure:"mail"`
+	Workspaces    WorkspacesConfig    `mapstructure:"workspaces"`
+	Backup        BackupConfig        `mapstructure:"backup"`
+	CDN           CDNConfig           `mapstructure:"cdn"`
+	Encryption    EncryptionConfig    `mapstructure:"encryption"`
 }
 
 type ServerConfig struct {
@@ -23,16 +45,44 @@ type ServerConfig struct {
 }
 
 type DatabaseConfig struct {
+	Driver   string `mapstructure:"driver"` // only "postgres" is supported, see docs/database.md
 	Host     string `mapstructure:"host"`
 	Port     int    `mapstructure:"port"`
 	Name     string `mapstructure:"name"`
 	User     string `mapstructure:"user"`
 	Password string `mapstructure:"password"`
 	SSLMode  string `mapstructure:"sslmode"`
+
+	MaxConns          int32         `mapstructure:"max_conns"`
+	MinConns          int32         `mapstructure:"min_conns"`
+	MaxConnLifetime   time.Duration `mapstructure:"max_conn_lifetime"`
+	MaxConnIdleTime   time.Duration `mapstructure:"max_conn_idle_time"`
+	HealthCheckPeriod time.Duration `mapstructure:"health_check_period"`
+	StatementTimeout  time.Duration `mapstructure:"statement_timeout"` // 0 disables it
+	// ConnectTimeout is how long startup keeps retrying while the database is unreachable
+	ConnectTimeout time.Duration `mapstructure:"connect_timeout"`
+	// TrigramSearch routes entry search through the pg_trgm index, for large libraries
+	TrigramSearch bool `mapstructure:"trigram_search"`
+	// Replicas are DSNs of streaming replicas that serve listings, search,
+	// stats and sync pulls; empty reads everything from the primary
+	Replicas []string `mapstructure:"replicas"`
+	// MaxReplicaLag is how far a replica may fall behind before reads go back to the primary
+	MaxReplicaLag time.Duration `mapstructure:"max_replica_lag"`
 }
 
 type LoggingConfig struct {
-	Format string `mapstructure:"format"` // "json" or "console"
+	Format   string               `mapstructure:"format"` // "json" or "console"
+	Level    string               `mapstructure:"level"`  // "debug", "info", "warn" or "error"; empty uses the format's default
+	Payloads PayloadLoggingConfig `mapstructure:"payloads"`
+}
+
+// PayloadLoggingConfig turns on logging of request and response bodies for
+// diagnosing client payloads. Routes are "[METHOD ]PATH" patterns matched
+// with path.Match against the full path, e.g. "POST /api/*/entries"; bodies
+// are cut at MaxBytes and secrets are redacted. Off while Routes is empty.
+type PayloadLoggingConfig struct {
+	Routes   []string `mapstructure:"routes"`
+	MaxBytes int      `mapstructure:"max_bytes"`
 }
 
 type JWTConfig struct {
@@ -48,10 +98,85 @@ type AppleConfig struct {
 	BundleID string `mapstructure:"bundle_id"`
 }
 
+// AuthConfig controls email sign-in. Addresses are always trimmed and
+// lowercased; StripEmailPlusTags also treats user+tag@x.com as user@x.com.
+// IntrospectionToken is the bearer credential internal services present to
+// POST /auth/introspect; the endpoint is disabled while it is empty.
+type AuthConfig struct {
+	StripEmailPlusTags bool   `mapstructure:"strip_email_plus_tags"`
+	IntrospectionToken string `mapstructure:"introspection_token"`
+}
+
 type OpenRouterConfig struct {
 	APIKey  string `mapstructure:"api_key"`
 	BaseURL string `mapstructure:"base_url"`
 	Model   string `mapstructure:"model"`
+
+	Routing AIRoutingConfig `mapstructure:"routing"`
+	Budget  AIBudgetConfig  `mapstructure:"budget"`
+}
+
+// AIRoutingConfig sends AI searches to other models than Model. A type hint
+// from the client picks TypeModels[type]; otherwise short title-like queries
+// go to SimpleModel and descriptive ones to AmbiguousModel. Empty values fall
+// back to Model. When a model fails, FallbackModels are tried in order, then
+// Model.
+type AIRoutingConfig struct {
+	TypeModels     map[string]string `mapstructure:"type_models"`
+	SimpleModel    string            `mapstructure:"simple_model"`
+	AmbiguousModel string            `mapstructure:"ambiguous_model"`
+	FallbackModels []string          `mapstructure:"fallback_models"`
+}
+
+// AIBudgetConfig caps what AI search sends to OpenRouter across all users
+// per UTC day, against runaway provider bills. Once either limit is reached,
+// searches are answered from metadata providers (book lookups) only and
+// admins are notified. Zero disables a limit.
+type AIBudgetConfig struct {
+	DailyRequests int     `mapstructure:"daily_requests"`
+	DailySpendUSD float64 `mapstructure:"daily_spend_usd"`
+}
+
+// Exceeded reports whether the requests and spend so far reach a limit.
+func (b AIBudgetConfig) Exceeded(requests int64, spendUSD float64) bool {
+	return (b.DailyRequests > 0 && requests >= int64(b.DailyRequests)) ||
+		(b.DailySpendUSD > 0 && spendUSD >= b.DailySpendUSD)
+}
+
+// BooksConfig points book lookups (ISBN scans) at an Open Library compatible
+// API. An empty BaseURL disables them.
+type BooksConfig struct {
+	BaseURL string        `mapstructure:"base_url"`
+	Timeout time.Duration `mapstructure:"timeout"`
+}
+
+// ChannelsConfig sets up the external notification channels users can
+// connect. Telegram needs a bot token (from @BotFather) and is disabled
+// without one; Discord channels are user-supplied webhooks and need no setup.
+type ChannelsConfig struct {
+	TelegramBotToken string        `mapstructure:"telegram_bot_token"`
+	TelegramBaseURL  string        `mapstructure:"telegram_base_url"`
+	Timeout          time.Duration `mapstructure:"timeout"`
+}
+
+// MailConfig points transactional email (verification codes) at an SMTP
+// server. STARTTLS is used when the server offers it. An empty Host disables
+// sending, and with it re-verifying a signed-in user's email.
+type MailConfig struct {
+	Host     string        `mapstructure:"host"`
+	Port     int           `mapstructure:"port"`
+	Username string        `mapstructure:"username"` // empty skips authentication
+	Password string        `mapstructure:"password"`
+	From     string        `mapstructure:"from"` // e.g. "Livlog <no-reply@livlog.app>"
+	Timeout  time.Duration `mapstructure:"timeout"`
+	// AppleRelay declares the From domain registered with Apple for Email
+	// Communication; Apple's private relay drops mail from other domains.
+	AppleRelay bool `mapstructure:"apple_relay"`
+}
+
+// Enabled reports whether a mail server is configured.
+func (c MailConfig) Enabled() bool {
+	return c.Host != ""
 }
 
 type RateLimitConfig struct {
@@ -59,6 +184,176 @@ type RateLimitConfig struct {
 	AISearchProLimit       int    `mapstructure:"ai_search_pro_limit"`
 	AISearchUnlimitedLimit int    `mapstructure:"ai_search_unlimited_limit"` // 0 means no limit
 	AISearchPeriod         string `mapstructure:"ai_search_period"`
+
+	// Per-user budgets for expensive routes
+	Search RouteLimit `mapstructure:"search"` // GET /entries/search
+	Bulk   RouteLimit `mapstructure:"bulk"`   // DELETE /entries, POST /entries/bulk-score
+}
+
+// RouteLimit allows each user Requests per Period. Zero Requests disables it.
+type RouteLimit struct {
+	Requests int           `mapstructure:"requests"`
+	Period   time.Duration `mapstructure:"period"`
+}
+
+type TracingConfig struct {
+	Enabled     bool    `mapstructure:"enabled"`
+	Endpoint    string  `mapstructure:"endpoint"` // OTLP/HTTP collector host:port
+	Insecure    bool    `mapstructure:"insecure"` // use plain HTTP instead of HTTPS
+	ServiceName string  `mapstructure:"service_name"`
+	SampleRatio float64 `mapstructure:"sample_ratio"` // 0.0 - 1.0
+}
+
+// DebugConfig controls the internal listener serving pprof and expvar.
+// Keep it bound to a private interface; it has no authentication.
+type DebugConfig struct {
+	Enabled bool   `mapstructure:"enabled"`
+	Host    string `mapstructure:"host"`
+	Port    int    `mapstructure:"port"`
+}
+
+// GRPCConfig controls the gRPC listener that serves the API alongside REST.
+type GRPCConfig struct {
+	Enabled bool   `mapstructure:"enabled"`
+	Host    string `mapstructure:"host"`
+	Port    int    `mapstructure:"port"`
+}
+
+// QuotasConfig caps what each user can store, enforced when records are
+// created or updated. Zero means unlimited.
+type QuotasConfig struct {
+	MaxEntries        int `mapstructure:"max_entries"`
+	MaxCollections    int `mapstructure:"max_collections"`
+	MaxImagesPerEntry int `mapstructure:"max_images_per_entry"`
+}
+
+// EntriesConfig tunes entry writes. An update less than
+// ConcurrentUpdateWindow after a write from another of the user's devices is
+// flagged as concurrent; 0 turns the hint off.
+type EntriesConfig struct {
+	ConcurrentUpdateWindow time.Duration `mapstructure:"concurrent_update_window"`
+}
+
+// RetentionConfig controls the purge job that hard-deletes accounts some time
+// after the user deleted them, together with everything they own.
+type RetentionConfig struct {
+	DeletedUsers  time.Duration `mapstructure:"deleted_users"` // grace period; 0 disables purging
+	PurgeInterval time.Duration `mapstructure:"purge_interval"`
+	BatchSize     int           `mapstructure:"batch_size"` // accounts per run
+	DryRun        bool          `mapstructure:"dry_run"`    // log what would be purged without deleting
+}
+
+// MetricsConfig controls access to the Prometheus /metrics endpoint. With a
+// Port it is served on its own listener instead of the public one. Either way
+// it can require basic auth and a client address allowlist.
+type MetricsConfig struct {
+	Host       string   `mapstructure:"host"`
+	Port       int      `mapstructure:"port"` // 0 serves /metrics on the public listener
+	Username   string   `mapstructure:"username"`
+	Password   string   `mapstructure:"password"`    // basic auth is required when set
+	AllowedIPs []string `mapstructure:"allowed_ips"` // IPs or CIDRs; empty allows any address
+}
+
+// CORSConfig controls which browser origins may call the API. With no allowed
+// origins, browsers are blocked from cross-origin requests.
+type CORSConfig struct {
+	AllowedOrigins   []string `mapstructure:"allowed_origins"` // exact origins like "https://app.example.com", or "*"
+	AllowedHeaders   []string `mapstructure:"allowed_headers"`
+	AllowCredentials bool     `mapstructure:"allow_credentials"`
+	MaxAge           int      `mapstructure:"max_age"` // seconds browsers may cache a preflight response
+}
+
+// LimitsConfig bounds request body sizes and handler run time. Routes that
+// accept base64 images (entries, sync push) get the upload limits.
+type LimitsConfig struct {
+	MaxBodyBytes       int64         `mapstructure:"max_body_bytes"`
+	MaxUploadBodyBytes int64         `mapstructure:"max_upload_body_bytes"`
+	RequestTimeout     time.Duration `mapstructure:"request_timeout"`
+	UploadTimeout      time.Duration `mapstructure:"upload_timeout"`
+	AISearchTimeout    time.Duration `mapstructure:"ai_search_timeout"`
+}
+
+// APIConfig controls list responses: without ?limit= a list returns
+// DefaultPageSize items, and never more than MaxPageSize.
+type APIConfig struct {
+	DefaultPageSize int `mapstructure:"default_page_size"`
+	MaxPageSize     int `mapstructure:"max_page_size"`
+}
+
+// WorkspacesConfig controls shared workspaces. While disabled the workspace
+// routes are not mounted and every collection stays personal.
+type WorkspacesConfig struct {
+	Enabled       bool          `mapstructure:"enabled"`
+	MaxMembers    int           `mapstructure:"max_members"` // including the owner
+	InvitationTTL time.Duration `mapstructure:"invitation_ttl"`
+}
+
+// BackupConfig controls database backups: pg_dump archives written to a
+// directory or an S3-compatible bucket, taken every Interval and on demand
+// from POST /admin/backups. Backups older than Retention are deleted, except
+// the latest successful one. Without a Dir or S3Bucket backups are disabled.
+type BackupConfig struct {
+	Interval   time.Duration `mapstructure:"interval"`  // 0 disables scheduled backups
+	Retention  time.Duration `mapstructure:"retention"` // 0 keeps backups forever
+	PGDumpPath string        `mapstructure:"pg_dump_path"`
+	Timeout    time.Duration `mapstructure:"timeout"` // per backup
+
+	Dir string `mapstructure:"dir"` // local or mounted directory
+
+	S3Endpoint  string `mapstructure:"s3_endpoint"` // e.g. https://s3.eu-central-1.amazonaws.com
+	S3Region    string `mapstructure:"s3_region"`
+	S3Bucket    string `mapstructure:"s3_bucket"`
+	S3Prefix    string `mapstructure:"s3_prefix"`
+	S3AccessKey string `mapstructure:"s3_access_key"`
+	S3SecretKey string `mapstructure:"s3_secret_key"`
+}
+
+// Enabled reports whether a backup destination is configured.
+func (b *BackupConfig) Enabled() bool {
+	return b.Dir != "" || b.S3Bucket != ""
+}
+
+// CDNConfig points image URLs in API responses at a CDN that pulls from this
+// server, instead of at the server itself. When OriginAuthSecret is set the
+// image routes only answer requests carrying it in OriginAuthHeader, so
+// clients can't bypass the CDN.
+type CDNConfig struct {
+	BaseURL          string `mapstructure:"base_url"` // e.g. https://cdn.livlog.app/api/v1
+	OriginAuthHeader string `mapstructure:"origin_auth_header"`
+	OriginAuthSecret string `mapstructure:"origin_auth_secret"`
+}
+
+// Enabled reports whether a CDN base URL is configured.
+func (c *CDNConfig) Enabled() bool {
+	return c.BaseURL != ""
+}
+
+// EncryptionConfig turns on application-level encryption of users' emails,
+// email sign-in identities and device info. Key is the base64 key-encryption
+// key (32 bytes) that wraps the data keys stored in the database; keys it
+// replaced go in PreviousKeys until livlogctl rotate-encryption-key has
+// re-wrapped everything. Disabled while Key is empty.
+type EncryptionConfig struct {
+	Key          string   `mapstructure:"key"`
+	PreviousKeys []string `mapstructure:"previous_keys"`
+}
+
+// Enabled reports whether a key-encryption key is configured.
+func (e *EncryptionConfig) Enabled() bool {
+	return e.Key != ""
+}
+
+// ErrorTrackingConfig controls reporting of panics, 5xx responses and
+// background job failures to Sentry or a Sentry-compatible service.
+type ErrorTrackingConfig struct {
+	DSN         string  `mapstructure:"dsn"` // empty disables error tracking
+	Environment string  `mapstructure:"environment"`
+	SampleRate  float64 `mapstructure:"sample_rate"` // 0.0 - 1.0
+}
+
+// Enabled reports whether a DSN is configured.
+func (e *ErrorTrackingConfig) Enabled() bool {
+	return e.DSN != ""
 }
 
 // GetAISearchLimit returns the AI search limit for the given policy
@@ -75,10 +370,28 @@ func (r *RateLimitConfig) GetAISearchLimit(policy string) int {
 	}
 }
 
+// Enabled reports whether AI search is configured. Without an API key the
+// search endpoint reports the feature as unavailable.
+func (o *OpenRouterConfig) Enabled() bool {
+	return o.APIKey != ""
+}
+
 func (s *ServerConfig) Address() string {
 	return fmt.Sprintf("%s:%d", s.Host, s.Port)
 }
 
+func (d *DebugConfig) Address() string {
+	return fmt.Sprintf("%s:%d", d.Host, d.Port)
+}
+
+func (g *GRPCConfig) Address() string {
+	return fmt.Sprintf("%s:%d", g.Host, g.Port)
+}
+
+func (m *MetricsConfig) Address() string {
+	return fmt.Sprintf("%s:%d", m.Host, m.Port)
+}
+
 func (d *DatabaseConfig) DSN() string {
 	return fmt.Sprintf(
 		"postgres://%s:%s@%s:%d/%s?sslmode=%s",
@@ -97,13 +410,27 @@ func Load(configPath string) (*Config, error) {
 	// Set defaults
 	v.SetDefault("server.host", "0.0.0.0")
 	v.SetDefault("server.port", 8080)
+	v.SetDefault("database.driver", "postgres")
 	v.SetDefault("database.host", "localhost")
 	v.SetDefault("database.port", 5432)
 	v.SetDefault("database.name", "livlog")
 	v.SetDefault("database.user", "livlog")
 	v.SetDefault("database.password", "livlog")
 	v.SetDefault("database.sslmode", "disable")
+	v.SetDefault("database.max_conns", 10)
+	v.SetDefault("database.min_conns", 2)
+	v.SetDefault("database.max_conn_lifetime", "1h")
+	v.SetDefault("database.max_conn_idle_time", "30m")
+	v.SetDefault("database.health_check_period", "1m")
+	v.SetDefault("database.statement_timeout", "30s")
+	v.SetDefault("database.connect_timeout", "60s")
+	v.SetDefault("database.trigram_search", false)
+	v.SetDefault("database.replicas", []string{})
+	v.SetDefault("database.max_replica_lag", "5s")
 	v.SetDefault("logging.format", "console")
+	v.SetDefault("logging.level", "")
+	v.SetDefault("logging.payloads.routes", []string{})
+	v.SetDefault("logging.payloads.max_bytes", 4096)
 	v.SetDefault("jwt.private_key_path", "./keys/private_key.pem")
 	v.SetDefault("jwt.public_key_path", "./keys/public_key.pem")
 	v.SetDefault("jwt.access_token_lifetime", 3600)
@@ -111,12 +438,89 @@ func Load(configPath string) (*Config, error) {
 	v.SetDefault("jwt.issuer", "livlog-api")
 	v.SetDefault("jwt.audience", "livlog-app")
 	v.SetDefault("apple.bundle_id", "net.avalarin.livlog")
+	v.SetDefault("auth.strip_email_plus_tags", false)
+	v.SetDefault("auth.introspection_token", "")
 	v.SetDefault("openrouter.base_url", "https://openrouter.ai/api/v1/chat/completions")
 	v.SetDefault("openrouter.model", "perplexity/sonar")
+	v.SetDefault("openrouter.budget.daily_requests", 0)
+	v.SetDefault("openrouter.budget.daily_spend_usd", 0)
+	v.SetDefault("books.base_url", "https://openlibrary.org")
+	v.SetDefault("books.timeout", "10s")
+	v.SetDefault("channels.telegram_bot_token", "")
+	v.SetDefault("channels.telegram_base_url", "https://api.telegram.org")
+	v.SetDefault("channels.timeout", "10s")
+	v.SetDefault("mail.host", "")
+	v.SetDefault("mail.port", 587)
+	v.SetDefault("mail.username", "")
+	v.SetDefault("mail.password", "")
+	v.SetDefault("mail.from", "")
+	v.SetDefault("mail.timeout", "10s")
+	v.SetDefault("mail.apple_relay", false)
 	v.SetDefault("ratelimit.ai_search_basic_limit", 5)
 	v.SetDefault("ratelimit.ai_search_pro_limit", 50)
 	v.SetDefault("ratelimit.ai_search_unlimited_limit", 0) // 0 means no limit
 	v.SetDefault("ratelimit.ai_search_period", "24h")
+	v.SetDefault("ratelimit.search.requests", 60)
+	v.SetDefault("ratelimit.search.period", "1m")
+	v.SetDefault("ratelimit.bulk.requests", 10)
+	v.SetDefault("ratelimit.bulk.period", "1m")
+	v.SetDefault("tracing.enabled", false)
+	v.SetDefault("tracing.endpoint", "localhost:4318")
+	v.SetDefault("tracing.insecure", true)
+	v.SetDefault("tracing.service_name", "livlog-backend")
+	v.SetDefault("tracing.sample_ratio", 1.0)
+	v.SetDefault("debug.enabled", false)
+	v.SetDefault("debug.host", "127.0.0.1")
+	v.SetDefault("debug.port", 6060)
+	v.SetDefault("grpc.enabled", false)
+	v.SetDefault("grpc.host", "0.0.0.0")
+	v.SetDefault("grpc.port", 9090)
+	v.SetDefault("cors.allowed_origins", []string{})
+	v.SetDefault("cors.allowed_headers", []string{"Authorization", "Content-Type", "X-Device-ID"})
+	v.SetDefault("cors.allow_credentials", false)
+	v.SetDefault("cors.max_age", 600)
+	v.SetDefault("limits.max_body_bytes", 1<<20)         // 1 MiB
+	v.SetDefault("limits.max_upload_body_bytes", 32<<20) // 32 MiB
+	v.SetDefault("limits.request_timeout", "10s")
+	v.SetDefault("limits.upload_timeout", "60s")
+	v.SetDefault("limits.ai_search_timeout", "45s")
+	v.SetDefault("api.default_page_size", 50)
+	v.SetDefault("api.max_page_size", 100)
+	v.SetDefault("errortracking.dsn", "")
+	v.SetDefault("errortracking.environment", "production")
+	v.SetDefault("errortracking.sample_rate", 1.0)
+	v.SetDefault("quotas.max_entries", 0)
+	v.SetDefault("quotas.max_collections", 0)
+	v.SetDefault("quotas.max_images_per_entry", 0)
+	v.SetDefault("entries.concurrent_update_window", "1m")
+	v.SetDefault("retention.deleted_users", "720h") // 30 days
+	v.SetDefault("retention.purge_interval", "1h")
+	v.SetDefault("retention.batch_size", 100)
+	v.SetDefault("retention.dry_run", false)
+	v.SetDefault("metrics.host", "0.0.0.0")
+	v.SetDefault("metrics.port", 0)
+	v.SetDefault("metrics.username", "")
+	v.SetDefault("metrics.password", "")
+	v.SetDefault("metrics.allowed_ips", []string{})
+	v.SetDefault("workspaces.enabled", false)
+	v.SetDefault("workspaces.max_members", 10)
+	v.SetDefault("workspaces.invitation_ttl", "168h") // 7 days
+	v.SetDefault("backup.interval", "24h")
+	v.SetDefault("backup.retention", "720h") // 30 days
+	v.SetDefault("backup.pg_dump_path", "pg_dump")
+	v.SetDefault("backup.timeout", "30m")
+	v.SetDefault("backup.dir", "")
+	v.SetDefault("backup.s3_endpoint", "")
+	v.SetDefault("backup.s3_region", "us-east-1")
+	v.SetDefault("backup.s3_bucket", "")
+	v.SetDefault("backup.s3_prefix", "backups/")
+	v.SetDefault("backup.s3_access_key", "")
+	v.SetDefault("backup.s3_secret_key", "")
+	v.SetDefault("cdn.base_url", "")
+	v.SetDefault("cdn.origin_auth_header", "X-Origin-Auth")
+	v.SetDefault("cdn.origin_auth_secret", "")
+	v.SetDefault("encryption.key", "")
+	v.SetDefault("encryption.previous_keys", []string{})
 
 	// Read config file
 	if configPath != "" {
@@ -145,5 +549,43 @@ func Load(configPath string) (*Config, error) {
 		return nil, fmt.Errorf("failed to unmarshal config: %w", err)
 	}
 
+	if err := readSecretFiles(v, &cfg); err != nil {
+		return nil, err
+	}
+
 	return &cfg, nil
 }
+
+// readSecretFiles loads secrets from files for keys set with a _file suffix
+// (e.g. LIVLOG_DATABASE_PASSWORD_FILE=/run/secrets/db_password), as used by
+// Docker and Kubernetes secrets. A file takes precedence over the plain value.
+func readSecretFiles(v *viper.Viper, cfg *Config) error {
+	secrets := []struct {
+		key string
+		dst *string
+	}{
+		{"database.password", &cfg.Database.Password},
+		{"openrouter.api_key", &cfg.OpenRouter.APIKey},
+		{"errortracking.dsn", &cfg.ErrorTracking.DSN},
+		{"metrics.password", &cfg.Metrics.Password},
+		{"backup.s3_secret_key", &cfg.Backup.S3SecretKey},
+		{"auth.introspection_token", &cfg.Auth.IntrospectionToken},
+		{"cdn.origin_auth_secret", &cfg.CDN.OriginAuthSecret},
+		{"encryption.key", &cfg.Encryption.Key},
+		{"mail.password", &cfg.Mail.Password},
+	}
+
+	for _, s := range secrets {
+		path := v.GetString(s.key + "_file")
+		if path == "" {
+			continue
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return fmt.Errorf("failed to read %s_file: %w", s.key, err)
+		}
+		*s.dst = strings.TrimSpace(string(data))
+	}
+
+	return nil
+}
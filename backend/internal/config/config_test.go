@@ -1,9 +1,12 @@
 package config
 
 import (
+	"encoding/base64"
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
+	"time"
 )
 
 func TestLoad_Defaults(t *testing.T) {
@@ -27,6 +30,12 @@ func TestLoad_Defaults(t *testing.T) {
 	if cfg.Logging.Format != "console" {
 		t.Errorf("expected default logging format console, got %s", cfg.Logging.Format)
 	}
+	if cfg.Debug.Enabled {
+		t.Error("expected debug listener to be disabled by default")
+	}
+	if cfg.Debug.Address() != "127.0.0.1:6060" {
+		t.Errorf("expected default debug address 127.0.0.1:6060, got %s", cfg.Debug.Address())
+	}
 }
 
 func TestLoad_FromFile(t *testing.T) {
@@ -93,3 +102,135 @@ func TestDatabaseConfig_DSN(t *testing.T) {
 		t.Errorf("expected %s, got %s", expected, cfg.DSN())
 	}
 }
+
+func TestValidate_Defaults(t *testing.T) {
+	tmpDir := t.TempDir()
+	origDir, _ := os.Getwd()
+	defer func() { _ = os.Chdir(origDir) }()
+	_ = os.Chdir(tmpDir)
+
+	cfg, err := Load("")
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	if err := cfg.Validate(); err != nil {
+		t.Errorf("expected defaults to be valid, got %v", err)
+	}
+	if cfg.OpenRouter.Enabled() {
+		t.Error("expected AI search to be disabled without an API key")
+	}
+	if cfg.Workspaces.Enabled {
+		t.Error("expected workspaces to be disabled by default")
+	}
+	if cfg.Entries.ConcurrentUpdateWindow != time.Minute {
+		t.Errorf("expected a 1m concurrent update window, got %s", cfg.Entries.ConcurrentUpdateWindow)
+	}
+}
+
+func TestValidate_ReportsAllErrors(t *testing.T) {
+	tmpDir := t.TempDir()
+	origDir, _ := os.Getwd()
+	defer func() { _ = os.Chdir(origDir) }()
+	_ = os.Chdir(tmpDir)
+
+	cfg, err := Load("")
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	cfg.Server.Port = 0
+	cfg.RateLimit.AISearchPeriod = "daily"
+	cfg.Logging.Format = "xml"
+	cfg.API.MaxPageSize = cfg.API.DefaultPageSize - 1
+	cfg.Mail.Host = "smtp.example.com"
+
+	err = cfg.Validate()
+	if err == nil {
+		t.Fatal("expected validation error")
+	}
+	for _, want := range []string{"server.port", "ratelimit.ai_search_period", "logging.format", "api.max_page_size", "mail.from"} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("expected error to mention %s, got %v", want, err)
+		}
+	}
+}
+
+func TestLoad_SecretFiles(t *testing.T) {
+	tmpDir := t.TempDir()
+	origDir, _ := os.Getwd()
+	defer func() { _ = os.Chdir(origDir) }()
+	_ = os.Chdir(tmpDir)
+
+	secretPath := filepath.Join(tmpDir, "db_password")
+	if err := os.WriteFile(secretPath, []byte("s3cret\n"), 0600); err != nil {
+		t.Fatalf("failed to write secret file: %v", err)
+	}
+	t.Setenv("LIVLOG_DATABASE_PASSWORD_FILE", secretPath)
+
+	cfg, err := Load("")
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	if cfg.Database.Password != "s3cret" {
+		t.Errorf("expected password from file, got %q", cfg.Database.Password)
+	}
+}
+
+func TestValidate_EncryptionKeys(t *testing.T) {
+	tmpDir := t.TempDir()
+	origDir, _ := os.Getwd()
+	defer func() { _ = os.Chdir(origDir) }()
+	_ = os.Chdir(tmpDir)
+
+	cfg, err := Load("")
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if cfg.Encryption.Enabled() {
+		t.Error("expected encryption to be disabled by default")
+	}
+
+	key := base64.StdEncoding.EncodeToString(make([]byte, 32))
+	cfg.Encryption.Key = key
+	cfg.Encryption.PreviousKeys = []string{key}
+	if err := cfg.Validate(); err != nil {
+		t.Errorf("expected valid keys to pass, got %v", err)
+	}
+
+	cfg.Encryption.Key = base64.StdEncoding.EncodeToString(make([]byte, 16))
+	cfg.Encryption.PreviousKeys = []string{"not base64!"}
+	err = cfg.Validate()
+	if err == nil {
+		t.Fatal("expected validation error")
+	}
+	for _, want := range []string{"encryption.key", "encryption.previous_keys[0]"} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("expected error to mention %s, got %v", want, err)
+		}
+	}
+}
+
+func TestAIBudgetConfig_Exceeded(t *testing.T) {
+	tests := []struct {
+		name     string
+		budget   AIBudgetConfig
+		requests int64
+		spend    float64
+		want     bool
+	}{
+		{"unlimited", AIBudgetConfig{}, 1000000, 5000, false},
+		{"under both", AIBudgetConfig{DailyRequests: 100, DailySpendUSD: 5}, 99, 4.99, false},
+		{"requests reached", AIBudgetConfig{DailyRequests: 100, DailySpendUSD: 5}, 100, 0, true},
+		{"spend reached", AIBudgetConfig{DailyRequests: 100, DailySpendUSD: 5}, 1, 5, true},
+		{"spend only", AIBudgetConfig{DailySpendUSD: 5}, 1000000, 4, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.budget.Exceeded(tt.requests, tt.spend); got != tt.want {
+				t.Errorf("Exceeded(%d, %v) = %v, want %v", tt.requests, tt.spend, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,239 @@
+package config
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net"
+	"net/mail"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// Validate checks the loaded configuration and reports every problem at once,
+// so a bad deployment fails at startup with the full list instead of at the
+// first request that touches a misconfigured subsystem.
+func (c *Config) Validate() error {
+	var errs []error
+	check := func(ok bool, format string, args ...interface{}) {
+		if !ok {
+			errs = append(errs, fmt.Errorf(format, args...))
+		}
+	}
+
+	check(validPort(c.Server.Port), "server.port must be between 1 and 65535, got %d", c.Server.Port)
+
+	check(c.Database.Driver == "postgres",
+		"database.driver %q is not supported, only \"postgres\" is available", c.Database.Driver)
+	check(c.Database.Host != "", "database.host is required")
+	check(validPort(c.Database.Port), "database.port must be between 1 and 65535, got %d", c.Database.Port)
+	check(c.Database.Name != "", "database.name is required")
+	check(c.Database.User != "", "database.user is required")
+	check(oneOf(c.Database.SSLMode, "disable", "allow", "prefer", "require", "verify-ca", "verify-full"),
+		"database.sslmode %q is not a valid sslmode", c.Database.SSLMode)
+	check(c.Database.MaxConns > 0, "database.max_conns must be positive, got %d", c.Database.MaxConns)
+	check(c.Database.MinConns >= 0 && c.Database.MinConns <= c.Database.MaxConns,
+		"database.min_conns must be between 0 and database.max_conns, got %d", c.Database.MinConns)
+	check(c.Database.MaxConnLifetime > 0, "database.max_conn_lifetime must be positive")
+	check(c.Database.MaxConnIdleTime > 0, "database.max_conn_idle_time must be positive")
+	check(c.Database.HealthCheckPeriod > 0, "database.health_check_period must be positive")
+	check(c.Database.StatementTimeout >= 0, "database.statement_timeout must not be negative")
+	check(c.Database.ConnectTimeout >= 0, "database.connect_timeout must not be negative")
+
+	check(oneOf(c.Logging.Format, "json", "console"),
+		"logging.format must be \"json\" or \"console\", got %q", c.Logging.Format)
+	check(oneOf(c.Logging.Level, "", "debug", "info", "warn", "error"),
+		"logging.level must be one of debug, info, warn, error, got %q", c.Logging.Level)
+
+	check(c.JWT.PrivateKeyPath != "", "jwt.private_key_path is required")
+	check(c.JWT.PublicKeyPath != "", "jwt.public_key_path is required")
+	check(c.JWT.AccessTokenLifetime > 0, "jwt.access_token_lifetime must be positive, got %d", c.JWT.AccessTokenLifetime)
+	check(c.JWT.RefreshTokenLifetime > c.JWT.AccessTokenLifetime,
+		"jwt.refresh_token_lifetime (%d) must be longer than jwt.access_token_lifetime (%d)",
+		c.JWT.RefreshTokenLifetime, c.JWT.AccessTokenLifetime)
+
+	check(c.Apple.BundleID != "", "apple.bundle_id is required")
+
+	// AI search is optional: without an API key it is disabled, see OpenRouterConfig.Enabled
+	if c.OpenRouter.Enabled() {
+		u, err := url.Parse(c.OpenRouter.BaseURL)
+		check(err == nil && u.Scheme != "" && u.Host != "",
+			"openrouter.base_url %q must be an absolute URL", c.OpenRouter.BaseURL)
+		check(c.OpenRouter.Model != "", "openrouter.model is required when openrouter.api_key is set")
+		check(c.OpenRouter.Budget.DailyRequests >= 0, "openrouter.budget.daily_requests must not be negative")
+		check(c.OpenRouter.Budget.DailySpendUSD >= 0, "openrouter.budget.daily_spend_usd must not be negative")
+	}
+
+	if c.Books.BaseURL != "" {
+		u, err := url.Parse(c.Books.BaseURL)
+		check(err == nil && u.Scheme != "" && u.Host != "",
+			"books.base_url %q must be an absolute URL", c.Books.BaseURL)
+		check(c.Books.Timeout > 0, "books.timeout must be positive")
+	}
+
+	check(c.Channels.Timeout > 0, "channels.timeout must be positive")
+	if c.Channels.TelegramBotToken != "" {
+		u, err := url.Parse(c.Channels.TelegramBaseURL)
+		check(err == nil && u.Scheme != "" && u.Host != "",
+			"channels.telegram_base_url %q must be an absolute URL", c.Channels.TelegramBaseURL)
+	}
+
+	if c.Mail.Enabled() {
+		check(c.Mail.Port > 0 && c.Mail.Port <= 65535, "mail.port must be between 1 and 65535")
+		_, err := mail.ParseAddress(c.Mail.From)
+		check(err == nil, "mail.from %q must be an email address", c.Mail.From)
+		check(c.Mail.Timeout > 0, "mail.timeout must be positive")
+	}
+
+	check(c.RateLimit.AISearchBasicLimit >= 0, "ratelimit.ai_search_basic_limit must not be negative")
+	check(c.RateLimit.AISearchProLimit >= 0, "ratelimit.ai_search_pro_limit must not be negative")
+	check(c.RateLimit.AISearchUnlimitedLimit >= 0, "ratelimit.ai_search_unlimited_limit must not be negative")
+	period, err := time.ParseDuration(c.RateLimit.AISearchPeriod)
+	check(err == nil && period > 0,
+		"ratelimit.ai_search_period %q must be a positive duration like \"24h\"", c.RateLimit.AISearchPeriod)
+
+	routeLimits := []struct {
+		name  string
+		limit RouteLimit
+	}{
+		{"search", c.RateLimit.Search},
+		{"bulk", c.RateLimit.Bulk},
+	}
+	for _, l := range routeLimits {
+		check(l.limit.Requests >= 0, "ratelimit.%s.requests must not be negative", l.name)
+		check(l.limit.Period > 0, "ratelimit.%s.period must be positive", l.name)
+	}
+
+	if c.Tracing.Enabled {
+		check(c.Tracing.Endpoint != "", "tracing.endpoint is required when tracing is enabled")
+		check(c.Tracing.SampleRatio >= 0 && c.Tracing.SampleRatio <= 1,
+			"tracing.sample_ratio must be between 0 and 1, got %g", c.Tracing.SampleRatio)
+	}
+
+	if c.Debug.Enabled {
+		check(validPort(c.Debug.Port), "debug.port must be between 1 and 65535, got %d", c.Debug.Port)
+		check(c.Debug.Port != c.Server.Port, "debug.port must differ from server.port")
+	}
+
+	if c.GRPC.Enabled {
+		check(validPort(c.GRPC.Port), "grpc.port must be between 1 and 65535, got %d", c.GRPC.Port)
+		check(c.GRPC.Port != c.Server.Port, "grpc.port must differ from server.port")
+		check(!c.Debug.Enabled || c.GRPC.Port != c.Debug.Port, "grpc.port must differ from debug.port")
+	}
+
+	for _, origin := range c.CORS.AllowedOrigins {
+		if origin == "*" {
+			check(!c.CORS.AllowCredentials, "cors.allowed_origins cannot contain \"*\" when cors.allow_credentials is set")
+			continue
+		}
+		u, err := url.Parse(origin)
+		check(err == nil && u.Scheme != "" && u.Host != "" && (u.Path == "" || u.Path == "/"),
+			"cors.allowed_origins entry %q must be an origin like \"https://app.example.com\"", origin)
+	}
+	check(c.CORS.MaxAge >= 0, "cors.max_age must not be negative")
+
+	check(c.Limits.MaxBodyBytes > 0, "limits.max_body_bytes must be positive")
+	check(c.Limits.MaxUploadBodyBytes >= c.Limits.MaxBodyBytes,
+		"limits.max_upload_body_bytes must be at least limits.max_body_bytes")
+	check(c.Limits.RequestTimeout > 0, "limits.request_timeout must be positive")
+	check(c.Limits.UploadTimeout > 0, "limits.upload_timeout must be positive")
+	check(c.Limits.AISearchTimeout > 0, "limits.ai_search_timeout must be positive")
+
+	check(c.API.DefaultPageSize > 0, "api.default_page_size must be positive, got %d", c.API.DefaultPageSize)
+	check(c.API.MaxPageSize >= c.API.DefaultPageSize,
+		"api.max_page_size must be at least api.default_page_size, got %d", c.API.MaxPageSize)
+
+	check(c.Quotas.MaxEntries >= 0, "quotas.max_entries must not be negative")
+	check(c.Quotas.MaxCollections >= 0, "quotas.max_collections must not be negative")
+	check(c.Quotas.MaxImagesPerEntry >= 0, "quotas.max_images_per_entry must not be negative")
+	check(c.Entries.ConcurrentUpdateWindow >= 0, "entries.concurrent_update_window must not be negative")
+
+	check(c.Retention.DeletedUsers >= 0, "retention.deleted_users must not be negative")
+	if c.Retention.DeletedUsers > 0 {
+		check(c.Retention.PurgeInterval > 0, "retention.purge_interval must be positive")
+		check(c.Retention.BatchSize > 0, "retention.batch_size must be positive")
+	}
+
+	if c.Metrics.Port != 0 {
+		check(validPort(c.Metrics.Port), "metrics.port must be between 1 and 65535, got %d", c.Metrics.Port)
+		check(c.Metrics.Port != c.Server.Port, "metrics.port must differ from server.port")
+		check(!c.Debug.Enabled || c.Metrics.Port != c.Debug.Port, "metrics.port must differ from debug.port")
+		check(!c.GRPC.Enabled || c.Metrics.Port != c.GRPC.Port, "metrics.port must differ from grpc.port")
+	}
+	check(c.Metrics.Username == "" || c.Metrics.Password != "", "metrics.password is required when metrics.username is set")
+	for _, ip := range c.Metrics.AllowedIPs {
+		_, _, cidrErr := net.ParseCIDR(ip)
+		check(cidrErr == nil || net.ParseIP(ip) != nil, "metrics.allowed_ips entry %q is not an IP or CIDR", ip)
+	}
+
+	if c.Workspaces.Enabled {
+		check(c.Workspaces.MaxMembers >= 2, "workspaces.max_members must be at least 2, got %d", c.Workspaces.MaxMembers)
+		check(c.Workspaces.InvitationTTL > 0, "workspaces.invitation_ttl must be positive")
+	}
+
+	if c.Backup.Enabled() {
+		check(c.Backup.Dir == "" || c.Backup.S3Bucket == "", "backup.dir and backup.s3_bucket cannot both be set")
+		check(c.Backup.Interval >= 0, "backup.interval must not be negative")
+		check(c.Backup.Retention >= 0, "backup.retention must not be negative")
+		check(c.Backup.Timeout > 0, "backup.timeout must be positive")
+		check(c.Backup.PGDumpPath != "", "backup.pg_dump_path is required")
+		if c.Backup.S3Bucket != "" {
+			u, err := url.Parse(c.Backup.S3Endpoint)
+			check(err == nil && u.Scheme != "" && u.Host != "",
+				"backup.s3_endpoint %q must be an absolute URL", c.Backup.S3Endpoint)
+			check(c.Backup.S3Region != "", "backup.s3_region is required")
+			check(c.Backup.S3AccessKey != "" && c.Backup.S3SecretKey != "",
+				"backup.s3_access_key and backup.s3_secret_key are required")
+		}
+	}
+
+	if c.CDN.Enabled() {
+		u, err := url.Parse(c.CDN.BaseURL)
+		check(err == nil && (u.Scheme == "http" || u.Scheme == "https") && u.Host != "",
+			"cdn.base_url %q must be an absolute URL", c.CDN.BaseURL)
+	}
+	if c.CDN.OriginAuthSecret != "" {
+		check(c.CDN.Enabled(), "cdn.origin_auth_secret requires cdn.base_url")
+		check(c.CDN.OriginAuthHeader != "", "cdn.origin_auth_header is required when cdn.origin_auth_secret is set")
+	}
+
+	if c.Encryption.Enabled() {
+		check(validEncryptionKey(c.Encryption.Key), "encryption.key must be 32 bytes encoded as base64")
+	}
+	check(len(c.Encryption.PreviousKeys) == 0 || c.Encryption.Enabled(), "encryption.previous_keys requires encryption.key")
+	for i, key := range c.Encryption.PreviousKeys {
+		check(validEncryptionKey(key), "encryption.previous_keys[%d] must be 32 bytes encoded as base64", i)
+	}
+
+	if c.ErrorTracking.Enabled() {
+		u, err := url.Parse(c.ErrorTracking.DSN)
+		check(err == nil && u.Scheme != "" && u.Host != "" && u.User.Username() != "" && strings.Trim(u.Path, "/") != "",
+			"errortracking.dsn must look like \"https://<key>@<host>/<project>\"")
+		check(c.ErrorTracking.SampleRate >= 0 && c.ErrorTracking.SampleRate <= 1,
+			"errortracking.sample_rate must be between 0 and 1, got %g", c.ErrorTracking.SampleRate)
+	}
+
+	if len(errs) > 0 {
+		return fmt.Errorf("invalid configuration:\n%w", errors.Join(errs...))
+	}
+	return nil
+}
+
+func validPort(port int) bool {
+	return port > 0 && port <= 65535
+}
+
+func validEncryptionKey(key string) bool {
+	raw, err := base64.StdEncoding.DecodeString(strings.TrimSpace(key))
+	return err == nil && len(raw) == 32
+}
+
+func oneOf(value string, allowed ...string) bool {
+	for _, a := range allowed {
+		if value == a {
+			return true
+		}
+	}
+	return false
+}
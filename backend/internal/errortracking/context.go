@@ -0,0 +1,17 @@
+package errortracking
+
+import "context"
+
+type ctxKey struct{}
+
+// WithContext returns a copy of ctx carrying t.
+func WithContext(ctx context.Context, t *Tracker) context.Context {
+	return context.WithValue(ctx, ctxKey{}, t)
+}
+
+// FromContext returns the tracker stored by WithContext, or nil, which is safe
+// to call Capture on.
+func FromContext(ctx context.Context) *Tracker {
+	t, _ := ctx.Value(ctxKey{}).(*Tracker)
+	return t
+}
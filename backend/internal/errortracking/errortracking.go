@@ -0,0 +1,318 @@
+// Package errortracking reports errors to Sentry or a Sentry-compatible
+// service (GlitchTip, self-hosted Sentry, ...) through the envelope HTTP API.
+//
+// A nil *Tracker is valid and drops everything, so callers never need to check
+// whether error tracking is configured.
+package errortracking
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	mathrand "math/rand"
+	"net/http"
+	"net/url"
+	"os"
+	"reflect"
+	"runtime"
+	"strings"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/avalarin/livlog/backend/internal/config"
+)
+
+const (
+	queueSize      = 100
+	requestTimeout = 5 * time.Second
+	maxFrames      = 50
+)
+
+// Event describes one captured error.
+type Event struct {
+	Err     error
+	Tags    map[string]string // searchable in the tracker, e.g. route, job, request_id
+	UserID  string
+	Request *http.Request // method, URL and non-sensitive headers are attached
+}
+
+// Tracker sends events in the background. Events are dropped, with a warning,
+// when the queue is full so a tracker outage never slows down requests.
+type Tracker struct {
+	endpoint    string
+	auth        string
+	dsn         string
+	environment string
+	release     string
+	serverName  string
+	sampleRate  float64
+
+	httpClient *http.Client
+	logger     *zap.Logger
+
+	queue chan []byte
+	done  chan struct{}
+	once  sync.Once
+}
+
+// New creates a tracker for cfg and starts its sender, or returns nil when
+// error tracking is disabled.
+func New(cfg *config.ErrorTrackingConfig, release string, logger *zap.Logger) (*Tracker, error) {
+	if !cfg.Enabled() {
+		return nil, nil
+	}
+
+	u, err := url.Parse(cfg.DSN)
+	if err != nil {
+		return nil, fmt.Errorf("invalid errortracking dsn: %w", err)
+	}
+	path := strings.Trim(u.Path, "/")
+	i := strings.LastIndex(path, "/")
+	prefix, project := "", path
+	if i >= 0 {
+		prefix, project = "/"+path[:i], path[i+1:]
+	}
+
+	hostname, _ := os.Hostname()
+
+	t := &Tracker{
+		endpoint: fmt.Sprintf("%s://%s%s/api/%s/envelope/", u.Scheme, u.Host, prefix, project),
+		auth: fmt.Sprintf("Sentry sentry_version=7, sentry_client=livlog-backend/%s, sentry_key=%s",
+			release, u.User.Username()),
+		dsn:         cfg.DSN,
+		environment: cfg.Environment,
+		release:     release,
+		serverName:  hostname,
+		sampleRate:  cfg.SampleRate,
+		httpClient:  &http.Client{Timeout: requestTimeout},
+		logger:      logger,
+		queue:       make(chan []byte, queueSize),
+		done:        make(chan struct{}),
+	}
+	go t.run()
+
+	return t, nil
+}
+
+// Capture queues e for sending. The stack trace is taken at the call site, so
+// when capturing a recovered panic call it from the deferred function.
+func (t *Tracker) Capture(e Event) {
+	if t == nil || e.Err == nil {
+		return
+	}
+	if t.sampleRate < 1 && mathrand.Float64() >= t.sampleRate {
+		return
+	}
+
+	id, err := eventID()
+	if err != nil {
+		t.logger.Warn("failed to capture error", zap.Error(err))
+		return
+	}
+
+	envelope, err := t.envelope(id, t.event(id, e, stacktrace(3)))
+	if err != nil {
+		t.logger.Warn("failed to capture error", zap.Error(err))
+		return
+	}
+
+	select {
+	case t.queue <- envelope:
+	default:
+		t.logger.Warn("error tracking queue is full, dropping event", zap.Error(e.Err))
+	}
+}
+
+// Close sends queued events and stops the tracker, or gives up when ctx ends.
+// Capture must not be called after Close.
+func (t *Tracker) Close(ctx context.Context) error {
+	if t == nil {
+		return nil
+	}
+	t.once.Do(func() { close(t.queue) })
+
+	select {
+	case <-t.done:
+		return nil
+	case <-ctx.Done():
+		return fmt.Errorf("error tracking queue not flushed: %w", ctx.Err())
+	}
+}
+
+func (t *Tracker) run() {
+	defer close(t.done)
+	for envelope := range t.queue {
+		if err := t.send(envelope); err != nil {
+			t.logger.Warn("failed to send error event", zap.Error(err))
+		}
+	}
+}
+
+func (t *Tracker) send(envelope []byte) error {
+	req, err := http.NewRequest(http.MethodPost, t.endpoint, bytes.NewReader(envelope))
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/x-sentry-envelope")
+	req.Header.Set("X-Sentry-Auth", t.auth)
+
+	resp, err := t.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("request failed: %w", err)
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("unexpected status %d", resp.StatusCode)
+	}
+	return nil
+}
+
+type event struct {
+	EventID     string            `json:"event_id"`
+	Timestamp   time.Time         `json:"timestamp"`
+	Platform    string            `json:"platform"`
+	Level       string            `json:"level"`
+	Release     string            `json:"release,omitempty"`
+	Environment string            `json:"environment,omitempty"`
+	ServerName  string            `json:"server_name,omitempty"`
+	Tags        map[string]string `json:"tags,omitempty"`
+	User        *user             `json:"user,omitempty"`
+	Request     *request          `json:"request,omitempty"`
+	Exception   struct {
+		Values []exception `json:"values"`
+	} `json:"exception"`
+}
+
+type user struct {
+	ID string `json:"id"`
+}
+
+type request struct {
+	Method  string            `json:"method"`
+	URL     string            `json:"url"`
+	Headers map[string]string `json:"headers,omitempty"`
+}
+
+type exception struct {
+	Type       string      `json:"type"`
+	Value      string      `json:"value"`
+	Stacktrace *stackTrace `json:"stacktrace,omitempty"`
+}
+
+type stackTrace struct {
+	Frames []frame `json:"frames"`
+}
+
+type frame struct {
+	Function string `json:"function"`
+	AbsPath  string `json:"abs_path"`
+	Lineno   int    `json:"lineno"`
+	InApp    bool   `json:"in_app"`
+}
+
+// sensitiveHeaders are never sent to the tracker.
+var sensitiveHeaders = map[string]bool{
+	"Authorization": true,
+	"Cookie":        true,
+	"X-Api-Key":     true,
+}
+
+func (t *Tracker) event(id string, e Event, frames []frame) *event {
+	ev := &event{
+		EventID:     id,
+		Timestamp:   time.Now().UTC(),
+		Platform:    "go",
+		Level:       "error",
+		Release:     t.release,
+		Environment: t.environment,
+		ServerName:  t.serverName,
+		Tags:        e.Tags,
+	}
+	if e.UserID != "" {
+		ev.User = &user{ID: e.UserID}
+	}
+	if r := e.Request; r != nil {
+		headers := make(map[string]string, len(r.Header))
+		for name := range r.Header {
+			if !sensitiveHeaders[name] {
+				headers[name] = r.Header.Get(name)
+			}
+		}
+		// The query can carry tokens (e.g. the sync stream), so only the path is sent
+		ev.Request = &request{Method: r.Method, URL: r.URL.Path, Headers: headers}
+	}
+
+	// Sentry lists the outermost error last
+	for err := e.Err; err != nil; err = errors.Unwrap(err) {
+		ev.Exception.Values = append([]exception{{
+			Type:  reflect.TypeOf(err).String(),
+			Value: err.Error(),
+		}}, ev.Exception.Values...)
+	}
+	last := &ev.Exception.Values[len(ev.Exception.Values)-1]
+	last.Stacktrace = &stackTrace{Frames: frames}
+
+	return ev
+}
+
+func (t *Tracker) envelope(id string, ev *event) ([]byte, error) {
+	payload, err := json.Marshal(ev)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode event: %w", err)
+	}
+	header, err := json.Marshal(map[string]interface{}{
+		"event_id": id,
+		"sent_at":  time.Now().UTC(),
+		"dsn":      t.dsn,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode envelope header: %w", err)
+	}
+
+	var buf bytes.Buffer
+	buf.Write(header)
+	buf.WriteString("\n")
+	fmt.Fprintf(&buf, `{"type":"event","length":%d}`+"\n", len(payload))
+	buf.Write(payload)
+	buf.WriteString("\n")
+	return buf.Bytes(), nil
+}
+
+// stacktrace returns the caller's stack, oldest frame first as Sentry expects.
+func stacktrace(skip int) []frame {
+	pcs := make([]uintptr, maxFrames)
+	n := runtime.Callers(skip, pcs)
+	frames := runtime.CallersFrames(pcs[:n])
+
+	var out []frame
+	for {
+		f, more := frames.Next()
+		out = append([]frame{{
+			Function: f.Function,
+			AbsPath:  f.File,
+			Lineno:   f.Line,
+			InApp:    strings.HasPrefix(f.Function, "github.com/avalarin/livlog/"),
+		}}, out...)
+		if !more {
+			break
+		}
+	}
+	return out
+}
+
+func eventID() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("failed to generate event id: %w", err)
+	}
+	return hex.EncodeToString(b), nil
+}
@@ -0,0 +1,126 @@
+package errortracking
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/avalarin/livlog/backend/internal/config"
+)
+
+func TestNew_Disabled(t *testing.T) {
+	tracker, err := New(&config.ErrorTrackingConfig{}, "test", zap.NewNop())
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	if tracker != nil {
+		t.Fatal("expected nil tracker without a DSN")
+	}
+
+	// A nil tracker accepts events and closes cleanly
+	tracker.Capture(Event{Err: errors.New("boom")})
+	if err := tracker.Close(context.Background()); err != nil {
+		t.Errorf("Close() error = %v", err)
+	}
+}
+
+func TestCapture_SendsEnvelope(t *testing.T) {
+	type received struct {
+		path, auth string
+		body       []byte
+	}
+	got := make(chan received, 1)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		got <- received{r.URL.Path, r.Header.Get("X-Sentry-Auth"), body}
+	}))
+	defer srv.Close()
+
+	dsn := strings.Replace(srv.URL, "http://", "http://public@", 1) + "/sentry/42"
+	tracker, err := New(&config.ErrorTrackingConfig{DSN: dsn, Environment: "test", SampleRate: 1}, "1.2.3", zap.NewNop())
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/entries?token=secret", nil)
+	req.Header.Set("Authorization", "Bearer secret")
+	req.Header.Set("User-Agent", "livlog-ios")
+
+	cause := errors.New("connection refused")
+	tracker.Capture(Event{
+		Err:     fmt.Errorf("failed to create entry: %w", cause),
+		Tags:    map[string]string{"route": "/api/v1/entries"},
+		UserID:  "user-1",
+		Request: req,
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := tracker.Close(ctx); err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+
+	var r received
+	select {
+	case r = <-got:
+	default:
+		t.Fatal("no event was sent")
+	}
+
+	if r.path != "/sentry/api/42/envelope/" {
+		t.Errorf("path = %q", r.path)
+	}
+	if !strings.Contains(r.auth, "sentry_key=public") {
+		t.Errorf("X-Sentry-Auth = %q", r.auth)
+	}
+
+	lines := bufio.NewScanner(bytes.NewReader(r.body))
+	var items []string
+	for lines.Scan() {
+		items = append(items, lines.Text())
+	}
+	if len(items) != 3 {
+		t.Fatalf("envelope has %d lines, want 3", len(items))
+	}
+
+	var ev event
+	if err := json.Unmarshal([]byte(items[2]), &ev); err != nil {
+		t.Fatalf("failed to decode event: %v", err)
+	}
+	if ev.Release != "1.2.3" || ev.Environment != "test" || ev.User == nil || ev.User.ID != "user-1" {
+		t.Errorf("unexpected event metadata: %+v", ev)
+	}
+	if ev.Tags["route"] != "/api/v1/entries" {
+		t.Errorf("tags = %v", ev.Tags)
+	}
+	if n := len(ev.Exception.Values); n != 2 {
+		t.Fatalf("got %d exceptions, want the error and its cause", n)
+	}
+	if ev.Exception.Values[0].Value != "connection refused" {
+		t.Errorf("innermost exception = %q", ev.Exception.Values[0].Value)
+	}
+	if ev.Exception.Values[1].Stacktrace == nil || len(ev.Exception.Values[1].Stacktrace.Frames) == 0 {
+		t.Error("outermost exception has no stack trace")
+	}
+
+	if ev.Request == nil || ev.Request.URL != "/api/v1/entries" {
+		t.Errorf("request = %+v", ev.Request)
+	}
+	if _, ok := ev.Request.Headers["Authorization"]; ok {
+		t.Error("Authorization header was sent")
+	}
+	if ev.Request.Headers["User-Agent"] != "livlog-ios" {
+		t.Errorf("headers = %v", ev.Request.Headers)
+	}
+}
@@ -0,0 +1,328 @@
+// Package fieldcrypt encrypts sensitive column values, such as email
+// addresses, with AES-256-GCM for deployments that must not keep them in
+// plaintext at rest.
+//
+// Values are encrypted with a data key that is stored in the database
+// wrapped by a key-encryption key (KEK) from the configuration, so replacing
+// the KEK re-wraps a few keys instead of re-encrypting every value. Columns
+// that are looked up by value carry a blind index instead: an HMAC of the
+// normalized value under a separate index key, which matches equal values
+// without revealing them.
+package fieldcrypt
+
+import (
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Key purposes, see StoredKey.
+const (
+	PurposeData  = "data"
+	PurposeIndex = "index"
+)
+
+// KeySize is the size of KEKs, data keys and index keys: AES-256.
+const KeySize = 32
+
+// prefix marks encrypted values, which read "enc:v1:<key id>:<base64>".
+// Values without it are legacy plaintext and are returned as they are.
+const prefix = "enc:v1:"
+
+// reloadInterval limits how often a keyring goes back to the database for a
+// key it doesn't know, e.g. one another instance just created.
+const reloadInterval = time.Minute
+
+var (
+	// ErrUnknownKey is returned for values encrypted with a key the keyring
+	// doesn't have.
+	ErrUnknownKey = errors.New("encryption key not found")
+	// ErrNoKEK is returned for stored keys that none of the configured KEKs
+	// wrapped.
+	ErrNoKEK = errors.New("no configured key-encryption key matches")
+	// ErrMalformed is returned for encrypted values that can't be decoded.
+	ErrMalformed = errors.New("malformed encrypted value")
+)
+
+// KEK is a key-encryption key, which wraps the data and index keys.
+type KEK struct {
+	aead cipher.AEAD
+	// Fingerprint identifies the KEK a stored key is wrapped with without
+	// revealing it.
+	Fingerprint string
+}
+
+// ParseKEK decodes a base64 KEK of KeySize bytes.
+func ParseKEK(encoded string) (*KEK, error) {
+	key, err := base64.StdEncoding.DecodeString(strings.TrimSpace(encoded))
+	if err != nil {
+		return nil, fmt.Errorf("key-encryption key is not valid base64: %w", err)
+	}
+	if len(key) != KeySize {
+		return nil, fmt.Errorf("key-encryption key must be %d bytes, got %d", KeySize, len(key))
+	}
+
+	aead, err := newAEAD(key)
+	if err != nil {
+		return nil, err
+	}
+	sum := sha256.Sum256(key)
+	return &KEK{aead: aead, Fingerprint: hex.EncodeToString(sum[:8])}, nil
+}
+
+// Wrap encrypts a data or index key.
+func (k *KEK) Wrap(key []byte) ([]byte, error) {
+	return seal(k.aead, key, []byte(k.Fingerprint))
+}
+
+// Unwrap decrypts a key wrapped with this KEK.
+func (k *KEK) Unwrap(wrapped []byte) ([]byte, error) {
+	key, err := open(k.aead, wrapped, []byte(k.Fingerprint))
+	if err != nil {
+		return nil, fmt.Errorf("failed to unwrap key: %w", err)
+	}
+	return key, nil
+}
+
+// StoredKey is a data or index key as kept in the encryption_keys table.
+// There is one index key, which never changes, and one active data key;
+// retired data keys are kept to read values encrypted before a rotation.
+type StoredKey struct {
+	ID             uuid.UUID
+	Purpose        string
+	WrappedKey     []byte
+	KEKFingerprint string
+	CreatedAt      time.Time
+	RetiredAt      *time.Time
+}
+
+// NewKey generates a key for purpose, wrapped with kek.
+func NewKey(purpose string, kek *KEK) (StoredKey, error) {
+	key := make([]byte, KeySize)
+	if _, err := rand.Read(key); err != nil {
+		return StoredKey{}, fmt.Errorf("failed to generate key: %w", err)
+	}
+	wrapped, err := kek.Wrap(key)
+	if err != nil {
+		return StoredKey{}, err
+	}
+	return StoredKey{
+		ID:             uuid.New(),
+		Purpose:        purpose,
+		WrappedKey:     wrapped,
+		KEKFingerprint: kek.Fingerprint,
+	}, nil
+}
+
+// UnwrapWith unwraps the key with whichever of keks wrapped it.
+func (s StoredKey) UnwrapWith(keks []*KEK) ([]byte, error) {
+	for _, kek := range keks {
+		if kek.Fingerprint == s.KEKFingerprint {
+			return kek.Unwrap(s.WrappedKey)
+		}
+	}
+	return nil, fmt.Errorf("%w: key %s is wrapped with %s", ErrNoKEK, s.ID, s.KEKFingerprint)
+}
+
+// LoadFunc returns the stored keys.
+type LoadFunc func(ctx context.Context) ([]StoredKey, error)
+
+// Keyring encrypts with the active data key and decrypts with any stored
+// one. It is safe for concurrent use.
+type Keyring struct {
+	keks []*KEK
+	load LoadFunc
+
+	mu       sync.RWMutex
+	active   uuid.UUID
+	data     map[uuid.UUID]cipher.AEAD
+	index    []byte
+	loadedAt time.Time
+}
+
+// NewKeyring loads the stored keys and unwraps them with keks. There must be
+// an active data key and an index key.
+func NewKeyring(ctx context.Context, keks []*KEK, load LoadFunc) (*Keyring, error) {
+	k := &Keyring{keks: keks, load: load}
+	if err := k.reload(ctx); err != nil {
+		return nil, err
+	}
+	return k, nil
+}
+
+func (k *Keyring) reload(ctx context.Context) error {
+	keys, err := k.load(ctx)
+	if err != nil {
+		return err
+	}
+
+	var active uuid.UUID
+	var index []byte
+	data := make(map[uuid.UUID]cipher.AEAD, len(keys))
+	for _, s := range keys {
+		key, err := s.UnwrapWith(k.keks)
+		if err != nil {
+			return err
+		}
+		switch s.Purpose {
+		case PurposeData:
+			aead, err := newAEAD(key)
+			if err != nil {
+				return err
+			}
+			data[s.ID] = aead
+			if s.RetiredAt == nil {
+				active = s.ID
+			}
+		case PurposeIndex:
+			index = key
+		}
+	}
+	if active == uuid.Nil {
+		return errors.New("no active data encryption key")
+	}
+	if index == nil {
+		return errors.New("no blind index key")
+	}
+
+	k.mu.Lock()
+	k.active, k.data, k.index, k.loadedAt = active, data, index, time.Now()
+	k.mu.Unlock()
+	return nil
+}
+
+// ActiveKeyID returns the id of the data key new values are encrypted with.
+func (k *Keyring) ActiveKeyID() uuid.UUID {
+	k.mu.RLock()
+	defer k.mu.RUnlock()
+	return k.active
+}
+
+// ActivePrefix returns the prefix of values encrypted with the active data
+// key, for finding those that aren't in SQL.
+func (k *Keyring) ActivePrefix() string {
+	return prefix + k.ActiveKeyID().String() + ":"
+}
+
+// Encrypt encrypts plaintext with the active data key.
+func (k *Keyring) Encrypt(plaintext string) (string, error) {
+	k.mu.RLock()
+	id, aead := k.active, k.data[k.active]
+	k.mu.RUnlock()
+
+	sealed, err := seal(aead, []byte(plaintext), id[:])
+	if err != nil {
+		return "", err
+	}
+	return prefix + id.String() + ":" + base64.RawStdEncoding.EncodeToString(sealed), nil
+}
+
+// Decrypt decrypts a value from Encrypt. Plaintext values are returned as
+// they are, so columns can hold both while they are being encrypted.
+func (k *Keyring) Decrypt(ctx context.Context, value string) (string, error) {
+	if !IsEncrypted(value) {
+		return value, nil
+	}
+
+	idPart, payload, ok := strings.Cut(strings.TrimPrefix(value, prefix), ":")
+	if !ok {
+		return "", ErrMalformed
+	}
+	id, err := uuid.Parse(idPart)
+	if err != nil {
+		return "", ErrMalformed
+	}
+	sealed, err := base64.RawStdEncoding.DecodeString(payload)
+	if err != nil {
+		return "", ErrMalformed
+	}
+
+	aead, err := k.dataKey(ctx, id)
+	if err != nil {
+		return "", err
+	}
+	plaintext, err := open(aead, sealed, id[:])
+	if err != nil {
+		return "", fmt.Errorf("failed to decrypt value: %w", err)
+	}
+	return string(plaintext), nil
+}
+
+// dataKey returns the data key with id, reloading the keys once in a while
+// when it isn't known yet.
+func (k *Keyring) dataKey(ctx context.Context, id uuid.UUID) (cipher.AEAD, error) {
+	k.mu.RLock()
+	aead, ok := k.data[id]
+	stale := time.Since(k.loadedAt) > reloadInterval
+	k.mu.RUnlock()
+	if ok {
+		return aead, nil
+	}
+	if !stale {
+		return nil, fmt.Errorf("%w: %s", ErrUnknownKey, id)
+	}
+
+	if err := k.reload(ctx); err != nil {
+		return nil, err
+	}
+	k.mu.RLock()
+	aead, ok = k.data[id]
+	k.mu.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrUnknownKey, id)
+	}
+	return aead, nil
+}
+
+// BlindIndex returns the blind index of value, ignoring case and
+// surrounding spaces.
+func (k *Keyring) BlindIndex(value string) string {
+	k.mu.RLock()
+	mac := hmac.New(sha256.New, k.index)
+	k.mu.RUnlock()
+
+	mac.Write([]byte(strings.ToLower(strings.TrimSpace(value))))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// IsEncrypted reports whether value came from Keyring.Encrypt.
+func IsEncrypted(value string) bool {
+	return strings.HasPrefix(value, prefix)
+}
+
+func newAEAD(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create cipher: %w", err)
+	}
+	return cipher.NewGCM(block)
+}
+
+// seal encrypts plaintext and returns it after a random nonce.
+func seal(aead cipher.AEAD, plaintext, additionalData []byte) ([]byte, error) {
+	nonce := make([]byte, aead.NonceSize(), aead.NonceSize()+len(plaintext)+aead.Overhead())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, fmt.Errorf("failed to generate nonce: %w", err)
+	}
+	return aead.Seal(nonce, nonce, plaintext, additionalData), nil
+}
+
+func open(aead cipher.AEAD, sealed, additionalData []byte) ([]byte, error) {
+	if len(sealed) < aead.NonceSize() {
+		return nil, ErrMalformed
+	}
+	nonce, ciphertext := sealed[:aead.NonceSize()], sealed[aead.NonceSize():]
+	return aead.Open(nil, nonce, ciphertext, additionalData)
+}
@@ -0,0 +1,155 @@
+package fieldcrypt
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func newTestKEK(t *testing.T) (*KEK, string) {
+	t.Helper()
+	raw := make([]byte, KeySize)
+	if _, err := rand.Read(raw); err != nil {
+		t.Fatal(err)
+	}
+	encoded := base64.StdEncoding.EncodeToString(raw)
+	kek, err := ParseKEK(encoded)
+	if err != nil {
+		t.Fatalf("ParseKEK: %v", err)
+	}
+	return kek, encoded
+}
+
+func newTestKeys(t *testing.T, kek *KEK) []StoredKey {
+	t.Helper()
+	data, err := NewKey(PurposeData, kek)
+	if err != nil {
+		t.Fatal(err)
+	}
+	index, err := NewKey(PurposeIndex, kek)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return []StoredKey{data, index}
+}
+
+func staticKeys(keys *[]StoredKey) LoadFunc {
+	return func(ctx context.Context) ([]StoredKey, error) {
+		return *keys, nil
+	}
+}
+
+func TestParseKEK(t *testing.T) {
+	kek, encoded := newTestKEK(t)
+	again, err := ParseKEK(" " + encoded + "\n")
+	if err != nil || again.Fingerprint != kek.Fingerprint {
+		t.Errorf("ParseKEK of the same key = %v, %v; want fingerprint %s", again, err, kek.Fingerprint)
+	}
+
+	for _, bad := range []string{"", "not base64!", base64.StdEncoding.EncodeToString([]byte("short"))} {
+		if _, err := ParseKEK(bad); err == nil {
+			t.Errorf("ParseKEK(%q) succeeded", bad)
+		}
+	}
+}
+
+func TestKeyring_EncryptDecrypt(t *testing.T) {
+	ctx := context.Background()
+	kek, _ := newTestKEK(t)
+	keys := newTestKeys(t, kek)
+	keyring, err := NewKeyring(ctx, []*KEK{kek}, staticKeys(&keys))
+	if err != nil {
+		t.Fatalf("NewKeyring: %v", err)
+	}
+
+	encrypted, err := keyring.Encrypt("user@example.com")
+	if err != nil {
+		t.Fatalf("Encrypt: %v", err)
+	}
+	if !IsEncrypted(encrypted) || !strings.HasPrefix(encrypted, keyring.ActivePrefix()) {
+		t.Errorf("Encrypt = %q, want the active key prefix %q", encrypted, keyring.ActivePrefix())
+	}
+	if strings.Contains(encrypted, "example") {
+		t.Errorf("Encrypt = %q leaks the plaintext", encrypted)
+	}
+
+	again, _ := keyring.Encrypt("user@example.com")
+	if again == encrypted {
+		t.Error("Encrypt is deterministic, want a random nonce")
+	}
+
+	decrypted, err := keyring.Decrypt(ctx, encrypted)
+	if err != nil || decrypted != "user@example.com" {
+		t.Errorf("Decrypt = %q, %v; want user@example.com", decrypted, err)
+	}
+
+	plain, err := keyring.Decrypt(ctx, "legacy@example.com")
+	if err != nil || plain != "legacy@example.com" {
+		t.Errorf("Decrypt of plaintext = %q, %v; want it unchanged", plain, err)
+	}
+
+	tampered := encrypted[:len(encrypted)-2] + "AA"
+	if _, err := keyring.Decrypt(ctx, tampered); err == nil {
+		t.Error("Decrypt of a tampered value succeeded")
+	}
+	if _, err := keyring.Decrypt(ctx, prefix+"garbage"); !errors.Is(err, ErrMalformed) {
+		t.Errorf("Decrypt of a malformed value = %v, want ErrMalformed", err)
+	}
+}
+
+func TestKeyring_Rotation(t *testing.T) {
+	ctx := context.Background()
+	oldKEK, _ := newTestKEK(t)
+	keys := newTestKeys(t, oldKEK)
+
+	newKEK, _ := newTestKEK(t)
+	keyring, err := NewKeyring(ctx, []*KEK{newKEK, oldKEK}, staticKeys(&keys))
+	if err != nil {
+		t.Fatalf("NewKeyring with a previous KEK: %v", err)
+	}
+	oldValue, _ := keyring.Encrypt("device")
+	index := keyring.BlindIndex("User@Example.com ")
+
+	if _, err := NewKeyring(ctx, []*KEK{newKEK}, staticKeys(&keys)); !errors.Is(err, ErrNoKEK) {
+		t.Errorf("NewKeyring without the wrapping KEK = %v, want ErrNoKEK", err)
+	}
+
+	// A new data key from another process shows up on the next reload
+	retired := time.Now()
+	keys[0].RetiredAt = &retired
+	next, err := NewKey(PurposeData, newKEK)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keys = append(keys, next)
+
+	other, err := NewKeyring(ctx, []*KEK{newKEK, oldKEK}, staticKeys(&keys))
+	if err != nil {
+		t.Fatal(err)
+	}
+	newValue, _ := other.Encrypt("device")
+	if !strings.HasPrefix(newValue, prefix+next.ID.String()) {
+		t.Errorf("Encrypt after rotation = %q, want key %s", newValue, next.ID)
+	}
+	if got, err := other.Decrypt(ctx, oldValue); err != nil || got != "device" {
+		t.Errorf("Decrypt with a retired key = %q, %v", got, err)
+	}
+	if got := other.BlindIndex("user@example.com"); got != index {
+		t.Errorf("BlindIndex changed across data key rotation: %s != %s", got, index)
+	}
+
+	if _, err := keyring.Decrypt(ctx, newValue); !errors.Is(err, ErrUnknownKey) {
+		t.Errorf("Decrypt with an unknown key right after loading = %v, want ErrUnknownKey", err)
+	}
+	keyring.loadedAt = time.Now().Add(-2 * reloadInterval)
+	if got, err := keyring.Decrypt(ctx, newValue); err != nil || got != "device" {
+		t.Errorf("Decrypt after reload = %q, %v", got, err)
+	}
+	if keyring.ActiveKeyID() != next.ID {
+		t.Errorf("ActiveKeyID after reload = %s, want %s", keyring.ActiveKeyID(), next.ID)
+	}
+}
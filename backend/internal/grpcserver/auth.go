@@ -0,0 +1,156 @@
+package grpcserver
+
+import (
+	"context"
+	"net"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	livlogv1 "github.com/avalarin/livlog/backend/proto/livlog/v1"
+
+	"github.com/avalarin/livlog/backend/internal/middleware"
+	"github.com/avalarin/livlog/backend/internal/repository"
+	"github.com/avalarin/livlog/backend/internal/service"
+)
+
+// maxUserAgentLength caps the user-agent stored with refresh tokens, like
+// the REST API does.
+const maxUserAgentLength = 200
+
+type authServer struct {
+	livlogv1.UnimplementedAuthServiceServer
+	authService      *service.AuthService
+	emailAuthService *service.EmailAuthService
+}
+
+func (s *authServer) SignInWithApple(ctx context.Context, req *livlogv1.SignInWithAppleRequest) (*livlogv1.AuthResponse, error) {
+	if req.GetIdentityToken() == "" {
+		return nil, status.Error(codes.InvalidArgument, "identity_token is required")
+	}
+
+	appleReq := &service.AppleAuthRequest{
+		IdentityToken:     req.GetIdentityToken(),
+		AuthorizationCode: req.AuthorizationCode,
+		Email:             req.Email,
+	}
+	if req.GivenName != nil || req.FamilyName != nil {
+		appleReq.FullName = &service.PersonNameComponents{
+			GivenName:  req.GivenName,
+			FamilyName: req.FamilyName,
+		}
+	}
+
+	resp, err := s.authService.AuthenticateWithApple(ctx, appleReq, tokenClient(ctx))
+	if err != nil {
+		return nil, toStatus(err, "Failed to authenticate")
+	}
+	return mapAuthResponse(resp), nil
+}
+
+func (s *authServer) SendVerificationCode(ctx context.Context, req *livlogv1.SendVerificationCodeRequest) (*livlogv1.SendVerificationCodeResponse, error) {
+	if req.GetEmail() == "" {
+		return nil, status.Error(codes.InvalidArgument, "email is required")
+	}
+
+	if err := s.emailAuthService.SendVerificationCode(ctx, req.GetEmail()); err != nil {
+		return nil, toStatus(err, "Failed to send verification code")
+	}
+	return &livlogv1.SendVerificationCodeResponse{
+		ExpiresIn: int32(service.VerificationCodeExpiry.Seconds()),
+	}, nil
+}
+
+func (s *authServer) VerifyEmailCode(ctx context.Context, req *livlogv1.VerifyEmailCodeRequest) (*livlogv1.AuthResponse, error) {
+	if req.GetEmail() == "" || req.GetCode() == "" {
+		return nil, status.Error(codes.InvalidArgument, "email and code are required")
+	}
+
+	resp, err := s.emailAuthService.VerifyCode(ctx, req.GetEmail(), req.GetCode(), tokenClient(ctx))
+	if err != nil {
+		return nil, toStatus(err, "Failed to verify code")
+	}
+	return mapAuthResponse(resp), nil
+}
+
+func (s *authServer) RefreshToken(ctx context.Context, req *livlogv1.RefreshTokenRequest) (*livlogv1.AuthResponse, error) {
+	if req.GetRefreshToken() == "" {
+		return nil, status.Error(codes.InvalidArgument, "refresh_token is required")
+	}
+
+	resp, err := s.authService.RefreshToken(ctx, req.GetRefreshToken(), tokenClient(ctx))
+	if err != nil {
+		return nil, toStatus(err, "Failed to refresh token")
+	}
+	return mapAuthResponse(resp), nil
+}
+
+func (s *authServer) Logout(ctx context.Context, req *livlogv1.LogoutRequest) (*livlogv1.LogoutResponse, error) {
+	if req.GetRefreshToken() == "" {
+		return nil, status.Error(codes.InvalidArgument, "refresh_token is required")
+	}
+
+	if err := s.authService.Logout(ctx, req.GetRefreshToken()); err != nil {
+		return nil, toStatus(err, "Failed to logout")
+	}
+	return &livlogv1.LogoutResponse{}, nil
+}
+
+func (s *authServer) GetMe(ctx context.Context, _ *livlogv1.GetMeRequest) (*livlogv1.User, error) {
+	user, err := s.authService.GetUserByID(ctx, middleware.GetUserIDFromContext(ctx))
+	if err != nil {
+		return nil, toStatus(err, "Failed to get user")
+	}
+	return mapUser(user), nil
+}
+
+// tokenClient returns the client a sign-in or token refresh came from: its
+// user-agent metadata and peer address. Devices aren't tracked over gRPC.
+func tokenClient(ctx context.Context) repository.TokenClient {
+	var client repository.TokenClient
+
+	md, _ := metadata.FromIncomingContext(ctx)
+	if values := md.Get("user-agent"); len(values) > 0 {
+		userAgent := []rune(values[0])
+		if len(userAgent) > maxUserAgentLength {
+			userAgent = userAgent[:maxUserAgentLength]
+		}
+		client.UserAgent = string(userAgent)
+	}
+
+	if p, ok := peer.FromContext(ctx); ok && p.Addr != nil {
+		if host, _, err := net.SplitHostPort(p.Addr.String()); err == nil {
+			client.IP = host
+		}
+	}
+
+	return client
+}
+
+func mapAuthResponse(resp *service.AuthResponse) *livlogv1.AuthResponse {
+	return &livlogv1.AuthResponse{
+		AccessToken:  resp.AccessToken,
+		RefreshToken: resp.RefreshToken,
+		ExpiresIn:    int32(resp.ExpiresIn),
+		User:         mapUser(resp.User),
+	}
+}
+
+func mapUser(u *service.User) *livlogv1.User {
+	if u == nil {
+		return nil
+	}
+
+	user := &livlogv1.User{
+		Id:            u.ID,
+		Email:         u.Email,
+		EmailVerified: u.EmailVerified,
+		DisplayName:   u.DisplayName,
+		AuthProviders: u.AuthProviders,
+	}
+	user.CreatedAt = timestamppb.New(u.CreatedAt)
+	return user
+}
@@ -0,0 +1,109 @@
+package grpcserver
+
+import (
+	"context"
+
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	livlogv1 "github.com/avalarin/livlog/backend/proto/livlog/v1"
+
+	"github.com/avalarin/livlog/backend/internal/repository"
+	"github.com/avalarin/livlog/backend/internal/service"
+)
+
+type collectionServer struct {
+	livlogv1.UnimplementedCollectionServiceServer
+	collectionService *service.CollectionService
+}
+
+func (s *collectionServer) ListCollections(ctx context.Context, _ *livlogv1.ListCollectionsRequest) (*livlogv1.ListCollectionsResponse, error) {
+	uid, err := userIDFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	collections, err := s.collectionService.GetCollectionsByUserID(ctx, uid)
+	if err != nil {
+		return nil, toStatus(err, "Failed to get collections")
+	}
+
+	resp := &livlogv1.ListCollectionsResponse{Collections: make([]*livlogv1.Collection, len(collections))}
+	for i, c := range collections {
+		resp.Collections[i] = mapCollection(c)
+	}
+	return resp, nil
+}
+
+func (s *collectionServer) GetCollection(ctx context.Context, req *livlogv1.GetCollectionRequest) (*livlogv1.Collection, error) {
+	uid, err := userIDFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	cid, err := parseID(req.GetId(), "collection ID")
+	if err != nil {
+		return nil, err
+	}
+
+	collection, err := s.collectionService.GetCollectionByID(ctx, cid, uid)
+	if err != nil {
+		return nil, toStatus(err, "Failed to get collection")
+	}
+	return mapCollection(collection), nil
+}
+
+func (s *collectionServer) CreateCollection(ctx context.Context, req *livlogv1.CreateCollectionRequest) (*livlogv1.Collection, error) {
+	uid, err := userIDFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	collection, err := s.collectionService.CreateCollection(ctx, uid, req.GetName(), service.CollectionIcon{Emoji: req.GetIcon()}, nil)
+	if err != nil {
+		return nil, toStatus(err, "Failed to create collection")
+	}
+	return mapCollection(collection), nil
+}
+
+func (s *collectionServer) UpdateCollection(ctx context.Context, req *livlogv1.UpdateCollectionRequest) (*livlogv1.Collection, error) {
+	uid, err := userIDFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	cid, err := parseID(req.GetId(), "collection ID")
+	if err != nil {
+		return nil, err
+	}
+
+	collection, err := s.collectionService.UpdateCollection(ctx, cid, uid, req.GetName(), service.CollectionIcon{Emoji: req.GetIcon()}, nil)
+	if err != nil {
+		return nil, toStatus(err, "Failed to update collection")
+	}
+	return mapCollection(collection), nil
+}
+
+func (s *collectionServer) DeleteCollection(ctx context.Context, req *livlogv1.DeleteCollectionRequest) (*livlogv1.DeleteCollectionResponse, error) {
+	uid, err := userIDFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	cid, err := parseID(req.GetId(), "collection ID")
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.collectionService.DeleteCollection(ctx, cid, uid); err != nil {
+		return nil, toStatus(err, "Failed to delete collection")
+	}
+	return &livlogv1.DeleteCollectionResponse{}, nil
+}
+
+func mapCollection(c *repository.Collection) *livlogv1.Collection {
+	return &livlogv1.Collection{
+		Id:         c.ID.String(),
+		Name:       c.Name,
+		Icon:       c.Icon,
+		EntryCount: int32(c.EntryCount),
+		CreatedAt:  timestamppb.New(c.CreatedAt),
+		UpdatedAt:  timestamppb.New(c.UpdatedAt),
+	}
+}
@@ -0,0 +1,257 @@
+package grpcserver
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	livlogv1 "github.com/avalarin/livlog/backend/proto/livlog/v1"
+
+	"github.com/avalarin/livlog/backend/internal/repository"
+	"github.com/avalarin/livlog/backend/internal/service"
+)
+
+const dateLayout = "2006-01-02"
+
+type entryServer struct {
+	livlogv1.UnimplementedEntryServiceServer
+	entryService *service.EntryService
+}
+
+func (s *entryServer) ListEntries(ctx context.Context, req *livlogv1.ListEntriesRequest) (*livlogv1.ListEntriesResponse, error) {
+	uid, err := userIDFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	collectionID, err := parseOptionalID(req.CollectionId, "collection ID")
+	if err != nil {
+		return nil, err
+	}
+
+	entries, err := s.entryService.ListEntriesWithImages(ctx, uid, repository.EntryFilter{CollectionID: collectionID}, int(req.GetLimit()), int(req.GetOffset()))
+	if err != nil {
+		return nil, toStatus(err, "Failed to get entries")
+	}
+
+	resp := &livlogv1.ListEntriesResponse{Entries: make([]*livlogv1.Entry, len(entries))}
+	for i, e := range entries {
+		resp.Entries[i] = mapEntry(e.Entry, e.Images)
+	}
+	return resp, nil
+}
+
+func (s *entryServer) SearchEntries(ctx context.Context, req *livlogv1.SearchEntriesRequest) (*livlogv1.ListEntriesResponse, error) {
+	uid, err := userIDFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	entries, err := s.entryService.SearchEntries(ctx, uid, req.GetQuery(), int(req.GetLimit()), int(req.GetOffset()))
+	if err != nil {
+		return nil, toStatus(err, "Failed to search entries")
+	}
+
+	entryIDs := make([]uuid.UUID, len(entries))
+	for i, e := range entries {
+		entryIDs[i] = e.ID
+	}
+	imageMetas, err := s.entryService.GetImageMetasByEntryIDs(ctx, entryIDs, false)
+	if err != nil {
+		return nil, toStatus(err, "Failed to get image metadata")
+	}
+
+	resp := &livlogv1.ListEntriesResponse{Entries: make([]*livlogv1.Entry, len(entries))}
+	for i, e := range entries {
+		resp.Entries[i] = mapEntry(e, imageMetas[e.ID])
+	}
+	return resp, nil
+}
+
+func (s *entryServer) GetEntry(ctx context.Context, req *livlogv1.GetEntryRequest) (*livlogv1.Entry, error) {
+	uid, err := userIDFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	eid, err := parseID(req.GetId(), "entry ID")
+	if err != nil {
+		return nil, err
+	}
+
+	entry, err := s.entryService.GetEntryByID(ctx, eid, uid)
+	if err != nil {
+		return nil, toStatus(err, "Failed to get entry")
+	}
+	return s.withImages(ctx, entry), nil
+}
+
+func (s *entryServer) CreateEntry(ctx context.Context, req *livlogv1.CreateEntryRequest) (*livlogv1.Entry, error) {
+	uid, err := userIDFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	collectionID, err := parseOptionalID(req.CollectionId, "collection ID")
+	if err != nil {
+		return nil, err
+	}
+	typeID, err := parseOptionalID(req.TypeId, "type ID")
+	if err != nil {
+		return nil, err
+	}
+	date, err := parseDate(req.GetDate())
+	if err != nil {
+		return nil, err
+	}
+
+	seedImageIDs := make([]uuid.UUID, 0, len(req.GetSeedImageIds()))
+	for _, idStr := range req.GetSeedImageIds() {
+		sid, err := parseID(idStr, "seed image ID")
+		if err != nil {
+			return nil, err
+		}
+		seedImageIDs = append(seedImageIDs, sid)
+	}
+
+	entry, err := s.entryService.CreateEntry(
+		ctx,
+		uid,
+		collectionID,
+		typeID,
+		req.GetTitle(),
+		req.GetDescription(),
+		"", "", // the gRPC API doesn't carry the original title and language yet
+		int(req.GetScore()),
+		0,  // the gRPC API doesn't carry priority yet
+		"", // nor visibility, so entries are private
+		date,
+		req.GetAdditionalFields(),
+		mapImageUploads(req.GetImages()),
+		seedImageIDs,
+		nil, // gRPC clients don't identify their device
+	)
+	if err != nil {
+		return nil, toStatus(err, "Failed to create entry")
+	}
+	return s.withImages(ctx, entry), nil
+}
+
+func (s *entryServer) UpdateEntry(ctx context.Context, req *livlogv1.UpdateEntryRequest) (*livlogv1.Entry, error) {
+	uid, err := userIDFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	eid, err := parseID(req.GetId(), "entry ID")
+	if err != nil {
+		return nil, err
+	}
+	collectionID, err := parseOptionalID(req.CollectionId, "collection ID")
+	if err != nil {
+		return nil, err
+	}
+	typeID, err := parseOptionalID(req.TypeId, "type ID")
+	if err != nil {
+		return nil, err
+	}
+	date, err := parseDate(req.GetDate())
+	if err != nil {
+		return nil, err
+	}
+
+	// nil images keep the current ones
+	var images []repository.EntryImage
+	if req.GetReplaceImages() {
+		images = mapImageUploads(req.GetImages())
+	}
+
+	entry, _, err := s.entryService.UpdateEntry(
+		ctx,
+		eid,
+		uid,
+		collectionID,
+		typeID,
+		req.GetTitle(),
+		req.GetDescription(),
+		nil, nil, // keep the original title and language set through the REST API
+		int(req.GetScore()),
+		nil, // keep the priority set through the REST API
+		nil, // and the visibility
+		date,
+		req.GetAdditionalFields(),
+		images,
+		nil,
+	)
+	if err != nil {
+		return nil, toStatus(err, "Failed to update entry")
+	}
+	return s.withImages(ctx, entry), nil
+}
+
+func (s *entryServer) DeleteEntry(ctx context.Context, req *livlogv1.DeleteEntryRequest) (*livlogv1.DeleteEntryResponse, error) {
+	uid, err := userIDFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	eid, err := parseID(req.GetId(), "entry ID")
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.entryService.DeleteEntry(ctx, eid, uid); err != nil {
+		return nil, toStatus(err, "Failed to delete entry")
+	}
+	return &livlogv1.DeleteEntryResponse{}, nil
+}
+
+func (s *entryServer) withImages(ctx context.Context, entry *repository.Entry) *livlogv1.Entry {
+	imageMetas, _ := s.entryService.GetEntryImageMetas(ctx, entry.ID)
+	return mapEntry(entry, imageMetas)
+}
+
+func parseDate(date string) (time.Time, error) {
+	t, err := time.Parse(dateLayout, date)
+	if err != nil {
+		return time.Time{}, status.Error(codes.InvalidArgument, "Invalid date format (use YYYY-MM-DD)")
+	}
+	return t, nil
+}
+
+func mapImageUploads(uploads []*livlogv1.ImageUpload) []repository.EntryImage {
+	images := make([]repository.EntryImage, len(uploads))
+	for i, img := range uploads {
+		images[i] = repository.EntryImage{
+			ImageData: img.GetData(),
+			IsCover:   img.GetIsCover(),
+			Position:  int(img.GetPosition()),
+		}
+	}
+	return images
+}
+
+func mapEntry(e *repository.Entry, imageMetas []repository.ImageMeta) *livlogv1.Entry {
+	entry := &livlogv1.Entry{
+		Id:               e.ID.String(),
+		Title:            e.Title,
+		Description:      e.Description,
+		Score:            int32(e.Score),
+		Date:             e.Date.Format(dateLayout),
+		AdditionalFields: e.AdditionalFields,
+		Images:           make([]*livlogv1.ImageMeta, len(imageMetas)),
+		CreatedAt:        timestamppb.New(e.CreatedAt),
+		UpdatedAt:        timestamppb.New(e.UpdatedAt),
+	}
+	if e.CollectionID != nil {
+		id := e.CollectionID.String()
+		entry.CollectionId = &id
+	}
+	if e.TypeID != nil {
+		id := e.TypeID.String()
+		entry.TypeId = &id
+	}
+	for i, m := range imageMetas {
+		entry.Images[i] = &livlogv1.ImageMeta{Id: m.ID.String(), IsCover: m.IsCover, Position: int32(m.Position)}
+	}
+	return entry
+}
@@ -0,0 +1,60 @@
+package grpcserver
+
+import (
+	"errors"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/avalarin/livlog/backend/internal/repository"
+	"github.com/avalarin/livlog/backend/internal/service"
+)
+
+// toStatus maps service and repository errors to gRPC status codes,
+// matching the HTTP statuses the REST handlers return for the same errors.
+func toStatus(err error, fallback string) error {
+	switch {
+	case errors.Is(err, repository.ErrEntryNotFound):
+		return status.Error(codes.NotFound, "Entry not found")
+	case errors.Is(err, repository.ErrCollectionNotFound):
+		return status.Error(codes.NotFound, "Collection not found")
+	case errors.Is(err, repository.ErrUserNotFound):
+		return status.Error(codes.NotFound, "User not found")
+	case errors.Is(err, service.ErrInvalidTitle),
+		errors.Is(err, service.ErrInvalidDescription),
+		errors.Is(err, service.ErrInvalidScore),
+		errors.Is(err, service.ErrInvalidFieldValue),
+		errors.Is(err, service.ErrInvalidPriority),
+		errors.Is(err, service.ErrInvalidVisibility),
+		errors.Is(err, service.ErrInvalidOriginalTitle),
+		errors.Is(err, service.ErrInvalidLanguage),
+		errors.Is(err, service.ErrTypeNotAllowed),
+		errors.Is(err, repository.ErrTypeNotFound),
+		errors.Is(err, service.ErrInvalidCollectionName),
+		errors.Is(err, service.ErrInvalidIcon),
+		errors.Is(err, service.ErrIconChoice),
+		errors.Is(err, service.ErrInvalidTypeName),
+		errors.Is(err, service.ErrInvalidTypeIcon),
+		errors.Is(err, service.ErrInvalidScale),
+		errors.Is(err, service.ErrInvalidEmail):
+		return status.Error(codes.InvalidArgument, err.Error())
+	case errors.Is(err, service.ErrInvalidToken),
+		errors.Is(err, service.ErrInvalidIssuer),
+		errors.Is(err, service.ErrInvalidAudience):
+		return status.Error(codes.Unauthenticated, "Invalid Apple token")
+	case errors.Is(err, service.ErrInvalidCredentials):
+		return status.Error(codes.Unauthenticated, "Invalid refresh token")
+	case errors.Is(err, service.ErrInvalidCode),
+		errors.Is(err, service.ErrCodeExpired),
+		errors.Is(err, service.ErrCodeAlreadyUsed):
+		return status.Error(codes.Unauthenticated, "Verification code is invalid or expired")
+	case errors.Is(err, service.ErrAccountPendingDeletion):
+		return status.Error(codes.FailedPrecondition, "Account is deleted and awaiting removal")
+	case errors.Is(err, service.ErrQuotaExceeded):
+		return status.Error(codes.ResourceExhausted, err.Error())
+	case errors.Is(err, service.ErrRateLimitExceeded):
+		return status.Error(codes.ResourceExhausted, "Please wait before requesting another code")
+	default:
+		return status.Error(codes.Internal, fallback)
+	}
+}
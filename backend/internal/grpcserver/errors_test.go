@@ -0,0 +1,35 @@
+package grpcserver
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/avalarin/livlog/backend/internal/repository"
+	"github.com/avalarin/livlog/backend/internal/service"
+)
+
+func TestToStatus(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want codes.Code
+	}{
+		{"not found", repository.ErrEntryNotFound, codes.NotFound},
+		{"invalid argument", service.ErrInvalidTitle, codes.InvalidArgument},
+		{"pending deletion", service.ErrAccountPendingDeletion, codes.FailedPrecondition},
+		{"wrapped pending deletion", fmt.Errorf("failed to find or create user: %w", service.ErrAccountPendingDeletion), codes.FailedPrecondition},
+		{"unknown", errors.New("boom"), codes.Internal},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := status.Code(toStatus(tt.err, "Failed")); got != tt.want {
+				t.Errorf("toStatus(%v) = %s, want %s", tt.err, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,178 @@
+package grpcserver
+
+import (
+	"context"
+	"strconv"
+	"strings"
+
+	"github.com/google/uuid"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+
+	livlogv1 "github.com/avalarin/livlog/backend/proto/livlog/v1"
+
+	"github.com/avalarin/livlog/backend/internal/apperror"
+	"github.com/avalarin/livlog/backend/internal/middleware"
+	"github.com/avalarin/livlog/backend/internal/service"
+)
+
+// publicMethods do not require an access token.
+var publicMethods = map[string]bool{
+	livlogv1.AuthService_SignInWithApple_FullMethodName:      true,
+	livlogv1.AuthService_SendVerificationCode_FullMethodName: true,
+	livlogv1.AuthService_VerifyEmailCode_FullMethodName:      true,
+	livlogv1.AuthService_RefreshToken_FullMethodName:         true,
+}
+
+// authInterceptor validates the bearer token from the "authorization"
+// metadata and stores the user ID in the context the same way AuthMiddleware
+// does for HTTP.
+func authInterceptor(jwtService *service.JWTService) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		if publicMethods[info.FullMethod] || strings.HasPrefix(info.FullMethod, "/grpc.reflection.") {
+			return handler(ctx, req)
+		}
+
+		md, _ := metadata.FromIncomingContext(ctx)
+		values := md.Get("authorization")
+		if len(values) == 0 {
+			return nil, status.Error(codes.Unauthenticated, "Authorization metadata required")
+		}
+
+		parts := strings.SplitN(values[0], " ", 2)
+		if len(parts) != 2 || parts[0] != "Bearer" {
+			return nil, status.Error(codes.Unauthenticated, "Invalid authorization metadata format")
+		}
+
+		claims, err := jwtService.ValidateAccessToken(parts[1])
+		if err != nil {
+			return nil, status.Error(codes.Unauthenticated, "Invalid or expired token")
+		}
+
+		ctx = context.WithValue(ctx, "userID", claims.UserID)
+		return handler(ctx, req)
+	}
+}
+
+// countCallsInterceptor calls record with the user of every authenticated
+// call, for their usage, like middleware.CountCalls does for HTTP. It runs
+// after authInterceptor.
+func countCallsInterceptor(record func(userID uuid.UUID)) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, _ *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		if userID, err := uuid.Parse(middleware.GetUserIDFromContext(ctx)); err == nil {
+			record(userID)
+		}
+		return handler(ctx, req)
+	}
+}
+
+// writeMethods change entries, collections or types, so their responses
+// carry the change sequence.
+var writeMethods = map[string]bool{
+	livlogv1.CollectionService_CreateCollection_FullMethodName: true,
+	livlogv1.CollectionService_UpdateCollection_FullMethodName: true,
+	livlogv1.CollectionService_DeleteCollection_FullMethodName: true,
+	livlogv1.EntryService_CreateEntry_FullMethodName:           true,
+	livlogv1.EntryService_UpdateEntry_FullMethodName:           true,
+	livlogv1.EntryService_DeleteEntry_FullMethodName:           true,
+	livlogv1.TypeService_CreateType_FullMethodName:             true,
+}
+
+// changeSeqInterceptor sets the x-change-seq header on successful
+// writeMethods calls, like middleware.ChangeSeq does for HTTP. seq is read
+// after the handler returns, once its changes are committed; a failure to
+// read it leaves the header out.
+func changeSeqInterceptor(seq func(ctx context.Context) (int64, error)) grpc.UnaryServerInterceptor {
+	header := strings.ToLower(middleware.ChangeSeqHeader)
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		resp, err := handler(ctx, req)
+		if err != nil || !writeMethods[info.FullMethod] {
+			return resp, err
+		}
+		if n, seqErr := seq(ctx); seqErr == nil {
+			_ = grpc.SetHeader(ctx, metadata.Pairs(header, strconv.FormatInt(n, 10)))
+		}
+		return resp, nil
+	}
+}
+
+// limitedMethods names the per-user limit a method shares with the REST
+// routes that do the same work.
+var limitedMethods = map[string]string{
+	livlogv1.EntryService_SearchEntries_FullMethodName: "search",
+}
+
+// rateLimitInterceptor applies the limiters to limitedMethods per user, like
+// middleware.RateLimit does for HTTP. Responses carry the ratelimit-limit,
+// ratelimit-remaining and ratelimit-reset headers; rejected calls get
+// ResourceExhausted with retry-after. It runs after authInterceptor.
+func rateLimitInterceptor(limiters map[string]service.Limiter) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		name := limitedMethods[info.FullMethod]
+		limiter, ok := limiters[name]
+		if !ok {
+			return handler(ctx, req)
+		}
+
+		decision := limiter.Take(middleware.GetUserIDFromContext(ctx))
+		if decision.Limit == 0 {
+			return handler(ctx, req)
+		}
+		service.RecordRateLimitDecision(name, "user", decision.Allowed)
+
+		limit := apperror.RateLimit{Limit: decision.Limit, Remaining: decision.Remaining, Reset: decision.Reset}
+		md := metadata.Pairs(
+			"ratelimit-limit", strconv.Itoa(limit.Limit),
+			"ratelimit-remaining", strconv.Itoa(limit.Remaining),
+			"ratelimit-reset", strconv.Itoa(limit.ResetSeconds()),
+		)
+		if !decision.Allowed {
+			md.Set("retry-after", strconv.Itoa(limit.ResetSeconds()))
+			_ = grpc.SetHeader(ctx, md)
+			return nil, status.Error(codes.ResourceExhausted, "Too many requests. Please try again later.")
+		}
+		_ = grpc.SetHeader(ctx, md)
+
+		return handler(ctx, req)
+	}
+}
+
+// recoveryInterceptor turns handler panics into Internal errors instead of
+// crashing the process, like chi's Recoverer does for HTTP.
+func recoveryInterceptor(ctx context.Context, req interface{}, _ *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (resp interface{}, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = status.Error(codes.Internal, "Internal error")
+		}
+	}()
+	return handler(ctx, req)
+}
+
+func userIDFromContext(ctx context.Context) (uuid.UUID, error) {
+	uid, err := uuid.Parse(middleware.GetUserIDFromContext(ctx))
+	if err != nil {
+		return uuid.Nil, status.Error(codes.Unauthenticated, "User not authenticated")
+	}
+	return uid, nil
+}
+
+func parseID(id, field string) (uuid.UUID, error) {
+	parsed, err := uuid.Parse(id)
+	if err != nil {
+		return uuid.Nil, status.Errorf(codes.InvalidArgument, "Invalid %s", field)
+	}
+	return parsed, nil
+}
+
+func parseOptionalID(id *string, field string) (*uuid.UUID, error) {
+	if id == nil {
+		return nil, nil
+	}
+	parsed, err := parseID(*id, field)
+	if err != nil {
+		return nil, err
+	}
+	return &parsed, nil
+}
@@ -0,0 +1,143 @@
+package grpcserver
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+
+	livlogv1 "github.com/avalarin/livlog/backend/proto/livlog/v1"
+
+	"github.com/avalarin/livlog/backend/internal/service"
+)
+
+func TestAuthInterceptor(t *testing.T) {
+	interceptor := authInterceptor(nil)
+	ok := func(ctx context.Context, req interface{}) (interface{}, error) { return "ok", nil }
+
+	tests := []struct {
+		name   string
+		method string
+		md     metadata.MD
+		want   codes.Code
+	}{
+		{"public method without token", livlogv1.AuthService_RefreshToken_FullMethodName, nil, codes.OK},
+		{"protected method without token", livlogv1.EntryService_ListEntries_FullMethodName, nil, codes.Unauthenticated},
+		{"malformed authorization", livlogv1.EntryService_ListEntries_FullMethodName, metadata.Pairs("authorization", "Token abc"), codes.Unauthenticated},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			if tt.md != nil {
+				ctx = metadata.NewIncomingContext(ctx, tt.md)
+			}
+
+			_, err := interceptor(ctx, nil, &grpc.UnaryServerInfo{FullMethod: tt.method}, ok)
+			if got := status.Code(err); got != tt.want {
+				t.Errorf("expected %s, got %s (%v)", tt.want, got, err)
+			}
+		})
+	}
+}
+
+func TestRateLimitInterceptor(t *testing.T) {
+	interceptor := rateLimitInterceptor(map[string]service.Limiter{
+		"search": service.NewWindowLimiter(1, time.Minute, service.SystemClock),
+	})
+	ok := func(ctx context.Context, req interface{}) (interface{}, error) { return "ok", nil }
+
+	call := func(userID, method string) codes.Code {
+		ctx := context.WithValue(context.Background(), "userID", userID)
+		_, err := interceptor(ctx, nil, &grpc.UnaryServerInfo{FullMethod: method}, ok)
+		return status.Code(err)
+	}
+
+	if got := call("alice", livlogv1.EntryService_SearchEntries_FullMethodName); got != codes.OK {
+		t.Fatalf("first search: expected OK, got %s", got)
+	}
+	if got := call("alice", livlogv1.EntryService_SearchEntries_FullMethodName); got != codes.ResourceExhausted {
+		t.Errorf("second search: expected ResourceExhausted, got %s", got)
+	}
+
+	// Other methods and other users aren't limited by it
+	if got := call("alice", livlogv1.EntryService_ListEntries_FullMethodName); got != codes.OK {
+		t.Errorf("list: expected OK, got %s", got)
+	}
+	if got := call("bob", livlogv1.EntryService_SearchEntries_FullMethodName); got != codes.OK {
+		t.Errorf("another user: expected OK, got %s", got)
+	}
+}
+
+func TestCountCallsInterceptor(t *testing.T) {
+	var counted []uuid.UUID
+	interceptor := countCallsInterceptor(func(userID uuid.UUID) { counted = append(counted, userID) })
+	ok := func(ctx context.Context, req interface{}) (interface{}, error) { return "ok", nil }
+	info := &grpc.UnaryServerInfo{FullMethod: livlogv1.EntryService_ListEntries_FullMethodName}
+
+	userID := uuid.New()
+	if _, err := interceptor(context.WithValue(context.Background(), "userID", userID.String()), nil, info, ok); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	// Public calls have no user to count
+	if _, err := interceptor(context.Background(), nil, info, ok); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if len(counted) != 1 || counted[0] != userID {
+		t.Errorf("counted %v, want [%s]", counted, userID)
+	}
+}
+
+// headerStream records the headers a handler sets.
+type headerStream struct {
+	header metadata.MD
+}
+
+func (s *headerStream) Method() string { return "" }
+
+func (s *headerStream) SetHeader(md metadata.MD) error {
+	s.header = metadata.Join(s.header, md)
+	return nil
+}
+
+func (s *headerStream) SendHeader(md metadata.MD) error { return s.SetHeader(md) }
+
+func (s *headerStream) SetTrailer(metadata.MD) error { return nil }
+
+func TestChangeSeqInterceptor(t *testing.T) {
+	interceptor := changeSeqInterceptor(func(context.Context) (int64, error) { return 42, nil })
+	ok := func(ctx context.Context, req interface{}) (interface{}, error) { return "ok", nil }
+	fail := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return nil, status.Error(codes.NotFound, "Entry not found")
+	}
+
+	tests := []struct {
+		name    string
+		method  string
+		handler grpc.UnaryHandler
+		want    []string
+	}{
+		{"write", livlogv1.EntryService_UpdateEntry_FullMethodName, ok, []string{"42"}},
+		{"read", livlogv1.EntryService_ListEntries_FullMethodName, ok, nil},
+		{"failed write", livlogv1.EntryService_DeleteEntry_FullMethodName, fail, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			stream := &headerStream{}
+			ctx := grpc.NewContextWithServerTransportStream(context.Background(), stream)
+
+			_, _ = interceptor(ctx, nil, &grpc.UnaryServerInfo{FullMethod: tt.method}, tt.handler)
+			got := stream.header.Get("x-change-seq")
+			if len(got) != len(tt.want) || (len(got) > 0 && got[0] != tt.want[0]) {
+				t.Errorf("x-change-seq = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,49 @@
+// Package grpcserver exposes the service layer over gRPC, alongside the REST
+// API. Message definitions live in proto/livlog/v1.
+package grpcserver
+
+import (
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/reflection"
+
+	livlogv1 "github.com/avalarin/livlog/backend/proto/livlog/v1"
+
+	"github.com/avalarin/livlog/backend/internal/service"
+)
+
+// Services groups the service-layer dependencies shared with the REST handlers.
+type Services struct {
+	JWT        *service.JWTService
+	Auth       *service.AuthService
+	EmailAuth  *service.EmailAuthService
+	Collection *service.CollectionService
+	Entry      *service.EntryService
+	Type       *service.TypeService
+	Usage      *service.UsageService
+	Sync       *service.SyncService
+
+	// Limiters are the per-user limits of expensive methods, by the name of
+	// the REST route limit they share, e.g. "search".
+	Limiters map[string]service.Limiter
+}
+
+// New creates a gRPC server with all livlog.v1 services registered.
+func New(s Services) *grpc.Server {
+	server := grpc.NewServer(
+		grpc.ChainUnaryInterceptor(
+			recoveryInterceptor,
+			authInterceptor(s.JWT),
+			countCallsInterceptor(s.Usage.RecordCall),
+			changeSeqInterceptor(s.Sync.ChangeSeq),
+			rateLimitInterceptor(s.Limiters),
+		),
+	)
+
+	livlogv1.RegisterAuthServiceServer(server, &authServer{authService: s.Auth, emailAuthService: s.EmailAuth})
+	livlogv1.RegisterCollectionServiceServer(server, &collectionServer{collectionService: s.Collection})
+	livlogv1.RegisterEntryServiceServer(server, &entryServer{entryService: s.Entry})
+	livlogv1.RegisterTypeServiceServer(server, &typeServer{typeService: s.Type})
+	reflection.Register(server)
+
+	return server
+}
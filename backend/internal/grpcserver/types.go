@@ -0,0 +1,65 @@
+package grpcserver
+
+import (
+	"context"
+
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	livlogv1 "github.com/avalarin/livlog/backend/proto/livlog/v1"
+
+	"github.com/avalarin/livlog/backend/internal/repository"
+	"github.com/avalarin/livlog/backend/internal/service"
+)
+
+type typeServer struct {
+	livlogv1.UnimplementedTypeServiceServer
+	typeService *service.TypeService
+}
+
+func (s *typeServer) ListTypes(ctx context.Context, _ *livlogv1.ListTypesRequest) (*livlogv1.ListTypesResponse, error) {
+	uid, err := userIDFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	types, err := s.typeService.GetAllTypes(ctx, uid)
+	if err != nil {
+		return nil, toStatus(err, "Failed to get types")
+	}
+
+	resp := &livlogv1.ListTypesResponse{Types: make([]*livlogv1.EntryType, len(types))}
+	for i, t := range types {
+		resp.Types[i] = mapType(t)
+	}
+	return resp, nil
+}
+
+func (s *typeServer) CreateType(ctx context.Context, req *livlogv1.CreateTypeRequest) (*livlogv1.EntryType, error) {
+	uid, err := userIDFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	t, err := s.typeService.CreateType(ctx, uid, req.GetName(), req.GetIcon(), "", nil)
+	if err != nil {
+		return nil, toStatus(err, "Failed to create type")
+	}
+	return mapType(t), nil
+}
+
+func mapType(t *repository.EntryType) *livlogv1.EntryType {
+	fields := make([]*livlogv1.FieldDefinition, len(t.Fields))
+	for i, f := range t.Fields {
+		fields[i] = &livlogv1.FieldDefinition{Key: f.Key, Label: f.Label, Type: f.Type}
+	}
+
+	return &livlogv1.EntryType{
+		Id:        t.ID.String(),
+		Name:      t.Name,
+		Icon:      t.Icon,
+		Fields:    fields,
+		System:    t.UserID == nil,
+		CreatedAt: timestamppb.New(t.CreatedAt),
+		UpdatedAt: timestamppb.New(t.UpdatedAt),
+	}
+}
@@ -0,0 +1,105 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/avalarin/livlog/backend/internal/apperror"
+	"github.com/avalarin/livlog/backend/internal/middleware"
+	"github.com/avalarin/livlog/backend/internal/repository"
+	"github.com/avalarin/livlog/backend/internal/service"
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+)
+
+type ActivityHandler struct {
+	activityService *service.ActivityService
+}
+
+func NewActivityHandler(activityService *service.ActivityService) *ActivityHandler {
+	return &ActivityHandler{
+		activityService: activityService,
+	}
+}
+
+func (h *ActivityHandler) RegisterRoutes(r chi.Router) {
+	r.Get("/activity", h.GetActivity)
+}
+
+type activityResponse struct {
+	ID        int64                             `json:"id"`
+	ActorID   string                            `json:"actor_id"`
+	Entity    string                            `json:"entity"`
+	EntityID  string                            `json:"entity_id"`
+	Op        string                            `json:"op"`
+	Changes   map[string]repository.FieldChange `json:"changes"`
+	CreatedAt string                            `json:"created_at"`
+}
+
+// GetActivity lists changes to the user's entries and collections, newest
+// first. Pass the last id as ?before= for the next page.
+func (h *ActivityHandler) GetActivity(w http.ResponseWriter, r *http.Request) {
+	userID := middleware.GetUserIDFromContext(r.Context())
+	if userID == "" {
+		respondWithError(w, r, apperror.Unauthorized("User not authenticated", nil))
+		return
+	}
+
+	uid, err := uuid.Parse(userID)
+	if err != nil {
+		respondWithError(w, r, apperror.BadRequest("Invalid user ID", err))
+		return
+	}
+
+	query := r.URL.Query()
+	filter := repository.ActivityFilter{Entity: query.Get("entity")}
+	var appErr *apperror.Error
+	if filter.Limit, appErr = limitParam(r); appErr != nil {
+		respondWithError(w, r, appErr)
+		return
+	}
+
+	if v := query.Get("entity_id"); v != "" {
+		id, err := uuid.Parse(v)
+		if err != nil {
+			respondWithError(w, r, apperror.BadRequest("Invalid entity ID", err))
+			return
+		}
+		filter.EntityID = &id
+	}
+
+	if v := query.Get("before"); v != "" {
+		filter.Before, err = strconv.ParseInt(v, 10, 64)
+		if err != nil {
+			respondWithError(w, r, apperror.BadRequest("Invalid before cursor", err))
+			return
+		}
+	}
+
+	activities, err := h.activityService.GetActivity(r.Context(), uid, filter)
+	if err != nil {
+		if errors.Is(err, service.ErrInvalidActivityEntity) {
+			respondWithError(w, r, apperror.Validation(err.Error(), err))
+			return
+		}
+		respondWithError(w, r, apperror.Internal("Failed to get activity", err))
+		return
+	}
+
+	layout := timeLayout(r)
+	response := make([]activityResponse, len(activities))
+	for i, a := range activities {
+		response[i] = activityResponse{
+			ID:        a.ID,
+			ActorID:   a.ActorID.String(),
+			Entity:    a.Entity,
+			EntityID:  a.EntityID.String(),
+			Op:        a.Op,
+			Changes:   a.Changes,
+			CreatedAt: layout.Format(a.CreatedAt),
+		}
+	}
+
+	respondWithJSON(w, http.StatusOK, response)
+}
@@ -0,0 +1,246 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/avalarin/livlog/backend/internal/apperror"
+	"github.com/avalarin/livlog/backend/internal/middleware"
+	"github.com/avalarin/livlog/backend/internal/repository"
+	"github.com/avalarin/livlog/backend/internal/service"
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+)
+
+// AdminHandler serves the internal dashboard. Mount it behind
+// middleware.RequireRole(..., repository.UserRoleAdmin). A nil backup service
+// means backups are not configured; the backup routes report it as
+// unavailable.
+type AdminHandler struct {
+	statsService     *service.StatsService
+	backupService    *service.BackupService
+	rateLimitService *service.RateLimitService
+}
+
+func NewAdminHandler(
+	statsService *service.StatsService,
+	backupService *service.BackupService,
+	rateLimitService *service.RateLimitService,
+) *AdminHandler {
+	return &AdminHandler{
+		statsService:     statsService,
+		backupService:    backupService,
+		rateLimitService: rateLimitService,
+	}
+}
+
+func (h *AdminHandler) RegisterRoutes(r chi.Router) {
+	r.Get("/admin/stats", h.GetStats)
+	r.Get("/admin/backups", h.ListBackups)
+	r.Post("/admin/backups", h.CreateBackup)
+	r.Get("/admin/rate-limits/{userID}", h.GetRateLimits)
+	r.Delete("/admin/rate-limits/{userID}", h.ResetRateLimits)
+}
+
+type adminStatsResponse struct {
+	Days             int                  `json:"days"`
+	TotalUsers       int64                `json:"total_users"`
+	DailyActiveUsers []dailyCountResponse `json:"daily_active_users"`
+	EntriesCreated   []dailyCountResponse `json:"entries_created"`
+	AISearches       []dailyCountResponse `json:"ai_searches"`
+	Storage          storageResponse      `json:"storage"`
+}
+
+type dailyCountResponse struct {
+	Date  string `json:"date"`
+	Count int64  `json:"count"`
+}
+
+type storageResponse struct {
+	DatabaseBytes int64 `json:"database_bytes"`
+	EntriesBytes  int64 `json:"entries_bytes"`
+	ImagesBytes   int64 `json:"images_bytes"`
+	Images        int64 `json:"images"`
+}
+
+func (h *AdminHandler) GetStats(w http.ResponseWriter, r *http.Request) {
+	days := 30
+	if param := r.URL.Query().Get("days"); param != "" {
+		var err error
+		days, err = strconv.Atoi(param)
+		if err != nil || days < 1 || days > 365 {
+			respondWithError(w, r, apperror.BadRequest("days must be between 1 and 365", err))
+			return
+		}
+	}
+
+	stats, err := h.statsService.AdminStats(r.Context(), days)
+	if err != nil {
+		respondWithError(w, r, apperror.Internal("Failed to get stats", err))
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, adminStatsResponse{
+		Days:             stats.Days,
+		TotalUsers:       stats.TotalUsers,
+		DailyActiveUsers: mapDailyCounts(stats.DailyActiveUsers),
+		EntriesCreated:   mapDailyCounts(stats.EntriesCreated),
+		AISearches:       mapDailyCounts(stats.AISearches),
+		Storage: storageResponse{
+			DatabaseBytes: stats.Storage.DatabaseBytes,
+			EntriesBytes:  stats.Storage.EntriesBytes,
+			ImagesBytes:   stats.Storage.ImagesBytes,
+			Images:        stats.Storage.Images,
+		},
+	})
+}
+
+func mapDailyCounts(counts []repository.DailyCount) []dailyCountResponse {
+	response := make([]dailyCountResponse, len(counts))
+	for i, c := range counts {
+		response[i] = dailyCountResponse{
+			Date:  c.Day.Format(dateLayout),
+			Count: c.Count,
+		}
+	}
+	return response
+}
+
+type backupResponse struct {
+	ID          string     `json:"id"`
+	Status      string     `json:"status"`
+	Scheduled   bool       `json:"scheduled"`
+	Key         *string    `json:"key"`
+	SizeBytes   int64      `json:"size_bytes"`
+	SHA256      *string    `json:"sha256"`
+	Error       *string    `json:"error"`
+	Verified    *bool      `json:"verified"`
+	VerifyError string     `json:"verify_error,omitempty"`
+	FinishedAt  *time.Time `json:"finished_at"`
+	CreatedAt   time.Time  `json:"created_at"`
+}
+
+func mapBackupToResponse(b *repository.Backup) backupResponse {
+	return backupResponse{
+		ID:         b.ID.String(),
+		Status:     b.Status,
+		Scheduled:  b.RequestedBy == nil,
+		Key:        b.Key,
+		SizeBytes:  b.SizeBytes,
+		SHA256:     b.SHA256,
+		Error:      b.Error,
+		FinishedAt: b.FinishedAt,
+		CreatedAt:  b.CreatedAt,
+	}
+}
+
+// ListBackups lists the latest backups and checks that the archives of the
+// finished ones are still in the store.
+func (h *AdminHandler) ListBackups(w http.ResponseWriter, r *http.Request) {
+	if h.backupService == nil {
+		respondWithError(w, r, apperror.New(apperror.CodeUnavailable, "Backups are not configured"))
+		return
+	}
+
+	backups, err := h.backupService.ListBackups(r.Context())
+	if err != nil {
+		respondWithError(w, r, apperror.Internal("Failed to list backups", err))
+		return
+	}
+
+	response := make([]backupResponse, len(backups))
+	for i, b := range backups {
+		response[i] = mapBackupToResponse(b.Backup)
+		response[i].Verified = b.Verified
+		response[i].VerifyError = b.VerifyError
+	}
+
+	respondWithJSON(w, http.StatusOK, response)
+}
+
+// CreateBackup queues a backup; the backup job takes it within a minute.
+func (h *AdminHandler) CreateBackup(w http.ResponseWriter, r *http.Request) {
+	if h.backupService == nil {
+		respondWithError(w, r, apperror.New(apperror.CodeUnavailable, "Backups are not configured"))
+		return
+	}
+
+	adminID, err := uuid.Parse(middleware.GetUserIDFromContext(r.Context()))
+	if err != nil {
+		respondWithError(w, r, apperror.Unauthorized("User not authenticated", nil))
+		return
+	}
+
+	backup, err := h.backupService.RequestBackup(r.Context(), adminID)
+	if err != nil {
+		respondWithError(w, r, apperror.Internal("Failed to queue backup", err))
+		return
+	}
+
+	respondWithJSON(w, http.StatusAccepted, mapBackupToResponse(backup))
+}
+
+type rateLimitResponse struct {
+	Name      string     `json:"name"`
+	Limit     int        `json:"limit"`
+	Remaining int        `json:"remaining"`
+	ResetsAt  *time.Time `json:"resets_at"`
+}
+
+// GetRateLimits shows the state of a user's rate limits.
+func (h *AdminHandler) GetRateLimits(w http.ResponseWriter, r *http.Request) {
+	userID, err := uuid.Parse(chi.URLParam(r, "userID"))
+	if err != nil {
+		respondWithError(w, r, apperror.BadRequest("Invalid user ID", err))
+		return
+	}
+
+	h.respondWithRateLimits(w, r, userID)
+}
+
+// ResetRateLimits refills a user's rate limits and responds with their new
+// state.
+func (h *AdminHandler) ResetRateLimits(w http.ResponseWriter, r *http.Request) {
+	userID, err := uuid.Parse(chi.URLParam(r, "userID"))
+	if err != nil {
+		respondWithError(w, r, apperror.BadRequest("Invalid user ID", err))
+		return
+	}
+
+	if err := h.rateLimitService.ResetUserRateLimits(r.Context(), userID); err != nil {
+		respondWithRateLimitError(w, r, err, "Failed to reset rate limits")
+		return
+	}
+
+	h.respondWithRateLimits(w, r, userID)
+}
+
+func (h *AdminHandler) respondWithRateLimits(w http.ResponseWriter, r *http.Request, userID uuid.UUID) {
+	limits, err := h.rateLimitService.GetUserRateLimits(r.Context(), userID)
+	if err != nil {
+		respondWithRateLimitError(w, r, err, "Failed to get rate limits")
+		return
+	}
+
+	response := make([]rateLimitResponse, len(limits))
+	for i, l := range limits {
+		response[i] = rateLimitResponse{
+			Name:      l.Name,
+			Limit:     l.Limit,
+			Remaining: l.Remaining,
+			ResetsAt:  l.ResetsAt,
+		}
+	}
+
+	respondWithJSON(w, http.StatusOK, response)
+}
+
+func respondWithRateLimitError(w http.ResponseWriter, r *http.Request, err error, message string) {
+	if errors.Is(err, repository.ErrUserNotFound) {
+		respondWithError(w, r, apperror.New(apperror.CodeUserNotFound, "User not found"))
+		return
+	}
+	respondWithError(w, r, apperror.Internal(message, err))
+}
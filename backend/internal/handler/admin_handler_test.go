@@ -0,0 +1,23 @@
+package handler
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+)
+
+func TestAdminHandler_RateLimits_InvalidUserID(t *testing.T) {
+	r := chi.NewRouter()
+	(&AdminHandler{}).RegisterRoutes(r)
+
+	for _, method := range []string{http.MethodGet, http.MethodDelete} {
+		rec := httptest.NewRecorder()
+		r.ServeHTTP(rec, httptest.NewRequest(method, "/admin/rate-limits/not-a-uuid", nil))
+
+		if rec.Code != http.StatusBadRequest {
+			t.Errorf("%s: expected status 400, got %d", method, rec.Code)
+		}
+	}
+}
@@ -1,15 +1,18 @@
 package handler
 
 import (
-	"encoding/json"
 	"errors"
 	"net/http"
 
+	"github.com/avalarin/livlog/backend/internal/apperror"
+	"github.com/avalarin/livlog/backend/internal/middleware"
 	"github.com/avalarin/livlog/backend/internal/service"
 	"github.com/go-chi/chi/v5"
 	"github.com/google/uuid"
 )
 
+// AISearchHandler serves AI search. A nil service means AI search is not
+// configured; the route stays mounted and reports it as unavailable.
 type AISearchHandler struct {
 	aiSearchService *service.AISearchService
 }
@@ -25,61 +28,61 @@ func (h *AISearchHandler) RegisterRoutes(r chi.Router) {
 }
 
 type searchRequest struct {
-	Query string `json:"query"`
+	Query string `json:"query" validate:"required"`
+	Type  string `json:"type" validate:"omitempty,oneof=movie book game custom"` // optional hint
 }
 
+// searchResponse carries where the options came from: "ai", or "metadata"
+// while the daily AI budget is used up.
 type searchResponse struct {
 	Options []service.SearchOption `json:"options"`
+	Source  string                 `json:"source"`
 }
 
 func (h *AISearchHandler) Search(w http.ResponseWriter, r *http.Request) {
-	userID := getUserIDFromContext(r.Context())
+	userID := middleware.GetUserIDFromContext(r.Context())
 	if userID == "" {
-		respondWithError(w, http.StatusUnauthorized, "User not authenticated", nil)
+		respondWithError(w, r, apperror.Unauthorized("User not authenticated", nil))
 		return
 	}
 
-	uid, err := uuid.Parse(userID)
-	if err != nil {
-		respondWithError(w, http.StatusBadRequest, "Invalid user ID", err)
+	if h.aiSearchService == nil {
+		respondWithError(w, r, apperror.New(apperror.CodeUnavailable, "AI search is not available"))
 		return
 	}
 
-	var req searchRequest
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		respondWithError(w, http.StatusBadRequest, "Invalid request body", err)
+	uid, err := uuid.Parse(userID)
+	if err != nil {
+		respondWithError(w, r, apperror.BadRequest("Invalid user ID", err))
 		return
 	}
 
-	if req.Query == "" {
-		respondWithError(w, http.StatusBadRequest, "Query is required", nil)
+	var req searchRequest
+	if appErr := decodeAndValidate(r, &req); appErr != nil {
+		respondWithError(w, r, appErr)
 		return
 	}
 
-	options, err := h.aiSearchService.SearchOptions(r.Context(), uid, req.Query)
+	result, err := h.aiSearchService.SearchOptions(r.Context(), uid, req.Query, req.Type)
 	if err != nil {
 		if errors.Is(err, service.ErrAISearchRateLimitExceeded) {
-			// Return 429 rate limit error according to API spec
-			w.Header().Set("Content-Type", "application/json")
-			w.WriteHeader(http.StatusTooManyRequests)
-
-			errorResp := map[string]interface{}{
-				"error": map[string]interface{}{
-					"code":    "RATE_LIMIT_EXCEEDED",
-					"message": "Too many AI search requests. Please try again later.",
-					"details": map[string]interface{}{
-						"retryAfter": 86400, // 24 hours in seconds
-					},
-				},
-			}
-
-			json.NewEncoder(w).Encode(errorResp)
+			respondWithError(w, r, rateLimitError(err, "Too many AI search requests. Please try again later."))
+			return
+		}
+		var consentErr *service.AIConsentError
+		if errors.As(err, &consentErr) {
+			respondWithError(w, r, apperror.Wrap(err, apperror.CodeAIConsentRequired, "AI consent required").
+				WithDetails(map[string]interface{}{"consent": consentErr.Consent}))
 			return
 		}
 
-		respondWithError(w, http.StatusInternalServerError, "Failed to perform search", err)
+		respondWithError(w, r, apperror.Internal("Failed to perform search", err))
 		return
 	}
 
-	respondWithJSON(w, http.StatusOK, searchResponse{Options: options})
+	response := searchResponse{Options: result.Options, Source: "ai"}
+	if result.Degraded {
+		response.Source = "metadata"
+	}
+	respondWithJSON(w, http.StatusOK, response)
 }
@@ -1,24 +1,49 @@
 package handler
 
 import (
-	"context"
 	"encoding/json"
 	"errors"
 	"net/http"
-
+	"time"
+
+	"github.com/avalarin/livlog/backend/internal/apitime"
+	"github.com/avalarin/livlog/backend/internal/apperror"
+	"github.com/avalarin/livlog/backend/internal/errortracking"
+	"github.com/avalarin/livlog/backend/internal/logger"
+	"github.com/avalarin/livlog/backend/internal/middleware"
+	"github.com/avalarin/livlog/backend/internal/repository"
 	"github.com/avalarin/livlog/backend/internal/service"
 	"github.com/go-chi/chi/v5"
+	chimw "github.com/go-chi/chi/v5/middleware"
+	"github.com/google/uuid"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"go.uber.org/zap"
+)
+
+var httpErrorsTotal = promauto.NewCounterVec(
+	prometheus.CounterOpts{
+		Name: "http_errors_total",
+		Help: "Total number of API error responses by error code",
+	},
+	[]string{"method", "path", "code"},
 )
 
 type AuthHandler struct {
 	authService      *service.AuthService
 	emailAuthService *service.EmailAuthService
+	aiSearchService  *service.AISearchService // nil when AI search is disabled
 }
 
-func NewAuthHandler(authService *service.AuthService, emailAuthService *service.EmailAuthService) *AuthHandler {
+func NewAuthHandler(
+	authService *service.AuthService,
+	emailAuthService *service.EmailAuthService,
+	aiSearchService *service.AISearchService,
+) *AuthHandler {
 	return &AuthHandler{
 		authService:      authService,
 		emailAuthService: emailAuthService,
+		aiSearchService:  aiSearchService,
 	}
 }
 
@@ -30,78 +55,144 @@ func (h *AuthHandler) RegisterRoutes(r chi.Router) {
 	r.Post("/auth/refresh", h.RefreshToken)
 	r.Post("/auth/logout", h.Logout)
 	r.Get("/auth/me", h.GetMe)
+	r.Get("/auth/me/overview", h.GetMeOverview)
+	r.Put("/auth/me/ai-consent", h.SetAIConsent)
+	r.Put("/auth/me/settings", h.SetSettings)
+	r.Post("/auth/me/email/send-code", h.SendUserEmailCode)
+	r.Post("/auth/me/email/verify", h.VerifyUserEmail)
+	r.Get("/auth/me/email/events", h.GetEmailEvents)
 	r.Delete("/auth/account", h.DeleteAccount)
 }
 
+type authResponse struct {
+	AccessToken  string        `json:"access_token"`
+	RefreshToken string        `json:"refresh_token"`
+	ExpiresIn    int           `json:"expires_in"`
+	User         *userResponse `json:"user"`
+}
+
+type userResponse struct {
+	ID             string            `json:"id"`
+	Email          *string           `json:"email,omitempty"`
+	EmailVerified  bool              `json:"email_verified"`
+	IsPrivateEmail bool              `json:"is_private_email"` // Apple private relay address
+	DisplayName    *string           `json:"display_name,omitempty"`
+	AuthProviders  []string          `json:"auth_providers"`
+	AIConsent      aiConsentResponse `json:"ai_consent"`
+	Settings       userSettings      `json:"settings"`
+	CreatedAt      string            `json:"created_at"`
+	UpdatedAt      string            `json:"updated_at"`
+}
+
+func mapAuthToResponse(resp *service.AuthResponse, layout apitime.Layout) authResponse {
+	return authResponse{
+		AccessToken:  resp.AccessToken,
+		RefreshToken: resp.RefreshToken,
+		ExpiresIn:    resp.ExpiresIn,
+		User:         mapUserToResponse(resp.User, layout),
+	}
+}
+
+func mapUserToResponse(u *service.User, layout apitime.Layout) *userResponse {
+	return &userResponse{
+		ID:             u.ID,
+		Email:          u.Email,
+		EmailVerified:  u.EmailVerified,
+		IsPrivateEmail: u.IsPrivateEmail,
+		DisplayName:    u.DisplayName,
+		AuthProviders:  u.AuthProviders,
+		AIConsent:      aiConsentResponse{Queries: u.AIConsent.Queries, Enrichment: u.AIConsent.Enrichment},
+		Settings:       userSettings{AutoAssignCollection: &u.Settings.AutoAssignCollection},
+		CreatedAt:      layout.Format(u.CreatedAt),
+		UpdatedAt:      layout.Format(u.UpdatedAt),
+	}
+}
+
 func (h *AuthHandler) AppleAuth(w http.ResponseWriter, r *http.Request) {
 	var req service.AppleAuthRequest
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		respondWithError(w, http.StatusBadRequest, "Invalid request body", err)
+	if appErr := decodeAndValidate(r, &req); appErr != nil {
+		respondWithError(w, r, appErr)
 		return
 	}
 
-	authResp, err := h.authService.AuthenticateWithApple(r.Context(), &req)
+	authResp, err := h.authService.AuthenticateWithApple(r.Context(), &req, requestTokenClient(r))
 	if err != nil {
 		if errors.Is(err, service.ErrInvalidToken) ||
 			errors.Is(err, service.ErrInvalidIssuer) ||
 			errors.Is(err, service.ErrInvalidAudience) {
-			respondWithError(w, http.StatusUnauthorized, "Invalid Apple token", err)
+			respondWithError(w, r, apperror.Wrap(err, apperror.CodeInvalidAppleToken, "Invalid Apple token"))
+			return
+		}
+		if errors.Is(err, service.ErrAccountPendingDeletion) {
+			respondWithError(w, r, apperror.Wrap(err, apperror.CodeAccountPendingDeletion, "Account is deleted and awaiting removal"))
 			return
 		}
-		respondWithError(w, http.StatusInternalServerError, "Failed to authenticate", err)
+		respondWithError(w, r, apperror.Internal("Failed to authenticate", err))
 		return
 	}
 
-	respondWithJSON(w, http.StatusOK, authResp)
+	respondWithJSON(w, http.StatusOK, mapAuthToResponse(authResp, timeLayout(r)))
 }
 
 type refreshTokenRequest struct {
-	RefreshToken string `json:"refresh_token"`
+	RefreshToken string `json:"refresh_token" validate:"required"`
 }
 
 func (h *AuthHandler) RefreshToken(w http.ResponseWriter, r *http.Request) {
 	var req refreshTokenRequest
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		respondWithError(w, http.StatusBadRequest, "Invalid request body", err)
+	if appErr := decodeAndValidate(r, &req); appErr != nil {
+		respondWithError(w, r, appErr)
 		return
 	}
 
-	if req.RefreshToken == "" {
-		respondWithError(w, http.StatusBadRequest, "Refresh token is required", nil)
-		return
-	}
-
-	authResp, err := h.authService.RefreshToken(r.Context(), req.RefreshToken)
+	authResp, err := h.authService.RefreshToken(r.Context(), req.RefreshToken, requestTokenClient(r))
 	if err != nil {
 		if errors.Is(err, service.ErrInvalidCredentials) {
-			respondWithError(w, http.StatusUnauthorized, "Invalid refresh token", err)
+			respondWithError(w, r, apperror.Wrap(err, apperror.CodeInvalidRefreshToken, "Invalid refresh token"))
 			return
 		}
-		respondWithError(w, http.StatusInternalServerError, "Failed to refresh token", err)
+		respondWithError(w, r, apperror.Internal("Failed to refresh token", err))
 		return
 	}
 
-	respondWithJSON(w, http.StatusOK, authResp)
+	respondWithJSON(w, http.StatusOK, mapAuthToResponse(authResp, timeLayout(r)))
 }
 
-type logoutRequest struct {
-	RefreshToken string `json:"refresh_token"`
+type introspectRequest struct {
+	Token string `json:"token" validate:"required"`
 }
 
-func (h *AuthHandler) Logout(w http.ResponseWriter, r *http.Request) {
-	var req logoutRequest
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		respondWithError(w, http.StatusBadRequest, "Invalid request body", err)
+// Introspect tells internal services whether an access token is active and
+// whom it belongs to. It is mounted behind middleware.ServiceAuth.
+func (h *AuthHandler) Introspect(w http.ResponseWriter, r *http.Request) {
+	var req introspectRequest
+	if appErr := decodeAndValidate(r, &req); appErr != nil {
+		respondWithError(w, r, appErr)
+		return
+	}
+
+	result, err := h.authService.IntrospectAccessToken(r.Context(), req.Token)
+	if err != nil {
+		respondWithError(w, r, apperror.Internal("Failed to introspect token", err))
 		return
 	}
 
-	if req.RefreshToken == "" {
-		respondWithError(w, http.StatusBadRequest, "Refresh token is required", nil)
+	respondWithJSON(w, http.StatusOK, result)
+}
+
+type logoutRequest struct {
+	RefreshToken string `json:"refresh_token" validate:"required"`
+}
+
+func (h *AuthHandler) Logout(w http.ResponseWriter, r *http.Request) {
+	var req logoutRequest
+	if appErr := decodeAndValidate(r, &req); appErr != nil {
+		respondWithError(w, r, appErr)
 		return
 	}
 
 	if err := h.authService.Logout(r.Context(), req.RefreshToken); err != nil {
-		respondWithError(w, http.StatusInternalServerError, "Failed to logout", err)
+		respondWithError(w, r, apperror.Internal("Failed to logout", err))
 		return
 	}
 
@@ -109,30 +200,163 @@ func (h *AuthHandler) Logout(w http.ResponseWriter, r *http.Request) {
 }
 
 func (h *AuthHandler) GetMe(w http.ResponseWriter, r *http.Request) {
-	userID := getUserIDFromContext(r.Context())
+	userID := middleware.GetUserIDFromContext(r.Context())
 	if userID == "" {
-		respondWithError(w, http.StatusUnauthorized, "User not authenticated", nil)
+		respondWithError(w, r, apperror.Unauthorized("User not authenticated", nil))
 		return
 	}
 
 	user, err := h.authService.GetUserByID(r.Context(), userID)
 	if err != nil {
-		respondWithError(w, http.StatusInternalServerError, "Failed to get user", err)
+		respondWithError(w, r, apperror.Internal("Failed to get user", err))
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, mapUserToResponse(user, timeLayout(r)))
+}
+
+type aiConsentResponse struct {
+	Queries    bool `json:"queries"`
+	Enrichment bool `json:"enrichment"`
+}
+
+type aiConsentRequest struct {
+	Queries    *bool `json:"queries" validate:"required"`
+	Enrichment *bool `json:"enrichment" validate:"required"`
+}
+
+// SetAIConsent records the user's answers on the app's AI consent sheet.
+// Both are sent every time; false withdraws a consent given before.
+func (h *AuthHandler) SetAIConsent(w http.ResponseWriter, r *http.Request) {
+	userID := middleware.GetUserIDFromContext(r.Context())
+	if userID == "" {
+		respondWithError(w, r, apperror.Unauthorized("User not authenticated", nil))
+		return
+	}
+
+	var req aiConsentRequest
+	if appErr := decodeAndValidate(r, &req); appErr != nil {
+		respondWithError(w, r, appErr)
+		return
+	}
+
+	user, err := h.authService.SetAIConsent(r.Context(), userID, service.AIConsent{
+		Queries:    *req.Queries,
+		Enrichment: *req.Enrichment,
+	})
+	if err != nil {
+		respondWithError(w, r, apperror.Internal("Failed to set AI consent", err))
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, mapUserToResponse(user, timeLayout(r)))
+}
+
+// userSettings is both the settings in a user response and the body of
+// PUT /auth/me/settings, which replaces all of them.
+type userSettings struct {
+	AutoAssignCollection *bool `json:"auto_assign_collection" validate:"required"`
+}
+
+func (h *AuthHandler) SetSettings(w http.ResponseWriter, r *http.Request) {
+	userID := middleware.GetUserIDFromContext(r.Context())
+	if userID == "" {
+		respondWithError(w, r, apperror.Unauthorized("User not authenticated", nil))
+		return
+	}
+
+	var req userSettings
+	if appErr := decodeAndValidate(r, &req); appErr != nil {
+		respondWithError(w, r, appErr)
+		return
+	}
+
+	user, err := h.authService.SetSettings(r.Context(), userID, service.UserSettings{
+		AutoAssignCollection: *req.AutoAssignCollection,
+	})
+	if err != nil {
+		respondWithError(w, r, apperror.Internal("Failed to set settings", err))
 		return
 	}
 
-	respondWithJSON(w, http.StatusOK, user)
+	respondWithJSON(w, http.StatusOK, mapUserToResponse(user, timeLayout(r)))
+}
+
+type meOverviewResponse struct {
+	User         *userResponse       `json:"user"`
+	Collections  int64               `json:"collections"`
+	Entries      int64               `json:"entries"`
+	Images       int64               `json:"images"`
+	StorageBytes int64               `json:"storage_bytes"`
+	AISearch     aiAllowanceResponse `json:"ai_search"`
+}
+
+type aiAllowanceResponse struct {
+	Enabled   bool    `json:"enabled"`
+	Limit     *int    `json:"limit"`
+	Remaining *int    `json:"remaining"`
+	ResetsAt  *string `json:"resets_at"`
+}
+
+// GetMeOverview returns the user with their counts and remaining AI searches,
+// everything the app's profile screen shows, in one request.
+func (h *AuthHandler) GetMeOverview(w http.ResponseWriter, r *http.Request) {
+	userID := middleware.GetUserIDFromContext(r.Context())
+	if userID == "" {
+		respondWithError(w, r, apperror.Unauthorized("User not authenticated", nil))
+		return
+	}
+
+	user, overview, err := h.authService.GetOverview(r.Context(), userID)
+	if err != nil {
+		respondWithError(w, r, apperror.Internal("Failed to get user overview", err))
+		return
+	}
+
+	layout := timeLayout(r)
+	respondWithJSON(w, http.StatusOK, meOverviewResponse{
+		User:         mapUserToResponse(user, layout),
+		Collections:  overview.Collections,
+		Entries:      overview.Entries,
+		Images:       overview.Images,
+		StorageBytes: overview.ImageBytes,
+		AISearch:     mapAIAllowance(h.aiSearchService, overview, layout),
+	})
+}
+
+// mapAIAllowance reports the user's remaining AI searches; aiSearchService is
+// nil while AI search is disabled.
+func mapAIAllowance(aiSearchService *service.AISearchService, overview *repository.UserOverview, layout apitime.Layout) aiAllowanceResponse {
+	if aiSearchService == nil {
+		return aiAllowanceResponse{}
+	}
+
+	allowance := aiSearchService.Allowance(overview.AIUsagePolicy, overview.AISearches, overview.AIPeriodEnd)
+	response := aiAllowanceResponse{
+		Enabled:   true,
+		Limit:     allowance.Limit,
+		Remaining: allowance.Remaining,
+	}
+	if allowance.ResetsAt != nil {
+		resetsAt := layout.Format(*allowance.ResetsAt)
+		response.ResetsAt = &resetsAt
+	}
+	return response
 }
 
 func (h *AuthHandler) DeleteAccount(w http.ResponseWriter, r *http.Request) {
-	userID := getUserIDFromContext(r.Context())
+	userID := middleware.GetUserIDFromContext(r.Context())
 	if userID == "" {
-		respondWithError(w, http.StatusUnauthorized, "User not authenticated", nil)
+		respondWithError(w, r, apperror.Unauthorized("User not authenticated", nil))
 		return
 	}
 
 	if err := h.authService.DeleteAccount(r.Context(), userID); err != nil {
-		respondWithError(w, http.StatusInternalServerError, "Failed to delete account", err)
+		if errors.Is(err, service.ErrEmailVerificationRequired) {
+			respondWithError(w, r, apperror.Wrap(err, apperror.CodeEmailVerificationRequired, "Email must be verified again"))
+			return
+		}
+		respondWithError(w, r, apperror.Internal("Failed to delete account", err))
 		return
 	}
 
@@ -142,7 +366,7 @@ func (h *AuthHandler) DeleteAccount(w http.ResponseWriter, r *http.Request) {
 // Email Authentication Handlers
 
 type sendCodeRequest struct {
-	Email string `json:"email"`
+	Email string `json:"email" validate:"required"`
 }
 
 type sendCodeResponse struct {
@@ -152,22 +376,21 @@ type sendCodeResponse struct {
 
 func (h *AuthHandler) SendVerificationCode(w http.ResponseWriter, r *http.Request) {
 	var req sendCodeRequest
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		respondWithError(w, http.StatusBadRequest, "Invalid request body", err)
-		return
-	}
-
-	if req.Email == "" {
-		respondWithError(w, http.StatusBadRequest, "Email is required", nil)
+	if appErr := decodeAndValidate(r, &req); appErr != nil {
+		respondWithError(w, r, appErr)
 		return
 	}
 
 	if err := h.emailAuthService.SendVerificationCode(r.Context(), req.Email); err != nil {
 		if errors.Is(err, service.ErrInvalidEmail) {
-			respondWithError(w, http.StatusBadRequest, "Invalid email format", err)
+			respondWithError(w, r, apperror.Wrap(err, apperror.CodeInvalidEmail, "Invalid email format"))
+			return
+		}
+		if errors.Is(err, service.ErrMailRelayUnavailable) {
+			respondWithError(w, r, apperror.Wrap(err, apperror.CodeUnavailable, "Email to Apple private relay addresses is not available"))
 			return
 		}
-		respondWithError(w, http.StatusInternalServerError, "Failed to send verification code", err)
+		respondWithError(w, r, apperror.Internal("Failed to send verification code", err))
 		return
 	}
 
@@ -178,48 +401,30 @@ func (h *AuthHandler) SendVerificationCode(w http.ResponseWriter, r *http.Reques
 }
 
 type resendCodeRequest struct {
-	Email string `json:"email"`
+	Email string `json:"email" validate:"required"`
 }
 
 func (h *AuthHandler) ResendVerificationCode(w http.ResponseWriter, r *http.Request) {
 	var req resendCodeRequest
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		respondWithError(w, http.StatusBadRequest, "Invalid request body", err)
-		return
-	}
-
-	if req.Email == "" {
-		respondWithError(w, http.StatusBadRequest, "Email is required", nil)
+	if appErr := decodeAndValidate(r, &req); appErr != nil {
+		respondWithError(w, r, appErr)
 		return
 	}
 
 	if err := h.emailAuthService.ResendVerificationCode(r.Context(), req.Email); err != nil {
 		if errors.Is(err, service.ErrInvalidEmail) {
-			respondWithError(w, http.StatusBadRequest, "Invalid email format", err)
+			respondWithError(w, r, apperror.Wrap(err, apperror.CodeInvalidEmail, "Invalid email format"))
 			return
 		}
 		if errors.Is(err, service.ErrRateLimitExceeded) {
-			retryAfter := h.emailAuthService.GetRetryAfter(req.Email)
-			w.Header().Set("Retry-After", http.StatusText(retryAfter))
-
-			type rateLimitError struct {
-				Error   string         `json:"error"`
-				Message string         `json:"message"`
-				Details map[string]int `json:"details"`
-			}
-
-			resp := rateLimitError{
-				Error:   "RATE_LIMIT_EXCEEDED",
-				Message: "Please wait before requesting another code",
-				Details: map[string]int{"retry_after": retryAfter},
-			}
-
-			w.Header().Set("Content-Type", "application/json")
-			w.WriteHeader(http.StatusTooManyRequests)
-			json.NewEncoder(w).Encode(resp)
+			respondWithError(w, r, rateLimitError(err, "Please wait before requesting another code"))
+			return
+		}
+		if errors.Is(err, service.ErrMailRelayUnavailable) {
+			respondWithError(w, r, apperror.Wrap(err, apperror.CodeUnavailable, "Email to Apple private relay addresses is not available"))
 			return
 		}
-		respondWithError(w, http.StatusInternalServerError, "Failed to resend verification code", err)
+		respondWithError(w, r, apperror.Internal("Failed to resend verification code", err))
 		return
 	}
 
@@ -230,66 +435,209 @@ func (h *AuthHandler) ResendVerificationCode(w http.ResponseWriter, r *http.Requ
 }
 
 type verifyCodeRequest struct {
-	Email string `json:"email"`
-	Code  string `json:"code"`
+	Email string `json:"email" validate:"required"`
+	Code  string `json:"code" validate:"required"`
 }
 
 func (h *AuthHandler) VerifyEmailCode(w http.ResponseWriter, r *http.Request) {
 	var req verifyCodeRequest
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		respondWithError(w, http.StatusBadRequest, "Invalid request body", err)
+	if appErr := decodeAndValidate(r, &req); appErr != nil {
+		respondWithError(w, r, appErr)
 		return
 	}
 
-	if req.Email == "" {
-		respondWithError(w, http.StatusBadRequest, "Email is required", nil)
+	authResp, err := h.emailAuthService.VerifyCode(r.Context(), req.Email, req.Code, requestTokenClient(r))
+	if err != nil {
+		if errors.Is(err, service.ErrInvalidEmail) {
+			respondWithError(w, r, apperror.Wrap(err, apperror.CodeInvalidEmail, "Invalid email format"))
+			return
+		}
+		if errors.Is(err, service.ErrInvalidCode) ||
+			errors.Is(err, service.ErrCodeExpired) ||
+			errors.Is(err, service.ErrCodeAlreadyUsed) {
+			respondWithError(w, r, apperror.Wrap(err, apperror.CodeInvalidVerificationCode, "Verification code is invalid or expired"))
+			return
+		}
+		if errors.Is(err, service.ErrAccountPendingDeletion) {
+			respondWithError(w, r, apperror.Wrap(err, apperror.CodeAccountPendingDeletion, "Account is deleted and awaiting removal"))
+			return
+		}
+		respondWithError(w, r, apperror.Internal("Failed to verify code", err))
 		return
 	}
 
-	if req.Code == "" {
-		respondWithError(w, http.StatusBadRequest, "Verification code is required", nil)
+	respondWithJSON(w, http.StatusOK, mapAuthToResponse(authResp, timeLayout(r)))
+}
+
+// SendUserEmailCode sends a code to the signed-in user's email, to verify
+// it again with VerifyUserEmail.
+func (h *AuthHandler) SendUserEmailCode(w http.ResponseWriter, r *http.Request) {
+	userID := middleware.GetUserIDFromContext(r.Context())
+	if userID == "" {
+		respondWithError(w, r, apperror.Unauthorized("User not authenticated", nil))
 		return
 	}
 
-	authResp, err := h.emailAuthService.VerifyCode(r.Context(), req.Email, req.Code)
+	uid, err := uuid.Parse(userID)
 	if err != nil {
-		if errors.Is(err, service.ErrInvalidEmail) {
-			respondWithError(w, http.StatusBadRequest, "Invalid email format", err)
+		respondWithError(w, r, apperror.BadRequest("Invalid user ID", err))
+		return
+	}
+
+	if err := h.emailAuthService.SendUserEmailCode(r.Context(), uid); err != nil {
+		if errors.Is(err, service.ErrMailUnavailable) {
+			respondWithError(w, r, apperror.Wrap(err, apperror.CodeUnavailable, "Email verification is not available"))
+			return
+		}
+		if errors.Is(err, service.ErrMailRelayUnavailable) {
+			respondWithError(w, r, apperror.Wrap(err, apperror.CodeUnavailable, "Email to Apple private relay addresses is not available"))
+			return
+		}
+		if errors.Is(err, service.ErrNoEmail) {
+			respondWithError(w, r, apperror.Wrap(err, apperror.CodeInvalidEmail, "Account has no email"))
+			return
+		}
+		if errors.Is(err, service.ErrRateLimitExceeded) {
+			respondWithError(w, r, rateLimitError(err, "Please wait before requesting another code"))
+			return
+		}
+		respondWithError(w, r, apperror.Internal("Failed to send verification code", err))
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, sendCodeResponse{
+		Message:   "Verification code sent",
+		ExpiresIn: int(service.VerificationCodeExpiry.Seconds()),
+	})
+}
+
+type verifyUserEmailRequest struct {
+	Code string `json:"code" validate:"required"`
+}
+
+func (h *AuthHandler) VerifyUserEmail(w http.ResponseWriter, r *http.Request) {
+	userID := middleware.GetUserIDFromContext(r.Context())
+	if userID == "" {
+		respondWithError(w, r, apperror.Unauthorized("User not authenticated", nil))
+		return
+	}
+
+	uid, err := uuid.Parse(userID)
+	if err != nil {
+		respondWithError(w, r, apperror.BadRequest("Invalid user ID", err))
+		return
+	}
+
+	var req verifyUserEmailRequest
+	if appErr := decodeAndValidate(r, &req); appErr != nil {
+		respondWithError(w, r, appErr)
+		return
+	}
+
+	user, err := h.emailAuthService.VerifyUserEmail(r.Context(), uid, req.Code)
+	if err != nil {
+		if errors.Is(err, service.ErrMailUnavailable) {
+			respondWithError(w, r, apperror.Wrap(err, apperror.CodeUnavailable, "Email verification is not available"))
+			return
+		}
+		if errors.Is(err, service.ErrNoEmail) {
+			respondWithError(w, r, apperror.Wrap(err, apperror.CodeInvalidEmail, "Account has no email"))
 			return
 		}
 		if errors.Is(err, service.ErrInvalidCode) ||
 			errors.Is(err, service.ErrCodeExpired) ||
 			errors.Is(err, service.ErrCodeAlreadyUsed) {
-			respondWithError(w, http.StatusUnauthorized, "Verification code is invalid or expired", err)
+			respondWithError(w, r, apperror.Wrap(err, apperror.CodeInvalidVerificationCode, "Verification code is invalid or expired"))
 			return
 		}
-		respondWithError(w, http.StatusInternalServerError, "Failed to verify code", err)
+		respondWithError(w, r, apperror.Internal("Failed to verify email", err))
 		return
 	}
 
-	respondWithJSON(w, http.StatusOK, authResp)
+	respondWithJSON(w, http.StatusOK, mapUserToResponse(user, timeLayout(r)))
 }
 
-// Helper functions
-
-type errorResponse struct {
-	Error   string `json:"error"`
-	Message string `json:"message"`
+type emailEventResponse struct {
+	ID           string `json:"id"`
+	Source       string `json:"source"`
+	EmailChanged bool   `json:"email_changed"`
+	WasVerified  bool   `json:"was_verified"`
+	Verified     bool   `json:"verified"`
+	CreatedAt    string `json:"created_at"`
 }
 
-func respondWithError(w http.ResponseWriter, code int, message string, err error) {
-	resp := errorResponse{
-		Error:   http.StatusText(code),
-		Message: message,
+// GetEmailEvents lists how the user's email and its verification changed.
+func (h *AuthHandler) GetEmailEvents(w http.ResponseWriter, r *http.Request) {
+	userID := middleware.GetUserIDFromContext(r.Context())
+	if userID == "" {
+		respondWithError(w, r, apperror.Unauthorized("User not authenticated", nil))
+		return
 	}
 
-	// Log the actual error internally (in production, use proper logger)
+	events, err := h.authService.GetEmailEvents(r.Context(), userID)
 	if err != nil {
-		// log.Printf("Error: %v", err)
-		_ = err
+		respondWithError(w, r, apperror.Internal("Failed to get email events", err))
+		return
 	}
 
-	respondWithJSON(w, code, resp)
+	respondWithJSON(w, http.StatusOK, mapEmailEventsToResponse(events, timeLayout(r)))
+}
+
+func mapEmailEventsToResponse(events []repository.EmailEvent, layout apitime.Layout) []emailEventResponse {
+	response := make([]emailEventResponse, len(events))
+	for i, e := range events {
+		response[i] = emailEventResponse{
+			ID:           e.ID.String(),
+			Source:       e.Source,
+			EmailChanged: e.EmailChanged,
+			WasVerified:  e.WasVerified,
+			Verified:     e.Verified,
+			CreatedAt:    layout.Format(e.CreatedAt),
+		}
+	}
+	return response
+}
+
+// Helper functions
+
+// respondWithError writes err as the standard error envelope. The wrapped
+// cause is never sent to the client.
+// respondWithError writes err to the client, logs it with its cause and counts
+// it per route and code. Server errors are logged at error level; client errors
+// are expected traffic and logged at info.
+func respondWithError(w http.ResponseWriter, r *http.Request, err *apperror.Error) {
+	route := r.URL.Path
+	if rctx := chi.RouteContext(r.Context()); rctx != nil && rctx.RoutePattern() != "" {
+		route = rctx.RoutePattern()
+	}
+
+	httpErrorsTotal.WithLabelValues(r.Method, route, string(err.Code)).Inc()
+
+	fields := []zap.Field{
+		zap.String("method", r.Method),
+		zap.String("route", route),
+		zap.String("code", string(err.Code)),
+		zap.Int("status", err.Status()),
+		zap.Error(err),
+	}
+	if userID := middleware.GetUserIDFromContext(r.Context()); userID != "" {
+		fields = append(fields, zap.String("user_id", userID))
+	}
+
+	log := logger.FromContext(r.Context())
+	if err.Status() >= http.StatusInternalServerError {
+		log.Error("request failed", fields...)
+		errortracking.FromContext(r.Context()).Capture(errortracking.Event{
+			Err:     err,
+			Tags:    map[string]string{"route": route, "code": string(err.Code), "request_id": chimw.GetReqID(r.Context())},
+			UserID:  middleware.GetUserIDFromContext(r.Context()),
+			Request: r,
+		})
+	} else {
+		log.Info("request rejected", fields...)
+	}
+
+	apperror.Write(w, r, err)
 }
 
 func respondWithJSON(w http.ResponseWriter, code int, payload interface{}) {
@@ -304,10 +652,24 @@ func respondWithJSON(w http.ResponseWriter, code int, payload interface{}) {
 	}
 }
 
-func getUserIDFromContext(ctx context.Context) string {
-	userID, ok := ctx.Value("userID").(string)
-	if !ok {
-		return ""
+// quotaError reports a service.QuotaExceededError with the exceeded
+// resource and limit in the details.
+func quotaError(err error) *apperror.Error {
+	appErr := apperror.Wrap(err, apperror.CodeQuotaExceeded, "Quota exceeded")
+	var qe *service.QuotaExceededError
+	if errors.As(err, &qe) {
+		appErr = appErr.WithDetails(map[string]interface{}{"resource": qe.Resource, "limit": qe.Limit})
+	}
+	return appErr
+}
+
+// rateLimitError reports a service.RateLimitError as a 429 with Retry-After
+// and RateLimit-* headers, in the same shape as the rate limit middleware.
+func rateLimitError(err error, message string) *apperror.Error {
+	limit := apperror.RateLimit{Reset: time.Minute}
+	var rle *service.RateLimitError
+	if errors.As(err, &rle) {
+		limit = apperror.RateLimit{Limit: rle.Limit, Reset: rle.RetryAfter}
 	}
-	return userID
+	return apperror.RateLimited(message, err, limit)
 }
@@ -0,0 +1,40 @@
+package handler
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/avalarin/livlog/backend/internal/apitime"
+	"github.com/avalarin/livlog/backend/internal/repository"
+	"github.com/google/uuid"
+)
+
+func TestMapEmailEventsToResponse(t *testing.T) {
+	id := uuid.New()
+	resp := mapEmailEventsToResponse([]repository.EmailEvent{{
+		ID:           id,
+		Source:       repository.EmailEventApple,
+		EmailChanged: true,
+		WasVerified:  true,
+		Verified:     false,
+		CreatedAt:    time.Date(2025, 1, 20, 18, 30, 0, 0, time.UTC),
+	}}, apitime.Legacy)
+
+	want := emailEventResponse{
+		ID:           id.String(),
+		Source:       "apple",
+		EmailChanged: true,
+		WasVerified:  true,
+		CreatedAt:    "2025-01-20T18:30:00Z",
+	}
+	if len(resp) != 1 || resp[0] != want {
+		t.Errorf("events = %+v, want %+v", resp, want)
+	}
+
+	// No events is an empty list, not null
+	body, _ := json.Marshal(mapEmailEventsToResponse(nil, apitime.Legacy))
+	if string(body) != "[]" {
+		t.Errorf("empty events = %s", body)
+	}
+}
@@ -0,0 +1,108 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/avalarin/livlog/backend/internal/apitime"
+	"github.com/avalarin/livlog/backend/internal/apiv1"
+	"github.com/avalarin/livlog/backend/internal/apperror"
+	"github.com/avalarin/livlog/backend/internal/middleware"
+	"github.com/avalarin/livlog/backend/internal/service"
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+)
+
+type BootstrapHandler struct {
+	bootstrapService *service.BootstrapService
+	aiSearchService  *service.AISearchService // nil while AI search is disabled
+}
+
+func NewBootstrapHandler(bootstrapService *service.BootstrapService, aiSearchService *service.AISearchService) *BootstrapHandler {
+	return &BootstrapHandler{
+		bootstrapService: bootstrapService,
+		aiSearchService:  aiSearchService,
+	}
+}
+
+func (h *BootstrapHandler) RegisterRoutes(r chi.Router) {
+	r.Get("/bootstrap", h.GetBootstrap)
+}
+
+// bootstrapResponse combines GET /auth/me, /profile, /collections and /types
+// with the user's quotas, in the same shapes.
+type bootstrapResponse struct {
+	User        *userResponse           `json:"user"`
+	Profile     *profileResponse        `json:"profile"` // null until the user sets one up
+	Collections []apiv1.Collection      `json:"collections"`
+	Types       []apiv1.Type            `json:"types"`
+	Quotas      bootstrapQuotasResponse `json:"quotas"`
+}
+
+type bootstrapQuotasResponse struct {
+	Collections       quotaResponse       `json:"collections"`
+	Entries           quotaResponse       `json:"entries"`
+	MaxImagesPerEntry *int                `json:"max_images_per_entry"` // null when unlimited
+	AISearch          aiAllowanceResponse `json:"ai_search"`
+}
+
+type quotaResponse struct {
+	Used  int64 `json:"used"`
+	Limit *int  `json:"limit"` // null when unlimited
+}
+
+// GetBootstrap returns everything the app loads on launch in one request.
+func (h *BootstrapHandler) GetBootstrap(w http.ResponseWriter, r *http.Request) {
+	userID := middleware.GetUserIDFromContext(r.Context())
+	if userID == "" {
+		respondWithError(w, r, apperror.Unauthorized("User not authenticated", nil))
+		return
+	}
+
+	uid, err := uuid.Parse(userID)
+	if err != nil {
+		respondWithError(w, r, apperror.BadRequest("Invalid user ID", err))
+		return
+	}
+
+	bootstrap, err := h.bootstrapService.Load(r.Context(), uid)
+	if err != nil {
+		respondWithError(w, r, apperror.Internal("Failed to load bootstrap", err))
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, mapBootstrapToResponse(bootstrap, h.aiSearchService, timeLayout(r)))
+}
+
+func mapBootstrapToResponse(b *service.Bootstrap, aiSearchService *service.AISearchService, layout apitime.Layout) bootstrapResponse {
+	response := bootstrapResponse{
+		User:        mapUserToResponse(b.User, layout),
+		Collections: make([]apiv1.Collection, len(b.Collections)),
+		Types:       make([]apiv1.Type, len(b.Types)),
+		Quotas: bootstrapQuotasResponse{
+			Collections:       quotaResponse{Used: b.Overview.Collections, Limit: quotaLimit(b.Quotas.MaxCollections)},
+			Entries:           quotaResponse{Used: b.Overview.Entries, Limit: quotaLimit(b.Quotas.MaxEntries)},
+			MaxImagesPerEntry: quotaLimit(b.Quotas.MaxImagesPerEntry),
+			AISearch:          mapAIAllowance(aiSearchService, b.Overview, layout),
+		},
+	}
+	if b.Profile != nil {
+		profile := mapProfileToResponse(b.Profile, layout)
+		response.Profile = &profile
+	}
+	for i, c := range b.Collections {
+		response.Collections[i] = apiv1.MapCollection(c, layout)
+	}
+	for i, t := range b.Types {
+		response.Types[i] = apiv1.MapType(t, layout)
+	}
+	return response
+}
+
+// quotaLimit maps a configured quota to the response, where a zero
+// (unlimited) quota is null.
+func quotaLimit(limit int) *int {
+	if limit == 0 {
+		return nil
+	}
+	return &limit
+}
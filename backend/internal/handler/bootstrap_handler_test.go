@@ -0,0 +1,70 @@
+package handler
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/avalarin/livlog/backend/internal/apitime"
+	"github.com/avalarin/livlog/backend/internal/config"
+	"github.com/avalarin/livlog/backend/internal/repository"
+	"github.com/avalarin/livlog/backend/internal/service"
+	"github.com/google/uuid"
+)
+
+func TestMapBootstrapToResponse(t *testing.T) {
+	now := time.Date(2026, 10, 16, 8, 0, 0, 0, time.UTC)
+	bootstrap := &service.Bootstrap{
+		User:     &service.User{ID: uuid.NewString(), AuthProviders: []string{"email"}},
+		Overview: &repository.UserOverview{Collections: 2, Entries: 41},
+		Collections: []*repository.Collection{
+			{ID: uuid.New(), Name: "Books", Icon: "📚", EntryCount: 41, CreatedAt: now, UpdatedAt: now},
+		},
+		Types: []*repository.EntryType{
+			{ID: uuid.New(), Name: "Book", Icon: "📖", CreatedAt: now, UpdatedAt: now},
+		},
+		Quotas: config.QuotasConfig{MaxCollections: 50},
+	}
+
+	body, err := json.Marshal(mapBootstrapToResponse(bootstrap, nil, apitime.Legacy))
+	if err != nil {
+		t.Fatalf("marshal: %v", err)
+	}
+
+	var got struct {
+		Profile     json.RawMessage `json:"profile"`
+		Collections []struct {
+			Name       string `json:"name"`
+			EntryCount int    `json:"entry_count"`
+		} `json:"collections"`
+		Types  []struct{ Name string } `json:"types"`
+		Quotas struct {
+			Collections       struct{ Used, Limit *int } `json:"collections"`
+			Entries           struct{ Used, Limit *int } `json:"entries"`
+			MaxImagesPerEntry *int                       `json:"max_images_per_entry"`
+			AISearch          struct{ Enabled bool }     `json:"ai_search"`
+		} `json:"quotas"`
+	}
+	if err := json.Unmarshal(body, &got); err != nil {
+		t.Fatalf("unmarshal: %v", err)
+	}
+
+	if string(got.Profile) != "null" {
+		t.Errorf("profile = %s, want null before the user sets one up", got.Profile)
+	}
+	if len(got.Collections) != 1 || got.Collections[0].EntryCount != 41 || len(got.Types) != 1 {
+		t.Errorf("collections and types = %s", body)
+	}
+
+	// Zero quotas are unlimited and come out as null
+	q := got.Quotas
+	if q.Collections.Used == nil || *q.Collections.Used != 2 || q.Collections.Limit == nil || *q.Collections.Limit != 50 {
+		t.Errorf("collections quota = %s", body)
+	}
+	if q.Entries.Used == nil || *q.Entries.Used != 41 || q.Entries.Limit != nil || q.MaxImagesPerEntry != nil {
+		t.Errorf("entries quota = %s", body)
+	}
+	if q.AISearch.Enabled {
+		t.Error("ai_search.enabled = true without AI search")
+	}
+}
@@ -1,10 +1,13 @@
 package handler
 
 import (
-	"encoding/json"
+	"encoding/base64"
 	"errors"
 	"net/http"
 
+	"github.com/avalarin/livlog/backend/internal/apiv1"
+	"github.com/avalarin/livlog/backend/internal/apperror"
+	"github.com/avalarin/livlog/backend/internal/middleware"
 	"github.com/avalarin/livlog/backend/internal/repository"
 	"github.com/avalarin/livlog/backend/internal/service"
 	"github.com/go-chi/chi/v5"
@@ -28,228 +31,445 @@ func (h *CollectionHandler) RegisterRoutes(r chi.Router) {
 	r.Get("/collections/{id}", h.GetCollection)
 	r.Put("/collections/{id}", h.UpdateCollection)
 	r.Delete("/collections/{id}", h.DeleteCollection)
+	r.Post("/collections/icons", h.UploadIcon)
+	r.Put("/collections/{id}/share", h.ShareCollection)
+	r.Delete("/collections/{id}/share", h.UnshareCollection)
 }
 
+// RegisterPublicRoutes registers routes that do not require authentication.
+func (h *CollectionHandler) RegisterPublicRoutes(r chi.Router) {
+	r.Get("/collections/icons/{id}", h.GetIcon)
+}
+
+// createCollectionRequest sets the icon as either an emoji (icon) or an
+// image uploaded with POST /collections/icons (icon_image_id). On update, an
+// absent allowed_type_ids keeps the collection's types and an empty list
+// lifts the restriction.
 type createCollectionRequest struct {
-	Name string `json:"name"`
-	Icon string `json:"icon"`
+	Name           string    `json:"name" validate:"required,max=50"`
+	Icon           string    `json:"icon" validate:"required_without=IconImageID,max=20"`
+	IconImageID    *string   `json:"icon_image_id" validate:"omitempty,uuid"`
+	AllowedTypeIDs *[]string `json:"allowed_type_ids" validate:"omitempty,max=20,dive,uuid"`
+}
+
+// icon converts the validated icon fields.
+func (req *createCollectionRequest) icon() (service.CollectionIcon, error) {
+	imageID, err := parseOptionalUUID(req.IconImageID)
+	if err != nil {
+		return service.CollectionIcon{}, err
+	}
+	return service.CollectionIcon{Emoji: req.Icon, ImageID: imageID}, nil
 }
 
-type collectionResponse struct {
-	ID         string `json:"id"`
-	Name       string `json:"name"`
-	Icon       string `json:"icon"`
-	EntryCount int    `json:"entry_count"`
-	CreatedAt  string `json:"created_at"`
-	UpdatedAt  string `json:"updated_at"`
+// allowedTypeIDs converts the validated allowed types, nil when absent.
+func (req *createCollectionRequest) allowedTypeIDs() ([]uuid.UUID, error) {
+	if req.AllowedTypeIDs == nil {
+		return nil, nil
+	}
+	return parseUUIDs(*req.AllowedTypeIDs)
 }
 
+type shareCollectionRequest struct {
+	Slug string `json:"slug" validate:"required,max=60"`
+}
+
+type uploadIconRequest struct {
+	Data string `json:"data" validate:"required,base64"`
+}
+
+type collectionIconResponse struct {
+	ID  string `json:"id"`
+	URL string `json:"url"`
+}
+
+// GetCollections lists the user's collections, oldest first, a page at a
+// time when ?limit= is set (and always from v2 on), see pageParams.
 func (h *CollectionHandler) GetCollections(w http.ResponseWriter, r *http.Request) {
-	userID := getUserIDFromContext(r.Context())
+	userID := middleware.GetUserIDFromContext(r.Context())
 	if userID == "" {
-		respondWithError(w, http.StatusUnauthorized, "User not authenticated", nil)
+		respondWithError(w, r, apperror.Unauthorized("User not authenticated", nil))
 		return
 	}
 
 	uid, err := uuid.Parse(userID)
 	if err != nil {
-		respondWithError(w, http.StatusBadRequest, "Invalid user ID", err)
+		respondWithError(w, r, apperror.BadRequest("Invalid user ID", err))
+		return
+	}
+
+	cursor, limit, appErr := pageParams(r)
+	if appErr != nil {
+		respondWithError(w, r, appErr)
 		return
 	}
 
-	collections, err := h.collectionService.GetCollectionsByUserID(r.Context(), uid)
+	page, err := h.collectionService.ListCollections(r.Context(), uid, cursor, limit)
 	if err != nil {
-		respondWithError(w, http.StatusInternalServerError, "Failed to get collections", err)
+		if errors.Is(err, service.ErrInvalidPageCursor) {
+			respondWithError(w, r, apperror.BadRequest("Invalid cursor", err))
+			return
+		}
+		respondWithError(w, r, apperror.Internal("Failed to get collections", err))
 		return
 	}
 
-	response := make([]collectionResponse, len(collections))
-	for i, c := range collections {
-		response[i] = mapCollectionToResponse(c)
+	response := make([]apiv1.Collection, len(page.Items))
+	for i, c := range page.Items {
+		response[i] = apiv1.MapCollection(c, timeLayout(r))
 	}
 
-	respondWithJSON(w, http.StatusOK, response)
+	respondWithPage(w, r, pageResponse[apiv1.Collection]{Items: response, NextCursor: page.NextCursor})
 }
 
 func (h *CollectionHandler) CreateCollection(w http.ResponseWriter, r *http.Request) {
-	userID := getUserIDFromContext(r.Context())
+	userID := middleware.GetUserIDFromContext(r.Context())
 	if userID == "" {
-		respondWithError(w, http.StatusUnauthorized, "User not authenticated", nil)
+		respondWithError(w, r, apperror.Unauthorized("User not authenticated", nil))
 		return
 	}
 
 	uid, err := uuid.Parse(userID)
 	if err != nil {
-		respondWithError(w, http.StatusBadRequest, "Invalid user ID", err)
+		respondWithError(w, r, apperror.BadRequest("Invalid user ID", err))
 		return
 	}
 
 	var req createCollectionRequest
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		respondWithError(w, http.StatusBadRequest, "Invalid request body", err)
+	if appErr := decodeAndValidate(r, &req); appErr != nil {
+		respondWithError(w, r, appErr)
 		return
 	}
 
-	collection, err := h.collectionService.CreateCollection(r.Context(), uid, req.Name, req.Icon)
+	icon, err := req.icon()
 	if err != nil {
-		if errors.Is(err, service.ErrInvalidCollectionName) || errors.Is(err, service.ErrInvalidIcon) {
-			respondWithError(w, http.StatusBadRequest, err.Error(), err)
+		respondWithError(w, r, apperror.BadRequest("Invalid request body", err))
+		return
+	}
+	allowedTypeIDs, err := req.allowedTypeIDs()
+	if err != nil {
+		respondWithError(w, r, apperror.BadRequest("Invalid request body", err))
+		return
+	}
+
+	collection, err := h.collectionService.CreateCollection(r.Context(), uid, req.Name, icon, allowedTypeIDs)
+	if err != nil {
+		if errors.Is(err, service.ErrInvalidCollectionName) ||
+			errors.Is(err, service.ErrInvalidIcon) ||
+			errors.Is(err, service.ErrIconChoice) ||
+			errors.Is(err, service.ErrInvalidAllowedTypes) {
+			respondWithError(w, r, apperror.Validation(err.Error(), err))
+			return
+		}
+		if errors.Is(err, repository.ErrImageNotFound) {
+			respondWithError(w, r, apperror.Wrap(err, apperror.CodeImageNotFound, "Icon image not found"))
+			return
+		}
+		if errors.Is(err, service.ErrQuotaExceeded) {
+			respondWithError(w, r, quotaError(err))
 			return
 		}
-		respondWithError(w, http.StatusInternalServerError, "Failed to create collection", err)
+		respondWithError(w, r, apperror.Internal("Failed to create collection", err))
 		return
 	}
 
-	respondWithJSON(w, http.StatusCreated, mapCollectionToResponse(collection))
+	respondWithJSON(w, http.StatusCreated, apiv1.MapCollection(collection, timeLayout(r)))
 }
 
 func (h *CollectionHandler) CreateDefaultCollections(w http.ResponseWriter, r *http.Request) {
-	userID := getUserIDFromContext(r.Context())
+	userID := middleware.GetUserIDFromContext(r.Context())
 	if userID == "" {
-		respondWithError(w, http.StatusUnauthorized, "User not authenticated", nil)
+		respondWithError(w, r, apperror.Unauthorized("User not authenticated", nil))
 		return
 	}
 
 	uid, err := uuid.Parse(userID)
 	if err != nil {
-		respondWithError(w, http.StatusBadRequest, "Invalid user ID", err)
+		respondWithError(w, r, apperror.BadRequest("Invalid user ID", err))
 		return
 	}
 
 	collections, err := h.collectionService.CreateDefaultCollections(r.Context(), uid)
 	if err != nil {
-		if err.Error() == "user already has collections" {
-			respondWithError(w, http.StatusBadRequest, "User already has collections", err)
+		if errors.Is(err, service.ErrCollectionsExist) {
+			respondWithError(w, r, apperror.Wrap(err, apperror.CodeCollectionsAlreadyCreated, "User already has collections"))
 			return
 		}
-		respondWithError(w, http.StatusInternalServerError, "Failed to create default collections", err)
+		respondWithError(w, r, apperror.Internal("Failed to create default collections", err))
 		return
 	}
 
-	response := make([]collectionResponse, len(collections))
+	response := make([]apiv1.Collection, len(collections))
 	for i, c := range collections {
-		response[i] = mapCollectionToResponse(c)
+		response[i] = apiv1.MapCollection(c, timeLayout(r))
 	}
 
 	respondWithJSON(w, http.StatusCreated, response)
 }
 
 func (h *CollectionHandler) GetCollection(w http.ResponseWriter, r *http.Request) {
-	userID := getUserIDFromContext(r.Context())
+	userID := middleware.GetUserIDFromContext(r.Context())
 	if userID == "" {
-		respondWithError(w, http.StatusUnauthorized, "User not authenticated", nil)
+		respondWithError(w, r, apperror.Unauthorized("User not authenticated", nil))
 		return
 	}
 
 	uid, err := uuid.Parse(userID)
 	if err != nil {
-		respondWithError(w, http.StatusBadRequest, "Invalid user ID", err)
+		respondWithError(w, r, apperror.BadRequest("Invalid user ID", err))
 		return
 	}
 
 	collectionID := chi.URLParam(r, "id")
 	cid, err := uuid.Parse(collectionID)
 	if err != nil {
-		respondWithError(w, http.StatusBadRequest, "Invalid collection ID", err)
+		respondWithError(w, r, apperror.BadRequest("Invalid collection ID", err))
 		return
 	}
 
 	collection, err := h.collectionService.GetCollectionByID(r.Context(), cid, uid)
 	if err != nil {
 		if errors.Is(err, repository.ErrCollectionNotFound) {
-			respondWithError(w, http.StatusNotFound, "Collection not found", err)
+			respondWithError(w, r, apperror.Wrap(err, apperror.CodeCollectionNotFound, "Collection not found"))
 			return
 		}
-		respondWithError(w, http.StatusInternalServerError, "Failed to get collection", err)
+		respondWithError(w, r, apperror.Internal("Failed to get collection", err))
 		return
 	}
 
-	respondWithJSON(w, http.StatusOK, mapCollectionToResponse(collection))
+	respondWithJSON(w, http.StatusOK, apiv1.MapCollection(collection, timeLayout(r)))
 }
 
 func (h *CollectionHandler) UpdateCollection(w http.ResponseWriter, r *http.Request) {
-	userID := getUserIDFromContext(r.Context())
+	userID := middleware.GetUserIDFromContext(r.Context())
 	if userID == "" {
-		respondWithError(w, http.StatusUnauthorized, "User not authenticated", nil)
+		respondWithError(w, r, apperror.Unauthorized("User not authenticated", nil))
 		return
 	}
 
 	uid, err := uuid.Parse(userID)
 	if err != nil {
-		respondWithError(w, http.StatusBadRequest, "Invalid user ID", err)
+		respondWithError(w, r, apperror.BadRequest("Invalid user ID", err))
 		return
 	}
 
 	collectionID := chi.URLParam(r, "id")
 	cid, err := uuid.Parse(collectionID)
 	if err != nil {
-		respondWithError(w, http.StatusBadRequest, "Invalid collection ID", err)
+		respondWithError(w, r, apperror.BadRequest("Invalid collection ID", err))
 		return
 	}
 
 	var req createCollectionRequest
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		respondWithError(w, http.StatusBadRequest, "Invalid request body", err)
+	if appErr := decodeAndValidate(r, &req); appErr != nil {
+		respondWithError(w, r, appErr)
 		return
 	}
 
-	collection, err := h.collectionService.UpdateCollection(r.Context(), cid, uid, req.Name, req.Icon)
+	icon, err := req.icon()
+	if err != nil {
+		respondWithError(w, r, apperror.BadRequest("Invalid request body", err))
+		return
+	}
+	allowedTypeIDs, err := req.allowedTypeIDs()
+	if err != nil {
+		respondWithError(w, r, apperror.BadRequest("Invalid request body", err))
+		return
+	}
+
+	collection, err := h.collectionService.UpdateCollection(r.Context(), cid, uid, req.Name, icon, allowedTypeIDs)
 	if err != nil {
 		if errors.Is(err, repository.ErrCollectionNotFound) {
-			respondWithError(w, http.StatusNotFound, "Collection not found", err)
+			respondWithError(w, r, apperror.Wrap(err, apperror.CodeCollectionNotFound, "Collection not found"))
 			return
 		}
-		if errors.Is(err, service.ErrInvalidCollectionName) || errors.Is(err, service.ErrInvalidIcon) {
-			respondWithError(w, http.StatusBadRequest, err.Error(), err)
+		if errors.Is(err, service.ErrInvalidCollectionName) ||
+			errors.Is(err, service.ErrInvalidIcon) ||
+			errors.Is(err, service.ErrIconChoice) ||
+			errors.Is(err, service.ErrInvalidAllowedTypes) {
+			respondWithError(w, r, apperror.Validation(err.Error(), err))
 			return
 		}
-		respondWithError(w, http.StatusInternalServerError, "Failed to update collection", err)
+		if errors.Is(err, repository.ErrImageNotFound) {
+			respondWithError(w, r, apperror.Wrap(err, apperror.CodeImageNotFound, "Icon image not found"))
+			return
+		}
+		respondWithError(w, r, apperror.Internal("Failed to update collection", err))
 		return
 	}
 
-	respondWithJSON(w, http.StatusOK, mapCollectionToResponse(collection))
+	respondWithJSON(w, http.StatusOK, apiv1.MapCollection(collection, timeLayout(r)))
 }
 
 func (h *CollectionHandler) DeleteCollection(w http.ResponseWriter, r *http.Request) {
-	userID := getUserIDFromContext(r.Context())
+	userID := middleware.GetUserIDFromContext(r.Context())
 	if userID == "" {
-		respondWithError(w, http.StatusUnauthorized, "User not authenticated", nil)
+		respondWithError(w, r, apperror.Unauthorized("User not authenticated", nil))
 		return
 	}
 
 	uid, err := uuid.Parse(userID)
 	if err != nil {
-		respondWithError(w, http.StatusBadRequest, "Invalid user ID", err)
+		respondWithError(w, r, apperror.BadRequest("Invalid user ID", err))
 		return
 	}
 
 	collectionID := chi.URLParam(r, "id")
 	cid, err := uuid.Parse(collectionID)
 	if err != nil {
-		respondWithError(w, http.StatusBadRequest, "Invalid collection ID", err)
+		respondWithError(w, r, apperror.BadRequest("Invalid collection ID", err))
 		return
 	}
 
 	err = h.collectionService.DeleteCollection(r.Context(), cid, uid)
 	if err != nil {
 		if errors.Is(err, repository.ErrCollectionNotFound) {
-			respondWithError(w, http.StatusNotFound, "Collection not found", err)
+			respondWithError(w, r, apperror.Wrap(err, apperror.CodeCollectionNotFound, "Collection not found"))
 			return
 		}
-		respondWithError(w, http.StatusInternalServerError, "Failed to delete collection", err)
+		respondWithError(w, r, apperror.Internal("Failed to delete collection", err))
 		return
 	}
 
 	respondWithJSON(w, http.StatusOK, map[string]string{"message": "Collection deleted successfully"})
 }
 
-func mapCollectionToResponse(c *repository.Collection) collectionResponse {
-	return collectionResponse{
-		ID:         c.ID.String(),
-		Name:       c.Name,
-		Icon:       c.Icon,
-		EntryCount: c.EntryCount,
-		CreatedAt:  c.CreatedAt.Format("2006-01-02T15:04:05Z07:00"),
-		UpdatedAt:  c.UpdatedAt.Format("2006-01-02T15:04:05Z07:00"),
+// ShareCollection shares a collection at GET /public/c/{slug} under a slug
+// the user picks.
+func (h *CollectionHandler) ShareCollection(w http.ResponseWriter, r *http.Request) {
+	userID := middleware.GetUserIDFromContext(r.Context())
+	if userID == "" {
+		respondWithError(w, r, apperror.Unauthorized("User not authenticated", nil))
+		return
 	}
+
+	uid, err := uuid.Parse(userID)
+	if err != nil {
+		respondWithError(w, r, apperror.BadRequest("Invalid user ID", err))
+		return
+	}
+
+	cid, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		respondWithError(w, r, apperror.BadRequest("Invalid collection ID", err))
+		return
+	}
+
+	var req shareCollectionRequest
+	if appErr := decodeAndValidate(r, &req); appErr != nil {
+		respondWithError(w, r, appErr)
+		return
+	}
+
+	collection, err := h.collectionService.ShareCollection(r.Context(), cid, uid, req.Slug)
+	if err != nil {
+		switch {
+		case errors.Is(err, repository.ErrCollectionNotFound):
+			respondWithError(w, r, apperror.Wrap(err, apperror.CodeCollectionNotFound, "Collection not found"))
+		case errors.Is(err, service.ErrInvalidShareSlug):
+			respondWithError(w, r, apperror.Validation(err.Error(), err))
+		case errors.Is(err, service.ErrShareSlugReserved), errors.Is(err, repository.ErrShareSlugTaken):
+			respondWithError(w, r, apperror.Wrap(err, apperror.CodeShareSlugTaken, "Share link is already taken"))
+		default:
+			respondWithError(w, r, apperror.Internal("Failed to share collection", err))
+		}
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, apiv1.MapCollection(collection, timeLayout(r)))
+}
+
+// UnshareCollection stops sharing a collection; its link stops working.
+func (h *CollectionHandler) UnshareCollection(w http.ResponseWriter, r *http.Request) {
+	userID := middleware.GetUserIDFromContext(r.Context())
+	if userID == "" {
+		respondWithError(w, r, apperror.Unauthorized("User not authenticated", nil))
+		return
+	}
+
+	uid, err := uuid.Parse(userID)
+	if err != nil {
+		respondWithError(w, r, apperror.BadRequest("Invalid user ID", err))
+		return
+	}
+
+	cid, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		respondWithError(w, r, apperror.BadRequest("Invalid collection ID", err))
+		return
+	}
+
+	collection, err := h.collectionService.UnshareCollection(r.Context(), cid, uid)
+	if err != nil {
+		if errors.Is(err, repository.ErrCollectionNotFound) {
+			respondWithError(w, r, apperror.Wrap(err, apperror.CodeCollectionNotFound, "Collection not found"))
+			return
+		}
+		respondWithError(w, r, apperror.Internal("Failed to unshare collection", err))
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, apiv1.MapCollection(collection, timeLayout(r)))
+}
+
+func (h *CollectionHandler) UploadIcon(w http.ResponseWriter, r *http.Request) {
+	userID := middleware.GetUserIDFromContext(r.Context())
+	if userID == "" {
+		respondWithError(w, r, apperror.Unauthorized("User not authenticated", nil))
+		return
+	}
+
+	uid, err := uuid.Parse(userID)
+	if err != nil {
+		respondWithError(w, r, apperror.BadRequest("Invalid user ID", err))
+		return
+	}
+
+	var req uploadIconRequest
+	if appErr := decodeAndValidate(r, &req); appErr != nil {
+		respondWithError(w, r, appErr)
+		return
+	}
+
+	data, err := base64.StdEncoding.DecodeString(req.Data)
+	if err != nil {
+		respondWithError(w, r, apperror.BadRequest("Invalid request body", err))
+		return
+	}
+
+	icon, err := h.collectionService.UploadIcon(r.Context(), uid, data)
+	if err != nil {
+		if errors.Is(err, service.ErrInvalidIconImage) {
+			respondWithError(w, r, apperror.Validation(err.Error(), err))
+			return
+		}
+		respondWithError(w, r, apperror.Internal("Failed to upload icon", err))
+		return
+	}
+
+	respondWithJSON(w, http.StatusCreated, collectionIconResponse{
+		ID:  icon.ID.String(),
+		URL: apiv1.CollectionIconURL(icon.ID, icon.Hash),
+	})
+}
+
+// GetIcon serves an uploaded collection icon. Like entry images, icons are
+// public and addressed by their unguessable ID.
+func (h *CollectionHandler) GetIcon(w http.ResponseWriter, r *http.Request) {
+	iconID, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		respondWithError(w, r, apperror.BadRequest("Invalid image ID", err))
+		return
+	}
+
+	icon, err := h.collectionService.GetIcon(r.Context(), iconID)
+	if err != nil {
+		if errors.Is(err, repository.ErrImageNotFound) {
+			respondWithError(w, r, apperror.Wrap(err, apperror.CodeImageNotFound, "Image not found"))
+			return
+		}
+		respondWithError(w, r, apperror.Internal("Failed to get icon", err))
+		return
+	}
+
+	writeImage(w, http.DetectContentType(icon.ImageData), icon.ImageData)
 }
@@ -0,0 +1,39 @@
+package handler
+
+import (
+	"expvar"
+	"net/http"
+	"sync"
+
+	"github.com/go-chi/chi/v5"
+	chimw "github.com/go-chi/chi/v5/middleware"
+
+	"github.com/avalarin/livlog/backend/internal/repository"
+)
+
+var publishOnce sync.Once
+
+// NewDebugRouter returns a router exposing net/http/pprof under /debug/pprof/
+// and an expvar snapshot under /debug/vars. It must only be served on the
+// internal debug listener, never on the public API address.
+func NewDebugRouter(db *repository.DB) http.Handler {
+	publishOnce.Do(func() {
+		expvar.Publish("version", expvar.Func(func() interface{} { return Version }))
+		expvar.Publish("db_pool", expvar.Func(func() interface{} {
+			stat := db.Pool.Stat()
+			return map[string]interface{}{
+				"acquired_conns":      stat.AcquiredConns(),
+				"idle_conns":          stat.IdleConns(),
+				"total_conns":         stat.TotalConns(),
+				"max_conns":           stat.MaxConns(),
+				"acquire_count":       stat.AcquireCount(),
+				"empty_acquire_count": stat.EmptyAcquireCount(),
+				"acquire_duration_ms": stat.AcquireDuration().Milliseconds(),
+			}
+		}))
+	})
+
+	r := chi.NewRouter()
+	r.Mount("/debug", chimw.Profiler())
+	return r
+}
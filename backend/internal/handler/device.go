@@ -0,0 +1,70 @@
+package handler
+
+import (
+	"net"
+	"net/http"
+	"unicode"
+
+	"github.com/avalarin/livlog/backend/internal/repository"
+)
+
+// deviceIDHeader carries the id the app generated for its install, so
+// writes from different devices of the same user can be told apart.
+const deviceIDHeader = "X-Device-ID"
+
+const (
+	maxDeviceIDLength   = 128
+	maxDeviceNameLength = 200
+)
+
+// requestDevice returns the device a request came from, named by its
+// User-Agent. The ID is empty, and the device unknown, when the header is
+// missing or not a short printable string.
+func requestDevice(r *http.Request) repository.Device {
+	id := r.Header.Get(deviceIDHeader)
+	if len(id) > maxDeviceIDLength || !printable(id) {
+		id = ""
+	}
+
+	name := []rune(r.Header.Get("User-Agent"))
+	if len(name) > maxDeviceNameLength {
+		name = name[:maxDeviceNameLength]
+	}
+
+	return repository.Device{ID: id, Name: string(name)}
+}
+
+// entryDevice returns the device entry writes of a request are recorded
+// with, nil when it is unknown.
+func entryDevice(r *http.Request) *repository.Device {
+	device := requestDevice(r)
+	if device.ID == "" {
+		return nil
+	}
+	return &device
+}
+
+// requestTokenClient returns the client a sign-in or token refresh came from:
+// its device, and the address chi's RealIP middleware left in RemoteAddr.
+func requestTokenClient(r *http.Request) repository.TokenClient {
+	device := requestDevice(r)
+
+	ip, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		ip = r.RemoteAddr
+	}
+	if net.ParseIP(ip) == nil {
+		ip = ""
+	}
+
+	return repository.TokenClient{DeviceID: device.ID, UserAgent: device.Name, IP: ip}
+}
+
+func printable(s string) bool {
+	for _, c := range s {
+		if !unicode.IsPrint(c) {
+			return false
+		}
+	}
+	return true
+}
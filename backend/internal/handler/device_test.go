@@ -0,0 +1,65 @@
+package handler
+
+import (
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestRequestDevice(t *testing.T) {
+	tests := []struct {
+		name      string
+		id        string
+		userAgent string
+		wantID    string
+		wantName  string
+	}{
+		{"app", "5F0C7A2E-1B7D-4C52-9E0A-3D1C2B4A5E6F", "Livlog/2.3 iOS/18.1", "5F0C7A2E-1B7D-4C52-9E0A-3D1C2B4A5E6F", "Livlog/2.3 iOS/18.1"},
+		{"no header", "", "curl/8.5", "", "curl/8.5"},
+		{"control characters", "abc\x1b[31m", "", "", ""},
+		{"too long", strings.Repeat("a", maxDeviceIDLength+1), "", "", ""},
+		{"long user agent", "a", strings.Repeat("ü", maxDeviceNameLength+5), "a", strings.Repeat("ü", maxDeviceNameLength)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest("PUT", "/api/v1/entries/1", nil)
+			if tt.id != "" {
+				r.Header.Set(deviceIDHeader, tt.id)
+			}
+			if tt.userAgent != "" {
+				r.Header.Set("User-Agent", tt.userAgent)
+			}
+			got := requestDevice(r)
+			if got.ID != tt.wantID || got.Name != tt.wantName {
+				t.Errorf("requestDevice() = %+v, want id %q name %q", got, tt.wantID, tt.wantName)
+			}
+		})
+	}
+}
+
+func TestRequestTokenClient(t *testing.T) {
+	tests := []struct {
+		name       string
+		remoteAddr string
+		wantIP     string
+	}{
+		{"host and port", "203.0.113.7:52114", "203.0.113.7"},
+		{"set by RealIP", "2001:db8::1", "2001:db8::1"},
+		{"not an address", "proxy.internal", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest("POST", "/api/v1/auth/refresh", nil)
+			r.RemoteAddr = tt.remoteAddr
+			r.Header.Set(deviceIDHeader, "device-1")
+			r.Header.Set("User-Agent", "Livlog/2.3 iOS/18.1")
+
+			got := requestTokenClient(r)
+			if got.IP != tt.wantIP || got.DeviceID != "device-1" || got.UserAgent != "Livlog/2.3 iOS/18.1" {
+				t.Errorf("requestTokenClient() = %+v, want ip %q", got, tt.wantIP)
+			}
+		})
+	}
+}
@@ -2,25 +2,22 @@ package handler
 
 import (
 	"encoding/base64"
-	"encoding/json"
 	"errors"
 	"fmt"
 	"net/http"
 	"strconv"
 	"time"
 
+	"github.com/avalarin/livlog/backend/internal/apitime"
+	"github.com/avalarin/livlog/backend/internal/apiv1"
+	"github.com/avalarin/livlog/backend/internal/apperror"
+	"github.com/avalarin/livlog/backend/internal/middleware"
 	"github.com/avalarin/livlog/backend/internal/repository"
 	"github.com/avalarin/livlog/backend/internal/service"
 	"github.com/go-chi/chi/v5"
 	"github.com/google/uuid"
 )
 
-type imageMetaResponse struct {
-	ID       string `json:"id"`
-	IsCover  bool   `json:"is_cover"`
-	Position int    `json:"position"`
-}
-
 type EntryHandler struct {
 	entryService *service.EntryService
 }
@@ -33,12 +30,29 @@ func NewEntryHandler(entryService *service.EntryService) *EntryHandler {
 
 func (h *EntryHandler) RegisterRoutes(r chi.Router) {
 	r.Get("/entries", h.GetEntries)
+	r.Head("/entries", h.HeadEntries)
+	r.Get("/entries/changed-since", h.GetEntriesChangedSince)
+	r.Get("/entries/recent", h.GetRecentEntries)
 	r.Post("/entries", h.CreateEntry)
-	r.Delete("/entries", h.BulkDeleteEntries)
-	r.Get("/entries/search", h.SearchEntries)
 	r.Get("/entries/{id}", h.GetEntry)
 	r.Put("/entries/{id}", h.UpdateEntry)
 	r.Delete("/entries/{id}", h.DeleteEntry)
+	r.Put("/entries/{id}/cover", h.SetEntryCover)
+	r.Post("/entries/{id}/viewed", h.RecordEntryView)
+	r.Put("/collections/{id}/entries/order", h.SetEntryOrder)
+}
+
+// RegisterSearchRoutes registers full-text search, which is rate limited
+// separately from the other entry routes.
+func (h *EntryHandler) RegisterSearchRoutes(r chi.Router) {
+	r.Get("/entries/search", h.SearchEntries)
+}
+
+// RegisterBulkRoutes registers operations on many entries at once, which are
+// rate limited separately from the other entry routes.
+func (h *EntryHandler) RegisterBulkRoutes(r chi.Router) {
+	r.Delete("/entries", h.BulkDeleteEntries)
+	r.Post("/entries/bulk-score", h.BulkScoreEntries)
 }
 
 // RegisterPublicRoutes registers routes that do not require authentication.
@@ -47,376 +61,672 @@ func (h *EntryHandler) RegisterPublicRoutes(r chi.Router) {
 }
 
 type imageData struct {
-	Data     string `json:"data"`      // base64 encoded
+	Data     string `json:"data" validate:"required,base64"`
 	IsCover  bool   `json:"is_cover"`
-	Position int    `json:"position"`
+	Position int    `json:"position" validate:"min=0"`
 }
 
 type createEntryRequest struct {
-	CollectionID     *string           `json:"collection_id,omitempty"`
-	TypeID           *string           `json:"type_id,omitempty"`
-	Title            string            `json:"title"`
-	Description      string            `json:"description"`
-	Score            int               `json:"score"`
-	Date             string            `json:"date"` // YYYY-MM-DD
+	CollectionID     *string           `json:"collection_id,omitempty" validate:"omitempty,uuid"`
+	TypeID           *string           `json:"type_id,omitempty" validate:"omitempty,uuid"`
+	Title            string            `json:"title" validate:"required,max=200"`
+	OriginalTitle    *string           `json:"original_title,omitempty" validate:"omitempty,max=200"`
+	Language         *string           `json:"language,omitempty" validate:"omitempty,max=35"`
+	Description      string            `json:"description" validate:"required,max=2000"`
+	Score            int               `json:"score" validate:"min=0,max=10"`
+	Priority         *int              `json:"priority,omitempty" validate:"omitempty,min=0,max=5"`
+	Visibility       *string           `json:"visibility,omitempty" validate:"omitempty,oneof=private shared public"`
+	Date             string            `json:"date" validate:"required,date"` // YYYY-MM-DD
 	AdditionalFields map[string]string `json:"additional_fields,omitempty"`
-	Images           []imageData       `json:"images,omitempty"`
-	SeedImageIDs     []string          `json:"seed_image_ids,omitempty"`
+	Images           []imageData       `json:"images,omitempty" validate:"dive"`
+	SeedImageIDs     []string          `json:"seed_image_ids,omitempty" validate:"dive,uuid"`
 }
 
-type entryResponse struct {
-	ID               string              `json:"id"`
-	CollectionID     *string             `json:"collection_id,omitempty"`
-	TypeID           *string             `json:"type_id,omitempty"`
-	Title            string              `json:"title"`
-	Description      string              `json:"description"`
-	Score            int                 `json:"score"`
-	Date             string              `json:"date"`
-	AdditionalFields map[string]string   `json:"additional_fields"`
-	Images           []imageMetaResponse `json:"images"`
-	CreatedAt        string              `json:"created_at"`
-	UpdatedAt        string              `json:"updated_at"`
+// parsedEntryRequest holds createEntryRequest fields converted to domain types.
+type parsedEntryRequest struct {
+	collectionID  *uuid.UUID
+	typeID        *uuid.UUID
+	priority      int    // 0 if not provided
+	visibility    string // "" if not provided
+	originalTitle string // "" if not provided
+	language      string // "" if not provided
+	date          time.Time
+	images        []repository.EntryImage // nil if not provided
+	seedImageIDs  []uuid.UUID
+}
+
+// parse converts a validated request. Errors are not expected after
+// validation but are still returned rather than panicking.
+func (req *createEntryRequest) parse() (*parsedEntryRequest, error) {
+	var p parsedEntryRequest
+	var err error
+
+	if p.collectionID, err = parseOptionalUUID(req.CollectionID); err != nil {
+		return nil, err
+	}
+	if p.typeID, err = parseOptionalUUID(req.TypeID); err != nil {
+		return nil, err
+	}
+	if p.date, err = time.Parse(dateLayout, req.Date); err != nil {
+		return nil, err
+	}
+	if req.Priority != nil {
+		p.priority = *req.Priority
+	}
+	if req.Visibility != nil {
+		p.visibility = *req.Visibility
+	}
+	if req.OriginalTitle != nil {
+		p.originalTitle = *req.OriginalTitle
+	}
+	if req.Language != nil {
+		p.language = *req.Language
+	}
+
+	for _, img := range req.Images {
+		imageBytes, err := base64.StdEncoding.DecodeString(img.Data)
+		if err != nil {
+			return nil, err
+		}
+		p.images = append(p.images, repository.EntryImage{
+			ImageData: imageBytes,
+			IsCover:   img.IsCover,
+			Position:  img.Position,
+		})
+	}
+
+	for _, idStr := range req.SeedImageIDs {
+		sid, err := uuid.Parse(idStr)
+		if err != nil {
+			return nil, err
+		}
+		p.seedImageIDs = append(p.seedImageIDs, sid)
+	}
+
+	return &p, nil
 }
 
+func parseOptionalUUID(s *string) (*uuid.UUID, error) {
+	if s == nil {
+		return nil, nil
+	}
+	id, err := uuid.Parse(*s)
+	if err != nil {
+		return nil, err
+	}
+	return &id, nil
+}
+
+// entryWriteResponse is the entry as saved by a create or update, with the
+// warnings about it the service raised; see service.Warning. An update made
+// shortly after another device's carries that device, see
+// service.ConcurrentUpdate.
+type entryWriteResponse struct {
+	apiv1.Entry
+	Warnings         []service.Warning         `json:"warnings"`
+	ConcurrentUpdate bool                      `json:"concurrent_update"`
+	ConcurrentDevice *concurrentDeviceResponse `json:"concurrent_device,omitempty"`
+}
+
+type concurrentDeviceResponse struct {
+	ID        string `json:"id"`
+	Name      string `json:"name"`
+	UpdatedAt string `json:"updated_at"`
+}
+
+// recentEntryResponse is an entry in GET /entries/recent, with when the user
+// last opened it.
+type recentEntryResponse struct {
+	apiv1.Entry
+	ViewedAt string `json:"viewed_at"`
+}
+
+type entryViewResponse struct {
+	ViewedAt string `json:"viewed_at"`
+}
 
 func (h *EntryHandler) GetEntries(w http.ResponseWriter, r *http.Request) {
-	userID := getUserIDFromContext(r.Context())
+	userID := middleware.GetUserIDFromContext(r.Context())
 	if userID == "" {
-		respondWithError(w, http.StatusUnauthorized, "User not authenticated", nil)
+		respondWithError(w, r, apperror.Unauthorized("User not authenticated", nil))
 		return
 	}
 
 	uid, err := uuid.Parse(userID)
 	if err != nil {
-		respondWithError(w, http.StatusBadRequest, "Invalid user ID", err)
+		respondWithError(w, r, apperror.BadRequest("Invalid user ID", err))
 		return
 	}
 
 	// Parse query parameters
+	filter, appErr := parseEntryFilter(r)
+	if appErr != nil {
+		respondWithError(w, r, appErr)
+		return
+	}
+
+	limit, offset, appErr := offsetParams(r)
+	if appErr != nil {
+		respondWithError(w, r, appErr)
+		return
+	}
+
+	fields, appErr := parseEntryFields(r)
+	if appErr != nil {
+		respondWithError(w, r, appErr)
+		return
+	}
+
+	if fields != nil {
+		entries, err := h.entryService.ListEntryFields(r.Context(), uid, filter, fields, limit, offset)
+		if err != nil {
+			respondWithError(w, r, listEntriesError(err))
+			return
+		}
+		respondWithEntryFields(w, r, entries, fields)
+		return
+	}
+
+	entries, err := h.entryService.ListEntriesWithImages(r.Context(), uid, filter, limit, offset)
+	if err != nil {
+		respondWithError(w, r, listEntriesError(err))
+		return
+	}
+
+	response := make([]apiv1.Entry, len(entries))
+	for i, e := range entries {
+		response[i] = apiv1.MapEntry(e.Entry, e.Images, timeLayout(r))
+	}
+
+	respondWithJSON(w, http.StatusOK, response)
+}
+
+// parseEntryFilter reads the collection_id, min_priority and sort query
+// parameters of an entry list.
+func parseEntryFilter(r *http.Request) (repository.EntryFilter, *apperror.Error) {
+	filter := repository.EntryFilter{Sort: repository.EntrySort(r.URL.Query().Get("sort"))}
+	if collectionParam := r.URL.Query().Get("collection_id"); collectionParam != "" {
+		cid, err := uuid.Parse(collectionParam)
+		if err != nil {
+			return filter, apperror.BadRequest("Invalid collection ID", err)
+		}
+		filter.CollectionID = &cid
+	}
+
+	if v := r.URL.Query().Get("min_priority"); v != "" {
+		minPriority, err := strconv.Atoi(v)
+		if err != nil {
+			return filter, apperror.BadRequest("Invalid min_priority", err)
+		}
+		filter.MinPriority = minPriority
+	}
+
+	return filter, nil
+}
+
+func listEntriesError(err error) *apperror.Error {
+	if errors.Is(err, service.ErrInvalidSort) ||
+		errors.Is(err, service.ErrManualSortScope) ||
+		errors.Is(err, service.ErrInvalidPriority) {
+		return apperror.Validation(err.Error(), err)
+	}
+	return apperror.Internal("Failed to get entries", err)
+}
+
+// HeadEntries lets clients check whether their cached list is stale without
+// downloading it: X-Total-Count has the number of entries (optionally in
+// collection_id) and Last-Modified the latest update among them.
+func (h *EntryHandler) HeadEntries(w http.ResponseWriter, r *http.Request) {
+	userID := middleware.GetUserIDFromContext(r.Context())
+	if userID == "" {
+		respondWithError(w, r, apperror.Unauthorized("User not authenticated", nil))
+		return
+	}
+
+	uid, err := uuid.Parse(userID)
+	if err != nil {
+		respondWithError(w, r, apperror.BadRequest("Invalid user ID", err))
+		return
+	}
+
 	var collectionID *uuid.UUID
 	if collectionParam := r.URL.Query().Get("collection_id"); collectionParam != "" {
 		cid, err := uuid.Parse(collectionParam)
 		if err != nil {
-			respondWithError(w, http.StatusBadRequest, "Invalid collection ID", err)
+			respondWithError(w, r, apperror.BadRequest("Invalid collection ID", err))
 			return
 		}
 		collectionID = &cid
 	}
 
-	limit, _ := strconv.Atoi(r.URL.Query().Get("limit"))
-	if limit == 0 {
-		limit = 50
+	count, lastUpdated, err := h.entryService.GetEntriesSummary(r.Context(), uid, collectionID)
+	if err != nil {
+		respondWithError(w, r, apperror.Internal("Failed to count entries", err))
+		return
 	}
 
-	offset, _ := strconv.Atoi(r.URL.Query().Get("offset"))
+	w.Header().Set("X-Total-Count", strconv.Itoa(count))
+	if lastUpdated != nil {
+		w.Header().Set("Last-Modified", lastUpdated.UTC().Format(http.TimeFormat))
+	}
+	w.WriteHeader(http.StatusOK)
+}
 
-	entries, err := h.entryService.GetEntriesByUserID(r.Context(), uid, collectionID, limit, offset)
+type entryChangeResponse struct {
+	ID        string `json:"id"`
+	UpdatedAt string `json:"updated_at"`
+}
+
+// GetEntriesChangedSince lists the ids of entries created or updated after ts,
+// so clients can refetch only those. Deletions are not listed; compare
+// X-Total-Count from HEAD /entries or use sync for them.
+func (h *EntryHandler) GetEntriesChangedSince(w http.ResponseWriter, r *http.Request) {
+	userID := middleware.GetUserIDFromContext(r.Context())
+	if userID == "" {
+		respondWithError(w, r, apperror.Unauthorized("User not authenticated", nil))
+		return
+	}
+
+	uid, err := uuid.Parse(userID)
 	if err != nil {
-		respondWithError(w, http.StatusInternalServerError, "Failed to get entries", err)
+		respondWithError(w, r, apperror.BadRequest("Invalid user ID", err))
 		return
 	}
 
-	// Batch fetch image metadata for all entries
-	entryIDs := make([]uuid.UUID, len(entries))
-	for i, e := range entries {
-		entryIDs[i] = e.ID
+	since, err := apitime.Parse(r.URL.Query().Get("ts"))
+	if err != nil {
+		respondWithError(w, r, apperror.BadRequest("ts must be an RFC 3339 timestamp", err))
+		return
 	}
-	imageMetasMap, err := h.entryService.GetImageMetasByEntryIDs(r.Context(), entryIDs)
+
+	changes, err := h.entryService.ListEntriesChangedSince(r.Context(), uid, since)
 	if err != nil {
-		respondWithError(w, http.StatusInternalServerError, "Failed to get image metadata", err)
+		respondWithError(w, r, apperror.Internal("Failed to get changed entries", err))
 		return
 	}
 
-	response := make([]entryResponse, len(entries))
-	for i, e := range entries {
-		response[i] = mapEntryToResponse(e, imageMetasMap[e.ID])
+	layout := timeLayout(r)
+	if layout == apitime.Legacy {
+		// v1 has always sent full precision here, for clients to pass back as ts
+		layout = time.RFC3339Nano
+	}
+	response := make([]entryChangeResponse, len(changes))
+	for i, c := range changes {
+		response[i] = entryChangeResponse{
+			ID:        c.ID.String(),
+			UpdatedAt: layout.Format(c.UpdatedAt),
+		}
 	}
 
 	respondWithJSON(w, http.StatusOK, response)
 }
 
-func (h *EntryHandler) CreateEntry(w http.ResponseWriter, r *http.Request) {
-	userID := getUserIDFromContext(r.Context())
+// GetRecentEntries returns the entries the user opened most recently on any
+// device, optionally only those in collection_id.
+func (h *EntryHandler) GetRecentEntries(w http.ResponseWriter, r *http.Request) {
+	userID := middleware.GetUserIDFromContext(r.Context())
 	if userID == "" {
-		respondWithError(w, http.StatusUnauthorized, "User not authenticated", nil)
+		respondWithError(w, r, apperror.Unauthorized("User not authenticated", nil))
 		return
 	}
 
 	uid, err := uuid.Parse(userID)
 	if err != nil {
-		respondWithError(w, http.StatusBadRequest, "Invalid user ID", err)
-		return
-	}
-
-	var req createEntryRequest
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		respondWithError(w, http.StatusBadRequest, "Invalid request body", err)
+		respondWithError(w, r, apperror.BadRequest("Invalid user ID", err))
 		return
 	}
 
-	// Parse collection ID
 	var collectionID *uuid.UUID
-	if req.CollectionID != nil {
-		cid, err := uuid.Parse(*req.CollectionID)
+	if collectionParam := r.URL.Query().Get("collection_id"); collectionParam != "" {
+		cid, err := uuid.Parse(collectionParam)
 		if err != nil {
-			respondWithError(w, http.StatusBadRequest, "Invalid collection ID", err)
+			respondWithError(w, r, apperror.BadRequest("Invalid collection ID", err))
 			return
 		}
 		collectionID = &cid
 	}
 
-	// Parse type ID
-	var typeID *uuid.UUID
-	if req.TypeID != nil {
-		tid, err := uuid.Parse(*req.TypeID)
-		if err != nil {
-			respondWithError(w, http.StatusBadRequest, "Invalid type ID", err)
-			return
-		}
-		typeID = &tid
+	limit, appErr := limitParam(r)
+	if appErr != nil {
+		respondWithError(w, r, appErr)
+		return
 	}
 
-	// Parse date
-	date, err := time.Parse("2006-01-02", req.Date)
+	entries, err := h.entryService.ListRecentEntries(r.Context(), uid, collectionID, limit)
 	if err != nil {
-		respondWithError(w, http.StatusBadRequest, "Invalid date format (use YYYY-MM-DD)", err)
+		respondWithError(w, r, apperror.Internal("Failed to get recent entries", err))
 		return
 	}
 
-	// Parse images
-	var images []repository.EntryImage
-	for _, img := range req.Images {
-		imageBytes, err := base64.StdEncoding.DecodeString(img.Data)
-		if err != nil {
-			respondWithError(w, http.StatusBadRequest, "Invalid image data", err)
-			return
+	layout := timeLayout(r)
+	response := make([]recentEntryResponse, len(entries))
+	for i, e := range entries {
+		response[i] = recentEntryResponse{
+			Entry:    apiv1.MapEntry(e.Entry, e.Images, layout),
+			ViewedAt: layout.Format(e.ViewedAt),
 		}
-		images = append(images, repository.EntryImage{
-			ImageData: imageBytes,
-			IsCover:   img.IsCover,
-			Position:  img.Position,
-		})
 	}
 
-	// Parse seed image IDs
-	var seedImageIDs []uuid.UUID
-	for _, idStr := range req.SeedImageIDs {
-		sid, err := uuid.Parse(idStr)
-		if err != nil {
-			respondWithError(w, http.StatusBadRequest, "Invalid seed image ID", err)
+	respondWithJSON(w, http.StatusOK, response)
+}
+
+// RecordEntryView puts the entry first in the user's recent entries. The app
+// calls it when an entry is opened; reads alone don't count, so syncing and
+// prefetching leave the list alone.
+func (h *EntryHandler) RecordEntryView(w http.ResponseWriter, r *http.Request) {
+	userID := middleware.GetUserIDFromContext(r.Context())
+	if userID == "" {
+		respondWithError(w, r, apperror.Unauthorized("User not authenticated", nil))
+		return
+	}
+
+	uid, err := uuid.Parse(userID)
+	if err != nil {
+		respondWithError(w, r, apperror.BadRequest("Invalid user ID", err))
+		return
+	}
+
+	eid, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		respondWithError(w, r, apperror.BadRequest("Invalid entry ID", err))
+		return
+	}
+
+	viewedAt, err := h.entryService.RecordEntryView(r.Context(), eid, uid)
+	if err != nil {
+		if errors.Is(err, repository.ErrEntryNotFound) {
+			respondWithError(w, r, apperror.Wrap(err, apperror.CodeEntryNotFound, "Entry not found"))
 			return
 		}
-		seedImageIDs = append(seedImageIDs, sid)
+		respondWithError(w, r, apperror.Internal("Failed to record entry view", err))
+		return
 	}
 
+	respondWithJSON(w, http.StatusOK, entryViewResponse{ViewedAt: timeLayout(r).Format(viewedAt)})
+}
+
+func (h *EntryHandler) CreateEntry(w http.ResponseWriter, r *http.Request) {
+	userID := middleware.GetUserIDFromContext(r.Context())
+	if userID == "" {
+		respondWithError(w, r, apperror.Unauthorized("User not authenticated", nil))
+		return
+	}
+
+	uid, err := uuid.Parse(userID)
+	if err != nil {
+		respondWithError(w, r, apperror.BadRequest("Invalid user ID", err))
+		return
+	}
+
+	var req createEntryRequest
+	if appErr := decodeAndValidate(r, &req); appErr != nil {
+		respondWithError(w, r, appErr)
+		return
+	}
+
+	parsed, err := req.parse()
+	if err != nil {
+		respondWithError(w, r, apperror.BadRequest("Invalid request body", err))
+		return
+	}
+
+	ctx := service.WithWarnings(r.Context())
 	entry, err := h.entryService.CreateEntry(
-		r.Context(),
+		ctx,
 		uid,
-		collectionID,
-		typeID,
+		parsed.collectionID,
+		parsed.typeID,
 		req.Title,
 		req.Description,
+		parsed.originalTitle,
+		parsed.language,
 		req.Score,
-		date,
+		parsed.priority,
+		parsed.visibility,
+		parsed.date,
 		req.AdditionalFields,
-		images,
-		seedImageIDs,
+		parsed.images,
+		parsed.seedImageIDs,
+		entryDevice(r),
 	)
 	if err != nil {
 		if errors.Is(err, service.ErrInvalidTitle) ||
 			errors.Is(err, service.ErrInvalidDescription) ||
 			errors.Is(err, service.ErrInvalidScore) ||
 			errors.Is(err, service.ErrInvalidFieldValue) ||
+			errors.Is(err, service.ErrInvalidPriority) ||
+			errors.Is(err, service.ErrInvalidVisibility) ||
+			errors.Is(err, service.ErrInvalidOriginalTitle) ||
+			errors.Is(err, service.ErrInvalidLanguage) ||
+			errors.Is(err, service.ErrTypeNotAllowed) ||
 			errors.Is(err, repository.ErrTypeNotFound) {
-			respondWithError(w, http.StatusBadRequest, err.Error(), err)
+			respondWithError(w, r, apperror.Validation(err.Error(), err))
 			return
 		}
-		respondWithError(w, http.StatusInternalServerError, "Failed to create entry", err)
+		if errors.Is(err, service.ErrQuotaExceeded) {
+			respondWithError(w, r, quotaError(err))
+			return
+		}
+		respondWithError(w, r, apperror.Internal("Failed to create entry", err))
 		return
 	}
 
 	imageMetas, _ := h.entryService.GetEntryImageMetas(r.Context(), entry.ID)
-	respondWithJSON(w, http.StatusCreated, mapEntryToResponse(entry, imageMetas))
+	respondWithJSON(w, http.StatusCreated, mapEntryToWriteResponse(entry, imageMetas, service.Warnings(ctx), nil, timeLayout(r)))
 }
 
 func (h *EntryHandler) GetEntry(w http.ResponseWriter, r *http.Request) {
-	userID := getUserIDFromContext(r.Context())
+	userID := middleware.GetUserIDFromContext(r.Context())
 	if userID == "" {
-		respondWithError(w, http.StatusUnauthorized, "User not authenticated", nil)
+		respondWithError(w, r, apperror.Unauthorized("User not authenticated", nil))
 		return
 	}
 
 	uid, err := uuid.Parse(userID)
 	if err != nil {
-		respondWithError(w, http.StatusBadRequest, "Invalid user ID", err)
+		respondWithError(w, r, apperror.BadRequest("Invalid user ID", err))
 		return
 	}
 
 	entryID := chi.URLParam(r, "id")
 	eid, err := uuid.Parse(entryID)
 	if err != nil {
-		respondWithError(w, http.StatusBadRequest, "Invalid entry ID", err)
+		respondWithError(w, r, apperror.BadRequest("Invalid entry ID", err))
 		return
 	}
 
 	entry, err := h.entryService.GetEntryByID(r.Context(), eid, uid)
 	if err != nil {
 		if errors.Is(err, repository.ErrEntryNotFound) {
-			respondWithError(w, http.StatusNotFound, "Entry not found", err)
+			respondWithError(w, r, apperror.Wrap(err, apperror.CodeEntryNotFound, "Entry not found"))
 			return
 		}
-		respondWithError(w, http.StatusInternalServerError, "Failed to get entry", err)
+		respondWithError(w, r, apperror.Internal("Failed to get entry", err))
+		return
+	}
+
+	history, err := h.entryService.GetScoreHistory(r.Context(), entry.ID)
+	if err != nil {
+		respondWithError(w, r, apperror.Internal("Failed to get score history", err))
 		return
 	}
 
 	imageMetas, _ := h.entryService.GetEntryImageMetas(r.Context(), entry.ID)
-	respondWithJSON(w, http.StatusOK, mapEntryToResponse(entry, imageMetas))
+	respondWithJSON(w, http.StatusOK, apiv1.MapEntryDetail(entry, imageMetas, history, timeLayout(r)))
 }
 
 func (h *EntryHandler) UpdateEntry(w http.ResponseWriter, r *http.Request) {
-	userID := getUserIDFromContext(r.Context())
+	userID := middleware.GetUserIDFromContext(r.Context())
 	if userID == "" {
-		respondWithError(w, http.StatusUnauthorized, "User not authenticated", nil)
+		respondWithError(w, r, apperror.Unauthorized("User not authenticated", nil))
 		return
 	}
 
 	uid, err := uuid.Parse(userID)
 	if err != nil {
-		respondWithError(w, http.StatusBadRequest, "Invalid user ID", err)
+		respondWithError(w, r, apperror.BadRequest("Invalid user ID", err))
 		return
 	}
 
 	entryID := chi.URLParam(r, "id")
 	eid, err := uuid.Parse(entryID)
 	if err != nil {
-		respondWithError(w, http.StatusBadRequest, "Invalid entry ID", err)
+		respondWithError(w, r, apperror.BadRequest("Invalid entry ID", err))
 		return
 	}
 
 	var req createEntryRequest
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		respondWithError(w, http.StatusBadRequest, "Invalid request body", err)
+	if appErr := decodeAndValidate(r, &req); appErr != nil {
+		respondWithError(w, r, appErr)
 		return
 	}
 
-	// Parse collection ID
-	var collectionID *uuid.UUID
-	if req.CollectionID != nil {
-		cid, err := uuid.Parse(*req.CollectionID)
-		if err != nil {
-			respondWithError(w, http.StatusBadRequest, "Invalid collection ID", err)
-			return
-		}
-		collectionID = &cid
-	}
-
-	// Parse type ID
-	var typeID *uuid.UUID
-	if req.TypeID != nil {
-		tid, err := uuid.Parse(*req.TypeID)
-		if err != nil {
-			respondWithError(w, http.StatusBadRequest, "Invalid type ID", err)
-			return
-		}
-		typeID = &tid
-	}
-
-	// Parse date
-	date, err := time.Parse("2006-01-02", req.Date)
+	parsed, err := req.parse()
 	if err != nil {
-		respondWithError(w, http.StatusBadRequest, "Invalid date format (use YYYY-MM-DD)", err)
+		respondWithError(w, r, apperror.BadRequest("Invalid request body", err))
 		return
 	}
 
-	// Parse images (nil if not provided, means don't update images)
-	var images []repository.EntryImage
-	if req.Images != nil {
-		for _, img := range req.Images {
-			imageBytes, err := base64.StdEncoding.DecodeString(img.Data)
-			if err != nil {
-				respondWithError(w, http.StatusBadRequest, "Invalid image data", err)
-				return
-			}
-			images = append(images, repository.EntryImage{
-				ImageData: imageBytes,
-				IsCover:   img.IsCover,
-				Position:  img.Position,
-			})
-		}
-	}
-
-	entry, err := h.entryService.UpdateEntry(
-		r.Context(),
+	ctx := service.WithWarnings(r.Context())
+	entry, concurrent, err := h.entryService.UpdateEntry(
+		ctx,
 		eid,
 		uid,
-		collectionID,
-		typeID,
+		parsed.collectionID,
+		parsed.typeID,
 		req.Title,
 		req.Description,
+		req.OriginalTitle,
+		req.Language,
 		req.Score,
-		date,
+		req.Priority,
+		req.Visibility,
+		parsed.date,
 		req.AdditionalFields,
-		images,
+		parsed.images,
+		entryDevice(r),
 	)
 	if err != nil {
 		if errors.Is(err, repository.ErrEntryNotFound) {
-			respondWithError(w, http.StatusNotFound, "Entry not found", err)
+			respondWithError(w, r, apperror.Wrap(err, apperror.CodeEntryNotFound, "Entry not found"))
 			return
 		}
 		if errors.Is(err, service.ErrInvalidTitle) ||
 			errors.Is(err, service.ErrInvalidDescription) ||
 			errors.Is(err, service.ErrInvalidScore) ||
 			errors.Is(err, service.ErrInvalidFieldValue) ||
+			errors.Is(err, service.ErrInvalidPriority) ||
+			errors.Is(err, service.ErrInvalidVisibility) ||
+			errors.Is(err, service.ErrInvalidOriginalTitle) ||
+			errors.Is(err, service.ErrInvalidLanguage) ||
+			errors.Is(err, service.ErrTypeNotAllowed) ||
 			errors.Is(err, repository.ErrTypeNotFound) {
-			respondWithError(w, http.StatusBadRequest, err.Error(), err)
+			respondWithError(w, r, apperror.Validation(err.Error(), err))
+			return
+		}
+		if errors.Is(err, service.ErrQuotaExceeded) {
+			respondWithError(w, r, quotaError(err))
 			return
 		}
-		respondWithError(w, http.StatusInternalServerError, "Failed to update entry", err)
+		respondWithError(w, r, apperror.Internal("Failed to update entry", err))
 		return
 	}
 
 	imageMetas, _ := h.entryService.GetEntryImageMetas(r.Context(), entry.ID)
-	respondWithJSON(w, http.StatusOK, mapEntryToResponse(entry, imageMetas))
+	respondWithJSON(w, http.StatusOK, mapEntryToWriteResponse(entry, imageMetas, service.Warnings(ctx), concurrent, timeLayout(r)))
 }
 
 func (h *EntryHandler) DeleteEntry(w http.ResponseWriter, r *http.Request) {
-	userID := getUserIDFromContext(r.Context())
+	userID := middleware.GetUserIDFromContext(r.Context())
 	if userID == "" {
-		respondWithError(w, http.StatusUnauthorized, "User not authenticated", nil)
+		respondWithError(w, r, apperror.Unauthorized("User not authenticated", nil))
 		return
 	}
 
 	uid, err := uuid.Parse(userID)
 	if err != nil {
-		respondWithError(w, http.StatusBadRequest, "Invalid user ID", err)
+		respondWithError(w, r, apperror.BadRequest("Invalid user ID", err))
 		return
 	}
 
 	entryID := chi.URLParam(r, "id")
 	eid, err := uuid.Parse(entryID)
 	if err != nil {
-		respondWithError(w, http.StatusBadRequest, "Invalid entry ID", err)
+		respondWithError(w, r, apperror.BadRequest("Invalid entry ID", err))
 		return
 	}
 
 	err = h.entryService.DeleteEntry(r.Context(), eid, uid)
 	if err != nil {
 		if errors.Is(err, repository.ErrEntryNotFound) {
-			respondWithError(w, http.StatusNotFound, "Entry not found", err)
+			respondWithError(w, r, apperror.Wrap(err, apperror.CodeEntryNotFound, "Entry not found"))
 			return
 		}
-		respondWithError(w, http.StatusInternalServerError, "Failed to delete entry", err)
+		respondWithError(w, r, apperror.Internal("Failed to delete entry", err))
 		return
 	}
 
 	respondWithJSON(w, http.StatusOK, map[string]string{"message": "Entry deleted successfully"})
 }
 
+type entryCoverRequest struct {
+	ImageID string `json:"image_id" validate:"required,uuid"`
+}
+
+// SetEntryCover makes one of the entry's existing images its cover.
+func (h *EntryHandler) SetEntryCover(w http.ResponseWriter, r *http.Request) {
+	userID := middleware.GetUserIDFromContext(r.Context())
+	if userID == "" {
+		respondWithError(w, r, apperror.Unauthorized("User not authenticated", nil))
+		return
+	}
+
+	uid, err := uuid.Parse(userID)
+	if err != nil {
+		respondWithError(w, r, apperror.BadRequest("Invalid user ID", err))
+		return
+	}
+
+	eid, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		respondWithError(w, r, apperror.BadRequest("Invalid entry ID", err))
+		return
+	}
+
+	var req entryCoverRequest
+	if appErr := decodeAndValidate(r, &req); appErr != nil {
+		respondWithError(w, r, appErr)
+		return
+	}
+	imageID, err := uuid.Parse(req.ImageID)
+	if err != nil {
+		respondWithError(w, r, apperror.BadRequest("Invalid image ID", err))
+		return
+	}
+
+	entry, err := h.entryService.SetEntryCover(r.Context(), eid, uid, imageID)
+	if err != nil {
+		if errors.Is(err, repository.ErrEntryNotFound) {
+			respondWithError(w, r, apperror.Wrap(err, apperror.CodeEntryNotFound, "Entry not found"))
+			return
+		}
+		if errors.Is(err, repository.ErrImageNotFound) {
+			respondWithError(w, r, apperror.Wrap(err, apperror.CodeImageNotFound, "Image not found"))
+			return
+		}
+		respondWithError(w, r, apperror.Internal("Failed to set cover", err))
+		return
+	}
+
+	imageMetas, _ := h.entryService.GetEntryImageMetas(r.Context(), entry.ID)
+	respondWithJSON(w, http.StatusOK, apiv1.MapEntry(entry, imageMetas, timeLayout(r)))
+}
+
 func (h *EntryHandler) GetImage(w http.ResponseWriter, r *http.Request) {
 	imageID := chi.URLParam(r, "id")
 	imgID, err := uuid.Parse(imageID)
 	if err != nil {
-		respondWithError(w, http.StatusBadRequest, "Invalid image ID", err)
+		respondWithError(w, r, apperror.BadRequest("Invalid image ID", err))
 		return
 	}
 
@@ -432,93 +742,219 @@ func (h *EntryHandler) GetImage(w http.ResponseWriter, r *http.Request) {
 	img, err := h.entryService.GetImageByID(r.Context(), imgID)
 	if err != nil {
 		if errors.Is(err, repository.ErrEntryNotFound) {
-			respondWithError(w, http.StatusNotFound, "Image not found", err)
+			respondWithError(w, r, apperror.Wrap(err, apperror.CodeImageNotFound, "Image not found"))
 			return
 		}
-		respondWithError(w, http.StatusInternalServerError, "Failed to get image", err)
+		respondWithError(w, r, apperror.Internal("Failed to get image", err))
 		return
 	}
 
-	w.Header().Set("Content-Type", "image/jpeg")
-	w.WriteHeader(http.StatusOK)
-	w.Write(img.ImageData)
+	writeImage(w, "image/jpeg", img.ImageData)
 }
 
 type bulkDeleteRequest struct {
-	IDs []string `json:"ids"`
+	IDs []string `json:"ids" validate:"required,min=1,max=100,dive,uuid"`
 }
 
 func (h *EntryHandler) BulkDeleteEntries(w http.ResponseWriter, r *http.Request) {
-	userID := getUserIDFromContext(r.Context())
+	userID := middleware.GetUserIDFromContext(r.Context())
 	if userID == "" {
-		respondWithError(w, http.StatusUnauthorized, "User not authenticated", nil)
+		respondWithError(w, r, apperror.Unauthorized("User not authenticated", nil))
 		return
 	}
 
 	uid, err := uuid.Parse(userID)
 	if err != nil {
-		respondWithError(w, http.StatusBadRequest, "Invalid user ID", err)
+		respondWithError(w, r, apperror.BadRequest("Invalid user ID", err))
 		return
 	}
 
 	var req bulkDeleteRequest
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		respondWithError(w, http.StatusBadRequest, "Invalid request body", err)
+	if appErr := decodeAndValidate(r, &req); appErr != nil {
+		respondWithError(w, r, appErr)
+		return
+	}
+
+	ids := make([]uuid.UUID, 0, len(req.IDs))
+	for _, idStr := range req.IDs {
+		id, err := uuid.Parse(idStr)
+		if err != nil {
+			respondWithError(w, r, apperror.BadRequest(fmt.Sprintf("Invalid entry ID: %s", idStr), err))
+			return
+		}
+		ids = append(ids, id)
+	}
+
+	count, err := h.entryService.DeleteEntries(r.Context(), ids, uid)
+	if err != nil {
+		respondWithError(w, r, apperror.Internal("Failed to delete entries", err))
 		return
 	}
 
-	if len(req.IDs) == 0 {
-		respondWithError(w, http.StatusBadRequest, "No IDs provided", nil)
+	respondWithJSON(w, http.StatusOK, map[string]int64{"deleted_count": count})
+}
+
+type bulkScoreItemRequest struct {
+	ID    string `json:"id" validate:"required,uuid"`
+	Score *int   `json:"score" validate:"required"`
+}
+
+type bulkScoreRequest struct {
+	Scores []bulkScoreItemRequest `json:"scores" validate:"required,min=1,max=100,unique=ID,dive"`
+}
+
+type bulkScoreResponse struct {
+	Results []syncResultResponse `json:"results"`
+}
+
+// BulkScoreEntries sets the scores of many entries at once, for rating a
+// backlog in one go. Like sync pushes, the request succeeds as a whole and
+// each score reports whether it was applied.
+func (h *EntryHandler) BulkScoreEntries(w http.ResponseWriter, r *http.Request) {
+	userID := middleware.GetUserIDFromContext(r.Context())
+	if userID == "" {
+		respondWithError(w, r, apperror.Unauthorized("User not authenticated", nil))
 		return
 	}
 
-	if len(req.IDs) > 100 {
-		respondWithError(w, http.StatusBadRequest, "Too many IDs: maximum 100", nil)
+	uid, err := uuid.Parse(userID)
+	if err != nil {
+		respondWithError(w, r, apperror.BadRequest("Invalid user ID", err))
 		return
 	}
 
-	ids := make([]uuid.UUID, 0, len(req.IDs))
-	for _, idStr := range req.IDs {
+	var req bulkScoreRequest
+	if appErr := decodeAndValidate(r, &req); appErr != nil {
+		respondWithError(w, r, appErr)
+		return
+	}
+
+	updates := make([]service.ScoreUpdate, len(req.Scores))
+	for i, item := range req.Scores {
+		id, err := uuid.Parse(item.ID)
+		if err != nil {
+			respondWithError(w, r, apperror.BadRequest(fmt.Sprintf("Invalid entry ID: %s", item.ID), err))
+			return
+		}
+		updates[i] = service.ScoreUpdate{EntryID: id, Score: *item.Score}
+	}
+
+	results, err := h.entryService.SetScores(r.Context(), uid, updates)
+	if err != nil {
+		respondWithError(w, r, apperror.Internal("Failed to update scores", err))
+		return
+	}
+
+	response := bulkScoreResponse{Results: make([]syncResultResponse, len(results))}
+	for i, err := range results {
+		result := syncResultResponse{ID: req.Scores[i].ID, Status: "applied"}
+		if err != nil {
+			appErr := mapSyncError(err)
+			result.Status = "failed"
+			result.Error = &syncErrorBody{Code: appErr.Code, Message: appErr.Message}
+		}
+		response.Results[i] = result
+	}
+
+	respondWithJSON(w, http.StatusOK, response)
+}
+
+type entryOrderRequest struct {
+	EntryIDs []string `json:"entry_ids" validate:"required,max=1000,dive,uuid"`
+}
+
+// SetEntryOrder ranks the entries of a collection for sort=manual. Entries
+// left out of entry_ids become unranked and list after the ranked ones.
+func (h *EntryHandler) SetEntryOrder(w http.ResponseWriter, r *http.Request) {
+	userID := middleware.GetUserIDFromContext(r.Context())
+	if userID == "" {
+		respondWithError(w, r, apperror.Unauthorized("User not authenticated", nil))
+		return
+	}
+
+	uid, err := uuid.Parse(userID)
+	if err != nil {
+		respondWithError(w, r, apperror.BadRequest("Invalid user ID", err))
+		return
+	}
+
+	collectionID, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		respondWithError(w, r, apperror.BadRequest("Invalid collection ID", err))
+		return
+	}
+
+	var req entryOrderRequest
+	if appErr := decodeAndValidate(r, &req); appErr != nil {
+		respondWithError(w, r, appErr)
+		return
+	}
+
+	ids := make([]uuid.UUID, 0, len(req.EntryIDs))
+	for _, idStr := range req.EntryIDs {
 		id, err := uuid.Parse(idStr)
 		if err != nil {
-			respondWithError(w, http.StatusBadRequest, fmt.Sprintf("Invalid entry ID: %s", idStr), err)
+			respondWithError(w, r, apperror.BadRequest(fmt.Sprintf("Invalid entry ID: %s", idStr), err))
 			return
 		}
 		ids = append(ids, id)
 	}
 
-	count, err := h.entryService.DeleteEntries(r.Context(), ids, uid)
-	if err != nil {
-		respondWithError(w, http.StatusInternalServerError, "Failed to delete entries", err)
+	if err := h.entryService.SetEntryOrder(r.Context(), uid, collectionID, ids); err != nil {
+		if errors.Is(err, repository.ErrCollectionNotFound) {
+			respondWithError(w, r, apperror.Wrap(err, apperror.CodeCollectionNotFound, "Collection not found"))
+			return
+		}
+		if errors.Is(err, service.ErrInvalidEntryOrder) {
+			respondWithError(w, r, apperror.Validation(service.ErrInvalidEntryOrder.Error(), err))
+			return
+		}
+		respondWithError(w, r, apperror.Internal("Failed to set entry order", err))
 		return
 	}
 
-	respondWithJSON(w, http.StatusOK, map[string]int64{"deleted_count": count})
+	respondWithJSON(w, http.StatusOK, map[string]string{"message": "Entry order updated"})
 }
 
 func (h *EntryHandler) SearchEntries(w http.ResponseWriter, r *http.Request) {
-	userID := getUserIDFromContext(r.Context())
+	userID := middleware.GetUserIDFromContext(r.Context())
 	if userID == "" {
-		respondWithError(w, http.StatusUnauthorized, "User not authenticated", nil)
+		respondWithError(w, r, apperror.Unauthorized("User not authenticated", nil))
 		return
 	}
 
 	uid, err := uuid.Parse(userID)
 	if err != nil {
-		respondWithError(w, http.StatusBadRequest, "Invalid user ID", err)
+		respondWithError(w, r, apperror.BadRequest("Invalid user ID", err))
 		return
 	}
 
 	query := r.URL.Query().Get("q")
-	limit, _ := strconv.Atoi(r.URL.Query().Get("limit"))
-	if limit == 0 {
-		limit = 50
+	limit, offset, appErr := offsetParams(r)
+	if appErr != nil {
+		respondWithError(w, r, appErr)
+		return
+	}
+
+	fields, appErr := parseEntryFields(r)
+	if appErr != nil {
+		respondWithError(w, r, appErr)
+		return
+	}
+
+	if fields != nil {
+		entries, err := h.entryService.SearchEntryFields(r.Context(), uid, query, fields, limit, offset)
+		if err != nil {
+			respondWithError(w, r, apperror.Internal("Failed to search entries", err))
+			return
+		}
+		respondWithEntryFields(w, r, entries, fields)
+		return
 	}
-	offset, _ := strconv.Atoi(r.URL.Query().Get("offset"))
 
 	entries, err := h.entryService.SearchEntries(r.Context(), uid, query, limit, offset)
 	if err != nil {
-		respondWithError(w, http.StatusInternalServerError, "Failed to search entries", err)
+		respondWithError(w, r, apperror.Internal("Failed to search entries", err))
 		return
 	}
 
@@ -527,53 +963,41 @@ func (h *EntryHandler) SearchEntries(w http.ResponseWriter, r *http.Request) {
 	for i, e := range entries {
 		entryIDs[i] = e.ID
 	}
-	imageMetasMap, err := h.entryService.GetImageMetasByEntryIDs(r.Context(), entryIDs)
+	imageMetasMap, err := h.entryService.GetImageMetasByEntryIDs(r.Context(), entryIDs, false)
 	if err != nil {
-		respondWithError(w, http.StatusInternalServerError, "Failed to get image metadata", err)
+		respondWithError(w, r, apperror.Internal("Failed to get image metadata", err))
 		return
 	}
 
-	response := make([]entryResponse, len(entries))
+	response := make([]apiv1.Entry, len(entries))
 	for i, e := range entries {
-		response[i] = mapEntryToResponse(e, imageMetasMap[e.ID])
+		response[i] = apiv1.MapEntry(e, imageMetasMap[e.ID], timeLayout(r))
 	}
 
 	respondWithJSON(w, http.StatusOK, response)
 }
 
-func mapEntryToResponse(e *repository.Entry, imageMetas []repository.ImageMeta) entryResponse {
-	var collectionID *string
-	if e.CollectionID != nil {
-		cid := e.CollectionID.String()
-		collectionID = &cid
-	}
-
-	var typeID *string
-	if e.TypeID != nil {
-		tid := e.TypeID.String()
-		typeID = &tid
-	}
-
-	images := make([]imageMetaResponse, len(imageMetas))
-	for i, m := range imageMetas {
-		images[i] = imageMetaResponse{
-			ID:       m.ID.String(),
-			IsCover:  m.IsCover,
-			Position: m.Position,
+func mapEntryToWriteResponse(
+	e *repository.Entry,
+	imageMetas []repository.ImageMeta,
+	warnings []service.Warning,
+	concurrent *service.ConcurrentUpdate,
+	layout apitime.Layout,
+) entryWriteResponse {
+	if warnings == nil {
+		warnings = []service.Warning{}
+	}
+	response := entryWriteResponse{
+		Entry:    apiv1.MapEntry(e, imageMetas, layout),
+		Warnings: warnings,
+	}
+	if concurrent != nil {
+		response.ConcurrentUpdate = true
+		response.ConcurrentDevice = &concurrentDeviceResponse{
+			ID:        concurrent.Device.ID,
+			Name:      concurrent.Device.Name,
+			UpdatedAt: layout.Format(concurrent.UpdatedAt),
 		}
 	}
-
-	return entryResponse{
-		ID:               e.ID.String(),
-		CollectionID:     collectionID,
-		TypeID:           typeID,
-		Title:            e.Title,
-		Description:      e.Description,
-		Score:            e.Score,
-		Date:             e.Date.Format("2006-01-02"),
-		AdditionalFields: e.AdditionalFields,
-		Images:           images,
-		CreatedAt:        e.CreatedAt.Format("2006-01-02T15:04:05Z07:00"),
-		UpdatedAt:        e.UpdatedAt.Format("2006-01-02T15:04:05Z07:00"),
-	}
+	return response
 }
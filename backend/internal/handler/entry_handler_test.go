@@ -0,0 +1,64 @@
+package handler
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/avalarin/livlog/backend/internal/apitime"
+	"github.com/avalarin/livlog/backend/internal/repository"
+	"github.com/avalarin/livlog/backend/internal/service"
+	"github.com/google/uuid"
+)
+
+func TestMapEntryToWriteResponse(t *testing.T) {
+	entry := &repository.Entry{ID: uuid.New(), Title: "Dune", Date: time.Date(2025, 1, 15, 0, 0, 0, 0, time.UTC)}
+
+	// Writes without warnings still carry the list, so clients needn't check for it
+	body, _ := json.Marshal(mapEntryToWriteResponse(entry, nil, nil, nil, apitime.Legacy))
+	var got map[string]json.RawMessage
+	if err := json.Unmarshal(body, &got); err != nil {
+		t.Fatalf("unmarshal: %v", err)
+	}
+	if string(got["warnings"]) != "[]" || string(got["title"]) != `"Dune"` {
+		t.Errorf("response = %s", body)
+	}
+
+	warning := service.Warning{Code: service.WarningUnknownField, Message: `type "Book" has no field "Pages"`, Field: "additional_fields.Pages"}
+	body, _ = json.Marshal(mapEntryToWriteResponse(entry, nil, []service.Warning{warning}, nil, apitime.Legacy))
+	var withWarning struct {
+		Warnings []service.Warning `json:"warnings"`
+	}
+	if err := json.Unmarshal(body, &withWarning); err != nil {
+		t.Fatalf("unmarshal: %v", err)
+	}
+	if len(withWarning.Warnings) != 1 || withWarning.Warnings[0] != warning {
+		t.Errorf("warnings = %+v", withWarning.Warnings)
+	}
+}
+
+func TestMapEntryToWriteResponse_ConcurrentUpdate(t *testing.T) {
+	entry := &repository.Entry{ID: uuid.New(), Title: "Dune", Date: time.Date(2025, 1, 15, 0, 0, 0, 0, time.UTC)}
+
+	body, _ := json.Marshal(mapEntryToWriteResponse(entry, nil, nil, nil, apitime.Legacy))
+	var got map[string]json.RawMessage
+	if err := json.Unmarshal(body, &got); err != nil {
+		t.Fatalf("unmarshal: %v", err)
+	}
+	if _, ok := got["concurrent_device"]; ok || string(got["concurrent_update"]) != "false" {
+		t.Errorf("response = %s", body)
+	}
+
+	concurrent := &service.ConcurrentUpdate{
+		Device:    repository.Device{ID: "ipad-1", Name: "Livlog/2.3 iPadOS/18.1"},
+		UpdatedAt: time.Date(2025, 1, 15, 10, 0, 0, 0, time.UTC),
+	}
+	response := mapEntryToWriteResponse(entry, nil, nil, concurrent, apitime.Legacy)
+	if !response.ConcurrentUpdate || response.ConcurrentDevice == nil {
+		t.Fatalf("response = %+v", response)
+	}
+	want := concurrentDeviceResponse{ID: "ipad-1", Name: "Livlog/2.3 iPadOS/18.1", UpdatedAt: "2025-01-15T10:00:00Z"}
+	if *response.ConcurrentDevice != want {
+		t.Errorf("concurrent device = %+v, want %+v", *response.ConcurrentDevice, want)
+	}
+}
@@ -0,0 +1,303 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/avalarin/livlog/backend/internal/apitime"
+	"github.com/avalarin/livlog/backend/internal/apperror"
+	"github.com/avalarin/livlog/backend/internal/middleware"
+	"github.com/avalarin/livlog/backend/internal/repository"
+	"github.com/avalarin/livlog/backend/internal/service"
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+)
+
+type ExportHandler struct {
+	exportService *service.ExportService
+}
+
+func NewExportHandler(exportService *service.ExportService) *ExportHandler {
+	return &ExportHandler{
+		exportService: exportService,
+	}
+}
+
+func (h *ExportHandler) RegisterRoutes(r chi.Router) {
+	r.Post("/export", h.CreateExport)
+	r.Post("/export/pdf", h.CreatePDFExport)
+	r.Get("/exports/{id}", h.GetExport)
+	r.Get("/exports/{id}/download", h.DownloadExport)
+}
+
+type exportScopeRequest struct {
+	CollectionID *string `json:"collection_id,omitempty" validate:"omitempty,uuid"`
+	Year         *int    `json:"year,omitempty"`
+}
+
+type exportResponse struct {
+	ID            string   `json:"id"`
+	Format        string   `json:"format"`
+	CollectionID  *string  `json:"collection_id,omitempty"`
+	Year          *int     `json:"year,omitempty"`
+	EntryIDs      []string `json:"entry_ids,omitempty"`
+	IncludeImages bool     `json:"include_images"`
+	Status        string   `json:"status"`
+	Size          int64    `json:"size,omitempty"`
+	Error         *string  `json:"error,omitempty"`
+	DownloadURL   string   `json:"download_url,omitempty"`
+	CreatedAt     string   `json:"created_at"`
+	FinishedAt    *string  `json:"finished_at,omitempty"`
+}
+
+// CreatePDFExport queues a PDF of a collection or a year. The result is
+// announced in the notification inbox; clients can also poll GET /exports/{id}.
+func (h *ExportHandler) CreatePDFExport(w http.ResponseWriter, r *http.Request) {
+	userID := middleware.GetUserIDFromContext(r.Context())
+	if userID == "" {
+		respondWithError(w, r, apperror.Unauthorized("User not authenticated", nil))
+		return
+	}
+
+	uid, err := uuid.Parse(userID)
+	if err != nil {
+		respondWithError(w, r, apperror.BadRequest("Invalid user ID", err))
+		return
+	}
+
+	var req exportScopeRequest
+	if appErr := decodeAndValidate(r, &req); appErr != nil {
+		respondWithError(w, r, appErr)
+		return
+	}
+
+	var collectionID *uuid.UUID
+	if req.CollectionID != nil {
+		cid, err := uuid.Parse(*req.CollectionID)
+		if err != nil {
+			respondWithError(w, r, apperror.BadRequest("Invalid collection ID", err))
+			return
+		}
+		collectionID = &cid
+	}
+
+	export, err := h.exportService.RequestPDFExport(r.Context(), uid, collectionID, req.Year)
+	if err != nil {
+		switch {
+		case errors.Is(err, service.ErrInvalidExportScope), errors.Is(err, service.ErrInvalidExportYear):
+			respondWithError(w, r, apperror.Validation(err.Error(), err))
+		case errors.Is(err, repository.ErrCollectionNotFound):
+			respondWithError(w, r, apperror.Wrap(err, apperror.CodeCollectionNotFound, "Collection not found"))
+		default:
+			respondWithError(w, r, apperror.Internal("Failed to create export", err))
+		}
+		return
+	}
+
+	respondWithJSON(w, http.StatusAccepted, mapExportToResponse(export, timeLayout(r)))
+}
+
+// CreateExport queues a JSON or CSV export selected by the format,
+// collection_id, year, entry_ids (comma-separated) and images query
+// parameters. With images=true the file comes in a ZIP with the entries'
+// images.
+func (h *ExportHandler) CreateExport(w http.ResponseWriter, r *http.Request) {
+	userID := middleware.GetUserIDFromContext(r.Context())
+	if userID == "" {
+		respondWithError(w, r, apperror.Unauthorized("User not authenticated", nil))
+		return
+	}
+
+	uid, err := uuid.Parse(userID)
+	if err != nil {
+		respondWithError(w, r, apperror.BadRequest("Invalid user ID", err))
+		return
+	}
+
+	format, sel, appErr := parseExportSelection(r)
+	if appErr != nil {
+		respondWithError(w, r, appErr)
+		return
+	}
+
+	export, err := h.exportService.RequestExport(r.Context(), uid, format, sel)
+	if err != nil {
+		switch {
+		case errors.Is(err, service.ErrInvalidExportFormat),
+			errors.Is(err, service.ErrInvalidExportYear),
+			errors.Is(err, service.ErrTooManyExportEntries):
+			respondWithError(w, r, apperror.Validation(err.Error(), err))
+		case errors.Is(err, repository.ErrCollectionNotFound):
+			respondWithError(w, r, apperror.Wrap(err, apperror.CodeCollectionNotFound, "Collection not found"))
+		case errors.Is(err, repository.ErrEntryNotFound):
+			respondWithError(w, r, apperror.Wrap(err, apperror.CodeEntryNotFound, "Entry not found"))
+		default:
+			respondWithError(w, r, apperror.Internal("Failed to create export", err))
+		}
+		return
+	}
+
+	respondWithJSON(w, http.StatusAccepted, mapExportToResponse(export, timeLayout(r)))
+}
+
+// parseExportSelection reads the query parameters of POST /export. The
+// format defaults to json.
+func parseExportSelection(r *http.Request) (string, service.ExportSelection, *apperror.Error) {
+	query := r.URL.Query()
+	var sel service.ExportSelection
+
+	format := query.Get("format")
+	if format == "" {
+		format = service.ExportFormatJSON
+	}
+
+	if v := query.Get("collection_id"); v != "" {
+		cid, err := uuid.Parse(v)
+		if err != nil {
+			return "", sel, apperror.BadRequest("Invalid collection ID", err)
+		}
+		sel.CollectionID = &cid
+	}
+
+	if v := query.Get("year"); v != "" {
+		year, err := strconv.Atoi(v)
+		if err != nil {
+			return "", sel, apperror.BadRequest("Invalid year", err)
+		}
+		sel.Year = &year
+	}
+
+	if v := query.Get("entry_ids"); v != "" {
+		for _, part := range strings.Split(v, ",") {
+			id, err := uuid.Parse(strings.TrimSpace(part))
+			if err != nil {
+				return "", sel, apperror.BadRequest("Invalid entry ID", err)
+			}
+			sel.EntryIDs = append(sel.EntryIDs, id)
+		}
+	}
+
+	if v := query.Get("images"); v != "" {
+		images, err := strconv.ParseBool(v)
+		if err != nil {
+			return "", sel, apperror.BadRequest("Invalid images flag", err)
+		}
+		sel.IncludeImages = images
+	}
+
+	return format, sel, nil
+}
+
+func (h *ExportHandler) GetExport(w http.ResponseWriter, r *http.Request) {
+	userID := middleware.GetUserIDFromContext(r.Context())
+	if userID == "" {
+		respondWithError(w, r, apperror.Unauthorized("User not authenticated", nil))
+		return
+	}
+
+	uid, err := uuid.Parse(userID)
+	if err != nil {
+		respondWithError(w, r, apperror.BadRequest("Invalid user ID", err))
+		return
+	}
+
+	exportID := chi.URLParam(r, "id")
+	eid, err := uuid.Parse(exportID)
+	if err != nil {
+		respondWithError(w, r, apperror.BadRequest("Invalid export ID", err))
+		return
+	}
+
+	export, err := h.exportService.GetExport(r.Context(), eid, uid)
+	if err != nil {
+		if errors.Is(err, repository.ErrExportNotFound) {
+			respondWithError(w, r, apperror.Wrap(err, apperror.CodeExportNotFound, "Export not found"))
+			return
+		}
+		respondWithError(w, r, apperror.Internal("Failed to get export", err))
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, mapExportToResponse(export, timeLayout(r)))
+}
+
+func (h *ExportHandler) DownloadExport(w http.ResponseWriter, r *http.Request) {
+	userID := middleware.GetUserIDFromContext(r.Context())
+	if userID == "" {
+		respondWithError(w, r, apperror.Unauthorized("User not authenticated", nil))
+		return
+	}
+
+	uid, err := uuid.Parse(userID)
+	if err != nil {
+		respondWithError(w, r, apperror.BadRequest("Invalid user ID", err))
+		return
+	}
+
+	exportID := chi.URLParam(r, "id")
+	eid, err := uuid.Parse(exportID)
+	if err != nil {
+		respondWithError(w, r, apperror.BadRequest("Invalid export ID", err))
+		return
+	}
+
+	export, err := h.exportService.GetExport(r.Context(), eid, uid)
+	if err != nil {
+		respondWithExportError(w, r, err)
+		return
+	}
+
+	data, err := h.exportService.GetExportFile(r.Context(), eid, uid)
+	if err != nil {
+		respondWithExportError(w, r, err)
+		return
+	}
+
+	contentType, ext := service.ExportFileType(export)
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("Content-Disposition", `attachment; filename="livlog-`+eid.String()[:8]+`.`+ext+`"`)
+	w.Header().Set("Content-Length", strconv.Itoa(len(data)))
+	w.WriteHeader(http.StatusOK)
+	w.Write(data)
+}
+
+func respondWithExportError(w http.ResponseWriter, r *http.Request, err error) {
+	switch {
+	case errors.Is(err, repository.ErrExportNotFound):
+		respondWithError(w, r, apperror.Wrap(err, apperror.CodeExportNotFound, "Export not found"))
+	case errors.Is(err, repository.ErrExportNotReady):
+		respondWithError(w, r, apperror.Wrap(err, apperror.CodeExportNotReady, "Export is not ready"))
+	default:
+		respondWithError(w, r, apperror.Internal("Failed to get export", err))
+	}
+}
+
+func mapExportToResponse(e *repository.Export, layout apitime.Layout) exportResponse {
+	response := exportResponse{
+		ID:            e.ID.String(),
+		Format:        e.Format,
+		Year:          e.Year,
+		IncludeImages: e.IncludeImages,
+		Status:        e.Status,
+		Size:          e.FileSize,
+		Error:         e.Error,
+		CreatedAt:     layout.Format(e.CreatedAt),
+	}
+	for _, id := range e.EntryIDs {
+		response.EntryIDs = append(response.EntryIDs, id.String())
+	}
+	if e.CollectionID != nil {
+		collectionID := e.CollectionID.String()
+		response.CollectionID = &collectionID
+	}
+	if e.Status == repository.ExportStatusDone {
+		response.DownloadURL = "/exports/" + e.ID.String() + "/download"
+	}
+	if e.FinishedAt != nil {
+		finishedAt := layout.Format(*e.FinishedAt)
+		response.FinishedAt = &finishedAt
+	}
+	return response
+}
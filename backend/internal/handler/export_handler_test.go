@@ -0,0 +1,67 @@
+package handler
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/avalarin/livlog/backend/internal/service"
+)
+
+func TestParseExportSelection(t *testing.T) {
+	req := httptest.NewRequest(http.MethodPost,
+		"/export?format=csv&collection_id=550e8400-e29b-41d4-a716-446655440000&year=2024"+
+			"&entry_ids=7d4e2b9a-1c3f-4a8e-b5d6-0f9e8a7b6c5d,%203f1c2b9a-1c3f-4a8e-b5d6-0f9e8a7b6c5d&images=true", nil)
+
+	format, sel, appErr := parseExportSelection(req)
+	if appErr != nil {
+		t.Fatalf("unexpected error: %v", appErr)
+	}
+	if format != service.ExportFormatCSV {
+		t.Errorf("format = %q, want csv", format)
+	}
+	if sel.CollectionID == nil || sel.CollectionID.String() != "550e8400-e29b-41d4-a716-446655440000" {
+		t.Errorf("collection_id = %v", sel.CollectionID)
+	}
+	if sel.Year == nil || *sel.Year != 2024 {
+		t.Errorf("year = %v, want 2024", sel.Year)
+	}
+	if len(sel.EntryIDs) != 2 || sel.EntryIDs[1].String() != "3f1c2b9a-1c3f-4a8e-b5d6-0f9e8a7b6c5d" {
+		t.Errorf("entry_ids = %v", sel.EntryIDs)
+	}
+	if !sel.IncludeImages {
+		t.Error("images = false, want true")
+	}
+}
+
+func TestParseExportSelection_Defaults(t *testing.T) {
+	format, sel, appErr := parseExportSelection(httptest.NewRequest(http.MethodPost, "/export", nil))
+	if appErr != nil {
+		t.Fatalf("unexpected error: %v", appErr)
+	}
+	if format != service.ExportFormatJSON {
+		t.Errorf("format = %q, want json", format)
+	}
+	if sel.CollectionID != nil || sel.Year != nil || sel.EntryIDs != nil || sel.IncludeImages {
+		t.Errorf("selection = %+v, want empty", sel)
+	}
+}
+
+func TestParseExportSelection_Invalid(t *testing.T) {
+	for _, query := range []string{
+		"collection_id=nope",
+		"year=last",
+		"entry_ids=7d4e2b9a-1c3f-4a8e-b5d6-0f9e8a7b6c5d,nope",
+		"images=maybe",
+	} {
+		t.Run(query, func(t *testing.T) {
+			_, _, appErr := parseExportSelection(httptest.NewRequest(http.MethodPost, "/export?"+query, nil))
+			if appErr == nil {
+				t.Fatal("expected an error")
+			}
+			if appErr.Status() != http.StatusBadRequest {
+				t.Errorf("status = %d, want 400", appErr.Status())
+			}
+		})
+	}
+}
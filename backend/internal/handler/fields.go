@@ -0,0 +1,50 @@
+package handler
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/avalarin/livlog/backend/internal/apiv1"
+	"github.com/avalarin/livlog/backend/internal/apperror"
+	"github.com/avalarin/livlog/backend/internal/repository"
+)
+
+// parseEntryFields reads the ?fields= list of entry fields to return
+// (e.g. fields=id,title,score,cover). It returns nil when the parameter is
+// absent, meaning the full entry.
+func parseEntryFields(r *http.Request) (repository.EntryFields, *apperror.Error) {
+	param := r.URL.Query().Get("fields")
+	if param == "" {
+		return nil, nil
+	}
+
+	known := make(map[string]bool, len(repository.EntryFieldNames))
+	for _, name := range repository.EntryFieldNames {
+		known[name] = true
+	}
+
+	fields := repository.EntryFields{"id": true}
+	for _, name := range strings.Split(param, ",") {
+		name = strings.TrimSpace(name)
+		if name == "" {
+			continue
+		}
+		if !known[name] {
+			return nil, apperror.BadRequest(fmt.Sprintf("Unknown field %q", name), nil).
+				WithDetails(map[string]interface{}{"fields": repository.EntryFieldNames})
+		}
+		fields[name] = true
+	}
+
+	return fields, nil
+}
+
+func respondWithEntryFields(w http.ResponseWriter, r *http.Request, entries []*repository.EntryWithImages, fields repository.EntryFields) {
+	layout := timeLayout(r)
+	response := make([]map[string]interface{}, len(entries))
+	for i, e := range entries {
+		response[i] = apiv1.SelectEntryFields(e, fields, layout)
+	}
+	respondWithJSON(w, http.StatusOK, response)
+}
@@ -0,0 +1,50 @@
+package handler
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestParseEntryFields(t *testing.T) {
+	tests := []struct {
+		query   string
+		want    []string
+		wantErr bool
+	}{
+		{"", nil, false},
+		{"fields=title,score", []string{"id", "title", "score"}, false},
+		{"fields=id,%20cover,", []string{"id", "cover"}, false},
+		{"fields=title,password", nil, true},
+	}
+
+	for _, tt := range tests {
+		req := httptest.NewRequest(http.MethodGet, "/entries?"+tt.query, nil)
+		fields, appErr := parseEntryFields(req)
+
+		if tt.wantErr {
+			if appErr == nil || appErr.Status() != http.StatusBadRequest {
+				t.Errorf("%q: expected 400, got %v", tt.query, appErr)
+			}
+			continue
+		}
+		if appErr != nil {
+			t.Errorf("%q: unexpected error %v", tt.query, appErr)
+			continue
+		}
+		if tt.want == nil {
+			if fields != nil {
+				t.Errorf("%q: expected all fields, got %v", tt.query, fields)
+			}
+			continue
+		}
+		if len(fields) != len(tt.want) {
+			t.Errorf("%q: expected %v, got %v", tt.query, tt.want, fields)
+		}
+		for _, name := range tt.want {
+			if !fields[name] {
+				t.Errorf("%q: expected field %s", tt.query, name)
+			}
+		}
+	}
+}
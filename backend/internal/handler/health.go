@@ -2,40 +2,78 @@ package handler
 
 import (
 	"context"
-	"encoding/json"
+	"fmt"
 	"net/http"
+	"sync"
 	"time"
 
 	"github.com/avalarin/livlog/backend/internal/repository"
+	"github.com/go-chi/chi/v5"
 )
 
 const Version = "1.0.0"
 
 type HealthHandler struct {
-	db        *repository.DB
-	startTime time.Time
+	db               *repository.DB
+	migrationVersion uint // schema version this build expects
+	checks           []HealthCheck
+	startTime        time.Time
 }
 
-func NewHealthHandler(db *repository.DB) *HealthHandler {
+func NewHealthHandler(db *repository.DB, migrationVersion uint) *HealthHandler {
 	return &HealthHandler{
-		db:        db,
-		startTime: time.Now(),
+		db:               db,
+		migrationVersion: migrationVersion,
+		startTime:        time.Now(),
 	}
 }
 
+// RegisterProbeRoutes registers the Kubernetes-style probes. They live outside
+// /api/v1, next to /metrics.
+func (h *HealthHandler) RegisterProbeRoutes(r chi.Router) {
+	r.Get("/healthz", h.Liveness)
+	r.Get("/readyz", h.Readiness)
+}
+
 type DatabaseStatus struct {
 	Status string `json:"status"`
 	PingMs int64  `json:"ping_ms"`
 }
 
+// ComponentStatus is one dependency in the verbose health report.
+type ComponentStatus struct {
+	Status    string `json:"status"` // "ok" or "failing"
+	Optional  bool   `json:"optional"`
+	LatencyMs int64  `json:"latency_ms"`
+}
+
 type HealthResponse struct {
-	Status    string         `json:"status"`
-	Timestamp string         `json:"timestamp"`
-	Version   string         `json:"version"`
-	Uptime    string         `json:"uptime"`
-	Database  DatabaseStatus `json:"database"`
+	Status     string                     `json:"status"` // "ok", "degraded" or "down"
+	Timestamp  string                     `json:"timestamp"`
+	Version    string                     `json:"version"`
+	Uptime     string                     `json:"uptime"`
+	Database   DatabaseStatus             `json:"database"`
+	Components map[string]ComponentStatus `json:"components,omitempty"`
 }
 
+// HealthCheck probes one dependency for the verbose health report. A failing
+// optional component degrades the service; a failing required one takes it
+// down.
+type HealthCheck struct {
+	Name     string
+	Optional bool
+	Check    func(ctx context.Context) error
+}
+
+// AddCheck adds a component to the verbose health report. The database is
+// always checked.
+func (h *HealthHandler) AddCheck(check HealthCheck) {
+	h.checks = append(h.checks, check)
+}
+
+// Health reports service health. With ?verbose=true it also checks every
+// registered component and lists each with its latency. Only a failing
+// required component turns the response into a 503.
 func (h *HealthHandler) Health(w http.ResponseWriter, r *http.Request) {
 	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
 	defer cancel()
@@ -53,21 +91,115 @@ func (h *HealthHandler) Health(w http.ResponseWriter, r *http.Request) {
 
 	pingDuration, err := h.db.Ping(ctx)
 	if err != nil {
-		response.Status = "degraded"
+		response.Status = "down"
 		response.Database.Status = "disconnected"
 	} else {
 		response.Database.PingMs = pingDuration.Milliseconds()
 	}
 
-	w.Header().Set("Content-Type", "application/json")
+	if r.URL.Query().Get("verbose") == "true" {
+		response.Components = h.checkComponents(ctx)
+		response.Components["database"] = ComponentStatus{
+			Status:    componentStatus(err),
+			LatencyMs: pingDuration.Milliseconds(),
+		}
+
+		for _, c := range response.Components {
+			switch {
+			case c.Status == "ok":
+			case !c.Optional:
+				response.Status = "down"
+			case response.Status == "ok":
+				response.Status = "degraded"
+			}
+		}
+	}
 
 	statusCode := http.StatusOK
-	if response.Status != "ok" {
+	if response.Status == "down" {
 		statusCode = http.StatusServiceUnavailable
 	}
-	w.WriteHeader(statusCode)
 
-	if err := json.NewEncoder(w).Encode(response); err != nil {
-		http.Error(w, "failed to encode response", http.StatusInternalServerError)
+	respondWithJSON(w, statusCode, response)
+}
+
+// checkComponents runs the registered checks concurrently.
+func (h *HealthHandler) checkComponents(ctx context.Context) map[string]ComponentStatus {
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	components := make(map[string]ComponentStatus, len(h.checks)+1)
+
+	for _, check := range h.checks {
+		wg.Add(1)
+		go func(check HealthCheck) {
+			defer wg.Done()
+
+			start := time.Now()
+			err := check.Check(ctx)
+			status := ComponentStatus{
+				Status:    componentStatus(err),
+				Optional:  check.Optional,
+				LatencyMs: time.Since(start).Milliseconds(),
+			}
+
+			mu.Lock()
+			components[check.Name] = status
+			mu.Unlock()
+		}(check)
 	}
+	wg.Wait()
+
+	return components
+}
+
+func componentStatus(err error) string {
+	if err != nil {
+		return "failing"
+	}
+	return "ok"
+}
+
+type probeResponse struct {
+	Status string            `json:"status"`
+	Checks map[string]string `json:"checks,omitempty"`
+}
+
+// Liveness reports that the process is up and serving. It checks no
+// dependencies, so a database outage does not get the pod restarted.
+func (h *HealthHandler) Liveness(w http.ResponseWriter, r *http.Request) {
+	respondWithJSON(w, http.StatusOK, probeResponse{Status: "ok"})
+}
+
+// Readiness reports whether the instance should receive traffic: the database
+// is reachable and its schema is at least the version this build expects.
+func (h *HealthHandler) Readiness(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(r.Context(), 2*time.Second)
+	defer cancel()
+
+	response := probeResponse{
+		Status: "ready",
+		Checks: map[string]string{"database": "ok", "migrations": "ok"},
+	}
+
+	if _, err := h.db.Ping(ctx); err != nil {
+		response.Checks["database"] = "unreachable"
+		response.Checks["migrations"] = "unknown"
+	} else if version, dirty, err := h.db.MigrationVersion(ctx); err != nil {
+		response.Checks["migrations"] = "unknown"
+	} else if dirty {
+		response.Checks["migrations"] = fmt.Sprintf("version %d is dirty", version)
+	} else if version < h.migrationVersion {
+		response.Checks["migrations"] = fmt.Sprintf("at version %d, expected %d", version, h.migrationVersion)
+	}
+
+	statusCode := http.StatusOK
+	for _, check := range response.Checks {
+		if check != "ok" {
+			response.Status = "not_ready"
+			statusCode = http.StatusServiceUnavailable
+			break
+		}
+	}
+
+	respondWithJSON(w, statusCode, response)
 }
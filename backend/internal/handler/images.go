@@ -0,0 +1,14 @@
+package handler
+
+import (
+	"net/http"
+)
+
+// writeImage serves an uploaded image. Images never change under an ID, so
+// clients and the CDN may keep them for a year.
+func writeImage(w http.ResponseWriter, contentType string, data []byte) {
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("Cache-Control", "public, max-age=31536000, immutable")
+	w.WriteHeader(http.StatusOK)
+	w.Write(data)
+}
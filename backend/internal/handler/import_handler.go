@@ -0,0 +1,119 @@
+package handler
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+
+	"github.com/avalarin/livlog/backend/internal/apperror"
+	"github.com/avalarin/livlog/backend/internal/middleware"
+	"github.com/avalarin/livlog/backend/internal/service"
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+)
+
+type ImportHandler struct {
+	importService *service.ImportService
+}
+
+func NewImportHandler(importService *service.ImportService) *ImportHandler {
+	return &ImportHandler{
+		importService: importService,
+	}
+}
+
+func (h *ImportHandler) RegisterRoutes(r chi.Router) {
+	r.Post("/import/livlog", h.ImportLivlog)
+}
+
+type importResultResponse struct {
+	DryRun      bool                     `json:"dry_run"`
+	Types       int                      `json:"types"`
+	Collections int                      `json:"collections"`
+	Entries     int                      `json:"entries"`
+	Images      int                      `json:"images"`
+	Conflicts   []importConflictResponse `json:"conflicts"`
+}
+
+type importConflictResponse struct {
+	Code     string  `json:"code"`
+	SourceID *string `json:"source_id,omitempty"`
+	Message  string  `json:"message"`
+}
+
+// ImportLivlog imports the file of a JSON export from another livlog server,
+// sent as the request body, optionally zipped with its images. With
+// dry_run=true nothing is created and the response tells what would be.
+func (h *ImportHandler) ImportLivlog(w http.ResponseWriter, r *http.Request) {
+	userID := middleware.GetUserIDFromContext(r.Context())
+	if userID == "" {
+		respondWithError(w, r, apperror.Unauthorized("User not authenticated", nil))
+		return
+	}
+
+	uid, err := uuid.Parse(userID)
+	if err != nil {
+		respondWithError(w, r, apperror.BadRequest("Invalid user ID", err))
+		return
+	}
+
+	dryRun := false
+	if v := r.URL.Query().Get("dry_run"); v != "" {
+		dryRun, err = strconv.ParseBool(v)
+		if err != nil {
+			respondWithError(w, r, apperror.BadRequest("Invalid dry_run flag", err))
+			return
+		}
+	}
+
+	file, err := io.ReadAll(r.Body)
+	if err != nil {
+		var maxErr *http.MaxBytesError
+		if errors.As(err, &maxErr) {
+			respondWithError(w, r, apperror.Wrap(err, apperror.CodePayloadTooLarge, "Request body is too large").
+				WithDetails(map[string]interface{}{"limit_bytes": maxErr.Limit}))
+			return
+		}
+		respondWithError(w, r, apperror.BadRequest("Invalid request body", err))
+		return
+	}
+
+	result, err := h.importService.ImportLivlog(r.Context(), uid, file, dryRun)
+	if err != nil {
+		switch {
+		case errors.Is(err, service.ErrInvalidImportFile):
+			respondWithError(w, r, apperror.Validation(err.Error(), err))
+		case errors.Is(err, service.ErrQuotaExceeded):
+			respondWithError(w, r, quotaError(err))
+		default:
+			respondWithError(w, r, apperror.Internal("Failed to import", err))
+		}
+		return
+	}
+
+	status := http.StatusCreated
+	if result.DryRun {
+		status = http.StatusOK
+	}
+	respondWithJSON(w, status, mapImportResultToResponse(result))
+}
+
+func mapImportResultToResponse(result *service.ImportResult) importResultResponse {
+	response := importResultResponse{
+		DryRun:      result.DryRun,
+		Types:       result.Types,
+		Collections: result.Collections,
+		Entries:     result.Entries,
+		Images:      result.Images,
+		Conflicts:   make([]importConflictResponse, len(result.Conflicts)),
+	}
+	for i, c := range result.Conflicts {
+		response.Conflicts[i] = importConflictResponse{Code: c.Code, Message: c.Message}
+		if c.SourceID != uuid.Nil {
+			id := c.SourceID.String()
+			response.Conflicts[i].SourceID = &id
+		}
+	}
+	return response
+}
@@ -0,0 +1,30 @@
+package handler
+
+import (
+	"testing"
+
+	"github.com/avalarin/livlog/backend/internal/service"
+	"github.com/google/uuid"
+)
+
+func TestMapImportResultToResponse(t *testing.T) {
+	collectionID := uuid.New()
+	resp := mapImportResultToResponse(&service.ImportResult{
+		DryRun:  true,
+		Entries: 3,
+		Conflicts: []service.ImportConflict{
+			{Code: service.ConflictCollectionExists, SourceID: collectionID, Message: "merged"},
+			{Code: service.ConflictQuotaExceeded, Message: "over quota"},
+		},
+	})
+
+	if !resp.DryRun || resp.Entries != 3 || len(resp.Conflicts) != 2 {
+		t.Fatalf("response = %+v", resp)
+	}
+	if id := resp.Conflicts[0].SourceID; id == nil || *id != collectionID.String() {
+		t.Errorf("source_id = %v, want %s", id, collectionID)
+	}
+	if resp.Conflicts[1].SourceID != nil {
+		t.Errorf("source_id of an import-wide conflict = %q, want none", *resp.Conflicts[1].SourceID)
+	}
+}
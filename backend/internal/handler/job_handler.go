@@ -0,0 +1,247 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/avalarin/livlog/backend/internal/apitime"
+	"github.com/avalarin/livlog/backend/internal/apperror"
+	"github.com/avalarin/livlog/backend/internal/middleware"
+	"github.com/avalarin/livlog/backend/internal/repository"
+	"github.com/avalarin/livlog/backend/internal/service"
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+)
+
+// jobDownloadLifetime is how long a download link from GET /jobs/{id} works.
+const jobDownloadLifetime = 15 * time.Minute
+
+// jobKinds are the kinds accepted by POST /jobs/{kind}.
+var jobKinds = []string{"export-pdf", "export-json", "export-csv"}
+
+// JobHandler serves long-running work (exports) through one flow: queue with
+// POST /jobs/{kind}, poll GET /jobs/{id}, then fetch the file from the signed
+// download link, which needs no Authorization header.
+type JobHandler struct {
+	exportService *service.ExportService
+	jwtService    *service.JWTService
+}
+
+func NewJobHandler(exportService *service.ExportService, jwtService *service.JWTService) *JobHandler {
+	return &JobHandler{
+		exportService: exportService,
+		jwtService:    jwtService,
+	}
+}
+
+func (h *JobHandler) RegisterRoutes(r chi.Router) {
+	r.Post("/jobs/{kind}", h.CreateJob)
+	r.Get("/jobs/{id}", h.GetJob)
+}
+
+// RegisterPublicRoutes registers routes authorized by a signed link instead of
+// an access token.
+func (h *JobHandler) RegisterPublicRoutes(r chi.Router) {
+	r.Get("/jobs/{id}/download", h.DownloadJob)
+}
+
+type jobResponse struct {
+	ID                string  `json:"id"`
+	Kind              string  `json:"kind"`
+	Status            string  `json:"status"`
+	Progress          int     `json:"progress"`
+	Error             *string `json:"error,omitempty"`
+	DownloadURL       string  `json:"download_url,omitempty"`
+	DownloadExpiresAt *string `json:"download_expires_at,omitempty"`
+	CreatedAt         string  `json:"created_at"`
+	FinishedAt        *string `json:"finished_at,omitempty"`
+}
+
+func (h *JobHandler) CreateJob(w http.ResponseWriter, r *http.Request) {
+	userID := middleware.GetUserIDFromContext(r.Context())
+	if userID == "" {
+		respondWithError(w, r, apperror.Unauthorized("User not authenticated", nil))
+		return
+	}
+
+	uid, err := uuid.Parse(userID)
+	if err != nil {
+		respondWithError(w, r, apperror.BadRequest("Invalid user ID", err))
+		return
+	}
+
+	kind := chi.URLParam(r, "kind")
+	format, ok := strings.CutPrefix(kind, "export-")
+	if !ok || (format != service.ExportFormatPDF && format != service.ExportFormatJSON && format != service.ExportFormatCSV) {
+		respondWithError(w, r, apperror.New(apperror.CodeNotFound, "Unknown job kind").
+			WithDetails(map[string]interface{}{"kinds": jobKinds}))
+		return
+	}
+
+	var req exportScopeRequest
+	if appErr := decodeAndValidate(r, &req); appErr != nil {
+		respondWithError(w, r, appErr)
+		return
+	}
+
+	var collectionID *uuid.UUID
+	if req.CollectionID != nil {
+		cid, err := uuid.Parse(*req.CollectionID)
+		if err != nil {
+			respondWithError(w, r, apperror.BadRequest("Invalid collection ID", err))
+			return
+		}
+		collectionID = &cid
+	}
+
+	var export *repository.Export
+	if format == service.ExportFormatPDF {
+		export, err = h.exportService.RequestPDFExport(r.Context(), uid, collectionID, req.Year)
+	} else {
+		export, err = h.exportService.RequestExport(r.Context(), uid, format, service.ExportSelection{
+			CollectionID: collectionID,
+			Year:         req.Year,
+		})
+	}
+	if err != nil {
+		switch {
+		case errors.Is(err, service.ErrInvalidExportScope), errors.Is(err, service.ErrInvalidExportYear):
+			respondWithError(w, r, apperror.Validation(err.Error(), err))
+		case errors.Is(err, repository.ErrCollectionNotFound):
+			respondWithError(w, r, apperror.Wrap(err, apperror.CodeCollectionNotFound, "Collection not found"))
+		default:
+			respondWithError(w, r, apperror.Internal("Failed to create job", err))
+		}
+		return
+	}
+
+	response, err := h.mapJobToResponse(export, timeLayout(r))
+	if err != nil {
+		respondWithError(w, r, apperror.Internal("Failed to create job", err))
+		return
+	}
+
+	respondWithJSON(w, http.StatusAccepted, response)
+}
+
+func (h *JobHandler) GetJob(w http.ResponseWriter, r *http.Request) {
+	userID := middleware.GetUserIDFromContext(r.Context())
+	if userID == "" {
+		respondWithError(w, r, apperror.Unauthorized("User not authenticated", nil))
+		return
+	}
+
+	uid, err := uuid.Parse(userID)
+	if err != nil {
+		respondWithError(w, r, apperror.BadRequest("Invalid user ID", err))
+		return
+	}
+
+	jobID := chi.URLParam(r, "id")
+	jid, err := uuid.Parse(jobID)
+	if err != nil {
+		respondWithError(w, r, apperror.BadRequest("Invalid job ID", err))
+		return
+	}
+
+	export, err := h.exportService.GetExport(r.Context(), jid, uid)
+	if err != nil {
+		respondWithJobError(w, r, err)
+		return
+	}
+
+	response, err := h.mapJobToResponse(export, timeLayout(r))
+	if err != nil {
+		respondWithError(w, r, apperror.Internal("Failed to get job", err))
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, response)
+}
+
+// DownloadJob serves a finished job's file to anyone holding a valid link.
+func (h *JobHandler) DownloadJob(w http.ResponseWriter, r *http.Request) {
+	jobID := chi.URLParam(r, "id")
+	jid, err := uuid.Parse(jobID)
+	if err != nil {
+		respondWithError(w, r, apperror.BadRequest("Invalid job ID", err))
+		return
+	}
+
+	claims, err := h.jwtService.ValidateDownloadToken(r.URL.Query().Get("token"))
+	if err != nil || claims.ID != jid.String() {
+		respondWithError(w, r, apperror.Wrap(err, apperror.CodeInvalidDownloadLink, "Invalid or expired download link"))
+		return
+	}
+
+	uid, err := uuid.Parse(claims.Subject)
+	if err != nil {
+		respondWithError(w, r, apperror.Wrap(err, apperror.CodeInvalidDownloadLink, "Invalid or expired download link"))
+		return
+	}
+
+	export, err := h.exportService.GetExport(r.Context(), jid, uid)
+	if err != nil {
+		respondWithJobError(w, r, err)
+		return
+	}
+
+	data, err := h.exportService.GetExportFile(r.Context(), jid, uid)
+	if err != nil {
+		respondWithJobError(w, r, err)
+		return
+	}
+
+	contentType, ext := service.ExportFileType(export)
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("Content-Disposition", `attachment; filename="livlog-`+jid.String()[:8]+`.`+ext+`"`)
+	w.Header().Set("Content-Length", strconv.Itoa(len(data)))
+	w.Header().Set("Cache-Control", "private, no-store")
+	w.WriteHeader(http.StatusOK)
+	w.Write(data)
+}
+
+func respondWithJobError(w http.ResponseWriter, r *http.Request, err error) {
+	switch {
+	case errors.Is(err, repository.ErrExportNotFound):
+		respondWithError(w, r, apperror.Wrap(err, apperror.CodeExportNotFound, "Job not found"))
+	case errors.Is(err, repository.ErrExportNotReady):
+		respondWithError(w, r, apperror.Wrap(err, apperror.CodeExportNotReady, "Job is not finished"))
+	default:
+		respondWithError(w, r, apperror.Internal("Failed to get job", err))
+	}
+}
+
+// mapJobToResponse renders an export as a job. Finished jobs get a fresh
+// download link each time they are fetched.
+func (h *JobHandler) mapJobToResponse(e *repository.Export, layout apitime.Layout) (jobResponse, error) {
+	response := jobResponse{
+		ID:        e.ID.String(),
+		Kind:      "export-" + e.Format,
+		Status:    e.Status,
+		Progress:  e.Progress,
+		Error:     e.Error,
+		CreatedAt: layout.Format(e.CreatedAt),
+	}
+	if e.FinishedAt != nil {
+		finishedAt := layout.Format(*e.FinishedAt)
+		response.FinishedAt = &finishedAt
+	}
+
+	if e.Status == repository.ExportStatusDone {
+		token, expiresAt, err := h.jwtService.GenerateDownloadToken(e.UserID.String(), e.ID.String(), jobDownloadLifetime)
+		if err != nil {
+			return jobResponse{}, err
+		}
+		// The path is relative to the API base (e.g. /api/v1)
+		response.DownloadURL = "/jobs/" + e.ID.String() + "/download?token=" + url.QueryEscape(token)
+		expires := layout.Format(expiresAt)
+		response.DownloadExpiresAt = &expires
+	}
+
+	return response, nil
+}
@@ -0,0 +1,105 @@
+package handler
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/avalarin/livlog/backend/internal/apitime"
+	"github.com/avalarin/livlog/backend/internal/repository"
+	"github.com/avalarin/livlog/backend/internal/service"
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+)
+
+func newTestJWTService(t *testing.T) *service.JWTService {
+	t.Helper()
+	dir := t.TempDir()
+	privatePath, publicPath := filepath.Join(dir, "private.pem"), filepath.Join(dir, "public.pem")
+	if err := service.GenerateJWTKeys(privatePath, publicPath); err != nil {
+		t.Fatalf("failed to generate keys: %v", err)
+	}
+	jwtService, err := service.NewJWTService(privatePath, publicPath, 3600, 86400, "livlog-api", "livlog-app", service.SystemClock)
+	if err != nil {
+		t.Fatalf("failed to create jwt service: %v", err)
+	}
+	return jwtService
+}
+
+func TestMapJobToResponse_DownloadLink(t *testing.T) {
+	jwtService := newTestJWTService(t)
+	h := NewJobHandler(nil, jwtService)
+
+	finished := time.Date(2025, 1, 20, 10, 0, 0, 0, time.UTC)
+	job := &repository.Export{
+		ID:         uuid.New(),
+		UserID:     uuid.New(),
+		Format:     service.ExportFormatJSON,
+		Status:     repository.ExportStatusDone,
+		Progress:   100,
+		FinishedAt: &finished,
+		CreatedAt:  finished.Add(-time.Minute),
+	}
+
+	resp, err := h.mapJobToResponse(job, apitime.Legacy)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if resp.Kind != "export-json" || resp.Progress != 100 {
+		t.Errorf("kind, progress = %q, %d, want export-json, 100", resp.Kind, resp.Progress)
+	}
+
+	prefix := "/jobs/" + job.ID.String() + "/download?token="
+	if !strings.HasPrefix(resp.DownloadURL, prefix) || resp.DownloadExpiresAt == nil {
+		t.Fatalf("download_url = %q, expires = %v", resp.DownloadURL, resp.DownloadExpiresAt)
+	}
+
+	claims, err := jwtService.ValidateDownloadToken(strings.TrimPrefix(resp.DownloadURL, prefix))
+	if err != nil {
+		t.Fatalf("download token does not validate: %v", err)
+	}
+	if claims.Subject != job.UserID.String() || claims.ID != job.ID.String() {
+		t.Errorf("claims = %s/%s, want %s/%s", claims.Subject, claims.ID, job.UserID, job.ID)
+	}
+
+	// A download link must never work as an access token
+	if _, err := jwtService.ValidateAccessToken(strings.TrimPrefix(resp.DownloadURL, prefix)); err == nil {
+		t.Error("download token accepted as access token")
+	}
+
+	job.Status = repository.ExportStatusRunning
+	resp, err = h.mapJobToResponse(job, apitime.Legacy)
+	if err != nil || resp.DownloadURL != "" {
+		t.Errorf("running job download_url = %q, err = %v", resp.DownloadURL, err)
+	}
+}
+
+func TestDownloadJob_RejectsForeignToken(t *testing.T) {
+	jwtService := newTestJWTService(t)
+	h := NewJobHandler(nil, jwtService)
+
+	r := chi.NewRouter()
+	h.RegisterPublicRoutes(r)
+
+	token, _, err := jwtService.GenerateDownloadToken(uuid.NewString(), uuid.NewString(), time.Minute)
+	if err != nil {
+		t.Fatalf("failed to sign token: %v", err)
+	}
+	accessToken, err := jwtService.GenerateAccessToken(uuid.NewString(), "user@example.com")
+	if err != nil {
+		t.Fatalf("failed to sign token: %v", err)
+	}
+
+	for name, tok := range map[string]string{"other job": token, "access token": accessToken, "missing": ""} {
+		req := httptest.NewRequest(http.MethodGet, "/jobs/"+uuid.NewString()+"/download?token="+tok, nil)
+		rec := httptest.NewRecorder()
+		r.ServeHTTP(rec, req)
+
+		if rec.Code != http.StatusUnauthorized || !strings.Contains(rec.Body.String(), "INVALID_DOWNLOAD_LINK") {
+			t.Errorf("%s: got %d %s, want 401 INVALID_DOWNLOAD_LINK", name, rec.Code, rec.Body.String())
+		}
+	}
+}
@@ -0,0 +1,68 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/avalarin/livlog/backend/internal/apperror"
+	"github.com/avalarin/livlog/backend/internal/service"
+	"github.com/go-chi/chi/v5"
+)
+
+type LookupHandler struct {
+	booksService *service.BooksService
+}
+
+func NewLookupHandler(booksService *service.BooksService) *LookupHandler {
+	return &LookupHandler{
+		booksService: booksService,
+	}
+}
+
+func (h *LookupHandler) RegisterRoutes(r chi.Router) {
+	r.Get("/lookup/isbn/{isbn}", h.LookupISBN)
+}
+
+type bookResponse struct {
+	ISBN          string   `json:"isbn"`
+	Title         string   `json:"title"`
+	Subtitle      string   `json:"subtitle,omitempty"`
+	OriginalTitle string   `json:"original_title,omitempty"`
+	Language      string   `json:"language,omitempty"`
+	Authors       []string `json:"authors"`
+	Publisher     string   `json:"publisher,omitempty"`
+	Year          string   `json:"year,omitempty"`
+	Pages         int      `json:"pages,omitempty"`
+	CoverURL      string   `json:"cover_url,omitempty"`
+}
+
+// LookupISBN resolves a scanned barcode to book metadata.
+func (h *LookupHandler) LookupISBN(w http.ResponseWriter, r *http.Request) {
+	book, err := h.booksService.LookupISBN(r.Context(), chi.URLParam(r, "isbn"))
+	if err != nil {
+		switch {
+		case errors.Is(err, service.ErrInvalidISBN):
+			respondWithError(w, r, apperror.Validation(err.Error(), err))
+		case errors.Is(err, service.ErrBookNotFound):
+			respondWithError(w, r, apperror.Wrap(err, apperror.CodeBookNotFound, "Book not found"))
+		case errors.Is(err, service.ErrBookLookupUnavailable):
+			respondWithError(w, r, apperror.Wrap(err, apperror.CodeUnavailable, "Book lookup is unavailable"))
+		default:
+			respondWithError(w, r, apperror.Internal("Failed to look up book", err))
+		}
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, bookResponse{
+		ISBN:          book.ISBN,
+		Title:         book.Title,
+		Subtitle:      book.Subtitle,
+		OriginalTitle: book.OriginalTitle,
+		Language:      book.Language,
+		Authors:       book.Authors,
+		Publisher:     book.Publisher,
+		Year:          book.Year,
+		Pages:         book.Pages,
+		CoverURL:      book.CoverURL,
+	})
+}
@@ -0,0 +1,303 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/avalarin/livlog/backend/internal/apitime"
+	"github.com/avalarin/livlog/backend/internal/apperror"
+	"github.com/avalarin/livlog/backend/internal/middleware"
+	"github.com/avalarin/livlog/backend/internal/repository"
+	"github.com/avalarin/livlog/backend/internal/service"
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+)
+
+type NotificationHandler struct {
+	notificationService *service.NotificationService
+	channelService      *service.ChannelService
+}
+
+func NewNotificationHandler(
+	notificationService *service.NotificationService,
+	channelService *service.ChannelService,
+) *NotificationHandler {
+	return &NotificationHandler{
+		notificationService: notificationService,
+		channelService:      channelService,
+	}
+}
+
+func (h *NotificationHandler) RegisterRoutes(r chi.Router) {
+	r.Get("/notifications", h.GetNotifications)
+	r.Post("/notifications/{id}/read", h.MarkRead)
+
+	r.Get("/notifications/channels", h.GetChannels)
+	r.Post("/notifications/channels", h.CreateChannel)
+	r.Delete("/notifications/channels/{id}", h.DeleteChannel)
+	r.Post("/notifications/channels/{id}/test", h.TestChannel)
+}
+
+type notificationResponse struct {
+	ID        string                 `json:"id"`
+	Kind      string                 `json:"kind"`
+	Title     string                 `json:"title"`
+	Body      string                 `json:"body"`
+	Data      map[string]interface{} `json:"data"`
+	Read      bool                   `json:"read"`
+	ReadAt    *string                `json:"read_at,omitempty"`
+	CreatedAt string                 `json:"created_at"`
+}
+
+func (h *NotificationHandler) GetNotifications(w http.ResponseWriter, r *http.Request) {
+	userID := middleware.GetUserIDFromContext(r.Context())
+	if userID == "" {
+		respondWithError(w, r, apperror.Unauthorized("User not authenticated", nil))
+		return
+	}
+
+	uid, err := uuid.Parse(userID)
+	if err != nil {
+		respondWithError(w, r, apperror.BadRequest("Invalid user ID", err))
+		return
+	}
+
+	unreadOnly, _ := strconv.ParseBool(r.URL.Query().Get("unread"))
+	limit, offset, appErr := offsetParams(r)
+	if appErr != nil {
+		respondWithError(w, r, appErr)
+		return
+	}
+
+	notifications, err := h.notificationService.GetNotifications(r.Context(), uid, unreadOnly, limit, offset)
+	if err != nil {
+		respondWithError(w, r, apperror.Internal("Failed to get notifications", err))
+		return
+	}
+
+	response := make([]notificationResponse, len(notifications))
+	for i, n := range notifications {
+		response[i] = mapNotificationToResponse(n, timeLayout(r))
+	}
+
+	respondWithJSON(w, http.StatusOK, response)
+}
+
+func (h *NotificationHandler) MarkRead(w http.ResponseWriter, r *http.Request) {
+	userID := middleware.GetUserIDFromContext(r.Context())
+	if userID == "" {
+		respondWithError(w, r, apperror.Unauthorized("User not authenticated", nil))
+		return
+	}
+
+	uid, err := uuid.Parse(userID)
+	if err != nil {
+		respondWithError(w, r, apperror.BadRequest("Invalid user ID", err))
+		return
+	}
+
+	notificationID := chi.URLParam(r, "id")
+	nid, err := uuid.Parse(notificationID)
+	if err != nil {
+		respondWithError(w, r, apperror.BadRequest("Invalid notification ID", err))
+		return
+	}
+
+	notification, err := h.notificationService.MarkRead(r.Context(), nid, uid)
+	if err != nil {
+		if errors.Is(err, repository.ErrNotificationNotFound) {
+			respondWithError(w, r, apperror.Wrap(err, apperror.CodeNotificationNotFound, "Notification not found"))
+			return
+		}
+		respondWithError(w, r, apperror.Internal("Failed to mark notification as read", err))
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, mapNotificationToResponse(notification, timeLayout(r)))
+}
+
+type createChannelRequest struct {
+	Kind   string `json:"kind" validate:"required,oneof=telegram discord"`
+	Target string `json:"target" validate:"required,max=500"`
+}
+
+type channelResponse struct {
+	ID              string  `json:"id"`
+	Kind            string  `json:"kind"`
+	Target          string  `json:"target"`
+	LastError       *string `json:"last_error,omitempty"`
+	LastDeliveredAt *string `json:"last_delivered_at,omitempty"`
+	CreatedAt       string  `json:"created_at"`
+}
+
+func (h *NotificationHandler) GetChannels(w http.ResponseWriter, r *http.Request) {
+	userID := middleware.GetUserIDFromContext(r.Context())
+	if userID == "" {
+		respondWithError(w, r, apperror.Unauthorized("User not authenticated", nil))
+		return
+	}
+
+	uid, err := uuid.Parse(userID)
+	if err != nil {
+		respondWithError(w, r, apperror.BadRequest("Invalid user ID", err))
+		return
+	}
+
+	channels, err := h.channelService.GetChannels(r.Context(), uid)
+	if err != nil {
+		respondWithError(w, r, apperror.Internal("Failed to get notification channels", err))
+		return
+	}
+
+	response := make([]channelResponse, len(channels))
+	for i, c := range channels {
+		response[i] = mapChannelToResponse(c, timeLayout(r))
+	}
+
+	respondWithJSON(w, http.StatusOK, response)
+}
+
+func (h *NotificationHandler) CreateChannel(w http.ResponseWriter, r *http.Request) {
+	userID := middleware.GetUserIDFromContext(r.Context())
+	if userID == "" {
+		respondWithError(w, r, apperror.Unauthorized("User not authenticated", nil))
+		return
+	}
+
+	uid, err := uuid.Parse(userID)
+	if err != nil {
+		respondWithError(w, r, apperror.BadRequest("Invalid user ID", err))
+		return
+	}
+
+	var req createChannelRequest
+	if appErr := decodeAndValidate(r, &req); appErr != nil {
+		respondWithError(w, r, appErr)
+		return
+	}
+
+	channel, err := h.channelService.CreateChannel(r.Context(), uid, req.Kind, req.Target)
+	if err != nil {
+		switch {
+		case errors.Is(err, service.ErrInvalidChannelKind), errors.Is(err, service.ErrInvalidChannelTarget):
+			respondWithError(w, r, apperror.Validation(err.Error(), err))
+		case errors.Is(err, service.ErrChannelUnavailable):
+			respondWithError(w, r, apperror.Wrap(err, apperror.CodeUnavailable, "This channel is not available on this server"))
+		case errors.Is(err, service.ErrChannelLimitReached):
+			respondWithError(w, r, apperror.Wrap(err, apperror.CodeChannelLimitReached, "Notification channel limit reached"))
+		default:
+			respondWithError(w, r, apperror.Internal("Failed to create notification channel", err))
+		}
+		return
+	}
+
+	respondWithJSON(w, http.StatusCreated, mapChannelToResponse(channel, timeLayout(r)))
+}
+
+func (h *NotificationHandler) DeleteChannel(w http.ResponseWriter, r *http.Request) {
+	userID := middleware.GetUserIDFromContext(r.Context())
+	if userID == "" {
+		respondWithError(w, r, apperror.Unauthorized("User not authenticated", nil))
+		return
+	}
+
+	uid, err := uuid.Parse(userID)
+	if err != nil {
+		respondWithError(w, r, apperror.BadRequest("Invalid user ID", err))
+		return
+	}
+
+	channelID := chi.URLParam(r, "id")
+	cid, err := uuid.Parse(channelID)
+	if err != nil {
+		respondWithError(w, r, apperror.BadRequest("Invalid channel ID", err))
+		return
+	}
+
+	if err := h.channelService.DeleteChannel(r.Context(), cid, uid); err != nil {
+		if errors.Is(err, repository.ErrChannelNotFound) {
+			respondWithError(w, r, apperror.Wrap(err, apperror.CodeChannelNotFound, "Notification channel not found"))
+			return
+		}
+		respondWithError(w, r, apperror.Internal("Failed to delete notification channel", err))
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, map[string]string{"message": "Notification channel deleted successfully"})
+}
+
+// TestChannel sends a test message so the user can check the setup. A failed
+// send returns CHANNEL_DELIVERY_FAILED with the service's error in details.
+func (h *NotificationHandler) TestChannel(w http.ResponseWriter, r *http.Request) {
+	userID := middleware.GetUserIDFromContext(r.Context())
+	if userID == "" {
+		respondWithError(w, r, apperror.Unauthorized("User not authenticated", nil))
+		return
+	}
+
+	uid, err := uuid.Parse(userID)
+	if err != nil {
+		respondWithError(w, r, apperror.BadRequest("Invalid user ID", err))
+		return
+	}
+
+	channelID := chi.URLParam(r, "id")
+	cid, err := uuid.Parse(channelID)
+	if err != nil {
+		respondWithError(w, r, apperror.BadRequest("Invalid channel ID", err))
+		return
+	}
+
+	channel, err := h.channelService.TestChannel(r.Context(), cid, uid)
+	if err != nil {
+		switch {
+		case errors.Is(err, repository.ErrChannelNotFound):
+			respondWithError(w, r, apperror.Wrap(err, apperror.CodeChannelNotFound, "Notification channel not found"))
+		case errors.Is(err, service.ErrChannelDeliveryFailed):
+			details := map[string]interface{}{}
+			if channel != nil && channel.LastError != nil {
+				details["error"] = *channel.LastError
+			}
+			respondWithError(w, r, apperror.Wrap(err, apperror.CodeChannelDeliveryFailed, "Test message could not be delivered").
+				WithDetails(details))
+		default:
+			respondWithError(w, r, apperror.Internal("Failed to test notification channel", err))
+		}
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, mapChannelToResponse(channel, timeLayout(r)))
+}
+
+func mapChannelToResponse(c *repository.NotificationChannel, layout apitime.Layout) channelResponse {
+	response := channelResponse{
+		ID:        c.ID.String(),
+		Kind:      c.Kind,
+		Target:    service.MaskChannelTarget(c.Kind, c.Target),
+		LastError: c.LastError,
+		CreatedAt: layout.Format(c.CreatedAt),
+	}
+	if c.LastDeliveredAt != nil {
+		deliveredAt := layout.Format(*c.LastDeliveredAt)
+		response.LastDeliveredAt = &deliveredAt
+	}
+	return response
+}
+
+func mapNotificationToResponse(n *repository.Notification, layout apitime.Layout) notificationResponse {
+	response := notificationResponse{
+		ID:        n.ID.String(),
+		Kind:      n.Kind,
+		Title:     n.Title,
+		Body:      n.Body,
+		Data:      n.Data,
+		Read:      n.ReadAt != nil,
+		CreatedAt: layout.Format(n.CreatedAt),
+	}
+	if n.ReadAt != nil {
+		readAt := layout.Format(*n.ReadAt)
+		response.ReadAt = &readAt
+	}
+	return response
+}
@@ -0,0 +1,34 @@
+package handler
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/avalarin/livlog/backend/internal/apitime"
+	"github.com/avalarin/livlog/backend/internal/repository"
+	"github.com/avalarin/livlog/backend/internal/service"
+	"github.com/google/uuid"
+)
+
+func TestMapChannelToResponse_HidesWebhookSecret(t *testing.T) {
+	discord := &repository.NotificationChannel{
+		ID:        uuid.New(),
+		Kind:      service.ChannelDiscord,
+		Target:    "https://discord.com/api/webhooks/123456/s3cr3t-token",
+		CreatedAt: time.Now(),
+	}
+
+	resp := mapChannelToResponse(discord, apitime.Legacy)
+	if strings.Contains(resp.Target, "s3cr3t") || strings.Contains(resp.Target, "123456") {
+		t.Errorf("target leaks the webhook secret: %q", resp.Target)
+	}
+	if !strings.HasPrefix(resp.Target, "https://discord.com/") {
+		t.Errorf("target = %q, want the webhook host", resp.Target)
+	}
+
+	telegram := &repository.NotificationChannel{ID: uuid.New(), Kind: service.ChannelTelegram, Target: "-100123456"}
+	if got := mapChannelToResponse(telegram, apitime.Legacy).Target; got != "-100123456" {
+		t.Errorf("telegram target = %q, want the chat id", got)
+	}
+}
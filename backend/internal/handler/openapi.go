@@ -0,0 +1,61 @@
+package handler
+
+import (
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"gopkg.in/yaml.v3"
+)
+
+// openAPISpec is the hand-maintained API description. Keep it in sync with
+// the request/response DTOs when changing an endpoint.
+//
+//go:embed openapi.yaml
+var openAPISpec []byte
+
+//go:embed swagger.html
+var swaggerUI []byte
+
+type OpenAPIHandler struct {
+	specJSON []byte
+}
+
+// NewOpenAPIHandler converts the embedded YAML spec to JSON once at startup,
+// so a malformed spec fails fast instead of on first request.
+func NewOpenAPIHandler() (*OpenAPIHandler, error) {
+	var spec map[string]interface{}
+	if err := yaml.Unmarshal(openAPISpec, &spec); err != nil {
+		return nil, fmt.Errorf("failed to parse openapi spec: %w", err)
+	}
+
+	if info, ok := spec["info"].(map[string]interface{}); ok {
+		info["version"] = Version
+	}
+
+	specJSON, err := json.Marshal(spec)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode openapi spec: %w", err)
+	}
+
+	return &OpenAPIHandler{specJSON: specJSON}, nil
+}
+
+func (h *OpenAPIHandler) RegisterRoutes(r chi.Router) {
+	r.Get("/openapi.json", h.Spec)
+	r.Get("/docs", h.UI)
+}
+
+func (h *OpenAPIHandler) Spec(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	w.Write(h.specJSON)
+}
+
+func (h *OpenAPIHandler) UI(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.WriteHeader(http.StatusOK)
+	w.Write(swaggerUI)
+}
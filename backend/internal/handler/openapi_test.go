@@ -0,0 +1,68 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+)
+
+func TestOpenAPISpec_CoversRoutes(t *testing.T) {
+	h, err := NewOpenAPIHandler()
+	if err != nil {
+		t.Fatalf("failed to load spec: %v", err)
+	}
+
+	var spec struct {
+		Paths map[string]map[string]interface{} `json:"paths"`
+	}
+	if err := json.Unmarshal(h.specJSON, &spec); err != nil {
+		t.Fatalf("spec is not valid JSON: %v", err)
+	}
+
+	// Handlers are only used for their route tables here.
+	r := chi.NewRouter()
+	r.Get("/health", (&HealthHandler{}).Health)
+	(&AuthHandler{}).RegisterRoutes(r)
+	(&UsageHandler{}).RegisterRoutes(r)
+	(&BootstrapHandler{}).RegisterRoutes(r)
+	(&CollectionHandler{}).RegisterRoutes(r)
+	(&EntryHandler{}).RegisterRoutes(r)
+	(&EntryHandler{}).RegisterSearchRoutes(r)
+	(&EntryHandler{}).RegisterBulkRoutes(r)
+	(&EntryHandler{}).RegisterPublicRoutes(r)
+	(&TypeHandler{}).RegisterRoutes(r)
+	(&AISearchHandler{}).RegisterRoutes(r)
+	(&SyncHandler{}).RegisterRoutes(r)
+	(&SyncHandler{}).RegisterStreamRoutes(r)
+	(&WebhookHandler{}).RegisterRoutes(r)
+	(&NotificationHandler{}).RegisterRoutes(r)
+	(&ActivityHandler{}).RegisterRoutes(r)
+	(&StatsHandler{}).RegisterRoutes(r)
+	(&AdminHandler{}).RegisterRoutes(r)
+	(&LookupHandler{}).RegisterRoutes(r)
+	(&ExportHandler{}).RegisterRoutes(r)
+	(&ImportHandler{}).RegisterRoutes(r)
+	(&JobHandler{}).RegisterRoutes(r)
+	(&JobHandler{}).RegisterPublicRoutes(r)
+	(&ProfileHandler{}).RegisterRoutes(r)
+	(&ProfileHandler{}).RegisterPublicRoutes(r)
+	(&WorkspaceHandler{}).RegisterRoutes(r)
+
+	err = chi.Walk(r, func(method, route string, _ http.Handler, _ ...func(http.Handler) http.Handler) error {
+		ops, ok := spec.Paths[route]
+		if !ok {
+			t.Errorf("route %s %s is missing from openapi.yaml", method, route)
+			return nil
+		}
+		if _, ok := ops[strings.ToLower(method)]; !ok {
+			t.Errorf("operation %s %s is missing from openapi.yaml", method, route)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("failed to walk routes: %v", err)
+	}
+}
@@ -0,0 +1,93 @@
+package handler
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/avalarin/livlog/backend/internal/apperror"
+	"github.com/avalarin/livlog/backend/internal/middleware"
+	"github.com/avalarin/livlog/backend/internal/service"
+)
+
+// limitParam reads the limit query parameter of a list, 0 when absent. The
+// service applies the configured default and maximum, see
+// service.UsePageSizes.
+func limitParam(r *http.Request) (int, *apperror.Error) {
+	v := r.URL.Query().Get("limit")
+	if v == "" {
+		return 0, nil
+	}
+	n, err := strconv.Atoi(v)
+	if err != nil || n < 1 {
+		return 0, apperror.BadRequest("limit must be a positive number", err)
+	}
+	return n, nil
+}
+
+// offsetParams reads the limit and offset query parameters of a list paged
+// by offset.
+func offsetParams(r *http.Request) (int, int, *apperror.Error) {
+	limit, appErr := limitParam(r)
+	if appErr != nil {
+		return 0, 0, appErr
+	}
+
+	offset := 0
+	if v := r.URL.Query().Get("offset"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 0 {
+			return 0, 0, apperror.BadRequest("offset must be a non-negative number", err)
+		}
+		offset = n
+	}
+
+	return limit, offset, nil
+}
+
+// pageParams reads the cursor and limit query parameters of a paginated
+// list. Without a limit v1 returns everything, as before lists were
+// paginated; later versions return service.DefaultPageSize items.
+func pageParams(r *http.Request) (string, int, *apperror.Error) {
+	limit, appErr := limitParam(r)
+	if appErr != nil {
+		return "", 0, appErr
+	}
+	if limit == 0 && middleware.APIVersionFromContext(r.Context()) >= 2 {
+		limit = service.DefaultPageSize()
+	}
+
+	return r.URL.Query().Get("cursor"), limit, nil
+}
+
+// pageResponse is one page of a list: a bare array for v1, the shape the
+// list had before pagination, and an envelope with the next cursor from v2
+// on.
+type pageResponse[T any] struct {
+	Items      []T
+	NextCursor string
+}
+
+type pageEnvelope[T any] struct {
+	Items      []T     `json:"items"`
+	NextCursor *string `json:"next_cursor"`
+}
+
+func (p pageResponse[T]) ForVersion(version int) interface{} {
+	if version < 2 {
+		return p.Items
+	}
+	envelope := pageEnvelope[T]{Items: p.Items}
+	if p.NextCursor != "" {
+		envelope.NextCursor = &p.NextCursor
+	}
+	return envelope
+}
+
+// respondWithPage writes a list page. The next cursor is also sent as
+// X-Next-Cursor, so v1 clients can page through the bare array.
+func respondWithPage[T any](w http.ResponseWriter, r *http.Request, page pageResponse[T]) {
+	if page.NextCursor != "" {
+		w.Header().Set("X-Next-Cursor", page.NextCursor)
+	}
+	respondWithVersionedJSON(w, r, http.StatusOK, page)
+}
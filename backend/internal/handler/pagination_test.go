@@ -0,0 +1,111 @@
+package handler
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/avalarin/livlog/backend/internal/middleware"
+	"github.com/avalarin/livlog/backend/internal/service"
+)
+
+func TestPageParams(t *testing.T) {
+	tests := []struct {
+		name       string
+		version    int
+		query      string
+		wantLimit  int
+		wantCursor string
+		wantErr    bool
+	}{
+		{"v1 lists everything by default", 1, "", 0, "", false},
+		{"v2 defaults to a page", 2, "", service.DefaultPageSize(), "", false},
+		{"explicit limit", 1, "?limit=20&cursor=abc", 20, "abc", false},
+		{"zero limit", 2, "?limit=0", 0, "", true},
+		{"malformed limit", 2, "?limit=ten", 0, "", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var cursor string
+			var limit int
+			var appErrSet bool
+			handler := middleware.Versioned(middleware.APIVersion{Number: tt.version})(
+				http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+					c, l, appErr := pageParams(r)
+					cursor, limit, appErrSet = c, l, appErr != nil
+				}),
+			)
+			handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/collections"+tt.query, nil))
+
+			if appErrSet != tt.wantErr {
+				t.Fatalf("error = %v, want %v", appErrSet, tt.wantErr)
+			}
+			if !tt.wantErr && limit != tt.wantLimit {
+				t.Errorf("limit = %d, want %d", limit, tt.wantLimit)
+			}
+			if cursor != tt.wantCursor {
+				t.Errorf("cursor = %q, want %q", cursor, tt.wantCursor)
+			}
+		})
+	}
+}
+
+func TestOffsetParams(t *testing.T) {
+	tests := []struct {
+		query      string
+		wantLimit  int
+		wantOffset int
+		wantErr    bool
+	}{
+		{"", 0, 0, false},
+		{"?limit=20&offset=40", 20, 40, false},
+		{"?limit=0", 0, 0, true},
+		{"?limit=-5", 0, 0, true},
+		{"?offset=-1", 0, 0, true},
+		{"?offset=ten", 0, 0, true},
+	}
+
+	for _, tt := range tests {
+		limit, offset, appErr := offsetParams(httptest.NewRequest(http.MethodGet, "/entries"+tt.query, nil))
+		if (appErr != nil) != tt.wantErr {
+			t.Errorf("%q: error = %v, want %v", tt.query, appErr, tt.wantErr)
+			continue
+		}
+		if limit != tt.wantLimit || offset != tt.wantOffset {
+			t.Errorf("%q: got limit %d offset %d, want %d %d", tt.query, limit, offset, tt.wantLimit, tt.wantOffset)
+		}
+	}
+}
+
+func TestRespondWithPage(t *testing.T) {
+	tests := []struct {
+		version    int
+		next       string
+		wantBody   string
+		wantHeader string
+	}{
+		{1, "abc", `["a"]`, "abc"},
+		{2, "abc", `{"items":["a"],"next_cursor":"abc"}`, "abc"},
+		{2, "", `{"items":["a"],"next_cursor":null}`, ""},
+	}
+
+	for _, tt := range tests {
+		handler := middleware.Versioned(middleware.APIVersion{Number: tt.version})(
+			http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				respondWithPage(w, r, pageResponse[string]{Items: []string{"a"}, NextCursor: tt.next})
+			}),
+		)
+
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+
+		if got := strings.TrimSpace(rec.Body.String()); got != tt.wantBody {
+			t.Errorf("v%d: expected %s, got %s", tt.version, tt.wantBody, got)
+		}
+		if got := rec.Header().Get("X-Next-Cursor"); got != tt.wantHeader {
+			t.Errorf("v%d: X-Next-Cursor = %q, want %q", tt.version, got, tt.wantHeader)
+		}
+	}
+}
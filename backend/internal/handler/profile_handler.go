@@ -0,0 +1,311 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/avalarin/livlog/backend/internal/apitime"
+	"github.com/avalarin/livlog/backend/internal/apiv1"
+	"github.com/avalarin/livlog/backend/internal/apperror"
+	"github.com/avalarin/livlog/backend/internal/middleware"
+	"github.com/avalarin/livlog/backend/internal/repository"
+	"github.com/avalarin/livlog/backend/internal/service"
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+)
+
+type ProfileHandler struct {
+	profileService *service.ProfileService
+}
+
+func NewProfileHandler(profileService *service.ProfileService) *ProfileHandler {
+	return &ProfileHandler{
+		profileService: profileService,
+	}
+}
+
+func (h *ProfileHandler) RegisterRoutes(r chi.Router) {
+	r.Get("/profile", h.GetProfile)
+	r.Put("/profile", h.UpdateProfile)
+}
+
+// RegisterPublicRoutes registers routes that do not require authentication.
+func (h *ProfileHandler) RegisterPublicRoutes(r chi.Router) {
+	r.Get("/public/users/{handle}", h.GetPublicProfile)
+	r.Get("/public/c/{slug}", h.GetSharedCollection)
+}
+
+type updateProfileRequest struct {
+	Handle           string   `json:"handle" validate:"required,max=30"`
+	Public           bool     `json:"public"`
+	Bio              string   `json:"bio" validate:"max=1200"`
+	CollectionIDs    []string `json:"collection_ids" validate:"max=100,dive,uuid"`
+	FavoriteEntryIDs []string `json:"favorite_entry_ids" validate:"max=100,dive,uuid"`
+}
+
+type profileResponse struct {
+	Handle           string   `json:"handle"`
+	Public           bool     `json:"public"`
+	Bio              string   `json:"bio"`
+	CollectionIDs    []string `json:"collection_ids"`
+	FavoriteEntryIDs []string `json:"favorite_entry_ids"`
+	UpdatedAt        string   `json:"updated_at"`
+}
+
+type publicProfileResponse struct {
+	Handle      string                     `json:"handle"`
+	DisplayName *string                    `json:"display_name,omitempty"`
+	Bio         string                     `json:"bio"`
+	MemberSince string                     `json:"member_since"`
+	Stats       publicProfileStats         `json:"stats"`
+	Collections []publicCollectionResponse `json:"collections"`
+	Favorites   []publicEntryResponse      `json:"favorites"`
+}
+
+type publicProfileStats struct {
+	Collections     int `json:"collections"`
+	Entries         int `json:"entries"`
+	EntriesThisYear int `json:"entries_this_year"`
+}
+
+type publicCollectionResponse struct {
+	ID         string `json:"id"`
+	Name       string `json:"name"`
+	Icon       string `json:"icon"`
+	EntryCount int    `json:"entry_count"`
+}
+
+type sharedCollectionResponse struct {
+	Slug       string                `json:"slug"`
+	Name       string                `json:"name"`
+	Icon       string                `json:"icon"`
+	IconURL    *string               `json:"icon_url,omitempty"`
+	EntryCount int                   `json:"entry_count"`
+	Entries    []publicEntryResponse `json:"entries"`
+}
+
+// publicEntryResponse leaves out additional fields and timestamps, which are
+// not part of what a user chooses to show.
+type publicEntryResponse struct {
+	ID           string  `json:"id"`
+	CollectionID *string `json:"collection_id,omitempty"`
+	TypeID       *string `json:"type_id,omitempty"`
+	Title        string  `json:"title"`
+	Description  string  `json:"description"`
+	Score        int     `json:"score"`
+	Date         string  `json:"date"`
+	CoverURL     *string `json:"cover_url,omitempty"`
+}
+
+func (h *ProfileHandler) GetProfile(w http.ResponseWriter, r *http.Request) {
+	userID := middleware.GetUserIDFromContext(r.Context())
+	if userID == "" {
+		respondWithError(w, r, apperror.Unauthorized("User not authenticated", nil))
+		return
+	}
+
+	uid, err := uuid.Parse(userID)
+	if err != nil {
+		respondWithError(w, r, apperror.BadRequest("Invalid user ID", err))
+		return
+	}
+
+	profile, err := h.profileService.GetProfile(r.Context(), uid)
+	if err != nil {
+		if errors.Is(err, repository.ErrProfileNotFound) {
+			respondWithError(w, r, apperror.Wrap(err, apperror.CodeProfileNotFound, "Profile not found"))
+			return
+		}
+		respondWithError(w, r, apperror.Internal("Failed to get profile", err))
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, mapProfileToResponse(profile, timeLayout(r)))
+}
+
+func (h *ProfileHandler) UpdateProfile(w http.ResponseWriter, r *http.Request) {
+	userID := middleware.GetUserIDFromContext(r.Context())
+	if userID == "" {
+		respondWithError(w, r, apperror.Unauthorized("User not authenticated", nil))
+		return
+	}
+
+	uid, err := uuid.Parse(userID)
+	if err != nil {
+		respondWithError(w, r, apperror.BadRequest("Invalid user ID", err))
+		return
+	}
+
+	var req updateProfileRequest
+	if appErr := decodeAndValidate(r, &req); appErr != nil {
+		respondWithError(w, r, appErr)
+		return
+	}
+
+	collectionIDs, err := parseUUIDs(req.CollectionIDs)
+	if err != nil {
+		respondWithError(w, r, apperror.BadRequest("Invalid collection ID", err))
+		return
+	}
+	favoriteEntryIDs, err := parseUUIDs(req.FavoriteEntryIDs)
+	if err != nil {
+		respondWithError(w, r, apperror.BadRequest("Invalid entry ID", err))
+		return
+	}
+
+	profile, err := h.profileService.SaveProfile(r.Context(), uid, req.Handle, req.Public, req.Bio, collectionIDs, favoriteEntryIDs)
+	if err != nil {
+		switch {
+		case errors.Is(err, service.ErrInvalidHandle),
+			errors.Is(err, service.ErrInvalidBio),
+			errors.Is(err, service.ErrTooManyFavorites),
+			errors.Is(err, service.ErrInvalidFavorite),
+			errors.Is(err, service.ErrInvalidCollection):
+			respondWithError(w, r, apperror.Validation(err.Error(), err))
+		case errors.Is(err, service.ErrHandleReserved), errors.Is(err, repository.ErrHandleTaken):
+			respondWithError(w, r, apperror.Wrap(err, apperror.CodeHandleTaken, "Handle is already taken"))
+		default:
+			respondWithError(w, r, apperror.Internal("Failed to update profile", err))
+		}
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, mapProfileToResponse(profile, timeLayout(r)))
+}
+
+// GetPublicProfile serves a public profile to anyone. Unknown and private
+// handles both return PROFILE_NOT_FOUND, so private profiles can't be probed.
+func (h *ProfileHandler) GetPublicProfile(w http.ResponseWriter, r *http.Request) {
+	profile, err := h.profileService.GetPublicProfile(r.Context(), chi.URLParam(r, "handle"))
+	if err != nil {
+		if errors.Is(err, repository.ErrProfileNotFound) {
+			respondWithError(w, r, apperror.Wrap(err, apperror.CodeProfileNotFound, "Profile not found"))
+			return
+		}
+		respondWithError(w, r, apperror.Internal("Failed to get profile", err))
+		return
+	}
+
+	w.Header().Set("Cache-Control", "public, max-age=60")
+	respondWithJSON(w, http.StatusOK, mapPublicProfileToResponse(profile, timeLayout(r)))
+}
+
+// GetSharedCollection serves a collection shared under a slug to anyone,
+// with only its public entries.
+func (h *ProfileHandler) GetSharedCollection(w http.ResponseWriter, r *http.Request) {
+	shared, err := h.profileService.GetSharedCollection(r.Context(), chi.URLParam(r, "slug"))
+	if err != nil {
+		if errors.Is(err, repository.ErrCollectionNotFound) {
+			respondWithError(w, r, apperror.Wrap(err, apperror.CodeCollectionNotFound, "Collection not found"))
+			return
+		}
+		respondWithError(w, r, apperror.Internal("Failed to get collection", err))
+		return
+	}
+
+	w.Header().Set("Cache-Control", "public, max-age=60")
+	respondWithJSON(w, http.StatusOK, mapSharedCollectionToResponse(shared, timeLayout(r)))
+}
+
+func mapProfileToResponse(p *repository.Profile, layout apitime.Layout) profileResponse {
+	return profileResponse{
+		Handle:           p.Handle,
+		Public:           p.Public,
+		Bio:              p.Bio,
+		CollectionIDs:    uuidStrings(p.CollectionIDs),
+		FavoriteEntryIDs: uuidStrings(p.FavoriteEntryIDs),
+		UpdatedAt:        layout.Format(p.UpdatedAt),
+	}
+}
+
+func mapPublicProfileToResponse(p *service.PublicProfile, layout apitime.Layout) publicProfileResponse {
+	response := publicProfileResponse{
+		Handle:      p.Profile.Handle,
+		DisplayName: p.Profile.DisplayName,
+		Bio:         p.Profile.Bio,
+		MemberSince: layout.Format(p.Profile.MemberSince),
+		Stats: publicProfileStats{
+			Collections:     len(p.Collections),
+			Entries:         p.Stats.Entries,
+			EntriesThisYear: p.Stats.EntriesThisYear,
+		},
+		Collections: make([]publicCollectionResponse, len(p.Collections)),
+		Favorites:   make([]publicEntryResponse, len(p.Favorites)),
+	}
+
+	for i, c := range p.Collections {
+		response.Collections[i] = publicCollectionResponse{
+			ID:         c.ID.String(),
+			Name:       c.Name,
+			Icon:       c.Icon,
+			EntryCount: c.EntryCount,
+		}
+	}
+
+	for i, e := range p.Favorites {
+		response.Favorites[i] = mapPublicEntryToResponse(e, layout)
+	}
+
+	return response
+}
+
+func mapSharedCollectionToResponse(s *service.SharedCollection, layout apitime.Layout) sharedCollectionResponse {
+	c := s.Collection
+	response := sharedCollectionResponse{
+		Name:       c.Name,
+		Icon:       c.Icon,
+		EntryCount: c.EntryCount,
+		Entries:    make([]publicEntryResponse, len(s.Entries)),
+	}
+	if c.ShareSlug != nil {
+		response.Slug = *c.ShareSlug
+	}
+	if c.IconImageID != nil {
+		url := apiv1.CollectionIconURL(*c.IconImageID, c.IconImageHash)
+		response.IconURL = &url
+	}
+
+	for i, e := range s.Entries {
+		response.Entries[i] = mapPublicEntryToResponse(e, layout)
+	}
+
+	return response
+}
+
+func mapPublicEntryToResponse(e *repository.EntryWithImages, layout apitime.Layout) publicEntryResponse {
+	full := apiv1.MapEntry(e.Entry, nil, layout)
+	entry := publicEntryResponse{
+		ID:           full.ID,
+		CollectionID: full.CollectionID,
+		TypeID:       full.TypeID,
+		Title:        full.Title,
+		Description:  full.Description,
+		Score:        full.Score,
+		Date:         full.Date,
+	}
+	if e.CoverImageID != nil {
+		url := apiv1.EntryImageURL(*e.CoverImageID, e.CoverImageHash)
+		entry.CoverURL = &url
+	}
+	return entry
+}
+
+func parseUUIDs(values []string) ([]uuid.UUID, error) {
+	ids := make([]uuid.UUID, 0, len(values))
+	for _, v := range values {
+		id, err := uuid.Parse(v)
+		if err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	return ids, nil
+}
+
+func uuidStrings(ids []uuid.UUID) []string {
+	result := make([]string, len(ids))
+	for i, id := range ids {
+		result[i] = id.String()
+	}
+	return result
+}
@@ -0,0 +1,110 @@
+package handler
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/avalarin/livlog/backend/internal/apitime"
+	"github.com/avalarin/livlog/backend/internal/apiv1"
+	"github.com/avalarin/livlog/backend/internal/repository"
+	"github.com/avalarin/livlog/backend/internal/service"
+	"github.com/google/uuid"
+)
+
+func TestMapPublicProfileToResponse(t *testing.T) {
+	collectionID := uuid.New()
+	cover := uuid.New()
+	profile := &service.PublicProfile{
+		Profile: &repository.Profile{
+			UserID:      uuid.New(),
+			Handle:      "anna_reads",
+			Bio:         "Mostly sci-fi.",
+			MemberSince: time.Date(2024, 11, 3, 18, 20, 0, 0, time.UTC),
+		},
+		Stats: &repository.ProfileStats{Entries: 42, EntriesThisYear: 5},
+		Collections: []*repository.Collection{
+			{ID: collectionID, Name: "Books", Icon: "📚", EntryCount: 42},
+		},
+		Favorites: []*repository.EntryWithImages{
+			{
+				Entry: &repository.Entry{
+					ID:               uuid.New(),
+					CollectionID:     &collectionID,
+					Title:            "Dune",
+					Score:            3,
+					Date:             time.Date(2025, 1, 15, 0, 0, 0, 0, time.UTC),
+					AdditionalFields: map[string]string{"Notes": "private"},
+				},
+				CoverImageID: &cover,
+			},
+		},
+	}
+
+	resp := mapPublicProfileToResponse(profile, apitime.Legacy)
+	if resp.Stats.Collections != 1 || resp.Stats.Entries != 42 || resp.Stats.EntriesThisYear != 5 {
+		t.Errorf("stats = %+v", resp.Stats)
+	}
+	if resp.MemberSince != "2024-11-03T18:20:00Z" {
+		t.Errorf("member_since = %q", resp.MemberSince)
+	}
+	if len(resp.Favorites) != 1 || resp.Favorites[0].CoverURL == nil || *resp.Favorites[0].CoverURL != "/images/"+cover.String() {
+		t.Fatalf("favorites = %+v", resp.Favorites)
+	}
+
+	// Only what the user chose to show goes out
+	body, err := json.Marshal(resp)
+	if err != nil {
+		t.Fatalf("marshal: %v", err)
+	}
+	for _, leaked := range []string{"private", "user_id", "additional_fields", "created_at"} {
+		if strings.Contains(string(body), leaked) {
+			t.Errorf("public profile contains %q: %s", leaked, body)
+		}
+	}
+}
+
+func TestMapSharedCollectionToResponse(t *testing.T) {
+	slug := "anna-best-books-2024"
+	icon := uuid.New()
+	workspace := uuid.New()
+	shared := &service.SharedCollection{
+		Collection: &repository.Collection{
+			ID:          uuid.New(),
+			UserID:      uuid.New(),
+			Name:        "Best Books 2024",
+			IconImageID: &icon,
+			WorkspaceID: &workspace,
+			ShareSlug:   &slug,
+			EntryCount:  1,
+		},
+		Entries: []*repository.EntryWithImages{
+			{Entry: &repository.Entry{
+				ID:               uuid.New(),
+				Title:            "Dune",
+				Score:            3,
+				Date:             time.Date(2024, 3, 15, 0, 0, 0, 0, time.UTC),
+				AdditionalFields: map[string]string{"Notes": "private"},
+			}},
+		},
+	}
+
+	resp := mapSharedCollectionToResponse(shared, apitime.Legacy)
+	if resp.Slug != slug || resp.EntryCount != 1 || len(resp.Entries) != 1 || resp.Entries[0].Date != "2024-03-15" {
+		t.Fatalf("response = %+v", resp)
+	}
+	if resp.IconURL == nil || *resp.IconURL != apiv1.CollectionIconURL(icon, "") {
+		t.Errorf("icon_url = %v", resp.IconURL)
+	}
+
+	body, err := json.Marshal(resp)
+	if err != nil {
+		t.Fatalf("marshal: %v", err)
+	}
+	for _, leaked := range []string{"private", "user_id", "workspace_id", "additional_fields"} {
+		if strings.Contains(string(body), leaked) {
+			t.Errorf("shared collection contains %q: %s", leaked, body)
+		}
+	}
+}
@@ -0,0 +1,130 @@
+package handler
+
+import (
+	"math"
+	"net/http"
+	"time"
+
+	"github.com/avalarin/livlog/backend/internal/apitime"
+	"github.com/avalarin/livlog/backend/internal/apiv1"
+	"github.com/avalarin/livlog/backend/internal/apperror"
+	"github.com/avalarin/livlog/backend/internal/middleware"
+	"github.com/avalarin/livlog/backend/internal/service"
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+)
+
+const monthLayout = "2006-01"
+
+type StatsHandler struct {
+	statsService *service.StatsService
+}
+
+func NewStatsHandler(statsService *service.StatsService) *StatsHandler {
+	return &StatsHandler{
+		statsService: statsService,
+	}
+}
+
+func (h *StatsHandler) RegisterRoutes(r chi.Router) {
+	r.Get("/stats/month/{month}", h.GetMonthSummary)
+}
+
+type monthSummaryResponse struct {
+	Month         string               `json:"month"`
+	Entries       int                  `json:"entries"`
+	PreviousMonth previousMonthSummary `json:"previous_month"`
+	Change        int                  `json:"change"`
+	ChangePercent *float64             `json:"change_percent"`
+	AverageScore  *float64             `json:"average_score"`
+	TopEntry      *apiv1.Entry         `json:"top_entry"`
+	Types         []typeCountResponse  `json:"types"`
+}
+
+type previousMonthSummary struct {
+	Month   string `json:"month"`
+	Entries int    `json:"entries"`
+}
+
+type typeCountResponse struct {
+	TypeID *string `json:"type_id"`
+	Name   *string `json:"name"`
+	Icon   *string `json:"icon"`
+	Count  int     `json:"count"`
+}
+
+// GetMonthSummary returns the recap of the month given as YYYY-MM, by entry date.
+func (h *StatsHandler) GetMonthSummary(w http.ResponseWriter, r *http.Request) {
+	userID := middleware.GetUserIDFromContext(r.Context())
+	if userID == "" {
+		respondWithError(w, r, apperror.Unauthorized("User not authenticated", nil))
+		return
+	}
+
+	uid, err := uuid.Parse(userID)
+	if err != nil {
+		respondWithError(w, r, apperror.BadRequest("Invalid user ID", err))
+		return
+	}
+
+	month, err := time.Parse(monthLayout, chi.URLParam(r, "month"))
+	if err != nil {
+		respondWithError(w, r, apperror.BadRequest("Invalid month, expected YYYY-MM", err))
+		return
+	}
+
+	summary, err := h.statsService.MonthSummary(r.Context(), uid, month)
+	if err != nil {
+		respondWithError(w, r, apperror.Internal("Failed to get month summary", err))
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, mapMonthSummaryToResponse(summary, timeLayout(r)))
+}
+
+func mapMonthSummaryToResponse(s *service.MonthSummary, layout apitime.Layout) monthSummaryResponse {
+	response := monthSummaryResponse{
+		Month:   s.Month.Format(monthLayout),
+		Entries: s.Entries,
+		PreviousMonth: previousMonthSummary{
+			Month:   s.Month.AddDate(0, -1, 0).Format(monthLayout),
+			Entries: s.PreviousEntries,
+		},
+		Change: s.Entries - s.PreviousEntries,
+		Types:  make([]typeCountResponse, len(s.Types)),
+	}
+
+	if s.PreviousEntries > 0 {
+		percent := roundTo(float64(response.Change)/float64(s.PreviousEntries)*100, 1)
+		response.ChangePercent = &percent
+	}
+	if s.AverageScore != nil {
+		avg := roundTo(*s.AverageScore, 2)
+		response.AverageScore = &avg
+	}
+	if s.TopEntry != nil {
+		top := apiv1.MapEntry(s.TopEntry, s.TopEntryImages, layout)
+		response.TopEntry = &top
+	}
+
+	for i, tc := range s.Types {
+		var typeID *string
+		if tc.TypeID != nil {
+			tid := tc.TypeID.String()
+			typeID = &tid
+		}
+		response.Types[i] = typeCountResponse{
+			TypeID: typeID,
+			Name:   tc.Name,
+			Icon:   tc.Icon,
+			Count:  tc.Count,
+		}
+	}
+
+	return response
+}
+
+func roundTo(v float64, places int) float64 {
+	p := math.Pow(10, float64(places))
+	return math.Round(v*p) / p
+}
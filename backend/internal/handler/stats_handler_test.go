@@ -0,0 +1,62 @@
+package handler
+
+import (
+	"testing"
+	"time"
+
+	"github.com/avalarin/livlog/backend/internal/apitime"
+	"github.com/avalarin/livlog/backend/internal/repository"
+	"github.com/avalarin/livlog/backend/internal/service"
+	"github.com/google/uuid"
+)
+
+func TestMapMonthSummaryToResponse(t *testing.T) {
+	avg := 2.3333
+	typeID := uuid.New()
+	name, icon := "Book", "book"
+	entry := &repository.Entry{ID: uuid.New(), Title: "Dune", Score: 3, Date: time.Date(2025, 1, 20, 0, 0, 0, 0, time.UTC)}
+
+	resp := mapMonthSummaryToResponse(&service.MonthSummary{
+		Month:           time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC),
+		Entries:         6,
+		PreviousEntries: 4,
+		AverageScore:    &avg,
+		TopEntry:        entry,
+		Types: []repository.TypeCount{
+			{TypeID: &typeID, Name: &name, Icon: &icon, Count: 5},
+			{Count: 1},
+		},
+	}, apitime.Legacy)
+
+	if resp.Month != "2025-01" || resp.PreviousMonth.Month != "2024-12" {
+		t.Errorf("months = %q, %q, want 2025-01, 2024-12", resp.Month, resp.PreviousMonth.Month)
+	}
+	if resp.Change != 2 {
+		t.Errorf("change = %d, want 2", resp.Change)
+	}
+	if resp.ChangePercent == nil || *resp.ChangePercent != 50 {
+		t.Errorf("change_percent = %v, want 50", resp.ChangePercent)
+	}
+	if resp.AverageScore == nil || *resp.AverageScore != 2.33 {
+		t.Errorf("average_score = %v, want 2.33", resp.AverageScore)
+	}
+	if resp.TopEntry == nil || resp.TopEntry.Title != "Dune" {
+		t.Errorf("top_entry = %+v, want Dune", resp.TopEntry)
+	}
+	if len(resp.Types) != 2 || *resp.Types[0].TypeID != typeID.String() || resp.Types[1].TypeID != nil {
+		t.Errorf("types = %+v", resp.Types)
+	}
+}
+
+func TestMapMonthSummaryToResponse_Empty(t *testing.T) {
+	resp := mapMonthSummaryToResponse(&service.MonthSummary{
+		Month: time.Date(2025, 3, 1, 0, 0, 0, 0, time.UTC),
+	}, apitime.Legacy)
+
+	if resp.ChangePercent != nil || resp.AverageScore != nil || resp.TopEntry != nil {
+		t.Errorf("expected no percent, average or top entry, got %+v", resp)
+	}
+	if resp.Types == nil {
+		t.Error("types should be an empty list, not null")
+	}
+}
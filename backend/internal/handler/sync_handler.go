@@ -0,0 +1,357 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/avalarin/livlog/backend/internal/apiv1"
+	"github.com/avalarin/livlog/backend/internal/apperror"
+	"github.com/avalarin/livlog/backend/internal/middleware"
+	"github.com/avalarin/livlog/backend/internal/repository"
+	"github.com/avalarin/livlog/backend/internal/service"
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+)
+
+// streamHeartbeatInterval keeps idle streams alive through proxies that drop
+// silent connections.
+const streamHeartbeatInterval = 25 * time.Second
+
+type SyncHandler struct {
+	syncService *service.SyncService
+	changeFeed  *service.ChangeFeed
+}
+
+func NewSyncHandler(syncService *service.SyncService, changeFeed *service.ChangeFeed) *SyncHandler {
+	return &SyncHandler{
+		syncService: syncService,
+		changeFeed:  changeFeed,
+	}
+}
+
+func (h *SyncHandler) RegisterRoutes(r chi.Router) {
+	r.Get("/sync/changes", h.GetChanges)
+	r.Post("/sync/push", h.Push)
+}
+
+// RegisterStreamRoutes registers the long-lived change stream. It is kept
+// apart from RegisterRoutes so it can be mounted without request timeouts.
+func (h *SyncHandler) RegisterStreamRoutes(r chi.Router) {
+	r.Get("/sync/stream", h.Stream)
+}
+
+type tombstoneResponse struct {
+	EntityType string `json:"entity_type"`
+	EntityID   string `json:"entity_id"`
+	DeletedAt  string `json:"deleted_at"`
+}
+
+type syncChangesResponse struct {
+	Cursor      string              `json:"cursor"`
+	Entries     []apiv1.Entry       `json:"entries"`
+	Collections []apiv1.Collection  `json:"collections"`
+	Types       []apiv1.Type        `json:"types"`
+	Deleted     []tombstoneResponse `json:"deleted"`
+}
+
+type syncMutationRequest struct {
+	Op         string                   `json:"op" validate:"required,oneof=upsert delete"`
+	Entity     string                   `json:"entity" validate:"required,oneof=entry collection"`
+	ID         string                   `json:"id" validate:"required,uuid"`
+	Entry      *createEntryRequest      `json:"entry,omitempty" validate:"required_if=Op upsert Entity entry"`
+	Collection *createCollectionRequest `json:"collection,omitempty" validate:"required_if=Op upsert Entity collection"`
+}
+
+type syncPushRequest struct {
+	Mutations []syncMutationRequest `json:"mutations" validate:"required,min=1,max=100,dive"`
+}
+
+type syncErrorBody struct {
+	Code    apperror.Code `json:"code"`
+	Message string        `json:"message"`
+}
+
+type syncResultResponse struct {
+	ID     string         `json:"id"`
+	Status string         `json:"status"` // "applied" or "failed"
+	Error  *syncErrorBody `json:"error,omitempty"`
+}
+
+type syncPushResponse struct {
+	Results []syncResultResponse `json:"results"`
+}
+
+type changeEventResponse struct {
+	Entity string `json:"entity"`
+	ID     string `json:"id"`
+	Op     string `json:"op"`
+}
+
+// GetChanges returns entries, collections and types changed since the
+// `since` cursor, plus tombstones for deleted records and the next cursor.
+// With `min_seq` the changes include every write whose response carried that
+// change sequence or a lower one.
+func (h *SyncHandler) GetChanges(w http.ResponseWriter, r *http.Request) {
+	userID := middleware.GetUserIDFromContext(r.Context())
+	if userID == "" {
+		respondWithError(w, r, apperror.Unauthorized("User not authenticated", nil))
+		return
+	}
+
+	uid, err := uuid.Parse(userID)
+	if err != nil {
+		respondWithError(w, r, apperror.BadRequest("Invalid user ID", err))
+		return
+	}
+
+	var minSeq int64
+	if param := r.URL.Query().Get("min_seq"); param != "" {
+		minSeq, err = strconv.ParseInt(param, 10, 64)
+		if err != nil {
+			respondWithError(w, r, apperror.BadRequest("Invalid change sequence", err))
+			return
+		}
+	}
+
+	changes, err := h.syncService.Changes(r.Context(), uid, r.URL.Query().Get("since"), minSeq)
+	if err != nil {
+		if errors.Is(err, service.ErrInvalidSyncCursor) {
+			respondWithError(w, r, apperror.BadRequest("Invalid sync cursor", err))
+			return
+		}
+		if errors.Is(err, service.ErrInvalidChangeSeq) {
+			respondWithError(w, r, apperror.BadRequest("Invalid change sequence", err))
+			return
+		}
+		respondWithError(w, r, apperror.Internal("Failed to get changes", err))
+		return
+	}
+
+	layout := timeLayout(r)
+	response := syncChangesResponse{
+		Cursor:      changes.Cursor,
+		Entries:     make([]apiv1.Entry, len(changes.Entries)),
+		Collections: make([]apiv1.Collection, len(changes.Collections)),
+		Types:       make([]apiv1.Type, len(changes.Types)),
+		Deleted:     make([]tombstoneResponse, len(changes.Deleted)),
+	}
+	for i, e := range changes.Entries {
+		response.Entries[i] = apiv1.MapEntry(e.Entry, e.Images, layout)
+	}
+	for i, c := range changes.Collections {
+		response.Collections[i] = apiv1.MapCollection(c, layout)
+	}
+	for i, t := range changes.Types {
+		response.Types[i] = apiv1.MapType(t, layout)
+	}
+	for i, t := range changes.Deleted {
+		response.Deleted[i] = tombstoneResponse{
+			EntityType: t.EntityType,
+			EntityID:   t.EntityID.String(),
+			DeletedAt:  layout.Format(t.DeletedAt),
+		}
+	}
+
+	respondWithJSON(w, http.StatusOK, response)
+}
+
+// Push applies a batch of offline mutations in order. The request succeeds as
+// a whole; each mutation reports whether it was applied.
+func (h *SyncHandler) Push(w http.ResponseWriter, r *http.Request) {
+	userID := middleware.GetUserIDFromContext(r.Context())
+	if userID == "" {
+		respondWithError(w, r, apperror.Unauthorized("User not authenticated", nil))
+		return
+	}
+
+	uid, err := uuid.Parse(userID)
+	if err != nil {
+		respondWithError(w, r, apperror.BadRequest("Invalid user ID", err))
+		return
+	}
+
+	var req syncPushRequest
+	if appErr := decodeAndValidate(r, &req); appErr != nil {
+		respondWithError(w, r, appErr)
+		return
+	}
+
+	mutations := make([]service.SyncMutation, len(req.Mutations))
+	for i := range req.Mutations {
+		m, err := req.Mutations[i].toMutation()
+		if err != nil {
+			respondWithError(w, r, apperror.BadRequest("Invalid request body", err))
+			return
+		}
+		mutations[i] = m
+	}
+
+	results := h.syncService.Push(r.Context(), uid, entryDevice(r), mutations)
+
+	response := syncPushResponse{Results: make([]syncResultResponse, len(results))}
+	for i, err := range results {
+		result := syncResultResponse{ID: mutations[i].ID.String(), Status: "applied"}
+		if err != nil {
+			appErr := mapSyncError(err)
+			result.Status = "failed"
+			result.Error = &syncErrorBody{Code: appErr.Code, Message: appErr.Message}
+		}
+		response.Results[i] = result
+	}
+
+	respondWithJSON(w, http.StatusOK, response)
+}
+
+// Stream sends the user's entry and collection changes as server-sent events
+// until the client disconnects. Events only say what changed; clients pull
+// /sync/changes to fetch it, including once after (re)connecting.
+func (h *SyncHandler) Stream(w http.ResponseWriter, r *http.Request) {
+	userID := middleware.GetUserIDFromContext(r.Context())
+	if userID == "" {
+		respondWithError(w, r, apperror.Unauthorized("User not authenticated", nil))
+		return
+	}
+
+	uid, err := uuid.Parse(userID)
+	if err != nil {
+		respondWithError(w, r, apperror.BadRequest("Invalid user ID", err))
+		return
+	}
+
+	// Streams outlive the server's write timeout
+	rc := http.NewResponseController(w)
+	_ = rc.SetWriteDeadline(time.Time{})
+
+	events, unsubscribe := h.changeFeed.Subscribe(uid)
+	defer unsubscribe()
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+
+	// Tell the client how long to wait before reconnecting
+	if _, err := fmt.Fprint(w, "retry: 5000\n\n"); err != nil {
+		return
+	}
+	if err := rc.Flush(); err != nil {
+		return
+	}
+
+	heartbeat := time.NewTicker(streamHeartbeatInterval)
+	defer heartbeat.Stop()
+
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case <-heartbeat.C:
+			if _, err := fmt.Fprint(w, ": ping\n\n"); err != nil {
+				return
+			}
+		case event, ok := <-events:
+			if !ok {
+				return
+			}
+			data, err := json.Marshal(changeEventResponse{
+				Entity: event.Entity,
+				ID:     event.ID.String(),
+				Op:     event.Op,
+			})
+			if err != nil {
+				return
+			}
+			if _, err := fmt.Fprintf(w, "event: change\ndata: %s\n\n", data); err != nil {
+				return
+			}
+		}
+
+		if err := rc.Flush(); err != nil {
+			return
+		}
+	}
+}
+
+// toMutation converts a validated request. Payloads that do not apply to the
+// operation (e.g. an entry on a delete) are ignored.
+func (req *syncMutationRequest) toMutation() (service.SyncMutation, error) {
+	m := service.SyncMutation{Op: req.Op, Entity: req.Entity}
+
+	id, err := uuid.Parse(req.ID)
+	if err != nil {
+		return m, err
+	}
+	m.ID = id
+
+	if req.Op != service.SyncOpUpsert {
+		return m, nil
+	}
+
+	switch req.Entity {
+	case service.SyncEntityEntry:
+		parsed, err := req.Entry.parse()
+		if err != nil {
+			return m, err
+		}
+		m.Entry = &service.EntryInput{
+			CollectionID:     parsed.collectionID,
+			TypeID:           parsed.typeID,
+			Title:            req.Entry.Title,
+			OriginalTitle:    req.Entry.OriginalTitle,
+			Language:         req.Entry.Language,
+			Description:      req.Entry.Description,
+			Score:            req.Entry.Score,
+			Priority:         req.Entry.Priority,
+			Visibility:       req.Entry.Visibility,
+			Date:             parsed.date,
+			AdditionalFields: req.Entry.AdditionalFields,
+			Images:           parsed.images,
+		}
+	case service.SyncEntityCollection:
+		icon, err := req.Collection.icon()
+		if err != nil {
+			return m, err
+		}
+		m.Collection = &service.CollectionInput{
+			Name: req.Collection.Name,
+			Icon: icon,
+		}
+	}
+
+	return m, nil
+}
+
+// mapSyncError maps a per-mutation failure to the error reported for it.
+func mapSyncError(err error) *apperror.Error {
+	switch {
+	case errors.Is(err, repository.ErrEntryNotFound):
+		return apperror.Wrap(err, apperror.CodeEntryNotFound, "Entry not found")
+	case errors.Is(err, repository.ErrCollectionNotFound):
+		return apperror.Wrap(err, apperror.CodeCollectionNotFound, "Collection not found")
+	case errors.Is(err, repository.ErrImageNotFound):
+		return apperror.Wrap(err, apperror.CodeImageNotFound, "Icon image not found")
+	case errors.Is(err, service.ErrInvalidTitle),
+		errors.Is(err, service.ErrInvalidDescription),
+		errors.Is(err, service.ErrInvalidScore),
+		errors.Is(err, service.ErrInvalidFieldValue),
+		errors.Is(err, service.ErrInvalidPriority),
+		errors.Is(err, service.ErrInvalidVisibility),
+		errors.Is(err, service.ErrInvalidOriginalTitle),
+		errors.Is(err, service.ErrInvalidLanguage),
+		errors.Is(err, service.ErrTypeNotAllowed),
+		errors.Is(err, service.ErrInvalidCollectionName),
+		errors.Is(err, service.ErrInvalidIcon),
+		errors.Is(err, service.ErrIconChoice),
+		errors.Is(err, service.ErrInvalidMutation),
+		errors.Is(err, repository.ErrTypeNotFound):
+		return apperror.Validation(err.Error(), err)
+	case errors.Is(err, service.ErrQuotaExceeded):
+		return quotaError(err)
+	default:
+		return apperror.Internal("Failed to apply mutation", err)
+	}
+}
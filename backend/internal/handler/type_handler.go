@@ -1,11 +1,12 @@
 package handler
 
 import (
-	"encoding/json"
 	"errors"
 	"net/http"
 
-	"github.com/avalarin/livlog/backend/internal/repository"
+	"github.com/avalarin/livlog/backend/internal/apiv1"
+	"github.com/avalarin/livlog/backend/internal/apperror"
+	"github.com/avalarin/livlog/backend/internal/middleware"
 	"github.com/avalarin/livlog/backend/internal/service"
 	"github.com/go-chi/chi/v5"
 	"github.com/google/uuid"
@@ -25,89 +26,92 @@ func (h *TypeHandler) RegisterRoutes(r chi.Router) {
 }
 
 type createTypeRequest struct {
-	Name string `json:"name"`
-	Icon string `json:"icon"`
+	Name       string             `json:"name" validate:"required,max=50"`
+	Icon       string             `json:"icon" validate:"required,max=20"`
+	ScoreScale *scoreScaleRequest `json:"score_scale,omitempty"`
 }
 
-type typeResponse struct {
-	ID        string                        `json:"id"`
-	Name      string                        `json:"name"`
-	Icon      string                        `json:"icon"`
-	Fields    []repository.FieldDefinition  `json:"fields"`
-	CreatedAt string                        `json:"created_at"`
-	UpdatedAt string                        `json:"updated_at"`
+type scoreScaleRequest struct {
+	Kind   string   `json:"kind" validate:"required,oneof=reaction thumbs stars points"`
+	Labels []string `json:"labels,omitempty" validate:"omitempty,max=10"`
 }
 
+// GetTypes lists system types plus the user's own, a page at a time when
+// ?limit= is set (and always from v2 on), see pageParams.
 func (h *TypeHandler) GetTypes(w http.ResponseWriter, r *http.Request) {
-	userID := getUserIDFromContext(r.Context())
+	userID := middleware.GetUserIDFromContext(r.Context())
 	if userID == "" {
-		respondWithError(w, http.StatusUnauthorized, "User not authenticated", nil)
+		respondWithError(w, r, apperror.Unauthorized("User not authenticated", nil))
 		return
 	}
 
 	uid, err := uuid.Parse(userID)
 	if err != nil {
-		respondWithError(w, http.StatusBadRequest, "Invalid user ID", err)
+		respondWithError(w, r, apperror.BadRequest("Invalid user ID", err))
 		return
 	}
 
-	types, err := h.typeService.GetAllTypes(r.Context(), uid)
+	cursor, limit, appErr := pageParams(r)
+	if appErr != nil {
+		respondWithError(w, r, appErr)
+		return
+	}
+
+	page, err := h.typeService.ListTypes(r.Context(), uid, cursor, limit)
 	if err != nil {
-		respondWithError(w, http.StatusInternalServerError, "Failed to get types", err)
+		if errors.Is(err, service.ErrInvalidPageCursor) {
+			respondWithError(w, r, apperror.BadRequest("Invalid cursor", err))
+			return
+		}
+		respondWithError(w, r, apperror.Internal("Failed to get types", err))
 		return
 	}
 
-	response := make([]typeResponse, len(types))
-	for i, t := range types {
-		response[i] = mapTypeToResponse(t)
+	response := make([]apiv1.Type, len(page.Items))
+	for i, t := range page.Items {
+		response[i] = apiv1.MapType(t, timeLayout(r))
 	}
 
-	respondWithJSON(w, http.StatusOK, response)
+	respondWithPage(w, r, pageResponse[apiv1.Type]{Items: response, NextCursor: page.NextCursor})
 }
 
 func (h *TypeHandler) CreateType(w http.ResponseWriter, r *http.Request) {
-	userID := getUserIDFromContext(r.Context())
+	userID := middleware.GetUserIDFromContext(r.Context())
 	if userID == "" {
-		respondWithError(w, http.StatusUnauthorized, "User not authenticated", nil)
+		respondWithError(w, r, apperror.Unauthorized("User not authenticated", nil))
 		return
 	}
 
 	uid, err := uuid.Parse(userID)
 	if err != nil {
-		respondWithError(w, http.StatusBadRequest, "Invalid user ID", err)
+		respondWithError(w, r, apperror.BadRequest("Invalid user ID", err))
 		return
 	}
 
 	var req createTypeRequest
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		respondWithError(w, http.StatusBadRequest, "Invalid request body", err)
+	if appErr := decodeAndValidate(r, &req); appErr != nil {
+		respondWithError(w, r, appErr)
 		return
 	}
 
-	t, err := h.typeService.CreateType(r.Context(), uid, req.Name, req.Icon)
+	var scaleKind string
+	var scaleLabels []string
+	if req.ScoreScale != nil {
+		scaleKind = req.ScoreScale.Kind
+		scaleLabels = req.ScoreScale.Labels
+	}
+
+	t, err := h.typeService.CreateType(r.Context(), uid, req.Name, req.Icon, scaleKind, scaleLabels)
 	if err != nil {
-		if errors.Is(err, service.ErrInvalidTypeName) || errors.Is(err, service.ErrInvalidTypeIcon) {
-			respondWithError(w, http.StatusBadRequest, err.Error(), err)
+		if errors.Is(err, service.ErrInvalidTypeName) ||
+			errors.Is(err, service.ErrInvalidTypeIcon) ||
+			errors.Is(err, service.ErrInvalidScale) {
+			respondWithError(w, r, apperror.Validation(err.Error(), err))
 			return
 		}
-		respondWithError(w, http.StatusInternalServerError, "Failed to create type", err)
+		respondWithError(w, r, apperror.Internal("Failed to create type", err))
 		return
 	}
 
-	respondWithJSON(w, http.StatusCreated, mapTypeToResponse(t))
-}
-
-func mapTypeToResponse(t *repository.EntryType) typeResponse {
-	fields := t.Fields
-	if fields == nil {
-		fields = []repository.FieldDefinition{}
-	}
-	return typeResponse{
-		ID:        t.ID.String(),
-		Name:      t.Name,
-		Icon:      t.Icon,
-		Fields:    fields,
-		CreatedAt: t.CreatedAt.Format("2006-01-02T15:04:05Z07:00"),
-		UpdatedAt: t.UpdatedAt.Format("2006-01-02T15:04:05Z07:00"),
-	}
+	respondWithJSON(w, http.StatusCreated, apiv1.MapType(t, timeLayout(r)))
 }
@@ -0,0 +1,89 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/avalarin/livlog/backend/internal/apperror"
+	"github.com/avalarin/livlog/backend/internal/middleware"
+	"github.com/avalarin/livlog/backend/internal/service"
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+)
+
+type UsageHandler struct {
+	usageService *service.UsageService
+}
+
+func NewUsageHandler(usageService *service.UsageService) *UsageHandler {
+	return &UsageHandler{
+		usageService: usageService,
+	}
+}
+
+func (h *UsageHandler) RegisterRoutes(r chi.Router) {
+	r.Get("/auth/me/usage", h.GetUsage)
+}
+
+type usageResponse struct {
+	From         string               `json:"from"`
+	To           string               `json:"to"`
+	APICalls     int64                `json:"api_calls"`
+	AISearches   int                  `json:"ai_searches"`
+	Exports      int                  `json:"exports"`
+	Images       int64                `json:"images"`
+	StorageBytes int64                `json:"storage_bytes"`
+	Days         []dailyUsageResponse `json:"days"`
+}
+
+type dailyUsageResponse struct {
+	Date       string `json:"date"`
+	APICalls   int64  `json:"api_calls"`
+	AISearches int    `json:"ai_searches"`
+	Exports    int    `json:"exports"`
+}
+
+// GetUsage returns the user's API calls, AI searches and exports over the
+// last 30 days, day by day, with what they store now.
+func (h *UsageHandler) GetUsage(w http.ResponseWriter, r *http.Request) {
+	userID := middleware.GetUserIDFromContext(r.Context())
+	if userID == "" {
+		respondWithError(w, r, apperror.Unauthorized("User not authenticated", nil))
+		return
+	}
+
+	uid, err := uuid.Parse(userID)
+	if err != nil {
+		respondWithError(w, r, apperror.BadRequest("Invalid user ID", err))
+		return
+	}
+
+	usage, err := h.usageService.GetUsage(r.Context(), uid)
+	if err != nil {
+		respondWithError(w, r, apperror.Internal("Failed to get usage", err))
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, mapUsageToResponse(usage))
+}
+
+func mapUsageToResponse(u *service.Usage) usageResponse {
+	response := usageResponse{
+		From:         u.From.Format(dateLayout),
+		To:           u.To.Format(dateLayout),
+		APICalls:     u.APICalls,
+		AISearches:   u.AISearches,
+		Exports:      u.Exports,
+		Images:       u.Images,
+		StorageBytes: u.StorageBytes,
+		Days:         make([]dailyUsageResponse, len(u.Days)),
+	}
+	for i, d := range u.Days {
+		response.Days[i] = dailyUsageResponse{
+			Date:       d.Day.Format(dateLayout),
+			APICalls:   d.APICalls,
+			AISearches: d.AISearches,
+			Exports:    d.Exports,
+		}
+	}
+	return response
+}
@@ -0,0 +1,34 @@
+package handler
+
+import (
+	"testing"
+	"time"
+
+	"github.com/avalarin/livlog/backend/internal/repository"
+	"github.com/avalarin/livlog/backend/internal/service"
+)
+
+func TestMapUsageToResponse(t *testing.T) {
+	from := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	resp := mapUsageToResponse(&service.Usage{
+		From:         from,
+		To:           from.AddDate(0, 0, 1),
+		APICalls:     15,
+		AISearches:   2,
+		StorageBytes: 2048,
+		Days: []repository.DailyUsage{
+			{Day: from, APICalls: 10, AISearches: 2},
+			{Day: from.AddDate(0, 0, 1), APICalls: 5},
+		},
+	})
+
+	if resp.From != "2025-01-01" || resp.To != "2025-01-02" {
+		t.Errorf("from, to = %q, %q, want 2025-01-01, 2025-01-02", resp.From, resp.To)
+	}
+	if resp.APICalls != 15 || resp.AISearches != 2 || resp.Exports != 0 || resp.StorageBytes != 2048 {
+		t.Errorf("totals = %+v", resp)
+	}
+	if len(resp.Days) != 2 || resp.Days[1].Date != "2025-01-02" || resp.Days[1].APICalls != 5 {
+		t.Errorf("days = %+v", resp.Days)
+	}
+}
@@ -0,0 +1,123 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/go-playground/validator/v10"
+
+	"github.com/avalarin/livlog/backend/internal/apiv1"
+	"github.com/avalarin/livlog/backend/internal/apperror"
+)
+
+// dateLayout is the wire format for calendar dates (entries, filters).
+const dateLayout = apiv1.DateLayout
+
+var validate = newValidator()
+
+func newValidator() *validator.Validate {
+	v := validator.New(validator.WithRequiredStructEnabled())
+
+	// Report fields by their JSON names so details match the request body.
+	v.RegisterTagNameFunc(func(f reflect.StructField) string {
+		name := strings.SplitN(f.Tag.Get("json"), ",", 2)[0]
+		if name == "-" {
+			return ""
+		}
+		return name
+	})
+
+	_ = v.RegisterValidation("date", func(fl validator.FieldLevel) bool {
+		_, err := time.Parse(dateLayout, fl.Field().String())
+		return err == nil
+	})
+
+	return v
+}
+
+// decodeAndValidate decodes the JSON request body into dst and validates it
+// against its `validate` struct tags. All failing fields are reported at once
+// in the error details, keyed by JSON path (e.g. "images[0].data").
+func decodeAndValidate(r *http.Request, dst interface{}) *apperror.Error {
+	if err := json.NewDecoder(r.Body).Decode(dst); err != nil {
+		var maxErr *http.MaxBytesError
+		if errors.As(err, &maxErr) {
+			return apperror.Wrap(err, apperror.CodePayloadTooLarge, "Request body is too large").
+				WithDetails(map[string]interface{}{"limit_bytes": maxErr.Limit})
+		}
+		return apperror.BadRequest("Invalid request body", err)
+	}
+	return validateStruct(dst)
+}
+
+func validateStruct(s interface{}) *apperror.Error {
+	err := validate.Struct(s)
+	if err == nil {
+		return nil
+	}
+
+	var fieldErrs validator.ValidationErrors
+	if !errors.As(err, &fieldErrs) {
+		return apperror.Internal("Failed to validate request", err)
+	}
+
+	details := make(map[string]interface{}, len(fieldErrs))
+	for _, fe := range fieldErrs {
+		field := fieldPath(fe)
+		msgs, _ := details[field].([]string)
+		details[field] = append(msgs, fieldMessage(fe))
+	}
+
+	return apperror.Validation("Validation failed", err).WithDetails(details)
+}
+
+// fieldPath strips the struct name from the namespace:
+// "createEntryRequest.images[0].data" -> "images[0].data".
+func fieldPath(fe validator.FieldError) string {
+	ns := fe.Namespace()
+	if i := strings.IndexByte(ns, '.'); i >= 0 {
+		return ns[i+1:]
+	}
+	return ns
+}
+
+func fieldMessage(fe validator.FieldError) string {
+	switch fe.Tag() {
+	case "required", "required_without":
+		return "is required"
+	case "uuid":
+		return "must be a valid UUID"
+	case "date":
+		return "must be a date in YYYY-MM-DD format"
+	case "base64":
+		return "must be base64 encoded"
+	case "oneof":
+		return "must be one of: " + strings.ReplaceAll(fe.Param(), " ", ", ")
+	case "required_if":
+		return "is required for this operation"
+	case "url":
+		return "must be a valid URL"
+	case "unique":
+		return "must not contain duplicates"
+	case "min", "max":
+		bound := "at least"
+		if fe.Tag() == "max" {
+			bound = "at most"
+		}
+		switch fe.Kind() {
+		case reflect.String:
+			return fmt.Sprintf("must be %s %s characters", bound, fe.Param())
+		case reflect.Slice, reflect.Map:
+			return fmt.Sprintf("must contain %s %s items", bound, fe.Param())
+		default:
+			return fmt.Sprintf("must be %s %s", bound, fe.Param())
+		}
+	default:
+		return fmt.Sprintf("failed %q validation", fe.Tag())
+	}
+}
@@ -0,0 +1,277 @@
+package handler
+
+import (
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/avalarin/livlog/backend/internal/apperror"
+)
+
+func TestDecodeAndValidate_CreateEntry(t *testing.T) {
+	body := `{
+		"collection_id": "not-a-uuid",
+		"title": "",
+		"description": "ok",
+		"score": 11,
+		"date": "15.01.2025",
+		"images": [{"data": "%%%", "position": 0}]
+	}`
+	r := httptest.NewRequest("POST", "/api/v1/entries", strings.NewReader(body))
+
+	var req createEntryRequest
+	appErr := decodeAndValidate(r, &req)
+	if appErr == nil {
+		t.Fatal("expected validation error")
+	}
+	if appErr.Code != apperror.CodeValidation {
+		t.Errorf("expected code %s, got %s", apperror.CodeValidation, appErr.Code)
+	}
+
+	want := map[string]interface{}{
+		"collection_id":  []string{"must be a valid UUID"},
+		"title":          []string{"is required"},
+		"score":          []string{"must be at most 10"},
+		"date":           []string{"must be a date in YYYY-MM-DD format"},
+		"images[0].data": []string{"must be base64 encoded"},
+	}
+	if !reflect.DeepEqual(appErr.Details, want) {
+		t.Errorf("unexpected details:\n got: %v\nwant: %v", appErr.Details, want)
+	}
+}
+
+func TestDecodeAndValidate_Valid(t *testing.T) {
+	body := `{"title": "Dune", "description": "Spice", "score": 3, "date": "2025-01-15", "seed_image_ids": ["00000000-0000-0000-0000-000000000001"]}`
+	r := httptest.NewRequest("POST", "/api/v1/entries", strings.NewReader(body))
+
+	var req createEntryRequest
+	if appErr := decodeAndValidate(r, &req); appErr != nil {
+		t.Fatalf("expected no error, got %v", appErr)
+	}
+
+	parsed, err := req.parse()
+	if err != nil {
+		t.Fatalf("expected no parse error, got %v", err)
+	}
+	if parsed.date.Format(dateLayout) != "2025-01-15" || len(parsed.seedImageIDs) != 1 {
+		t.Errorf("unexpected parsed request: %+v", parsed)
+	}
+}
+
+func TestDecodeAndValidate_MalformedJSON(t *testing.T) {
+	r := httptest.NewRequest("POST", "/api/v1/collections", strings.NewReader(`{"name":`))
+
+	var req createCollectionRequest
+	appErr := decodeAndValidate(r, &req)
+	if appErr == nil || appErr.Code != apperror.CodeBadRequest {
+		t.Fatalf("expected %s, got %v", apperror.CodeBadRequest, appErr)
+	}
+}
+
+func TestDecodeAndValidate_CollectionAllowedTypes(t *testing.T) {
+	r := httptest.NewRequest("POST", "/api/v1/collections", strings.NewReader(`{"name": "Books", "icon": "📚", "allowed_type_ids": ["book"]}`))
+
+	var req createCollectionRequest
+	appErr := decodeAndValidate(r, &req)
+	if appErr == nil {
+		t.Fatal("expected validation error")
+	}
+	want := map[string]interface{}{"allowed_type_ids[0]": []string{"must be a valid UUID"}}
+	if !reflect.DeepEqual(appErr.Details, want) {
+		t.Errorf("unexpected details:\n got: %v\nwant: %v", appErr.Details, want)
+	}
+
+	// Absent keeps the current types on update, empty lifts the restriction
+	for body, wantNil := range map[string]bool{
+		`{"name": "Books", "icon": "📚"}`:                         true,
+		`{"name": "Books", "icon": "📚", "allowed_type_ids": []}`: false,
+	} {
+		var req createCollectionRequest
+		r := httptest.NewRequest("PUT", "/api/v1/collections/1", strings.NewReader(body))
+		if appErr := decodeAndValidate(r, &req); appErr != nil {
+			t.Fatalf("%s: unexpected error %v", body, appErr)
+		}
+		ids, err := req.allowedTypeIDs()
+		if err != nil || (ids == nil) != wantNil || len(ids) != 0 {
+			t.Errorf("%s: allowedTypeIDs = %v, %v", body, ids, err)
+		}
+	}
+}
+
+func TestDecodeAndValidate_SyncPush(t *testing.T) {
+	body := `{"mutations": [
+		{"op": "upsert", "entity": "entry", "id": "00000000-0000-0000-0000-000000000001"},
+		{"op": "move", "entity": "collection", "id": "00000000-0000-0000-0000-000000000002"},
+		{"op": "delete", "entity": "entry", "id": "00000000-0000-0000-0000-000000000003"}
+	]}`
+	r := httptest.NewRequest("POST", "/api/v1/sync/push", strings.NewReader(body))
+
+	var req syncPushRequest
+	appErr := decodeAndValidate(r, &req)
+	if appErr == nil {
+		t.Fatal("expected validation error")
+	}
+
+	want := map[string]interface{}{
+		"mutations[0].entry": []string{"is required for this operation"},
+		"mutations[1].op":    []string{"must be one of: upsert, delete"},
+	}
+	if !reflect.DeepEqual(appErr.Details, want) {
+		t.Errorf("unexpected details:\n got: %v\nwant: %v", appErr.Details, want)
+	}
+}
+
+func TestDecodeAndValidate_EntryOrder(t *testing.T) {
+	tests := []struct {
+		name    string
+		body    string
+		wantErr bool
+	}{
+		{"empty order clears the ranking", `{"entry_ids": []}`, false},
+		{"ranked entries", `{"entry_ids": ["00000000-0000-0000-0000-000000000001"]}`, false},
+		{"invalid id", `{"entry_ids": ["nope"]}`, true},
+		{"missing entry_ids", `{}`, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest("PUT", "/api/v1/collections/x/entries/order", strings.NewReader(tt.body))
+			var req entryOrderRequest
+			appErr := decodeAndValidate(r, &req)
+			if (appErr != nil) != tt.wantErr {
+				t.Fatalf("wantErr %v, got %v", tt.wantErr, appErr)
+			}
+		})
+	}
+}
+
+func TestDecodeAndValidate_EntryPriority(t *testing.T) {
+	tests := []struct {
+		name    string
+		body    string
+		wantErr bool
+	}{
+		{"omitted", `{"title": "Dune", "description": "Spice", "date": "2025-01-15"}`, false},
+		{"highest", `{"title": "Dune", "description": "Spice", "date": "2025-01-15", "priority": 5}`, false},
+		{"too high", `{"title": "Dune", "description": "Spice", "date": "2025-01-15", "priority": 6}`, true},
+		{"negative", `{"title": "Dune", "description": "Spice", "date": "2025-01-15", "priority": -1}`, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest("POST", "/api/v1/entries", strings.NewReader(tt.body))
+			var req createEntryRequest
+			appErr := decodeAndValidate(r, &req)
+			if (appErr != nil) != tt.wantErr {
+				t.Fatalf("wantErr %v, got %v", tt.wantErr, appErr)
+			}
+		})
+	}
+}
+
+func TestDecodeAndValidate_EntryVisibility(t *testing.T) {
+	tests := []struct {
+		name    string
+		body    string
+		wantErr bool
+	}{
+		{"omitted", `{"title": "Dune", "description": "Spice", "date": "2025-01-15"}`, false},
+		{"shared", `{"title": "Dune", "description": "Spice", "date": "2025-01-15", "visibility": "shared"}`, false},
+		{"unknown", `{"title": "Dune", "description": "Spice", "date": "2025-01-15", "visibility": "friends"}`, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest("POST", "/api/v1/entries", strings.NewReader(tt.body))
+			var req createEntryRequest
+			appErr := decodeAndValidate(r, &req)
+			if (appErr != nil) != tt.wantErr {
+				t.Fatalf("wantErr %v, got %v", tt.wantErr, appErr)
+			}
+		})
+	}
+}
+
+func TestDecodeAndValidate_CollectionIcon(t *testing.T) {
+	r := httptest.NewRequest("POST", "/api/v1/collections", strings.NewReader(`{"name": "Films", "icon_image_id": "nope"}`))
+
+	var req createCollectionRequest
+	appErr := decodeAndValidate(r, &req)
+	if appErr == nil {
+		t.Fatal("expected validation error")
+	}
+	want := map[string]interface{}{"icon_image_id": []string{"must be a valid UUID"}}
+	if !reflect.DeepEqual(appErr.Details, want) {
+		t.Errorf("unexpected details:\n got: %v\nwant: %v", appErr.Details, want)
+	}
+
+	r = httptest.NewRequest("POST", "/api/v1/collections", strings.NewReader(`{"name": "Films"}`))
+	req = createCollectionRequest{}
+	appErr = decodeAndValidate(r, &req)
+	if appErr == nil {
+		t.Fatal("expected an error without an icon")
+	}
+	want = map[string]interface{}{"icon": []string{"is required"}}
+	if !reflect.DeepEqual(appErr.Details, want) {
+		t.Errorf("unexpected details:\n got: %v\nwant: %v", appErr.Details, want)
+	}
+
+	r = httptest.NewRequest("POST", "/api/v1/collections", strings.NewReader(`{"name": "Films", "icon_image_id": "00000000-0000-0000-0000-000000000001"}`))
+	req = createCollectionRequest{}
+	if appErr := decodeAndValidate(r, &req); appErr != nil {
+		t.Fatalf("expected no error, got %v", appErr)
+	}
+	icon, err := req.icon()
+	if err != nil || icon.Emoji != "" || icon.ImageID == nil {
+		t.Errorf("unexpected icon: %+v, %v", icon, err)
+	}
+}
+
+func TestDecodeAndValidate_BulkScore(t *testing.T) {
+	const id = "550e8400-e29b-41d4-a716-446655440101"
+	tests := []struct {
+		name    string
+		body    string
+		wantErr bool
+	}{
+		{"valid", `{"scores": [{"id": "` + id + `", "score": 0}]}`, false},
+		{"empty", `{"scores": []}`, true},
+		{"score omitted", `{"scores": [{"id": "` + id + `"}]}`, true},
+		{"repeated id", `{"scores": [{"id": "` + id + `", "score": 1}, {"id": "` + id + `", "score": 2}]}`, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest("POST", "/api/v1/entries/bulk-score", strings.NewReader(tt.body))
+			var req bulkScoreRequest
+			appErr := decodeAndValidate(r, &req)
+			if (appErr != nil) != tt.wantErr {
+				t.Fatalf("wantErr %v, got %v", tt.wantErr, appErr)
+			}
+		})
+	}
+}
+
+func TestDecodeAndValidate_UserSettings(t *testing.T) {
+	tests := []struct {
+		name    string
+		body    string
+		wantErr bool
+	}{
+		{"on", `{"auto_assign_collection": true}`, false},
+		{"off", `{"auto_assign_collection": false}`, false},
+		{"missing", `{}`, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest("PUT", "/api/v1/auth/me/settings", strings.NewReader(tt.body))
+			var req userSettings
+			appErr := decodeAndValidate(r, &req)
+			if (appErr != nil) != tt.wantErr {
+				t.Fatalf("wantErr %v, got %v", tt.wantErr, appErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,27 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/avalarin/livlog/backend/internal/apitime"
+	"github.com/avalarin/livlog/backend/internal/middleware"
+)
+
+// VersionedResponse is implemented by response DTOs whose shape changes
+// between API versions. ForVersion maps the current DTO to what the given
+// version's clients expect, e.g. a bare array for v1 and a pagination
+// envelope for v2. Versions without changes return the DTO itself.
+type VersionedResponse interface {
+	ForVersion(version int) interface{}
+}
+
+// respondWithVersionedJSON writes payload mapped for the request's API version.
+func respondWithVersionedJSON(w http.ResponseWriter, r *http.Request, code int, payload VersionedResponse) {
+	respondWithJSON(w, code, payload.ForVersion(middleware.APIVersionFromContext(r.Context())))
+}
+
+// timeLayout returns how timestamps are written for the request's API
+// version. Mappers of responses with timestamps take it as a parameter.
+func timeLayout(r *http.Request) apitime.Layout {
+	return apitime.ForVersion(middleware.APIVersionFromContext(r.Context()))
+}
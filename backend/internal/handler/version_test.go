@@ -0,0 +1,75 @@
+package handler
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/avalarin/livlog/backend/internal/apiv1"
+	"github.com/avalarin/livlog/backend/internal/middleware"
+	"github.com/avalarin/livlog/backend/internal/repository"
+)
+
+type pageDTO struct {
+	Items []string
+	Total int
+}
+
+func (p pageDTO) ForVersion(version int) interface{} {
+	if version < 2 {
+		return p.Items
+	}
+	return map[string]interface{}{"items": p.Items, "total": p.Total}
+}
+
+func TestRespondWithVersionedJSON(t *testing.T) {
+	tests := []struct {
+		version int
+		want    string
+	}{
+		{1, `["a"]`},
+		{2, `{"items":["a"],"total":1}`},
+	}
+
+	for _, tt := range tests {
+		handler := middleware.Versioned(middleware.APIVersion{Number: tt.version})(
+			http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				respondWithVersionedJSON(w, r, http.StatusOK, pageDTO{Items: []string{"a"}, Total: 1})
+			}),
+		)
+
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+
+		if got := strings.TrimSpace(rec.Body.String()); got != tt.want {
+			t.Errorf("v%d: expected %s, got %s", tt.version, tt.want, got)
+		}
+	}
+}
+
+func TestTimeLayout(t *testing.T) {
+	createdAt := time.Date(2025, 1, 20, 13, 0, 0, 500000000, time.FixedZone("CET", 60*60))
+	tests := []struct {
+		version int
+		want    string
+	}{
+		{1, "2025-01-20T13:00:00+01:00"},
+		{2, "2025-01-20T12:00:00.500000Z"},
+	}
+
+	for _, tt := range tests {
+		var got string
+		handler := middleware.Versioned(middleware.APIVersion{Number: tt.version})(
+			http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				got = apiv1.MapType(&repository.EntryType{CreatedAt: createdAt}, timeLayout(r)).CreatedAt
+			}),
+		)
+		handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+
+		if got != tt.want {
+			t.Errorf("v%d: created_at = %s, want %s", tt.version, got, tt.want)
+		}
+	}
+}
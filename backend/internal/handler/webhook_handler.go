@@ -0,0 +1,152 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/avalarin/livlog/backend/internal/apitime"
+	"github.com/avalarin/livlog/backend/internal/apperror"
+	"github.com/avalarin/livlog/backend/internal/middleware"
+	"github.com/avalarin/livlog/backend/internal/repository"
+	"github.com/avalarin/livlog/backend/internal/service"
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+)
+
+type WebhookHandler struct {
+	webhookService *service.WebhookService
+}
+
+func NewWebhookHandler(webhookService *service.WebhookService) *WebhookHandler {
+	return &WebhookHandler{
+		webhookService: webhookService,
+	}
+}
+
+func (h *WebhookHandler) RegisterRoutes(r chi.Router) {
+	r.Get("/webhooks", h.GetWebhooks)
+	r.Post("/webhooks", h.CreateWebhook)
+	r.Delete("/webhooks/{id}", h.DeleteWebhook)
+}
+
+type createWebhookRequest struct {
+	URL    string   `json:"url" validate:"required,url,max=2000"`
+	Events []string `json:"events,omitempty" validate:"omitempty,unique,dive,required"`
+}
+
+type webhookResponse struct {
+	ID        string   `json:"id"`
+	URL       string   `json:"url"`
+	Events    []string `json:"events"`
+	Secret    string   `json:"secret,omitempty"` // only returned on creation
+	CreatedAt string   `json:"created_at"`
+}
+
+func (h *WebhookHandler) GetWebhooks(w http.ResponseWriter, r *http.Request) {
+	userID := middleware.GetUserIDFromContext(r.Context())
+	if userID == "" {
+		respondWithError(w, r, apperror.Unauthorized("User not authenticated", nil))
+		return
+	}
+
+	uid, err := uuid.Parse(userID)
+	if err != nil {
+		respondWithError(w, r, apperror.BadRequest("Invalid user ID", err))
+		return
+	}
+
+	webhooks, err := h.webhookService.GetWebhooksByUserID(r.Context(), uid)
+	if err != nil {
+		respondWithError(w, r, apperror.Internal("Failed to get webhooks", err))
+		return
+	}
+
+	response := make([]webhookResponse, len(webhooks))
+	for i, wh := range webhooks {
+		response[i] = mapWebhookToResponse(wh, timeLayout(r))
+	}
+
+	respondWithJSON(w, http.StatusOK, response)
+}
+
+// CreateWebhook registers a webhook. The signing secret is only returned here.
+func (h *WebhookHandler) CreateWebhook(w http.ResponseWriter, r *http.Request) {
+	userID := middleware.GetUserIDFromContext(r.Context())
+	if userID == "" {
+		respondWithError(w, r, apperror.Unauthorized("User not authenticated", nil))
+		return
+	}
+
+	uid, err := uuid.Parse(userID)
+	if err != nil {
+		respondWithError(w, r, apperror.BadRequest("Invalid user ID", err))
+		return
+	}
+
+	var req createWebhookRequest
+	if appErr := decodeAndValidate(r, &req); appErr != nil {
+		respondWithError(w, r, appErr)
+		return
+	}
+
+	webhook, err := h.webhookService.CreateWebhook(r.Context(), uid, req.URL, req.Events)
+	if err != nil {
+		if errors.Is(err, service.ErrInvalidWebhookURL) ||
+			errors.Is(err, service.ErrWebhookAddressBlocked) ||
+			errors.Is(err, service.ErrInvalidWebhookEvent) {
+			respondWithError(w, r, apperror.Validation(err.Error(), err))
+			return
+		}
+		if errors.Is(err, service.ErrWebhookLimitReached) {
+			respondWithError(w, r, apperror.Wrap(err, apperror.CodeWebhookLimitReached, "Webhook limit reached"))
+			return
+		}
+		respondWithError(w, r, apperror.Internal("Failed to create webhook", err))
+		return
+	}
+
+	response := mapWebhookToResponse(webhook, timeLayout(r))
+	response.Secret = webhook.Secret
+	respondWithJSON(w, http.StatusCreated, response)
+}
+
+func (h *WebhookHandler) DeleteWebhook(w http.ResponseWriter, r *http.Request) {
+	userID := middleware.GetUserIDFromContext(r.Context())
+	if userID == "" {
+		respondWithError(w, r, apperror.Unauthorized("User not authenticated", nil))
+		return
+	}
+
+	uid, err := uuid.Parse(userID)
+	if err != nil {
+		respondWithError(w, r, apperror.BadRequest("Invalid user ID", err))
+		return
+	}
+
+	webhookID := chi.URLParam(r, "id")
+	wid, err := uuid.Parse(webhookID)
+	if err != nil {
+		respondWithError(w, r, apperror.BadRequest("Invalid webhook ID", err))
+		return
+	}
+
+	if err := h.webhookService.DeleteWebhook(r.Context(), wid, uid); err != nil {
+		if errors.Is(err, repository.ErrWebhookNotFound) {
+			respondWithError(w, r, apperror.Wrap(err, apperror.CodeWebhookNotFound, "Webhook not found"))
+			return
+		}
+		respondWithError(w, r, apperror.Internal("Failed to delete webhook", err))
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, map[string]string{"message": "Webhook deleted successfully"})
+}
+
+func mapWebhookToResponse(wh *repository.Webhook, layout apitime.Layout) webhookResponse {
+	return webhookResponse{
+		ID:        wh.ID.String(),
+		URL:       wh.URL,
+		Events:    wh.Events,
+		CreatedAt: layout.Format(wh.CreatedAt),
+	}
+}
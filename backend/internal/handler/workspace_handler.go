@@ -0,0 +1,648 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/avalarin/livlog/backend/internal/apitime"
+	"github.com/avalarin/livlog/backend/internal/apiv1"
+	"github.com/avalarin/livlog/backend/internal/apperror"
+	"github.com/avalarin/livlog/backend/internal/middleware"
+	"github.com/avalarin/livlog/backend/internal/repository"
+	"github.com/avalarin/livlog/backend/internal/service"
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+)
+
+type WorkspaceHandler struct {
+	workspaceService *service.WorkspaceService
+}
+
+func NewWorkspaceHandler(workspaceService *service.WorkspaceService) *WorkspaceHandler {
+	return &WorkspaceHandler{
+		workspaceService: workspaceService,
+	}
+}
+
+func (h *WorkspaceHandler) RegisterRoutes(r chi.Router) {
+	r.Get("/workspaces", h.ListWorkspaces)
+	r.Post("/workspaces", h.CreateWorkspace)
+	r.Post("/workspaces/invitations/accept", h.AcceptInvitation)
+	r.Get("/workspaces/{id}", h.GetWorkspace)
+	r.Delete("/workspaces/{id}", h.DeleteWorkspace)
+	r.Get("/workspaces/{id}/members", h.ListMembers)
+	r.Delete("/workspaces/{id}/members/{user_id}", h.RemoveMember)
+	r.Get("/workspaces/{id}/invitations", h.ListInvitations)
+	r.Post("/workspaces/{id}/invitations", h.CreateInvitation)
+	r.Delete("/workspaces/{id}/invitations/{invitation_id}", h.RevokeInvitation)
+	r.Get("/workspaces/{id}/collections", h.ListCollections)
+	r.Get("/workspaces/{id}/types", h.ListTypes)
+	r.Get("/workspaces/{id}/entries", h.ListEntries)
+	r.Put("/collections/{id}/workspace", h.MoveCollection)
+	r.Put("/types/{id}/workspace", h.MoveType)
+}
+
+type createWorkspaceRequest struct {
+	Name string `json:"name" validate:"required,max=50"`
+}
+
+type acceptInvitationRequest struct {
+	Token string `json:"token" validate:"required,max=100"`
+}
+
+// moveToWorkspaceRequest moves a collection or type; a null workspace_id
+// moves it back to the personal workspace.
+type moveToWorkspaceRequest struct {
+	WorkspaceID *string `json:"workspace_id" validate:"omitempty,uuid"`
+}
+
+type workspaceResponse struct {
+	ID          string `json:"id"`
+	Name        string `json:"name"`
+	Personal    bool   `json:"personal"`
+	OwnerID     string `json:"owner_id"`
+	Role        string `json:"role"`
+	MemberCount int    `json:"member_count"`
+	CreatedAt   string `json:"created_at"`
+	UpdatedAt   string `json:"updated_at"`
+}
+
+type workspaceMemberResponse struct {
+	UserID      string  `json:"user_id"`
+	DisplayName *string `json:"display_name"`
+	Role        string  `json:"role"`
+	JoinedAt    string  `json:"joined_at"`
+}
+
+type invitationResponse struct {
+	ID        string `json:"id"`
+	InvitedBy string `json:"invited_by"`
+	Token     string `json:"token,omitempty"` // only when created
+	ExpiresAt string `json:"expires_at"`
+	CreatedAt string `json:"created_at"`
+}
+
+// workspaceEntryResponse is an entry in a shared workspace, which may have
+// been filed by any member.
+type workspaceEntryResponse struct {
+	apiv1.Entry
+	UserID string `json:"user_id"`
+}
+
+func (h *WorkspaceHandler) ListWorkspaces(w http.ResponseWriter, r *http.Request) {
+	userID := middleware.GetUserIDFromContext(r.Context())
+	if userID == "" {
+		respondWithError(w, r, apperror.Unauthorized("User not authenticated", nil))
+		return
+	}
+
+	uid, err := uuid.Parse(userID)
+	if err != nil {
+		respondWithError(w, r, apperror.BadRequest("Invalid user ID", err))
+		return
+	}
+
+	workspaces, err := h.workspaceService.ListWorkspaces(r.Context(), uid)
+	if err != nil {
+		respondWithError(w, r, apperror.Internal("Failed to get workspaces", err))
+		return
+	}
+
+	response := make([]workspaceResponse, len(workspaces))
+	for i, ws := range workspaces {
+		response[i] = mapWorkspaceToResponse(ws, timeLayout(r))
+	}
+
+	respondWithJSON(w, http.StatusOK, response)
+}
+
+func (h *WorkspaceHandler) CreateWorkspace(w http.ResponseWriter, r *http.Request) {
+	userID := middleware.GetUserIDFromContext(r.Context())
+	if userID == "" {
+		respondWithError(w, r, apperror.Unauthorized("User not authenticated", nil))
+		return
+	}
+
+	uid, err := uuid.Parse(userID)
+	if err != nil {
+		respondWithError(w, r, apperror.BadRequest("Invalid user ID", err))
+		return
+	}
+
+	var req createWorkspaceRequest
+	if appErr := decodeAndValidate(r, &req); appErr != nil {
+		respondWithError(w, r, appErr)
+		return
+	}
+
+	workspace, err := h.workspaceService.CreateWorkspace(r.Context(), uid, req.Name)
+	if err != nil {
+		respondWithError(w, r, workspaceError(err, "Failed to create workspace"))
+		return
+	}
+
+	respondWithJSON(w, http.StatusCreated, mapWorkspaceToResponse(workspace, timeLayout(r)))
+}
+
+func (h *WorkspaceHandler) GetWorkspace(w http.ResponseWriter, r *http.Request) {
+	userID := middleware.GetUserIDFromContext(r.Context())
+	if userID == "" {
+		respondWithError(w, r, apperror.Unauthorized("User not authenticated", nil))
+		return
+	}
+
+	uid, err := uuid.Parse(userID)
+	if err != nil {
+		respondWithError(w, r, apperror.BadRequest("Invalid user ID", err))
+		return
+	}
+
+	id, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		respondWithError(w, r, apperror.BadRequest("Invalid workspace ID", err))
+		return
+	}
+
+	workspace, err := h.workspaceService.GetWorkspace(r.Context(), uid, id)
+	if err != nil {
+		respondWithError(w, r, workspaceError(err, "Failed to get workspace"))
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, mapWorkspaceToResponse(workspace, timeLayout(r)))
+}
+
+func (h *WorkspaceHandler) DeleteWorkspace(w http.ResponseWriter, r *http.Request) {
+	userID := middleware.GetUserIDFromContext(r.Context())
+	if userID == "" {
+		respondWithError(w, r, apperror.Unauthorized("User not authenticated", nil))
+		return
+	}
+
+	uid, err := uuid.Parse(userID)
+	if err != nil {
+		respondWithError(w, r, apperror.BadRequest("Invalid user ID", err))
+		return
+	}
+
+	id, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		respondWithError(w, r, apperror.BadRequest("Invalid workspace ID", err))
+		return
+	}
+
+	if err := h.workspaceService.DeleteWorkspace(r.Context(), uid, id); err != nil {
+		respondWithError(w, r, workspaceError(err, "Failed to delete workspace"))
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, map[string]string{"message": "Workspace deleted successfully"})
+}
+
+func (h *WorkspaceHandler) ListMembers(w http.ResponseWriter, r *http.Request) {
+	userID := middleware.GetUserIDFromContext(r.Context())
+	if userID == "" {
+		respondWithError(w, r, apperror.Unauthorized("User not authenticated", nil))
+		return
+	}
+
+	uid, err := uuid.Parse(userID)
+	if err != nil {
+		respondWithError(w, r, apperror.BadRequest("Invalid user ID", err))
+		return
+	}
+
+	id, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		respondWithError(w, r, apperror.BadRequest("Invalid workspace ID", err))
+		return
+	}
+
+	members, err := h.workspaceService.ListMembers(r.Context(), uid, id)
+	if err != nil {
+		respondWithError(w, r, workspaceError(err, "Failed to get workspace members"))
+		return
+	}
+
+	layout := timeLayout(r)
+	response := make([]workspaceMemberResponse, len(members))
+	for i, m := range members {
+		response[i] = workspaceMemberResponse{
+			UserID:      m.UserID.String(),
+			DisplayName: m.DisplayName,
+			Role:        m.Role,
+			JoinedAt:    layout.Format(m.JoinedAt),
+		}
+	}
+
+	respondWithJSON(w, http.StatusOK, response)
+}
+
+// RemoveMember removes a member; members pass their own user id to leave.
+func (h *WorkspaceHandler) RemoveMember(w http.ResponseWriter, r *http.Request) {
+	userID := middleware.GetUserIDFromContext(r.Context())
+	if userID == "" {
+		respondWithError(w, r, apperror.Unauthorized("User not authenticated", nil))
+		return
+	}
+
+	uid, err := uuid.Parse(userID)
+	if err != nil {
+		respondWithError(w, r, apperror.BadRequest("Invalid user ID", err))
+		return
+	}
+
+	id, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		respondWithError(w, r, apperror.BadRequest("Invalid workspace ID", err))
+		return
+	}
+
+	memberID, err := uuid.Parse(chi.URLParam(r, "user_id"))
+	if err != nil {
+		respondWithError(w, r, apperror.BadRequest("Invalid user ID", err))
+		return
+	}
+
+	if err := h.workspaceService.RemoveMember(r.Context(), uid, id, memberID); err != nil {
+		respondWithError(w, r, workspaceError(err, "Failed to remove workspace member"))
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, map[string]string{"message": "Member removed"})
+}
+
+func (h *WorkspaceHandler) ListInvitations(w http.ResponseWriter, r *http.Request) {
+	userID := middleware.GetUserIDFromContext(r.Context())
+	if userID == "" {
+		respondWithError(w, r, apperror.Unauthorized("User not authenticated", nil))
+		return
+	}
+
+	uid, err := uuid.Parse(userID)
+	if err != nil {
+		respondWithError(w, r, apperror.BadRequest("Invalid user ID", err))
+		return
+	}
+
+	id, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		respondWithError(w, r, apperror.BadRequest("Invalid workspace ID", err))
+		return
+	}
+
+	invitations, err := h.workspaceService.ListInvitations(r.Context(), uid, id)
+	if err != nil {
+		respondWithError(w, r, workspaceError(err, "Failed to get invitations"))
+		return
+	}
+
+	response := make([]invitationResponse, len(invitations))
+	for i, inv := range invitations {
+		response[i] = mapInvitationToResponse(inv, "", timeLayout(r))
+	}
+
+	respondWithJSON(w, http.StatusOK, response)
+}
+
+func (h *WorkspaceHandler) CreateInvitation(w http.ResponseWriter, r *http.Request) {
+	userID := middleware.GetUserIDFromContext(r.Context())
+	if userID == "" {
+		respondWithError(w, r, apperror.Unauthorized("User not authenticated", nil))
+		return
+	}
+
+	uid, err := uuid.Parse(userID)
+	if err != nil {
+		respondWithError(w, r, apperror.BadRequest("Invalid user ID", err))
+		return
+	}
+
+	id, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		respondWithError(w, r, apperror.BadRequest("Invalid workspace ID", err))
+		return
+	}
+
+	invitation, token, err := h.workspaceService.CreateInvitation(r.Context(), uid, id)
+	if err != nil {
+		respondWithError(w, r, workspaceError(err, "Failed to create invitation"))
+		return
+	}
+
+	respondWithJSON(w, http.StatusCreated, mapInvitationToResponse(invitation, token, timeLayout(r)))
+}
+
+func (h *WorkspaceHandler) RevokeInvitation(w http.ResponseWriter, r *http.Request) {
+	userID := middleware.GetUserIDFromContext(r.Context())
+	if userID == "" {
+		respondWithError(w, r, apperror.Unauthorized("User not authenticated", nil))
+		return
+	}
+
+	uid, err := uuid.Parse(userID)
+	if err != nil {
+		respondWithError(w, r, apperror.BadRequest("Invalid user ID", err))
+		return
+	}
+
+	id, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		respondWithError(w, r, apperror.BadRequest("Invalid workspace ID", err))
+		return
+	}
+
+	invitationID, err := uuid.Parse(chi.URLParam(r, "invitation_id"))
+	if err != nil {
+		respondWithError(w, r, apperror.BadRequest("Invalid invitation ID", err))
+		return
+	}
+
+	if err := h.workspaceService.RevokeInvitation(r.Context(), uid, id, invitationID); err != nil {
+		respondWithError(w, r, workspaceError(err, "Failed to revoke invitation"))
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, map[string]string{"message": "Invitation revoked"})
+}
+
+func (h *WorkspaceHandler) AcceptInvitation(w http.ResponseWriter, r *http.Request) {
+	userID := middleware.GetUserIDFromContext(r.Context())
+	if userID == "" {
+		respondWithError(w, r, apperror.Unauthorized("User not authenticated", nil))
+		return
+	}
+
+	uid, err := uuid.Parse(userID)
+	if err != nil {
+		respondWithError(w, r, apperror.BadRequest("Invalid user ID", err))
+		return
+	}
+
+	var req acceptInvitationRequest
+	if appErr := decodeAndValidate(r, &req); appErr != nil {
+		respondWithError(w, r, appErr)
+		return
+	}
+
+	workspace, err := h.workspaceService.AcceptInvitation(r.Context(), uid, req.Token)
+	if err != nil {
+		respondWithError(w, r, workspaceError(err, "Failed to accept invitation"))
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, mapWorkspaceToResponse(workspace, timeLayout(r)))
+}
+
+func (h *WorkspaceHandler) ListCollections(w http.ResponseWriter, r *http.Request) {
+	userID := middleware.GetUserIDFromContext(r.Context())
+	if userID == "" {
+		respondWithError(w, r, apperror.Unauthorized("User not authenticated", nil))
+		return
+	}
+
+	uid, err := uuid.Parse(userID)
+	if err != nil {
+		respondWithError(w, r, apperror.BadRequest("Invalid user ID", err))
+		return
+	}
+
+	id, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		respondWithError(w, r, apperror.BadRequest("Invalid workspace ID", err))
+		return
+	}
+
+	collections, err := h.workspaceService.ListCollections(r.Context(), uid, id)
+	if err != nil {
+		respondWithError(w, r, workspaceError(err, "Failed to get collections"))
+		return
+	}
+
+	response := make([]apiv1.Collection, len(collections))
+	for i, c := range collections {
+		response[i] = apiv1.MapCollection(c, timeLayout(r))
+	}
+
+	respondWithJSON(w, http.StatusOK, response)
+}
+
+func (h *WorkspaceHandler) ListTypes(w http.ResponseWriter, r *http.Request) {
+	userID := middleware.GetUserIDFromContext(r.Context())
+	if userID == "" {
+		respondWithError(w, r, apperror.Unauthorized("User not authenticated", nil))
+		return
+	}
+
+	uid, err := uuid.Parse(userID)
+	if err != nil {
+		respondWithError(w, r, apperror.BadRequest("Invalid user ID", err))
+		return
+	}
+
+	id, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		respondWithError(w, r, apperror.BadRequest("Invalid workspace ID", err))
+		return
+	}
+
+	types, err := h.workspaceService.ListTypes(r.Context(), uid, id)
+	if err != nil {
+		respondWithError(w, r, workspaceError(err, "Failed to get types"))
+		return
+	}
+
+	response := make([]apiv1.Type, len(types))
+	for i, t := range types {
+		response[i] = apiv1.MapType(t, timeLayout(r))
+	}
+
+	respondWithJSON(w, http.StatusOK, response)
+}
+
+func (h *WorkspaceHandler) ListEntries(w http.ResponseWriter, r *http.Request) {
+	userID := middleware.GetUserIDFromContext(r.Context())
+	if userID == "" {
+		respondWithError(w, r, apperror.Unauthorized("User not authenticated", nil))
+		return
+	}
+
+	uid, err := uuid.Parse(userID)
+	if err != nil {
+		respondWithError(w, r, apperror.BadRequest("Invalid user ID", err))
+		return
+	}
+
+	id, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		respondWithError(w, r, apperror.BadRequest("Invalid workspace ID", err))
+		return
+	}
+
+	filter, appErr := parseEntryFilter(r)
+	if appErr != nil {
+		respondWithError(w, r, appErr)
+		return
+	}
+
+	limit, offset, appErr := offsetParams(r)
+	if appErr != nil {
+		respondWithError(w, r, appErr)
+		return
+	}
+
+	entries, err := h.workspaceService.ListEntries(r.Context(), uid, id, filter, limit, offset)
+	if err != nil {
+		if errors.Is(err, repository.ErrWorkspaceNotFound) {
+			respondWithError(w, r, workspaceError(err, "Failed to get entries"))
+			return
+		}
+		respondWithError(w, r, listEntriesError(err))
+		return
+	}
+
+	response := make([]workspaceEntryResponse, len(entries))
+	for i, e := range entries {
+		response[i] = workspaceEntryResponse{
+			Entry:  apiv1.MapEntry(e.Entry, e.Images, timeLayout(r)),
+			UserID: e.UserID.String(),
+		}
+	}
+
+	respondWithJSON(w, http.StatusOK, response)
+}
+
+func (h *WorkspaceHandler) MoveCollection(w http.ResponseWriter, r *http.Request) {
+	userID := middleware.GetUserIDFromContext(r.Context())
+	if userID == "" {
+		respondWithError(w, r, apperror.Unauthorized("User not authenticated", nil))
+		return
+	}
+
+	uid, err := uuid.Parse(userID)
+	if err != nil {
+		respondWithError(w, r, apperror.BadRequest("Invalid user ID", err))
+		return
+	}
+
+	collectionID, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		respondWithError(w, r, apperror.BadRequest("Invalid collection ID", err))
+		return
+	}
+
+	var req moveToWorkspaceRequest
+	if appErr := decodeAndValidate(r, &req); appErr != nil {
+		respondWithError(w, r, appErr)
+		return
+	}
+
+	workspaceID, err := parseOptionalUUID(req.WorkspaceID)
+	if err != nil {
+		respondWithError(w, r, apperror.BadRequest("Invalid workspace ID", err))
+		return
+	}
+
+	collection, err := h.workspaceService.MoveCollection(r.Context(), uid, collectionID, workspaceID)
+	if err != nil {
+		if errors.Is(err, repository.ErrCollectionNotFound) {
+			respondWithError(w, r, apperror.Wrap(err, apperror.CodeCollectionNotFound, "Collection not found"))
+			return
+		}
+		respondWithError(w, r, workspaceError(err, "Failed to move collection"))
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, apiv1.MapCollection(collection, timeLayout(r)))
+}
+
+func (h *WorkspaceHandler) MoveType(w http.ResponseWriter, r *http.Request) {
+	userID := middleware.GetUserIDFromContext(r.Context())
+	if userID == "" {
+		respondWithError(w, r, apperror.Unauthorized("User not authenticated", nil))
+		return
+	}
+
+	uid, err := uuid.Parse(userID)
+	if err != nil {
+		respondWithError(w, r, apperror.BadRequest("Invalid user ID", err))
+		return
+	}
+
+	typeID, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		respondWithError(w, r, apperror.BadRequest("Invalid type ID", err))
+		return
+	}
+
+	var req moveToWorkspaceRequest
+	if appErr := decodeAndValidate(r, &req); appErr != nil {
+		respondWithError(w, r, appErr)
+		return
+	}
+
+	workspaceID, err := parseOptionalUUID(req.WorkspaceID)
+	if err != nil {
+		respondWithError(w, r, apperror.BadRequest("Invalid workspace ID", err))
+		return
+	}
+
+	entryType, err := h.workspaceService.MoveType(r.Context(), uid, typeID, workspaceID)
+	if err != nil {
+		if errors.Is(err, repository.ErrTypeNotFound) {
+			respondWithError(w, r, apperror.Wrap(err, apperror.CodeTypeNotFound, "Type not found"))
+			return
+		}
+		respondWithError(w, r, workspaceError(err, "Failed to move type"))
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, apiv1.MapType(entryType, timeLayout(r)))
+}
+
+// workspaceError maps workspace service errors to API errors, falling back
+// to an internal error with the given message.
+func workspaceError(err error, message string) *apperror.Error {
+	switch {
+	case errors.Is(err, repository.ErrWorkspaceNotFound):
+		return apperror.Wrap(err, apperror.CodeWorkspaceNotFound, "Workspace not found")
+	case errors.Is(err, repository.ErrMemberNotFound):
+		return apperror.Wrap(err, apperror.CodeWorkspaceMemberNotFound, "Workspace member not found")
+	case errors.Is(err, repository.ErrInvitationNotFound):
+		return apperror.Wrap(err, apperror.CodeInvitationNotFound, "Invitation not found")
+	case errors.Is(err, repository.ErrAlreadyMember):
+		return apperror.Wrap(err, apperror.CodeAlreadyWorkspaceMember, "Already a member of the workspace")
+	case errors.Is(err, repository.ErrWorkspaceFull):
+		return apperror.Wrap(err, apperror.CodeWorkspaceFull, "Workspace member limit reached")
+	case errors.Is(err, service.ErrNotWorkspaceOwner):
+		return apperror.Wrap(err, apperror.CodeForbidden, err.Error())
+	case errors.Is(err, service.ErrPersonalWorkspace), errors.Is(err, service.ErrOwnerCannotLeave):
+		return apperror.Wrap(err, apperror.CodeConflict, err.Error())
+	case errors.Is(err, service.ErrInvalidWorkspaceName):
+		return apperror.Validation(err.Error(), err)
+	}
+	return apperror.Internal(message, err)
+}
+
+func mapWorkspaceToResponse(ws *repository.Workspace, layout apitime.Layout) workspaceResponse {
+	return workspaceResponse{
+		ID:          ws.ID.String(),
+		Name:        ws.Name,
+		Personal:    ws.Personal,
+		OwnerID:     ws.OwnerID.String(),
+		Role:        ws.Role,
+		MemberCount: ws.MemberCount,
+		CreatedAt:   layout.Format(ws.CreatedAt),
+		UpdatedAt:   layout.Format(ws.UpdatedAt),
+	}
+}
+
+func mapInvitationToResponse(inv *repository.WorkspaceInvitation, token string, layout apitime.Layout) invitationResponse {
+	return invitationResponse{
+		ID:        inv.ID.String(),
+		InvitedBy: inv.InvitedBy.String(),
+		Token:     token,
+		ExpiresAt: layout.Format(inv.ExpiresAt),
+		CreatedAt: layout.Format(inv.CreatedAt),
+	}
+}
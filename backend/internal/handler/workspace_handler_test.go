@@ -0,0 +1,65 @@
+package handler
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/avalarin/livlog/backend/internal/apitime"
+	"github.com/avalarin/livlog/backend/internal/apperror"
+	"github.com/avalarin/livlog/backend/internal/repository"
+	"github.com/avalarin/livlog/backend/internal/service"
+	"github.com/google/uuid"
+)
+
+func TestWorkspaceError(t *testing.T) {
+	tests := []struct {
+		err  error
+		want apperror.Code
+	}{
+		{repository.ErrWorkspaceNotFound, apperror.CodeWorkspaceNotFound},
+		{repository.ErrMemberNotFound, apperror.CodeWorkspaceMemberNotFound},
+		{repository.ErrInvitationNotFound, apperror.CodeInvitationNotFound},
+		{repository.ErrAlreadyMember, apperror.CodeAlreadyWorkspaceMember},
+		{repository.ErrWorkspaceFull, apperror.CodeWorkspaceFull},
+		{service.ErrNotWorkspaceOwner, apperror.CodeForbidden},
+		{service.ErrPersonalWorkspace, apperror.CodeConflict},
+		{service.ErrOwnerCannotLeave, apperror.CodeConflict},
+		{service.ErrInvalidWorkspaceName, apperror.CodeValidation},
+		{fmt.Errorf("query: %w", repository.ErrWorkspaceNotFound), apperror.CodeWorkspaceNotFound},
+		{fmt.Errorf("connection refused"), apperror.CodeInternal},
+	}
+
+	for _, tt := range tests {
+		if got := workspaceError(tt.err, "Failed").Code; got != tt.want {
+			t.Errorf("%v: expected %s, got %s", tt.err, tt.want, got)
+		}
+	}
+}
+
+func TestMapInvitationToResponse_TokenOnlyOnCreate(t *testing.T) {
+	inv := &repository.WorkspaceInvitation{
+		ID:        uuid.New(),
+		InvitedBy: uuid.New(),
+		ExpiresAt: time.Date(2025, 3, 8, 12, 0, 0, 0, time.UTC),
+		CreatedAt: time.Date(2025, 3, 1, 12, 0, 0, 0, time.UTC),
+	}
+
+	created, err := json.Marshal(mapInvitationToResponse(inv, "wsinv_abc", apitime.Legacy))
+	if err != nil {
+		t.Fatalf("marshal: %v", err)
+	}
+	if !strings.Contains(string(created), `"token":"wsinv_abc"`) {
+		t.Errorf("expected token in %s", created)
+	}
+
+	listed, err := json.Marshal(mapInvitationToResponse(inv, "", apitime.Legacy))
+	if err != nil {
+		t.Fatalf("marshal: %v", err)
+	}
+	if strings.Contains(string(listed), "token") {
+		t.Errorf("expected no token in %s", listed)
+	}
+}
@@ -0,0 +1,74 @@
+// Package i18n picks a response language from Accept-Language and looks up
+// translated strings. English is the fallback for anything not translated.
+package i18n
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Supported response languages.
+const (
+	English = "en"
+	Russian = "ru"
+)
+
+// Default is used when the client accepts none of the supported languages.
+const Default = English
+
+var supported = map[string]bool{English: true, Russian: true}
+
+// Negotiate returns the supported language the client prefers most in an
+// Accept-Language header like "ru-RU,ru;q=0.9,en;q=0.8". Regional variants
+// match their base language.
+func Negotiate(acceptLanguage string) string {
+	type candidate struct {
+		lang string
+		q    float64
+	}
+	var candidates []candidate
+
+	for _, part := range strings.Split(acceptLanguage, ",") {
+		tag, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		if tag == "" {
+			continue
+		}
+
+		q := 1.0
+		if v, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok {
+			parsed, err := strconv.ParseFloat(v, 64)
+			if err != nil {
+				continue
+			}
+			q = parsed
+		}
+		if q <= 0 {
+			continue
+		}
+
+		base, _, _ := strings.Cut(strings.ToLower(tag), "-")
+		if supported[base] {
+			candidates = append(candidates, candidate{base, q})
+		}
+	}
+
+	if len(candidates) == 0 {
+		return Default
+	}
+	// Stable keeps the client's order among equal weights
+	sort.SliceStable(candidates, func(i, j int) bool { return candidates[i].q > candidates[j].q })
+	return candidates[0].lang
+}
+
+// Catalog maps a language to its translations by key.
+type Catalog map[string]map[string]string
+
+// Lookup returns the translation of key in lang, falling back to English.
+// It returns "" if the key is not translated at all.
+func (c Catalog) Lookup(lang, key string) string {
+	if s, ok := c[lang][key]; ok {
+		return s
+	}
+	return c[Default][key]
+}
@@ -0,0 +1,42 @@
+package i18n
+
+import "testing"
+
+func TestNegotiate(t *testing.T) {
+	tests := []struct {
+		header string
+		want   string
+	}{
+		{"", English},
+		{"ru", Russian},
+		{"ru-RU,ru;q=0.9,en;q=0.8", Russian},
+		{"en-US,ru;q=0.5", English},
+		{"de-DE,ru;q=0.7,en;q=0.3", Russian},
+		{"fr", English},
+		{"ru;q=0,en", English},
+		{"*", English},
+	}
+
+	for _, tt := range tests {
+		if got := Negotiate(tt.header); got != tt.want {
+			t.Errorf("Negotiate(%q) = %q, want %q", tt.header, got, tt.want)
+		}
+	}
+}
+
+func TestCatalog_FallsBackToEnglish(t *testing.T) {
+	c := Catalog{
+		English: {"greeting": "Hello", "bye": "Bye"},
+		Russian: {"greeting": "Привет"},
+	}
+
+	if got := c.Lookup(Russian, "greeting"); got != "Привет" {
+		t.Errorf("got %q", got)
+	}
+	if got := c.Lookup(Russian, "bye"); got != "Bye" {
+		t.Errorf("expected English fallback, got %q", got)
+	}
+	if got := c.Lookup(Russian, "missing"); got != "" {
+		t.Errorf("expected empty string, got %q", got)
+	}
+}
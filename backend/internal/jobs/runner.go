@@ -0,0 +1,235 @@
+// Package jobs runs the server's periodic background work (cleanups, webhook
+// delivery, ...) with per-run timeouts, retries, metrics and graceful shutdown.
+package jobs
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"go.uber.org/zap"
+)
+
+var (
+	jobRunsTotal = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "job_runs_total",
+			Help: "Total number of background job runs by outcome",
+		},
+		[]string{"job", "status"},
+	)
+
+	jobDuration = promauto.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Name:    "job_duration_seconds",
+			Help:    "Background job run duration in seconds, including retries",
+			Buckets: prometheus.DefBuckets,
+		},
+		[]string{"job"},
+	)
+
+	jobAttemptFailures = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "job_attempt_failures_total",
+			Help: "Total number of failed background job attempts, including those a retry recovered from",
+		},
+		[]string{"job"},
+	)
+
+	jobDeletedRows = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "job_deleted_rows_total",
+			Help: "Total number of rows deleted by cleanup jobs",
+		},
+		[]string{"job"},
+	)
+
+	jobLastSuccess = promauto.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "job_last_success_timestamp_seconds",
+			Help: "Unix time of the last successful run of a background job",
+		},
+		[]string{"job"},
+	)
+)
+
+// Job is a unit of periodic background work.
+type Job struct {
+	Name     string
+	Interval time.Duration // time between runs; the first run is one interval after Start
+	Timeout  time.Duration // per attempt; defaults to Interval
+
+	// Retries is how many times a failed run is retried before waiting for the
+	// next interval. Retries back off from RetryDelay, doubling each time.
+	Retries    int
+	RetryDelay time.Duration
+
+	Run func(ctx context.Context) error
+	// Cleanup is set instead of Run by jobs that delete old rows and return
+	// how many; the count is logged and added to job_deleted_rows_total.
+	Cleanup func(ctx context.Context) (int64, error)
+}
+
+// Runner schedules registered jobs. Each job runs in its own goroutine and
+// never overlaps with itself.
+type Runner struct {
+	logger    *zap.Logger
+	jobs      []Job
+	onFailure func(job string, err error)
+
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+func NewRunner(logger *zap.Logger) *Runner {
+	return &Runner{logger: logger}
+}
+
+// OnFailure sets a function called when a run fails after all retries, e.g. to
+// report it to error tracking. It must be called before Start.
+func (r *Runner) OnFailure(fn func(job string, err error)) {
+	r.onFailure = fn
+}
+
+// Register adds a job. It must be called before Start.
+func (r *Runner) Register(job Job) {
+	if job.Timeout == 0 {
+		job.Timeout = job.Interval
+	}
+	if job.RetryDelay == 0 {
+		job.RetryDelay = time.Second
+	}
+	if job.Run == nil && job.Cleanup != nil {
+		job.Run = r.cleanup(job.Name, job.Cleanup)
+	}
+	r.jobs = append(r.jobs, job)
+}
+
+// Start begins running all registered jobs until Stop is called or ctx is
+// cancelled.
+func (r *Runner) Start(ctx context.Context) {
+	ctx, r.cancel = context.WithCancel(ctx)
+
+	for _, job := range r.jobs {
+		r.wg.Add(1)
+		go func(job Job) {
+			defer r.wg.Done()
+			r.loop(ctx, job)
+		}(job)
+	}
+
+	r.logger.Info("background jobs started", zap.Int("jobs", len(r.jobs)))
+}
+
+// Stop cancels running jobs and waits for them to return, or for ctx to end.
+func (r *Runner) Stop(ctx context.Context) error {
+	if r.cancel == nil {
+		return nil
+	}
+	r.cancel()
+
+	done := make(chan struct{})
+	go func() {
+		r.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return fmt.Errorf("background jobs did not stop: %w", ctx.Err())
+	}
+}
+
+func (r *Runner) loop(ctx context.Context, job Job) {
+	ticker := time.NewTicker(job.Interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			r.runOnce(ctx, job)
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// runOnce runs the job, retrying failures, and records the outcome.
+func (r *Runner) runOnce(ctx context.Context, job Job) {
+	start := time.Now()
+	delay := job.RetryDelay
+
+	var err error
+	for attempt := 0; ; attempt++ {
+		err = r.attempt(ctx, job)
+		if err == nil || ctx.Err() != nil {
+			break
+		}
+		jobAttemptFailures.WithLabelValues(job.Name).Inc()
+		if attempt >= job.Retries {
+			break
+		}
+
+		r.logger.Warn("background job failed, retrying",
+			zap.String("job", job.Name),
+			zap.Int("attempt", attempt+1),
+			zap.Duration("retry_in", delay),
+			zap.Error(err),
+		)
+
+		select {
+		case <-time.After(delay):
+			delay *= 2
+		case <-ctx.Done():
+		}
+	}
+
+	jobDuration.WithLabelValues(job.Name).Observe(time.Since(start).Seconds())
+
+	switch {
+	case err == nil:
+		jobRunsTotal.WithLabelValues(job.Name, "success").Inc()
+		jobLastSuccess.WithLabelValues(job.Name).SetToCurrentTime()
+	case ctx.Err() != nil:
+		// Interrupted by shutdown; not a job failure
+		jobRunsTotal.WithLabelValues(job.Name, "cancelled").Inc()
+	default:
+		jobRunsTotal.WithLabelValues(job.Name, "failure").Inc()
+		r.logger.Error("background job failed", zap.String("job", job.Name), zap.Error(err))
+		if r.onFailure != nil {
+			r.onFailure(job.Name, err)
+		}
+	}
+}
+
+// attempt runs the job once with its timeout, turning a panic into an error so
+// one broken job cannot take the server down.
+func (r *Runner) attempt(ctx context.Context, job Job) (err error) {
+	ctx, cancel := context.WithTimeout(ctx, job.Timeout)
+	defer cancel()
+
+	defer func() {
+		if p := recover(); p != nil {
+			err = fmt.Errorf("panic: %v", p)
+		}
+	}()
+
+	return job.Run(ctx)
+}
+
+// cleanup adapts a Cleanup function to Run, recording what it deleted.
+func (r *Runner) cleanup(name string, cleanup func(ctx context.Context) (int64, error)) func(ctx context.Context) error {
+	return func(ctx context.Context) error {
+		deleted, err := cleanup(ctx)
+		if deleted > 0 {
+			jobDeletedRows.WithLabelValues(name).Add(float64(deleted))
+			r.logger.Info("background job deleted rows", zap.String("job", name), zap.Int64("deleted", deleted))
+		}
+		return err
+	}
+}
@@ -0,0 +1,106 @@
+package jobs
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"go.uber.org/zap"
+)
+
+func TestRunner_RetriesFailedRun(t *testing.T) {
+	calls := 0
+	r := NewRunner(zap.NewNop())
+	r.Register(Job{
+		Name:       "flaky",
+		Interval:   time.Minute,
+		Retries:    2,
+		RetryDelay: time.Millisecond,
+		Run: func(ctx context.Context) error {
+			calls++
+			if calls < 3 {
+				return errors.New("temporary")
+			}
+			return nil
+		},
+	})
+
+	r.runOnce(context.Background(), r.jobs[0])
+
+	if calls != 3 {
+		t.Errorf("expected 3 attempts, got %d", calls)
+	}
+}
+
+func TestRunner_RecoversPanic(t *testing.T) {
+	r := NewRunner(zap.NewNop())
+	job := Job{
+		Name:    "broken",
+		Timeout: time.Second,
+		Run: func(ctx context.Context) error {
+			panic("boom")
+		},
+	}
+
+	if err := r.attempt(context.Background(), job); err == nil {
+		t.Fatal("expected panic to be returned as an error")
+	}
+}
+
+func TestRunner_StopWaitsForJobs(t *testing.T) {
+	started := make(chan struct{})
+	r := NewRunner(zap.NewNop())
+	r.Register(Job{
+		Name:     "slow",
+		Interval: time.Millisecond,
+		Run: func(ctx context.Context) error {
+			select {
+			case started <- struct{}{}:
+			default:
+			}
+			<-ctx.Done()
+			return ctx.Err()
+		},
+	})
+
+	r.Start(context.Background())
+	<-started
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	if err := r.Stop(ctx); err != nil {
+		t.Fatalf("expected jobs to stop, got %v", err)
+	}
+}
+
+func TestRunner_CountsDeletedRowsAndFailedAttempts(t *testing.T) {
+	r := NewRunner(zap.NewNop())
+	calls := 0
+	r.Register(Job{
+		Name:       "test_cleanup",
+		Interval:   time.Minute,
+		Retries:    1,
+		RetryDelay: time.Millisecond,
+		Cleanup: func(ctx context.Context) (int64, error) {
+			calls++
+			if calls == 1 {
+				return 0, errors.New("temporary")
+			}
+			return 7, nil
+		},
+	})
+
+	r.runOnce(context.Background(), r.jobs[0])
+
+	if got := testutil.ToFloat64(jobDeletedRows.WithLabelValues("test_cleanup")); got != 7 {
+		t.Errorf("expected 7 deleted rows, got %v", got)
+	}
+	if got := testutil.ToFloat64(jobAttemptFailures.WithLabelValues("test_cleanup")); got != 1 {
+		t.Errorf("expected 1 failed attempt, got %v", got)
+	}
+	if got := testutil.ToFloat64(jobRunsTotal.WithLabelValues("test_cleanup", "success")); got != 1 {
+		t.Errorf("expected the run to succeed after the retry, got %v successes", got)
+	}
+}
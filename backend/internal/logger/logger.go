@@ -1,11 +1,15 @@
 package logger
 
 import (
+	"fmt"
+
 	"go.uber.org/zap"
 	"go.uber.org/zap/zapcore"
 )
 
-func New(format string) (*zap.Logger, error) {
+// New builds the application logger. The returned AtomicLevel can be used to
+// change the level at runtime, see SetLevel.
+func New(format, level string) (*zap.Logger, zap.AtomicLevel, error) {
 	var config zap.Config
 
 	if format == "json" {
@@ -17,5 +21,30 @@ func New(format string) (*zap.Logger, error) {
 		config.EncoderConfig.EncodeLevel = zapcore.CapitalColorLevelEncoder
 	}
 
-	return config.Build()
+	if err := SetLevel(config.Level, format, level); err != nil {
+		return nil, config.Level, err
+	}
+
+	log, err := config.Build()
+	return log, config.Level, err
+}
+
+// SetLevel sets the logger level. An empty level restores the format's
+// default: debug for console, info for json.
+func SetLevel(atom zap.AtomicLevel, format, level string) error {
+	if level == "" {
+		if format == "json" {
+			level = "info"
+		} else {
+			level = "debug"
+		}
+	}
+
+	l, err := zapcore.ParseLevel(level)
+	if err != nil {
+		return fmt.Errorf("invalid log level: %w", err)
+	}
+
+	atom.SetLevel(l)
+	return nil
 }
@@ -0,0 +1,60 @@
+package middleware
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+type apiVersionKey struct{}
+
+// APIVersion describes an API version mounted at /api/v<Number>. Versions are
+// served side by side so old app builds keep working while new ones move on.
+type APIVersion struct {
+	Number int
+
+	// Deprecated and Sunset are zero while the version is current. Set them
+	// when a successor ships; clients see them as Deprecation (RFC 9745) and
+	// Sunset (RFC 8594) headers on every response.
+	Deprecated time.Time
+	Sunset     time.Time
+	Successor  int // version to migrate to, advertised in a Link header
+}
+
+// Prefix returns the path the version is mounted at.
+func (v APIVersion) Prefix() string {
+	return "/api/v" + strconv.Itoa(v.Number)
+}
+
+// Versioned tags requests with the API version, see APIVersionFromContext, and
+// adds deprecation headers once the version is deprecated.
+func Versioned(v APIVersion) func(next http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if !v.Deprecated.IsZero() {
+				w.Header().Set("Deprecation", fmt.Sprintf("@%d", v.Deprecated.Unix()))
+			}
+			if !v.Sunset.IsZero() {
+				w.Header().Set("Sunset", v.Sunset.UTC().Format(http.TimeFormat))
+			}
+			if v.Successor != 0 {
+				successor := APIVersion{Number: v.Successor}
+				w.Header().Add("Link", fmt.Sprintf(`<%s>; rel="successor-version"`, successor.Prefix()))
+			}
+
+			ctx := context.WithValue(r.Context(), apiVersionKey{}, v.Number)
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
+
+// APIVersionFromContext returns the API version of the request, or 1 outside
+// a versioned route.
+func APIVersionFromContext(ctx context.Context) int {
+	if v, ok := ctx.Value(apiVersionKey{}).(int); ok {
+		return v
+	}
+	return 1
+}
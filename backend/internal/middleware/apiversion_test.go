@@ -0,0 +1,56 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestVersioned_CurrentVersion(t *testing.T) {
+	var got int
+	handler := Versioned(APIVersion{Number: 2})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = APIVersionFromContext(r.Context())
+	}))
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/v2/entries", nil))
+
+	if got != 2 {
+		t.Errorf("expected version 2 in context, got %d", got)
+	}
+	for _, h := range []string{"Deprecation", "Sunset", "Link"} {
+		if v := rec.Header().Get(h); v != "" {
+			t.Errorf("expected no %s header on a current version, got %q", h, v)
+		}
+	}
+}
+
+func TestVersioned_DeprecatedVersion(t *testing.T) {
+	v := APIVersion{
+		Number:     1,
+		Deprecated: time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC),
+		Sunset:     time.Date(2026, 7, 1, 0, 0, 0, 0, time.UTC),
+		Successor:  2,
+	}
+	handler := Versioned(v)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/v1/entries", nil))
+
+	if got := rec.Header().Get("Deprecation"); got != "@1767225600" {
+		t.Errorf("Deprecation = %q", got)
+	}
+	if got := rec.Header().Get("Sunset"); got != "Wed, 01 Jul 2026 00:00:00 GMT" {
+		t.Errorf("Sunset = %q", got)
+	}
+	if got := rec.Header().Get("Link"); got != `</api/v2>; rel="successor-version"` {
+		t.Errorf("Link = %q", got)
+	}
+}
+
+func TestAPIVersionFromContext_DefaultsToV1(t *testing.T) {
+	if got := APIVersionFromContext(httptest.NewRequest(http.MethodGet, "/", nil).Context()); got != 1 {
+		t.Errorf("expected version 1, got %d", got)
+	}
+}
@@ -2,36 +2,46 @@ package middleware
 
 import (
 	"context"
-	"encoding/json"
+	"errors"
 	"net/http"
 	"strings"
 
+	"github.com/avalarin/livlog/backend/internal/apperror"
+	"github.com/avalarin/livlog/backend/internal/repository"
 	"github.com/avalarin/livlog/backend/internal/service"
+	"github.com/google/uuid"
 )
 
-func AuthMiddleware(jwtService *service.JWTService) func(http.Handler) http.Handler {
+// TokenValidator turns a bearer token into the claims of its user.
+// *service.JWTService is the production implementation; tests and other
+// token schemes can plug in their own.
+type TokenValidator interface {
+	ValidateAccessToken(token string) (*service.AccessTokenClaims, error)
+}
+
+func AuthMiddleware(validator TokenValidator) func(http.Handler) http.Handler {
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 			// Extract Authorization header
 			authHeader := r.Header.Get("Authorization")
 			if authHeader == "" {
-				respondUnauthorized(w, "Authorization header required")
+				respondUnauthorized(w, r, "Authorization header required")
 				return
 			}
 
 			// Extract Bearer token
 			parts := strings.SplitN(authHeader, " ", 2)
 			if len(parts) != 2 || parts[0] != "Bearer" {
-				respondUnauthorized(w, "Invalid authorization header format")
+				respondUnauthorized(w, r, "Invalid authorization header format")
 				return
 			}
 
 			token := parts[1]
 
 			// Validate token
-			claims, err := jwtService.ValidateAccessToken(token)
+			claims, err := validator.ValidateAccessToken(token)
 			if err != nil {
-				respondUnauthorized(w, "Invalid or expired token")
+				respondUnauthorized(w, r, "Invalid or expired token")
 				return
 			}
 
@@ -44,6 +54,45 @@ func AuthMiddleware(jwtService *service.JWTService) func(http.Handler) http.Hand
 	}
 }
 
+// UserLookup loads the authenticated user for role checks.
+type UserLookup interface {
+	GetUserByID(ctx context.Context, id uuid.UUID) (*repository.User, error)
+}
+
+// RequireRole lets through only users with the given role. The role is read
+// from the database on each request, so revoking it takes effect immediately.
+// It must run after AuthMiddleware.
+func RequireRole(users UserLookup, role repository.UserRole) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			id, err := uuid.Parse(GetUserIDFromContext(r.Context()))
+			if err != nil {
+				respondUnauthorized(w, r, "User not authenticated")
+				return
+			}
+
+			user, err := users.GetUserByID(r.Context(), id)
+			if errors.Is(err, repository.ErrUserNotFound) {
+				respondUnauthorized(w, r, "User not found")
+				return
+			}
+			if err != nil {
+				apperror.Write(w, r, apperror.Internal("Failed to check permissions", err))
+				return
+			}
+
+			if user.Role != role {
+				apperror.Write(w, r, apperror.New(apperror.CodeForbidden, "Insufficient permissions"))
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// GetUserIDFromContext returns the user ID stored by AuthMiddleware, or an
+// empty string on unauthenticated requests.
 func GetUserIDFromContext(ctx context.Context) string {
 	userID, ok := ctx.Value("userID").(string)
 	if !ok {
@@ -52,19 +101,6 @@ func GetUserIDFromContext(ctx context.Context) string {
 	return userID
 }
 
-type errorResponse struct {
-	Error   string `json:"error"`
-	Message string `json:"message"`
-}
-
-func respondUnauthorized(w http.ResponseWriter, message string) {
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(http.StatusUnauthorized)
-
-	resp := errorResponse{
-		Error:   "Unauthorized",
-		Message: message,
-	}
-
-	json.NewEncoder(w).Encode(resp)
+func respondUnauthorized(w http.ResponseWriter, r *http.Request, message string) {
+	apperror.Write(w, r, apperror.Unauthorized(message, nil))
 }
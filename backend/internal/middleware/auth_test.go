@@ -0,0 +1,98 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/avalarin/livlog/backend/internal/repository"
+	"github.com/avalarin/livlog/backend/internal/service"
+	"github.com/google/uuid"
+)
+
+type fakeUsers map[uuid.UUID]repository.UserRole
+
+func (f fakeUsers) GetUserByID(ctx context.Context, id uuid.UUID) (*repository.User, error) {
+	role, ok := f[id]
+	if !ok {
+		return nil, repository.ErrUserNotFound
+	}
+	return &repository.User{ID: id, Role: role}, nil
+}
+
+func TestRequireRole(t *testing.T) {
+	admin, user := uuid.New(), uuid.New()
+	users := fakeUsers{admin: repository.UserRoleAdmin, user: repository.UserRoleUser}
+
+	handler := RequireRole(users, repository.UserRoleAdmin)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+
+	tests := []struct {
+		name   string
+		userID string
+		want   int
+	}{
+		{"admin", admin.String(), http.StatusOK},
+		{"regular user", user.String(), http.StatusForbidden},
+		{"unknown user", uuid.NewString(), http.StatusUnauthorized},
+		{"unauthenticated", "", http.StatusUnauthorized},
+	}
+
+	for _, tt := range tests {
+		req := httptest.NewRequest(http.MethodGet, "/admin/stats", nil)
+		req = req.WithContext(context.WithValue(req.Context(), "userID", tt.userID))
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+
+		if rec.Code != tt.want {
+			t.Errorf("%s: expected status %d, got %d", tt.name, tt.want, rec.Code)
+		}
+	}
+}
+
+type fakeValidator map[string]string
+
+func (f fakeValidator) ValidateAccessToken(token string) (*service.AccessTokenClaims, error) {
+	userID, ok := f[token]
+	if !ok {
+		return nil, errors.New("invalid token")
+	}
+	return &service.AccessTokenClaims{UserID: userID}, nil
+}
+
+func TestAuthMiddleware(t *testing.T) {
+	userID := uuid.NewString()
+	var got string
+	handler := AuthMiddleware(fakeValidator{"good": userID})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = GetUserIDFromContext(r.Context())
+	}))
+
+	tests := []struct {
+		name   string
+		header string
+		want   int
+	}{
+		{"valid token", "Bearer good", http.StatusOK},
+		{"unknown token", "Bearer bad", http.StatusUnauthorized},
+		{"wrong scheme", "Basic good", http.StatusUnauthorized},
+		{"missing header", "", http.StatusUnauthorized},
+	}
+
+	for _, tt := range tests {
+		got = ""
+		req := httptest.NewRequest(http.MethodGet, "/entries", nil)
+		if tt.header != "" {
+			req.Header.Set("Authorization", tt.header)
+		}
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+
+		if rec.Code != tt.want {
+			t.Errorf("%s: expected status %d, got %d", tt.name, tt.want, rec.Code)
+		}
+		if tt.want == http.StatusOK && got != userID {
+			t.Errorf("%s: expected user %q in context, got %q", tt.name, userID, got)
+		}
+	}
+}
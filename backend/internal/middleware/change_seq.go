@@ -0,0 +1,59 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+)
+
+// ChangeSeqHeader carries the change sequence of a successful write. Clients
+// echo the latest one as min_seq on their next sync pull.
+const ChangeSeqHeader = "X-Change-Seq"
+
+// ChangeSeq sets ChangeSeqHeader on successful responses to requests that may
+// write (anything but GET, HEAD and OPTIONS). seq is read when the handler
+// writes the status, after its changes are committed; a failure to read it
+// leaves the header out.
+func ChangeSeq(seq func(ctx context.Context) (int64, error)) func(next http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			switch r.Method {
+			case http.MethodGet, http.MethodHead, http.MethodOptions:
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			next.ServeHTTP(&changeSeqWriter{ResponseWriter: w, ctx: r.Context(), seq: seq}, r)
+		})
+	}
+}
+
+type changeSeqWriter struct {
+	http.ResponseWriter
+	ctx         context.Context
+	seq         func(ctx context.Context) (int64, error)
+	wroteHeader bool
+}
+
+func (cw *changeSeqWriter) WriteHeader(code int) {
+	if !cw.wroteHeader {
+		cw.wroteHeader = true
+		if code >= 200 && code < 300 {
+			if seq, err := cw.seq(cw.ctx); err == nil {
+				cw.Header().Set(ChangeSeqHeader, strconv.FormatInt(seq, 10))
+			}
+		}
+	}
+	cw.ResponseWriter.WriteHeader(code)
+}
+
+func (cw *changeSeqWriter) Write(b []byte) (int, error) {
+	if !cw.wroteHeader {
+		cw.WriteHeader(http.StatusOK)
+	}
+	return cw.ResponseWriter.Write(b)
+}
+
+func (cw *changeSeqWriter) Unwrap() http.ResponseWriter {
+	return cw.ResponseWriter
+}
@@ -0,0 +1,60 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestChangeSeq(t *testing.T) {
+	seq := func(ctx context.Context) (int64, error) { return 42, nil }
+
+	tests := []struct {
+		name   string
+		method string
+		status int
+		want   string
+	}{
+		{"successful write", http.MethodPost, http.StatusCreated, "42"},
+		{"implicit status", http.MethodPut, 0, "42"},
+		{"failed write", http.MethodDelete, http.StatusNotFound, ""},
+		{"read", http.MethodGet, http.StatusOK, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler := ChangeSeq(seq)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if tt.status != 0 {
+					w.WriteHeader(tt.status)
+				}
+				w.Write([]byte("{}"))
+			}))
+
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, httptest.NewRequest(tt.method, "/entries", nil))
+
+			if got := rec.Header().Get(ChangeSeqHeader); got != tt.want {
+				t.Errorf("%s = %q, want %q", ChangeSeqHeader, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestChangeSeq_Error(t *testing.T) {
+	seq := func(ctx context.Context) (int64, error) { return 0, errors.New("database is down") }
+	handler := ChangeSeq(seq)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/sync/push", nil))
+
+	if rec.Code != http.StatusOK {
+		t.Errorf("expected status 200, got %d", rec.Code)
+	}
+	if got := rec.Header().Get(ChangeSeqHeader); got != "" {
+		t.Errorf("expected no %s, got %q", ChangeSeqHeader, got)
+	}
+}
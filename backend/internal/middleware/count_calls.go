@@ -0,0 +1,20 @@
+package middleware
+
+import (
+	"net/http"
+
+	"github.com/google/uuid"
+)
+
+// CountCalls calls record with the user of every authenticated request, for
+// their usage (see service.UsageService). It runs after AuthMiddleware.
+func CountCalls(record func(userID uuid.UUID)) func(next http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if userID, err := uuid.Parse(GetUserIDFromContext(r.Context())); err == nil {
+				record(userID)
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
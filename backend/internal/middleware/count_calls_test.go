@@ -0,0 +1,29 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/google/uuid"
+)
+
+func TestCountCalls(t *testing.T) {
+	var counted []uuid.UUID
+	handler := CountCalls(func(userID uuid.UUID) {
+		counted = append(counted, userID)
+	})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+
+	userID := uuid.New()
+	req := httptest.NewRequest(http.MethodGet, "/entries", nil)
+	req = req.WithContext(context.WithValue(req.Context(), "userID", userID.String()))
+	handler.ServeHTTP(httptest.NewRecorder(), req)
+
+	// Requests without a user aren't anyone's usage
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/health", nil))
+
+	if len(counted) != 1 || counted[0] != userID {
+		t.Errorf("counted = %v, want [%s]", counted, userID)
+	}
+}
@@ -0,0 +1,70 @@
+package middleware
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/avalarin/livlog/backend/internal/apperror"
+)
+
+// MaxBodySize rejects request bodies larger than limit with 413. Bodies that
+// announce their size are rejected up front; others are cut off while being
+// read, which decodeAndValidate reports as the same error.
+func MaxBodySize(limit int64) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.ContentLength > limit {
+				apperror.Write(w, r,
+					apperror.New(apperror.CodePayloadTooLarge, "Request body is too large").
+						WithDetails(map[string]interface{}{"limit_bytes": limit}))
+				return
+			}
+
+			r.Body = http.MaxBytesReader(w, r.Body, limit)
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// Timeout bounds the handler run time with http.TimeoutHandler and answers
+// requests that run out of time with a JSON 504. The write deadline is moved
+// past the timeout, so routes may run longer than the server's WriteTimeout.
+// Not for streaming routes: the timeout writer does not support Flush.
+func Timeout(timeout time.Duration) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			_ = http.NewResponseController(w).SetWriteDeadline(time.Now().Add(timeout + time.Second))
+
+			ctx, cancel := context.WithTimeout(r.Context(), timeout)
+			defer cancel()
+
+			body, _ := json.Marshal(apperror.NewResponse(r, apperror.New(apperror.CodeTimeout, "Request timed out")))
+
+			tw := &timeoutStatusWriter{ResponseWriter: w, ctx: ctx}
+			http.TimeoutHandler(next, timeout, string(body)).ServeHTTP(tw, r.WithContext(ctx))
+		})
+	}
+}
+
+// timeoutStatusWriter turns the plain 503 that http.TimeoutHandler sends when
+// the deadline passes into a JSON 504. A 503 written by the handler itself
+// before the deadline is passed through unchanged.
+type timeoutStatusWriter struct {
+	http.ResponseWriter
+	ctx context.Context
+}
+
+func (tw *timeoutStatusWriter) WriteHeader(code int) {
+	if code == http.StatusServiceUnavailable && errors.Is(tw.ctx.Err(), context.DeadlineExceeded) {
+		tw.Header().Set("Content-Type", "application/json")
+		code = http.StatusGatewayTimeout
+	}
+	tw.ResponseWriter.WriteHeader(code)
+}
+
+func (tw *timeoutStatusWriter) Unwrap() http.ResponseWriter {
+	return tw.ResponseWriter
+}
@@ -2,16 +2,25 @@ package middleware
 
 import (
 	"net/http"
+	"strings"
 	"time"
 
 	"github.com/go-chi/chi/v5/middleware"
+	"go.opentelemetry.io/otel/trace"
 	"go.uber.org/zap"
+
+	applog "github.com/avalarin/livlog/backend/internal/logger"
 )
 
+// Logging logs every request once it completes, and puts a logger tagged with
+// the request id into the request context for handlers, see logger.FromContext.
 func Logging(logger *zap.Logger) func(next http.Handler) http.Handler {
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-			if r.URL.Path == "/api/v1/health" {
+			reqLogger := logger.With(zap.String("request_id", middleware.GetReqID(r.Context())))
+			r = r.WithContext(applog.WithContext(r.Context(), reqLogger))
+
+			if isHealthCheck(r.URL.Path) {
 				next.ServeHTTP(w, r)
 				return
 			}
@@ -21,7 +30,7 @@ func Logging(logger *zap.Logger) func(next http.Handler) http.Handler {
 			ww := middleware.NewWrapResponseWriter(w, r.ProtoMajor)
 
 			defer func() {
-				logger.Info("http request",
+				fields := []zap.Field{
 					zap.String("method", r.Method),
 					zap.String("path", r.URL.Path),
 					zap.Int("status", ww.Status()),
@@ -29,10 +38,30 @@ func Logging(logger *zap.Logger) func(next http.Handler) http.Handler {
 					zap.Duration("duration", time.Since(start)),
 					zap.String("remote_addr", r.RemoteAddr),
 					zap.String("user_agent", r.UserAgent()),
-				)
+				}
+				if spanContext := trace.SpanContextFromContext(r.Context()); spanContext.HasTraceID() {
+					fields = append(fields, zap.String("trace_id", spanContext.TraceID().String()))
+				}
+				reqLogger.Info("http request", fields...)
 			}()
 
 			next.ServeHTTP(ww, r)
 		})
 	}
 }
+
+// isHealthCheck reports whether path is polled by probes and load balancers;
+// those requests are not logged or traced.
+func isHealthCheck(path string) bool {
+	switch path {
+	case "/healthz", "/readyz":
+		return true
+	}
+	// /api/v<N>/health for every mounted API version
+	version, ok := strings.CutPrefix(path, "/api/v")
+	if !ok {
+		return false
+	}
+	version, ok = strings.CutSuffix(version, "/health")
+	return ok && version != "" && !strings.Contains(version, "/")
+}
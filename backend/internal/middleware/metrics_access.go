@@ -0,0 +1,68 @@
+package middleware
+
+import (
+	"crypto/subtle"
+	"net"
+	"net/http"
+	"strings"
+
+	"github.com/avalarin/livlog/backend/internal/config"
+)
+
+// MetricsAccess restricts a handler to the clients allowed by cfg: addresses
+// in AllowedIPs and, when a password is set, basic auth credentials. With
+// neither configured every request passes.
+//
+// The address is taken from RemoteAddr, which chi's RealIP middleware fills
+// from X-Forwarded-For on the public listener. Only rely on the allowlist
+// there behind a proxy that overwrites that header, or use metrics.port.
+func MetricsAccess(cfg config.MetricsConfig) func(next http.Handler) http.Handler {
+	var nets []*net.IPNet
+	for _, entry := range cfg.AllowedIPs {
+		if !strings.Contains(entry, "/") {
+			if ip := net.ParseIP(entry); ip != nil && ip.To4() != nil {
+				entry += "/32"
+			} else {
+				entry += "/128"
+			}
+		}
+		if _, n, err := net.ParseCIDR(entry); err == nil {
+			nets = append(nets, n)
+		}
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if len(nets) > 0 && !ipAllowed(clientIP(r), nets) {
+				http.Error(w, http.StatusText(http.StatusForbidden), http.StatusForbidden)
+				return
+			}
+
+			if cfg.Password != "" {
+				user, pass, ok := r.BasicAuth()
+				if !ok ||
+					subtle.ConstantTimeCompare([]byte(user), []byte(cfg.Username)) != 1 ||
+					subtle.ConstantTimeCompare([]byte(pass), []byte(cfg.Password)) != 1 {
+					w.Header().Set("WWW-Authenticate", `Basic realm="metrics"`)
+					http.Error(w, http.StatusText(http.StatusUnauthorized), http.StatusUnauthorized)
+					return
+				}
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+func ipAllowed(addr string, nets []*net.IPNet) bool {
+	ip := net.ParseIP(addr)
+	if ip == nil {
+		return false
+	}
+	for _, n := range nets {
+		if n.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,58 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/avalarin/livlog/backend/internal/config"
+)
+
+func TestMetricsAccess(t *testing.T) {
+	cfg := config.MetricsConfig{
+		Username:   "prometheus",
+		Password:   "secret",
+		AllowedIPs: []string{"10.0.0.0/8", "192.168.1.5"},
+	}
+	handler := MetricsAccess(cfg)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+
+	tests := []struct {
+		name       string
+		remoteAddr string
+		user, pass string
+		want       int
+	}{
+		{"allowed network with credentials", "10.1.2.3:5000", "prometheus", "secret", http.StatusOK},
+		{"allowed single IP with credentials", "192.168.1.5:5000", "prometheus", "secret", http.StatusOK},
+		{"outside allowlist", "203.0.113.7:5000", "prometheus", "secret", http.StatusForbidden},
+		{"wrong password", "10.1.2.3:5000", "prometheus", "nope", http.StatusUnauthorized},
+		{"no credentials", "10.1.2.3:5000", "", "", http.StatusUnauthorized},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/metrics", nil)
+			req.RemoteAddr = tt.remoteAddr
+			if tt.user != "" {
+				req.SetBasicAuth(tt.user, tt.pass)
+			}
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+
+			if rec.Code != tt.want {
+				t.Errorf("expected status %d, got %d", tt.want, rec.Code)
+			}
+		})
+	}
+}
+
+func TestMetricsAccess_OpenByDefault(t *testing.T) {
+	handler := MetricsAccess(config.MetricsConfig{})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+
+	if rec.Code != http.StatusOK {
+		t.Errorf("expected status 200, got %d", rec.Code)
+	}
+}
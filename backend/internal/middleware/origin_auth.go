@@ -0,0 +1,22 @@
+package middleware
+
+import (
+	"crypto/subtle"
+	"net/http"
+)
+
+// OriginAuth restricts a handler to requests carrying secret in header, as
+// sent by a CDN pulling from this server, so clients can't bypass the CDN.
+func OriginAuth(header, secret string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			presented := r.Header.Get(header)
+			if secret == "" || subtle.ConstantTimeCompare([]byte(presented), []byte(secret)) != 1 {
+				respondUnauthorized(w, r, "Invalid origin credentials")
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
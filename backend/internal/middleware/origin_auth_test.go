@@ -0,0 +1,36 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestOriginAuth(t *testing.T) {
+	handler := OriginAuth("X-Origin-Auth", "cdn-secret")(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+
+	tests := []struct {
+		name   string
+		header string
+		want   int
+	}{
+		{"valid secret", "cdn-secret", http.StatusOK},
+		{"wrong secret", "other-secret", http.StatusUnauthorized},
+		{"missing header", "", http.StatusUnauthorized},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/images/5f0c1c9e-8a43-4b8e-9d7a-1f2b3c4d5e6f", nil)
+			if tt.header != "" {
+				req.Header.Set("X-Origin-Auth", tt.header)
+			}
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+
+			if rec.Code != tt.want {
+				t.Errorf("expected status %d, got %d", tt.want, rec.Code)
+			}
+		})
+	}
+}
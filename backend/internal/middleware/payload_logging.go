@@ -0,0 +1,132 @@
+package middleware
+
+import (
+	"bytes"
+	"io"
+	"mime"
+	"net/http"
+	"path"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/go-chi/chi/v5/middleware"
+	"go.uber.org/zap"
+
+	"github.com/avalarin/livlog/backend/internal/config"
+	applog "github.com/avalarin/livlog/backend/internal/logger"
+)
+
+// redactedValue replaces the values of secret fields in logged payloads.
+const redactedValue = "[REDACTED]"
+
+var (
+	// Notification channel targets are webhook URLs that post to the user's
+	// channel on their own, and download URLs carry a token.
+	responseSecretFields = secretFieldPattern("password|secret|token|access_token|refresh_token|identity_token|authorization_code|api_key|target|download_url")
+	// Requests also carry sign-in codes; "code" in responses is the error
+	// code, which is worth keeping.
+	requestSecretFields = secretFieldPattern("password|secret|token|access_token|refresh_token|identity_token|authorization_code|api_key|target|download_url|code")
+)
+
+// secretFieldPattern matches JSON string fields named by names, including a
+// value cut off at the size cap.
+func secretFieldPattern(names string) *regexp.Regexp {
+	return regexp.MustCompile(`(?i)"(` + names + `)"\s*:\s*"(?:[^"\\]|\\.)*(?:"|$)`)
+}
+
+// PayloadLogging logs the bodies of requests matching cfg.Routes together
+// with their responses, once the response is written. Only JSON and text
+// bodies are logged, cut at cfg.MaxBytes, with secret fields redacted. It
+// must run after Logging, whose request logger it uses.
+func PayloadLogging(cfg config.PayloadLoggingConfig) func(next http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		if len(cfg.Routes) == 0 {
+			return next
+		}
+
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if !payloadRouteMatches(cfg.Routes, r.Method, r.URL.Path) {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			reqBody := &cappedBuffer{max: cfg.MaxBytes}
+			if r.Body != nil {
+				r.Body = &teeReadCloser{Reader: io.TeeReader(r.Body, reqBody), Closer: r.Body}
+			}
+			respBody := &cappedBuffer{max: cfg.MaxBytes}
+			ww := middleware.NewWrapResponseWriter(w, r.ProtoMajor)
+			ww.Tee(respBody)
+
+			defer func() {
+				applog.FromContext(r.Context()).Info("http payload",
+					zap.String("method", r.Method),
+					zap.String("path", r.URL.Path),
+					zap.Int("status", ww.Status()),
+					zap.String("request_body", describePayload(r.Header.Get("Content-Type"), reqBody, requestSecretFields)),
+					zap.String("response_body", describePayload(ww.Header().Get("Content-Type"), respBody, responseSecretFields)),
+				)
+			}()
+
+			next.ServeHTTP(ww, r)
+		})
+	}
+}
+
+// payloadRouteMatches reports whether any "[METHOD ]PATH" pattern covers the
+// request.
+func payloadRouteMatches(routes []string, method, urlPath string) bool {
+	for _, route := range routes {
+		pattern := route
+		if m, p, ok := strings.Cut(route, " "); ok {
+			if !strings.EqualFold(m, method) {
+				continue
+			}
+			pattern = strings.TrimSpace(p)
+		}
+		if ok, _ := path.Match(pattern, urlPath); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// describePayload renders a captured body for the log: redacted text for
+// JSON and text bodies, a size note for anything else.
+func describePayload(contentType string, body *cappedBuffer, secrets *regexp.Regexp) string {
+	if body.total == 0 {
+		return ""
+	}
+
+	mediaType, _, _ := mime.ParseMediaType(contentType)
+	if mediaType != "" && mediaType != "application/json" && !strings.HasPrefix(mediaType, "text/") {
+		return "<" + strconv.Itoa(body.total) + " bytes of " + mediaType + ">"
+	}
+
+	text := secrets.ReplaceAllString(body.buf.String(), `"$1":"`+redactedValue+`"`)
+	if body.total > body.buf.Len() {
+		text += "... (" + strconv.Itoa(body.total) + " bytes)"
+	}
+	return text
+}
+
+// cappedBuffer keeps the first max bytes written to it and counts the rest.
+type cappedBuffer struct {
+	buf   bytes.Buffer
+	max   int
+	total int
+}
+
+func (b *cappedBuffer) Write(p []byte) (int, error) {
+	b.total += len(p)
+	if room := b.max - b.buf.Len(); room > 0 {
+		b.buf.Write(p[:min(room, len(p))])
+	}
+	return len(p), nil
+}
+
+type teeReadCloser struct {
+	io.Reader
+	io.Closer
+}
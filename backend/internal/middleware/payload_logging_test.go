@@ -0,0 +1,88 @@
+package middleware
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zaptest/observer"
+
+	"github.com/avalarin/livlog/backend/internal/config"
+	applog "github.com/avalarin/livlog/backend/internal/logger"
+)
+
+func TestPayloadLogging(t *testing.T) {
+	core, logs := observer.New(zap.InfoLevel)
+	cfg := config.PayloadLoggingConfig{Routes: []string{"POST /api/*/auth/email/verify"}, MaxBytes: 1024}
+
+	handler := PayloadLogging(cfg)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		if !strings.Contains(string(body), "123456") {
+			t.Errorf("handler got a modified body: %s", body)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"access_token":"eyJhbGci","user":{"email":"user@example.com"}}`))
+	}))
+
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/auth/email/verify",
+		strings.NewReader(`{"email":"user@example.com","code":"123456"}`))
+	req.Header.Set("Content-Type", "application/json")
+	req = req.WithContext(applog.WithContext(req.Context(), zap.New(core)))
+	handler.ServeHTTP(httptest.NewRecorder(), req)
+
+	entries := logs.FilterMessage("http payload").All()
+	if len(entries) != 1 {
+		t.Fatalf("expected 1 payload log, got %d", len(entries))
+	}
+	fields := entries[0].ContextMap()
+	if got := fields["request_body"]; got != `{"email":"user@example.com","code":"[REDACTED]"}` {
+		t.Errorf("request_body = %v", got)
+	}
+	if got := fields["response_body"]; got != `{"access_token":"[REDACTED]","user":{"email":"user@example.com"}}` {
+		t.Errorf("response_body = %v", got)
+	}
+}
+
+func TestPayloadLogging_UnmatchedRoute(t *testing.T) {
+	core, logs := observer.New(zap.InfoLevel)
+	cfg := config.PayloadLoggingConfig{Routes: []string{"POST /api/*/entries"}, MaxBytes: 1024}
+	handler := PayloadLogging(cfg)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/entries", nil)
+	req = req.WithContext(applog.WithContext(req.Context(), zap.New(core)))
+	handler.ServeHTTP(httptest.NewRecorder(), req)
+
+	if logs.Len() != 0 {
+		t.Errorf("expected no payload log for a GET, got %d", logs.Len())
+	}
+}
+
+func TestDescribePayload(t *testing.T) {
+	tests := []struct {
+		name        string
+		contentType string
+		body        string
+		max         int
+		want        string
+	}{
+		{"empty", "application/json", "", 100, ""},
+		{"truncated secret", "application/json", `{"refresh_token":"abcdefghij"}`, 25, `{"refresh_token":"[REDACTED]"... (30 bytes)`},
+		{"escaped quote", "application/json", `{"password":"a\"b","x":1}`, 100, `{"password":"[REDACTED]","x":1}`},
+		{"channel target", "application/json", `{"kind":"discord","target":"https://discord.com/api/webhooks/1/abc"}`, 100, `{"kind":"discord","target":"[REDACTED]"}`},
+		{"download url", "application/json", `{"status":"done","download_url":"/api/v1/exports/1/download?token=eyJhbGci"}`, 100, `{"status":"done","download_url":"[REDACTED]"}`},
+		{"binary", "image/jpeg", "\xff\xd8\xff", 100, "<3 bytes of image/jpeg>"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			body := &cappedBuffer{max: tt.max}
+			body.Write([]byte(tt.body))
+			if got := describePayload(tt.contentType, body, requestSecretFields); got != tt.want {
+				t.Errorf("describePayload = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,50 @@
+package middleware
+
+import (
+	"net"
+	"net/http"
+
+	"github.com/avalarin/livlog/backend/internal/apperror"
+	"github.com/avalarin/livlog/backend/internal/service"
+)
+
+// RateLimit applies limiter per user, or per client IP on routes without
+// authentication. Responses carry RateLimit-Limit, RateLimit-Remaining and
+// RateLimit-Reset headers; rejected requests get 429 with Retry-After.
+// Decisions are counted under name, see service.RecordRateLimitDecision.
+func RateLimit(name string, limiter service.Limiter) func(next http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			key, keyClass := GetUserIDFromContext(r.Context()), "user"
+			if key == "" {
+				key, keyClass = "ip:"+clientIP(r), "ip"
+			}
+
+			status := limiter.Take(key)
+			if status.Limit == 0 {
+				next.ServeHTTP(w, r)
+				return
+			}
+			service.RecordRateLimitDecision(name, keyClass, status.Allowed)
+
+			limit := apperror.RateLimit{Limit: status.Limit, Remaining: status.Remaining, Reset: status.Reset}
+			if !status.Allowed {
+				apperror.Write(w, r, apperror.RateLimited("Too many requests. Please try again later.", nil, limit))
+				return
+			}
+			limit.SetHeaders(w.Header())
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// clientIP returns the host part of RemoteAddr, which chi's RealIP middleware
+// has already replaced with the forwarded address.
+func clientIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
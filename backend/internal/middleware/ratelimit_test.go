@@ -0,0 +1,85 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/avalarin/livlog/backend/internal/service"
+)
+
+func TestRateLimit_PerUser(t *testing.T) {
+	handler := RateLimit("test", service.NewWindowLimiter(2, time.Minute, service.SystemClock))(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+
+	request := func(userID string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/entries/search", nil)
+		req = req.WithContext(context.WithValue(req.Context(), "userID", userID))
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		return rec
+	}
+
+	for i, wantRemaining := range []string{"1", "0"} {
+		rec := request("alice")
+		if rec.Code != http.StatusOK {
+			t.Fatalf("request %d: expected status 200, got %d", i+1, rec.Code)
+		}
+		if got := rec.Header().Get("RateLimit-Remaining"); got != wantRemaining {
+			t.Errorf("request %d: RateLimit-Remaining = %q, want %q", i+1, got, wantRemaining)
+		}
+	}
+
+	rec := request("alice")
+	if rec.Code != http.StatusTooManyRequests {
+		t.Errorf("expected status 429, got %d", rec.Code)
+	}
+	if rec.Header().Get("Retry-After") == "" {
+		t.Error("expected Retry-After header")
+	}
+
+	// Another user has their own budget
+	if rec := request("bob"); rec.Code != http.StatusOK {
+		t.Errorf("expected another user to be allowed, got %d", rec.Code)
+	}
+}
+
+func TestRateLimit_Disabled(t *testing.T) {
+	handler := RateLimit("test", service.NewWindowLimiter(0, time.Minute, service.SystemClock))(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+
+	if rec.Code != http.StatusOK {
+		t.Errorf("expected status 200, got %d", rec.Code)
+	}
+	if got := rec.Header().Get("RateLimit-Limit"); got != "" {
+		t.Errorf("expected no rate limit headers, got RateLimit-Limit %q", got)
+	}
+}
+
+func TestRateLimit_WindowResets(t *testing.T) {
+	clock := service.NewManualClock(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC))
+	handler := RateLimit("test", service.NewWindowLimiter(1, time.Minute, clock))(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+
+	request := func() int {
+		req := httptest.NewRequest(http.MethodGet, "/entries/search", nil)
+		req = req.WithContext(context.WithValue(req.Context(), "userID", "alice"))
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		return rec.Code
+	}
+
+	if code := request(); code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d", code)
+	}
+	if code := request(); code != http.StatusTooManyRequests {
+		t.Fatalf("expected status 429, got %d", code)
+	}
+
+	clock.Advance(time.Minute)
+	if code := request(); code != http.StatusOK {
+		t.Errorf("expected status 200 after the window, got %d", code)
+	}
+}
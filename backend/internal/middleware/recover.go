@@ -0,0 +1,59 @@
+package middleware
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+	"go.uber.org/zap"
+
+	"github.com/avalarin/livlog/backend/internal/apperror"
+	"github.com/avalarin/livlog/backend/internal/errortracking"
+	"github.com/avalarin/livlog/backend/internal/logger"
+)
+
+// Recoverer turns a handler panic into a logged, reported INTERNAL_ERROR
+// response. It also puts tracker into the request context so handlers can
+// report 5xx errors, see errortracking.FromContext. tracker may be nil.
+func Recoverer(tracker *errortracking.Tracker) func(next http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			r = r.WithContext(errortracking.WithContext(r.Context(), tracker))
+
+			defer func() {
+				p := recover()
+				if p == nil {
+					return
+				}
+				if p == http.ErrAbortHandler {
+					// Deliberate abort of the response, see http.ErrAbortHandler
+					panic(p)
+				}
+
+				reqID := middleware.GetReqID(r.Context())
+				route := r.URL.Path
+				if rctx := chi.RouteContext(r.Context()); rctx != nil && rctx.RoutePattern() != "" {
+					route = rctx.RoutePattern()
+				}
+				err := fmt.Errorf("panic: %v", p)
+
+				logger.FromContext(r.Context()).Error("handler panicked",
+					zap.String("method", r.Method),
+					zap.String("route", route),
+					zap.Error(err),
+					zap.Stack("stack"),
+				)
+				tracker.Capture(errortracking.Event{
+					Err:     err,
+					Tags:    map[string]string{"route": route, "request_id": reqID, "panic": "true"},
+					Request: r,
+				})
+
+				apperror.Write(w, r, apperror.Internal("Internal server error", err))
+			}()
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
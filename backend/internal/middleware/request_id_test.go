@@ -0,0 +1,53 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/google/uuid"
+)
+
+func TestRequestID(t *testing.T) {
+	tests := []struct {
+		name     string
+		incoming string
+		keep     bool
+	}{
+		{"generated when missing", "", false},
+		{"client id kept", "ios-3F2A9C1E-7B4D", true},
+		{"too long replaced", strings.Repeat("a", 129), false},
+		{"unsafe characters replaced", "abc\ninjected", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var inContext string
+			handler := RequestID(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				inContext = middleware.GetReqID(r.Context())
+			}))
+
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			if tt.incoming != "" {
+				req.Header.Set(RequestIDHeader, tt.incoming)
+			}
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+
+			got := rec.Header().Get(RequestIDHeader)
+			if got != inContext {
+				t.Errorf("response header %q differs from context id %q", got, inContext)
+			}
+			if tt.keep && got != tt.incoming {
+				t.Errorf("expected client id %q to be kept, got %q", tt.incoming, got)
+			}
+			if !tt.keep {
+				if _, err := uuid.Parse(got); err != nil {
+					t.Errorf("expected a generated UUID, got %q", got)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,24 @@
+package middleware
+
+import (
+	"crypto/subtle"
+	"net/http"
+	"strings"
+)
+
+// ServiceAuth restricts a handler to internal services presenting token as
+// a bearer credential. It is not a user token, so the handler gets no user
+// in its context.
+func ServiceAuth(token string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			presented, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+			if !ok || token == "" || subtle.ConstantTimeCompare([]byte(presented), []byte(token)) != 1 {
+				respondUnauthorized(w, r, "Invalid service credentials")
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
@@ -0,0 +1,34 @@
+package middleware
+
+import (
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Tracing starts a server span for every request. The span is renamed to the
+// matched chi route pattern once routing is done, so spans group by endpoint
+// rather than by raw path.
+func Tracing(next http.Handler) http.Handler {
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		next.ServeHTTP(w, r)
+
+		routePattern := chi.RouteContext(r.Context()).RoutePattern()
+		if routePattern == "" {
+			return
+		}
+
+		span := trace.SpanFromContext(r.Context())
+		span.SetName(r.Method + " " + routePattern)
+		span.SetAttributes(semconv.HTTPRoute(routePattern))
+	})
+
+	return otelhttp.NewHandler(handler, "http.server",
+		otelhttp.WithFilter(func(r *http.Request) bool {
+			return !isHealthCheck(r.URL.Path) && r.URL.Path != "/metrics"
+		}),
+	)
+}
@@ -0,0 +1,340 @@
+// Package pdf writes simple PDF documents: A4 pages with text in the standard
+// Helvetica fonts and JPEG images. It covers what generated exports need
+// without an external dependency.
+//
+// Text uses the built-in fonts with WinAnsiEncoding, so characters outside
+// Windows-1252 (e.g. Cyrillic) are replaced with "?".
+package pdf
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/color"
+	"image/jpeg"
+	_ "image/png" // decode PNG images for re-encoding
+	"io"
+	"strings"
+)
+
+// A4 page size in points.
+const (
+	PageWidth  = 595.28
+	PageHeight = 841.89
+)
+
+// Font selects one of the standard fonts.
+type Font int
+
+const (
+	Regular Font = iota
+	Bold
+)
+
+var fontNames = []string{"Helvetica", "Helvetica-Bold"}
+
+// Document is a PDF being built. The zero value is not usable; call New.
+type Document struct {
+	pages  []*Page
+	images []*Image
+}
+
+// Page is a page of a Document. Coordinates are in points from the bottom
+// left corner.
+type Page struct {
+	content bytes.Buffer
+	images  []*Image
+}
+
+// Image is a JPEG image added to a Document, which pages can draw any
+// number of times.
+type Image struct {
+	index  int
+	data   []byte
+	Width  int
+	Height int
+	gray   bool
+}
+
+// New returns an empty document.
+func New() *Document {
+	return &Document{}
+}
+
+// AddPage appends an empty A4 page.
+func (d *Document) AddPage() *Page {
+	p := &Page{}
+	d.pages = append(d.pages, p)
+	return p
+}
+
+// AddImage adds a JPEG or PNG image. Images larger than maxSide pixels on
+// their longer side are scaled down; maxSide 0 keeps the original size. JPEGs
+// that need no scaling are embedded as is, everything else is re-encoded as JPEG.
+func (d *Document) AddImage(data []byte, maxSide int) (*Image, error) {
+	cfg, format, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode image: %w", err)
+	}
+
+	img := &Image{index: len(d.images), data: data, Width: cfg.Width, Height: cfg.Height}
+
+	reencode := format != "jpeg" || (maxSide > 0 && max(cfg.Width, cfg.Height) > maxSide)
+	switch cfg.ColorModel {
+	case color.GrayModel:
+		img.gray = true
+	case color.YCbCrModel:
+	default:
+		// CMYK and other JPEGs need re-encoding to be readable with DeviceRGB
+		reencode = true
+	}
+
+	if reencode {
+		decoded, _, err := image.Decode(bytes.NewReader(data))
+		if err != nil {
+			return nil, fmt.Errorf("failed to decode image: %w", err)
+		}
+		if maxSide > 0 {
+			decoded = downscale(decoded, maxSide)
+		}
+		var buf bytes.Buffer
+		if err := jpeg.Encode(&buf, decoded, &jpeg.Options{Quality: 85}); err != nil {
+			return nil, fmt.Errorf("failed to encode image: %w", err)
+		}
+		bounds := decoded.Bounds()
+		img.data = buf.Bytes()
+		img.Width, img.Height = bounds.Dx(), bounds.Dy()
+		_, img.gray = decoded.(*image.Gray)
+	}
+
+	d.images = append(d.images, img)
+	return img, nil
+}
+
+// downscale shrinks src so its longer side is at most maxSide, using
+// nearest-neighbour sampling. Smaller images are returned unchanged.
+func downscale(src image.Image, maxSide int) image.Image {
+	bounds := src.Bounds()
+	w, h := bounds.Dx(), bounds.Dy()
+	if w <= maxSide && h <= maxSide {
+		return src
+	}
+
+	dw, dh := maxSide, h*maxSide/w
+	if h > w {
+		dw, dh = w*maxSide/h, maxSide
+	}
+	dw, dh = max(dw, 1), max(dh, 1)
+
+	dst := image.NewRGBA(image.Rect(0, 0, dw, dh))
+	for y := 0; y < dh; y++ {
+		sy := bounds.Min.Y + y*h/dh
+		for x := 0; x < dw; x++ {
+			dst.Set(x, y, src.At(bounds.Min.X+x*w/dw, sy))
+		}
+	}
+	return dst
+}
+
+// Text draws s with its baseline starting at (x, y).
+func (p *Page) Text(x, y float64, font Font, size float64, s string) {
+	fmt.Fprintf(&p.content, "BT /F%d %.2f Tf %.2f %.2f Td (%s) Tj ET\n", font+1, size, x, y, escape(s))
+}
+
+// Color sets the fill color for following text and rectangles, with
+// components from 0 to 1.
+func (p *Page) Color(r, g, b float64) {
+	fmt.Fprintf(&p.content, "%.3f %.3f %.3f rg\n", r, g, b)
+}
+
+// Rect fills a rectangle with the current color.
+func (p *Page) Rect(x, y, w, h float64) {
+	fmt.Fprintf(&p.content, "%.2f %.2f %.2f %.2f re f\n", x, y, w, h)
+}
+
+// Image draws img scaled to w×h with its bottom left corner at (x, y).
+func (p *Page) Image(img *Image, x, y, w, h float64) {
+	p.images = append(p.images, img)
+	fmt.Fprintf(&p.content, "q %.2f 0 0 %.2f %.2f %.2f cm /Im%d Do Q\n", w, h, x, y, img.index+1)
+}
+
+// WriteTo writes the document.
+func (d *Document) WriteTo(w io.Writer) (int64, error) {
+	var buf bytes.Buffer
+	var offsets []int
+
+	// Object numbers: 1 catalog, 2 page tree, 3-4 fonts, then images, then a
+	// page and its content stream for each page.
+	imageObj := func(i int) int { return 5 + i }
+	pageObj := func(i int) int { return 5 + len(d.images) + 2*i }
+
+	begin := func(num int) {
+		offsets = append(offsets, buf.Len())
+		fmt.Fprintf(&buf, "%d 0 obj\n", num)
+	}
+	end := func() { buf.WriteString("endobj\n") }
+
+	buf.WriteString("%PDF-1.4\n%\xe2\xe3\xcf\xd3\n")
+
+	begin(1)
+	buf.WriteString("<< /Type /Catalog /Pages 2 0 R >>\n")
+	end()
+
+	begin(2)
+	kids := make([]string, len(d.pages))
+	for i := range d.pages {
+		kids[i] = fmt.Sprintf("%d 0 R", pageObj(i))
+	}
+	fmt.Fprintf(&buf, "<< /Type /Pages /Kids [%s] /Count %d >>\n", strings.Join(kids, " "), len(d.pages))
+	end()
+
+	for i, name := range fontNames {
+		begin(3 + i)
+		fmt.Fprintf(&buf, "<< /Type /Font /Subtype /Type1 /BaseFont /%s /Encoding /WinAnsiEncoding >>\n", name)
+		end()
+	}
+
+	for i, img := range d.images {
+		begin(imageObj(i))
+		colorSpace := "/DeviceRGB"
+		if img.gray {
+			colorSpace = "/DeviceGray"
+		}
+		fmt.Fprintf(&buf, "<< /Type /XObject /Subtype /Image /Width %d /Height %d /ColorSpace %s /BitsPerComponent 8 /Filter /DCTDecode /Length %d >>\nstream\n",
+			img.Width, img.Height, colorSpace, len(img.data))
+		buf.Write(img.data)
+		buf.WriteString("\nendstream\n")
+		end()
+	}
+
+	for i, p := range d.pages {
+		var xobjects strings.Builder
+		seen := map[int]bool{}
+		for _, img := range p.images {
+			if !seen[img.index] {
+				seen[img.index] = true
+				fmt.Fprintf(&xobjects, " /Im%d %d 0 R", img.index+1, imageObj(img.index))
+			}
+		}
+
+		begin(pageObj(i))
+		fmt.Fprintf(&buf, "<< /Type /Page /Parent 2 0 R /MediaBox [0 0 %.2f %.2f] /Contents %d 0 R "+
+			"/Resources << /Font << /F1 3 0 R /F2 4 0 R >> /XObject <<%s >> >> >>\n",
+			PageWidth, PageHeight, pageObj(i)+1, xobjects.String())
+		end()
+
+		begin(pageObj(i) + 1)
+		fmt.Fprintf(&buf, "<< /Length %d >>\nstream\n", p.content.Len())
+		buf.Write(p.content.Bytes())
+		buf.WriteString("endstream\n")
+		end()
+	}
+
+	xref := buf.Len()
+	fmt.Fprintf(&buf, "xref\n0 %d\n0000000000 65535 f \n", len(offsets)+1)
+	for _, off := range offsets {
+		fmt.Fprintf(&buf, "%010d 00000 n \n", off)
+	}
+	fmt.Fprintf(&buf, "trailer\n<< /Size %d /Root 1 0 R >>\nstartxref\n%d\n%%%%EOF\n", len(offsets)+1, xref)
+
+	n, err := w.Write(buf.Bytes())
+	return int64(n), err
+}
+
+// TextWidth estimates the width of s in points. The standard fonts are not
+// embedded, so this uses approximate Helvetica glyph widths; it is meant for
+// wrapping and alignment, not exact measurement.
+func TextWidth(font Font, size float64, s string) float64 {
+	var em float64
+	for _, r := range s {
+		switch {
+		case strings.ContainsRune("iljtfI.,:;!|' ", r):
+			em += 0.28
+		case strings.ContainsRune("r()-[]", r):
+			em += 0.34
+		case r == 'm' || r == 'w' || r == 'M' || r == 'W':
+			em += 0.85
+		case r >= 'A' && r <= 'Z':
+			em += 0.68
+		case r >= '0' && r <= '9':
+			em += 0.56
+		default:
+			em += 0.53
+		}
+	}
+	if font == Bold {
+		em *= 1.06
+	}
+	return em * size
+}
+
+// Wrap splits s into lines no wider than width, breaking at spaces and at
+// newlines in s. Words longer than a line are broken where they overflow.
+func Wrap(font Font, size float64, s string, width float64) []string {
+	var lines []string
+	for _, paragraph := range strings.Split(strings.ReplaceAll(s, "\r\n", "\n"), "\n") {
+		line := ""
+		for _, word := range strings.Fields(paragraph) {
+			candidate := word
+			if line != "" {
+				candidate = line + " " + word
+			}
+			if TextWidth(font, size, candidate) <= width {
+				line = candidate
+				continue
+			}
+			if line != "" {
+				lines = append(lines, line)
+			}
+			// Break words that don't fit on a line of their own
+			line = ""
+			for _, r := range word {
+				if line != "" && TextWidth(font, size, line+string(r)) > width {
+					lines = append(lines, line)
+					line = ""
+				}
+				line += string(r)
+			}
+		}
+		lines = append(lines, line)
+	}
+	return lines
+}
+
+// escape encodes s as a WinAnsi PDF string literal body.
+func escape(s string) string {
+	var b strings.Builder
+	for _, r := range s {
+		c, ok := winAnsi(r)
+		if !ok {
+			c = '?'
+		}
+		switch c {
+		case '(', ')', '\\':
+			b.WriteByte('\\')
+			b.WriteByte(c)
+		case '\n', '\r', '\t':
+			b.WriteByte(' ')
+		default:
+			b.WriteByte(c)
+		}
+	}
+	return b.String()
+}
+
+// winAnsiSpecials maps the characters Windows-1252 puts in 0x80-0x9F.
+var winAnsiSpecials = map[rune]byte{
+	'€': 0x80, '‚': 0x82, 'ƒ': 0x83, '„': 0x84, '…': 0x85, '†': 0x86, '‡': 0x87,
+	'ˆ': 0x88, '‰': 0x89, 'Š': 0x8A, '‹': 0x8B, 'Œ': 0x8C, 'Ž': 0x8E,
+	'‘': 0x91, '’': 0x92, '“': 0x93, '”': 0x94, '•': 0x95, '–': 0x96, '—': 0x97,
+	'˜': 0x98, '™': 0x99, 'š': 0x9A, '›': 0x9B, 'œ': 0x9C, 'ž': 0x9E, 'Ÿ': 0x9F,
+}
+
+func winAnsi(r rune) (byte, bool) {
+	switch {
+	case r >= 0x20 && r < 0x7F, r >= 0xA0 && r <= 0xFF, r == '\n', r == '\r', r == '\t':
+		return byte(r), true
+	}
+	c, ok := winAnsiSpecials[r]
+	return c, ok
+}
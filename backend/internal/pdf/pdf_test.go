@@ -0,0 +1,96 @@
+package pdf
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/png"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestDocument_WriteTo(t *testing.T) {
+	var pngData bytes.Buffer
+	if err := png.Encode(&pngData, image.NewRGBA(image.Rect(0, 0, 400, 200))); err != nil {
+		t.Fatal(err)
+	}
+
+	doc := New()
+	img, err := doc.AddImage(pngData.Bytes(), 100)
+	if err != nil {
+		t.Fatalf("AddImage: %v", err)
+	}
+	if img.Width != 100 || img.Height != 50 {
+		t.Errorf("expected image scaled to 100x50, got %dx%d", img.Width, img.Height)
+	}
+
+	doc.AddPage().Text(50, 800, Bold, 14, "Hello (world)")
+	doc.AddPage().Image(img, 50, 50, 100, 50)
+
+	var out bytes.Buffer
+	if _, err := doc.WriteTo(&out); err != nil {
+		t.Fatalf("WriteTo: %v", err)
+	}
+	pdf := out.String()
+
+	if !strings.HasPrefix(pdf, "%PDF-1.4") || !strings.HasSuffix(pdf, "%%EOF\n") {
+		t.Fatal("expected a PDF header and trailer")
+	}
+	if !strings.Contains(pdf, "/Count 2") {
+		t.Error("expected two pages")
+	}
+	if !strings.Contains(pdf, `(Hello \(world\)) Tj`) {
+		t.Error("expected escaped text")
+	}
+
+	// Every xref offset must point at the start of its object
+	start, err := strconv.Atoi(regexp.MustCompile(`startxref\n(\d+)`).FindStringSubmatch(pdf)[1])
+	if err != nil {
+		t.Fatal(err)
+	}
+	entries := regexp.MustCompile(`(\d{10}) 00000 n `).FindAllStringSubmatch(pdf[start:], -1)
+	if len(entries) == 0 {
+		t.Fatal("expected xref entries")
+	}
+	for i, e := range entries {
+		offset, _ := strconv.Atoi(e[1])
+		if want := fmt.Sprintf("%d 0 obj", i+1); !strings.HasPrefix(pdf[offset:], want) {
+			t.Errorf("xref entry %d does not point at %q", i+1, want)
+		}
+	}
+}
+
+func TestWrap(t *testing.T) {
+	lines := Wrap(Regular, 10, "one two three four five six seven eight nine ten", 80)
+	if len(lines) < 2 {
+		t.Fatalf("expected text to wrap, got %q", lines)
+	}
+	for _, line := range lines {
+		if w := TextWidth(Regular, 10, line); w > 80 {
+			t.Errorf("line %q is %.1fpt wide, want at most 80", line, w)
+		}
+	}
+	if got := strings.Join(lines, " "); got != "one two three four five six seven eight nine ten" {
+		t.Errorf("expected all words kept in order, got %q", got)
+	}
+
+	if got := Wrap(Regular, 10, "first\nsecond", 500); len(got) != 2 {
+		t.Errorf("expected newlines to start a new line, got %q", got)
+	}
+	for _, line := range Wrap(Regular, 10, strings.Repeat("x", 100), 50) {
+		if TextWidth(Regular, 10, line) > 50 {
+			t.Errorf("expected long word to be broken, got %q", line)
+		}
+	}
+}
+
+func TestEscape(t *testing.T) {
+	if got := escape(`a\b`); got != `a\\b` {
+		t.Errorf("expected backslash escaped, got %q", got)
+	}
+	if got := escape("café — Привет"); got != "caf\xe9 \x97 ??????" {
+		t.Errorf("expected WinAnsi encoding, got %q", got)
+	}
+}
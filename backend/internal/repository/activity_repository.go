@@ -0,0 +1,111 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// FieldChange is one field's value before and after a write. Old is null for
+// inserts and New is null for deletes.
+type FieldChange struct {
+	Old interface{} `json:"old"`
+	New interface{} `json:"new"`
+}
+
+// Activity is one recorded write to an entry or collection, see the
+// activity_log table.
+type Activity struct {
+	ID        int64                  `json:"id"`
+	UserID    uuid.UUID              `json:"user_id"`
+	ActorID   uuid.UUID              `json:"actor_id"`
+	Entity    string                 `json:"entity"` // "entry" or "collection"
+	EntityID  uuid.UUID              `json:"entity_id"`
+	Op        string                 `json:"op"` // "insert", "update" or "delete"
+	Changes   map[string]FieldChange `json:"changes"`
+	CreatedAt time.Time              `json:"created_at"`
+}
+
+// ActivityFilter narrows a user's activity. Zero values leave a filter out;
+// Before is an activity id to page backwards from.
+type ActivityFilter struct {
+	Entity   string
+	EntityID *uuid.UUID
+	Before   int64
+	Limit    int
+}
+
+type ActivityRepository struct {
+	db *pgxpool.Pool
+}
+
+func NewActivityRepository(db *pgxpool.Pool) *ActivityRepository {
+	return &ActivityRepository{db: db}
+}
+
+// ListActivity returns a user's activity, newest first.
+func (r *ActivityRepository) ListActivity(
+	ctx context.Context,
+	userID uuid.UUID,
+	filter ActivityFilter,
+) ([]*Activity, error) {
+	query := `
+		SELECT id, user_id, actor_id, entity, entity_id, op, changes, created_at
+		FROM activity_log
+		WHERE user_id = $1
+		AND ($2 = '' OR entity = $2)
+		AND ($3::uuid IS NULL OR entity_id = $3)
+		AND ($4 = 0 OR id < $4)
+		ORDER BY id DESC
+		LIMIT $5
+	`
+
+	rows, err := r.db.Query(ctx, query, userID, filter.Entity, filter.EntityID, filter.Before, filter.Limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query activity: %w", err)
+	}
+	defer rows.Close()
+
+	activities := []*Activity{}
+	for rows.Next() {
+		var a Activity
+		var changesStr string
+		err := rows.Scan(
+			&a.ID,
+			&a.UserID,
+			&a.ActorID,
+			&a.Entity,
+			&a.EntityID,
+			&a.Op,
+			&changesStr,
+			&a.CreatedAt,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan activity: %w", err)
+		}
+		if err := json.Unmarshal([]byte(changesStr), &a.Changes); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal activity changes: %w", err)
+		}
+		activities = append(activities, &a)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating activity: %w", err)
+	}
+
+	return activities, nil
+}
+
+// DeleteActivityOlderThan removes activity recorded more than age ago.
+func (r *ActivityRepository) DeleteActivityOlderThan(ctx context.Context, age time.Duration) (int64, error) {
+	result, err := r.db.Exec(ctx,
+		`DELETE FROM activity_log WHERE created_at < NOW() - make_interval(secs => $1)`, age.Seconds())
+	if err != nil {
+		return 0, fmt.Errorf("failed to delete activity: %w", err)
+	}
+	return result.RowsAffected(), nil
+}
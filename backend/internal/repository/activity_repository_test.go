@@ -0,0 +1,64 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// Tests in this file need a migrated PostgreSQL database, like the
+// benchmarks in entry_repository_bench_test.go.
+func TestActivity_LeavesOutDerivedColumns(t *testing.T) {
+	dsn := os.Getenv("LIVLOG_TEST_DATABASE_URL")
+	if dsn == "" {
+		t.Skip("LIVLOG_TEST_DATABASE_URL is not set")
+	}
+
+	ctx := context.Background()
+	pool, err := pgxpool.New(ctx, dsn)
+	if err != nil {
+		t.Fatalf("failed to connect: %v", err)
+	}
+	t.Cleanup(pool.Close)
+
+	user, err := NewUserRepository(pool).CreateUser(ctx, fmt.Sprintf("activity-%s@example.com", uuid.NewString()), "Activity", true)
+	if err != nil {
+		t.Fatalf("failed to create user: %v", err)
+	}
+	t.Cleanup(func() {
+		_, _ = pool.Exec(context.Background(), `DELETE FROM activity_log WHERE user_id = $1`, user.ID)
+		_, _ = pool.Exec(context.Background(), `DELETE FROM users WHERE id = $1`, user.ID)
+	})
+
+	entries := NewEntryRepository(pool)
+	date := time.Date(2025, 3, 1, 0, 0, 0, 0, time.UTC)
+	entry, err := entries.CreateEntry(ctx, nil, user.ID, nil, nil, "The Matrix", "", "", "", 3, 0, VisibilityPrivate, date, map[string]string{}, nil)
+	if err != nil {
+		t.Fatalf("failed to create entry: %v", err)
+	}
+	if _, _, err := entries.UpdateEntry(ctx, entry.ID, nil, nil, "The Matrix Reloaded", "", nil, nil, 3, nil, nil, date, map[string]string{}, nil); err != nil {
+		t.Fatalf("failed to update entry: %v", err)
+	}
+
+	activities, err := NewActivityRepository(pool).ListActivity(ctx, user.ID, ActivityFilter{EntityID: &entry.ID, Limit: 10})
+	if err != nil {
+		t.Fatalf("ListActivity() error = %v", err)
+	}
+	if len(activities) != 2 {
+		t.Fatalf("ListActivity() returned %d rows, want the insert and the update", len(activities))
+	}
+	for _, a := range activities {
+		if _, ok := a.Changes["title"]; !ok {
+			t.Errorf("%s changes = %v, want title", a.Op, a.Changes)
+		}
+		// title_sort is generated from title, so it's no change of its own
+		if _, ok := a.Changes["title_sort"]; ok {
+			t.Errorf("%s changes include title_sort: %v", a.Op, a.Changes)
+		}
+	}
+}
@@ -25,6 +25,13 @@ type AISearchUsage struct {
 	UpdatedAt   time.Time `json:"updated_at"`
 }
 
+// AIProviderSpend is what AI search sent to the model provider on one (UTC)
+// day, see config.AIBudgetConfig. Cost is in USD, as reported by OpenRouter.
+type AIProviderSpend struct {
+	Requests int64
+	Cost     float64
+}
+
 type AISearchUsageRepository struct {
 	db *pgxpool.Pool
 }
@@ -174,3 +181,88 @@ func (r *AISearchUsageRepository) GetUsage(
 
 	return &usage, nil
 }
+
+// ResetUsage clears the user's usage so the next search starts a new period.
+func (r *AISearchUsageRepository) ResetUsage(ctx context.Context, userID uuid.UUID) error {
+	query := `DELETE FROM ai_search_usage WHERE user_id = $1`
+
+	if _, err := r.db.Exec(ctx, query, userID); err != nil {
+		return fmt.Errorf("failed to reset usage: %w", err)
+	}
+
+	return nil
+}
+
+// RecordSearch adds a search to today's (UTC) total and to the user's daily
+// usage, kept for every policy including unlimited ones.
+func (r *AISearchUsageRepository) RecordSearch(ctx context.Context, userID uuid.UUID) error {
+	query := `
+		WITH total AS (
+			INSERT INTO ai_search_daily (day, searches)
+			VALUES ((NOW() AT TIME ZONE 'UTC')::date, 1)
+			ON CONFLICT (day) DO UPDATE SET searches = ai_search_daily.searches + 1
+		)
+		INSERT INTO user_usage_daily (user_id, day, ai_searches)
+		VALUES ($1, (NOW() AT TIME ZONE 'UTC')::date, 1)
+		ON CONFLICT (user_id, day) DO UPDATE SET ai_searches = user_usage_daily.ai_searches + 1
+	`
+
+	if _, err := r.db.Exec(ctx, query, userID); err != nil {
+		return fmt.Errorf("failed to record search: %w", err)
+	}
+
+	return nil
+}
+
+// GetProviderSpend returns today's (UTC) provider requests and their cost.
+func (r *AISearchUsageRepository) GetProviderSpend(ctx context.Context) (*AIProviderSpend, error) {
+	query := `
+		SELECT provider_requests, provider_cost::float8
+		FROM ai_search_daily
+		WHERE day = (NOW() AT TIME ZONE 'UTC')::date
+	`
+
+	var spend AIProviderSpend
+	err := r.db.QueryRow(ctx, query).Scan(&spend.Requests, &spend.Cost)
+	if err != nil && !errors.Is(err, pgx.ErrNoRows) {
+		return nil, fmt.Errorf("failed to get provider spend: %w", err)
+	}
+
+	return &spend, nil
+}
+
+// RecordProviderRequest adds a provider request and its cost to today's
+// (UTC) total.
+func (r *AISearchUsageRepository) RecordProviderRequest(ctx context.Context, cost float64) error {
+	query := `
+		INSERT INTO ai_search_daily (day, provider_requests, provider_cost)
+		VALUES ((NOW() AT TIME ZONE 'UTC')::date, 1, $1)
+		ON CONFLICT (day) DO UPDATE SET
+			provider_requests = ai_search_daily.provider_requests + 1,
+			provider_cost = ai_search_daily.provider_cost + EXCLUDED.provider_cost
+	`
+
+	if _, err := r.db.Exec(ctx, query, cost); err != nil {
+		return fmt.Errorf("failed to record provider request: %w", err)
+	}
+
+	return nil
+}
+
+// MarkBudgetAlerted notes that admins were told today's (UTC) budget ran
+// out. Reports false if it was already noted, e.g. by another instance.
+func (r *AISearchUsageRepository) MarkBudgetAlerted(ctx context.Context) (bool, error) {
+	query := `
+		INSERT INTO ai_search_daily (day, budget_alerted_at)
+		VALUES ((NOW() AT TIME ZONE 'UTC')::date, NOW())
+		ON CONFLICT (day) DO UPDATE SET budget_alerted_at = EXCLUDED.budget_alerted_at
+		WHERE ai_search_daily.budget_alerted_at IS NULL
+	`
+
+	result, err := r.db.Exec(ctx, query)
+	if err != nil {
+		return false, fmt.Errorf("failed to mark budget alerted: %w", err)
+	}
+
+	return result.RowsAffected() > 0, nil
+}
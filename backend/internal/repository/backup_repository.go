@@ -0,0 +1,225 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// Backup statuses follow the export statuses: queued as pending, claimed by
+// the backup job as running, and ending as done or failed.
+const (
+	BackupStatusPending = "pending"
+	BackupStatusRunning = "running"
+	BackupStatusDone    = "done"
+	BackupStatusFailed  = "failed"
+)
+
+// Backup is a database backup; Key names its archive in the backup store.
+type Backup struct {
+	ID          uuid.UUID  `json:"id"`
+	RequestedBy *uuid.UUID `json:"requested_by,omitempty"`
+	Status      string     `json:"status"`
+	Key         *string    `json:"key,omitempty"`
+	SizeBytes   int64      `json:"size_bytes"`
+	SHA256      *string    `json:"sha256,omitempty"`
+	Error       *string    `json:"error,omitempty"`
+	FinishedAt  *time.Time `json:"finished_at,omitempty"`
+	CreatedAt   time.Time  `json:"created_at"`
+}
+
+const backupColumns = `id, requested_by, status, key, size_bytes, sha256, error, finished_at, created_at`
+
+type BackupRepository struct {
+	db *pgxpool.Pool
+}
+
+func NewBackupRepository(db *pgxpool.Pool) *BackupRepository {
+	return &BackupRepository{db: db}
+}
+
+// CreateBackup queues a backup; requestedBy is nil for scheduled ones.
+func (r *BackupRepository) CreateBackup(ctx context.Context, requestedBy *uuid.UUID) (*Backup, error) {
+	query := `
+		INSERT INTO backups (requested_by)
+		VALUES ($1)
+		RETURNING ` + backupColumns
+
+	return scanBackup(r.db.QueryRow(ctx, query, requestedBy))
+}
+
+// CreateBackupIfDue queues a scheduled backup unless one is queued, one
+// succeeded less than interval ago, or one failed less than retry ago. It
+// returns whether a backup was queued.
+func (r *BackupRepository) CreateBackupIfDue(ctx context.Context, interval, retry time.Duration) (bool, error) {
+	query := `
+		INSERT INTO backups (requested_by)
+		SELECT NULL
+		WHERE NOT EXISTS (
+			SELECT 1 FROM backups
+			WHERE status IN ('pending', 'running')
+				OR (status = 'done' AND created_at > NOW() - make_interval(secs => $1))
+				OR (status = 'failed' AND created_at > NOW() - make_interval(secs => $2))
+		)
+	`
+
+	result, err := r.db.Exec(ctx, query, interval.Seconds(), retry.Seconds())
+	if err != nil {
+		return false, fmt.Errorf("failed to schedule backup: %w", err)
+	}
+
+	return result.RowsAffected() > 0, nil
+}
+
+// ListBackups returns the latest backups, newest first.
+func (r *BackupRepository) ListBackups(ctx context.Context, limit int) ([]*Backup, error) {
+	query := `
+		SELECT ` + backupColumns + `
+		FROM backups
+		ORDER BY created_at DESC
+		LIMIT $1
+	`
+
+	rows, err := r.db.Query(ctx, query, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list backups: %w", err)
+	}
+	defer rows.Close()
+
+	var backups []*Backup
+	for rows.Next() {
+		b, err := scanBackup(rows)
+		if err != nil {
+			return nil, err
+		}
+		backups = append(backups, b)
+	}
+
+	return backups, rows.Err()
+}
+
+// ClaimBackup marks the oldest pending backup as running and returns it, or
+// nil when there is nothing to do. Running backups claimed more than lease ago
+// are assumed abandoned by a crashed server and claimed again.
+func (r *BackupRepository) ClaimBackup(ctx context.Context, lease time.Duration) (*Backup, error) {
+	query := `
+		UPDATE backups
+		SET status = 'running', claimed_at = NOW()
+		WHERE id = (
+			SELECT id FROM backups
+			WHERE status = 'pending'
+				OR (status = 'running' AND claimed_at < NOW() - make_interval(secs => $1))
+			ORDER BY created_at ASC
+			LIMIT 1
+			FOR UPDATE SKIP LOCKED
+		)
+		RETURNING ` + backupColumns
+
+	backup, err := scanBackup(r.db.QueryRow(ctx, query, lease.Seconds()))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to claim backup: %w", err)
+	}
+
+	return backup, nil
+}
+
+// CompleteBackup records the stored archive and marks the backup done.
+func (r *BackupRepository) CompleteBackup(ctx context.Context, id uuid.UUID, key string, size int64, sha256 string) error {
+	query := `
+		UPDATE backups
+		SET status = 'done', key = $2, size_bytes = $3, sha256 = $4, error = NULL, finished_at = NOW()
+		WHERE id = $1
+	`
+
+	if _, err := r.db.Exec(ctx, query, id, key, size, sha256); err != nil {
+		return fmt.Errorf("failed to complete backup: %w", err)
+	}
+
+	return nil
+}
+
+// FailBackup marks the backup failed with the given reason.
+func (r *BackupRepository) FailBackup(ctx context.Context, id uuid.UUID, reason string) error {
+	query := `
+		UPDATE backups
+		SET status = 'failed', error = $2, finished_at = NOW()
+		WHERE id = $1
+	`
+
+	if _, err := r.db.Exec(ctx, query, id, reason); err != nil {
+		return fmt.Errorf("failed to record backup failure: %w", err)
+	}
+
+	return nil
+}
+
+// ListExpiredBackups returns finished backups older than olderThan, except
+// the latest successful one, which is always kept.
+func (r *BackupRepository) ListExpiredBackups(ctx context.Context, olderThan time.Duration) ([]*Backup, error) {
+	query := `
+		SELECT ` + backupColumns + `
+		FROM backups
+		WHERE finished_at IS NOT NULL
+			AND created_at < NOW() - make_interval(secs => $1)
+			AND id IS DISTINCT FROM (
+				SELECT id FROM backups WHERE status = 'done' ORDER BY created_at DESC LIMIT 1
+			)
+		ORDER BY created_at ASC
+	`
+
+	rows, err := r.db.Query(ctx, query, olderThan.Seconds())
+	if err != nil {
+		return nil, fmt.Errorf("failed to list expired backups: %w", err)
+	}
+	defer rows.Close()
+
+	var backups []*Backup
+	for rows.Next() {
+		b, err := scanBackup(rows)
+		if err != nil {
+			return nil, err
+		}
+		backups = append(backups, b)
+	}
+
+	return backups, rows.Err()
+}
+
+// DeleteBackup removes a backup record.
+func (r *BackupRepository) DeleteBackup(ctx context.Context, id uuid.UUID) error {
+	if _, err := r.db.Exec(ctx, `DELETE FROM backups WHERE id = $1`, id); err != nil {
+		return fmt.Errorf("failed to delete backup: %w", err)
+	}
+	return nil
+}
+
+func scanBackup(row pgx.Row) (*Backup, error) {
+	var b Backup
+	err := row.Scan(
+		&b.ID,
+		&b.RequestedBy,
+		&b.Status,
+		&b.Key,
+		&b.SizeBytes,
+		&b.SHA256,
+		&b.Error,
+		&b.FinishedAt,
+		&b.CreatedAt,
+	)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, err
+		}
+		return nil, fmt.Errorf("failed to scan backup: %w", err)
+	}
+
+	return &b, nil
+}
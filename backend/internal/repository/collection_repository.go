@@ -8,49 +8,71 @@ import (
 
 	"github.com/google/uuid"
 	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
 	"github.com/jackc/pgx/v5/pgxpool"
 )
 
 var (
 	ErrCollectionNotFound = errors.New("collection not found")
+	ErrShareSlugTaken     = errors.New("share slug is taken")
 )
 
 type Collection struct {
-	ID         uuid.UUID `json:"id"`
-	UserID     uuid.UUID `json:"user_id"`
-	Name       string    `json:"name"`
-	Icon       string    `json:"icon"`
-	EntryCount int       `json:"entry_count"`
-	CreatedAt  time.Time `json:"created_at"`
-	UpdatedAt  time.Time `json:"updated_at"`
+	ID             uuid.UUID   `json:"id"`
+	UserID         uuid.UUID   `json:"user_id"`
+	Name           string      `json:"name"`
+	Icon           string      `json:"icon"`                       // emoji; empty when IconImageID is set
+	IconImageID    *uuid.UUID  `json:"icon_image_id,omitempty"`    // uploaded icon, see CollectionIcon
+	IconImageHash  string      `json:"-"`                          // MD5 of the uploaded icon
+	WorkspaceID    *uuid.UUID  `json:"workspace_id,omitempty"`     // nil for the owner's personal workspace
+	AllowedTypeIDs []uuid.UUID `json:"allowed_type_ids,omitempty"` // entry types it accepts; empty accepts any
+	ShareSlug      *string     `json:"share_slug,omitempty"`       // shared at GET /public/c/{slug}; nil when not shared
+	EntryCount     int         `json:"entry_count"`
+	CreatedAt      time.Time   `json:"created_at"`
+	UpdatedAt      time.Time   `json:"updated_at"`
 }
 
 type CollectionRepository struct {
-	db *pgxpool.Pool
+	db       *pgxpool.Pool
+	replicas *ReplicaRouter // nil reads from db
 }
 
 func NewCollectionRepository(db *pgxpool.Pool) *CollectionRepository {
 	return &CollectionRepository{db: db}
 }
 
+// UseReplicas sends ListCollectionsPage to replicas.
+func (r *CollectionRepository) UseReplicas(router *ReplicaRouter) {
+	r.replicas = router
+}
+
 // CreateCollection creates a new collection
 func (r *CollectionRepository) CreateCollection(
 	ctx context.Context,
+	id *uuid.UUID, // nil to generate one
 	userID uuid.UUID,
 	name, icon string,
+	iconImageID *uuid.UUID,
+	allowedTypeIDs []uuid.UUID, // nil or empty accepts any type
 ) (*Collection, error) {
 	query := `
-		INSERT INTO collections (user_id, name, icon)
-		VALUES ($1, $2, $3)
-		RETURNING id, user_id, name, icon, 0 AS entry_count, created_at, updated_at
+		INSERT INTO collections (id, user_id, name, icon, icon_image_id, allowed_type_ids)
+		VALUES (COALESCE($1::uuid, gen_random_uuid()), $2, $3, NULLIF($4, ''), $5, NULLIF($6::uuid[], '{}'))
+		RETURNING id, user_id, name, COALESCE(icon, ''), icon_image_id,
+			COALESCE((SELECT ci.content_hash FROM collection_icons ci WHERE ci.id = collections.icon_image_id), ''), workspace_id, allowed_type_ids, share_slug, 0 AS entry_count, created_at, updated_at
 	`
 
 	var collection Collection
-	err := r.db.QueryRow(ctx, query, userID, name, icon).Scan(
+	err := r.db.QueryRow(ctx, query, id, userID, name, icon, iconImageID, allowedTypeIDs).Scan(
 		&collection.ID,
 		&collection.UserID,
 		&collection.Name,
 		&collection.Icon,
+		&collection.IconImageID,
+		&collection.IconImageHash,
+		&collection.WorkspaceID,
+		&collection.AllowedTypeIDs,
+		&collection.ShareSlug,
 		&collection.EntryCount,
 		&collection.CreatedAt,
 		&collection.UpdatedAt,
@@ -67,18 +89,45 @@ func (r *CollectionRepository) GetCollectionsByUserID(
 	ctx context.Context,
 	userID uuid.UUID,
 ) ([]*Collection, error) {
+	collections, _, err := r.listCollectionsPage(ctx, r.db, userID, nil, 0)
+	return collections, err
+}
+
+// ListCollectionsPage returns up to limit of the user's collections after
+// the cursor, oldest first, and the cursor of the next page (nil on the
+// last page). A nil after starts at the beginning; a zero limit returns all.
+func (r *CollectionRepository) ListCollectionsPage(
+	ctx context.Context,
+	userID uuid.UUID,
+	after *PageCursor,
+	limit int,
+) ([]*Collection, *PageCursor, error) {
+	return r.listCollectionsPage(ctx, readPool(r.replicas, r.db), userID, after, limit)
+}
+
+func (r *CollectionRepository) listCollectionsPage(
+	ctx context.Context,
+	db *pgxpool.Pool,
+	userID uuid.UUID,
+	after *PageCursor,
+	limit int,
+) ([]*Collection, *PageCursor, error) {
 	query := `
-		SELECT c.id, c.user_id, c.name, c.icon, COUNT(e.id) AS entry_count, c.created_at, c.updated_at
+		SELECT c.id, c.user_id, c.name, COALESCE(c.icon, ''), c.icon_image_id,
+			COALESCE((SELECT ci.content_hash FROM collection_icons ci WHERE ci.id = c.icon_image_id), ''), c.workspace_id, c.allowed_type_ids, c.share_slug, COUNT(e.id) AS entry_count, c.created_at, c.updated_at
 		FROM collections c
 		LEFT JOIN entries e ON e.collection_id = c.id
 		WHERE c.user_id = $1
+		AND ($2::timestamptz IS NULL OR (c.created_at, c.id) > ($2, $3))
 		GROUP BY c.id
-		ORDER BY c.created_at ASC
+		ORDER BY c.created_at ASC, c.id ASC
+		LIMIT $4
 	`
 
-	rows, err := r.db.Query(ctx, query, userID)
+	_, afterCreatedAt, afterID, fetch := pageArgs(after, limit)
+	rows, err := db.Query(ctx, query, userID, afterCreatedAt, afterID, fetch)
 	if err != nil {
-		return nil, fmt.Errorf("failed to query collections: %w", err)
+		return nil, nil, fmt.Errorf("failed to query collections: %w", err)
 	}
 	defer rows.Close()
 
@@ -90,21 +139,30 @@ func (r *CollectionRepository) GetCollectionsByUserID(
 			&collection.UserID,
 			&collection.Name,
 			&collection.Icon,
+			&collection.IconImageID,
+			&collection.IconImageHash,
+			&collection.WorkspaceID,
+			&collection.AllowedTypeIDs,
+			&collection.ShareSlug,
 			&collection.EntryCount,
 			&collection.CreatedAt,
 			&collection.UpdatedAt,
 		)
 		if err != nil {
-			return nil, fmt.Errorf("failed to scan collection: %w", err)
+			return nil, nil, fmt.Errorf("failed to scan collection: %w", err)
 		}
 		collections = append(collections, &collection)
 	}
 
 	if err := rows.Err(); err != nil {
-		return nil, fmt.Errorf("error iterating collections: %w", err)
+		return nil, nil, fmt.Errorf("error iterating collections: %w", err)
 	}
 
-	return collections, nil
+	if limit == 0 || len(collections) <= limit {
+		return collections, nil, nil
+	}
+	last := collections[limit-1]
+	return collections[:limit], &PageCursor{CreatedAt: last.CreatedAt, ID: last.ID}, nil
 }
 
 // GetCollectionByID retrieves a single collection by ID with entry count.
@@ -113,7 +171,8 @@ func (r *CollectionRepository) GetCollectionByID(
 	id uuid.UUID,
 ) (*Collection, error) {
 	query := `
-		SELECT c.id, c.user_id, c.name, c.icon, COUNT(e.id) AS entry_count, c.created_at, c.updated_at
+		SELECT c.id, c.user_id, c.name, COALESCE(c.icon, ''), c.icon_image_id,
+			COALESCE((SELECT ci.content_hash FROM collection_icons ci WHERE ci.id = c.icon_image_id), ''), c.workspace_id, c.allowed_type_ids, c.share_slug, COUNT(e.id) AS entry_count, c.created_at, c.updated_at
 		FROM collections c
 		LEFT JOIN entries e ON e.collection_id = c.id
 		WHERE c.id = $1
@@ -126,6 +185,11 @@ func (r *CollectionRepository) GetCollectionByID(
 		&collection.UserID,
 		&collection.Name,
 		&collection.Icon,
+		&collection.IconImageID,
+		&collection.IconImageHash,
+		&collection.WorkspaceID,
+		&collection.AllowedTypeIDs,
+		&collection.ShareSlug,
 		&collection.EntryCount,
 		&collection.CreatedAt,
 		&collection.UpdatedAt,
@@ -140,25 +204,33 @@ func (r *CollectionRepository) GetCollectionByID(
 	return &collection, nil
 }
 
-// UpdateCollection updates a collection's name and/or icon
+// UpdateCollection updates a collection's name, icon and allowed types
 func (r *CollectionRepository) UpdateCollection(
 	ctx context.Context,
 	id uuid.UUID,
 	name, icon string,
+	iconImageID *uuid.UUID,
+	allowedTypeIDs []uuid.UUID, // nil or empty accepts any type
 ) (*Collection, error) {
 	query := `
 		UPDATE collections
-		SET name = $2, icon = $3, updated_at = NOW()
+		SET name = $2, icon = NULLIF($3, ''), icon_image_id = $4, allowed_type_ids = NULLIF($5::uuid[], '{}'), updated_at = NOW()
 		WHERE id = $1
-		RETURNING id, user_id, name, icon, 0 AS entry_count, created_at, updated_at
+		RETURNING id, user_id, name, COALESCE(icon, ''), icon_image_id,
+			COALESCE((SELECT ci.content_hash FROM collection_icons ci WHERE ci.id = collections.icon_image_id), ''), workspace_id, allowed_type_ids, share_slug, 0 AS entry_count, created_at, updated_at
 	`
 
 	var collection Collection
-	err := r.db.QueryRow(ctx, query, id, name, icon).Scan(
+	err := r.db.QueryRow(ctx, query, id, name, icon, iconImageID, allowedTypeIDs).Scan(
 		&collection.ID,
 		&collection.UserID,
 		&collection.Name,
 		&collection.Icon,
+		&collection.IconImageID,
+		&collection.IconImageHash,
+		&collection.WorkspaceID,
+		&collection.AllowedTypeIDs,
+		&collection.ShareSlug,
 		&collection.EntryCount,
 		&collection.CreatedAt,
 		&collection.UpdatedAt,
@@ -200,7 +272,8 @@ func (r *CollectionRepository) CreateDefaultCollections(
 	query := `
 		INSERT INTO collections (user_id, name, icon)
 		VALUES ($1, $2, $3)
-		RETURNING id, user_id, name, icon, 0 AS entry_count, created_at, updated_at
+		RETURNING id, user_id, name, COALESCE(icon, ''), icon_image_id,
+			COALESCE((SELECT ci.content_hash FROM collection_icons ci WHERE ci.id = collections.icon_image_id), ''), workspace_id, allowed_type_ids, share_slug, 0 AS entry_count, created_at, updated_at
 	`
 
 	var collection Collection
@@ -209,6 +282,11 @@ func (r *CollectionRepository) CreateDefaultCollections(
 		&collection.UserID,
 		&collection.Name,
 		&collection.Icon,
+		&collection.IconImageID,
+		&collection.IconImageHash,
+		&collection.WorkspaceID,
+		&collection.AllowedTypeIDs,
+		&collection.ShareSlug,
 		&collection.EntryCount,
 		&collection.CreatedAt,
 		&collection.UpdatedAt,
@@ -220,6 +298,18 @@ func (r *CollectionRepository) CreateDefaultCollections(
 	return []*Collection{&collection}, nil
 }
 
+// CountCollections returns how many collections a user has
+func (r *CollectionRepository) CountCollections(ctx context.Context, userID uuid.UUID) (int, error) {
+	query := `SELECT COUNT(*) FROM collections WHERE user_id = $1`
+
+	var count int
+	if err := r.db.QueryRow(ctx, query, userID).Scan(&count); err != nil {
+		return 0, fmt.Errorf("failed to count collections: %w", err)
+	}
+
+	return count, nil
+}
+
 // HasCollections checks if user has any collections
 func (r *CollectionRepository) HasCollections(
 	ctx context.Context,
@@ -235,3 +325,198 @@ func (r *CollectionRepository) HasCollections(
 
 	return exists, nil
 }
+
+// GetCollectionsByWorkspace retrieves every collection placed in a shared
+// workspace, regardless of which member owns it.
+func (r *CollectionRepository) GetCollectionsByWorkspace(
+	ctx context.Context,
+	workspaceID uuid.UUID,
+) ([]*Collection, error) {
+	query := `
+		SELECT c.id, c.user_id, c.name, COALESCE(c.icon, ''), c.icon_image_id,
+			COALESCE((SELECT ci.content_hash FROM collection_icons ci WHERE ci.id = c.icon_image_id), ''), c.workspace_id, c.allowed_type_ids, c.share_slug, COUNT(e.id) AS entry_count, c.created_at, c.updated_at
+		FROM collections c
+		LEFT JOIN entries e ON e.collection_id = c.id
+		WHERE c.workspace_id = $1
+		GROUP BY c.id
+		ORDER BY c.created_at ASC
+	`
+
+	rows, err := r.db.Query(ctx, query, workspaceID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query workspace collections: %w", err)
+	}
+	defer rows.Close()
+
+	var collections []*Collection
+	for rows.Next() {
+		var collection Collection
+		err := rows.Scan(
+			&collection.ID,
+			&collection.UserID,
+			&collection.Name,
+			&collection.Icon,
+			&collection.IconImageID,
+			&collection.IconImageHash,
+			&collection.WorkspaceID,
+			&collection.AllowedTypeIDs,
+			&collection.ShareSlug,
+			&collection.EntryCount,
+			&collection.CreatedAt,
+			&collection.UpdatedAt,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan collection: %w", err)
+		}
+		collections = append(collections, &collection)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating collections: %w", err)
+	}
+
+	return collections, nil
+}
+
+// SetCollectionWorkspace moves a collection into a shared workspace, or back
+// to its owner's personal workspace when workspaceID is nil.
+func (r *CollectionRepository) SetCollectionWorkspace(
+	ctx context.Context,
+	id uuid.UUID,
+	workspaceID *uuid.UUID,
+) error {
+	query := `UPDATE collections SET workspace_id = $2, updated_at = NOW() WHERE id = $1`
+
+	result, err := r.db.Exec(ctx, query, id, workspaceID)
+	if err != nil {
+		return fmt.Errorf("failed to set collection workspace: %w", err)
+	}
+
+	if result.RowsAffected() == 0 {
+		return ErrCollectionNotFound
+	}
+
+	return nil
+}
+
+// SetCollectionShareSlug shares a collection under slug, or stops sharing it
+// when slug is nil. A slug another collection is shared under returns
+// ErrShareSlugTaken.
+func (r *CollectionRepository) SetCollectionShareSlug(
+	ctx context.Context,
+	id uuid.UUID,
+	slug *string,
+) error {
+	query := `UPDATE collections SET share_slug = $2, updated_at = NOW() WHERE id = $1`
+
+	result, err := r.db.Exec(ctx, query, id, slug)
+	if err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == "23505" {
+			return ErrShareSlugTaken
+		}
+		return fmt.Errorf("failed to set collection share slug: %w", err)
+	}
+
+	if result.RowsAffected() == 0 {
+		return ErrCollectionNotFound
+	}
+
+	return nil
+}
+
+// GetSharedCollection retrieves the collection shared under slug, counting
+// only its public entries. Collections of deleted users are not found.
+func (r *CollectionRepository) GetSharedCollection(
+	ctx context.Context,
+	slug string,
+) (*Collection, error) {
+	query := `
+		SELECT c.id, c.user_id, c.name, COALESCE(c.icon, ''), c.icon_image_id,
+			COALESCE((SELECT ci.content_hash FROM collection_icons ci WHERE ci.id = c.icon_image_id), ''), c.workspace_id, c.allowed_type_ids, c.share_slug,
+			COUNT(e.id) FILTER (WHERE e.visibility = 'public') AS entry_count, c.created_at, c.updated_at
+		FROM collections c
+		JOIN users u ON u.id = c.user_id
+		LEFT JOIN entries e ON e.collection_id = c.id
+		WHERE c.share_slug = $1 AND u.deleted_at IS NULL
+		GROUP BY c.id
+	`
+
+	var collection Collection
+	err := r.db.QueryRow(ctx, query, slug).Scan(
+		&collection.ID,
+		&collection.UserID,
+		&collection.Name,
+		&collection.Icon,
+		&collection.IconImageID,
+		&collection.IconImageHash,
+		&collection.WorkspaceID,
+		&collection.AllowedTypeIDs,
+		&collection.ShareSlug,
+		&collection.EntryCount,
+		&collection.CreatedAt,
+		&collection.UpdatedAt,
+	)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrCollectionNotFound
+		}
+		return nil, fmt.Errorf("failed to get shared collection: %w", err)
+	}
+
+	return &collection, nil
+}
+
+// CollectionIcon is an icon image uploaded for the user's collections.
+type CollectionIcon struct {
+	ID        uuid.UUID `json:"id"`
+	UserID    uuid.UUID `json:"user_id"`
+	ImageData []byte    `json:"-"`
+	Hash      string    `json:"-"` // MD5 of the image data
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// CreateCollectionIcon stores an uploaded icon image
+func (r *CollectionRepository) CreateCollectionIcon(
+	ctx context.Context,
+	userID uuid.UUID,
+	imageData []byte,
+) (*CollectionIcon, error) {
+	query := `
+		INSERT INTO collection_icons (user_id, image_data)
+		VALUES ($1, $2)
+		RETURNING id, user_id, content_hash, created_at
+	`
+
+	icon := CollectionIcon{ImageData: imageData}
+	err := r.db.QueryRow(ctx, query, userID, imageData).Scan(&icon.ID, &icon.UserID, &icon.Hash, &icon.CreatedAt)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create collection icon: %w", err)
+	}
+
+	return &icon, nil
+}
+
+// GetCollectionIcon retrieves an uploaded icon image, failing with
+// ErrImageNotFound if there is none with this ID.
+func (r *CollectionRepository) GetCollectionIcon(
+	ctx context.Context,
+	id uuid.UUID,
+) (*CollectionIcon, error) {
+	query := `
+		SELECT id, user_id, image_data, content_hash, created_at
+		FROM collection_icons
+		WHERE id = $1
+	`
+
+	var icon CollectionIcon
+	err := r.db.QueryRow(ctx, query, id).Scan(&icon.ID, &icon.UserID, &icon.ImageData, &icon.Hash, &icon.CreatedAt)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrImageNotFound
+		}
+		return nil, fmt.Errorf("failed to get collection icon: %w", err)
+	}
+
+	return &icon, nil
+}
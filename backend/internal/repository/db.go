@@ -3,6 +3,7 @@ package repository
 import (
 	"context"
 	"fmt"
+	"strconv"
 	"time"
 
 	"github.com/jackc/pgx/v5/pgxpool"
@@ -17,30 +18,44 @@ type DB struct {
 }
 
 func NewDB(ctx context.Context, cfg *config.DatabaseConfig, logger *zap.Logger) (*DB, error) {
-	poolConfig, err := pgxpool.ParseConfig(cfg.DSN())
+	return connect(ctx, cfg.DSN(), cfg, logger)
+}
+
+// NewReplicaDB connects to the replica at dsn with the pool settings of cfg.
+func NewReplicaDB(ctx context.Context, dsn string, cfg *config.DatabaseConfig, logger *zap.Logger) (*DB, error) {
+	return connect(ctx, dsn, cfg, logger)
+}
+
+func connect(ctx context.Context, dsn string, cfg *config.DatabaseConfig, logger *zap.Logger) (*DB, error) {
+	poolConfig, err := pgxpool.ParseConfig(dsn)
 	if err != nil {
 		return nil, fmt.Errorf("failed to parse database config: %w", err)
 	}
 
-	poolConfig.MaxConns = 10
-	poolConfig.MinConns = 2
-	poolConfig.MaxConnLifetime = time.Hour
-	poolConfig.MaxConnIdleTime = 30 * time.Minute
+	poolConfig.MaxConns = cfg.MaxConns
+	poolConfig.MinConns = cfg.MinConns
+	poolConfig.MaxConnLifetime = cfg.MaxConnLifetime
+	poolConfig.MaxConnIdleTime = cfg.MaxConnIdleTime
+	poolConfig.HealthCheckPeriod = cfg.HealthCheckPeriod
+	if cfg.StatementTimeout > 0 {
+		poolConfig.ConnConfig.RuntimeParams["statement_timeout"] = strconv.FormatInt(cfg.StatementTimeout.Milliseconds(), 10)
+	}
+	poolConfig.ConnConfig.Tracer = newQueryTracer()
 
 	pool, err := pgxpool.NewWithConfig(ctx, poolConfig)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create connection pool: %w", err)
 	}
 
-	if err := pool.Ping(ctx); err != nil {
+	if err := waitForDB(ctx, pool, cfg.ConnectTimeout, logger); err != nil {
 		pool.Close()
-		return nil, fmt.Errorf("failed to ping database: %w", err)
+		return nil, err
 	}
 
 	logger.Info("connected to database",
-		zap.String("host", cfg.Host),
-		zap.Int("port", cfg.Port),
-		zap.String("database", cfg.Name),
+		zap.String("host", poolConfig.ConnConfig.Host),
+		zap.Uint16("port", poolConfig.ConnConfig.Port),
+		zap.String("database", poolConfig.ConnConfig.Database),
 	)
 
 	return &DB{
@@ -49,6 +64,35 @@ func NewDB(ctx context.Context, cfg *config.DatabaseConfig, logger *zap.Logger)
 	}, nil
 }
 
+// waitForDB pings the database until it answers, backing off between attempts,
+// so the server can start alongside a Postgres that is still booting.
+func waitForDB(ctx context.Context, pool *pgxpool.Pool, timeout time.Duration, logger *zap.Logger) error {
+	deadline := time.Now().Add(timeout)
+	backoff := 500 * time.Millisecond
+
+	for {
+		err := pool.Ping(ctx)
+		if err == nil {
+			return nil
+		}
+		if time.Now().Add(backoff).After(deadline) {
+			return fmt.Errorf("failed to ping database: %w", err)
+		}
+
+		logger.Warn("database is not reachable yet, retrying",
+			zap.Duration("retry_in", backoff),
+			zap.Error(err),
+		)
+
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("failed to ping database: %w", ctx.Err())
+		case <-time.After(backoff):
+		}
+		backoff = min(backoff*2, 10*time.Second)
+	}
+}
+
 func (db *DB) Close() {
 	db.Pool.Close()
 	db.logger.Info("database connection closed")
@@ -0,0 +1,112 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/avalarin/livlog/backend/internal/fieldcrypt"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type EncryptionKeyRepository struct {
+	db *pgxpool.Pool
+}
+
+func NewEncryptionKeyRepository(db *pgxpool.Pool) *EncryptionKeyRepository {
+	return &EncryptionKeyRepository{db: db}
+}
+
+// ListEncryptionKeys returns every stored key, oldest first.
+func (r *EncryptionKeyRepository) ListEncryptionKeys(ctx context.Context) ([]fieldcrypt.StoredKey, error) {
+	query := `
+		SELECT id, purpose, wrapped_key, kek_fingerprint, created_at, retired_at
+		FROM encryption_keys
+		ORDER BY created_at, id
+	`
+
+	rows, err := r.db.Query(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query encryption keys: %w", err)
+	}
+	defer rows.Close()
+
+	keys := []fieldcrypt.StoredKey{}
+	for rows.Next() {
+		var k fieldcrypt.StoredKey
+		if err := rows.Scan(&k.ID, &k.Purpose, &k.WrappedKey, &k.KEKFingerprint, &k.CreatedAt, &k.RetiredAt); err != nil {
+			return nil, fmt.Errorf("failed to scan encryption key: %w", err)
+		}
+		keys = append(keys, k)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating encryption keys: %w", err)
+	}
+
+	return keys, nil
+}
+
+// CreateEncryptionKeyIfMissing stores key unless there already is an index
+// key, or an active data key, which instances starting at the same time
+// race to create. Reports whether it was stored.
+func (r *EncryptionKeyRepository) CreateEncryptionKeyIfMissing(ctx context.Context, key fieldcrypt.StoredKey) (bool, error) {
+	query := `
+		INSERT INTO encryption_keys (id, purpose, wrapped_key, kek_fingerprint)
+		VALUES ($1, $2, $3, $4)
+		ON CONFLICT DO NOTHING
+	`
+
+	result, err := r.db.Exec(ctx, query, key.ID, key.Purpose, key.WrappedKey, key.KEKFingerprint)
+	if err != nil {
+		return false, fmt.Errorf("failed to create encryption key: %w", err)
+	}
+
+	return result.RowsAffected() > 0, nil
+}
+
+// RotateDataKey retires the active data key and stores key in its place.
+func (r *EncryptionKeyRepository) RotateDataKey(ctx context.Context, key fieldcrypt.StoredKey) error {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	_, err = tx.Exec(ctx, `
+		UPDATE encryption_keys SET retired_at = NOW()
+		WHERE purpose = 'data' AND retired_at IS NULL
+	`)
+	if err != nil {
+		return fmt.Errorf("failed to retire data key: %w", err)
+	}
+
+	_, err = tx.Exec(ctx, `
+		INSERT INTO encryption_keys (id, purpose, wrapped_key, kek_fingerprint)
+		VALUES ($1, 'data', $2, $3)
+	`, key.ID, key.WrappedKey, key.KEKFingerprint)
+	if err != nil {
+		return fmt.Errorf("failed to create data key: %w", err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return nil
+}
+
+// RewrapEncryptionKey replaces a key's wrapping after a KEK rotation.
+func (r *EncryptionKeyRepository) RewrapEncryptionKey(ctx context.Context, id uuid.UUID, wrappedKey []byte, kekFingerprint string) error {
+	query := `
+		UPDATE encryption_keys
+		SET wrapped_key = $2, kek_fingerprint = $3
+		WHERE id = $1
+	`
+
+	if _, err := r.db.Exec(ctx, query, id, wrappedKey, kekFingerprint); err != nil {
+		return fmt.Errorf("failed to rewrap encryption key: %w", err)
+	}
+
+	return nil
+}
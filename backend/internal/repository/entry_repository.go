@@ -5,16 +5,30 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"strings"
 	"time"
 
 	"github.com/google/uuid"
 	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgxpool"
+
+	"github.com/avalarin/livlog/backend/internal/fieldcrypt"
 )
 
 var (
-	ErrEntryNotFound      = errors.New("entry not found")
-	ErrSeedImageNotFound  = errors.New("seed image not found")
+	ErrEntryNotFound        = errors.New("entry not found")
+	ErrSeedImageNotFound    = errors.New("seed image not found")
+	ErrEntryNotInCollection = errors.New("entry is not in the collection")
+	ErrImageNotFound        = errors.New("image not found")
+)
+
+// Entry visibility levels. The owner always sees their entries; shared
+// entries are also shown to members of the shared workspace the entry's
+// collection is in, and public ones on the owner's public profile as well.
+const (
+	VisibilityPrivate = "private"
+	VisibilityShared  = "shared"
+	VisibilityPublic  = "public"
 )
 
 type Entry struct {
@@ -23,14 +37,32 @@ type Entry struct {
 	TypeID           *uuid.UUID        `json:"type_id,omitempty"`
 	UserID           uuid.UUID         `json:"user_id"`
 	Title            string            `json:"title"`
+	OriginalTitle    string            `json:"original_title"`
+	Language         string            `json:"language"`
 	Description      string            `json:"description"`
 	Score            int               `json:"score"`
+	Priority         int               `json:"priority"`
+	Visibility       string            `json:"visibility"`
 	Date             time.Time         `json:"date"`
 	AdditionalFields map[string]string `json:"additional_fields"`
 	CreatedAt        time.Time         `json:"created_at"`
 	UpdatedAt        time.Time         `json:"updated_at"`
 }
 
+// Device is the app install a write came from: the client's X-Device-ID and
+// its User-Agent.
+type Device struct {
+	ID   string
+	Name string
+}
+
+// EntryWrite is the last write to an entry through CreateEntry or UpdateEntry:
+// when it happened and from which device, nil when that isn't known.
+type EntryWrite struct {
+	Device *Device
+	At     time.Time
+}
+
 type EntryImage struct {
 	ID        uuid.UUID `json:"id"`
 	EntryID   uuid.UUID `json:"entry_id"`
@@ -44,48 +76,134 @@ type ImageMeta struct {
 	ID       uuid.UUID `json:"id"`
 	IsCover  bool      `json:"is_cover"`
 	Position int       `json:"position"`
+	Hash     string    `json:"hash"` // MD5 of the image data
+}
+
+// ScoreChange is a score an entry was given, and when.
+type ScoreChange struct {
+	Score   int       `json:"score"`
+	RatedAt time.Time `json:"rated_at"`
+}
+
+// EntryWithImages is an entry together with the metadata of its images,
+// loaded by ListEntriesWithImages in a single round trip.
+type EntryWithImages struct {
+	*Entry
+	Images         []ImageMeta
+	CoverImageID   *uuid.UUID
+	CoverImageHash string
+	ImageCount     int
+}
+
+// RecentEntry is an entry the user opened, and when they last did.
+type RecentEntry struct {
+	*EntryWithImages
+	ViewedAt time.Time
+}
+
+// EntryChange is an entry id with the time the entry last changed.
+type EntryChange struct {
+	ID        uuid.UUID
+	UpdatedAt time.Time
 }
 
 type EntryRepository struct {
-	db *pgxpool.Pool
+	db       *pgxpool.Pool
+	replicas *ReplicaRouter      // nil reads from db
+	keyring  *fieldcrypt.Keyring // nil stores entry devices in plaintext
+
+	trigramSearch bool
 }
 
 func NewEntryRepository(db *pgxpool.Pool) *EntryRepository {
 	return &EntryRepository{db: db}
 }
 
+// UseTrigramSearch makes searches match the expression covered by the
+// idx_entries_search_trgm index instead of each column separately. Results
+// are the same apart from terms spanning two fields; large libraries are
+// searched through the index rather than a scan of all the user's entries.
+func (r *EntryRepository) UseTrigramSearch(enabled bool) {
+	r.trigramSearch = enabled
+}
+
+// UseReplicas sends entry listings, search and summaries to replicas. Reads
+// that back a write, like GetEntryByID and the counts checked against
+// quotas, stay on the primary.
+func (r *EntryRepository) UseReplicas(router *ReplicaRouter) {
+	r.replicas = router
+}
+
+// UseEncryption encrypts the devices entry writes are recorded with (see
+// Device) with the keyring. Devices recorded before stay readable until
+// EncryptDevices encrypts them.
+func (r *EntryRepository) UseEncryption(keyring *fieldcrypt.Keyring) {
+	r.keyring = keyring
+}
+
+func (r *EntryRepository) reader() *pgxpool.Pool {
+	return readPool(r.replicas, r.db)
+}
+
+// searchCondition matches the search pattern against title, original title
+// and description of entries aliased prefix ("" or "e.").
+func (r *EntryRepository) searchCondition(prefix, param string) string {
+	if r.trigramSearch {
+		return fmt.Sprintf("(%[1]stitle || ' ' || %[1]soriginal_title || ' ' || %[1]sdescription) ILIKE %[2]s", prefix, param)
+	}
+	return fmt.Sprintf("(%[1]stitle ILIKE %[2]s OR %[1]soriginal_title ILIKE %[2]s OR %[1]sdescription ILIKE %[2]s)", prefix, param)
+}
+
 // CreateEntry creates a new entry
 func (r *EntryRepository) CreateEntry(
 	ctx context.Context,
+	id *uuid.UUID, // nil to generate one
 	userID uuid.UUID,
 	collectionID *uuid.UUID,
 	typeID *uuid.UUID,
-	title, description string,
-	score int,
+	title, description, originalTitle, language string,
+	score, priority int,
+	visibility string,
 	date time.Time,
 	additionalFields map[string]string,
+	device *Device, // nil when the client didn't say
 ) (*Entry, error) {
 	additionalFieldsJSON, err := json.Marshal(additionalFields)
 	if err != nil {
 		return nil, fmt.Errorf("failed to marshal additional fields: %w", err)
 	}
+	deviceID, deviceName, err := r.sealDevice(device)
+	if err != nil {
+		return nil, err
+	}
 
 	query := `
-		INSERT INTO entries (user_id, collection_id, type_id, title, description, score, date, additional_fields)
-		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
-		RETURNING id, collection_id, type_id, user_id, title, description, score, date, additional_fields, created_at, updated_at
+		WITH created AS (
+			INSERT INTO entries (id, user_id, collection_id, type_id, title, description, original_title, language, score, priority, date, additional_fields, visibility)
+			VALUES (COALESCE($1::uuid, gen_random_uuid()), $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13)
+			RETURNING id, collection_id, type_id, user_id, title, original_title, language, description, score, priority, visibility, date, additional_fields, created_at, updated_at
+		), device AS (
+			INSERT INTO entry_devices (entry_id, device_id, device_name)
+			SELECT id, $14, $15 FROM created
+			WHERE $14::text IS NOT NULL
+		)
+		SELECT * FROM created
 	`
 
 	var entry Entry
 	var additionalFieldsStr string
-	err = r.db.QueryRow(ctx, query, userID, collectionID, typeID, title, description, score, date, additionalFieldsJSON).Scan(
+	err = r.db.QueryRow(ctx, query, id, userID, collectionID, typeID, title, description, originalTitle, language, score, priority, date, additionalFieldsJSON, visibility, deviceID, deviceName).Scan(
 		&entry.ID,
 		&entry.CollectionID,
 		&entry.TypeID,
 		&entry.UserID,
 		&entry.Title,
+		&entry.OriginalTitle,
+		&entry.Language,
 		&entry.Description,
 		&entry.Score,
+		&entry.Priority,
+		&entry.Visibility,
 		&entry.Date,
 		&additionalFieldsStr,
 		&entry.CreatedAt,
@@ -110,7 +228,7 @@ func (r *EntryRepository) GetEntriesByUserID(
 	limit, offset int,
 ) ([]*Entry, error) {
 	query := `
-		SELECT id, collection_id, type_id, user_id, title, description, score, date, additional_fields, created_at, updated_at
+		SELECT id, collection_id, type_id, user_id, title, original_title, language, description, score, priority, visibility, date, additional_fields, created_at, updated_at
 		FROM entries
 		WHERE user_id = $1
 		AND ($2::uuid IS NULL OR collection_id = $2)
@@ -134,8 +252,12 @@ func (r *EntryRepository) GetEntriesByUserID(
 			&entry.TypeID,
 			&entry.UserID,
 			&entry.Title,
+			&entry.OriginalTitle,
+			&entry.Language,
 			&entry.Description,
 			&entry.Score,
+			&entry.Priority,
+			&entry.Visibility,
 			&entry.Date,
 			&additionalFieldsStr,
 			&entry.CreatedAt,
@@ -159,13 +281,597 @@ func (r *EntryRepository) GetEntriesByUserID(
 	return entries, nil
 }
 
+// entryWithImagesColumns and entryImagesLateralJoin select an entry (aliased e)
+// together with its image metadata aggregated as JSON. Scan with scanEntryWithImages.
+const (
+	entryWithImagesColumns = `e.id, e.collection_id, e.type_id, e.user_id, e.title, e.original_title, e.language, e.description, e.score, e.priority, e.visibility, e.date,
+			e.additional_fields, e.created_at, e.updated_at,
+			COALESCE(img.metas, '[]'::json) AS image_metas`
+	entryImagesLateralJoin = `LEFT JOIN LATERAL (
+			SELECT json_agg(
+				json_build_object('id', i.id, 'is_cover', i.is_cover, 'position', i.position, 'hash', i.content_hash)
+				ORDER BY i.position ASC
+			) AS metas
+			FROM entry_images i
+			WHERE i.entry_id = e.id
+		) img ON TRUE`
+)
+
+// EntrySort is the order of an entry list.
+type EntrySort string
+
+const (
+	// EntrySortCreated lists the newest entries first.
+	EntrySortCreated EntrySort = "created"
+	// EntrySortManual lists entries by their position in the collection,
+	// unranked entries last and newest first.
+	EntrySortManual EntrySort = "manual"
+	// EntrySortPriority lists the highest priority first, newest first
+	// within a priority.
+	EntrySortPriority EntrySort = "priority"
+	// EntrySortTitle lists entries alphabetically by title_sort, which
+	// ignores a leading "The", "A" or "An", case and accents.
+	EntrySortTitle EntrySort = "title"
+)
+
+func (s EntrySort) orderBy() string {
+	switch s {
+	case EntrySortManual:
+		return "e.position ASC NULLS LAST, e.created_at DESC"
+	case EntrySortPriority:
+		return "e.priority DESC, e.created_at DESC"
+	case EntrySortTitle:
+		return "e.title_sort ASC, e.title ASC, e.created_at DESC"
+	}
+	return "e.created_at DESC"
+}
+
+// EntryFilter narrows and orders an entry list. Zero values leave a filter
+// out.
+type EntryFilter struct {
+	CollectionID *uuid.UUID
+	MinPriority  int
+	Sort         EntrySort
+}
+
+// ListEntriesWithImages retrieves entries for a user together with their image
+// metadata in one query. Images are aggregated per entry via a lateral subquery,
+// so the cover image id and image count come back without a second round trip.
+func (r *EntryRepository) ListEntriesWithImages(
+	ctx context.Context,
+	userID uuid.UUID,
+	filter EntryFilter,
+	limit, offset int,
+) ([]*EntryWithImages, error) {
+	query := `
+		SELECT ` + entryWithImagesColumns + `
+		FROM entries e
+		` + entryImagesLateralJoin + `
+		WHERE e.user_id = $1
+		AND ($2::uuid IS NULL OR e.collection_id = $2)
+		AND e.priority >= $5
+		ORDER BY ` + filter.Sort.orderBy() + `
+		LIMIT $3 OFFSET $4
+	`
+
+	rows, err := r.reader().Query(ctx, query, userID, filter.CollectionID, limit, offset, filter.MinPriority)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query entries: %w", err)
+	}
+	defer rows.Close()
+
+	var entries []*EntryWithImages
+	for rows.Next() {
+		entry, err := scanEntryWithImages(rows)
+		if err != nil {
+			return nil, err
+		}
+		entries = append(entries, entry)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating entries: %w", err)
+	}
+
+	return entries, nil
+}
+
+// ListWorkspaceEntries retrieves entries in the collections placed in a
+// shared workspace, optionally narrowed to one collection: the viewer's own
+// entries and the shared or public entries of the other members.
+func (r *EntryRepository) ListWorkspaceEntries(
+	ctx context.Context,
+	workspaceID, viewerID uuid.UUID,
+	filter EntryFilter,
+	limit, offset int,
+) ([]*EntryWithImages, error) {
+	query := `
+		SELECT ` + entryWithImagesColumns + `
+		FROM entries e
+		JOIN collections c ON c.id = e.collection_id
+		` + entryImagesLateralJoin + `
+		WHERE c.workspace_id = $1
+		AND (e.user_id = $6 OR e.visibility IN ('shared', 'public'))
+		AND ($2::uuid IS NULL OR e.collection_id = $2)
+		AND e.priority >= $5
+		ORDER BY ` + filter.Sort.orderBy() + `
+		LIMIT $3 OFFSET $4
+	`
+
+	rows, err := r.reader().Query(ctx, query, workspaceID, filter.CollectionID, limit, offset, filter.MinPriority, viewerID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query workspace entries: %w", err)
+	}
+	defer rows.Close()
+
+	var entries []*EntryWithImages
+	for rows.Next() {
+		entry, err := scanEntryWithImages(rows)
+		if err != nil {
+			return nil, err
+		}
+		entries = append(entries, entry)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating entries: %w", err)
+	}
+
+	return entries, nil
+}
+
+// EntryFields selects the fields a partial entry list reads, keyed by the names
+// in EntryFieldNames. The id is always read.
+type EntryFields map[string]bool
+
+// EntryFieldNames are the fields clients can request with ?fields=. "images"
+// is the metadata of all images, "cover" only the cover image id.
+var EntryFieldNames = []string{
+	"id", "collection_id", "type_id", "title", "original_title", "language", "description",
+	"score", "priority", "visibility", "date", "additional_fields", "images", "cover", "created_at", "updated_at",
+}
+
+// coversOnly reports whether the images are read for their cover alone, as
+// grids that only render covers ask.
+func (f EntryFields) coversOnly() bool {
+	return f["cover"] && !f["images"]
+}
+
+// entryFieldColumns are the selectable columns of entries aliased e, in select order.
+var entryFieldColumns = []struct {
+	field  string
+	column string
+}{
+	{"collection_id", "e.collection_id"},
+	{"type_id", "e.type_id"},
+	{"title", "e.title"},
+	{"original_title", "e.original_title"},
+	{"language", "e.language"},
+	{"description", "e.description"},
+	{"score", "e.score"},
+	{"priority", "e.priority"},
+	{"visibility", "e.visibility"},
+	{"date", "e.date"},
+	{"additional_fields", "e.additional_fields"},
+	{"created_at", "e.created_at"},
+	{"updated_at", "e.updated_at"},
+}
+
+// ListEntryFields is ListEntriesWithImages reading only the given fields;
+// unread fields are left zero. Image metadata is only read when "images" or
+// "cover" is requested, and for "cover" alone just each entry's cover.
+func (r *EntryRepository) ListEntryFields(
+	ctx context.Context,
+	userID uuid.UUID,
+	filter EntryFilter,
+	fields EntryFields,
+	limit, offset int,
+) ([]*EntryWithImages, error) {
+	conditions := `
+		WHERE e.user_id = $1
+		AND ($2::uuid IS NULL OR e.collection_id = $2)
+		AND e.priority >= $5
+		ORDER BY ` + filter.Sort.orderBy() + `
+		LIMIT $3 OFFSET $4
+	`
+
+	return r.queryEntryFields(ctx, fields, conditions, userID, filter.CollectionID, limit, offset, filter.MinPriority)
+}
+
+// SearchEntryFields is SearchEntries reading only the given fields, like
+// ListEntryFields.
+func (r *EntryRepository) SearchEntryFields(
+	ctx context.Context,
+	userID uuid.UUID,
+	searchQuery string,
+	fields EntryFields,
+	limit, offset int,
+) ([]*EntryWithImages, error) {
+	conditions := `
+		WHERE e.user_id = $1
+		AND ` + r.searchCondition("e.", "$2") + `
+		ORDER BY e.created_at DESC
+		LIMIT $3 OFFSET $4
+	`
+
+	searchPattern := "%" + searchQuery + "%"
+	return r.queryEntryFields(ctx, fields, conditions, userID, searchPattern, limit, offset)
+}
+
+// queryEntryFields selects fields from entries aliased e, filtered and ordered
+// by conditions.
+func (r *EntryRepository) queryEntryFields(
+	ctx context.Context,
+	fields EntryFields,
+	conditions string,
+	args ...interface{},
+) ([]*EntryWithImages, error) {
+	columns := []string{"e.id"}
+	for _, c := range entryFieldColumns {
+		if fields[c.field] {
+			columns = append(columns, c.column)
+		}
+	}
+	joins := ""
+	if fields["images"] {
+		columns = append(columns, "COALESCE(img.metas, '[]'::json) AS image_metas")
+		joins = entryImagesLateralJoin
+	}
+
+	query := "SELECT " + strings.Join(columns, ", ") + "\n\t\tFROM entries e\n\t\t" + joins + conditions
+
+	rows, err := r.reader().Query(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query entries: %w", err)
+	}
+	defer rows.Close()
+
+	var entries []*EntryWithImages
+	for rows.Next() {
+		var entry Entry
+		var additionalFieldsStr string
+		var imageMetasStr string
+
+		dest := []interface{}{&entry.ID}
+		for _, c := range entryFieldColumns {
+			if !fields[c.field] {
+				continue
+			}
+			d, err := entryFieldDest(c.field, &entry, &additionalFieldsStr)
+			if err != nil {
+				return nil, err
+			}
+			dest = append(dest, d)
+		}
+		if fields["images"] {
+			dest = append(dest, &imageMetasStr)
+		}
+
+		if err := rows.Scan(dest...); err != nil {
+			return nil, fmt.Errorf("failed to scan entry: %w", err)
+		}
+
+		if fields["additional_fields"] {
+			if err := json.Unmarshal([]byte(additionalFieldsStr), &entry.AdditionalFields); err != nil {
+				return nil, fmt.Errorf("failed to unmarshal additional fields: %w", err)
+			}
+		}
+
+		var metas []ImageMeta
+		if fields["images"] {
+			if err := json.Unmarshal([]byte(imageMetasStr), &metas); err != nil {
+				return nil, fmt.Errorf("failed to unmarshal image metas: %w", err)
+			}
+		}
+
+		entries = append(entries, newEntryWithImages(&entry, metas))
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating entries: %w", err)
+	}
+
+	if fields.coversOnly() && len(entries) > 0 {
+		ids := make([]uuid.UUID, len(entries))
+		for i, e := range entries {
+			ids[i] = e.ID
+		}
+		covers, err := r.GetImageMetasByEntryIDs(ctx, ids, true)
+		if err != nil {
+			return nil, err
+		}
+		for i, e := range entries {
+			entries[i] = newEntryWithImages(e.Entry, covers[e.ID])
+		}
+	}
+
+	return entries, nil
+}
+
+// entryFieldDest returns the scan destination for a column in entryFieldColumns.
+func entryFieldDest(field string, entry *Entry, additionalFields *string) (interface{}, error) {
+	switch field {
+	case "collection_id":
+		return &entry.CollectionID, nil
+	case "type_id":
+		return &entry.TypeID, nil
+	case "title":
+		return &entry.Title, nil
+	case "original_title":
+		return &entry.OriginalTitle, nil
+	case "language":
+		return &entry.Language, nil
+	case "description":
+		return &entry.Description, nil
+	case "score":
+		return &entry.Score, nil
+	case "priority":
+		return &entry.Priority, nil
+	case "visibility":
+		return &entry.Visibility, nil
+	case "date":
+		return &entry.Date, nil
+	case "additional_fields":
+		return additionalFields, nil
+	case "created_at":
+		return &entry.CreatedAt, nil
+	case "updated_at":
+		return &entry.UpdatedAt, nil
+	}
+	return nil, fmt.Errorf("unknown entry field %q", field)
+}
+
+// ListEntriesForExport retrieves a user's entries with image metadata for an
+// export, oldest first. A nil collectionID, year or entryIDs leaves that
+// filter out.
+func (r *EntryRepository) ListEntriesForExport(
+	ctx context.Context,
+	userID uuid.UUID,
+	collectionID *uuid.UUID,
+	year *int,
+	entryIDs []uuid.UUID,
+	limit int,
+) ([]*EntryWithImages, error) {
+	query := `
+		SELECT ` + entryWithImagesColumns + `
+		FROM entries e
+		` + entryImagesLateralJoin + `
+		WHERE e.user_id = $1
+		AND ($2::uuid IS NULL OR e.collection_id = $2)
+		AND ($3::int IS NULL OR (e.date >= make_date($3, 1, 1) AND e.date < make_date($3 + 1, 1, 1)))
+		AND ($4::uuid[] IS NULL OR e.id = ANY($4))
+		ORDER BY e.date ASC, e.created_at ASC
+		LIMIT $5
+	`
+
+	rows, err := r.db.Query(ctx, query, userID, collectionID, year, entryIDs, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query entries: %w", err)
+	}
+	defer rows.Close()
+
+	var entries []*EntryWithImages
+	for rows.Next() {
+		entry, err := scanEntryWithImages(rows)
+		if err != nil {
+			return nil, err
+		}
+		entries = append(entries, entry)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating entries: %w", err)
+	}
+
+	return entries, nil
+}
+
+// ListPublicEntriesByIDs retrieves a user's public entries with image
+// metadata in the order of ids. Entries outside collectionIDs are left out.
+func (r *EntryRepository) ListPublicEntriesByIDs(
+	ctx context.Context,
+	userID uuid.UUID,
+	ids []uuid.UUID,
+	collectionIDs []uuid.UUID,
+) ([]*EntryWithImages, error) {
+	query := `
+		SELECT ` + entryWithImagesColumns + `
+		FROM entries e
+		` + entryImagesLateralJoin + `
+		WHERE e.user_id = $1 AND e.id = ANY($2) AND e.collection_id = ANY($3) AND e.visibility = 'public'
+		ORDER BY array_position($2, e.id)
+	`
+
+	rows, err := r.db.Query(ctx, query, userID, ids, collectionIDs)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query entries: %w", err)
+	}
+	defer rows.Close()
+
+	var entries []*EntryWithImages
+	for rows.Next() {
+		entry, err := scanEntryWithImages(rows)
+		if err != nil {
+			return nil, err
+		}
+		entries = append(entries, entry)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating entries: %w", err)
+	}
+
+	return entries, nil
+}
+
+// ListPublicCollectionEntries retrieves up to limit public entries of a
+// collection with image metadata, most recent first.
+func (r *EntryRepository) ListPublicCollectionEntries(
+	ctx context.Context,
+	collectionID uuid.UUID,
+	limit int,
+) ([]*EntryWithImages, error) {
+	query := `
+		SELECT ` + entryWithImagesColumns + `
+		FROM entries e
+		` + entryImagesLateralJoin + `
+		WHERE e.collection_id = $1 AND e.visibility = 'public'
+		ORDER BY e.date DESC, e.created_at DESC, e.id DESC
+		LIMIT $2
+	`
+
+	rows, err := r.db.Query(ctx, query, collectionID, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query entries: %w", err)
+	}
+	defer rows.Close()
+
+	var entries []*EntryWithImages
+	for rows.Next() {
+		entry, err := scanEntryWithImages(rows)
+		if err != nil {
+			return nil, err
+		}
+		entries = append(entries, entry)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating entries: %w", err)
+	}
+
+	return entries, nil
+}
+
+// RecordEntryView notes that the user opened one of their entries now and
+// returns when. Entries of other users are not found.
+func (r *EntryRepository) RecordEntryView(ctx context.Context, userID, entryID uuid.UUID) (time.Time, error) {
+	query := `
+		INSERT INTO entry_views (user_id, entry_id)
+		SELECT user_id, id FROM entries WHERE id = $2 AND user_id = $1
+		ON CONFLICT (user_id, entry_id) DO UPDATE SET viewed_at = NOW()
+		RETURNING viewed_at
+	`
+
+	var viewedAt time.Time
+	if err := r.db.QueryRow(ctx, query, userID, entryID).Scan(&viewedAt); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return time.Time{}, ErrEntryNotFound
+		}
+		return time.Time{}, fmt.Errorf("failed to record entry view: %w", err)
+	}
+
+	return viewedAt, nil
+}
+
+// ListRecentEntries retrieves the entries the user opened most recently,
+// optionally only those in one collection, with image metadata.
+func (r *EntryRepository) ListRecentEntries(
+	ctx context.Context,
+	userID uuid.UUID,
+	collectionID *uuid.UUID,
+	limit int,
+) ([]*RecentEntry, error) {
+	query := `
+		SELECT ` + entryWithImagesColumns + `, v.viewed_at
+		FROM entry_views v
+		JOIN entries e ON e.id = v.entry_id
+		` + entryImagesLateralJoin + `
+		WHERE v.user_id = $1
+		AND ($2::uuid IS NULL OR e.collection_id = $2)
+		ORDER BY v.viewed_at DESC
+		LIMIT $3
+	`
+
+	rows, err := r.db.Query(ctx, query, userID, collectionID, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query recent entries: %w", err)
+	}
+	defer rows.Close()
+
+	entries := []*RecentEntry{}
+	for rows.Next() {
+		var viewedAt time.Time
+		entry, err := scanEntryWithImages(rows, &viewedAt)
+		if err != nil {
+			return nil, err
+		}
+		entries = append(entries, &RecentEntry{EntryWithImages: entry, ViewedAt: viewedAt})
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating recent entries: %w", err)
+	}
+
+	return entries, nil
+}
+
+// scanEntryWithImages scans a row selected with entryWithImagesColumns,
+// followed by the columns scanned into extra.
+func scanEntryWithImages(rows pgx.Rows, extra ...interface{}) (*EntryWithImages, error) {
+	var entry Entry
+	var additionalFieldsStr string
+	var imageMetasStr string
+	dest := []interface{}{
+		&entry.ID,
+		&entry.CollectionID,
+		&entry.TypeID,
+		&entry.UserID,
+		&entry.Title,
+		&entry.OriginalTitle,
+		&entry.Language,
+		&entry.Description,
+		&entry.Score,
+		&entry.Priority,
+		&entry.Visibility,
+		&entry.Date,
+		&additionalFieldsStr,
+		&entry.CreatedAt,
+		&entry.UpdatedAt,
+		&imageMetasStr,
+	}
+	if err := rows.Scan(append(dest, extra...)...); err != nil {
+		return nil, fmt.Errorf("failed to scan entry: %w", err)
+	}
+
+	if err := json.Unmarshal([]byte(additionalFieldsStr), &entry.AdditionalFields); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal additional fields: %w", err)
+	}
+
+	var metas []ImageMeta
+	if err := json.Unmarshal([]byte(imageMetasStr), &metas); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal image metas: %w", err)
+	}
+
+	return newEntryWithImages(&entry, metas), nil
+}
+
+// newEntryWithImages picks the cover image (the one flagged as cover, otherwise
+// the first by position) and counts the images.
+func newEntryWithImages(entry *Entry, metas []ImageMeta) *EntryWithImages {
+	result := &EntryWithImages{
+		Entry:      entry,
+		Images:     metas,
+		ImageCount: len(metas),
+	}
+	for i := range metas {
+		if metas[i].IsCover {
+			result.CoverImageID, result.CoverImageHash = &metas[i].ID, metas[i].Hash
+			break
+		}
+	}
+	if result.CoverImageID == nil && len(metas) > 0 {
+		result.CoverImageID, result.CoverImageHash = &metas[0].ID, metas[0].Hash
+	}
+	return result
+}
+
 // GetEntryByID retrieves a single entry by ID
 func (r *EntryRepository) GetEntryByID(
 	ctx context.Context,
 	id uuid.UUID,
 ) (*Entry, error) {
 	query := `
-		SELECT id, collection_id, type_id, user_id, title, description, score, date, additional_fields, created_at, updated_at
+		SELECT id, collection_id, type_id, user_id, title, original_title, language, description, score, priority, visibility, date, additional_fields, created_at, updated_at
 		FROM entries
 		WHERE id = $1
 	`
@@ -178,8 +884,12 @@ func (r *EntryRepository) GetEntryByID(
 		&entry.TypeID,
 		&entry.UserID,
 		&entry.Title,
+		&entry.OriginalTitle,
+		&entry.Language,
 		&entry.Description,
 		&entry.Score,
+		&entry.Priority,
+		&entry.Visibility,
 		&entry.Date,
 		&additionalFieldsStr,
 		&entry.CreatedAt,
@@ -199,56 +909,316 @@ func (r *EntryRepository) GetEntryByID(
 	return &entry, nil
 }
 
-// UpdateEntry updates an entry
+// UpdateEntry updates an entry and records the device it came from. It
+// returns the entry's previous write alongside, so callers can tell edits
+// from two devices made in quick succession.
 func (r *EntryRepository) UpdateEntry(
 	ctx context.Context,
 	id uuid.UUID,
 	collectionID *uuid.UUID,
 	typeID *uuid.UUID,
 	title, description string,
+	originalTitle, language *string, // nil keeps the current value
 	score int,
+	priority *int, // nil keeps the current priority
+	visibility *string, // nil keeps the current visibility
 	date time.Time,
 	additionalFields map[string]string,
-) (*Entry, error) {
+	device *Device, // nil when the client didn't say
+) (*Entry, *EntryWrite, error) {
 	additionalFieldsJSON, err := json.Marshal(additionalFields)
 	if err != nil {
-		return nil, fmt.Errorf("failed to marshal additional fields: %w", err)
+		return nil, nil, fmt.Errorf("failed to marshal additional fields: %w", err)
+	}
+	deviceID, deviceName, err := r.sealDevice(device)
+	if err != nil {
+		return nil, nil, err
 	}
 
+	// The device CTEs run on the statement's snapshot, so prev still sees
+	// the device of the write before
 	query := `
-		UPDATE entries
-		SET collection_id = $2, type_id = $3, title = $4, description = $5, score = $6, date = $7, additional_fields = $8, updated_at = NOW()
-		WHERE id = $1
-		RETURNING id, collection_id, type_id, user_id, title, description, score, date, additional_fields, created_at, updated_at
+		WITH prev AS (
+			SELECT e.id, e.updated_at, d.device_id, d.device_name
+			FROM entries e
+			LEFT JOIN entry_devices d ON d.entry_id = e.id
+			WHERE e.id = $1
+			FOR UPDATE OF e
+		), updated AS (
+			UPDATE entries e
+			SET collection_id = $2, type_id = $3, title = $4, description = $5, score = $6, date = $7, additional_fields = $8, updated_at = NOW(),
+				priority = COALESCE($9, e.priority), original_title = COALESCE($10, e.original_title), language = COALESCE($11, e.language),
+				visibility = COALESCE($12, e.visibility),
+				position = CASE WHEN e.collection_id IS DISTINCT FROM $2 THEN NULL ELSE e.position END
+			FROM prev
+			WHERE e.id = prev.id
+			RETURNING e.id, e.collection_id, e.type_id, e.user_id, e.title, e.original_title, e.language, e.description, e.score, e.priority, e.visibility, e.date, e.additional_fields, e.created_at, e.updated_at,
+				prev.updated_at AS prev_updated_at, prev.device_id AS prev_device_id, prev.device_name AS prev_device_name
+		), recorded AS (
+			INSERT INTO entry_devices (entry_id, device_id, device_name)
+			SELECT id, $13, $14 FROM updated
+			WHERE $13::text IS NOT NULL
+			ON CONFLICT (entry_id) DO UPDATE SET device_id = EXCLUDED.device_id, device_name = EXCLUDED.device_name
+		), forgotten AS (
+			DELETE FROM entry_devices
+			WHERE entry_id = (SELECT id FROM updated) AND $13::text IS NULL
+		)
+		SELECT * FROM updated
 	`
 
 	var entry Entry
 	var additionalFieldsStr string
-	err = r.db.QueryRow(ctx, query, id, collectionID, typeID, title, description, score, date, additionalFieldsJSON).Scan(
+	var prev EntryWrite
+	var prevDeviceID, prevDeviceName *string
+	err = r.db.QueryRow(ctx, query, id, collectionID, typeID, title, description, score, date, additionalFieldsJSON, priority, originalTitle, language, visibility, deviceID, deviceName).Scan(
 		&entry.ID,
 		&entry.CollectionID,
 		&entry.TypeID,
 		&entry.UserID,
 		&entry.Title,
+		&entry.OriginalTitle,
+		&entry.Language,
 		&entry.Description,
 		&entry.Score,
+		&entry.Priority,
+		&entry.Visibility,
 		&entry.Date,
 		&additionalFieldsStr,
 		&entry.CreatedAt,
 		&entry.UpdatedAt,
+		&prev.At,
+		&prevDeviceID,
+		&prevDeviceName,
 	)
 	if err != nil {
 		if errors.Is(err, pgx.ErrNoRows) {
-			return nil, ErrEntryNotFound
+			return nil, nil, ErrEntryNotFound
 		}
-		return nil, fmt.Errorf("failed to update entry: %w", err)
+		return nil, nil, fmt.Errorf("failed to update entry: %w", err)
 	}
 
 	if err := json.Unmarshal([]byte(additionalFieldsStr), &entry.AdditionalFields); err != nil {
-		return nil, fmt.Errorf("failed to unmarshal additional fields: %w", err)
+		return nil, nil, fmt.Errorf("failed to unmarshal additional fields: %w", err)
 	}
 
-	return &entry, nil
+	if prevDeviceID != nil {
+		prev.Device, err = r.openDevice(ctx, *prevDeviceID, *prevDeviceName)
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+
+	return &entry, &prev, nil
+}
+
+// sealDevice returns the entry_devices columns of a write's device,
+// encrypted when encryption is on; nil for an unknown device.
+func (r *EntryRepository) sealDevice(device *Device) (*string, *string, error) {
+	if device == nil {
+		return nil, nil, nil
+	}
+	id, name := device.ID, device.Name
+	if r.keyring != nil {
+		var err error
+		if id, err = r.keyring.Encrypt(id); err != nil {
+			return nil, nil, fmt.Errorf("failed to encrypt device: %w", err)
+		}
+		if name != "" {
+			if name, err = r.keyring.Encrypt(name); err != nil {
+				return nil, nil, fmt.Errorf("failed to encrypt device: %w", err)
+			}
+		}
+	}
+	return &id, &name, nil
+}
+
+// openDevice decrypts entry_devices columns, which may be plaintext.
+func (r *EntryRepository) openDevice(ctx context.Context, id, name string) (*Device, error) {
+	if r.keyring == nil {
+		return &Device{ID: id, Name: name}, nil
+	}
+	id, err := r.keyring.Decrypt(ctx, id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decrypt device: %w", err)
+	}
+	if name, err = r.keyring.Decrypt(ctx, name); err != nil {
+		return nil, fmt.Errorf("failed to decrypt device: %w", err)
+	}
+	return &Device{ID: id, Name: name}, nil
+}
+
+// EncryptDevices encrypts up to limit entry devices that are plaintext or
+// encrypted with a retired data key. Returns how many it encrypted; call it
+// until that is 0.
+func (r *EntryRepository) EncryptDevices(ctx context.Context, limit int) (int, error) {
+	rows, err := r.db.Query(ctx, `
+		SELECT entry_id, device_id, device_name
+		FROM entry_devices
+		WHERE device_id NOT LIKE $1 || '%' OR (device_name <> '' AND device_name NOT LIKE $1 || '%')
+		ORDER BY entry_id
+		LIMIT $2
+	`, r.keyring.ActivePrefix(), limit)
+	if err != nil {
+		return 0, fmt.Errorf("failed to query entry devices to encrypt: %w", err)
+	}
+	ids, devices := []uuid.UUID{}, []Device{}
+	for rows.Next() {
+		var id uuid.UUID
+		var d Device
+		if err := rows.Scan(&id, &d.ID, &d.Name); err != nil {
+			rows.Close()
+			return 0, fmt.Errorf("failed to scan entry device: %w", err)
+		}
+		ids = append(ids, id)
+		devices = append(devices, d)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, fmt.Errorf("error iterating entry devices: %w", err)
+	}
+
+	encrypted := 0
+	for i, id := range ids {
+		device, err := r.openDevice(ctx, devices[i].ID, devices[i].Name)
+		if err != nil {
+			return encrypted, fmt.Errorf("failed to decrypt device of entry %s: %w", id, err)
+		}
+		deviceID, deviceName, err := r.sealDevice(device)
+		if err != nil {
+			return encrypted, err
+		}
+		result, err := r.db.Exec(ctx,
+			`UPDATE entry_devices SET device_id = $2, device_name = $3 WHERE entry_id = $1`, id, *deviceID, *deviceName)
+		if err != nil {
+			return encrypted, fmt.Errorf("failed to encrypt device of entry %s: %w", id, err)
+		}
+		encrypted += int(result.RowsAffected())
+	}
+
+	return encrypted, nil
+}
+
+// SuggestCollection returns the collection the user filed most of their
+// latest history entries of the type in (untyped entries for a nil type), or
+// nil without any. Only collections the user can still add the entry to
+// count: their own and their workspaces', that accept the type.
+func (r *EntryRepository) SuggestCollection(ctx context.Context, userID uuid.UUID, typeID *uuid.UUID, history int) (*uuid.UUID, error) {
+	query := `
+		WITH recent AS (
+			SELECT collection_id, created_at
+			FROM entries
+			WHERE user_id = $1
+			AND (type_id = $2 OR ($2::uuid IS NULL AND type_id IS NULL))
+			AND collection_id IS NOT NULL
+			ORDER BY created_at DESC
+			LIMIT $3
+		)
+		SELECT c.id
+		FROM recent e
+		JOIN collections c ON c.id = e.collection_id
+		WHERE (c.user_id = $1 OR EXISTS(
+			SELECT 1 FROM workspace_members m WHERE m.workspace_id = c.workspace_id AND m.user_id = $1
+		))
+		AND (COALESCE(cardinality(c.allowed_type_ids), 0) = 0 OR $2 = ANY(c.allowed_type_ids))
+		GROUP BY c.id
+		ORDER BY COUNT(*) DESC, MAX(e.created_at) DESC
+		LIMIT 1
+	`
+
+	var id uuid.UUID
+	err := r.db.QueryRow(ctx, query, userID, typeID, history).Scan(&id)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to suggest collection: %w", err)
+	}
+
+	return &id, nil
+}
+
+// CountEntries returns how many entries a user has
+func (r *EntryRepository) CountEntries(ctx context.Context, userID uuid.UUID) (int, error) {
+	query := `SELECT COUNT(*) FROM entries WHERE user_id = $1`
+
+	var count int
+	if err := r.db.QueryRow(ctx, query, userID).Scan(&count); err != nil {
+		return 0, fmt.Errorf("failed to count entries: %w", err)
+	}
+
+	return count, nil
+}
+
+// CountEntriesByIDs returns how many of ids are entries of the user.
+func (r *EntryRepository) CountEntriesByIDs(ctx context.Context, userID uuid.UUID, ids []uuid.UUID) (int, error) {
+	query := `SELECT COUNT(*) FROM entries WHERE user_id = $1 AND id = ANY($2)`
+
+	var count int
+	if err := r.db.QueryRow(ctx, query, userID, ids).Scan(&count); err != nil {
+		return 0, fmt.Errorf("failed to count entries: %w", err)
+	}
+
+	return count, nil
+}
+
+// GetEntriesSummary returns how many entries a user has, optionally in one
+// collection, and when the most recently changed of them was updated (nil
+// without entries).
+func (r *EntryRepository) GetEntriesSummary(
+	ctx context.Context,
+	userID uuid.UUID,
+	collectionID *uuid.UUID,
+) (int, *time.Time, error) {
+	query := `
+		SELECT COUNT(*), MAX(updated_at)
+		FROM entries
+		WHERE user_id = $1
+		AND ($2::uuid IS NULL OR collection_id = $2)
+	`
+
+	var count int
+	var lastUpdated *time.Time
+	if err := r.reader().QueryRow(ctx, query, userID, collectionID).Scan(&count, &lastUpdated); err != nil {
+		return 0, nil, fmt.Errorf("failed to summarize entries: %w", err)
+	}
+
+	return count, lastUpdated, nil
+}
+
+// ListEntriesChangedSince returns the entries a user created or updated after
+// since, oldest change first.
+func (r *EntryRepository) ListEntriesChangedSince(
+	ctx context.Context,
+	userID uuid.UUID,
+	since time.Time,
+) ([]EntryChange, error) {
+	query := `
+		SELECT id, updated_at
+		FROM entries
+		WHERE user_id = $1 AND updated_at > $2
+		ORDER BY updated_at ASC
+	`
+
+	rows, err := r.db.Query(ctx, query, userID, since)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query changed entries: %w", err)
+	}
+	defer rows.Close()
+
+	changes := []EntryChange{}
+	for rows.Next() {
+		var c EntryChange
+		if err := rows.Scan(&c.ID, &c.UpdatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan entry change: %w", err)
+		}
+		changes = append(changes, c)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating changed entries: %w", err)
+	}
+
+	return changes, nil
 }
 
 // DeleteEntry deletes an entry
@@ -296,8 +1266,9 @@ func (r *EntryRepository) SaveEntryImages(
 			INSERT INTO entry_images (entry_id, image_data, is_cover, position)
 			VALUES ($1, $2, $3, $4)
 		`
-		for _, img := range images {
-			_, err = tx.Exec(ctx, insertQuery, entryID, img.ImageData, img.IsCover, img.Position)
+		cover := coverIndex(images)
+		for i, img := range images {
+			_, err = tx.Exec(ctx, insertQuery, entryID, img.ImageData, i == cover, img.Position)
 			if err != nil {
 				return fmt.Errorf("failed to insert image: %w", err)
 			}
@@ -311,6 +1282,57 @@ func (r *EntryRepository) SaveEntryImages(
 	return nil
 }
 
+// coverIndex picks the one cover idx_entry_images_one_cover allows: the first
+// image flagged as cover, otherwise the first image.
+func coverIndex(images []EntryImage) int {
+	for i, img := range images {
+		if img.IsCover {
+			return i
+		}
+	}
+	return 0
+}
+
+// SetEntryCover makes imageID the entry's cover and unflags the previous one.
+// Fails with ErrImageNotFound if the image doesn't belong to the entry.
+func (r *EntryRepository) SetEntryCover(ctx context.Context, entryID, imageID uuid.UUID) error {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to start transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	// Unique indexes are checked row by row, so clear the old cover first
+	_, err = tx.Exec(ctx,
+		`UPDATE entry_images SET is_cover = false WHERE entry_id = $1 AND is_cover AND id <> $2`,
+		entryID, imageID,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to clear cover: %w", err)
+	}
+
+	result, err := tx.Exec(ctx,
+		`UPDATE entry_images SET is_cover = true WHERE entry_id = $1 AND id = $2`,
+		entryID, imageID,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to set cover: %w", err)
+	}
+	if result.RowsAffected() == 0 {
+		return ErrImageNotFound
+	}
+
+	// Bump the entry so sync clients pick up the new cover
+	if _, err := tx.Exec(ctx, `UPDATE entries SET updated_at = NOW() WHERE id = $1`, entryID); err != nil {
+		return fmt.Errorf("failed to touch entry: %w", err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return nil
+}
+
 // GetEntryImages retrieves images for an entry
 func (r *EntryRepository) GetEntryImages(
 	ctx context.Context,
@@ -359,7 +1381,7 @@ func (r *EntryRepository) GetEntryImageMetas(
 	entryID uuid.UUID,
 ) ([]ImageMeta, error) {
 	query := `
-		SELECT id, is_cover, position FROM entry_images
+		SELECT id, is_cover, position, content_hash FROM entry_images
 		WHERE entry_id = $1
 		ORDER BY position ASC
 	`
@@ -373,7 +1395,7 @@ func (r *EntryRepository) GetEntryImageMetas(
 	var metas []ImageMeta
 	for rows.Next() {
 		var m ImageMeta
-		if err := rows.Scan(&m.ID, &m.IsCover, &m.Position); err != nil {
+		if err := rows.Scan(&m.ID, &m.IsCover, &m.Position, &m.Hash); err != nil {
 			return nil, fmt.Errorf("failed to scan image meta: %w", err)
 		}
 		metas = append(metas, m)
@@ -382,6 +1404,36 @@ func (r *EntryRepository) GetEntryImageMetas(
 	return metas, rows.Err()
 }
 
+// GetScoreHistory returns every score the entry has had, oldest first; the
+// last one is its current score.
+func (r *EntryRepository) GetScoreHistory(
+	ctx context.Context,
+	entryID uuid.UUID,
+) ([]ScoreChange, error) {
+	query := `
+		SELECT score, rated_at FROM entry_scores
+		WHERE entry_id = $1
+		ORDER BY rated_at ASC, id ASC
+	`
+
+	rows, err := r.db.Query(ctx, query, entryID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query score history: %w", err)
+	}
+	defer rows.Close()
+
+	var history []ScoreChange
+	for rows.Next() {
+		var c ScoreChange
+		if err := rows.Scan(&c.Score, &c.RatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan score change: %w", err)
+		}
+		history = append(history, c)
+	}
+
+	return history, rows.Err()
+}
+
 // GetImageByID retrieves a single image by its ID
 func (r *EntryRepository) GetImageByID(
 	ctx context.Context,
@@ -412,22 +1464,35 @@ func (r *EntryRepository) GetImageByID(
 	return &img, nil
 }
 
-// GetImageMetasByEntryIDs returns a map of entry ID -> image metadata for multiple entries
+// GetImageMetasByEntryIDs returns a map of entry ID -> image metadata for
+// multiple entries, by position. With coversOnly each entry gets just its
+// cover (the one flagged as cover, otherwise the first by position), which
+// is all list pages that render covers need.
 func (r *EntryRepository) GetImageMetasByEntryIDs(
 	ctx context.Context,
 	entryIDs []uuid.UUID,
+	coversOnly bool,
 ) (map[uuid.UUID][]ImageMeta, error) {
 	if len(entryIDs) == 0 {
 		return make(map[uuid.UUID][]ImageMeta), nil
 	}
 
+	// Images of an entry may share a position after a concurrent reorder;
+	// the id keeps their order stable.
 	query := `
-		SELECT entry_id, id, is_cover, position FROM entry_images
+		SELECT entry_id, id, is_cover, position, content_hash FROM entry_images
 		WHERE entry_id = ANY($1)
-		ORDER BY entry_id, position ASC
+		ORDER BY entry_id, position ASC, id ASC
 	`
+	if coversOnly {
+		query = `
+			SELECT DISTINCT ON (entry_id) entry_id, id, is_cover, position, content_hash FROM entry_images
+			WHERE entry_id = ANY($1)
+			ORDER BY entry_id, is_cover DESC, position ASC, id ASC
+		`
+	}
 
-	rows, err := r.db.Query(ctx, query, entryIDs)
+	rows, err := r.reader().Query(ctx, query, entryIDs)
 	if err != nil {
 		return nil, fmt.Errorf("failed to query image metas: %w", err)
 	}
@@ -437,7 +1502,7 @@ func (r *EntryRepository) GetImageMetasByEntryIDs(
 	for rows.Next() {
 		var entryID uuid.UUID
 		var m ImageMeta
-		if err := rows.Scan(&entryID, &m.ID, &m.IsCover, &m.Position); err != nil {
+		if err := rows.Scan(&entryID, &m.ID, &m.IsCover, &m.Position, &m.Hash); err != nil {
 			return nil, fmt.Errorf("failed to scan: %w", err)
 		}
 		result[entryID] = append(result[entryID], m)
@@ -454,16 +1519,16 @@ func (r *EntryRepository) SearchEntries(
 	limit, offset int,
 ) ([]*Entry, error) {
 	query := `
-		SELECT id, collection_id, type_id, user_id, title, description, score, date, additional_fields, created_at, updated_at
+		SELECT id, collection_id, type_id, user_id, title, original_title, language, description, score, priority, visibility, date, additional_fields, created_at, updated_at
 		FROM entries
 		WHERE user_id = $1
-		AND (title ILIKE $2 OR description ILIKE $2)
+		AND ` + r.searchCondition("", "$2") + `
 		ORDER BY created_at DESC
 		LIMIT $3 OFFSET $4
 	`
 
 	searchPattern := "%" + searchQuery + "%"
-	rows, err := r.db.Query(ctx, query, userID, searchPattern, limit, offset)
+	rows, err := r.reader().Query(ctx, query, userID, searchPattern, limit, offset)
 	if err != nil {
 		return nil, fmt.Errorf("failed to search entries: %w", err)
 	}
@@ -479,8 +1544,12 @@ func (r *EntryRepository) SearchEntries(
 			&entry.TypeID,
 			&entry.UserID,
 			&entry.Title,
+			&entry.OriginalTitle,
+			&entry.Language,
 			&entry.Description,
 			&entry.Score,
+			&entry.Priority,
+			&entry.Visibility,
 			&entry.Date,
 			&additionalFieldsStr,
 			&entry.CreatedAt,
@@ -546,29 +1615,159 @@ func (r *EntryRepository) DeleteEntriesByIDs(ctx context.Context, ids []uuid.UUI
 	return result.RowsAffected(), nil
 }
 
-// CopySeedImagesToEntry copies seed images into entry_images for a specific entry.
-func (r *EntryRepository) CopySeedImagesToEntry(ctx context.Context, entryID uuid.UUID, seedImageIDs []uuid.UUID) error {
-	tx, err := r.db.Begin(ctx)
+// ListEntryScoreScales returns the score scale of each of ids that is an
+// entry of the user: its type's, or nil for untyped entries. Other ids are
+// left out.
+func (r *EntryRepository) ListEntryScoreScales(ctx context.Context, userID uuid.UUID, ids []uuid.UUID) (map[uuid.UUID]*ScoreScale, error) {
+	query := `
+		SELECT e.id, t.score_scale
+		FROM entries e
+		LEFT JOIN entry_types t ON t.id = e.type_id
+		WHERE e.user_id = $1 AND e.id = ANY($2)
+	`
+
+	rows, err := r.db.Query(ctx, query, userID, ids)
 	if err != nil {
-		return fmt.Errorf("failed to start transaction: %w", err)
+		return nil, fmt.Errorf("failed to query entry score scales: %w", err)
 	}
-	defer tx.Rollback(ctx)
+	defer rows.Close()
 
-	for i, seedID := range seedImageIDs {
-		var data []byte
-		err := tx.QueryRow(ctx, `SELECT image_data FROM seed_images WHERE id = $1`, seedID).Scan(&data)
-		if err != nil {
-			return fmt.Errorf("seed image %s not found: %w", seedID, err)
+	scales := make(map[uuid.UUID]*ScoreScale, len(ids))
+	for rows.Next() {
+		var id uuid.UUID
+		var scaleStr *string
+		if err := rows.Scan(&id, &scaleStr); err != nil {
+			return nil, fmt.Errorf("failed to scan entry score scale: %w", err)
+		}
+		if scaleStr == nil {
+			scales[id] = nil
+			continue
+		}
+		var scale ScoreScale
+		if err := json.Unmarshal([]byte(*scaleStr), &scale); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal type score scale: %w", err)
 		}
+		scales[id] = &scale
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating entry score scales: %w", err)
+	}
 
-		isCover := i == 0
-		_, err = tx.Exec(ctx,
-			`INSERT INTO entry_images (entry_id, image_data, is_cover, position) VALUES ($1, $2, $3, $4)`,
-			entryID, data, isCover, i,
+	return scales, nil
+}
+
+// SetEntryScores gives each of the user's entries in ids the score at the
+// same index, in one statement, so either all of them are saved or none.
+// Entries that already have their score are left alone. Returns how many
+// entries changed.
+func (r *EntryRepository) SetEntryScores(ctx context.Context, userID uuid.UUID, ids []uuid.UUID, scores []int) (int64, error) {
+	query := `
+		UPDATE entries e
+		SET score = s.score, updated_at = NOW()
+		FROM unnest($2::uuid[], $3::int[]) AS s(id, score)
+		WHERE e.id = s.id AND e.user_id = $1
+		AND e.score IS DISTINCT FROM s.score
+	`
+
+	result, err := r.db.Exec(ctx, query, userID, ids, scores)
+	if err != nil {
+		return 0, fmt.Errorf("failed to set entry scores: %w", err)
+	}
+	return result.RowsAffected(), nil
+}
+
+// CopySeedImagesToEntry copies seed images into entry_images for a specific
+// entry in one statement, in the order given, the first becoming the cover.
+// It returns the ids that are not seed images, which are skipped.
+func (r *EntryRepository) CopySeedImagesToEntry(ctx context.Context, entryID uuid.UUID, seedImageIDs []uuid.UUID) ([]uuid.UUID, error) {
+	query := `
+		WITH found AS (
+			SELECT id, image_data, row_number() OVER (ORDER BY array_position($2::uuid[], id)) - 1 AS position
+			FROM seed_images
+			WHERE id = ANY($2)
+		), inserted AS (
+			INSERT INTO entry_images (entry_id, image_data, is_cover, position)
+			SELECT $1, image_data, position = 0, position
+			FROM found
 		)
-		if err != nil {
-			return fmt.Errorf("failed to insert entry image: %w", err)
+		SELECT id FROM found
+	`
+
+	rows, err := r.db.Query(ctx, query, entryID, seedImageIDs)
+	if err != nil {
+		return nil, fmt.Errorf("failed to copy seed images: %w", err)
+	}
+	defer rows.Close()
+
+	copied := make(map[uuid.UUID]bool, len(seedImageIDs))
+	for rows.Next() {
+		var id uuid.UUID
+		if err := rows.Scan(&id); err != nil {
+			return nil, fmt.Errorf("failed to scan seed image id: %w", err)
 		}
+		copied[id] = true
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to copy seed images: %w", err)
+	}
+	var missing []uuid.UUID
+	for _, id := range seedImageIDs {
+		if !copied[id] {
+			missing = append(missing, id)
+			copied[id] = true // report each id once
+		}
+	}
+
+	return missing, nil
+}
+
+// SetEntryOrder ranks the collection's entries in the order of ids; entries
+// of the collection that are not listed become unranked. Fails with
+// ErrEntryNotInCollection if an id isn't one of the collection's entries.
+func (r *EntryRepository) SetEntryOrder(
+	ctx context.Context,
+	userID, collectionID uuid.UUID,
+	ids []uuid.UUID,
+) error {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to start transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	_, err = tx.Exec(ctx, `
+		UPDATE entries e
+		SET position = o.position - 1, updated_at = NOW()
+		FROM unnest($3::uuid[]) WITH ORDINALITY AS o(id, position)
+		WHERE e.id = o.id AND e.user_id = $1 AND e.collection_id = $2
+		AND e.position IS DISTINCT FROM o.position - 1
+	`, userID, collectionID, ids)
+	if err != nil {
+		return fmt.Errorf("failed to set entry positions: %w", err)
+	}
+
+	// Unchanged entries are skipped above, so count membership separately
+	var listed int
+	err = tx.QueryRow(ctx, `
+		SELECT COUNT(*) FROM entries
+		WHERE id = ANY($3) AND user_id = $1 AND collection_id = $2
+	`, userID, collectionID, ids).Scan(&listed)
+	if err != nil {
+		return fmt.Errorf("failed to count ordered entries: %w", err)
+	}
+	if listed != len(ids) {
+		return ErrEntryNotInCollection
+	}
+
+	_, err = tx.Exec(ctx, `
+		UPDATE entries
+		SET position = NULL, updated_at = NOW()
+		WHERE user_id = $1 AND collection_id = $2
+		AND position IS NOT NULL AND NOT (id = ANY($3))
+	`, userID, collectionID, ids)
+	if err != nil {
+		return fmt.Errorf("failed to clear entry positions: %w", err)
 	}
 
 	return tx.Commit(ctx)
@@ -0,0 +1,235 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+var (
+	ErrExportNotFound = errors.New("export not found")
+	ErrExportNotReady = errors.New("export is not ready")
+)
+
+// Export statuses. An export is queued as pending, claimed by the worker as
+// running, and ends as done or failed.
+const (
+	ExportStatusPending = "pending"
+	ExportStatusRunning = "running"
+	ExportStatusDone    = "done"
+	ExportStatusFailed  = "failed"
+)
+
+// Export is a requested export without its file; use GetExportFile for the data.
+type Export struct {
+	ID            uuid.UUID   `json:"id"`
+	UserID        uuid.UUID   `json:"user_id"`
+	Format        string      `json:"format"`
+	CollectionID  *uuid.UUID  `json:"collection_id,omitempty"`
+	Year          *int        `json:"year,omitempty"`
+	EntryIDs      []uuid.UUID `json:"entry_ids,omitempty"`
+	IncludeImages bool        `json:"include_images"`
+	Status        string      `json:"status"`
+	Attempts      int         `json:"attempts"`
+	Progress      int         `json:"progress"` // percent
+	FileSize      int64       `json:"file_size"`
+	Error         *string     `json:"error,omitempty"`
+	FinishedAt    *time.Time  `json:"finished_at,omitempty"`
+	CreatedAt     time.Time   `json:"created_at"`
+}
+
+const exportColumns = `id, user_id, format, collection_id, year, entry_ids, include_images, status, attempts, progress,
+		COALESCE(octet_length(file_data), 0), error, finished_at, created_at`
+
+type ExportRepository struct {
+	db *pgxpool.Pool
+}
+
+func NewExportRepository(db *pgxpool.Pool) *ExportRepository {
+	return &ExportRepository{db: db}
+}
+
+// CreateExport queues an export for the worker. A nil collectionID, year or
+// entryIDs leaves that filter out.
+func (r *ExportRepository) CreateExport(
+	ctx context.Context,
+	userID uuid.UUID,
+	format string,
+	collectionID *uuid.UUID,
+	year *int,
+	entryIDs []uuid.UUID,
+	includeImages bool,
+) (*Export, error) {
+	query := `
+		INSERT INTO exports (user_id, format, collection_id, year, entry_ids, include_images)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		RETURNING ` + exportColumns
+
+	return scanExport(r.db.QueryRow(ctx, query, userID, format, collectionID, year, entryIDs, includeImages))
+}
+
+// GetExportByID retrieves a user's export.
+func (r *ExportRepository) GetExportByID(ctx context.Context, id, userID uuid.UUID) (*Export, error) {
+	query := `
+		SELECT ` + exportColumns + `
+		FROM exports
+		WHERE id = $1 AND user_id = $2
+	`
+
+	export, err := scanExport(r.db.QueryRow(ctx, query, id, userID))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrExportNotFound
+		}
+		return nil, err
+	}
+
+	return export, nil
+}
+
+// GetExportFile returns the file of a user's export, or ErrExportNotReady
+// if it is not done.
+func (r *ExportRepository) GetExportFile(ctx context.Context, id, userID uuid.UUID) ([]byte, error) {
+	query := `
+		SELECT status, file_data
+		FROM exports
+		WHERE id = $1 AND user_id = $2
+	`
+
+	var status string
+	var data []byte
+	err := r.db.QueryRow(ctx, query, id, userID).Scan(&status, &data)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrExportNotFound
+		}
+		return nil, fmt.Errorf("failed to get export file: %w", err)
+	}
+
+	if status != ExportStatusDone {
+		return nil, ErrExportNotReady
+	}
+
+	return data, nil
+}
+
+// ClaimExport marks the oldest pending export as running and returns it, or
+// nil when there is nothing to do. Running exports claimed more than lease ago
+// are assumed abandoned by a crashed worker and claimed again.
+func (r *ExportRepository) ClaimExport(ctx context.Context, lease time.Duration) (*Export, error) {
+	query := `
+		UPDATE exports
+		SET status = 'running', attempts = attempts + 1, progress = 0, claimed_at = NOW()
+		WHERE id = (
+			SELECT id FROM exports
+			WHERE status = 'pending'
+				OR (status = 'running' AND claimed_at < NOW() - make_interval(secs => $1))
+			ORDER BY created_at ASC
+			LIMIT 1
+			FOR UPDATE SKIP LOCKED
+		)
+		RETURNING ` + exportColumns
+
+	export, err := scanExport(r.db.QueryRow(ctx, query, lease.Seconds()))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to claim export: %w", err)
+	}
+
+	return export, nil
+}
+
+// CompleteExport stores the generated file and marks the export done.
+func (r *ExportRepository) CompleteExport(ctx context.Context, id uuid.UUID, data []byte) error {
+	query := `
+		UPDATE exports
+		SET status = 'done', file_data = $2, error = NULL, progress = 100, finished_at = NOW()
+		WHERE id = $1
+	`
+
+	if _, err := r.db.Exec(ctx, query, id, data); err != nil {
+		return fmt.Errorf("failed to complete export: %w", err)
+	}
+
+	return nil
+}
+
+// SetExportProgress records how far a running export is, in percent.
+func (r *ExportRepository) SetExportProgress(ctx context.Context, id uuid.UUID, progress int) error {
+	query := `
+		UPDATE exports
+		SET progress = $2
+		WHERE id = $1 AND status = 'running'
+	`
+
+	if _, err := r.db.Exec(ctx, query, id, progress); err != nil {
+		return fmt.Errorf("failed to update export progress: %w", err)
+	}
+
+	return nil
+}
+
+// FailExport marks the export failed with the given reason.
+func (r *ExportRepository) FailExport(ctx context.Context, id uuid.UUID, reason string) error {
+	query := `
+		UPDATE exports
+		SET status = 'failed', error = $2, finished_at = NOW()
+		WHERE id = $1
+	`
+
+	if _, err := r.db.Exec(ctx, query, id, reason); err != nil {
+		return fmt.Errorf("failed to record export failure: %w", err)
+	}
+
+	return nil
+}
+
+// DeleteFinishedExports removes exports finished more than olderThan ago.
+func (r *ExportRepository) DeleteFinishedExports(ctx context.Context, olderThan time.Duration) (int64, error) {
+	query := `
+		DELETE FROM exports
+		WHERE finished_at IS NOT NULL AND finished_at < NOW() - make_interval(secs => $1)
+	`
+
+	result, err := r.db.Exec(ctx, query, olderThan.Seconds())
+	if err != nil {
+		return 0, fmt.Errorf("failed to delete finished exports: %w", err)
+	}
+
+	return result.RowsAffected(), nil
+}
+
+func scanExport(row pgx.Row) (*Export, error) {
+	var e Export
+	err := row.Scan(
+		&e.ID,
+		&e.UserID,
+		&e.Format,
+		&e.CollectionID,
+		&e.Year,
+		&e.EntryIDs,
+		&e.IncludeImages,
+		&e.Status,
+		&e.Attempts,
+		&e.Progress,
+		&e.FileSize,
+		&e.Error,
+		&e.FinishedAt,
+		&e.CreatedAt,
+	)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, err
+		}
+		return nil, fmt.Errorf("failed to scan export: %w", err)
+	}
+
+	return &e, nil
+}
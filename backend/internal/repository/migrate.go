@@ -1,12 +1,18 @@
 package repository
 
 import (
+	"context"
 	"errors"
 	"fmt"
+	"os"
+	"regexp"
+	"strconv"
 
 	"github.com/golang-migrate/migrate/v4"
 	_ "github.com/golang-migrate/migrate/v4/database/postgres"
 	_ "github.com/golang-migrate/migrate/v4/source/file"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
 	"go.uber.org/zap"
 
 	"github.com/avalarin/livlog/backend/internal/config"
@@ -42,3 +48,78 @@ func RunMigrations(cfg *config.DatabaseConfig, migrationsPath string, logger *za
 
 	return nil
 }
+
+// RollbackMigrations reverts the last steps migrations.
+func RollbackMigrations(cfg *config.DatabaseConfig, migrationsPath string, steps int, logger *zap.Logger) error {
+	m, err := migrate.New(
+		fmt.Sprintf("file://%s", migrationsPath),
+		cfg.DSN(),
+	)
+	if err != nil {
+		return fmt.Errorf("failed to create migrate instance: %w", err)
+	}
+	defer m.Close()
+
+	if err := m.Steps(-steps); err != nil {
+		return fmt.Errorf("failed to roll back migrations: %w", err)
+	}
+
+	version, dirty, err := m.Version()
+	if err != nil && !errors.Is(err, migrate.ErrNilVersion) {
+		return fmt.Errorf("failed to get migration version: %w", err)
+	}
+
+	logger.Info("migrations rolled back",
+		zap.Int("steps", steps),
+		zap.Uint("version", version),
+		zap.Bool("dirty", dirty),
+	)
+
+	return nil
+}
+
+var migrationFilePattern = regexp.MustCompile(`^(\d+)_.*\.up\.sql$`)
+
+// LatestMigrationVersion returns the highest migration version found in
+// migrationsPath, i.e. the schema version this build expects.
+func LatestMigrationVersion(migrationsPath string) (uint, error) {
+	files, err := os.ReadDir(migrationsPath)
+	if err != nil {
+		return 0, fmt.Errorf("failed to read migrations directory: %w", err)
+	}
+
+	var latest uint
+	for _, f := range files {
+		m := migrationFilePattern.FindStringSubmatch(f.Name())
+		if m == nil {
+			continue
+		}
+		version, err := strconv.ParseUint(m[1], 10, 64)
+		if err != nil {
+			return 0, fmt.Errorf("invalid migration version in %s: %w", f.Name(), err)
+		}
+		if uint(version) > latest {
+			latest = uint(version)
+		}
+	}
+
+	return latest, nil
+}
+
+// MigrationVersion returns the schema version recorded by golang-migrate and
+// whether the last migration failed halfway. A database that was never
+// migrated is at version 0.
+func (db *DB) MigrationVersion(ctx context.Context) (uint, bool, error) {
+	var version int64
+	var dirty bool
+	err := db.Pool.QueryRow(ctx, `SELECT version, dirty FROM schema_migrations LIMIT 1`).Scan(&version, &dirty)
+	if err != nil {
+		var pgErr *pgconn.PgError
+		if errors.Is(err, pgx.ErrNoRows) || errors.As(err, &pgErr) && pgErr.Code == "42P01" {
+			return 0, false, nil
+		}
+		return 0, false, fmt.Errorf("failed to get migration version: %w", err)
+	}
+
+	return uint(version), dirty, nil
+}
@@ -0,0 +1,30 @@
+package repository
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestLatestMigrationVersion(t *testing.T) {
+	dir := t.TempDir()
+	for _, name := range []string{
+		"001_init.up.sql",
+		"001_init.down.sql",
+		"010_add_index.up.sql",
+		"010_add_index.down.sql",
+		"README.md",
+	} {
+		if err := os.WriteFile(filepath.Join(dir, name), nil, 0o644); err != nil {
+			t.Fatalf("failed to write %s: %v", name, err)
+		}
+	}
+
+	version, err := LatestMigrationVersion(dir)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if version != 10 {
+		t.Errorf("expected version 10, got %d", version)
+	}
+}
@@ -0,0 +1,172 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+var (
+	ErrChannelNotFound = errors.New("notification channel not found")
+)
+
+// NotificationChannel is an external service a user's notifications are
+// also sent to. Target is secret-bearing for some kinds (a Discord webhook
+// URL), so it is never rendered to clients as is.
+type NotificationChannel struct {
+	ID              uuid.UUID  `json:"id"`
+	UserID          uuid.UUID  `json:"user_id"`
+	Kind            string     `json:"kind"`
+	Target          string     `json:"-"`
+	LastError       *string    `json:"last_error,omitempty"`
+	LastDeliveredAt *time.Time `json:"last_delivered_at,omitempty"`
+	CreatedAt       time.Time  `json:"created_at"`
+	UpdatedAt       time.Time  `json:"updated_at"`
+}
+
+const notificationChannelColumns = `id, user_id, kind, target, last_error, last_delivered_at, created_at, updated_at`
+
+type NotificationChannelRepository struct {
+	db *pgxpool.Pool
+}
+
+func NewNotificationChannelRepository(db *pgxpool.Pool) *NotificationChannelRepository {
+	return &NotificationChannelRepository{db: db}
+}
+
+// CreateChannel adds a channel for the user.
+func (r *NotificationChannelRepository) CreateChannel(
+	ctx context.Context,
+	userID uuid.UUID,
+	kind, target string,
+) (*NotificationChannel, error) {
+	query := `
+		INSERT INTO notification_channels (user_id, kind, target)
+		VALUES ($1, $2, $3)
+		RETURNING ` + notificationChannelColumns
+
+	c, err := scanNotificationChannel(r.db.QueryRow(ctx, query, userID, kind, target))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create notification channel: %w", err)
+	}
+
+	return c, nil
+}
+
+// GetChannelsByUserID retrieves all channels of a user, oldest first.
+func (r *NotificationChannelRepository) GetChannelsByUserID(
+	ctx context.Context,
+	userID uuid.UUID,
+) ([]*NotificationChannel, error) {
+	query := `
+		SELECT ` + notificationChannelColumns + `
+		FROM notification_channels
+		WHERE user_id = $1
+		ORDER BY created_at ASC
+	`
+
+	rows, err := r.db.Query(ctx, query, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query notification channels: %w", err)
+	}
+	defer rows.Close()
+
+	var channels []*NotificationChannel
+	for rows.Next() {
+		c, err := scanNotificationChannel(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan notification channel: %w", err)
+		}
+		channels = append(channels, c)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating notification channels: %w", err)
+	}
+
+	return channels, nil
+}
+
+// GetChannelByID retrieves a channel owned by the user.
+func (r *NotificationChannelRepository) GetChannelByID(
+	ctx context.Context,
+	id, userID uuid.UUID,
+) (*NotificationChannel, error) {
+	query := `
+		SELECT ` + notificationChannelColumns + `
+		FROM notification_channels
+		WHERE id = $1 AND user_id = $2
+	`
+
+	c, err := scanNotificationChannel(r.db.QueryRow(ctx, query, id, userID))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrChannelNotFound
+		}
+		return nil, fmt.Errorf("failed to get notification channel: %w", err)
+	}
+
+	return c, nil
+}
+
+// CountChannels returns how many channels the user has.
+func (r *NotificationChannelRepository) CountChannels(ctx context.Context, userID uuid.UUID) (int, error) {
+	var count int
+	err := r.db.QueryRow(ctx, `SELECT COUNT(*) FROM notification_channels WHERE user_id = $1`, userID).Scan(&count)
+	if err != nil {
+		return 0, fmt.Errorf("failed to count notification channels: %w", err)
+	}
+	return count, nil
+}
+
+// DeleteChannel deletes a channel owned by the user.
+func (r *NotificationChannelRepository) DeleteChannel(ctx context.Context, id, userID uuid.UUID) error {
+	result, err := r.db.Exec(ctx, `DELETE FROM notification_channels WHERE id = $1 AND user_id = $2`, id, userID)
+	if err != nil {
+		return fmt.Errorf("failed to delete notification channel: %w", err)
+	}
+	if result.RowsAffected() == 0 {
+		return ErrChannelNotFound
+	}
+	return nil
+}
+
+// RecordDelivery stores the outcome of the latest send: a nil sendErr marks
+// it delivered, otherwise the error is kept for the user to see.
+func (r *NotificationChannelRepository) RecordDelivery(ctx context.Context, id uuid.UUID, sendErr *string) error {
+	query := `
+		UPDATE notification_channels
+		SET last_error = $2,
+			last_delivered_at = CASE WHEN $2::text IS NULL THEN NOW() ELSE last_delivered_at END,
+			updated_at = NOW()
+		WHERE id = $1
+	`
+
+	if _, err := r.db.Exec(ctx, query, id, sendErr); err != nil {
+		return fmt.Errorf("failed to record notification channel delivery: %w", err)
+	}
+	return nil
+}
+
+func scanNotificationChannel(row pgx.Row) (*NotificationChannel, error) {
+	var c NotificationChannel
+	err := row.Scan(
+		&c.ID,
+		&c.UserID,
+		&c.Kind,
+		&c.Target,
+		&c.LastError,
+		&c.LastDeliveredAt,
+		&c.CreatedAt,
+		&c.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &c, nil
+}
@@ -0,0 +1,160 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+var (
+	ErrNotificationNotFound = errors.New("notification not found")
+)
+
+type Notification struct {
+	ID        uuid.UUID              `json:"id"`
+	UserID    uuid.UUID              `json:"user_id"`
+	Kind      string                 `json:"kind"`
+	Title     string                 `json:"title"`
+	Body      string                 `json:"body"`
+	Data      map[string]interface{} `json:"data"`
+	ReadAt    *time.Time             `json:"read_at,omitempty"`
+	CreatedAt time.Time              `json:"created_at"`
+}
+
+type NotificationRepository struct {
+	db *pgxpool.Pool
+}
+
+func NewNotificationRepository(db *pgxpool.Pool) *NotificationRepository {
+	return &NotificationRepository{db: db}
+}
+
+// CreateNotification adds a notification to a user's inbox.
+func (r *NotificationRepository) CreateNotification(
+	ctx context.Context,
+	userID uuid.UUID,
+	kind, title, body string,
+	data map[string]interface{},
+) (*Notification, error) {
+	if data == nil {
+		data = map[string]interface{}{}
+	}
+	dataJSON, err := json.Marshal(data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal notification data: %w", err)
+	}
+
+	query := `
+		INSERT INTO notifications (user_id, kind, title, body, data)
+		VALUES ($1, $2, $3, $4, $5)
+		RETURNING id, user_id, kind, title, body, data, read_at, created_at
+	`
+
+	return scanNotification(r.db.QueryRow(ctx, query, userID, kind, title, body, dataJSON))
+}
+
+// GetNotificationsByUserID lists a user's notifications, newest first.
+func (r *NotificationRepository) GetNotificationsByUserID(
+	ctx context.Context,
+	userID uuid.UUID,
+	unreadOnly bool,
+	limit, offset int,
+) ([]*Notification, error) {
+	query := `
+		SELECT id, user_id, kind, title, body, data, read_at, created_at
+		FROM notifications
+		WHERE user_id = $1 AND (NOT $2 OR read_at IS NULL)
+		ORDER BY created_at DESC
+		LIMIT $3 OFFSET $4
+	`
+
+	rows, err := r.db.Query(ctx, query, userID, unreadOnly, limit, offset)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query notifications: %w", err)
+	}
+	defer rows.Close()
+
+	var notifications []*Notification
+	for rows.Next() {
+		n, err := scanNotification(rows)
+		if err != nil {
+			return nil, err
+		}
+		notifications = append(notifications, n)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating notifications: %w", err)
+	}
+
+	return notifications, nil
+}
+
+// MarkRead marks a user's notification as read. Marking it again keeps the
+// original read time.
+func (r *NotificationRepository) MarkRead(ctx context.Context, id, userID uuid.UUID) (*Notification, error) {
+	query := `
+		UPDATE notifications
+		SET read_at = COALESCE(read_at, NOW())
+		WHERE id = $1 AND user_id = $2
+		RETURNING id, user_id, kind, title, body, data, read_at, created_at
+	`
+
+	n, err := scanNotification(r.db.QueryRow(ctx, query, id, userID))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrNotificationNotFound
+		}
+		return nil, err
+	}
+
+	return n, nil
+}
+
+// DeleteReadNotifications removes notifications read more than olderThan ago.
+func (r *NotificationRepository) DeleteReadNotifications(ctx context.Context, olderThan time.Duration) (int64, error) {
+	query := `
+		DELETE FROM notifications
+		WHERE read_at IS NOT NULL AND read_at < NOW() - make_interval(secs => $1)
+	`
+
+	result, err := r.db.Exec(ctx, query, olderThan.Seconds())
+	if err != nil {
+		return 0, fmt.Errorf("failed to delete read notifications: %w", err)
+	}
+
+	return result.RowsAffected(), nil
+}
+
+func scanNotification(row pgx.Row) (*Notification, error) {
+	var n Notification
+	var data []byte
+	err := row.Scan(
+		&n.ID,
+		&n.UserID,
+		&n.Kind,
+		&n.Title,
+		&n.Body,
+		&data,
+		&n.ReadAt,
+		&n.CreatedAt,
+	)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, err
+		}
+		return nil, fmt.Errorf("failed to scan notification: %w", err)
+	}
+
+	if err := json.Unmarshal(data, &n.Data); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal notification data: %w", err)
+	}
+
+	return &n, nil
+}
@@ -0,0 +1,161 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// OutboxEvent is an entry or collection change recorded by the write_outbox
+// trigger in the transaction that made it. It names the change only; the
+// entity's data is loaded when the event is relayed.
+type OutboxEvent struct {
+	ID        int64
+	UserID    uuid.UUID
+	Entity    string // "entry" or "collection"
+	EntityID  uuid.UUID
+	Op        string // "insert", "update" or "delete"
+	CreatedAt time.Time
+}
+
+// OutboxWebhook is what an outbox event is delivered to webhooks as.
+type OutboxWebhook struct {
+	Event string          // webhook event name, e.g. "entry.created"
+	Data  json.RawMessage // the "data" of the delivery body
+}
+
+type OutboxRepository struct {
+	db *pgxpool.Pool
+}
+
+func NewOutboxRepository(db *pgxpool.Pool) *OutboxRepository {
+	return &OutboxRepository{db: db}
+}
+
+// Relay publishes up to limit pending events and removes them from the outbox,
+// all in one transaction: every event is published exactly once, or not at all
+// and retried on the next call. For each event it
+//   - sends a ChangeEvent on the change feed channel (delivered on commit), and
+//   - queues webhook deliveries when webhook returns one for it (nil skips
+//     webhooks). An error from webhook rolls the batch back.
+//
+// Concurrent relays skip each other's events. Returns the number relayed.
+func (r *OutboxRepository) Relay(
+	ctx context.Context,
+	limit int,
+	webhook func(context.Context, OutboxEvent) (*OutboxWebhook, error),
+) (int, error) {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	events, err := claimOutboxEvents(ctx, tx, limit)
+	if err != nil {
+		return 0, err
+	}
+	if len(events) == 0 {
+		return 0, nil
+	}
+
+	ids := make([]int64, len(events))
+	for i, e := range events {
+		ids[i] = e.ID
+
+		notification, err := json.Marshal(ChangeEvent{
+			UserID: e.UserID,
+			Entity: e.Entity,
+			ID:     e.EntityID,
+			Op:     e.Op,
+		})
+		if err != nil {
+			return 0, fmt.Errorf("failed to encode change event: %w", err)
+		}
+		if _, err := tx.Exec(ctx, `SELECT pg_notify($1, $2)`, changeChannel, string(notification)); err != nil {
+			return 0, fmt.Errorf("failed to notify change: %w", err)
+		}
+
+		w, err := webhook(ctx, e)
+		if err != nil {
+			return 0, err
+		}
+		if w != nil {
+			if err := enqueueWebhookDeliveries(ctx, tx, e, w); err != nil {
+				return 0, err
+			}
+		}
+	}
+
+	if _, err := tx.Exec(ctx, `DELETE FROM outbox WHERE id = ANY($1)`, ids); err != nil {
+		return 0, fmt.Errorf("failed to delete relayed outbox events: %w", err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return 0, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return len(events), nil
+}
+
+func claimOutboxEvents(ctx context.Context, tx pgx.Tx, limit int) ([]OutboxEvent, error) {
+	query := `
+		SELECT id, user_id, entity, entity_id, op, created_at
+		FROM outbox
+		ORDER BY id ASC
+		LIMIT $1
+		FOR UPDATE SKIP LOCKED
+	`
+
+	rows, err := tx.Query(ctx, query, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query outbox: %w", err)
+	}
+	defer rows.Close()
+
+	var events []OutboxEvent
+	for rows.Next() {
+		var e OutboxEvent
+		err := rows.Scan(
+			&e.ID,
+			&e.UserID,
+			&e.Entity,
+			&e.EntityID,
+			&e.Op,
+			&e.CreatedAt,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan outbox event: %w", err)
+		}
+		events = append(events, e)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating outbox: %w", err)
+	}
+
+	return events, nil
+}
+
+// enqueueWebhookDeliveries queues the event for every webhook of the user that
+// subscribes to it. All deliveries of one event share the same body and id.
+func enqueueWebhookDeliveries(ctx context.Context, tx pgx.Tx, e OutboxEvent, w *OutboxWebhook) error {
+	query := `
+		INSERT INTO webhook_deliveries (webhook_id, event, payload)
+		SELECT id, $2, jsonb_build_object('id', $3::uuid, 'event', $2::text, 'created_at', $4::timestamptz, 'data', $5::jsonb)
+		FROM webhooks
+		WHERE user_id = $1 AND $2 = ANY(events)
+	`
+
+	_, err := tx.Exec(ctx, query, e.UserID, w.Event, uuid.New(), e.CreatedAt, string(w.Data))
+	if err != nil {
+		return fmt.Errorf("failed to enqueue webhook deliveries: %w", err)
+	}
+
+	return nil
+}
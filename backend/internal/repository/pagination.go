@@ -0,0 +1,35 @@
+package repository
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// PageCursor is the sort key of the last item of a page; the next page
+// starts after it. Rank orders items ahead of created_at, e.g. to keep the
+// "Other" type last.
+type PageCursor struct {
+	Rank      int
+	CreatedAt time.Time
+	ID        uuid.UUID
+}
+
+// pageArgs returns the keyset arguments for after and the LIMIT for a page
+// of limit items plus one to detect a following page. A zero limit means
+// no limit (NULL).
+func pageArgs(after *PageCursor, limit int) (*int, *time.Time, uuid.UUID, *int) {
+	var rank *int
+	var createdAt *time.Time
+	var id uuid.UUID
+	if after != nil {
+		rank, createdAt, id = &after.Rank, &after.CreatedAt, after.ID
+	}
+
+	var fetch *int
+	if limit > 0 {
+		n := limit + 1
+		fetch = &n
+	}
+	return rank, createdAt, id, fetch
+}
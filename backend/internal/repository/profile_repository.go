@@ -0,0 +1,246 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+var (
+	ErrProfileNotFound = errors.New("profile not found")
+	ErrHandleTaken     = errors.New("handle is already taken")
+)
+
+// Profile is a user's opt-in public profile. Only CollectionIDs are shown
+// publicly, and favorites only while they are in one of those collections.
+type Profile struct {
+	UserID           uuid.UUID   `json:"user_id"`
+	Handle           string      `json:"handle"`
+	Public           bool        `json:"public"`
+	Bio              string      `json:"bio"`
+	CollectionIDs    []uuid.UUID `json:"collection_ids"`
+	FavoriteEntryIDs []uuid.UUID `json:"favorite_entry_ids"`
+	CreatedAt        time.Time   `json:"created_at"`
+	UpdatedAt        time.Time   `json:"updated_at"`
+
+	// From the user, filled by GetPublicProfileByHandle
+	DisplayName *string   `json:"display_name,omitempty"`
+	MemberSince time.Time `json:"member_since"`
+}
+
+// ProfileStats summarizes the public entries in a profile's collections.
+type ProfileStats struct {
+	Entries         int
+	EntriesThisYear int
+}
+
+const profileColumns = `p.user_id, p.handle, p.public, p.bio, p.collection_ids, p.favorite_entry_ids,
+		p.created_at, p.updated_at`
+
+type ProfileRepository struct {
+	db *pgxpool.Pool
+}
+
+func NewProfileRepository(db *pgxpool.Pool) *ProfileRepository {
+	return &ProfileRepository{db: db}
+}
+
+// GetProfile returns the user's profile, public or not.
+func (r *ProfileRepository) GetProfile(ctx context.Context, userID uuid.UUID) (*Profile, error) {
+	query := `
+		SELECT ` + profileColumns + `
+		FROM profiles p
+		WHERE p.user_id = $1
+	`
+
+	var p Profile
+	err := r.db.QueryRow(ctx, query, userID).Scan(profileDest(&p)...)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrProfileNotFound
+		}
+		return nil, fmt.Errorf("failed to get profile: %w", err)
+	}
+
+	return &p, nil
+}
+
+// GetPublicProfileByHandle returns a public profile of an active user.
+// Private profiles are reported as not found.
+func (r *ProfileRepository) GetPublicProfileByHandle(ctx context.Context, handle string) (*Profile, error) {
+	query := `
+		SELECT ` + profileColumns + `, u.display_name, u.created_at
+		FROM profiles p
+		JOIN users u ON u.id = p.user_id
+		WHERE p.handle = $1 AND p.public AND u.deleted_at IS NULL
+	`
+
+	var p Profile
+	dest := append(profileDest(&p), &p.DisplayName, &p.MemberSince)
+	err := r.db.QueryRow(ctx, query, handle).Scan(dest...)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrProfileNotFound
+		}
+		return nil, fmt.Errorf("failed to get profile: %w", err)
+	}
+
+	return &p, nil
+}
+
+// SaveProfile creates or replaces the user's profile. When the handle changes,
+// the old one is held for the user for holdReleased; a handle another user
+// holds or released less than holdReleased ago returns ErrHandleTaken.
+func (r *ProfileRepository) SaveProfile(
+	ctx context.Context,
+	userID uuid.UUID,
+	handle string,
+	public bool,
+	bio string,
+	collectionIDs, favoriteEntryIDs []uuid.UUID,
+	holdReleased time.Duration,
+) (*Profile, error) {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	var held bool
+	err = tx.QueryRow(ctx, `
+		SELECT EXISTS (
+			SELECT 1 FROM released_handles
+			WHERE handle = $1 AND user_id <> $2 AND released_at > NOW() - make_interval(secs => $3)
+		)
+	`, handle, userID, holdReleased.Seconds()).Scan(&held)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check handle: %w", err)
+	}
+	if held {
+		return nil, ErrHandleTaken
+	}
+
+	var oldHandle string
+	err = tx.QueryRow(ctx, `SELECT handle FROM profiles WHERE user_id = $1 FOR UPDATE`, userID).Scan(&oldHandle)
+	if err != nil && !errors.Is(err, pgx.ErrNoRows) {
+		return nil, fmt.Errorf("failed to get profile: %w", err)
+	}
+	if oldHandle != "" && oldHandle != handle {
+		_, err = tx.Exec(ctx, `
+			INSERT INTO released_handles (handle, user_id)
+			VALUES ($1, $2)
+			ON CONFLICT (handle) DO UPDATE SET user_id = EXCLUDED.user_id, released_at = NOW()
+		`, oldHandle, userID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to release handle: %w", err)
+		}
+	}
+
+	// Taking a handle back ends its hold
+	if _, err := tx.Exec(ctx, `DELETE FROM released_handles WHERE handle = $1`, handle); err != nil {
+		return nil, fmt.Errorf("failed to reclaim handle: %w", err)
+	}
+
+	query := `
+		INSERT INTO profiles AS p (user_id, handle, public, bio, collection_ids, favorite_entry_ids)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		ON CONFLICT (user_id) DO UPDATE SET
+			handle = EXCLUDED.handle,
+			public = EXCLUDED.public,
+			bio = EXCLUDED.bio,
+			collection_ids = EXCLUDED.collection_ids,
+			favorite_entry_ids = EXCLUDED.favorite_entry_ids,
+			updated_at = NOW()
+		RETURNING ` + profileColumns
+
+	var p Profile
+	err = tx.QueryRow(ctx, query, userID, handle, public, bio, collectionIDs, favoriteEntryIDs).Scan(profileDest(&p)...)
+	if err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == "23505" {
+			return nil, ErrHandleTaken
+		}
+		return nil, fmt.Errorf("failed to save profile: %w", err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return &p, nil
+}
+
+// GetProfileStats counts the user's public entries in the given collections.
+func (r *ProfileRepository) GetProfileStats(
+	ctx context.Context,
+	userID uuid.UUID,
+	collectionIDs []uuid.UUID,
+) (*ProfileStats, error) {
+	query := `
+		SELECT
+			COUNT(*),
+			COUNT(*) FILTER (WHERE date >= date_trunc('year', NOW()))
+		FROM entries
+		WHERE user_id = $1 AND collection_id = ANY($2) AND visibility = 'public'
+	`
+
+	var stats ProfileStats
+	err := r.db.QueryRow(ctx, query, userID, collectionIDs).Scan(&stats.Entries, &stats.EntriesThisYear)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get profile stats: %w", err)
+	}
+
+	return &stats, nil
+}
+
+// CountPublicEntries counts the user's public entries in each of the given
+// collections. Collections without any are left out.
+func (r *ProfileRepository) CountPublicEntries(
+	ctx context.Context,
+	userID uuid.UUID,
+	collectionIDs []uuid.UUID,
+) (map[uuid.UUID]int, error) {
+	query := `
+		SELECT collection_id, COUNT(*)
+		FROM entries
+		WHERE user_id = $1 AND collection_id = ANY($2) AND visibility = 'public'
+		GROUP BY collection_id
+	`
+
+	rows, err := r.db.Query(ctx, query, userID, collectionIDs)
+	if err != nil {
+		return nil, fmt.Errorf("failed to count public entries: %w", err)
+	}
+	defer rows.Close()
+
+	counts := make(map[uuid.UUID]int)
+	for rows.Next() {
+		var id uuid.UUID
+		var count int
+		if err := rows.Scan(&id, &count); err != nil {
+			return nil, fmt.Errorf("failed to scan entry count: %w", err)
+		}
+		counts[id] = count
+	}
+
+	return counts, rows.Err()
+}
+
+func profileDest(p *Profile) []interface{} {
+	return []interface{}{
+		&p.UserID,
+		&p.Handle,
+		&p.Public,
+		&p.Bio,
+		&p.CollectionIDs,
+		&p.FavoriteEntryIDs,
+		&p.CreatedAt,
+		&p.UpdatedAt,
+	}
+}
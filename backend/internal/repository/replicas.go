@@ -0,0 +1,180 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"sync/atomic"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"go.uber.org/zap"
+)
+
+var replicaLag = promauto.NewGaugeVec(
+	prometheus.GaugeOpts{
+		Name: "db_replica_lag_seconds",
+		Help: "How far each database replica is behind the primary, as of the last check",
+	},
+	[]string{"replica"},
+)
+
+// ReplicaRouter sends read-only queries to streaming replicas of the primary.
+// Repositories opt in per query with readPool; writes, and reads that decide
+// a write, stay on the primary. CheckLag keeps track of which replicas are
+// within the allowed lag; reads go to the primary while none is.
+type ReplicaRouter struct {
+	primary  *pgxpool.Pool
+	replicas []*replica
+	maxLag   time.Duration
+	next     atomic.Uint64
+	logger   *zap.Logger
+}
+
+type replica struct {
+	name  string // host:port, for logs and metrics
+	pool  *pgxpool.Pool
+	fresh atomic.Bool // within maxLag at the last check
+}
+
+// NewReplicaRouter routes reads to replicas of primary. Replicas take reads
+// only after the first CheckLag finds them caught up.
+func NewReplicaRouter(primary *DB, replicas []*DB, maxLag time.Duration, logger *zap.Logger) *ReplicaRouter {
+	r := &ReplicaRouter{
+		primary: primary.Pool,
+		maxLag:  maxLag,
+		logger:  logger,
+	}
+	for _, db := range replicas {
+		conn := db.Pool.Config().ConnConfig
+		r.replicas = append(r.replicas, &replica{
+			name: conn.Host + ":" + strconv.Itoa(int(conn.Port)),
+			pool: db.Pool,
+		})
+	}
+	return r
+}
+
+// Read returns a replica within the allowed lag, taking turns between them,
+// or the primary when there is none.
+func (r *ReplicaRouter) Read() *pgxpool.Pool {
+	if rep := r.pick(func(*replica) bool { return true }); rep != nil {
+		return rep.pool
+	}
+	return r.primary
+}
+
+// ReadAt is Read for queries that must see every write committed before the
+// WAL position minSeq was read (see SyncRepository.ChangeSeq). A replica is
+// only used once it has replayed that far; a minSeq of 0 accepts any.
+func (r *ReplicaRouter) ReadAt(ctx context.Context, minSeq int64) *pgxpool.Pool {
+	if minSeq == 0 {
+		return r.Read()
+	}
+
+	rep := r.pick(func(rep *replica) bool {
+		replayed, err := rep.replayed(ctx)
+		return err == nil && replayed >= minSeq
+	})
+	if rep != nil {
+		return rep.pool
+	}
+	return r.primary
+}
+
+// pick returns the next fresh replica accepted by ok, or nil.
+func (r *ReplicaRouter) pick(ok func(*replica) bool) *replica {
+	n := uint64(len(r.replicas))
+	if n == 0 {
+		return nil
+	}
+
+	start := r.next.Add(1)
+	for i := uint64(0); i < n; i++ {
+		rep := r.replicas[(start+i)%n]
+		if rep.fresh.Load() && ok(rep) {
+			return rep
+		}
+	}
+	return nil
+}
+
+// CheckLag measures how far each replica is behind the primary and takes
+// replicas out of rotation while they lag more than the allowed lag or can't
+// be reached. It fails only when the primary can't be reached.
+func (r *ReplicaRouter) CheckLag(ctx context.Context) error {
+	var primaryLSN int64
+	err := r.primary.QueryRow(ctx, `SELECT (pg_current_wal_insert_lsn() - '0/0')::bigint`).Scan(&primaryLSN)
+	if err != nil {
+		return fmt.Errorf("failed to read primary WAL position: %w", err)
+	}
+
+	for _, rep := range r.replicas {
+		lag, err := rep.lag(ctx, primaryLSN)
+		if err == nil {
+			replicaLag.WithLabelValues(rep.name).Set(lag.Seconds())
+		}
+
+		fresh := err == nil && lag <= r.maxLag
+		if rep.fresh.Swap(fresh) == fresh {
+			continue
+		}
+		if fresh {
+			r.logger.Info("database replica caught up", zap.String("replica", rep.name), zap.Duration("lag", lag))
+		} else {
+			r.logger.Warn("database replica taken out of rotation",
+				zap.String("replica", rep.name),
+				zap.Duration("lag", lag),
+				zap.Error(err),
+			)
+		}
+	}
+
+	return nil
+}
+
+// lag is zero once the replica has replayed the WAL up to primaryLSN, and
+// otherwise the age of the last transaction it replayed.
+func (rep *replica) lag(ctx context.Context, primaryLSN int64) (time.Duration, error) {
+	var (
+		inRecovery bool
+		replayed   int64
+		age        float64
+	)
+	err := rep.pool.QueryRow(ctx, `
+		SELECT pg_is_in_recovery(),
+			(COALESCE(pg_last_wal_replay_lsn(), '0/0') - '0/0')::bigint,
+			COALESCE(EXTRACT(EPOCH FROM NOW() - pg_last_xact_replay_timestamp()), 0)::float8
+	`).Scan(&inRecovery, &replayed, &age)
+	if err != nil {
+		return 0, fmt.Errorf("failed to read replica lag: %w", err)
+	}
+
+	// A promoted replica no longer follows the primary
+	if !inRecovery {
+		return 0, errors.New("server is not a replica")
+	}
+	if replayed >= primaryLSN {
+		return 0, nil
+	}
+	return time.Duration(age * float64(time.Second)), nil
+}
+
+// replayed returns the WAL position the replica has replayed up to.
+func (rep *replica) replayed(ctx context.Context) (int64, error) {
+	var replayed int64
+	err := rep.pool.QueryRow(ctx, `SELECT (COALESCE(pg_last_wal_replay_lsn(), '0/0') - '0/0')::bigint`).Scan(&replayed)
+	return replayed, err
+}
+
+// readPool returns the pool for a read-only query that may see data a few
+// seconds old: a replica when router has one in rotation, else primary.
+func readPool(router *ReplicaRouter, primary *pgxpool.Pool) *pgxpool.Pool {
+	if router == nil {
+		return primary
+	}
+	return router.Read()
+}
@@ -0,0 +1,43 @@
+package repository
+
+import (
+	"testing"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+func TestReplicaRouter_Read(t *testing.T) {
+	primary, first, second := &pgxpool.Pool{}, &pgxpool.Pool{}, &pgxpool.Pool{}
+	router := &ReplicaRouter{
+		primary:  primary,
+		replicas: []*replica{{name: "first", pool: first}, {name: "second", pool: second}},
+	}
+
+	if got := router.Read(); got != primary {
+		t.Error("expected reads to go to the primary before any replica is checked")
+	}
+
+	router.replicas[0].fresh.Store(true)
+	router.replicas[1].fresh.Store(true)
+	seen := map[*pgxpool.Pool]int{}
+	for i := 0; i < 4; i++ {
+		seen[router.Read()]++
+	}
+	if seen[first] != 2 || seen[second] != 2 {
+		t.Errorf("expected reads to alternate between replicas, got %d and %d", seen[first], seen[second])
+	}
+
+	router.replicas[0].fresh.Store(false)
+	for i := 0; i < 2; i++ {
+		if got := router.Read(); got != second {
+			t.Error("expected a lagging replica to be skipped")
+		}
+	}
+}
+
+func TestReadPool_WithoutReplicas(t *testing.T) {
+	primary := &pgxpool.Pool{}
+	if got := readPool(nil, primary); got != primary {
+		t.Error("expected reads to go to the primary without a router")
+	}
+}
@@ -0,0 +1,241 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// PeriodStats aggregates a user's entries dated within a period.
+type PeriodStats struct {
+	Entries      int
+	AverageScore *float64   // nil without entries
+	TopEntryID   *uuid.UUID // highest score, most recent on ties
+	Types        []TypeCount
+}
+
+// TypeCount is the number of entries of one type. TypeID, Name and Icon are
+// nil for entries without a type.
+type TypeCount struct {
+	TypeID *uuid.UUID
+	Name   *string
+	Icon   *string
+	Count  int
+}
+
+// DailyCount is a per-day total; Day is midnight UTC.
+type DailyCount struct {
+	Day   time.Time
+	Count int64
+}
+
+// StorageUsage is the on-disk size of the database and its largest tables,
+// including indexes and TOAST data.
+type StorageUsage struct {
+	DatabaseBytes int64
+	EntriesBytes  int64
+	ImagesBytes   int64
+	Images        int64
+}
+
+type StatsRepository struct {
+	db       *pgxpool.Pool
+	replicas *ReplicaRouter // nil reads from db
+}
+
+func NewStatsRepository(db *pgxpool.Pool) *StatsRepository {
+	return &StatsRepository{db: db}
+}
+
+// UseReplicas sends all stats queries to replicas; none of them decide a
+// write.
+func (r *StatsRepository) UseReplicas(router *ReplicaRouter) {
+	r.replicas = router
+}
+
+func (r *StatsRepository) reader() *pgxpool.Pool {
+	return readPool(r.replicas, r.db)
+}
+
+// CountEntriesInPeriod counts a user's entries dated in [from, to).
+func (r *StatsRepository) CountEntriesInPeriod(ctx context.Context, userID uuid.UUID, from, to time.Time) (int, error) {
+	query := `SELECT COUNT(*) FROM entries WHERE user_id = $1 AND date >= $2 AND date < $3`
+
+	var count int
+	if err := r.reader().QueryRow(ctx, query, userID, from, to).Scan(&count); err != nil {
+		return 0, fmt.Errorf("failed to count entries: %w", err)
+	}
+
+	return count, nil
+}
+
+// GetPeriodStats aggregates a user's entries dated in [from, to).
+func (r *StatsRepository) GetPeriodStats(ctx context.Context, userID uuid.UUID, from, to time.Time) (*PeriodStats, error) {
+	var stats PeriodStats
+	db := r.reader()
+
+	totalsQuery := `
+		SELECT COUNT(*), AVG(score)::float8
+		FROM entries
+		WHERE user_id = $1 AND date >= $2 AND date < $3
+	`
+
+	err := db.QueryRow(ctx, totalsQuery, userID, from, to).Scan(&stats.Entries, &stats.AverageScore)
+	if err != nil {
+		return nil, fmt.Errorf("failed to aggregate entries: %w", err)
+	}
+
+	if stats.Entries == 0 {
+		return &stats, nil
+	}
+
+	topQuery := `
+		SELECT id
+		FROM entries
+		WHERE user_id = $1 AND date >= $2 AND date < $3
+		ORDER BY score DESC, date DESC, created_at DESC
+		LIMIT 1
+	`
+
+	var topID uuid.UUID
+	err = db.QueryRow(ctx, topQuery, userID, from, to).Scan(&topID)
+	if err != nil && !errors.Is(err, pgx.ErrNoRows) {
+		return nil, fmt.Errorf("failed to get top entry: %w", err)
+	}
+	if err == nil {
+		stats.TopEntryID = &topID
+	}
+
+	typesQuery := `
+		SELECT e.type_id, t.name, t.icon, COUNT(*)
+		FROM entries e
+		LEFT JOIN entry_types t ON t.id = e.type_id
+		WHERE e.user_id = $1 AND e.date >= $2 AND e.date < $3
+		GROUP BY e.type_id, t.name, t.icon
+		ORDER BY COUNT(*) DESC, t.name ASC
+	`
+
+	rows, err := db.Query(ctx, typesQuery, userID, from, to)
+	if err != nil {
+		return nil, fmt.Errorf("failed to count entries by type: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var tc TypeCount
+		if err := rows.Scan(&tc.TypeID, &tc.Name, &tc.Icon, &tc.Count); err != nil {
+			return nil, fmt.Errorf("failed to scan type count: %w", err)
+		}
+		stats.Types = append(stats.Types, tc)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating type counts: %w", err)
+	}
+
+	return &stats, nil
+}
+
+// CountUsers returns the number of users that are not deleted.
+func (r *StatsRepository) CountUsers(ctx context.Context) (int64, error) {
+	query := `SELECT COUNT(*) FROM users WHERE deleted_at IS NULL`
+
+	var count int64
+	if err := r.reader().QueryRow(ctx, query).Scan(&count); err != nil {
+		return 0, fmt.Errorf("failed to count users: %w", err)
+	}
+
+	return count, nil
+}
+
+// DailyActiveUsers counts, per UTC day since from, the distinct users that
+// signed in or refreshed a token.
+func (r *StatsRepository) DailyActiveUsers(ctx context.Context, from time.Time) ([]DailyCount, error) {
+	return r.dailySeries(ctx, `
+		SELECT (created_at AT TIME ZONE 'UTC')::date AS day, COUNT(DISTINCT user_id)
+		FROM user_tokens
+		WHERE created_at >= $1::timestamptz
+		GROUP BY 1
+	`, from)
+}
+
+// EntriesPerDay counts entries created per UTC day since from.
+func (r *StatsRepository) EntriesPerDay(ctx context.Context, from time.Time) ([]DailyCount, error) {
+	return r.dailySeries(ctx, `
+		SELECT (created_at AT TIME ZONE 'UTC')::date AS day, COUNT(*)
+		FROM entries
+		WHERE created_at >= $1::timestamptz
+		GROUP BY 1
+	`, from)
+}
+
+// AISearchesPerDay returns the AI searches made per UTC day since from.
+func (r *StatsRepository) AISearchesPerDay(ctx context.Context, from time.Time) ([]DailyCount, error) {
+	return r.dailySeries(ctx, `
+		SELECT day, searches
+		FROM ai_search_daily
+		WHERE day >= ($1::timestamptz AT TIME ZONE 'UTC')::date
+	`, from)
+}
+
+// GetStorageUsage reports table sizes from the catalog, without scanning rows.
+// Images is the planner's row estimate.
+func (r *StatsRepository) GetStorageUsage(ctx context.Context) (*StorageUsage, error) {
+	query := `
+		SELECT
+			pg_database_size(current_database()),
+			pg_total_relation_size('entries'),
+			pg_total_relation_size('entry_images'),
+			GREATEST((SELECT reltuples FROM pg_class WHERE oid = 'entry_images'::regclass), 0)::bigint
+	`
+
+	var usage StorageUsage
+	err := r.reader().QueryRow(ctx, query).Scan(
+		&usage.DatabaseBytes,
+		&usage.EntriesBytes,
+		&usage.ImagesBytes,
+		&usage.Images,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get storage usage: %w", err)
+	}
+
+	return &usage, nil
+}
+
+// dailySeries runs a (day, count) aggregate and fills days without rows with
+// zero, so the result has one element per UTC day from from through today.
+func (r *StatsRepository) dailySeries(ctx context.Context, aggregate string, from time.Time) ([]DailyCount, error) {
+	query := `
+		SELECT d::date, COALESCE(c.count, 0)
+		FROM generate_series(($1::timestamptz AT TIME ZONE 'UTC')::date, (NOW() AT TIME ZONE 'UTC')::date, interval '1 day') d
+		LEFT JOIN (` + aggregate + `) AS c (day, count) ON c.day = d::date
+		ORDER BY 1
+	`
+
+	rows, err := r.reader().Query(ctx, query, from)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query daily counts: %w", err)
+	}
+	defer rows.Close()
+
+	var counts []DailyCount
+	for rows.Next() {
+		var c DailyCount
+		if err := rows.Scan(&c.Day, &c.Count); err != nil {
+			return nil, fmt.Errorf("failed to scan daily count: %w", err)
+		}
+		counts = append(counts, c)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating daily counts: %w", err)
+	}
+
+	return counts, nil
+}
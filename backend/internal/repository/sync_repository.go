@@ -0,0 +1,325 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// Tombstone records a deleted row so offline clients can drop their copy.
+type Tombstone struct {
+	EntityType string    `json:"entity_type"` // "entry", "collection" or "type"
+	EntityID   uuid.UUID `json:"entity_id"`
+	DeletedAt  time.Time `json:"deleted_at"`
+}
+
+// ChangeSet is everything that changed for a user since a sync cursor.
+type ChangeSet struct {
+	Cursor      string
+	Entries     []*EntryWithImages
+	Collections []*Collection
+	Types       []*EntryType
+	Deleted     []Tombstone
+}
+
+// ChangeEvent announces that an entry or collection was written. The outbox
+// relay sends it on the change channel.
+type ChangeEvent struct {
+	UserID uuid.UUID `json:"user_id"`
+	Entity string    `json:"entity"` // "entry" or "collection"
+	ID     uuid.UUID `json:"id"`
+	Op     string    `json:"op"` // "insert", "update" or "delete"
+}
+
+// changeChannel is the Postgres NOTIFY channel carrying ChangeEvents.
+const changeChannel = "sync_changes"
+
+type SyncRepository struct {
+	db       *pgxpool.Pool
+	replicas *ReplicaRouter // nil reads from db
+}
+
+func NewSyncRepository(db *pgxpool.Pool) *SyncRepository {
+	return &SyncRepository{db: db}
+}
+
+// UseReplicas makes GetChanges read from replicas. The change stream keeps
+// listening on the primary, as replicas don't deliver notifications.
+func (r *SyncRepository) UseReplicas(router *ReplicaRouter) {
+	r.replicas = router
+}
+
+// ChangeSeq returns the primary's current WAL position as a number. It grows
+// with every write, so a change sequence read after a commit is at or past
+// that commit's WAL record.
+func (r *SyncRepository) ChangeSeq(ctx context.Context) (int64, error) {
+	var seq int64
+	err := r.db.QueryRow(ctx, `SELECT (pg_current_wal_insert_lsn() - '0/0')::bigint`).Scan(&seq)
+	if err != nil {
+		return 0, fmt.Errorf("failed to read change sequence: %w", err)
+	}
+	return seq, nil
+}
+
+// GetChanges returns rows changed since the given cursor ("0" for a full sync)
+// together with the cursor for the next call. All reads share one snapshot, and
+// the new cursor is that snapshot's xmin: anything committed later, or still in
+// flight now, has a change_xid at or above it and is returned next time. A row
+// may therefore be delivered twice; clients apply changes idempotently.
+//
+// The snapshot includes every write committed before minSeq was read from
+// ChangeSeq: the replica is only used once it has replayed that far, and the
+// snapshot is taken after checking.
+func (r *SyncRepository) GetChanges(
+	ctx context.Context,
+	userID uuid.UUID,
+	since string,
+	minSeq int64,
+) (*ChangeSet, error) {
+	pool := r.db
+	if r.replicas != nil {
+		pool = r.replicas.ReadAt(ctx, minSeq)
+	}
+
+	tx, err := pool.BeginTx(ctx, pgx.TxOptions{
+		IsoLevel:   pgx.RepeatableRead,
+		AccessMode: pgx.ReadOnly,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	var changes ChangeSet
+	err = tx.QueryRow(ctx, `SELECT pg_snapshot_xmin(pg_current_snapshot())::text`).Scan(&changes.Cursor)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read sync cursor: %w", err)
+	}
+
+	if changes.Entries, err = r.changedEntries(ctx, tx, userID, since); err != nil {
+		return nil, err
+	}
+	if changes.Collections, err = r.changedCollections(ctx, tx, userID, since); err != nil {
+		return nil, err
+	}
+	if changes.Types, err = r.changedTypes(ctx, tx, userID, since); err != nil {
+		return nil, err
+	}
+	if changes.Deleted, err = r.tombstones(ctx, tx, userID, since); err != nil {
+		return nil, err
+	}
+
+	return &changes, nil
+}
+
+func (r *SyncRepository) changedEntries(
+	ctx context.Context,
+	tx pgx.Tx,
+	userID uuid.UUID,
+	since string,
+) ([]*EntryWithImages, error) {
+	query := `
+		SELECT ` + entryWithImagesColumns + `
+		FROM entries e
+		` + entryImagesLateralJoin + `
+		WHERE e.user_id = $1 AND e.change_xid >= $2::text::xid8
+		ORDER BY e.change_xid ASC
+	`
+
+	rows, err := tx.Query(ctx, query, userID, since)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query changed entries: %w", err)
+	}
+	defer rows.Close()
+
+	var entries []*EntryWithImages
+	for rows.Next() {
+		entry, err := scanEntryWithImages(rows)
+		if err != nil {
+			return nil, err
+		}
+		entries = append(entries, entry)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating changed entries: %w", err)
+	}
+
+	return entries, nil
+}
+
+func (r *SyncRepository) changedCollections(
+	ctx context.Context,
+	tx pgx.Tx,
+	userID uuid.UUID,
+	since string,
+) ([]*Collection, error) {
+	query := `
+		SELECT c.id, c.user_id, c.name, COALESCE(c.icon, ''), c.icon_image_id,
+			COALESCE((SELECT ci.content_hash FROM collection_icons ci WHERE ci.id = c.icon_image_id), ''), c.workspace_id, c.allowed_type_ids, c.share_slug, COUNT(e.id) AS entry_count, c.created_at, c.updated_at
+		FROM collections c
+		LEFT JOIN entries e ON e.collection_id = c.id
+		WHERE c.user_id = $1 AND c.change_xid >= $2::text::xid8
+		GROUP BY c.id
+		ORDER BY c.change_xid ASC
+	`
+
+	rows, err := tx.Query(ctx, query, userID, since)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query changed collections: %w", err)
+	}
+	defer rows.Close()
+
+	var collections []*Collection
+	for rows.Next() {
+		var c Collection
+		err := rows.Scan(
+			&c.ID,
+			&c.UserID,
+			&c.Name,
+			&c.Icon,
+			&c.IconImageID,
+			&c.IconImageHash,
+			&c.WorkspaceID,
+			&c.AllowedTypeIDs,
+			&c.ShareSlug,
+			&c.EntryCount,
+			&c.CreatedAt,
+			&c.UpdatedAt,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan collection: %w", err)
+		}
+		collections = append(collections, &c)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating changed collections: %w", err)
+	}
+
+	return collections, nil
+}
+
+func (r *SyncRepository) changedTypes(
+	ctx context.Context,
+	tx pgx.Tx,
+	userID uuid.UUID,
+	since string,
+) ([]*EntryType, error) {
+	query := `
+		SELECT id, user_id, workspace_id, name, icon, fields, score_scale, created_at, updated_at
+		FROM entry_types
+		WHERE (user_id IS NULL OR user_id = $1) AND change_xid >= $2::text::xid8
+		ORDER BY change_xid ASC
+	`
+
+	rows, err := tx.Query(ctx, query, userID, since)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query changed entry types: %w", err)
+	}
+	defer rows.Close()
+
+	var types []*EntryType
+	for rows.Next() {
+		var t EntryType
+		var fieldsStr, scaleStr string
+		err := rows.Scan(
+			&t.ID,
+			&t.UserID,
+			&t.WorkspaceID,
+			&t.Name,
+			&t.Icon,
+			&fieldsStr,
+			&scaleStr,
+			&t.CreatedAt,
+			&t.UpdatedAt,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan entry type: %w", err)
+		}
+		if err := json.Unmarshal([]byte(fieldsStr), &t.Fields); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal type fields: %w", err)
+		}
+		if err := json.Unmarshal([]byte(scaleStr), &t.ScoreScale); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal type score scale: %w", err)
+		}
+		types = append(types, &t)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating changed entry types: %w", err)
+	}
+
+	return types, nil
+}
+
+func (r *SyncRepository) tombstones(
+	ctx context.Context,
+	tx pgx.Tx,
+	userID uuid.UUID,
+	since string,
+) ([]Tombstone, error) {
+	query := `
+		SELECT entity_type, entity_id, deleted_at
+		FROM sync_tombstones
+		WHERE user_id = $1 AND change_xid >= $2::text::xid8
+		ORDER BY change_xid ASC, id ASC
+	`
+
+	rows, err := tx.Query(ctx, query, userID, since)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query tombstones: %w", err)
+	}
+	defer rows.Close()
+
+	var tombstones []Tombstone
+	for rows.Next() {
+		var t Tombstone
+		if err := rows.Scan(&t.EntityType, &t.EntityID, &t.DeletedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan tombstone: %w", err)
+		}
+		tombstones = append(tombstones, t)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating tombstones: %w", err)
+	}
+
+	return tombstones, nil
+}
+
+// ListenChanges calls fn for every change event until ctx is cancelled or the
+// connection fails. It holds a dedicated connection taken out of the pool for
+// as long as it runs, and always returns a non-nil error.
+func (r *SyncRepository) ListenChanges(ctx context.Context, fn func(ChangeEvent)) error {
+	poolConn, err := r.db.Acquire(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to acquire connection: %w", err)
+	}
+
+	// A listening connection must not go back to the pool
+	conn := poolConn.Hijack()
+	defer conn.Close(context.Background())
+
+	if _, err := conn.Exec(ctx, "LISTEN "+changeChannel); err != nil {
+		return fmt.Errorf("failed to listen for changes: %w", err)
+	}
+
+	for {
+		n, err := conn.WaitForNotification(ctx)
+		if err != nil {
+			return fmt.Errorf("failed to wait for notification: %w", err)
+		}
+
+		var event ChangeEvent
+		if err := json.Unmarshal([]byte(n.Payload), &event); err != nil {
+			continue
+		}
+		fn(event)
+	}
+}
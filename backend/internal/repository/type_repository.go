@@ -23,71 +23,137 @@ type FieldDefinition struct {
 	Type  string `json:"type"` // "string" or "number"
 }
 
+// Score scale kinds. Score 0 always means "not rated yet"; rated entries
+// score from 1 to the scale's Max.
+const (
+	ScoreScaleReaction = "reaction"
+	ScoreScaleThumbs   = "thumbs"
+	ScoreScaleStars    = "stars"
+	ScoreScalePoints   = "points"
+)
+
+// ScoreScale describes what an entry type's score means. Labels[i] names
+// score i+1.
+type ScoreScale struct {
+	Kind   string   `json:"kind"`
+	Max    int      `json:"max"`
+	Labels []string `json:"labels"`
+}
+
 type EntryType struct {
-	ID        uuid.UUID         `json:"id"`
-	UserID    *uuid.UUID        `json:"user_id,omitempty"`
-	Name      string            `json:"name"`
-	Icon      string            `json:"icon"`
-	Fields    []FieldDefinition `json:"fields"`
-	CreatedAt time.Time         `json:"created_at"`
-	UpdatedAt time.Time         `json:"updated_at"`
+	ID          uuid.UUID         `json:"id"`
+	UserID      *uuid.UUID        `json:"user_id,omitempty"`
+	WorkspaceID *uuid.UUID        `json:"workspace_id,omitempty"` // nil for the owner's personal workspace
+	Name        string            `json:"name"`
+	Icon        string            `json:"icon"`
+	Fields      []FieldDefinition `json:"fields"`
+	ScoreScale  ScoreScale        `json:"score_scale"`
+	CreatedAt   time.Time         `json:"created_at"`
+	UpdatedAt   time.Time         `json:"updated_at"`
 }
 
 type TypeRepository struct {
-	db *pgxpool.Pool
+	db       *pgxpool.Pool
+	replicas *ReplicaRouter // nil reads from db
 }
 
 func NewTypeRepository(db *pgxpool.Pool) *TypeRepository {
 	return &TypeRepository{db: db}
 }
 
+// UseReplicas sends ListTypesPage to replicas.
+func (r *TypeRepository) UseReplicas(router *ReplicaRouter) {
+	r.replicas = router
+}
+
 // GetAllTypes returns system types (user_id IS NULL) plus the given user's own types.
 func (r *TypeRepository) GetAllTypes(
 	ctx context.Context,
 	userID uuid.UUID,
 ) ([]*EntryType, error) {
+	types, _, err := r.listTypesPage(ctx, r.db, userID, nil, 0)
+	return types, err
+}
+
+// ListTypesPage returns up to limit of the system and the user's own types
+// after the cursor, and the cursor of the next page (nil on the last page).
+// Types are listed oldest first with the system "Other" type last. A nil
+// after starts at the beginning; a zero limit returns all.
+func (r *TypeRepository) ListTypesPage(
+	ctx context.Context,
+	userID uuid.UUID,
+	after *PageCursor,
+	limit int,
+) ([]*EntryType, *PageCursor, error) {
+	return r.listTypesPage(ctx, readPool(r.replicas, r.db), userID, after, limit)
+}
+
+func (r *TypeRepository) listTypesPage(
+	ctx context.Context,
+	db *pgxpool.Pool,
+	userID uuid.UUID,
+	after *PageCursor,
+	limit int,
+) ([]*EntryType, *PageCursor, error) {
 	query := `
-		SELECT id, user_id, name, icon, fields, created_at, updated_at
-		FROM entry_types
-		WHERE user_id IS NULL OR user_id = $1
-		ORDER BY
-			CASE WHEN user_id IS NULL AND name = 'Other' THEN 1 ELSE 0 END ASC,
-			created_at ASC
+		SELECT id, user_id, workspace_id, name, icon, fields, score_scale, created_at, updated_at, rank
+		FROM (
+			SELECT *, CASE WHEN user_id IS NULL AND name = 'Other' THEN 1 ELSE 0 END AS rank
+			FROM entry_types
+			WHERE user_id IS NULL OR user_id = $1
+		) t
+		WHERE $2::int IS NULL OR (rank, created_at, id) > ($2, $3, $4)
+		ORDER BY rank ASC, created_at ASC, id ASC
+		LIMIT $5
 	`
 
-	rows, err := r.db.Query(ctx, query, userID)
+	afterRank, afterCreatedAt, afterID, fetch := pageArgs(after, limit)
+	rows, err := db.Query(ctx, query, userID, afterRank, afterCreatedAt, afterID, fetch)
 	if err != nil {
-		return nil, fmt.Errorf("failed to query entry types: %w", err)
+		return nil, nil, fmt.Errorf("failed to query entry types: %w", err)
 	}
 	defer rows.Close()
 
 	var types []*EntryType
+	var ranks []int
 	for rows.Next() {
 		var t EntryType
-		var fieldsStr string
+		var fieldsStr, scaleStr string
+		var rank int
 		err := rows.Scan(
 			&t.ID,
 			&t.UserID,
+			&t.WorkspaceID,
 			&t.Name,
 			&t.Icon,
 			&fieldsStr,
+			&scaleStr,
 			&t.CreatedAt,
 			&t.UpdatedAt,
+			&rank,
 		)
 		if err != nil {
-			return nil, fmt.Errorf("failed to scan entry type: %w", err)
+			return nil, nil, fmt.Errorf("failed to scan entry type: %w", err)
 		}
 		if err := json.Unmarshal([]byte(fieldsStr), &t.Fields); err != nil {
-			return nil, fmt.Errorf("failed to unmarshal type fields: %w", err)
+			return nil, nil, fmt.Errorf("failed to unmarshal type fields: %w", err)
+		}
+		if err := json.Unmarshal([]byte(scaleStr), &t.ScoreScale); err != nil {
+			return nil, nil, fmt.Errorf("failed to unmarshal type score scale: %w", err)
 		}
 		types = append(types, &t)
+		ranks = append(ranks, rank)
 	}
 
 	if err := rows.Err(); err != nil {
-		return nil, fmt.Errorf("error iterating entry types: %w", err)
+		return nil, nil, fmt.Errorf("error iterating entry types: %w", err)
 	}
 
-	return types, nil
+	if limit == 0 || len(types) <= limit {
+		return types, nil, nil
+	}
+	last := types[limit-1]
+	return types[:limit], &PageCursor{Rank: ranks[limit-1], CreatedAt: last.CreatedAt, ID: last.ID}, nil
 }
 
 // GetTypeByID retrieves a single entry type by ID.
@@ -96,19 +162,21 @@ func (r *TypeRepository) GetTypeByID(
 	id uuid.UUID,
 ) (*EntryType, error) {
 	query := `
-		SELECT id, user_id, name, icon, fields, created_at, updated_at
+		SELECT id, user_id, workspace_id, name, icon, fields, score_scale, created_at, updated_at
 		FROM entry_types
 		WHERE id = $1
 	`
 
 	var t EntryType
-	var fieldsStr string
+	var fieldsStr, scaleStr string
 	err := r.db.QueryRow(ctx, query, id).Scan(
 		&t.ID,
 		&t.UserID,
+		&t.WorkspaceID,
 		&t.Name,
 		&t.Icon,
 		&fieldsStr,
+		&scaleStr,
 		&t.CreatedAt,
 		&t.UpdatedAt,
 	)
@@ -122,6 +190,9 @@ func (r *TypeRepository) GetTypeByID(
 	if err := json.Unmarshal([]byte(fieldsStr), &t.Fields); err != nil {
 		return nil, fmt.Errorf("failed to unmarshal type fields: %w", err)
 	}
+	if err := json.Unmarshal([]byte(scaleStr), &t.ScoreScale); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal type score scale: %w", err)
+	}
 
 	return &t, nil
 }
@@ -131,21 +202,29 @@ func (r *TypeRepository) CreateType(
 	ctx context.Context,
 	userID *uuid.UUID,
 	name, icon string,
+	scoreScale ScoreScale,
 ) (*EntryType, error) {
+	scaleJSON, err := json.Marshal(scoreScale)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal score scale: %w", err)
+	}
+
 	query := `
-		INSERT INTO entry_types (user_id, name, icon)
-		VALUES ($1, $2, $3)
-		RETURNING id, user_id, name, icon, fields, created_at, updated_at
+		INSERT INTO entry_types (user_id, name, icon, score_scale)
+		VALUES ($1, $2, $3, $4)
+		RETURNING id, user_id, workspace_id, name, icon, fields, score_scale, created_at, updated_at
 	`
 
 	var t EntryType
-	var fieldsStr string
-	err := r.db.QueryRow(ctx, query, userID, name, icon).Scan(
+	var fieldsStr, scaleStr string
+	err = r.db.QueryRow(ctx, query, userID, name, icon, scaleJSON).Scan(
 		&t.ID,
 		&t.UserID,
+		&t.WorkspaceID,
 		&t.Name,
 		&t.Icon,
 		&fieldsStr,
+		&scaleStr,
 		&t.CreatedAt,
 		&t.UpdatedAt,
 	)
@@ -156,6 +235,83 @@ func (r *TypeRepository) CreateType(
 	if err := json.Unmarshal([]byte(fieldsStr), &t.Fields); err != nil {
 		return nil, fmt.Errorf("failed to unmarshal type fields: %w", err)
 	}
+	if err := json.Unmarshal([]byte(scaleStr), &t.ScoreScale); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal type score scale: %w", err)
+	}
 
 	return &t, nil
 }
+
+// GetTypesByWorkspace returns the types placed in a shared workspace,
+// regardless of which member owns them.
+func (r *TypeRepository) GetTypesByWorkspace(
+	ctx context.Context,
+	workspaceID uuid.UUID,
+) ([]*EntryType, error) {
+	query := `
+		SELECT id, user_id, workspace_id, name, icon, fields, score_scale, created_at, updated_at
+		FROM entry_types
+		WHERE workspace_id = $1
+		ORDER BY created_at ASC
+	`
+
+	rows, err := r.db.Query(ctx, query, workspaceID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query workspace entry types: %w", err)
+	}
+	defer rows.Close()
+
+	var types []*EntryType
+	for rows.Next() {
+		var t EntryType
+		var fieldsStr, scaleStr string
+		err := rows.Scan(
+			&t.ID,
+			&t.UserID,
+			&t.WorkspaceID,
+			&t.Name,
+			&t.Icon,
+			&fieldsStr,
+			&scaleStr,
+			&t.CreatedAt,
+			&t.UpdatedAt,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan entry type: %w", err)
+		}
+		if err := json.Unmarshal([]byte(fieldsStr), &t.Fields); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal type fields: %w", err)
+		}
+		if err := json.Unmarshal([]byte(scaleStr), &t.ScoreScale); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal type score scale: %w", err)
+		}
+		types = append(types, &t)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating entry types: %w", err)
+	}
+
+	return types, nil
+}
+
+// SetTypeWorkspace moves a user-owned type into a shared workspace, or back
+// to its owner's personal workspace when workspaceID is nil.
+func (r *TypeRepository) SetTypeWorkspace(
+	ctx context.Context,
+	id uuid.UUID,
+	workspaceID *uuid.UUID,
+) error {
+	query := `UPDATE entry_types SET workspace_id = $2, updated_at = NOW() WHERE id = $1 AND user_id IS NOT NULL`
+
+	result, err := r.db.Exec(ctx, query, id, workspaceID)
+	if err != nil {
+		return fmt.Errorf("failed to set entry type workspace: %w", err)
+	}
+
+	if result.RowsAffected() == 0 {
+		return ErrTypeNotFound
+	}
+
+	return nil
+}
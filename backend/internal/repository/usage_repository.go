@@ -0,0 +1,97 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// DailyUsage is what a user used on one (UTC) day, see user_usage_daily.
+type DailyUsage struct {
+	Day        time.Time
+	APICalls   int64
+	AISearches int
+	Exports    int
+}
+
+type UsageRepository struct {
+	db *pgxpool.Pool
+}
+
+func NewUsageRepository(db *pgxpool.Pool) *UsageRepository {
+	return &UsageRepository{db: db}
+}
+
+// AddAPICalls adds calls per user to their usage on day. Users purged since
+// the calls were counted are skipped.
+func (r *UsageRepository) AddAPICalls(ctx context.Context, day time.Time, calls map[uuid.UUID]int64) error {
+	if len(calls) == 0 {
+		return nil
+	}
+
+	userIDs := make([]uuid.UUID, 0, len(calls))
+	counts := make([]int64, 0, len(calls))
+	for userID, n := range calls {
+		userIDs = append(userIDs, userID)
+		counts = append(counts, n)
+	}
+
+	query := `
+		INSERT INTO user_usage_daily (user_id, day, api_calls)
+		SELECT c.user_id, $1::date, c.calls
+		FROM unnest($2::uuid[], $3::bigint[]) AS c(user_id, calls)
+		JOIN users u ON u.id = c.user_id
+		ON CONFLICT (user_id, day) DO UPDATE SET api_calls = user_usage_daily.api_calls + EXCLUDED.api_calls
+	`
+
+	if _, err := r.db.Exec(ctx, query, day, userIDs, counts); err != nil {
+		return fmt.Errorf("failed to add api calls: %w", err)
+	}
+
+	return nil
+}
+
+// ListDailyUsage returns a user's usage from the given day on, oldest first.
+// Days without usage have no row.
+func (r *UsageRepository) ListDailyUsage(ctx context.Context, userID uuid.UUID, from time.Time) ([]*DailyUsage, error) {
+	query := `
+		SELECT day, api_calls, ai_searches, exports
+		FROM user_usage_daily
+		WHERE user_id = $1 AND day >= $2::date
+		ORDER BY day
+	`
+
+	rows, err := r.db.Query(ctx, query, userID, from)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query usage: %w", err)
+	}
+	defer rows.Close()
+
+	days := []*DailyUsage{}
+	for rows.Next() {
+		var d DailyUsage
+		if err := rows.Scan(&d.Day, &d.APICalls, &d.AISearches, &d.Exports); err != nil {
+			return nil, fmt.Errorf("failed to scan usage: %w", err)
+		}
+		days = append(days, &d)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating usage: %w", err)
+	}
+
+	return days, nil
+}
+
+// DeleteUsageOlderThan removes usage of days more than age ago.
+func (r *UsageRepository) DeleteUsageOlderThan(ctx context.Context, age time.Duration) (int64, error) {
+	result, err := r.db.Exec(ctx,
+		`DELETE FROM user_usage_daily WHERE day < (NOW() - make_interval(secs => $1))::date`, age.Seconds())
+	if err != nil {
+		return 0, fmt.Errorf("failed to delete usage: %w", err)
+	}
+	return result.RowsAffected(), nil
+}
@@ -4,18 +4,25 @@ import (
 	"context"
 	"crypto/sha256"
 	"encoding/hex"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"time"
 
+	"github.com/avalarin/livlog/backend/internal/fieldcrypt"
 	"github.com/google/uuid"
 	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
 	"github.com/jackc/pgx/v5/pgxpool"
 )
 
 var (
 	ErrUserNotFound         = errors.New("user not found")
 	ErrRefreshTokenNotFound = errors.New("refresh token not found")
+	ErrEmailTaken           = errors.New("email already in use")
+	// ErrUserDeleted is returned for accounts the user deleted that the
+	// retention job hasn't purged yet.
+	ErrUserDeleted = errors.New("user is deleted and awaiting purge")
 )
 
 // AIUsagePolicy represents the AI usage policy for a user
@@ -27,15 +34,49 @@ const (
 	AIUsagePolicyUnlimited AIUsagePolicy = "unlimited"
 )
 
+// UserRole separates operators from regular users
+type UserRole string
+
+const (
+	UserRoleUser  UserRole = "user"
+	UserRoleAdmin UserRole = "admin"
+)
+
 type User struct {
-	ID            uuid.UUID     `json:"id"`
-	Email         *string       `json:"email"`
-	EmailVerified bool          `json:"email_verified"`
-	DisplayName   *string       `json:"display_name"`
-	AIUsagePolicy AIUsagePolicy `json:"ai_usage_policy"`
-	CreatedAt     time.Time     `json:"created_at"`
-	UpdatedAt     time.Time     `json:"updated_at"`
-	DeletedAt     *time.Time    `json:"deleted_at,omitempty"`
+	ID             uuid.UUID     `json:"id"`
+	Email          *string       `json:"email"`
+	EmailVerified  bool          `json:"email_verified"`
+	IsPrivateEmail bool          `json:"is_private_email"` // Apple private relay address
+	DisplayName    *string       `json:"display_name"`
+	AIUsagePolicy  AIUsagePolicy `json:"ai_usage_policy"`
+	Role           UserRole      `json:"role"`
+	CreatedAt      time.Time     `json:"created_at"`
+	UpdatedAt      time.Time     `json:"updated_at"`
+	DeletedAt      *time.Time    `json:"deleted_at,omitempty"`
+	// When the user allowed their AI search queries to be sent to the model
+	// provider, and their entries to be enriched by it; nil until they do.
+	AIQueryConsentAt      *time.Time `json:"ai_query_consent_at,omitempty"`
+	AIEnrichmentConsentAt *time.Time `json:"ai_enrichment_consent_at,omitempty"`
+	// Files entries created without a collection in the suggested one, see
+	// EntryRepository.SuggestCollection.
+	AutoAssignCollection bool `json:"auto_assign_collection"`
+}
+
+// Sources of email events
+const (
+	EmailEventApple        = "apple"        // Sign in with Apple reported a new email
+	EmailEventVerification = "verification" // the user verified their email with a code
+)
+
+// EmailEvent is a transition of a user's email or its verification, kept
+// in user_email_events. Addresses aren't kept.
+type EmailEvent struct {
+	ID           uuid.UUID `json:"id"`
+	Source       string    `json:"source"`
+	EmailChanged bool      `json:"email_changed"`
+	WasVerified  bool      `json:"was_verified"`
+	Verified     bool      `json:"verified"`
+	CreatedAt    time.Time `json:"created_at"`
 }
 
 type RefreshToken struct {
@@ -43,49 +84,82 @@ type RefreshToken struct {
 	UserID           uuid.UUID  `json:"user_id"`
 	RefreshTokenHash string     `json:"-"`
 	DeviceInfo       *string    `json:"device_info,omitempty"`
+	IPAddress        *string    `json:"ip_address,omitempty"`
 	ExpiresAt        time.Time  `json:"expires_at"`
 	CreatedAt        time.Time  `json:"created_at"`
+	LastUsedAt       time.Time  `json:"last_used_at"`
 	RevokedAt        *time.Time `json:"revoked_at,omitempty"`
 }
 
+// TokenClient is the app a refresh token is issued to, as the request
+// reported it. The device ID and User-Agent are stored as the token's device
+// info, the IP address next to it.
+type TokenClient struct {
+	DeviceID  string `json:"device_id,omitempty"`
+	UserAgent string `json:"user_agent,omitempty"`
+	IP        string `json:"-"`
+}
+
 type UserRepository struct {
-	db *pgxpool.Pool
+	db      *pgxpool.Pool
+	keyring *fieldcrypt.Keyring // nil stores emails, device info and IP addresses in plaintext
 }
 
 func NewUserRepository(db *pgxpool.Pool) *UserRepository {
 	return &UserRepository{db: db}
 }
 
+// UseEncryption encrypts users' emails, email sign-in identities and their
+// refresh tokens' device info and IP addresses with the keyring. Emails are
+// then found by their blind index in email_hash; rows written before are
+// still found by their plaintext until EncryptEmails and its siblings
+// encrypt them.
+func (r *UserRepository) UseEncryption(keyring *fieldcrypt.Keyring) {
+	r.keyring = keyring
+}
+
 // Users
 
 func (r *UserRepository) CreateUser(ctx context.Context, email, displayName string, emailVerified bool) (*User, error) {
 	query := `
-		INSERT INTO users (email, email_verified, display_name)
-		VALUES ($1, $2, $3)
-		RETURNING id, email, email_verified, display_name, ai_usage_policy, created_at, updated_at, deleted_at
+		INSERT INTO users (email, email_hash, email_verified, display_name)
+		VALUES ($1, $2, $3, $4)
+		RETURNING id, email, email_verified, is_private_email, display_name, ai_usage_policy, role, created_at, updated_at, deleted_at,
+			ai_query_consent_at, ai_enrichment_consent_at, auto_assign_collection
 	`
 
+	storedEmail, emailHash, err := r.sealEmail(email)
+	if err != nil {
+		return nil, err
+	}
+
 	var user User
-	err := r.db.QueryRow(ctx, query, email, emailVerified, displayName).Scan(
+	err = r.db.QueryRow(ctx, query, storedEmail, emailHash, emailVerified, displayName).Scan(
 		&user.ID,
 		&user.Email,
 		&user.EmailVerified,
+		&user.IsPrivateEmail,
 		&user.DisplayName,
 		&user.AIUsagePolicy,
+		&user.Role,
 		&user.CreatedAt,
 		&user.UpdatedAt,
 		&user.DeletedAt,
+		&user.AIQueryConsentAt,
+		&user.AIEnrichmentConsentAt,
+		&user.AutoAssignCollection,
 	)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create user: %w", err)
 	}
 
-	return &user, nil
+	return &user, r.openUser(ctx, &user)
 }
 
 func (r *UserRepository) GetUserByID(ctx context.Context, id uuid.UUID) (*User, error) {
 	query := `
-		SELECT id, email, email_verified, display_name, ai_usage_policy, created_at, updated_at, deleted_at
+		SELECT id, email, email_verified, is_private_email, display_name, ai_usage_policy, role, created_at, updated_at, deleted_at,
+			ai_query_consent_at, ai_enrichment_consent_at, auto_assign_collection
 		FROM users
 		WHERE id = $1 AND deleted_at IS NULL
 	`
@@ -95,11 +169,16 @@ func (r *UserRepository) GetUserByID(ctx context.Context, id uuid.UUID) (*User,
 		&user.ID,
 		&user.Email,
 		&user.EmailVerified,
+		&user.IsPrivateEmail,
 		&user.DisplayName,
 		&user.AIUsagePolicy,
+		&user.Role,
 		&user.CreatedAt,
 		&user.UpdatedAt,
 		&user.DeletedAt,
+		&user.AIQueryConsentAt,
+		&user.AIEnrichmentConsentAt,
+		&user.AutoAssignCollection,
 	)
 	if err != nil {
 		if errors.Is(err, pgx.ErrNoRows) {
@@ -108,26 +187,36 @@ func (r *UserRepository) GetUserByID(ctx context.Context, id uuid.UUID) (*User,
 		return nil, fmt.Errorf("failed to get user: %w", err)
 	}
 
-	return &user, nil
+	return &user, r.openUser(ctx, &user)
 }
 
+// GetUserByEmail finds an active user by email, ignoring case. When older
+// data holds several accounts for the address, the oldest is returned.
 func (r *UserRepository) GetUserByEmail(ctx context.Context, email string) (*User, error) {
 	query := `
-		SELECT id, email, email_verified, display_name, ai_usage_policy, created_at, updated_at, deleted_at
+		SELECT id, email, email_verified, is_private_email, display_name, ai_usage_policy, role, created_at, updated_at, deleted_at,
+			ai_query_consent_at, ai_enrichment_consent_at, auto_assign_collection
 		FROM users
-		WHERE email = $1 AND deleted_at IS NULL
+		WHERE (LOWER(email) = LOWER($1) OR email_hash = $2) AND deleted_at IS NULL
+		ORDER BY created_at ASC
+		LIMIT 1
 	`
 
 	var user User
-	err := r.db.QueryRow(ctx, query, email).Scan(
+	err := r.db.QueryRow(ctx, query, email, r.emailHash(email)).Scan(
 		&user.ID,
 		&user.Email,
 		&user.EmailVerified,
+		&user.IsPrivateEmail,
 		&user.DisplayName,
 		&user.AIUsagePolicy,
+		&user.Role,
 		&user.CreatedAt,
 		&user.UpdatedAt,
 		&user.DeletedAt,
+		&user.AIQueryConsentAt,
+		&user.AIEnrichmentConsentAt,
+		&user.AutoAssignCollection,
 	)
 	if err != nil {
 		if errors.Is(err, pgx.ErrNoRows) {
@@ -136,7 +225,7 @@ func (r *UserRepository) GetUserByEmail(ctx context.Context, email string) (*Use
 		return nil, fmt.Errorf("failed to get user by email: %w", err)
 	}
 
-	return &user, nil
+	return &user, r.openUser(ctx, &user)
 }
 
 func (r *UserRepository) DeleteUser(ctx context.Context, id uuid.UUID) error {
@@ -158,26 +247,451 @@ func (r *UserRepository) DeleteUser(ctx context.Context, id uuid.UUID) error {
 	return nil
 }
 
+// IsEmailHeldByDeletedUser reports whether a deleted account that is not
+// purged yet has the email, ignoring case. Such an email is free for the
+// unique index but not for new sign-ups, see ErrUserDeleted.
+func (r *UserRepository) IsEmailHeldByDeletedUser(ctx context.Context, email string) (bool, error) {
+	query := `
+		SELECT EXISTS (
+			SELECT 1 FROM users
+			WHERE (LOWER(email) = LOWER($1) OR email_hash = $2) AND deleted_at IS NOT NULL
+		)
+	`
+
+	var held bool
+	if err := r.db.QueryRow(ctx, query, email, r.emailHash(email)).Scan(&held); err != nil {
+		return false, fmt.Errorf("failed to check email of deleted users: %w", err)
+	}
+
+	return held, nil
+}
+
+// UserOverview counts what a user has stored, with their AI search usage in
+// the current period.
+type UserOverview struct {
+	Collections   int64
+	Entries       int64
+	Images        int64
+	ImageBytes    int64
+	AIUsagePolicy AIUsagePolicy
+	AISearches    int        // searches in the current period
+	AIPeriodEnd   *time.Time // nil without a current period
+}
+
+// GetUserOverview gathers a user's counts in a single query.
+func (r *UserRepository) GetUserOverview(ctx context.Context, id uuid.UUID) (*UserOverview, error) {
+	query := `
+		SELECT
+			(SELECT COUNT(*) FROM collections WHERE user_id = u.id),
+			(SELECT COUNT(*) FROM entries WHERE user_id = u.id),
+			img.images,
+			img.image_bytes,
+			u.ai_usage_policy,
+			COALESCE(a.search_count, 0),
+			a.period_end
+		FROM users u
+		CROSS JOIN LATERAL (
+			SELECT COUNT(*) AS images, COALESCE(SUM(octet_length(i.image_data)), 0) AS image_bytes
+			FROM entry_images i JOIN entries e ON e.id = i.entry_id
+			WHERE e.user_id = u.id
+		) img
+		LEFT JOIN ai_search_usage a ON a.user_id = u.id AND a.period_end > NOW()
+		WHERE u.id = $1 AND u.deleted_at IS NULL
+	`
+
+	var overview UserOverview
+	err := r.db.QueryRow(ctx, query, id).Scan(
+		&overview.Collections,
+		&overview.Entries,
+		&overview.Images,
+		&overview.ImageBytes,
+		&overview.AIUsagePolicy,
+		&overview.AISearches,
+		&overview.AIPeriodEnd,
+	)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrUserNotFound
+		}
+		return nil, fmt.Errorf("failed to get user overview: %w", err)
+	}
+
+	return &overview, nil
+}
+
+// PurgeStats counts what purging a deleted user removes. Rows not listed
+// (tokens, auth providers, webhooks, ...) are removed too.
+type PurgeStats struct {
+	Collections int64
+	Entries     int64
+	Images      int64
+	Types       int64
+	ImageBytes  int64
+}
+
+// ListPurgeableUsers returns up to limit users soft-deleted before the cutoff,
+// oldest first.
+func (r *UserRepository) ListPurgeableUsers(ctx context.Context, deletedBefore time.Time, limit int) ([]uuid.UUID, error) {
+	query := `
+		SELECT id
+		FROM users
+		WHERE deleted_at IS NOT NULL AND deleted_at < $1
+		ORDER BY deleted_at
+		LIMIT $2
+	`
+
+	rows, err := r.db.Query(ctx, query, deletedBefore, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list purgeable users: %w", err)
+	}
+	defer rows.Close()
+
+	var ids []uuid.UUID
+	for rows.Next() {
+		var id uuid.UUID
+		if err := rows.Scan(&id); err != nil {
+			return nil, fmt.Errorf("failed to scan user id: %w", err)
+		}
+		ids = append(ids, id)
+	}
+
+	return ids, rows.Err()
+}
+
+// PurgeUser hard-deletes a soft-deleted user. Owned rows go with it through
+// ON DELETE CASCADE; sync tombstones, outbox events and activity written by the
+// cascade have no foreign key and are deleted explicitly. With dryRun the stats are
+// computed and nothing is deleted. Returns ErrUserNotFound if the user is not
+// soft-deleted.
+func (r *UserRepository) PurgeUser(ctx context.Context, id uuid.UUID, dryRun bool) (*PurgeStats, error) {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	var locked bool
+	err = tx.QueryRow(ctx,
+		`SELECT true FROM users WHERE id = $1 AND deleted_at IS NOT NULL FOR UPDATE`, id,
+	).Scan(&locked)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrUserNotFound
+		}
+		return nil, fmt.Errorf("failed to lock user: %w", err)
+	}
+
+	statsQuery := `
+		SELECT
+			(SELECT COUNT(*) FROM collections WHERE user_id = $1),
+			(SELECT COUNT(*) FROM entries WHERE user_id = $1),
+			(SELECT COUNT(*) FROM entry_images i JOIN entries e ON e.id = i.entry_id WHERE e.user_id = $1),
+			(SELECT COUNT(*) FROM entry_types WHERE user_id = $1),
+			(SELECT COALESCE(SUM(octet_length(i.image_data)), 0)
+				FROM entry_images i JOIN entries e ON e.id = i.entry_id WHERE e.user_id = $1)
+	`
+
+	var stats PurgeStats
+	err = tx.QueryRow(ctx, statsQuery, id).Scan(
+		&stats.Collections,
+		&stats.Entries,
+		&stats.Images,
+		&stats.Types,
+		&stats.ImageBytes,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to count user data: %w", err)
+	}
+
+	if dryRun {
+		return &stats, nil
+	}
+
+	if _, err := tx.Exec(ctx, `DELETE FROM users WHERE id = $1`, id); err != nil {
+		return nil, fmt.Errorf("failed to purge user: %w", err)
+	}
+	if _, err := tx.Exec(ctx, `DELETE FROM sync_tombstones WHERE user_id = $1`, id); err != nil {
+		return nil, fmt.Errorf("failed to purge sync tombstones: %w", err)
+	}
+	if _, err := tx.Exec(ctx, `DELETE FROM outbox WHERE user_id = $1`, id); err != nil {
+		return nil, fmt.Errorf("failed to purge outbox events: %w", err)
+	}
+	if _, err := tx.Exec(ctx, `DELETE FROM activity_log WHERE user_id = $1`, id); err != nil {
+		return nil, fmt.Errorf("failed to purge activity: %w", err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return &stats, nil
+}
+
+// UpdateUserEmail replaces a user's email, e.g. when their Apple private relay
+// address changes, and records the transition as event. Returns
+// ErrEmailTaken when another account has it.
+func (r *UserRepository) UpdateUserEmail(ctx context.Context, id uuid.UUID, email string, emailVerified, isPrivateEmail bool, event EmailEvent) error {
+	storedEmail, emailHash, err := r.sealEmail(email)
+	if err != nil {
+		return err
+	}
+
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	result, err := tx.Exec(ctx, `
+		UPDATE users
+		SET email = $2, email_hash = $3, email_verified = $4, is_private_email = $5, updated_at = NOW()
+		WHERE id = $1 AND deleted_at IS NULL
+	`, id, storedEmail, emailHash, emailVerified, isPrivateEmail)
+	if err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == "23505" {
+			return ErrEmailTaken
+		}
+		return fmt.Errorf("failed to update user email: %w", err)
+	}
+
+	if result.RowsAffected() == 0 {
+		return ErrUserNotFound
+	}
+
+	if err := insertEmailEvent(ctx, tx, id, event); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return nil
+}
+
+// MarkEmailVerified marks the user's email verified after they confirmed it
+// with a code, recording a verification event.
+func (r *UserRepository) MarkEmailVerified(ctx context.Context, id uuid.UUID) error {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	event := EmailEvent{Source: EmailEventVerification, Verified: true}
+	err = tx.QueryRow(ctx, `
+		SELECT email_verified FROM users
+		WHERE id = $1 AND deleted_at IS NULL
+		FOR UPDATE
+	`, id).Scan(&event.WasVerified)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return ErrUserNotFound
+		}
+		return fmt.Errorf("failed to get user: %w", err)
+	}
+
+	_, err = tx.Exec(ctx, `UPDATE users SET email_verified = TRUE, updated_at = NOW() WHERE id = $1`, id)
+	if err != nil {
+		return fmt.Errorf("failed to mark email verified: %w", err)
+	}
+
+	if err := insertEmailEvent(ctx, tx, id, event); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return nil
+}
+
+func insertEmailEvent(ctx context.Context, tx pgx.Tx, userID uuid.UUID, event EmailEvent) error {
+	_, err := tx.Exec(ctx, `
+		INSERT INTO user_email_events (user_id, source, email_changed, was_verified, verified)
+		VALUES ($1, $2, $3, $4, $5)
+	`, userID, event.Source, event.EmailChanged, event.WasVerified, event.Verified)
+	if err != nil {
+		return fmt.Errorf("failed to record email event: %w", err)
+	}
+	return nil
+}
+
+// ListEmailEvents returns the user's latest email events, newest first.
+func (r *UserRepository) ListEmailEvents(ctx context.Context, userID uuid.UUID, limit int) ([]EmailEvent, error) {
+	query := `
+		SELECT id, source, email_changed, was_verified, verified, created_at
+		FROM user_email_events
+		WHERE user_id = $1
+		ORDER BY created_at DESC, id DESC
+		LIMIT $2
+	`
+
+	rows, err := r.db.Query(ctx, query, userID, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query email events: %w", err)
+	}
+	defer rows.Close()
+
+	events := []EmailEvent{}
+	for rows.Next() {
+		var e EmailEvent
+		if err := rows.Scan(&e.ID, &e.Source, &e.EmailChanged, &e.WasVerified, &e.Verified, &e.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan email event: %w", err)
+		}
+		events = append(events, e)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating email events: %w", err)
+	}
+
+	return events, nil
+}
+
+// EmailVerificationRequired reports whether the user's latest email event
+// left their email unverified. Users without events never need it: emails
+// they signed up with aren't re-checked.
+func (r *UserRepository) EmailVerificationRequired(ctx context.Context, userID uuid.UUID) (bool, error) {
+	query := `
+		SELECT COALESCE((
+			SELECT NOT verified FROM user_email_events
+			WHERE user_id = $1
+			ORDER BY created_at DESC, id DESC
+			LIMIT 1
+		), FALSE)
+	`
+
+	var required bool
+	if err := r.db.QueryRow(ctx, query, userID).Scan(&required); err != nil {
+		return false, fmt.Errorf("failed to check email verification: %w", err)
+	}
+
+	return required, nil
+}
+
+// SetUserRole changes a user's role.
+func (r *UserRepository) SetUserRole(ctx context.Context, id uuid.UUID, role UserRole) error {
+	query := `
+		UPDATE users
+		SET role = $2, updated_at = NOW()
+		WHERE id = $1 AND deleted_at IS NULL
+	`
+
+	result, err := r.db.Exec(ctx, query, id, role)
+	if err != nil {
+		return fmt.Errorf("failed to set user role: %w", err)
+	}
+
+	if result.RowsAffected() == 0 {
+		return ErrUserNotFound
+	}
+
+	return nil
+}
+
+// ListUserIDsByRole returns the ids of the users with role, e.g. the admins
+// to alert about an operational problem. Deleted users are left out.
+func (r *UserRepository) ListUserIDsByRole(ctx context.Context, role UserRole) ([]uuid.UUID, error) {
+	query := `SELECT id FROM users WHERE role = $1 AND deleted_at IS NULL ORDER BY created_at`
+
+	rows, err := r.db.Query(ctx, query, role)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query users by role: %w", err)
+	}
+	defer rows.Close()
+
+	ids := []uuid.UUID{}
+	for rows.Next() {
+		var id uuid.UUID
+		if err := rows.Scan(&id); err != nil {
+			return nil, fmt.Errorf("failed to scan user id: %w", err)
+		}
+		ids = append(ids, id)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating users: %w", err)
+	}
+
+	return ids, nil
+}
+
+// SetAIConsent grants or withdraws the user's AI consents. A consent that
+// is granted again keeps the time it was first given.
+func (r *UserRepository) SetAIConsent(ctx context.Context, id uuid.UUID, queries, enrichment bool) error {
+	query := `
+		UPDATE users
+		SET ai_query_consent_at = CASE WHEN $2 THEN COALESCE(ai_query_consent_at, NOW()) END,
+			ai_enrichment_consent_at = CASE WHEN $3 THEN COALESCE(ai_enrichment_consent_at, NOW()) END,
+			updated_at = NOW()
+		WHERE id = $1 AND deleted_at IS NULL
+	`
+
+	result, err := r.db.Exec(ctx, query, id, queries, enrichment)
+	if err != nil {
+		return fmt.Errorf("failed to set AI consent: %w", err)
+	}
+
+	if result.RowsAffected() == 0 {
+		return ErrUserNotFound
+	}
+
+	return nil
+}
+
+// SetAutoAssignCollection turns filing new entries without a collection in
+// the suggested one on or off.
+func (r *UserRepository) SetAutoAssignCollection(ctx context.Context, id uuid.UUID, enabled bool) error {
+	query := `
+		UPDATE users
+		SET auto_assign_collection = $2, updated_at = NOW()
+		WHERE id = $1 AND deleted_at IS NULL
+	`
+
+	result, err := r.db.Exec(ctx, query, id, enabled)
+	if err != nil {
+		return fmt.Errorf("failed to set auto assign collection: %w", err)
+	}
+
+	if result.RowsAffected() == 0 {
+		return ErrUserNotFound
+	}
+
+	return nil
+}
+
 // Auth Providers
 
+// FindUserByProvider finds the user signing in with a provider. The identity
+// stays with a deleted account until it is purged: ErrUserDeleted until then,
+// ErrUserNotFound after.
 func (r *UserRepository) FindUserByProvider(ctx context.Context, provider, providerUserID string) (*User, error) {
 	query := `
-		SELECT u.id, u.email, u.email_verified, u.display_name, u.ai_usage_policy, u.created_at, u.updated_at, u.deleted_at
+		SELECT u.id, u.email, u.email_verified, u.is_private_email, u.display_name, u.ai_usage_policy, u.role, u.created_at, u.updated_at, u.deleted_at,
+			u.ai_query_consent_at, u.ai_enrichment_consent_at, u.auto_assign_collection
 		FROM users u
 		JOIN user_auth_providers p ON u.id = p.user_id
-		WHERE p.provider = $1 AND p.provider_user_id = $2 AND u.deleted_at IS NULL
+		WHERE p.provider = $1 AND p.provider_user_id = ANY($2)
 	`
 
 	var user User
-	err := r.db.QueryRow(ctx, query, provider, providerUserID).Scan(
+	err := r.db.QueryRow(ctx, query, provider, r.providerUserIDs(provider, providerUserID)).Scan(
 		&user.ID,
 		&user.Email,
 		&user.EmailVerified,
+		&user.IsPrivateEmail,
 		&user.DisplayName,
 		&user.AIUsagePolicy,
+		&user.Role,
 		&user.CreatedAt,
 		&user.UpdatedAt,
 		&user.DeletedAt,
+		&user.AIQueryConsentAt,
+		&user.AIEnrichmentConsentAt,
+		&user.AutoAssignCollection,
 	)
 	if err != nil {
 		if errors.Is(err, pgx.ErrNoRows) {
@@ -185,8 +699,11 @@ func (r *UserRepository) FindUserByProvider(ctx context.Context, provider, provi
 		}
 		return nil, fmt.Errorf("failed to find user by provider: %w", err)
 	}
+	if user.DeletedAt != nil {
+		return nil, ErrUserDeleted
+	}
 
-	return &user, nil
+	return &user, r.openUser(ctx, &user)
 }
 
 func (r *UserRepository) CreateAuthProvider(ctx context.Context, userID uuid.UUID, provider, providerUserID string) error {
@@ -195,7 +712,7 @@ func (r *UserRepository) CreateAuthProvider(ctx context.Context, userID uuid.UUI
 		VALUES ($1, $2, $3)
 	`
 
-	_, err := r.db.Exec(ctx, query, userID, provider, providerUserID)
+	_, err := r.db.Exec(ctx, query, userID, provider, r.storedProviderUserID(provider, providerUserID))
 	if err != nil {
 		return fmt.Errorf("failed to create auth provider: %w", err)
 	}
@@ -240,15 +757,22 @@ func hashToken(token string) string {
 	return hex.EncodeToString(hash[:])
 }
 
-func (r *UserRepository) SaveRefreshToken(ctx context.Context, userID uuid.UUID, token string, expiresAt time.Time) error {
+// SaveRefreshToken stores a refresh token issued to client; what the client
+// didn't report is left NULL. The token counts as used when it is issued.
+func (r *UserRepository) SaveRefreshToken(ctx context.Context, userID uuid.UUID, token string, expiresAt time.Time, client TokenClient) error {
 	tokenHash := hashToken(token)
 
+	deviceInfo, ipAddress, err := r.sealTokenClient(client)
+	if err != nil {
+		return err
+	}
+
 	query := `
-		INSERT INTO user_tokens (user_id, refresh_token_hash, expires_at)
-		VALUES ($1, $2, $3)
+		INSERT INTO user_tokens (user_id, refresh_token_hash, expires_at, device_info, ip_address)
+		VALUES ($1, $2, $3, $4::jsonb, $5)
 	`
 
-	_, err := r.db.Exec(ctx, query, userID, tokenHash, expiresAt)
+	_, err = r.db.Exec(ctx, query, userID, tokenHash, expiresAt, deviceInfo, ipAddress)
 	if err != nil {
 		return fmt.Errorf("failed to save refresh token: %w", err)
 	}
@@ -260,7 +784,7 @@ func (r *UserRepository) FindRefreshToken(ctx context.Context, token string) (*R
 	tokenHash := hashToken(token)
 
 	query := `
-		SELECT id, user_id, refresh_token_hash, device_info, expires_at, created_at, revoked_at
+		SELECT id, user_id, refresh_token_hash, device_info, ip_address, expires_at, created_at, last_used_at, revoked_at
 		FROM user_tokens
 		WHERE refresh_token_hash = $1 AND revoked_at IS NULL AND expires_at > NOW()
 	`
@@ -271,8 +795,10 @@ func (r *UserRepository) FindRefreshToken(ctx context.Context, token string) (*R
 		&rt.UserID,
 		&rt.RefreshTokenHash,
 		&rt.DeviceInfo,
+		&rt.IPAddress,
 		&rt.ExpiresAt,
 		&rt.CreatedAt,
+		&rt.LastUsedAt,
 		&rt.RevokedAt,
 	)
 	if err != nil {
@@ -282,15 +808,32 @@ func (r *UserRepository) FindRefreshToken(ctx context.Context, token string) (*R
 		return nil, fmt.Errorf("failed to find refresh token: %w", err)
 	}
 
+	if rt.DeviceInfo != nil {
+		deviceInfo, err := r.openDeviceInfo(ctx, *rt.DeviceInfo)
+		if err != nil {
+			return nil, err
+		}
+		rt.DeviceInfo = &deviceInfo
+	}
+	if rt.IPAddress != nil && r.keyring != nil {
+		ipAddress, err := r.keyring.Decrypt(ctx, *rt.IPAddress)
+		if err != nil {
+			return nil, fmt.Errorf("failed to decrypt IP address: %w", err)
+		}
+		rt.IPAddress = &ipAddress
+	}
+
 	return &rt, nil
 }
 
+// RevokeRefreshToken revokes a token when it is exchanged for a new one or
+// the user signs out, both of which count as using it.
 func (r *UserRepository) RevokeRefreshToken(ctx context.Context, token string) error {
 	tokenHash := hashToken(token)
 
 	query := `
 		UPDATE user_tokens
-		SET revoked_at = NOW()
+		SET revoked_at = NOW(), last_used_at = NOW()
 		WHERE refresh_token_hash = $1 AND revoked_at IS NULL
 	`
 
@@ -325,7 +868,7 @@ func (r *UserRepository) RevokeAllUserTokens(ctx context.Context, userID uuid.UU
 func (r *UserRepository) CreateUserWithProvider(
 	ctx context.Context,
 	email, displayName string,
-	emailVerified bool,
+	emailVerified, isPrivateEmail bool,
 	provider, providerUserID string,
 ) (*User, error) {
 	tx, err := r.db.Begin(ctx)
@@ -336,16 +879,22 @@ func (r *UserRepository) CreateUserWithProvider(
 
 	// Create user
 	userQuery := `
-		INSERT INTO users (email, email_verified, display_name)
-		VALUES ($1, $2, $3)
-		RETURNING id, email, email_verified, display_name, created_at, updated_at, deleted_at
+		INSERT INTO users (email, email_hash, email_verified, is_private_email, display_name)
+		VALUES ($1, $2, $3, $4, $5)
+		RETURNING id, email, email_verified, is_private_email, display_name, created_at, updated_at, deleted_at
 	`
 
+	storedEmail, emailHash, err := r.sealEmail(email)
+	if err != nil {
+		return nil, err
+	}
+
 	var user User
-	err = tx.QueryRow(ctx, userQuery, email, emailVerified, displayName).Scan(
+	err = tx.QueryRow(ctx, userQuery, storedEmail, emailHash, emailVerified, isPrivateEmail, displayName).Scan(
 		&user.ID,
 		&user.Email,
 		&user.EmailVerified,
+		&user.IsPrivateEmail,
 		&user.DisplayName,
 		&user.CreatedAt,
 		&user.UpdatedAt,
@@ -361,7 +910,7 @@ func (r *UserRepository) CreateUserWithProvider(
 		VALUES ($1, $2, $3)
 	`
 
-	_, err = tx.Exec(ctx, providerQuery, user.ID, provider, providerUserID)
+	_, err = tx.Exec(ctx, providerQuery, user.ID, provider, r.storedProviderUserID(provider, providerUserID))
 	if err != nil {
 		return nil, fmt.Errorf("failed to create auth provider: %w", err)
 	}
@@ -370,5 +919,322 @@ func (r *UserRepository) CreateUserWithProvider(
 		return nil, fmt.Errorf("failed to commit transaction: %w", err)
 	}
 
-	return &user, nil
+	return &user, r.openUser(ctx, &user)
+}
+
+// Encryption
+
+// emailProvider is the auth provider whose user ids are email addresses,
+// kept as blind indexes when encrypting.
+const emailProvider = "email"
+
+// sealEmail returns the email as stored, encrypted when encrypting, and its
+// blind index, nil when not encrypting or without an email.
+func (r *UserRepository) sealEmail(email string) (string, *string, error) {
+	if r.keyring == nil || email == "" {
+		return email, nil, nil
+	}
+	sealed, err := r.keyring.Encrypt(email)
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to encrypt email: %w", err)
+	}
+	return sealed, r.emailHash(email), nil
+}
+
+// emailHash returns the blind index to find email by, nil when not
+// encrypting.
+func (r *UserRepository) emailHash(email string) *string {
+	if r.keyring == nil || email == "" {
+		return nil
+	}
+	hash := r.keyring.BlindIndex(email)
+	return &hash
+}
+
+// providerUserIDs returns the stored ids a provider user id may have: email
+// identities written before encryption was enabled are plaintext.
+func (r *UserRepository) providerUserIDs(provider, providerUserID string) []string {
+	stored := r.storedProviderUserID(provider, providerUserID)
+	if stored == providerUserID {
+		return []string{providerUserID}
+	}
+	return []string{stored, providerUserID}
+}
+
+func (r *UserRepository) storedProviderUserID(provider, providerUserID string) string {
+	if r.keyring == nil || provider != emailProvider {
+		return providerUserID
+	}
+	return r.keyring.BlindIndex(providerUserID)
+}
+
+// openUser decrypts the user's email.
+func (r *UserRepository) openUser(ctx context.Context, user *User) error {
+	if r.keyring == nil || user.Email == nil {
+		return nil
+	}
+	email, err := r.keyring.Decrypt(ctx, *user.Email)
+	if err != nil {
+		return fmt.Errorf("failed to decrypt email of user %s: %w", user.ID, err)
+	}
+	user.Email = &email
+	return nil
+}
+
+// openDeviceInfo decrypts device info, which is kept encrypted as a JSON
+// string holding the original JSON.
+func (r *UserRepository) openDeviceInfo(ctx context.Context, deviceInfo string) (string, error) {
+	var sealed string
+	if r.keyring == nil || json.Unmarshal([]byte(deviceInfo), &sealed) != nil || !fieldcrypt.IsEncrypted(sealed) {
+		return deviceInfo, nil
+	}
+	opened, err := r.keyring.Decrypt(ctx, sealed)
+	if err != nil {
+		return "", fmt.Errorf("failed to decrypt device info: %w", err)
+	}
+	return opened, nil
+}
+
+// sealTokenClient returns a refresh token's device info and IP address as
+// stored, encrypted when encrypting; nil for what the client didn't report.
+func (r *UserRepository) sealTokenClient(client TokenClient) (*string, *string, error) {
+	var deviceInfo, ipAddress *string
+
+	if client.DeviceID != "" || client.UserAgent != "" {
+		raw, err := json.Marshal(client)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to encode device info: %w", err)
+		}
+		info := string(raw)
+		if r.keyring != nil {
+			sealed, err := r.keyring.Encrypt(info)
+			if err != nil {
+				return nil, nil, fmt.Errorf("failed to encrypt device info: %w", err)
+			}
+			raw, _ = json.Marshal(sealed)
+			info = string(raw)
+		}
+		deviceInfo = &info
+	}
+
+	if client.IP != "" {
+		ip := client.IP
+		if r.keyring != nil {
+			sealed, err := r.keyring.Encrypt(ip)
+			if err != nil {
+				return nil, nil, fmt.Errorf("failed to encrypt IP address: %w", err)
+			}
+			ip = sealed
+		}
+		ipAddress = &ip
+	}
+
+	return deviceInfo, ipAddress, nil
+}
+
+// EncryptEmails encrypts up to limit users' emails that are plaintext or
+// encrypted with a retired data key, and sets their blind index. Returns how
+// many it encrypted; call it until that is 0.
+func (r *UserRepository) EncryptEmails(ctx context.Context, limit int) (int, error) {
+	query := `
+		SELECT id, email
+		FROM users
+		WHERE email IS NOT NULL AND email <> '' AND (email NOT LIKE $1 || '%' OR email_hash IS NULL)
+		ORDER BY id
+		LIMIT $2
+	`
+
+	rows, err := r.db.Query(ctx, query, r.keyring.ActivePrefix(), limit)
+	if err != nil {
+		return 0, fmt.Errorf("failed to query emails to encrypt: %w", err)
+	}
+	type userEmail struct {
+		id    uuid.UUID
+		email string
+	}
+	var pending []userEmail
+	for rows.Next() {
+		var u userEmail
+		if err := rows.Scan(&u.id, &u.email); err != nil {
+			rows.Close()
+			return 0, fmt.Errorf("failed to scan email: %w", err)
+		}
+		pending = append(pending, u)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, fmt.Errorf("error iterating emails: %w", err)
+	}
+
+	encrypted := 0
+	for _, u := range pending {
+		email, err := r.keyring.Decrypt(ctx, u.email)
+		if err != nil {
+			return encrypted, fmt.Errorf("failed to decrypt email of user %s: %w", u.id, err)
+		}
+		sealed, hash, err := r.sealEmail(email)
+		if err != nil {
+			return encrypted, err
+		}
+
+		// Skipped when the email changed meanwhile; the next call picks it up
+		result, err := r.db.Exec(ctx,
+			`UPDATE users SET email = $2, email_hash = $3 WHERE id = $1 AND email = $4`,
+			u.id, sealed, hash, u.email)
+		if err != nil {
+			return encrypted, fmt.Errorf("failed to encrypt email of user %s: %w", u.id, err)
+		}
+		encrypted += int(result.RowsAffected())
+	}
+
+	return encrypted, nil
+}
+
+// EncryptProviderEmails replaces up to limit plaintext email sign-in
+// identities with their blind index. Returns how many it replaced; call it
+// until that is 0.
+func (r *UserRepository) EncryptProviderEmails(ctx context.Context, limit int) (int, error) {
+	rows, err := r.db.Query(ctx, `
+		SELECT id, provider_user_id
+		FROM user_auth_providers
+		WHERE provider = $1 AND provider_user_id LIKE '%@%'
+		ORDER BY id
+		LIMIT $2
+	`, emailProvider, limit)
+	if err != nil {
+		return 0, fmt.Errorf("failed to query identities to encrypt: %w", err)
+	}
+	ids, emails := []uuid.UUID{}, []string{}
+	for rows.Next() {
+		var id uuid.UUID
+		var email string
+		if err := rows.Scan(&id, &email); err != nil {
+			rows.Close()
+			return 0, fmt.Errorf("failed to scan identity: %w", err)
+		}
+		ids = append(ids, id)
+		emails = append(emails, r.keyring.BlindIndex(email))
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, fmt.Errorf("error iterating identities: %w", err)
+	}
+	if len(ids) == 0 {
+		return 0, nil
+	}
+
+	result, err := r.db.Exec(ctx, `
+		UPDATE user_auth_providers p
+		SET provider_user_id = c.hash
+		FROM unnest($1::uuid[], $2::text[]) AS c(id, hash)
+		WHERE p.id = c.id
+	`, ids, emails)
+	if err != nil {
+		return 0, fmt.Errorf("failed to encrypt identities: %w", err)
+	}
+
+	return int(result.RowsAffected()), nil
+}
+
+// EncryptDeviceInfo encrypts up to limit refresh tokens' device info that is
+// plaintext or encrypted with a retired data key. Returns how many it
+// encrypted; call it until that is 0.
+func (r *UserRepository) EncryptDeviceInfo(ctx context.Context, limit int) (int, error) {
+	rows, err := r.db.Query(ctx, `
+		SELECT id, device_info::text
+		FROM user_tokens
+		WHERE device_info IS NOT NULL
+			AND (jsonb_typeof(device_info) <> 'string' OR device_info #>> '{}' NOT LIKE $1 || '%')
+		ORDER BY id
+		LIMIT $2
+	`, r.keyring.ActivePrefix(), limit)
+	if err != nil {
+		return 0, fmt.Errorf("failed to query device info to encrypt: %w", err)
+	}
+	ids, infos := []uuid.UUID{}, []string{}
+	for rows.Next() {
+		var id uuid.UUID
+		var info string
+		if err := rows.Scan(&id, &info); err != nil {
+			rows.Close()
+			return 0, fmt.Errorf("failed to scan device info: %w", err)
+		}
+		ids = append(ids, id)
+		infos = append(infos, info)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, fmt.Errorf("error iterating device info: %w", err)
+	}
+
+	encrypted := 0
+	for i, id := range ids {
+		info, err := r.openDeviceInfo(ctx, infos[i])
+		if err != nil {
+			return encrypted, err
+		}
+		sealed, err := r.keyring.Encrypt(info)
+		if err != nil {
+			return encrypted, fmt.Errorf("failed to encrypt device info: %w", err)
+		}
+		result, err := r.db.Exec(ctx,
+			`UPDATE user_tokens SET device_info = to_jsonb($2::text) WHERE id = $1`, id, sealed)
+		if err != nil {
+			return encrypted, fmt.Errorf("failed to encrypt device info of token %s: %w", id, err)
+		}
+		encrypted += int(result.RowsAffected())
+	}
+
+	return encrypted, nil
+}
+
+// EncryptIPAddresses encrypts up to limit refresh tokens' IP addresses that
+// are plaintext or encrypted with a retired data key. Returns how many it
+// encrypted; call it until that is 0.
+func (r *UserRepository) EncryptIPAddresses(ctx context.Context, limit int) (int, error) {
+	rows, err := r.db.Query(ctx, `
+		SELECT id, ip_address
+		FROM user_tokens
+		WHERE ip_address IS NOT NULL AND ip_address NOT LIKE $1 || '%'
+		ORDER BY id
+		LIMIT $2
+	`, r.keyring.ActivePrefix(), limit)
+	if err != nil {
+		return 0, fmt.Errorf("failed to query IP addresses to encrypt: %w", err)
+	}
+	ids, addresses := []uuid.UUID{}, []string{}
+	for rows.Next() {
+		var id uuid.UUID
+		var address string
+		if err := rows.Scan(&id, &address); err != nil {
+			rows.Close()
+			return 0, fmt.Errorf("failed to scan IP address: %w", err)
+		}
+		ids = append(ids, id)
+		addresses = append(addresses, address)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, fmt.Errorf("error iterating IP addresses: %w", err)
+	}
+
+	encrypted := 0
+	for i, id := range ids {
+		address, err := r.keyring.Decrypt(ctx, addresses[i])
+		if err != nil {
+			return encrypted, fmt.Errorf("failed to decrypt IP address of token %s: %w", id, err)
+		}
+		sealed, err := r.keyring.Encrypt(address)
+		if err != nil {
+			return encrypted, fmt.Errorf("failed to encrypt IP address: %w", err)
+		}
+		result, err := r.db.Exec(ctx,
+			`UPDATE user_tokens SET ip_address = $2 WHERE id = $1`, id, sealed)
+		if err != nil {
+			return encrypted, fmt.Errorf("failed to encrypt IP address of token %s: %w", id, err)
+		}
+		encrypted += int(result.RowsAffected())
+	}
+
+	return encrypted, nil
 }
@@ -8,6 +8,7 @@ import (
 	"fmt"
 	"time"
 
+	"github.com/avalarin/livlog/backend/internal/fieldcrypt"
 	"github.com/google/uuid"
 	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgxpool"
@@ -29,13 +30,30 @@ type VerificationCode struct {
 }
 
 type VerificationCodeRepository struct {
-	db *pgxpool.Pool
+	db      *pgxpool.Pool
+	keyring *fieldcrypt.Keyring // nil stores emails in plaintext
 }
 
 func NewVerificationCodeRepository(db *pgxpool.Pool) *VerificationCodeRepository {
 	return &VerificationCodeRepository{db: db}
 }
 
+// UseEncryption stores the blind index of emails instead of the address;
+// codes are only ever looked up by email, so the address isn't needed back.
+// VerificationCode.Email then holds the index.
+func (r *VerificationCodeRepository) UseEncryption(keyring *fieldcrypt.Keyring) {
+	r.keyring = keyring
+}
+
+// storedEmails returns the value to store for email first, then the others
+// codes requested before encryption was enabled may have.
+func (r *VerificationCodeRepository) storedEmails(email string) []string {
+	if r.keyring == nil {
+		return []string{email}
+	}
+	return []string{r.keyring.BlindIndex(email), email}
+}
+
 // hashCode returns SHA256 hash of the verification code
 func hashCode(code string) string {
 	hash := sha256.Sum256([]byte(code))
@@ -62,9 +80,10 @@ func (r *VerificationCodeRepository) CreateVerificationCode(
 	invalidateQuery := `
 		UPDATE verification_codes
 		SET used_at = NOW()
-		WHERE email = $1 AND used_at IS NULL
+		WHERE email = ANY($1) AND used_at IS NULL
 	`
-	_, err = tx.Exec(ctx, invalidateQuery, email)
+	emails := r.storedEmails(email)
+	_, err = tx.Exec(ctx, invalidateQuery, emails)
 	if err != nil {
 		return nil, fmt.Errorf("failed to invalidate previous codes: %w", err)
 	}
@@ -77,7 +96,7 @@ func (r *VerificationCodeRepository) CreateVerificationCode(
 	`
 
 	var verificationCode VerificationCode
-	err = tx.QueryRow(ctx, query, email, codeHash, expiresAt).Scan(
+	err = tx.QueryRow(ctx, query, emails[0], codeHash, expiresAt).Scan(
 		&verificationCode.ID,
 		&verificationCode.Email,
 		&verificationCode.CodeHash,
@@ -107,13 +126,13 @@ func (r *VerificationCodeRepository) FindVerificationCode(
 	query := `
 		SELECT id, email, code_hash, created_at, expires_at, used_at
 		FROM verification_codes
-		WHERE email = $1 AND code_hash = $2 AND used_at IS NULL
+		WHERE email = ANY($1) AND code_hash = $2 AND used_at IS NULL
 		ORDER BY created_at DESC
 		LIMIT 1
 	`
 
 	var verificationCode VerificationCode
-	err := r.db.QueryRow(ctx, query, email, codeHash).Scan(
+	err := r.db.QueryRow(ctx, query, r.storedEmails(email), codeHash).Scan(
 		&verificationCode.ID,
 		&verificationCode.Email,
 		&verificationCode.CodeHash,
@@ -163,10 +182,10 @@ func (r *VerificationCodeRepository) InvalidatePreviousCodes(ctx context.Context
 	query := `
 		UPDATE verification_codes
 		SET used_at = NOW()
-		WHERE email = $1 AND used_at IS NULL
+		WHERE email = ANY($1) AND used_at IS NULL
 	`
 
-	_, err := r.db.Exec(ctx, query, email)
+	_, err := r.db.Exec(ctx, query, r.storedEmails(email))
 	if err != nil {
 		return fmt.Errorf("failed to invalidate previous codes: %w", err)
 	}
@@ -192,3 +211,48 @@ func (r *VerificationCodeRepository) CleanupExpiredCodes(
 
 	return result.RowsAffected(), nil
 }
+
+// EncryptEmails replaces up to limit plaintext emails with their blind
+// index. Returns how many it replaced; call it until that is 0.
+func (r *VerificationCodeRepository) EncryptEmails(ctx context.Context, limit int) (int, error) {
+	rows, err := r.db.Query(ctx, `
+		SELECT id, email
+		FROM verification_codes
+		WHERE email LIKE '%@%'
+		ORDER BY id
+		LIMIT $1
+	`, limit)
+	if err != nil {
+		return 0, fmt.Errorf("failed to query emails to encrypt: %w", err)
+	}
+	ids, hashes := []uuid.UUID{}, []string{}
+	for rows.Next() {
+		var id uuid.UUID
+		var email string
+		if err := rows.Scan(&id, &email); err != nil {
+			rows.Close()
+			return 0, fmt.Errorf("failed to scan email: %w", err)
+		}
+		ids = append(ids, id)
+		hashes = append(hashes, r.keyring.BlindIndex(email))
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, fmt.Errorf("error iterating emails: %w", err)
+	}
+	if len(ids) == 0 {
+		return 0, nil
+	}
+
+	result, err := r.db.Exec(ctx, `
+		UPDATE verification_codes v
+		SET email = c.hash
+		FROM unnest($1::uuid[], $2::text[]) AS c(id, hash)
+		WHERE v.id = c.id
+	`, ids, hashes)
+	if err != nil {
+		return 0, fmt.Errorf("failed to encrypt emails: %w", err)
+	}
+
+	return int(result.RowsAffected()), nil
+}
@@ -0,0 +1,257 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+var (
+	ErrWebhookNotFound = errors.New("webhook not found")
+)
+
+type Webhook struct {
+	ID        uuid.UUID `json:"id"`
+	UserID    uuid.UUID `json:"user_id"`
+	URL       string    `json:"url"`
+	Secret    string    `json:"-"`
+	Events    []string  `json:"events"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// WebhookDelivery is a claimed delivery together with the target it is sent to.
+type WebhookDelivery struct {
+	ID        uuid.UUID
+	WebhookID uuid.UUID
+	URL       string
+	Secret    string
+	Event     string
+	Payload   []byte
+	Attempts  int // including the current one
+}
+
+type WebhookRepository struct {
+	db *pgxpool.Pool
+}
+
+func NewWebhookRepository(db *pgxpool.Pool) *WebhookRepository {
+	return &WebhookRepository{db: db}
+}
+
+// CreateWebhook registers a webhook for the user.
+func (r *WebhookRepository) CreateWebhook(
+	ctx context.Context,
+	userID uuid.UUID,
+	url, secret string,
+	events []string,
+) (*Webhook, error) {
+	query := `
+		INSERT INTO webhooks (user_id, url, secret, events)
+		VALUES ($1, $2, $3, $4)
+		RETURNING id, user_id, url, secret, events, created_at, updated_at
+	`
+
+	var w Webhook
+	err := r.db.QueryRow(ctx, query, userID, url, secret, events).Scan(
+		&w.ID,
+		&w.UserID,
+		&w.URL,
+		&w.Secret,
+		&w.Events,
+		&w.CreatedAt,
+		&w.UpdatedAt,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create webhook: %w", err)
+	}
+
+	return &w, nil
+}
+
+// GetWebhooksByUserID retrieves all webhooks registered by a user.
+func (r *WebhookRepository) GetWebhooksByUserID(
+	ctx context.Context,
+	userID uuid.UUID,
+) ([]*Webhook, error) {
+	query := `
+		SELECT id, user_id, url, secret, events, created_at, updated_at
+		FROM webhooks
+		WHERE user_id = $1
+		ORDER BY created_at ASC
+	`
+
+	rows, err := r.db.Query(ctx, query, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query webhooks: %w", err)
+	}
+	defer rows.Close()
+
+	var webhooks []*Webhook
+	for rows.Next() {
+		var w Webhook
+		err := rows.Scan(
+			&w.ID,
+			&w.UserID,
+			&w.URL,
+			&w.Secret,
+			&w.Events,
+			&w.CreatedAt,
+			&w.UpdatedAt,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan webhook: %w", err)
+		}
+		webhooks = append(webhooks, &w)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating webhooks: %w", err)
+	}
+
+	return webhooks, nil
+}
+
+// CountWebhooks returns the number of webhooks registered by a user.
+func (r *WebhookRepository) CountWebhooks(ctx context.Context, userID uuid.UUID) (int, error) {
+	query := `SELECT COUNT(*) FROM webhooks WHERE user_id = $1`
+
+	var count int
+	if err := r.db.QueryRow(ctx, query, userID).Scan(&count); err != nil {
+		return 0, fmt.Errorf("failed to count webhooks: %w", err)
+	}
+
+	return count, nil
+}
+
+// DeleteWebhook deletes a user's webhook along with its pending deliveries.
+func (r *WebhookRepository) DeleteWebhook(ctx context.Context, id, userID uuid.UUID) error {
+	query := `DELETE FROM webhooks WHERE id = $1 AND user_id = $2`
+
+	result, err := r.db.Exec(ctx, query, id, userID)
+	if err != nil {
+		return fmt.Errorf("failed to delete webhook: %w", err)
+	}
+
+	if result.RowsAffected() == 0 {
+		return ErrWebhookNotFound
+	}
+
+	return nil
+}
+
+// ClaimDueDeliveries picks up to limit deliveries that are due and counts an
+// attempt for each. Claimed rows are pushed lease into the future, so another
+// dispatcher (or this one after a crash) retries them only if they are not
+// resolved by then.
+func (r *WebhookRepository) ClaimDueDeliveries(
+	ctx context.Context,
+	limit int,
+	lease time.Duration,
+) ([]*WebhookDelivery, error) {
+	query := `
+		UPDATE webhook_deliveries d
+		SET attempts = d.attempts + 1,
+			next_attempt_at = NOW() + make_interval(secs => $2)
+		FROM webhooks w
+		WHERE w.id = d.webhook_id
+			AND d.id IN (
+				SELECT id FROM webhook_deliveries
+				WHERE delivered_at IS NULL AND failed_at IS NULL AND next_attempt_at <= NOW()
+				ORDER BY next_attempt_at ASC
+				LIMIT $1
+				FOR UPDATE SKIP LOCKED
+			)
+		RETURNING d.id, d.webhook_id, w.url, w.secret, d.event, d.payload::text, d.attempts
+	`
+
+	rows, err := r.db.Query(ctx, query, limit, lease.Seconds())
+	if err != nil {
+		return nil, fmt.Errorf("failed to claim webhook deliveries: %w", err)
+	}
+	defer rows.Close()
+
+	var deliveries []*WebhookDelivery
+	for rows.Next() {
+		var d WebhookDelivery
+		var payload string
+		err := rows.Scan(
+			&d.ID,
+			&d.WebhookID,
+			&d.URL,
+			&d.Secret,
+			&d.Event,
+			&payload,
+			&d.Attempts,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan webhook delivery: %w", err)
+		}
+		d.Payload = []byte(payload)
+		deliveries = append(deliveries, &d)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating webhook deliveries: %w", err)
+	}
+
+	return deliveries, nil
+}
+
+// MarkDelivered records a successful delivery.
+func (r *WebhookRepository) MarkDelivered(ctx context.Context, id uuid.UUID) error {
+	query := `UPDATE webhook_deliveries SET delivered_at = NOW(), last_error = NULL WHERE id = $1`
+
+	if _, err := r.db.Exec(ctx, query, id); err != nil {
+		return fmt.Errorf("failed to mark webhook delivered: %w", err)
+	}
+
+	return nil
+}
+
+// RescheduleDelivery records a failed attempt and when to try again.
+func (r *WebhookRepository) RescheduleDelivery(
+	ctx context.Context,
+	id uuid.UUID,
+	lastError string,
+	next time.Time,
+) error {
+	query := `UPDATE webhook_deliveries SET last_error = $2, next_attempt_at = $3 WHERE id = $1`
+
+	if _, err := r.db.Exec(ctx, query, id, lastError, next); err != nil {
+		return fmt.Errorf("failed to reschedule webhook delivery: %w", err)
+	}
+
+	return nil
+}
+
+// FailDelivery records the last failed attempt and gives up on the delivery.
+func (r *WebhookRepository) FailDelivery(ctx context.Context, id uuid.UUID, lastError string) error {
+	query := `UPDATE webhook_deliveries SET last_error = $2, failed_at = NOW() WHERE id = $1`
+
+	if _, err := r.db.Exec(ctx, query, id, lastError); err != nil {
+		return fmt.Errorf("failed to fail webhook delivery: %w", err)
+	}
+
+	return nil
+}
+
+// DeleteFinishedDeliveries removes delivered or failed deliveries that finished
+// more than retention ago. Returns the number of rows deleted.
+func (r *WebhookRepository) DeleteFinishedDeliveries(ctx context.Context, retention time.Duration) (int64, error) {
+	query := `
+		DELETE FROM webhook_deliveries
+		WHERE COALESCE(delivered_at, failed_at) < NOW() - make_interval(secs => $1)
+	`
+
+	result, err := r.db.Exec(ctx, query, retention.Seconds())
+	if err != nil {
+		return 0, fmt.Errorf("failed to delete finished webhook deliveries: %w", err)
+	}
+
+	return result.RowsAffected(), nil
+}
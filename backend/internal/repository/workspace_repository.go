@@ -0,0 +1,478 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+var (
+	ErrWorkspaceNotFound  = errors.New("workspace not found")
+	ErrMemberNotFound     = errors.New("workspace member not found")
+	ErrAlreadyMember      = errors.New("already a workspace member")
+	ErrWorkspaceFull      = errors.New("workspace member limit reached")
+	ErrInvitationNotFound = errors.New("workspace invitation not found")
+)
+
+// Workspace member roles. The owner is the only member who can invite,
+// remove members or delete the workspace.
+const (
+	WorkspaceRoleOwner  = "owner"
+	WorkspaceRoleMember = "member"
+)
+
+// Workspace is a workspace as seen by one of its members; Role is that
+// member's role.
+type Workspace struct {
+	ID          uuid.UUID `json:"id"`
+	OwnerID     uuid.UUID `json:"owner_id"`
+	Name        string    `json:"name"`
+	Personal    bool      `json:"personal"`
+	Role        string    `json:"role"`
+	MemberCount int       `json:"member_count"`
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+type WorkspaceMember struct {
+	UserID      uuid.UUID `json:"user_id"`
+	DisplayName *string   `json:"display_name"`
+	Role        string    `json:"role"`
+	JoinedAt    time.Time `json:"joined_at"`
+}
+
+// WorkspaceInvitation is a pending invitation. The token itself is only
+// known when the invitation is created; only its hash is stored.
+type WorkspaceInvitation struct {
+	ID          uuid.UUID `json:"id"`
+	WorkspaceID uuid.UUID `json:"workspace_id"`
+	InvitedBy   uuid.UUID `json:"invited_by"`
+	ExpiresAt   time.Time `json:"expires_at"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+type WorkspaceRepository struct {
+	db *pgxpool.Pool
+}
+
+func NewWorkspaceRepository(db *pgxpool.Pool) *WorkspaceRepository {
+	return &WorkspaceRepository{db: db}
+}
+
+// workspaceColumns selects a workspace (aliased w) joined with the calling
+// member's row (aliased m). Scan with scanWorkspace.
+const workspaceColumns = `w.id, w.owner_id, w.name, w.personal, m.role,
+		(SELECT COUNT(*) FROM workspace_members wm WHERE wm.workspace_id = w.id) AS member_count,
+		w.created_at, w.updated_at`
+
+func scanWorkspace(row pgx.Row) (*Workspace, error) {
+	var w Workspace
+	err := row.Scan(
+		&w.ID,
+		&w.OwnerID,
+		&w.Name,
+		&w.Personal,
+		&w.Role,
+		&w.MemberCount,
+		&w.CreatedAt,
+		&w.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &w, nil
+}
+
+// EnsurePersonalWorkspace creates the user's personal workspace if it
+// doesn't exist yet.
+func (r *WorkspaceRepository) EnsurePersonalWorkspace(ctx context.Context, userID uuid.UUID) error {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to start transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	var id uuid.UUID
+	err = tx.QueryRow(ctx, `
+		INSERT INTO workspaces (owner_id, name, personal)
+		VALUES ($1, 'Personal', TRUE)
+		ON CONFLICT (owner_id) WHERE personal DO NOTHING
+		RETURNING id
+	`, userID).Scan(&id)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil
+		}
+		return fmt.Errorf("failed to create personal workspace: %w", err)
+	}
+
+	_, err = tx.Exec(ctx, `
+		INSERT INTO workspace_members (workspace_id, user_id, role)
+		VALUES ($1, $2, $3)
+	`, id, userID, WorkspaceRoleOwner)
+	if err != nil {
+		return fmt.Errorf("failed to add workspace owner: %w", err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return nil
+}
+
+// CreateWorkspace creates a shared workspace with ownerID as its owner.
+func (r *WorkspaceRepository) CreateWorkspace(
+	ctx context.Context,
+	ownerID uuid.UUID,
+	name string,
+) (*Workspace, error) {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to start transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	var id uuid.UUID
+	err = tx.QueryRow(ctx, `
+		INSERT INTO workspaces (owner_id, name)
+		VALUES ($1, $2)
+		RETURNING id
+	`, ownerID, name).Scan(&id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create workspace: %w", err)
+	}
+
+	_, err = tx.Exec(ctx, `
+		INSERT INTO workspace_members (workspace_id, user_id, role)
+		VALUES ($1, $2, $3)
+	`, id, ownerID, WorkspaceRoleOwner)
+	if err != nil {
+		return nil, fmt.Errorf("failed to add workspace owner: %w", err)
+	}
+
+	workspace, err := scanWorkspace(tx.QueryRow(ctx, `
+		SELECT `+workspaceColumns+`
+		FROM workspaces w
+		JOIN workspace_members m ON m.workspace_id = w.id AND m.user_id = $2
+		WHERE w.id = $1
+	`, id, ownerID))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read workspace: %w", err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return workspace, nil
+}
+
+// ListWorkspaces returns the workspaces the user is a member of, personal
+// workspace first.
+func (r *WorkspaceRepository) ListWorkspaces(ctx context.Context, userID uuid.UUID) ([]*Workspace, error) {
+	query := `
+		SELECT ` + workspaceColumns + `
+		FROM workspaces w
+		JOIN workspace_members m ON m.workspace_id = w.id AND m.user_id = $1
+		ORDER BY w.personal DESC, w.created_at ASC
+	`
+
+	rows, err := r.db.Query(ctx, query, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query workspaces: %w", err)
+	}
+	defer rows.Close()
+
+	var workspaces []*Workspace
+	for rows.Next() {
+		w, err := scanWorkspace(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan workspace: %w", err)
+		}
+		workspaces = append(workspaces, w)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating workspaces: %w", err)
+	}
+
+	return workspaces, nil
+}
+
+// GetWorkspace returns a workspace as seen by userID. Workspaces the user
+// isn't a member of are reported as not found.
+func (r *WorkspaceRepository) GetWorkspace(ctx context.Context, id, userID uuid.UUID) (*Workspace, error) {
+	query := `
+		SELECT ` + workspaceColumns + `
+		FROM workspaces w
+		JOIN workspace_members m ON m.workspace_id = w.id AND m.user_id = $2
+		WHERE w.id = $1
+	`
+
+	w, err := scanWorkspace(r.db.QueryRow(ctx, query, id, userID))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrWorkspaceNotFound
+		}
+		return nil, fmt.Errorf("failed to get workspace: %w", err)
+	}
+
+	return w, nil
+}
+
+// DeleteWorkspace deletes a workspace. Its collections and types fall back
+// to their owners' personal workspaces.
+func (r *WorkspaceRepository) DeleteWorkspace(ctx context.Context, id uuid.UUID) error {
+	result, err := r.db.Exec(ctx, `DELETE FROM workspaces WHERE id = $1`, id)
+	if err != nil {
+		return fmt.Errorf("failed to delete workspace: %w", err)
+	}
+
+	if result.RowsAffected() == 0 {
+		return ErrWorkspaceNotFound
+	}
+
+	return nil
+}
+
+// ListMembers returns the members of a workspace, owner first.
+func (r *WorkspaceRepository) ListMembers(ctx context.Context, workspaceID uuid.UUID) ([]*WorkspaceMember, error) {
+	query := `
+		SELECT m.user_id, u.display_name, m.role, m.created_at
+		FROM workspace_members m
+		JOIN users u ON u.id = m.user_id
+		WHERE m.workspace_id = $1
+		ORDER BY m.role = 'owner' DESC, m.created_at ASC
+	`
+
+	rows, err := r.db.Query(ctx, query, workspaceID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query workspace members: %w", err)
+	}
+	defer rows.Close()
+
+	var members []*WorkspaceMember
+	for rows.Next() {
+		var m WorkspaceMember
+		if err := rows.Scan(&m.UserID, &m.DisplayName, &m.Role, &m.JoinedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan workspace member: %w", err)
+		}
+		members = append(members, &m)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating workspace members: %w", err)
+	}
+
+	return members, nil
+}
+
+// RemoveMember removes a non-owner member from a workspace. The member's
+// collections and types placed in the workspace move back to their
+// personal workspace.
+func (r *WorkspaceRepository) RemoveMember(ctx context.Context, workspaceID, userID uuid.UUID) error {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to start transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	result, err := tx.Exec(ctx, `
+		DELETE FROM workspace_members
+		WHERE workspace_id = $1 AND user_id = $2 AND role <> $3
+	`, workspaceID, userID, WorkspaceRoleOwner)
+	if err != nil {
+		return fmt.Errorf("failed to remove workspace member: %w", err)
+	}
+	if result.RowsAffected() == 0 {
+		return ErrMemberNotFound
+	}
+
+	_, err = tx.Exec(ctx, `
+		UPDATE collections SET workspace_id = NULL, updated_at = NOW()
+		WHERE workspace_id = $1 AND user_id = $2
+	`, workspaceID, userID)
+	if err != nil {
+		return fmt.Errorf("failed to release member collections: %w", err)
+	}
+
+	_, err = tx.Exec(ctx, `
+		UPDATE entry_types SET workspace_id = NULL, updated_at = NOW()
+		WHERE workspace_id = $1 AND user_id = $2
+	`, workspaceID, userID)
+	if err != nil {
+		return fmt.Errorf("failed to release member types: %w", err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return nil
+}
+
+// IsMember reports whether the user is a member of the workspace.
+func (r *WorkspaceRepository) IsMember(ctx context.Context, workspaceID, userID uuid.UUID) (bool, error) {
+	query := `SELECT EXISTS(SELECT 1 FROM workspace_members WHERE workspace_id = $1 AND user_id = $2)`
+
+	var exists bool
+	if err := r.db.QueryRow(ctx, query, workspaceID, userID).Scan(&exists); err != nil {
+		return false, fmt.Errorf("failed to check workspace membership: %w", err)
+	}
+
+	return exists, nil
+}
+
+// CreateInvitation stores an invitation to a workspace under the hash of
+// token.
+func (r *WorkspaceRepository) CreateInvitation(
+	ctx context.Context,
+	workspaceID, invitedBy uuid.UUID,
+	token string,
+	expiresAt time.Time,
+) (*WorkspaceInvitation, error) {
+	query := `
+		INSERT INTO workspace_invitations (workspace_id, invited_by, token_hash, expires_at)
+		VALUES ($1, $2, $3, $4)
+		RETURNING id, workspace_id, invited_by, expires_at, created_at
+	`
+
+	var inv WorkspaceInvitation
+	err := r.db.QueryRow(ctx, query, workspaceID, invitedBy, hashToken(token), expiresAt).Scan(
+		&inv.ID,
+		&inv.WorkspaceID,
+		&inv.InvitedBy,
+		&inv.ExpiresAt,
+		&inv.CreatedAt,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create workspace invitation: %w", err)
+	}
+
+	return &inv, nil
+}
+
+// ListInvitations returns the pending, unexpired invitations of a workspace.
+func (r *WorkspaceRepository) ListInvitations(ctx context.Context, workspaceID uuid.UUID) ([]*WorkspaceInvitation, error) {
+	query := `
+		SELECT id, workspace_id, invited_by, expires_at, created_at
+		FROM workspace_invitations
+		WHERE workspace_id = $1 AND accepted_at IS NULL AND expires_at > NOW()
+		ORDER BY created_at DESC
+	`
+
+	rows, err := r.db.Query(ctx, query, workspaceID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query workspace invitations: %w", err)
+	}
+	defer rows.Close()
+
+	var invitations []*WorkspaceInvitation
+	for rows.Next() {
+		var inv WorkspaceInvitation
+		if err := rows.Scan(&inv.ID, &inv.WorkspaceID, &inv.InvitedBy, &inv.ExpiresAt, &inv.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan workspace invitation: %w", err)
+		}
+		invitations = append(invitations, &inv)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating workspace invitations: %w", err)
+	}
+
+	return invitations, nil
+}
+
+// DeleteInvitation revokes a pending invitation.
+func (r *WorkspaceRepository) DeleteInvitation(ctx context.Context, workspaceID, id uuid.UUID) error {
+	result, err := r.db.Exec(ctx, `
+		DELETE FROM workspace_invitations
+		WHERE id = $1 AND workspace_id = $2 AND accepted_at IS NULL
+	`, id, workspaceID)
+	if err != nil {
+		return fmt.Errorf("failed to delete workspace invitation: %w", err)
+	}
+
+	if result.RowsAffected() == 0 {
+		return ErrInvitationNotFound
+	}
+
+	return nil
+}
+
+// AcceptInvitation adds userID to the workspace the token invites to and
+// marks the invitation used. The workspace may hold at most maxMembers
+// members. It returns the joined workspace's id.
+func (r *WorkspaceRepository) AcceptInvitation(
+	ctx context.Context,
+	token string,
+	userID uuid.UUID,
+	maxMembers int,
+) (uuid.UUID, error) {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return uuid.Nil, fmt.Errorf("failed to start transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	var invitationID, workspaceID uuid.UUID
+	err = tx.QueryRow(ctx, `
+		SELECT id, workspace_id
+		FROM workspace_invitations
+		WHERE token_hash = $1 AND accepted_at IS NULL AND expires_at > NOW()
+		FOR UPDATE
+	`, hashToken(token)).Scan(&invitationID, &workspaceID)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return uuid.Nil, ErrInvitationNotFound
+		}
+		return uuid.Nil, fmt.Errorf("failed to find workspace invitation: %w", err)
+	}
+
+	// Lock the workspace so concurrent accepts can't overshoot the limit
+	var members int
+	err = tx.QueryRow(ctx, `
+		SELECT (SELECT COUNT(*) FROM workspace_members WHERE workspace_id = w.id)
+		FROM workspaces w
+		WHERE w.id = $1
+		FOR UPDATE
+	`, workspaceID).Scan(&members)
+	if err != nil {
+		return uuid.Nil, fmt.Errorf("failed to count workspace members: %w", err)
+	}
+	if members >= maxMembers {
+		return uuid.Nil, ErrWorkspaceFull
+	}
+
+	result, err := tx.Exec(ctx, `
+		INSERT INTO workspace_members (workspace_id, user_id, role)
+		VALUES ($1, $2, $3)
+		ON CONFLICT DO NOTHING
+	`, workspaceID, userID, WorkspaceRoleMember)
+	if err != nil {
+		return uuid.Nil, fmt.Errorf("failed to add workspace member: %w", err)
+	}
+	if result.RowsAffected() == 0 {
+		return uuid.Nil, ErrAlreadyMember
+	}
+
+	_, err = tx.Exec(ctx, `
+		UPDATE workspace_invitations SET accepted_by = $2, accepted_at = NOW()
+		WHERE id = $1
+	`, invitationID, userID)
+	if err != nil {
+		return uuid.Nil, fmt.Errorf("failed to mark workspace invitation accepted: %w", err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return uuid.Nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return workspaceID, nil
+}
@@ -0,0 +1,51 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/avalarin/livlog/backend/internal/repository"
+	"github.com/google/uuid"
+)
+
+var (
+	ErrInvalidActivityEntity = errors.New("entity must be entry or collection")
+)
+
+// activityRetention is how long entry and collection changes are kept.
+const activityRetention = 180 * 24 * time.Hour
+
+// ActivityService reads the activity log, which the database fills by
+// trigger on every entry and collection write.
+type ActivityService struct {
+	activityRepo *repository.ActivityRepository
+}
+
+func NewActivityService(activityRepo *repository.ActivityRepository) *ActivityService {
+	return &ActivityService{
+		activityRepo: activityRepo,
+	}
+}
+
+// GetActivity lists a user's activity, newest first.
+func (s *ActivityService) GetActivity(
+	ctx context.Context,
+	userID uuid.UUID,
+	filter repository.ActivityFilter,
+) ([]*repository.Activity, error) {
+	if filter.Entity != "" && filter.Entity != "entry" && filter.Entity != "collection" {
+		return nil, ErrInvalidActivityEntity
+	}
+	filter.Limit = listLimit(filter.Limit)
+	if filter.Before < 0 {
+		filter.Before = 0
+	}
+
+	return s.activityRepo.ListActivity(ctx, userID, filter)
+}
+
+// CleanupOld removes activity older than the retention period.
+func (s *ActivityService) CleanupOld(ctx context.Context) (int64, error) {
+	return s.activityRepo.DeleteActivityOlderThan(ctx, activityRetention)
+}
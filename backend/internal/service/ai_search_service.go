@@ -8,50 +8,164 @@ import (
 	"fmt"
 	"io"
 	"net/http"
+	"strconv"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/avalarin/livlog/backend/internal/config"
 	"github.com/avalarin/livlog/backend/internal/repository"
 	"github.com/google/uuid"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
 	"go.uber.org/zap"
 )
 
 var (
 	ErrAISearchRateLimitExceeded = errors.New("AI search rate limit exceeded")
+	ErrAIConsentRequired         = errors.New("AI consent required")
 )
 
+// AI consents a user gives in the app, see repository.User.
+const (
+	// AIConsentQueries allows sending AI search queries to the model provider.
+	AIConsentQueries = "queries"
+	// AIConsentEnrichment allows the model provider to enrich entries. No
+	// feature sends entries yet; the app asks for both in one sheet.
+	AIConsentEnrichment = "enrichment"
+)
+
+// AIConsentError is returned for AI features the user hasn't consented to;
+// Consent names the missing one so the app can ask for it.
+type AIConsentError struct {
+	Consent string
+}
+
+func (e *AIConsentError) Error() string {
+	return fmt.Sprintf("AI consent %q required", e.Consent)
+}
+
+func (e *AIConsentError) Is(target error) bool {
+	return target == ErrAIConsentRequired
+}
+
+// Image URLs from the model are checked with HEAD requests before they reach
+// the app, since models often return dead or non-image links.
+const (
+	imageCheckTimeout     = 3 * time.Second
+	imageCheckConcurrency = 8
+	maxImageBytes         = 10 << 20
+)
+
+var (
+	openRouterRequestDuration = promauto.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Name:    "openrouter_request_duration_seconds",
+			Help:    "OpenRouter API latency in seconds by response status (\"error\" when no response arrived)",
+			Buckets: []float64{0.5, 1, 2, 4, 8, 15, 30, 60},
+		},
+		[]string{"model", "provider", "status"},
+	)
+
+	openRouterParseTotal = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "openrouter_parse_total",
+			Help: "Total number of successful OpenRouter responses by whether their options parsed",
+		},
+		[]string{"model", "provider", "result"},
+	)
+
+	aiSearchDegradedTotal = promauto.NewCounter(
+		prometheus.CounterOpts{
+			Name: "ai_search_degraded_total",
+			Help: "Total number of AI searches answered from metadata providers because the daily OpenRouter budget ran out",
+		},
+	)
+
+	openRouterOptions = promauto.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Name:    "openrouter_options",
+			Help:    "Number of search options in parsed OpenRouter responses",
+			Buckets: []float64{0, 1, 2, 3, 4, 5},
+		},
+		[]string{"model", "provider"},
+	)
+)
+
+// modelProvider returns the vendor part of an OpenRouter model id, e.g.
+// "openai" for "openai/gpt-4o-mini".
+func modelProvider(model string) string {
+	if provider, _, ok := strings.Cut(model, "/"); ok {
+		return provider
+	}
+	return "unknown"
+}
+
+// aiSearchUsageStore is the part of repository.AISearchUsageRepository that
+// AISearchService uses.
+type aiSearchUsageStore interface {
+	CheckAndIncrementUsage(ctx context.Context, userID uuid.UUID, limit int, period time.Duration) error
+	GetUsage(ctx context.Context, userID uuid.UUID) (*repository.AISearchUsage, error)
+	RecordSearch(ctx context.Context, userID uuid.UUID) error
+	GetProviderSpend(ctx context.Context) (*repository.AIProviderSpend, error)
+	RecordProviderRequest(ctx context.Context, cost float64) error
+	MarkBudgetAlerted(ctx context.Context) (bool, error)
+}
+
 type AISearchService struct {
-	cfg        *config.Config
-	usageRepo  *repository.AISearchUsageRepository
-	userRepo   *repository.UserRepository
-	httpClient *http.Client
+	cfg           *config.Config
+	usageRepo     aiSearchUsageStore
+	userRepo      *repository.UserRepository
+	books         *BooksService        // answers searches past the budget
+	notifications *NotificationService // alerts admins about the budget
+	httpClient    *http.Client
+	clock         Clock
+	ids           IDGenerator
+	logger        *zap.Logger
+
+	// Settings that can change on config reload, guarded by mu
+	mu         sync.RWMutex
+	rateLimit  config.RateLimitConfig
 	ratePeriod time.Duration
-	logger     *zap.Logger
+	model      string
+	routing    config.AIRoutingConfig
+	budget     config.AIBudgetConfig
 }
 
 type SearchOption struct {
-	ID          string   `json:"id"`
-	Title       string   `json:"title"`
-	EntryType   string   `json:"entryType"`
-	Year        string   `json:"year,omitempty"`
-	Genre       string   `json:"genre,omitempty"`
-	Author      string   `json:"author,omitempty"`
-	Platform    string   `json:"platform,omitempty"`
-	Description string   `json:"description"`
-	ImageURLs   []string `json:"imageUrls"`
+	ID            string   `json:"id"`
+	Title         string   `json:"title"`
+	OriginalTitle string   `json:"originalTitle,omitempty"`
+	Language      string   `json:"language,omitempty"`
+	EntryType     string   `json:"entryType"`
+	Year          string   `json:"year,omitempty"`
+	Genre         string   `json:"genre,omitempty"`
+	Author        string   `json:"author,omitempty"`
+	Platform      string   `json:"platform,omitempty"`
+	Description   string   `json:"description"`
+	ImageURLs     []string `json:"imageUrls"`
+}
+
+// SearchResult is what SearchOptions found. Degraded results come from
+// metadata providers instead of the model, see config.AIBudgetConfig.
+type SearchResult struct {
+	Options  []SearchOption
+	Degraded bool
 }
 
 // DTO for parsing OpenRouter response
 type searchOptionDTO struct {
-	Title       string   `json:"title"`
-	EntryType   string   `json:"entryType"`
-	Year        string   `json:"year,omitempty"`
-	Genre       string   `json:"genre,omitempty"`
-	Author      string   `json:"author,omitempty"`
-	Platform    string   `json:"platform,omitempty"`
-	Description string   `json:"description"`
-	ImageURLs   []string `json:"imageUrls,omitempty"`
+	Title         string   `json:"title"`
+	OriginalTitle string   `json:"originalTitle,omitempty"`
+	Language      string   `json:"language,omitempty"`
+	EntryType     string   `json:"entryType"`
+	Year          string   `json:"year,omitempty"`
+	Genre         string   `json:"genre,omitempty"`
+	Author        string   `json:"author,omitempty"`
+	Platform      string   `json:"platform,omitempty"`
+	Description   string   `json:"description"`
+	ImageURLs     []string `json:"imageUrls,omitempty"`
 }
 
 type optionsResponseDTO struct {
@@ -65,12 +179,19 @@ type chatCompletionResponse struct {
 			Content string `json:"content"`
 		} `json:"message"`
 	} `json:"choices"`
+	Usage struct {
+		Cost float64 `json:"cost"` // USD
+	} `json:"usage"`
 }
 
 func NewAISearchService(
 	cfg *config.Config,
 	usageRepo *repository.AISearchUsageRepository,
 	userRepo *repository.UserRepository,
+	books *BooksService,
+	notifications *NotificationService,
+	clock Clock,
+	ids IDGenerator,
 	logger *zap.Logger,
 ) (*AISearchService, error) {
 	// Parse rate limit period
@@ -80,17 +201,144 @@ func NewAISearchService(
 	}
 
 	return &AISearchService{
-		cfg:        cfg,
-		usageRepo:  usageRepo,
-		userRepo:   userRepo,
-		httpClient: &http.Client{Timeout: 30 * time.Second},
-		ratePeriod: period,
+		cfg:           cfg,
+		usageRepo:     usageRepo,
+		userRepo:      userRepo,
+		books:         books,
+		notifications: notifications,
+		httpClient: &http.Client{
+			Timeout:   30 * time.Second,
+			Transport: otelhttp.NewTransport(http.DefaultTransport),
+		},
+		clock:      clock,
+		ids:        ids,
 		logger:     logger,
+		rateLimit:  cfg.RateLimit,
+		ratePeriod: period,
+		model:      cfg.OpenRouter.Model,
+		routing:    cfg.OpenRouter.Routing,
+		budget:     cfg.OpenRouter.Budget,
 	}, nil
 }
 
-// SearchOptions performs AI search and returns options with downloaded images
-func (s *AISearchService) SearchOptions(ctx context.Context, userID uuid.UUID, query string) ([]SearchOption, error) {
+// Reload applies the rate limits, model, routing and budget from a reloaded
+// configuration.
+// The API key and base URL are only read at startup.
+func (s *AISearchService) Reload(cfg *config.Config) error {
+	period, err := time.ParseDuration(cfg.RateLimit.AISearchPeriod)
+	if err != nil {
+		return fmt.Errorf("invalid ai_search_period: %w", err)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.rateLimit = cfg.RateLimit
+	s.ratePeriod = period
+	s.model = cfg.OpenRouter.Model
+	s.routing = cfg.OpenRouter.Routing
+	s.budget = cfg.OpenRouter.Budget
+
+	return nil
+}
+
+// AISearchAllowance is what is left of a user's AI searches. Limit and
+// Remaining are nil for unlimited policies; ResetsAt is nil until the first
+// search of a period.
+type AISearchAllowance struct {
+	Limit     *int
+	Remaining *int
+	ResetsAt  *time.Time
+}
+
+// Allowance computes a user's allowance from their policy and the searches
+// used in the current period.
+func (s *AISearchService) Allowance(policy repository.AIUsagePolicy, used int, periodEnd *time.Time) AISearchAllowance {
+	s.mu.RLock()
+	limit := s.rateLimit.GetAISearchLimit(string(policy))
+	s.mu.RUnlock()
+
+	if limit <= 0 {
+		return AISearchAllowance{}
+	}
+
+	remaining := max(limit-used, 0)
+	return AISearchAllowance{Limit: &limit, Remaining: &remaining, ResetsAt: periodEnd}
+}
+
+// Ping checks that the OpenRouter API is reachable. Any response below 500
+// counts, since the probe is not a valid completion request.
+func (s *AISearchService) Ping(ctx context.Context) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodHead, s.cfg.OpenRouter.BaseURL, nil)
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+
+	resp, err := s.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to reach OpenRouter: %w", err)
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode >= 500 {
+		return fmt.Errorf("OpenRouter returned status %d", resp.StatusCode)
+	}
+
+	return nil
+}
+
+// Query classes used to route AI searches, see config.AIRoutingConfig.
+const (
+	queryClassSimple    = "simple"
+	queryClassAmbiguous = "ambiguous"
+)
+
+// classifyQuery tells title lookups ("Inception 2010") from descriptions
+// ("film where people share dreams"): questions and queries of more than four
+// words are ambiguous.
+func classifyQuery(query string) string {
+	query = strings.TrimSpace(query)
+	if strings.HasSuffix(query, "?") || len(strings.Fields(query)) > 4 {
+		return queryClassAmbiguous
+	}
+	return queryClassSimple
+}
+
+// modelsFor returns the models to try for a query, in order: the routed
+// model, then the fallbacks, then the default model, without repeats.
+func (s *AISearchService) modelsFor(query, typeHint string) []string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	routed := s.routing.TypeModels[typeHint]
+	if routed == "" {
+		switch classifyQuery(query) {
+		case queryClassSimple:
+			routed = s.routing.SimpleModel
+		case queryClassAmbiguous:
+			routed = s.routing.AmbiguousModel
+		}
+	}
+
+	candidates := append([]string{routed}, s.routing.FallbackModels...)
+	candidates = append(candidates, s.model)
+
+	models := make([]string, 0, len(candidates))
+	seen := make(map[string]bool, len(candidates))
+	for _, m := range candidates {
+		if m != "" && !seen[m] {
+			seen[m] = true
+			models = append(models, m)
+		}
+	}
+	return models
+}
+
+// SearchOptions performs AI search and returns options with downloaded images.
+// typeHint is an optional entry type ("movie", "book", "game" or "custom") that
+// narrows the search and picks the model, see config.AIRoutingConfig. Past
+// the daily budget the options come from metadata providers, see
+// config.AIBudgetConfig.
+func (s *AISearchService) SearchOptions(ctx context.Context, userID uuid.UUID, query, typeHint string) (*SearchResult, error) {
 	s.logger.Info("starting AI search",
 		zap.String("user_id", userID.String()),
 		zap.String("query", query),
@@ -106,13 +354,28 @@ func (s *AISearchService) SearchOptions(ctx context.Context, userID uuid.UUID, q
 		return nil, fmt.Errorf("failed to get user: %w", err)
 	}
 
+	// The query leaves our servers, so it needs the user's consent; refused
+	// searches don't count against the rate limit
+	if user.AIQueryConsentAt == nil {
+		return nil, &AIConsentError{Consent: AIConsentQueries}
+	}
+
+	// Past the budget the model isn't asked, so the search doesn't count
+	// against the user's limit either
+	if s.budgetExceeded(ctx) {
+		return s.searchMetadata(ctx, query, typeHint), nil
+	}
+
 	s.logger.Info("user AI usage policy",
 		zap.String("user_id", userID.String()),
 		zap.String("policy", string(user.AIUsagePolicy)),
 	)
 
 	// Get the rate limit for the user's policy
-	limit := s.cfg.RateLimit.GetAISearchLimit(string(user.AIUsagePolicy))
+	s.mu.RLock()
+	limit := s.rateLimit.GetAISearchLimit(string(user.AIUsagePolicy))
+	period := s.ratePeriod
+	s.mu.RUnlock()
 
 	// Check rate limit (skip if limit is 0 - unlimited)
 	if limit > 0 {
@@ -120,8 +383,11 @@ func (s *AISearchService) SearchOptions(ctx context.Context, userID uuid.UUID, q
 			ctx,
 			userID,
 			limit,
-			s.ratePeriod,
+			period,
 		)
+		if err == nil || errors.Is(err, repository.ErrRateLimitExceeded) {
+			RecordRateLimitDecision("ai_search", "user", err == nil)
+		}
 		if err != nil {
 			if errors.Is(err, repository.ErrRateLimitExceeded) {
 				s.logger.Warn("rate limit exceeded",
@@ -129,7 +395,11 @@ func (s *AISearchService) SearchOptions(ctx context.Context, userID uuid.UUID, q
 					zap.String("policy", string(user.AIUsagePolicy)),
 					zap.Int("limit", limit),
 				)
-				return nil, ErrAISearchRateLimitExceeded
+				limitErr := &RateLimitError{Err: ErrAISearchRateLimitExceeded, Limit: limit, RetryAfter: period}
+				if usage, err := s.usageRepo.GetUsage(ctx, userID); err == nil && usage != nil {
+					limitErr.RetryAfter = usage.PeriodEnd.Sub(s.clock.Now())
+				}
+				return nil, limitErr
 			}
 			s.logger.Error("failed to check rate limit",
 				zap.String("user_id", userID.String()),
@@ -143,8 +413,26 @@ func (s *AISearchService) SearchOptions(ctx context.Context, userID uuid.UUID, q
 		)
 	}
 
-	// Call OpenRouter API
-	options, err := s.callOpenRouterAPI(ctx, query)
+	// Daily totals are only for the admin stats and usage; a failure must not
+	// fail the search
+	if err := s.usageRepo.RecordSearch(ctx, userID); err != nil {
+		s.logger.Warn("failed to record AI search", zap.Error(err))
+	}
+
+	// Call OpenRouter API, moving on to the next model when one fails
+	var options []searchOptionDTO
+	for _, model := range s.modelsFor(query, typeHint) {
+		var cost float64
+		options, cost, err = s.callOpenRouterAPI(ctx, model, query, typeHint)
+		s.recordProviderRequest(ctx, cost)
+		if err == nil || ctx.Err() != nil {
+			break
+		}
+		s.logger.Warn("AI search model failed",
+			zap.String("model", model),
+			zap.Error(err),
+		)
+	}
 	if err != nil {
 		s.logger.Error("failed to call OpenRouter API",
 			zap.String("query", query),
@@ -158,30 +446,43 @@ func (s *AISearchService) SearchOptions(ctx context.Context, userID uuid.UUID, q
 		zap.Int("results_count", len(options)),
 	)
 
-	// Download images for each option
+	// Keep the first 3 image URLs of each option that resolve to images
+	var candidates []string
+	for _, option := range options {
+		imageURLs := option.ImageURLs
+		if len(imageURLs) > 3 {
+			imageURLs = imageURLs[:3]
+		}
+		for _, imageURL := range imageURLs {
+			if s.isValidImageURL(imageURL) {
+				candidates = append(candidates, imageURL)
+			}
+		}
+	}
+	reachable := s.checkImageURLs(ctx, candidates)
+
 	var results []SearchOption
 	for _, option := range options {
 		result := SearchOption{
-			ID:          uuid.New().String(),
-			Title:       option.Title,
-			EntryType:   option.EntryType,
-			Year:        option.Year,
-			Genre:       option.Genre,
-			Author:      option.Author,
-			Platform:    option.Platform,
-			Description: option.Description,
-			ImageURLs:   []string{},
+			ID:            s.ids.NewID().String(),
+			Title:         option.Title,
+			OriginalTitle: option.OriginalTitle,
+			Language:      searchOptionLanguage(option.Language),
+			EntryType:     option.EntryType,
+			Year:          option.Year,
+			Genre:         option.Genre,
+			Author:        option.Author,
+			Platform:      option.Platform,
+			Description:   option.Description,
+			ImageURLs:     []string{},
 		}
 
-		// Download images (up to 3)
 		imageURLs := option.ImageURLs
 		if len(imageURLs) > 3 {
 			imageURLs = imageURLs[:3]
 		}
-
 		for _, imageURL := range imageURLs {
-			// Try to download the image
-			if s.isValidImageURL(imageURL) {
+			if reachable[imageURL] {
 				result.ImageURLs = append(result.ImageURLs, imageURL)
 			}
 		}
@@ -189,18 +490,134 @@ func (s *AISearchService) SearchOptions(ctx context.Context, userID uuid.UUID, q
 		results = append(results, result)
 	}
 
-	return results, nil
+	return &SearchResult{Options: results}, nil
 }
 
-// callOpenRouterAPI calls the OpenRouter API and returns search options
-func (s *AISearchService) callOpenRouterAPI(ctx context.Context, query string) ([]searchOptionDTO, error) {
-	prompt := fmt.Sprintf(`User is searching for: "%s"
+// recordProviderRequest adds an OpenRouter request to today's spend. The
+// request was made either way, so it is recorded even if the search was
+// canceled, and a failure to record must not fail the search.
+func (s *AISearchService) recordProviderRequest(ctx context.Context, cost float64) {
+	if err := s.usageRepo.RecordProviderRequest(context.WithoutCancel(ctx), cost); err != nil {
+		s.logger.Warn("failed to record OpenRouter request", zap.Error(err))
+	}
+}
+
+// budgetExceeded reports whether today's OpenRouter requests or spend reached
+// the budget. When the spend can't be read, searches go on as usual.
+func (s *AISearchService) budgetExceeded(ctx context.Context) bool {
+	s.mu.RLock()
+	budget := s.budget
+	s.mu.RUnlock()
+
+	if budget.DailyRequests == 0 && budget.DailySpendUSD == 0 {
+		return false
+	}
+
+	spend, err := s.usageRepo.GetProviderSpend(ctx)
+	if err != nil {
+		s.logger.Warn("failed to get OpenRouter spend", zap.Error(err))
+		return false
+	}
+	if !budget.Exceeded(spend.Requests, spend.Cost) {
+		return false
+	}
+
+	s.alertBudgetExceeded(ctx, spend)
+	return true
+}
+
+// alertBudgetExceeded notifies the admins the first time a day's budget is
+// found exceeded, across instances.
+func (s *AISearchService) alertBudgetExceeded(ctx context.Context, spend *repository.AIProviderSpend) {
+	first, err := s.usageRepo.MarkBudgetAlerted(ctx)
+	if err != nil {
+		s.logger.Warn("failed to mark AI search budget alerted", zap.Error(err))
+		return
+	}
+	if !first {
+		return
+	}
+
+	s.logger.Error("AI search budget exceeded, searching metadata providers only until tomorrow (UTC)",
+		zap.Int64("requests", spend.Requests),
+		zap.Float64("spend_usd", spend.Cost),
+	)
+
+	if s.notifications == nil {
+		return
+	}
+	adminIDs, err := s.userRepo.ListUserIDsByRole(ctx, repository.UserRoleAdmin)
+	if err != nil {
+		s.logger.Warn("failed to list admins", zap.Error(err))
+		return
+	}
+	body := fmt.Sprintf("%d OpenRouter requests costing $%.2f today. AI search answers from metadata providers only until the budget resets at midnight UTC.",
+		spend.Requests, spend.Cost)
+	data := map[string]interface{}{"requests": spend.Requests, "spend_usd": spend.Cost}
+	for _, adminID := range adminIDs {
+		if _, err := s.notifications.Notify(ctx, adminID, NotificationAIBudgetExceeded, "AI search budget exceeded", body, data); err != nil {
+			s.logger.Warn("failed to notify admin",
+				zap.String("user_id", adminID.String()),
+				zap.Error(err),
+			)
+		}
+	}
+}
+
+// metadataSearchLimit is how many options a metadata search returns, like
+// the model is asked for.
+const metadataSearchLimit = 5
 
+// searchMetadata answers a search from metadata providers alone. Only books
+// have one, so searches hinted at other types find nothing, and a failing
+// provider finds nothing rather than failing the search.
+func (s *AISearchService) searchMetadata(ctx context.Context, query, typeHint string) *SearchResult {
+	aiSearchDegradedTotal.Inc()
+
+	result := &SearchResult{Options: []SearchOption{}, Degraded: true}
+	if s.books == nil || (typeHint != "" && typeHint != "book") {
+		return result
+	}
+
+	books, err := s.books.SearchBooks(ctx, query, metadataSearchLimit)
+	if err != nil {
+		s.logger.Warn("metadata search failed", zap.Error(err))
+		return result
+	}
+
+	for _, book := range books {
+		option := SearchOption{
+			ID:        s.ids.NewID().String(),
+			Title:     book.Title,
+			EntryType: "book",
+			Year:      book.Year,
+			Author:    strings.Join(book.Authors, ", "),
+			ImageURLs: []string{},
+		}
+		if book.CoverURL != "" {
+			option.ImageURLs = append(option.ImageURLs, book.CoverURL)
+		}
+		result.Options = append(result.Options, option)
+	}
+	return result
+}
+
+// callOpenRouterAPI asks model through the OpenRouter API for search options
+func (s *AISearchService) callOpenRouterAPI(ctx context.Context, model, query, typeHint string) ([]searchOptionDTO, float64, error) {
+	hint := ""
+	if typeHint != "" {
+		hint = fmt.Sprintf("\nThe user is looking for a %s; prefer options of that entryType.\n", typeHint)
+	}
+
+	prompt := fmt.Sprintf(`User is searching for: "%s"
+%s
 Search and find what this might be. It could be a movie, book, game, or something else.
 Return up to 5 most relevant options as JSON array.
 
 For each option provide:
 - title: the exact title
+- originalTitle: the title in its original language, if it differs from title (null otherwise)
+- language: original language as a BCP 47 code, e.g. "ja" or "pt-BR"
 - entryType: one of "movie", "book", "game", or "custom"
 - year: release/publication year (if applicable)
 - genre: genre(s)
@@ -210,10 +627,12 @@ For each option provide:
 - imageUrls: array of up to 3 image URLs (posters, covers, screenshots) - direct links to images
 
 Return ONLY valid JSON in this exact format, no markdown, no extra text:
-{"options": [{"title": "...", "entryType": "...", "year": "...", "genre": "...", "author": null, "platform": null, "description": "...", "imageUrls": ["url1", "url2"]}]}`, query)
+{"options": [{"title": "...", "originalTitle": null, "language": "...", "entryType": "...", "year": "...", "genre": "...", "author": null, "platform": null, "description": "...", "imageUrls": ["url1", "url2"]}]}`, query, hint)
 
 	requestBody := map[string]interface{}{
-		"model": s.cfg.OpenRouter.Model,
+		"model": model,
+		// Asks OpenRouter for the cost of the request, see recordProviderRequest
+		"usage": map[string]bool{"include": true},
 		"messages": []map[string]string{
 			{
 				"role":    "user",
@@ -224,12 +643,12 @@ Return ONLY valid JSON in this exact format, no markdown, no extra text:
 
 	bodyBytes, err := json.Marshal(requestBody)
 	if err != nil {
-		return nil, fmt.Errorf("failed to marshal request: %w", err)
+		return nil, 0, fmt.Errorf("failed to marshal request: %w", err)
 	}
 
 	req, err := http.NewRequestWithContext(ctx, "POST", s.cfg.OpenRouter.BaseURL, bytes.NewBuffer(bodyBytes))
 	if err != nil {
-		return nil, fmt.Errorf("failed to create request: %w", err)
+		return nil, 0, fmt.Errorf("failed to create request: %w", err)
 	}
 
 	req.Header.Set("Content-Type", "application/json")
@@ -238,18 +657,22 @@ Return ONLY valid JSON in this exact format, no markdown, no extra text:
 
 	s.logger.Info("calling OpenRouter API",
 		zap.String("url", s.cfg.OpenRouter.BaseURL),
-		zap.String("model", s.cfg.OpenRouter.Model),
+		zap.String("model", model),
 		zap.String("query", query),
 	)
 
+	provider := modelProvider(model)
+	start := time.Now()
 	resp, err := s.httpClient.Do(req)
 	if err != nil {
+		openRouterRequestDuration.WithLabelValues(model, provider, "error").Observe(time.Since(start).Seconds())
 		s.logger.Error("OpenRouter API request failed",
 			zap.Error(err),
 		)
-		return nil, fmt.Errorf("failed to send request: %w", err)
+		return nil, 0, fmt.Errorf("failed to send request: %w", err)
 	}
 	defer resp.Body.Close()
+	openRouterRequestDuration.WithLabelValues(model, provider, strconv.Itoa(resp.StatusCode)).Observe(time.Since(start).Seconds())
 
 	s.logger.Info("OpenRouter API response received",
 		zap.Int("status_code", resp.StatusCode),
@@ -262,20 +685,24 @@ Return ONLY valid JSON in this exact format, no markdown, no extra text:
 			zap.Int("status_code", resp.StatusCode),
 			zap.String("response_body", bodyStr),
 		)
-		return nil, fmt.Errorf("OpenRouter API error (status %d): %s", resp.StatusCode, bodyStr)
+		return nil, 0, fmt.Errorf("OpenRouter API error (status %d): %s", resp.StatusCode, bodyStr)
 	}
 
 	var chatResp chatCompletionResponse
 	if err := json.NewDecoder(resp.Body).Decode(&chatResp); err != nil {
+		openRouterParseTotal.WithLabelValues(model, provider, "failure").Inc()
 		s.logger.Error("failed to decode OpenRouter response",
 			zap.Error(err),
 		)
-		return nil, fmt.Errorf("failed to decode response: %w", err)
+		return nil, 0, fmt.Errorf("failed to decode response: %w", err)
 	}
 
+	cost := chatResp.Usage.Cost
+
 	if len(chatResp.Choices) == 0 || chatResp.Choices[0].Message.Content == "" {
+		openRouterParseTotal.WithLabelValues(model, provider, "failure").Inc()
 		s.logger.Error("OpenRouter response has no content")
-		return nil, fmt.Errorf("no content in OpenRouter response")
+		return nil, cost, fmt.Errorf("no content in OpenRouter response")
 	}
 
 	// Parse the JSON from the text (remove markdown code blocks if present)
@@ -290,18 +717,22 @@ Return ONLY valid JSON in this exact format, no markdown, no extra text:
 
 	var optionsResp optionsResponseDTO
 	if err := json.Unmarshal([]byte(cleanedText), &optionsResp); err != nil {
+		openRouterParseTotal.WithLabelValues(model, provider, "failure").Inc()
 		s.logger.Error("failed to parse options JSON",
 			zap.Error(err),
 			zap.String("cleaned_text", cleanedText),
 		)
-		return nil, fmt.Errorf("failed to parse options JSON: %w", err)
+		return nil, cost, fmt.Errorf("failed to parse options JSON: %w", err)
 	}
 
+	openRouterParseTotal.WithLabelValues(model, provider, "success").Inc()
+	openRouterOptions.WithLabelValues(model, provider).Observe(float64(len(optionsResp.Options)))
+
 	s.logger.Info("successfully parsed OpenRouter response",
 		zap.Int("options_count", len(optionsResp.Options)),
 	)
 
-	return optionsResp.Options, nil
+	return optionsResp.Options, cost, nil
 }
 
 // isValidImageURL performs basic validation on image URLs
@@ -312,3 +743,73 @@ func (s *AISearchService) isValidImageURL(url string) bool {
 	// Basic URL validation
 	return strings.HasPrefix(url, "http://") || strings.HasPrefix(url, "https://")
 }
+
+// checkImageURLs sends HEAD requests to the URLs, at most
+// imageCheckConcurrency at a time, and returns the set of URLs that answer
+// with an image of at most maxImageBytes.
+func (s *AISearchService) checkImageURLs(ctx context.Context, urls []string) map[string]bool {
+	var (
+		mu        sync.Mutex
+		wg        sync.WaitGroup
+		reachable = make(map[string]bool, len(urls))
+		sem       = make(chan struct{}, imageCheckConcurrency)
+	)
+	for _, u := range urls {
+		wg.Add(1)
+		go func(u string) {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+
+			if s.isImageURL(ctx, u) {
+				mu.Lock()
+				reachable[u] = true
+				mu.Unlock()
+			}
+		}(u)
+	}
+	wg.Wait()
+
+	s.logger.Info("checked AI search image URLs",
+		zap.Int("checked", len(urls)),
+		zap.Int("reachable", len(reachable)),
+	)
+	return reachable
+}
+
+// isImageURL reports whether a HEAD request to u succeeds with an image
+// content type and a size, when the server sends one, of at most
+// maxImageBytes.
+func (s *AISearchService) isImageURL(ctx context.Context, u string) bool {
+	ctx, cancel := context.WithTimeout(ctx, imageCheckTimeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodHead, u, nil)
+	if err != nil {
+		return false
+	}
+	resp, err := s.httpClient.Do(req)
+	if err != nil {
+		return false
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return false
+	}
+	if !strings.HasPrefix(resp.Header.Get("Content-Type"), "image/") {
+		return false
+	}
+	// ContentLength is -1 when unknown
+	return resp.ContentLength != 0 && resp.ContentLength <= maxImageBytes
+}
+
+// searchOptionLanguage drops a language the model returned that isn't a
+// BCP 47 tag, so it can be saved on an entry as is.
+func searchOptionLanguage(language string) string {
+	tag, err := NormalizeLanguage(language)
+	if err != nil {
+		return ""
+	}
+	return tag
+}
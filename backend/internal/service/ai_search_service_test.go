@@ -0,0 +1,121 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+
+	"github.com/avalarin/livlog/backend/internal/config"
+	"github.com/avalarin/livlog/backend/internal/repository"
+)
+
+// fakeAIUsage serves today's OpenRouter spend; only the budget methods are
+// used by these tests.
+type fakeAIUsage struct {
+	aiSearchUsageStore
+	spend    repository.AIProviderSpend
+	spendErr error
+	alerts   int
+}
+
+func (f *fakeAIUsage) GetProviderSpend(context.Context) (*repository.AIProviderSpend, error) {
+	if f.spendErr != nil {
+		return nil, f.spendErr
+	}
+	return &f.spend, nil
+}
+
+func (f *fakeAIUsage) MarkBudgetAlerted(context.Context) (bool, error) {
+	f.alerts++
+	return f.alerts == 1, nil
+}
+
+func TestBudgetExceeded(t *testing.T) {
+	tests := []struct {
+		name     string
+		budget   config.AIBudgetConfig
+		spend    repository.AIProviderSpend
+		spendErr error
+		want     bool
+	}{
+		{"no budget", config.AIBudgetConfig{}, repository.AIProviderSpend{Requests: 1000, Cost: 50}, nil, false},
+		{"under budget", config.AIBudgetConfig{DailyRequests: 100, DailySpendUSD: 5}, repository.AIProviderSpend{Requests: 99, Cost: 4.99}, nil, false},
+		{"requests reached", config.AIBudgetConfig{DailyRequests: 100}, repository.AIProviderSpend{Requests: 100}, nil, true},
+		{"spend reached", config.AIBudgetConfig{DailySpendUSD: 5}, repository.AIProviderSpend{Requests: 10, Cost: 5}, nil, true},
+		{"spend unreadable", config.AIBudgetConfig{DailyRequests: 100}, repository.AIProviderSpend{}, errors.New("db down"), false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			usage := &fakeAIUsage{spend: tt.spend, spendErr: tt.spendErr}
+			s := &AISearchService{usageRepo: usage, budget: tt.budget, logger: zap.NewNop()}
+
+			if got := s.budgetExceeded(context.Background()); got != tt.want {
+				t.Errorf("budgetExceeded() = %t, want %t", got, tt.want)
+			}
+			if got := s.budgetExceeded(context.Background()); got != tt.want {
+				t.Errorf("second budgetExceeded() = %t, want %t", got, tt.want)
+			}
+			// Each exceeded check marks the alert; only the first one sends it
+			if tt.want && usage.alerts != 2 {
+				t.Errorf("alert marked %d times, want 2", usage.alerts)
+			}
+			if !tt.want && usage.alerts != 0 {
+				t.Errorf("alert marked %d times under budget", usage.alerts)
+			}
+		})
+	}
+}
+
+func TestSearchMetadata(t *testing.T) {
+	searches := 0
+	books := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		searches++
+		if r.URL.Query().Get("q") == "broken" {
+			w.WriteHeader(http.StatusBadGateway)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"docs": [
+			{"title": "Dune", "author_name": ["Frank Herbert"], "first_publish_year": 1965, "cover_i": 11481354},
+			{"title": "Dune Messiah", "author_name": ["Frank Herbert"]}
+		]}`))
+	}))
+	defer books.Close()
+
+	s := &AISearchService{
+		books:  NewBooksService(config.BooksConfig{BaseURL: books.URL, Timeout: time.Second}, zap.NewNop()),
+		ids:    NewSequentialIDs(uuid.Nil),
+		logger: zap.NewNop(),
+	}
+
+	result := s.searchMetadata(context.Background(), "dune", "")
+	if !result.Degraded || len(result.Options) != 2 {
+		t.Fatalf("searchMetadata() = %+v, want 2 degraded options", result)
+	}
+	dune := result.Options[0]
+	if dune.Title != "Dune" || dune.EntryType != "book" || dune.Author != "Frank Herbert" || dune.Year != "1965" ||
+		len(dune.ImageURLs) != 1 || dune.ImageURLs[0] != "https://covers.openlibrary.org/b/id/11481354-L.jpg" || dune.ID == "" {
+		t.Errorf("first option = %+v", dune)
+	}
+	if messiah := result.Options[1]; messiah.ImageURLs == nil || len(messiah.ImageURLs) != 0 {
+		t.Errorf("option without a cover has image urls %v, want []", messiah.ImageURLs)
+	}
+
+	// Only books have a metadata provider
+	searches = 0
+	if result := s.searchMetadata(context.Background(), "dune", "movie"); !result.Degraded || len(result.Options) != 0 || searches != 0 {
+		t.Errorf("movie search = %+v after %d book searches, want no options and no search", result, searches)
+	}
+
+	// A failing provider finds nothing rather than failing the search
+	if result := s.searchMetadata(context.Background(), "broken", "book"); !result.Degraded || result.Options == nil || len(result.Options) != 0 {
+		t.Errorf("failed search = %+v, want an empty degraded result", result)
+	}
+}
@@ -10,9 +10,11 @@ import (
 	"io"
 	"math/big"
 	"net/http"
+	"sync"
 	"time"
 
 	"github.com/golang-jwt/jwt/v5"
+	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
 )
 
 var (
@@ -23,20 +25,59 @@ var (
 	ErrAppleKeysNotFound = errors.New("apple public keys not found")
 )
 
-const appleKeysURL = "https://appleid.apple.com/auth/keys"
+const (
+	appleKeysURL = "https://appleid.apple.com/auth/keys"
+
+	// appleKeysTTL is how long fetched keys are used before they are fetched
+	// again. The apple_keys_refresh job normally renews them well before.
+	appleKeysTTL = 24 * time.Hour
+
+	// appleKeysRetryDelay bounds how often a token signed with an unknown kid
+	// triggers a fetch, so forged tokens can't make us hammer Apple.
+	appleKeysRetryDelay = time.Minute
+)
 
 type AppleTokenClaims struct {
-	Sub            string `json:"sub"`
-	Email          string `json:"email"`
-	EmailVerified  bool   `json:"email_verified"`
-	IsPrivateEmail bool   `json:"is_private_email"`
+	Sub            string    `json:"sub"`
+	Email          string    `json:"email"`
+	EmailVerified  appleBool `json:"email_verified"`
+	IsPrivateEmail appleBool `json:"is_private_email"` // Email is a private relay address
 	jwt.RegisteredClaims
 }
 
+// appleBool is a boolean claim, which Apple sends either as a JSON boolean or
+// as the string "true" or "false".
+type appleBool bool
+
+func (b *appleBool) UnmarshalJSON(data []byte) error {
+	switch string(data) {
+	case "true", `"true"`:
+		*b = true
+	case "false", `"false"`, "null":
+		*b = false
+	default:
+		return fmt.Errorf("invalid boolean claim %s", data)
+	}
+	return nil
+}
+
+// AppleVerifier verifies Sign in with Apple identity tokens against Apple's
+// public keys. Keys are cached for appleKeysTTL and refetched early when a
+// token names a key we don't have, which is how Apple key rotations show up.
 type AppleVerifier struct {
 	bundleID string
-	keys     map[string]*rsa.PublicKey
+	keysURL  string
 	client   *http.Client
+	now      func() time.Time
+
+	mu        sync.RWMutex
+	keys      map[string]*rsa.PublicKey
+	fetchedAt time.Time
+
+	// fetchMu serializes fetches, so concurrent logins wait for one fetch
+	// instead of each starting their own.
+	fetchMu        sync.Mutex
+	lastFetchStart time.Time // guarded by fetchMu
 }
 
 type appleJWKS struct {
@@ -55,10 +96,13 @@ type appleJWK struct {
 func NewAppleVerifier(bundleID string) *AppleVerifier {
 	return &AppleVerifier{
 		bundleID: bundleID,
+		keysURL:  appleKeysURL,
 		keys:     make(map[string]*rsa.PublicKey),
 		client: &http.Client{
-			Timeout: 10 * time.Second,
+			Timeout:   10 * time.Second,
+			Transport: otelhttp.NewTransport(http.DefaultTransport),
 		},
+		now: time.Now,
 	}
 }
 
@@ -111,30 +155,66 @@ func (v *AppleVerifier) VerifyIdentityToken(identityToken string) (*AppleTokenCl
 }
 
 func (v *AppleVerifier) getPublicKey(kid string) (*rsa.PublicKey, error) {
-	// Check cache
-	if key, exists := v.keys[kid]; exists {
+	key, fresh := v.cachedKey(kid)
+	if key != nil && fresh {
 		return key, nil
 	}
 
-	// Fetch keys from Apple
-	if err := v.fetchAppleKeys(); err != nil {
-		return nil, err
+	v.fetchMu.Lock()
+	defer v.fetchMu.Unlock()
+
+	// Another login may have fetched the keys while we waited
+	key, fresh = v.cachedKey(kid)
+	if key != nil && fresh {
+		return key, nil
 	}
 
-	// Check cache again
-	key, exists := v.keys[kid]
-	if !exists {
-		return nil, ErrAppleKeysNotFound
+	// Unknown kids are retried at most every appleKeysRetryDelay; stale keys
+	// are always refetched
+	if key != nil || v.now().Sub(v.lastFetchStart) >= appleKeysRetryDelay {
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+
+		if err := v.fetchKeysLocked(ctx); err != nil {
+			// Keep using a stale key while Apple is unreachable
+			if key != nil {
+				return key, nil
+			}
+			return nil, err
+		}
+		key, _ = v.cachedKey(kid)
 	}
 
+	if key == nil {
+		return nil, ErrAppleKeysNotFound
+	}
 	return key, nil
 }
 
-func (v *AppleVerifier) fetchAppleKeys() error {
-	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
-	defer cancel()
+// cachedKey returns the cached key for kid, or nil, and whether the cache is
+// still within its TTL.
+func (v *AppleVerifier) cachedKey(kid string) (*rsa.PublicKey, bool) {
+	v.mu.RLock()
+	defer v.mu.RUnlock()
+
+	return v.keys[kid], v.now().Sub(v.fetchedAt) < appleKeysTTL
+}
+
+// RefreshKeys fetches Apple's current keys, replacing the cached ones. It is
+// run periodically so logins rarely wait for a fetch.
+func (v *AppleVerifier) RefreshKeys(ctx context.Context) error {
+	v.fetchMu.Lock()
+	defer v.fetchMu.Unlock()
 
-	req, err := http.NewRequestWithContext(ctx, http.MethodGet, appleKeysURL, nil)
+	return v.fetchKeysLocked(ctx)
+}
+
+// fetchKeysLocked fetches Apple's keys and replaces the cache with them, so
+// keys Apple has rotated out stop being accepted. The caller holds fetchMu.
+func (v *AppleVerifier) fetchKeysLocked(ctx context.Context) error {
+	v.lastFetchStart = v.now()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, v.keysURL, nil)
 	if err != nil {
 		return err
 	}
@@ -160,6 +240,7 @@ func (v *AppleVerifier) fetchAppleKeys() error {
 	}
 
 	// Convert JWKs to RSA public keys
+	keys := make(map[string]*rsa.PublicKey, len(jwks.Keys))
 	for _, key := range jwks.Keys {
 		if key.Kty != "RSA" {
 			continue
@@ -181,13 +262,19 @@ func (v *AppleVerifier) fetchAppleKeys() error {
 			e = e<<8 + int(b)
 		}
 
-		publicKey := &rsa.PublicKey{
+		keys[key.Kid] = &rsa.PublicKey{
 			N: n,
 			E: e,
 		}
-
-		v.keys[key.Kid] = publicKey
 	}
+	if len(keys) == 0 {
+		return ErrAppleKeysNotFound
+	}
+
+	v.mu.Lock()
+	v.keys = keys
+	v.fetchedAt = v.now()
+	v.mu.Unlock()
 
 	return nil
 }
@@ -6,18 +6,44 @@ import (
 	"fmt"
 	"time"
 
+	"github.com/avalarin/livlog/backend/internal/logger"
 	"github.com/avalarin/livlog/backend/internal/repository"
 	"github.com/google/uuid"
+	"go.uber.org/zap"
 )
 
 var (
 	ErrInvalidCredentials = errors.New("invalid credentials")
+	// ErrAccountPendingDeletion is returned when someone signs in to or
+	// signs up with the identity or email of an account they deleted. Both
+	// are freed when the retention job purges the account.
+	ErrAccountPendingDeletion = errors.New("account is deleted and awaiting purge")
+	// ErrEmailVerificationRequired is returned for security-sensitive
+	// actions while the user's latest email transition left their email
+	// unverified; see EmailAuthService.VerifyUserEmail.
+	ErrEmailVerificationRequired = errors.New("email verification required")
 )
 
+// emailEventsLimit is how many email events GetEmailEvents returns.
+const emailEventsLimit = 50
+
+// accountStore is the part of repository.UserRepository that deleting an
+// account uses.
+type accountStore interface {
+	GetUserByID(ctx context.Context, id uuid.UUID) (*repository.User, error)
+	EmailVerificationRequired(ctx context.Context, userID uuid.UUID) (bool, error)
+	RevokeAllUserTokens(ctx context.Context, userID uuid.UUID) error
+	DeleteUser(ctx context.Context, id uuid.UUID) error
+}
+
 type AuthService struct {
 	userRepo      *repository.UserRepository
 	appleVerifier *AppleVerifier
 	jwtService    *JWTService
+	// mailer sends the codes users verify their email again with, see
+	// EmailAuthService.SendUserEmailCode; nil without a mail server
+	mailer Mailer
+	clock  Clock
 }
 
 type PersonNameComponents struct {
@@ -26,7 +52,7 @@ type PersonNameComponents struct {
 }
 
 type AppleAuthRequest struct {
-	IdentityToken     string                `json:"identity_token"`
+	IdentityToken     string                `json:"identity_token" validate:"required"`
 	AuthorizationCode *string               `json:"authorization_code,omitempty"`
 	FullName          *PersonNameComponents `json:"full_name,omitempty"`
 	Email             *string               `json:"email,omitempty"`
@@ -40,50 +66,74 @@ type AuthResponse struct {
 }
 
 type User struct {
-	ID            string   `json:"id"`
-	Email         *string  `json:"email,omitempty"`
-	EmailVerified bool     `json:"email_verified"`
-	DisplayName   *string  `json:"display_name,omitempty"`
-	AuthProviders []string `json:"auth_providers"`
-	CreatedAt     string   `json:"created_at"`
-	UpdatedAt     *string  `json:"updated_at,omitempty"`
+	ID             string       `json:"id"`
+	Email          *string      `json:"email,omitempty"`
+	EmailVerified  bool         `json:"email_verified"`
+	IsPrivateEmail bool         `json:"is_private_email"` // Apple private relay address
+	DisplayName    *string      `json:"display_name,omitempty"`
+	AuthProviders  []string     `json:"auth_providers"`
+	AIConsent      AIConsent    `json:"ai_consent"`
+	Settings       UserSettings `json:"settings"`
+	CreatedAt      time.Time    `json:"created_at"`
+	UpdatedAt      time.Time    `json:"updated_at"`
+}
+
+// AIConsent is what the user allowed AI features to do with their data.
+type AIConsent struct {
+	Queries    bool `json:"queries"`
+	Enrichment bool `json:"enrichment"`
+}
+
+// UserSettings are the user's preferences for how the server handles their
+// data.
+type UserSettings struct {
+	// AutoAssignCollection files entries created without a collection in the
+	// suggested one, see EntryService.SuggestCollection.
+	AutoAssignCollection bool `json:"auto_assign_collection"`
 }
 
 func NewAuthService(
 	userRepo *repository.UserRepository,
 	appleVerifier *AppleVerifier,
 	jwtService *JWTService,
+	mailer Mailer,
+	clock Clock,
 ) *AuthService {
 	return &AuthService{
 		userRepo:      userRepo,
 		appleVerifier: appleVerifier,
 		jwtService:    jwtService,
+		mailer:        mailer,
+		clock:         clock,
 	}
 }
 
-func (s *AuthService) AuthenticateWithApple(ctx context.Context, req *AppleAuthRequest) (*AuthResponse, error) {
+// AuthenticateWithApple signs in with an Apple identity token, registering
+// new users. The refresh token it issues is stored with client.
+func (s *AuthService) AuthenticateWithApple(ctx context.Context, req *AppleAuthRequest, client repository.TokenClient) (*AuthResponse, error) {
 	// Verify Apple identity token
 	claims, err := s.appleVerifier.VerifyIdentityToken(req.IdentityToken)
 	if err != nil {
 		return nil, fmt.Errorf("failed to verify Apple token: %w", err)
 	}
 
-	appleUserID := claims.Sub
-	email := claims.Email
-	emailVerified := claims.EmailVerified
-
 	// Try to find existing user
-	user, err := s.userRepo.FindUserByProvider(ctx, "apple", appleUserID)
+	user, err := s.userRepo.FindUserByProvider(ctx, "apple", claims.Sub)
 	if err != nil {
+		if errors.Is(err, repository.ErrUserDeleted) {
+			return nil, ErrAccountPendingDeletion
+		}
 		if errors.Is(err, repository.ErrUserNotFound) {
 			// Register new user
-			user, err = s.registerNewAppleUser(ctx, req, appleUserID, email, emailVerified)
+			user, err = s.registerNewAppleUser(ctx, req, claims)
 			if err != nil {
 				return nil, fmt.Errorf("failed to register user: %w", err)
 			}
 		} else {
 			return nil, fmt.Errorf("failed to find user: %w", err)
 		}
+	} else if err := s.syncAppleEmail(ctx, user, claims); err != nil {
+		return nil, fmt.Errorf("failed to update email: %w", err)
 	}
 
 	// Generate tokens
@@ -98,8 +148,8 @@ func (s *AuthService) AuthenticateWithApple(ctx context.Context, req *AppleAuthR
 	}
 
 	// Save refresh token
-	expiresAt := time.Now().Add(s.jwtService.GetRefreshTokenLifetime())
-	if err := s.userRepo.SaveRefreshToken(ctx, user.ID, refreshToken, expiresAt); err != nil {
+	expiresAt := s.clock.Now().Add(s.jwtService.GetRefreshTokenLifetime())
+	if err := s.userRepo.SaveRefreshToken(ctx, user.ID, refreshToken, expiresAt, client); err != nil {
 		return nil, fmt.Errorf("failed to save refresh token: %w", err)
 	}
 
@@ -117,7 +167,9 @@ func (s *AuthService) AuthenticateWithApple(ctx context.Context, req *AppleAuthR
 	}, nil
 }
 
-func (s *AuthService) RefreshToken(ctx context.Context, refreshToken string) (*AuthResponse, error) {
+// RefreshToken exchanges a refresh token for new tokens. The new refresh
+// token is stored with client, the one refreshing.
+func (s *AuthService) RefreshToken(ctx context.Context, refreshToken string, client repository.TokenClient) (*AuthResponse, error) {
 	// Find refresh token
 	token, err := s.userRepo.FindRefreshToken(ctx, refreshToken)
 	if err != nil {
@@ -150,8 +202,8 @@ func (s *AuthService) RefreshToken(ctx context.Context, refreshToken string) (*A
 	}
 
 	// Save new refresh token
-	expiresAt := time.Now().Add(s.jwtService.GetRefreshTokenLifetime())
-	if err := s.userRepo.SaveRefreshToken(ctx, user.ID, newRefreshToken, expiresAt); err != nil {
+	expiresAt := s.clock.Now().Add(s.jwtService.GetRefreshTokenLifetime())
+	if err := s.userRepo.SaveRefreshToken(ctx, user.ID, newRefreshToken, expiresAt, client); err != nil {
 		return nil, fmt.Errorf("failed to save new refresh token: %w", err)
 	}
 
@@ -199,40 +251,181 @@ func (s *AuthService) GetUserByID(ctx context.Context, userID string) (*User, er
 	return mapUserToResponse(user, providers), nil
 }
 
+// GetOverview returns the user together with what they have stored, for
+// the app's profile screen.
+func (s *AuthService) GetOverview(ctx context.Context, userID string) (*User, *repository.UserOverview, error) {
+	id, err := uuid.Parse(userID)
+	if err != nil {
+		return nil, nil, fmt.Errorf("invalid user ID: %w", err)
+	}
+
+	user, err := s.GetUserByID(ctx, userID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	overview, err := s.userRepo.GetUserOverview(ctx, id)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to get user overview: %w", err)
+	}
+
+	return user, overview, nil
+}
+
+// SetAIConsent records the user's answers on the AI consent sheet and
+// returns the updated user.
+func (s *AuthService) SetAIConsent(ctx context.Context, userID string, consent AIConsent) (*User, error) {
+	id, err := uuid.Parse(userID)
+	if err != nil {
+		return nil, fmt.Errorf("invalid user ID: %w", err)
+	}
+
+	if err := s.userRepo.SetAIConsent(ctx, id, consent.Queries, consent.Enrichment); err != nil {
+		return nil, fmt.Errorf("failed to set AI consent: %w", err)
+	}
+
+	return s.GetUserByID(ctx, userID)
+}
+
+// SetSettings saves the user's settings and returns the updated user.
+func (s *AuthService) SetSettings(ctx context.Context, userID string, settings UserSettings) (*User, error) {
+	id, err := uuid.Parse(userID)
+	if err != nil {
+		return nil, fmt.Errorf("invalid user ID: %w", err)
+	}
+
+	if err := s.userRepo.SetAutoAssignCollection(ctx, id, settings.AutoAssignCollection); err != nil {
+		return nil, fmt.Errorf("failed to set settings: %w", err)
+	}
+
+	return s.GetUserByID(ctx, userID)
+}
+
 func (s *AuthService) DeleteAccount(ctx context.Context, userID string) error {
 	id, err := uuid.Parse(userID)
 	if err != nil {
 		return fmt.Errorf("invalid user ID: %w", err)
 	}
+	return deleteAccount(ctx, s.userRepo, id, s.mailer)
+}
+
+// deleteAccount revokes the user's tokens and soft-deletes them. An account
+// whose email just changed to an unverified one may have been taken over
+// through it, so deleting it needs proof the user reads that inbox. When
+// mailer can't send that proof (no mail server or email, or an Apple private
+// relay address the server may not mail), the deletion goes ahead and is logged
+// rather than refused forever.
+func deleteAccount(ctx context.Context, users accountStore, id uuid.UUID, mailer Mailer) error {
+	required, err := users.EmailVerificationRequired(ctx, id)
+	if err != nil {
+		return err
+	}
+	if required {
+		user, err := users.GetUserByID(ctx, id)
+		if err != nil {
+			return fmt.Errorf("failed to get user: %w", err)
+		}
+		if email := getEmailString(user.Email); email != "" && mailer != nil && mailer.CanSend(email) {
+			return ErrEmailVerificationRequired
+		}
+		logger.FromContext(ctx).Warn("deleting account with an unverified email the server can't mail a code to",
+			zap.String("user_id", id.String()),
+		)
+	}
 
 	// Revoke all tokens
-	if err := s.userRepo.RevokeAllUserTokens(ctx, id); err != nil {
+	if err := users.RevokeAllUserTokens(ctx, id); err != nil {
 		return fmt.Errorf("failed to revoke tokens: %w", err)
 	}
 
 	// Soft delete user (cascades to auth providers via DB)
-	if err := s.userRepo.DeleteUser(ctx, id); err != nil {
+	if err := users.DeleteUser(ctx, id); err != nil {
 		return fmt.Errorf("failed to delete user: %w", err)
 	}
 
 	return nil
 }
 
+// GetEmailEvents returns the latest transitions of the user's email and its
+// verification, newest first.
+func (s *AuthService) GetEmailEvents(ctx context.Context, userID string) ([]repository.EmailEvent, error) {
+	id, err := uuid.Parse(userID)
+	if err != nil {
+		return nil, fmt.Errorf("invalid user ID: %w", err)
+	}
+
+	return s.userRepo.ListEmailEvents(ctx, id, emailEventsLimit)
+}
+
+// TokenIntrospection describes an access token for internal services, in
+// the shape of an RFC 7662 introspection response. Only Active is set for
+// tokens that are invalid, expired or belong to a deleted user.
+type TokenIntrospection struct {
+	Active    bool     `json:"active"`
+	TokenType string   `json:"token_type,omitempty"`
+	Subject   string   `json:"sub,omitempty"`
+	Email     string   `json:"email,omitempty"`
+	Issuer    string   `json:"iss,omitempty"`
+	Audience  []string `json:"aud,omitempty"`
+	IssuedAt  int64    `json:"iat,omitempty"`
+	ExpiresAt int64    `json:"exp,omitempty"`
+}
+
+// IntrospectAccessToken validates an access token and reports its claims.
+func (s *AuthService) IntrospectAccessToken(ctx context.Context, token string) (*TokenIntrospection, error) {
+	inactive := &TokenIntrospection{Active: false}
+
+	claims, err := s.jwtService.ValidateAccessToken(token)
+	if err != nil {
+		return inactive, nil
+	}
+
+	id, err := uuid.Parse(claims.UserID)
+	if err != nil {
+		return inactive, nil
+	}
+	if _, err := s.userRepo.GetUserByID(ctx, id); err != nil {
+		if errors.Is(err, repository.ErrUserNotFound) {
+			return inactive, nil
+		}
+		return nil, fmt.Errorf("failed to get user: %w", err)
+	}
+
+	result := &TokenIntrospection{
+		Active:    true,
+		TokenType: "access_token",
+		Subject:   claims.UserID,
+		Email:     claims.Email,
+		Issuer:    claims.Issuer,
+		Audience:  claims.Audience,
+	}
+	if claims.IssuedAt != nil {
+		result.IssuedAt = claims.IssuedAt.Unix()
+	}
+	if claims.ExpiresAt != nil {
+		result.ExpiresAt = claims.ExpiresAt.Unix()
+	}
+	return result, nil
+}
+
 // Helper functions
 
 func (s *AuthService) registerNewAppleUser(
 	ctx context.Context,
 	req *AppleAuthRequest,
-	appleUserID, email string,
-	emailVerified bool,
+	claims *AppleTokenClaims,
 ) (*repository.User, error) {
 	// Build display name from Apple's full name if available
 	displayName := buildDisplayName(req.FullName)
 
 	// Use provided email if available, otherwise use email from token
-	userEmail := email
+	tokenEmail := NormalizeEmail(claims.Email, false)
+	userEmail := tokenEmail
 	if req.Email != nil && *req.Email != "" {
-		userEmail = *req.Email
+		userEmail = NormalizeEmail(*req.Email, false)
+	}
+	if err := checkEmailNotPendingDeletion(ctx, s.userRepo, userEmail); err != nil {
+		return nil, err
 	}
 
 	// Create user with auth provider in a transaction
@@ -240,9 +433,10 @@ func (s *AuthService) registerNewAppleUser(
 		ctx,
 		userEmail,
 		displayName,
-		emailVerified,
+		bool(claims.EmailVerified),
+		bool(claims.IsPrivateEmail) && userEmail == tokenEmail,
 		"apple",
-		appleUserID,
+		claims.Sub,
 	)
 	if err != nil {
 		return nil, err
@@ -251,6 +445,64 @@ func (s *AuthService) registerNewAppleUser(
 	return user, nil
 }
 
+// syncAppleEmail updates a returning Apple user's email from their identity
+// token. Only private relay addresses (or a missing email) are replaced: the
+// relay address changes when the user stops and restarts using Sign in with
+// Apple, while a real address may have been set through email sign-in. An
+// address already used by another account, deleted or not, is left alone.
+func (s *AuthService) syncAppleEmail(ctx context.Context, user *repository.User, claims *AppleTokenClaims) error {
+	email := NormalizeEmail(claims.Email, false)
+	current := getEmailString(user.Email)
+	if email == "" || (current != "" && !user.IsPrivateEmail && current != email) {
+		return nil
+	}
+	if current == email && user.IsPrivateEmail == bool(claims.IsPrivateEmail) {
+		return nil
+	}
+	if err := checkEmailNotPendingDeletion(ctx, s.userRepo, email); err != nil {
+		if errors.Is(err, ErrAccountPendingDeletion) {
+			return nil
+		}
+		return err
+	}
+
+	event := repository.EmailEvent{
+		Source:       repository.EmailEventApple,
+		EmailChanged: current != email,
+		WasVerified:  user.EmailVerified,
+		Verified:     bool(claims.EmailVerified),
+	}
+	err := s.userRepo.UpdateUserEmail(ctx, user.ID, email, bool(claims.EmailVerified), bool(claims.IsPrivateEmail), event)
+	if errors.Is(err, repository.ErrEmailTaken) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	user.Email = &email
+	user.EmailVerified = bool(claims.EmailVerified)
+	user.IsPrivateEmail = bool(claims.IsPrivateEmail)
+	return nil
+}
+
+// checkEmailNotPendingDeletion returns ErrAccountPendingDeletion when a
+// deleted account that is not purged yet has the email, so it can't be
+// signed up with again until then.
+func checkEmailNotPendingDeletion(ctx context.Context, userRepo *repository.UserRepository, email string) error {
+	if email == "" {
+		return nil
+	}
+	held, err := userRepo.IsEmailHeldByDeletedUser(ctx, email)
+	if err != nil {
+		return err
+	}
+	if held {
+		return ErrAccountPendingDeletion
+	}
+	return nil
+}
+
 func buildDisplayName(fullName *PersonNameComponents) string {
 	if fullName == nil {
 		return ""
@@ -270,15 +522,22 @@ func buildDisplayName(fullName *PersonNameComponents) string {
 }
 
 func mapUserToResponse(user *repository.User, providers []string) *User {
-	updatedAt := user.UpdatedAt.Format(time.RFC3339)
 	return &User{
-		ID:            user.ID.String(),
-		Email:         user.Email,
-		EmailVerified: user.EmailVerified,
-		DisplayName:   user.DisplayName,
-		AuthProviders: providers,
-		CreatedAt:     user.CreatedAt.Format(time.RFC3339),
-		UpdatedAt:     &updatedAt,
+		ID:             user.ID.String(),
+		Email:          user.Email,
+		EmailVerified:  user.EmailVerified,
+		IsPrivateEmail: user.IsPrivateEmail,
+		DisplayName:    user.DisplayName,
+		AuthProviders:  providers,
+		AIConsent: AIConsent{
+			Queries:    user.AIQueryConsentAt != nil,
+			Enrichment: user.AIEnrichmentConsentAt != nil,
+		},
+		Settings: UserSettings{
+			AutoAssignCollection: user.AutoAssignCollection,
+		},
+		CreatedAt: user.CreatedAt,
+		UpdatedAt: user.UpdatedAt,
 	}
 }
 
@@ -0,0 +1,67 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/google/uuid"
+
+	"github.com/avalarin/livlog/backend/internal/repository"
+)
+
+// fakeAccounts is an account whose email may need verifying again, and
+// records whether it was deleted.
+type fakeAccounts struct {
+	email                string
+	verificationRequired bool
+	revoked, deleted     bool
+}
+
+func (f *fakeAccounts) GetUserByID(_ context.Context, id uuid.UUID) (*repository.User, error) {
+	return &repository.User{ID: id, Email: &f.email}, nil
+}
+
+func (f *fakeAccounts) EmailVerificationRequired(_ context.Context, _ uuid.UUID) (bool, error) {
+	return f.verificationRequired, nil
+}
+
+func (f *fakeAccounts) RevokeAllUserTokens(_ context.Context, _ uuid.UUID) error {
+	f.revoked = true
+	return nil
+}
+
+func (f *fakeAccounts) DeleteUser(_ context.Context, _ uuid.UUID) error {
+	f.deleted = true
+	return nil
+}
+
+func TestDeleteAccount(t *testing.T) {
+	tests := []struct {
+		name                 string
+		email                string
+		verificationRequired bool
+		mailer               Mailer
+		wantErr              error
+	}{
+		{"verified", "user@example.com", false, &fakeMailer{}, nil},
+		{"unverified", "user@example.com", true, &fakeMailer{}, ErrEmailVerificationRequired},
+		// Without a way to mail a code the email can never be verified again,
+		// so the gate would lock the account out of deletion for good
+		{"unverified without mailer", "user@example.com", true, nil, nil},
+		{"unverified relay address", "abc123@privaterelay.appleid.com", true, &fakeMailer{}, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			accounts := &fakeAccounts{email: tt.email, verificationRequired: tt.verificationRequired}
+
+			err := deleteAccount(context.Background(), accounts, uuid.New(), tt.mailer)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("deleteAccount() error = %v, want %v", err, tt.wantErr)
+			}
+			if wantDeleted := tt.wantErr == nil; accounts.revoked != wantDeleted || accounts.deleted != wantDeleted {
+				t.Errorf("revoked = %v, deleted = %v, want both %v", accounts.revoked, accounts.deleted, wantDeleted)
+			}
+		})
+	}
+}
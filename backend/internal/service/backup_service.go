@@ -0,0 +1,219 @@
+package service
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"github.com/avalarin/livlog/backend/internal/backup"
+	"github.com/avalarin/livlog/backend/internal/config"
+	"github.com/avalarin/livlog/backend/internal/repository"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+)
+
+const (
+	// backupRetryDelay is how long a failed scheduled backup waits before the
+	// next attempt.
+	backupRetryDelay = time.Hour
+	// backupListLimit caps GET /admin/backups.
+	backupListLimit = 100
+)
+
+// BackupService takes database backups with pg_dump and keeps them in the
+// configured backup.Store. Backups are queued on schedule or by an admin and
+// taken by RunDue; ones past the retention period are deleted afterwards.
+type BackupService struct {
+	backupRepo *repository.BackupRepository
+	store      backup.Store
+	database   config.DatabaseConfig
+	cfg        config.BackupConfig
+	logger     *zap.Logger
+}
+
+func NewBackupService(
+	backupRepo *repository.BackupRepository,
+	store backup.Store,
+	database config.DatabaseConfig,
+	cfg config.BackupConfig,
+	logger *zap.Logger,
+) *BackupService {
+	return &BackupService{
+		backupRepo: backupRepo,
+		store:      store,
+		database:   database,
+		cfg:        cfg,
+		logger:     logger,
+	}
+}
+
+// BackupStatus is a backup with the result of checking its archive in the
+// store. Verified is nil for backups that aren't done; VerifyError says why
+// a done backup failed the check.
+type BackupStatus struct {
+	*repository.Backup
+	Verified    *bool
+	VerifyError string
+}
+
+// RequestBackup queues a backup requested by an admin.
+func (s *BackupService) RequestBackup(ctx context.Context, adminID uuid.UUID) (*repository.Backup, error) {
+	return s.backupRepo.CreateBackup(ctx, &adminID)
+}
+
+// ListBackups returns the latest backups, checking that each done backup's
+// archive is in the store with the recorded size.
+func (s *BackupService) ListBackups(ctx context.Context) ([]BackupStatus, error) {
+	backups, err := s.backupRepo.ListBackups(ctx, backupListLimit)
+	if err != nil {
+		return nil, err
+	}
+
+	statuses := make([]BackupStatus, len(backups))
+	for i, b := range backups {
+		statuses[i] = BackupStatus{Backup: b}
+		if b.Status != repository.BackupStatusDone || b.Key == nil {
+			continue
+		}
+
+		verified := false
+		size, err := s.store.Stat(ctx, *b.Key)
+		switch {
+		case errors.Is(err, backup.ErrNotFound):
+			statuses[i].VerifyError = "archive is missing"
+		case err != nil:
+			statuses[i].VerifyError = err.Error()
+		case size != b.SizeBytes:
+			statuses[i].VerifyError = fmt.Sprintf("archive is %d bytes, expected %d", size, b.SizeBytes)
+		default:
+			verified = true
+		}
+		statuses[i].Verified = &verified
+	}
+
+	return statuses, nil
+}
+
+// RunDue queues a scheduled backup when one is due, takes queued backups one
+// at a time, then deletes backups past the retention period.
+func (s *BackupService) RunDue(ctx context.Context) error {
+	if s.cfg.Interval > 0 {
+		queued, err := s.backupRepo.CreateBackupIfDue(ctx, s.cfg.Interval, backupRetryDelay)
+		if err != nil {
+			return err
+		}
+		if queued {
+			s.logger.Info("scheduled database backup queued")
+		}
+	}
+
+	for ctx.Err() == nil {
+		b, err := s.backupRepo.ClaimBackup(ctx, s.cfg.Timeout)
+		if err != nil {
+			return err
+		}
+		if b == nil {
+			break
+		}
+		s.process(ctx, b)
+	}
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+
+	if s.cfg.Retention > 0 {
+		return s.deleteExpired(ctx)
+	}
+	return nil
+}
+
+func (s *BackupService) process(ctx context.Context, b *repository.Backup) {
+	fields := []zap.Field{zap.String("backup_id", b.ID.String())}
+	if b.RequestedBy != nil {
+		fields = append(fields, zap.String("requested_by", b.RequestedBy.String()))
+	}
+
+	key, size, sum, err := s.take(ctx, b)
+	if err != nil {
+		s.logger.Error("database backup failed", append(fields, zap.Error(err))...)
+		if err := s.backupRepo.FailBackup(ctx, b.ID, err.Error()); err != nil {
+			s.logger.Error("failed to record backup failure", append(fields, zap.Error(err))...)
+		}
+		return
+	}
+
+	if err := s.backupRepo.CompleteBackup(ctx, b.ID, key, size, sum); err != nil {
+		s.logger.Error("failed to save backup", append(fields, zap.Error(err))...)
+		return
+	}
+
+	s.logger.Info("database backup finished", append(fields,
+		zap.String("key", key),
+		zap.Int64("bytes", size),
+	)...)
+}
+
+// take dumps the database to a temporary file, then uploads it to the store.
+// It returns the archive's key, size and hex SHA-256.
+func (s *BackupService) take(ctx context.Context, b *repository.Backup) (string, int64, string, error) {
+	ctx, cancel := context.WithTimeout(ctx, s.cfg.Timeout)
+	defer cancel()
+
+	tmp, err := os.CreateTemp("", "livlog-backup-*.dump")
+	if err != nil {
+		return "", 0, "", fmt.Errorf("failed to create temporary file: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+	defer tmp.Close()
+
+	hash := sha256.New()
+	if err := backup.Dump(ctx, s.cfg.PGDumpPath, s.database, io.MultiWriter(tmp, hash)); err != nil {
+		return "", 0, "", err
+	}
+
+	size, err := tmp.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return "", 0, "", fmt.Errorf("failed to read archive size: %w", err)
+	}
+	if _, err := tmp.Seek(0, io.SeekStart); err != nil {
+		return "", 0, "", fmt.Errorf("failed to rewind archive: %w", err)
+	}
+
+	key := fmt.Sprintf("livlog-%s-%s.dump", b.CreatedAt.UTC().Format("20060102T150405Z"), b.ID.String()[:8])
+	sum := hex.EncodeToString(hash.Sum(nil))
+	if err := s.store.Put(ctx, key, tmp, size, sum); err != nil {
+		return "", 0, "", err
+	}
+
+	return key, size, sum, nil
+}
+
+// deleteExpired removes backups older than the retention period from the
+// store and the database, keeping the latest successful one.
+func (s *BackupService) deleteExpired(ctx context.Context) error {
+	expired, err := s.backupRepo.ListExpiredBackups(ctx, s.cfg.Retention)
+	if err != nil {
+		return err
+	}
+
+	for _, b := range expired {
+		if b.Key != nil {
+			if err := s.store.Delete(ctx, *b.Key); err != nil {
+				return fmt.Errorf("failed to delete backup %s: %w", b.ID, err)
+			}
+		}
+		if err := s.backupRepo.DeleteBackup(ctx, b.ID); err != nil {
+			return err
+		}
+	}
+
+	if len(expired) > 0 {
+		s.logger.Info("deleted expired backups", zap.Int("deleted", len(expired)))
+	}
+	return nil
+}
@@ -0,0 +1,350 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/avalarin/livlog/backend/internal/config"
+	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
+	"go.uber.org/zap"
+)
+
+var (
+	ErrInvalidISBN           = errors.New("isbn must be a valid ISBN-10 or ISBN-13")
+	ErrBookNotFound          = errors.New("book not found")
+	ErrBookLookupUnavailable = errors.New("book lookup is unavailable")
+)
+
+var yearPattern = regexp.MustCompile(`\b\d{4}\b`)
+
+// openLibraryCoversURL serves the covers that search results refer to by id.
+const openLibraryCoversURL = "https://covers.openlibrary.org/b/id/"
+
+// Book is the metadata found for an ISBN.
+type Book struct {
+	ISBN          string // ISBN-13
+	Title         string
+	Subtitle      string
+	OriginalTitle string // the title it was translated from, if any
+	Language      string // of the work as written, e.g. "fr" for a translation from French
+	Authors       []string
+	Publisher     string
+	Year          string
+	Pages         int
+	CoverURL      string
+}
+
+// BooksService resolves ISBNs to book metadata through an Open Library
+// compatible API (see config.BooksConfig).
+type BooksService struct {
+	baseURL    string
+	httpClient *http.Client
+	logger     *zap.Logger
+}
+
+func NewBooksService(cfg config.BooksConfig, logger *zap.Logger) *BooksService {
+	return &BooksService{
+		baseURL: strings.TrimRight(cfg.BaseURL, "/"),
+		httpClient: &http.Client{
+			Timeout:   cfg.Timeout,
+			Transport: otelhttp.NewTransport(http.DefaultTransport),
+		},
+		logger: logger,
+	}
+}
+
+// Open Library /api/books?jscmd=data response, keyed by bibkey
+type openLibraryBook struct {
+	Title    string `json:"title"`
+	Subtitle string `json:"subtitle"`
+	Authors  []struct {
+		Name string `json:"name"`
+	} `json:"authors"`
+	Publishers []struct {
+		Name string `json:"name"`
+	} `json:"publishers"`
+	PublishDate   string `json:"publish_date"`
+	NumberOfPages int    `json:"number_of_pages"`
+	Cover         struct {
+		Small  string `json:"small"`
+		Medium string `json:"medium"`
+		Large  string `json:"large"`
+	} `json:"cover"`
+}
+
+// Open Library edition record (/isbn/{isbn}.json), read for the fields the
+// data response leaves out
+type openLibraryEdition struct {
+	Languages []struct {
+		Key string `json:"key"` // e.g. "/languages/fre"
+	} `json:"languages"`
+	TranslatedFrom []struct {
+		Key string `json:"key"`
+	} `json:"translated_from"`
+	TranslationOf string `json:"translation_of"`
+}
+
+// marcLanguages maps the MARC language codes Open Library uses to BCP 47.
+// Codes not listed are kept as they are.
+var marcLanguages = map[string]string{
+	"ara": "ar", "chi": "zh", "cze": "cs", "dan": "da", "dut": "nl",
+	"eng": "en", "fin": "fi", "fre": "fr", "ger": "de", "gre": "el",
+	"heb": "he", "hin": "hi", "hun": "hu", "ita": "it", "jpn": "ja",
+	"kor": "ko", "nor": "no", "per": "fa", "pol": "pl", "por": "pt",
+	"rum": "ro", "rus": "ru", "spa": "es", "swe": "sv", "tur": "tr",
+	"ukr": "uk",
+}
+
+// LookupISBN returns the book with the given ISBN-10 or ISBN-13, as printed
+// or scanned from an EAN-13 barcode. Hyphens and spaces are ignored.
+func (s *BooksService) LookupISBN(ctx context.Context, isbn string) (*Book, error) {
+	isbn13, err := NormalizeISBN(isbn)
+	if err != nil {
+		return nil, err
+	}
+
+	if s.baseURL == "" {
+		return nil, ErrBookLookupUnavailable
+	}
+
+	bibkey := "ISBN:" + isbn13
+	query := url.Values{
+		"bibkeys": {bibkey},
+		"format":  {"json"},
+		"jscmd":   {"data"},
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, s.baseURL+"/api/books?"+query.Encode(), nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := s.httpClient.Do(req)
+	if err != nil {
+		s.logger.Warn("book lookup failed", zap.String("isbn", isbn13), zap.Error(err))
+		return nil, fmt.Errorf("%w: %v", ErrBookLookupUnavailable, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		s.logger.Warn("book lookup failed", zap.String("isbn", isbn13), zap.Int("status", resp.StatusCode))
+		return nil, fmt.Errorf("%w: status %d", ErrBookLookupUnavailable, resp.StatusCode)
+	}
+
+	var books map[string]openLibraryBook
+	if err := json.NewDecoder(io.LimitReader(resp.Body, 1<<20)).Decode(&books); err != nil {
+		return nil, fmt.Errorf("%w: invalid response: %v", ErrBookLookupUnavailable, err)
+	}
+
+	found, ok := books[bibkey]
+	if !ok || found.Title == "" {
+		return nil, ErrBookNotFound
+	}
+
+	book := &Book{
+		ISBN:     isbn13,
+		Title:    found.Title,
+		Subtitle: found.Subtitle,
+		Authors:  []string{},
+		Year:     yearPattern.FindString(found.PublishDate),
+		Pages:    found.NumberOfPages,
+	}
+	for _, a := range found.Authors {
+		book.Authors = append(book.Authors, a.Name)
+	}
+	if len(found.Publishers) > 0 {
+		book.Publisher = found.Publishers[0].Name
+	}
+	for _, cover := range []string{found.Cover.Large, found.Cover.Medium, found.Cover.Small} {
+		if cover != "" {
+			book.CoverURL = cover
+			break
+		}
+	}
+
+	s.addEditionDetails(ctx, book)
+
+	return book, nil
+}
+
+// Open Library /search.json response
+type openLibrarySearch struct {
+	Docs []struct {
+		Title            string   `json:"title"`
+		AuthorName       []string `json:"author_name"`
+		FirstPublishYear int      `json:"first_publish_year"`
+		CoverID          int      `json:"cover_i"`
+	} `json:"docs"`
+}
+
+// SearchBooks returns up to limit books matching a free-text query, best
+// match first. Results are works rather than editions, so they carry no ISBN.
+func (s *BooksService) SearchBooks(ctx context.Context, query string, limit int) ([]*Book, error) {
+	if s.baseURL == "" {
+		return nil, ErrBookLookupUnavailable
+	}
+
+	params := url.Values{
+		"q":      {query},
+		"limit":  {strconv.Itoa(limit)},
+		"fields": {"title,author_name,first_publish_year,cover_i"},
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, s.baseURL+"/search.json?"+params.Encode(), nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := s.httpClient.Do(req)
+	if err != nil {
+		s.logger.Warn("book search failed", zap.Error(err))
+		return nil, fmt.Errorf("%w: %v", ErrBookLookupUnavailable, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		s.logger.Warn("book search failed", zap.Int("status", resp.StatusCode))
+		return nil, fmt.Errorf("%w: status %d", ErrBookLookupUnavailable, resp.StatusCode)
+	}
+
+	var found openLibrarySearch
+	if err := json.NewDecoder(io.LimitReader(resp.Body, 1<<20)).Decode(&found); err != nil {
+		return nil, fmt.Errorf("%w: invalid response: %v", ErrBookLookupUnavailable, err)
+	}
+
+	books := make([]*Book, 0, len(found.Docs))
+	for _, doc := range found.Docs {
+		if doc.Title == "" {
+			continue
+		}
+		book := &Book{Title: doc.Title, Authors: doc.AuthorName}
+		if book.Authors == nil {
+			book.Authors = []string{}
+		}
+		if doc.FirstPublishYear > 0 {
+			book.Year = strconv.Itoa(doc.FirstPublishYear)
+		}
+		if doc.CoverID > 0 {
+			book.CoverURL = openLibraryCoversURL + strconv.Itoa(doc.CoverID) + "-L.jpg"
+		}
+		books = append(books, book)
+	}
+
+	return books, nil
+}
+
+// addEditionDetails fills in the original title and language from the
+// edition record. They are extras: a failed request leaves them empty.
+func (s *BooksService) addEditionDetails(ctx context.Context, book *Book) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, s.baseURL+"/isbn/"+book.ISBN+".json", nil)
+	if err != nil {
+		return
+	}
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := s.httpClient.Do(req)
+	if err != nil {
+		s.logger.Info("book edition lookup failed", zap.String("isbn", book.ISBN), zap.Error(err))
+		return
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return
+	}
+
+	var edition openLibraryEdition
+	if err := json.NewDecoder(io.LimitReader(resp.Body, 1<<20)).Decode(&edition); err != nil {
+		return
+	}
+
+	book.OriginalTitle = strings.TrimSpace(edition.TranslationOf)
+	languages := edition.TranslatedFrom
+	if len(languages) == 0 {
+		languages = edition.Languages
+	}
+	if len(languages) > 0 {
+		book.Language = marcLanguage(languages[0].Key)
+	}
+}
+
+// marcLanguage converts an Open Library language key to a BCP 47 tag, or ""
+// if it isn't one.
+func marcLanguage(key string) string {
+	code := strings.TrimPrefix(key, "/languages/")
+	if tag, ok := marcLanguages[code]; ok {
+		return tag
+	}
+	tag, err := NormalizeLanguage(code)
+	if err != nil {
+		return ""
+	}
+	return tag
+}
+
+// NormalizeISBN validates an ISBN-10 or ISBN-13 and returns it as ISBN-13.
+func NormalizeISBN(isbn string) (string, error) {
+	isbn = strings.ToUpper(strings.NewReplacer("-", "", " ", "").Replace(isbn))
+
+	switch len(isbn) {
+	case 10:
+		sum := 0
+		for i, c := range isbn {
+			var d int
+			switch {
+			case c >= '0' && c <= '9':
+				d = int(c - '0')
+			case c == 'X' && i == 9:
+				d = 10
+			default:
+				return "", ErrInvalidISBN
+			}
+			sum += d * (10 - i)
+		}
+		if sum%11 != 0 {
+			return "", ErrInvalidISBN
+		}
+		isbn13 := "978" + isbn[:9]
+		return isbn13 + string(rune('0'+isbn13CheckDigit(isbn13))), nil
+
+	case 13:
+		for _, c := range isbn {
+			if c < '0' || c > '9' {
+				return "", ErrInvalidISBN
+			}
+		}
+		if !strings.HasPrefix(isbn, "978") && !strings.HasPrefix(isbn, "979") {
+			return "", ErrInvalidISBN // an EAN-13 that is not a book
+		}
+		if int(isbn[12]-'0') != isbn13CheckDigit(isbn[:12]) {
+			return "", ErrInvalidISBN
+		}
+		return isbn, nil
+
+	default:
+		return "", ErrInvalidISBN
+	}
+}
+
+// isbn13CheckDigit computes the check digit for the first 12 digits.
+func isbn13CheckDigit(digits string) int {
+	sum := 0
+	for i := 0; i < 12; i++ {
+		d := int(digits[i] - '0')
+		if i%2 == 1 {
+			d *= 3
+		}
+		sum += d
+	}
+	return (10 - sum%10) % 10
+}
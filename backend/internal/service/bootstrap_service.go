@@ -0,0 +1,99 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"sync"
+
+	"github.com/avalarin/livlog/backend/internal/config"
+	"github.com/avalarin/livlog/backend/internal/repository"
+	"github.com/google/uuid"
+)
+
+// Bootstrap is everything the app loads when it starts: the user with their
+// counts, profile settings, collections and types, and the quotas the counts
+// are held against.
+type Bootstrap struct {
+	User        *User
+	Overview    *repository.UserOverview
+	Profile     *repository.Profile // nil until the user sets up a profile
+	Collections []*repository.Collection
+	Types       []*repository.EntryType
+	Quotas      config.QuotasConfig
+}
+
+// BootstrapService assembles a Bootstrap from the services behind the
+// individual endpoints, so it returns the same data they do.
+type BootstrapService struct {
+	authService       *AuthService
+	collectionService *CollectionService
+	typeService       *TypeService
+	profileService    *ProfileService
+	quotas            config.QuotasConfig
+}
+
+func NewBootstrapService(
+	authService *AuthService,
+	collectionService *CollectionService,
+	typeService *TypeService,
+	profileService *ProfileService,
+	quotas config.QuotasConfig,
+) *BootstrapService {
+	return &BootstrapService{
+		authService:       authService,
+		collectionService: collectionService,
+		typeService:       typeService,
+		profileService:    profileService,
+		quotas:            quotas,
+	}
+}
+
+// Load runs the parts of the bootstrap concurrently and fails if any does.
+func (s *BootstrapService) Load(ctx context.Context, userID uuid.UUID) (*Bootstrap, error) {
+	b := &Bootstrap{Quotas: s.quotas}
+
+	var wg sync.WaitGroup
+	errs := make([]error, 4)
+	run := func(i int, fn func() error) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			errs[i] = fn()
+		}()
+	}
+
+	run(0, func() (err error) {
+		b.User, b.Overview, err = s.authService.GetOverview(ctx, userID.String())
+		return err
+	})
+	run(1, func() error {
+		page, err := s.collectionService.ListCollections(ctx, userID, "", 0)
+		if err != nil {
+			return err
+		}
+		b.Collections = page.Items
+		return nil
+	})
+	run(2, func() error {
+		page, err := s.typeService.ListTypes(ctx, userID, "", 0)
+		if err != nil {
+			return err
+		}
+		b.Types = page.Items
+		return nil
+	})
+	run(3, func() error {
+		profile, err := s.profileService.GetProfile(ctx, userID)
+		if errors.Is(err, repository.ErrProfileNotFound) {
+			return nil
+		}
+		b.Profile = profile
+		return err
+	})
+	wg.Wait()
+
+	if err := errors.Join(errs...); err != nil {
+		return nil, err
+	}
+	return b, nil
+}
@@ -0,0 +1,120 @@
+package service
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/avalarin/livlog/backend/internal/repository"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+)
+
+const (
+	// changeFeedRetryDelay is how long to wait before re-listening after the
+	// notification connection fails.
+	changeFeedRetryDelay = 5 * time.Second
+
+	// subscriberBuffer bounds the events queued for a slow subscriber. Events
+	// beyond it are dropped; they are hints, and clients pull /sync/changes.
+	subscriberBuffer = 32
+)
+
+// ChangeFeed fans database change notifications out to per-user subscribers,
+// e.g. open /sync/stream connections.
+type ChangeFeed struct {
+	syncRepo *repository.SyncRepository
+	logger   *zap.Logger
+
+	mu          sync.Mutex
+	subscribers map[uuid.UUID]map[chan repository.ChangeEvent]struct{}
+	closed      bool
+}
+
+func NewChangeFeed(syncRepo *repository.SyncRepository, logger *zap.Logger) *ChangeFeed {
+	return &ChangeFeed{
+		syncRepo:    syncRepo,
+		logger:      logger,
+		subscribers: make(map[uuid.UUID]map[chan repository.ChangeEvent]struct{}),
+	}
+}
+
+// Run listens for changes until ctx is cancelled, reconnecting on failure.
+// Events written while reconnecting are missed; clients catch up by pulling.
+func (f *ChangeFeed) Run(ctx context.Context) {
+	for {
+		err := f.syncRepo.ListenChanges(ctx, f.publish)
+		if ctx.Err() != nil {
+			return
+		}
+		f.logger.Warn("change feed disconnected, retrying",
+			zap.Error(err),
+			zap.Duration("retry_in", changeFeedRetryDelay),
+		)
+
+		select {
+		case <-time.After(changeFeedRetryDelay):
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// Subscribe returns a channel of the user's change events and a function that
+// ends the subscription. The channel is closed when the feed is closed.
+func (f *ChangeFeed) Subscribe(userID uuid.UUID) (<-chan repository.ChangeEvent, func()) {
+	ch := make(chan repository.ChangeEvent, subscriberBuffer)
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.closed {
+		close(ch)
+		return ch, func() {}
+	}
+
+	if f.subscribers[userID] == nil {
+		f.subscribers[userID] = make(map[chan repository.ChangeEvent]struct{})
+	}
+	f.subscribers[userID][ch] = struct{}{}
+
+	return ch, func() {
+		f.mu.Lock()
+		defer f.mu.Unlock()
+
+		if _, ok := f.subscribers[userID][ch]; !ok {
+			return
+		}
+		delete(f.subscribers[userID], ch)
+		if len(f.subscribers[userID]) == 0 {
+			delete(f.subscribers, userID)
+		}
+		close(ch)
+	}
+}
+
+// Close ends all subscriptions, so open streams finish before shutdown.
+func (f *ChangeFeed) Close() {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.closed = true
+	for userID, subs := range f.subscribers {
+		for ch := range subs {
+			close(ch)
+		}
+		delete(f.subscribers, userID)
+	}
+}
+
+func (f *ChangeFeed) publish(event repository.ChangeEvent) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	for ch := range f.subscribers[event.UserID] {
+		select {
+		case ch <- event:
+		default:
+		}
+	}
+}
@@ -0,0 +1,335 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+
+	"github.com/avalarin/livlog/backend/internal/config"
+	"github.com/avalarin/livlog/backend/internal/repository"
+	"github.com/google/uuid"
+	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
+	"go.uber.org/zap"
+)
+
+var (
+	ErrInvalidChannelKind    = errors.New("unknown notification channel kind")
+	ErrInvalidChannelTarget  = errors.New("invalid notification channel target")
+	ErrChannelUnavailable    = errors.New("notification channel is not configured on this server")
+	ErrChannelLimitReached   = errors.New("notification channel limit reached")
+	ErrChannelDeliveryFailed = errors.New("notification channel delivery failed")
+)
+
+// Notification channel kinds.
+const (
+	ChannelTelegram = "telegram"
+	ChannelDiscord  = "discord"
+)
+
+const maxChannelsPerUser = 5
+
+// ChannelMessage is a notification rendered for an external service.
+type ChannelMessage struct {
+	Title string
+	Body  string
+}
+
+// Channel sends messages to one external service. The target names the
+// recipient there, e.g. a Telegram chat id or a Discord webhook URL.
+type Channel interface {
+	// NormalizeTarget returns the target in canonical form, or
+	// ErrInvalidChannelTarget.
+	NormalizeTarget(target string) (string, error)
+	Send(ctx context.Context, target string, msg ChannelMessage) error
+}
+
+// ChannelService manages the channels users connect and delivers their
+// notifications through them. Delivery is best effort: a failing channel
+// records its error and never fails the notification itself.
+type ChannelService struct {
+	channelRepo *repository.NotificationChannelRepository
+	channels    map[string]Channel
+	logger      *zap.Logger
+}
+
+// NewChannelService sets up the channel kinds the server supports: Discord
+// always, Telegram once a bot token is configured.
+func NewChannelService(
+	channelRepo *repository.NotificationChannelRepository,
+	cfg config.ChannelsConfig,
+	logger *zap.Logger,
+) *ChannelService {
+	httpClient := &http.Client{
+		Timeout:   cfg.Timeout,
+		Transport: otelhttp.NewTransport(http.DefaultTransport),
+		// A redirect could forward the message somewhere the user never chose
+		CheckRedirect: func(*http.Request, []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+
+	channels := map[string]Channel{
+		ChannelDiscord: &DiscordChannel{httpClient: httpClient},
+	}
+	if cfg.TelegramBotToken != "" {
+		channels[ChannelTelegram] = &TelegramChannel{
+			baseURL:    strings.TrimRight(cfg.TelegramBaseURL, "/"),
+			botToken:   cfg.TelegramBotToken,
+			httpClient: httpClient,
+		}
+	}
+
+	return &ChannelService{
+		channelRepo: channelRepo,
+		channels:    channels,
+		logger:      logger,
+	}
+}
+
+// CreateChannel connects a channel for the user after checking its target.
+func (s *ChannelService) CreateChannel(
+	ctx context.Context,
+	userID uuid.UUID,
+	kind, target string,
+) (*repository.NotificationChannel, error) {
+	if kind != ChannelTelegram && kind != ChannelDiscord {
+		return nil, ErrInvalidChannelKind
+	}
+	channel, ok := s.channels[kind]
+	if !ok {
+		return nil, ErrChannelUnavailable
+	}
+
+	target, err := channel.NormalizeTarget(target)
+	if err != nil {
+		return nil, err
+	}
+
+	count, err := s.channelRepo.CountChannels(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if count >= maxChannelsPerUser {
+		return nil, ErrChannelLimitReached
+	}
+
+	return s.channelRepo.CreateChannel(ctx, userID, kind, target)
+}
+
+// GetChannels retrieves all channels of a user
+func (s *ChannelService) GetChannels(ctx context.Context, userID uuid.UUID) ([]*repository.NotificationChannel, error) {
+	return s.channelRepo.GetChannelsByUserID(ctx, userID)
+}
+
+// DeleteChannel disconnects a channel
+func (s *ChannelService) DeleteChannel(ctx context.Context, id, userID uuid.UUID) error {
+	return s.channelRepo.DeleteChannel(ctx, id, userID)
+}
+
+// TestChannel sends a test message through a channel and returns it with the
+// outcome recorded. A failed send is reported as ErrChannelDeliveryFailed.
+func (s *ChannelService) TestChannel(ctx context.Context, id, userID uuid.UUID) (*repository.NotificationChannel, error) {
+	c, err := s.channelRepo.GetChannelByID(ctx, id, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	msg := ChannelMessage{Title: "Livlog", Body: "Notifications will arrive here."}
+	sendErr := s.send(ctx, c, msg)
+
+	c, err = s.channelRepo.GetChannelByID(ctx, id, userID)
+	if err != nil {
+		return nil, err
+	}
+	if sendErr != nil {
+		return c, fmt.Errorf("%w: %v", ErrChannelDeliveryFailed, sendErr)
+	}
+	return c, nil
+}
+
+// Deliver sends msg through every channel the user has connected.
+func (s *ChannelService) Deliver(ctx context.Context, userID uuid.UUID, msg ChannelMessage) {
+	channels, err := s.channelRepo.GetChannelsByUserID(ctx, userID)
+	if err != nil {
+		s.logger.Error("failed to get notification channels", zap.String("user_id", userID.String()), zap.Error(err))
+		return
+	}
+
+	for _, c := range channels {
+		if err := s.send(ctx, c, msg); err != nil {
+			s.logger.Info("notification channel delivery failed",
+				zap.String("channel_id", c.ID.String()),
+				zap.String("kind", c.Kind),
+				zap.Error(err),
+			)
+		}
+	}
+}
+
+// send delivers through one channel and records the outcome on it. Channels
+// whose kind is no longer configured fail without a request.
+func (s *ChannelService) send(ctx context.Context, c *repository.NotificationChannel, msg ChannelMessage) error {
+	sendErr := ErrChannelUnavailable
+	if channel, ok := s.channels[c.Kind]; ok {
+		sendErr = channel.Send(ctx, c.Target, msg)
+	}
+
+	var lastError *string
+	if sendErr != nil {
+		text := sendErr.Error()
+		lastError = &text
+	}
+	if err := s.channelRepo.RecordDelivery(ctx, c.ID, lastError); err != nil {
+		s.logger.Error("failed to record notification channel delivery", zap.String("channel_id", c.ID.String()), zap.Error(err))
+	}
+
+	return sendErr
+}
+
+// MaskChannelTarget hides the secret part of a target for display: Discord
+// webhook URLs keep only their host, Telegram chat ids are shown as is.
+func MaskChannelTarget(kind, target string) string {
+	if kind == ChannelDiscord {
+		if u, err := url.Parse(target); err == nil {
+			return u.Scheme + "://" + u.Host + "/api/webhooks/…"
+		}
+		return "…"
+	}
+	return target
+}
+
+// telegramChatPattern matches a numeric chat id (negative for groups) or a
+// public channel username.
+var telegramChatPattern = regexp.MustCompile(`^(-?[0-9]{1,20}|@[A-Za-z0-9_]{5,32})$`)
+
+// TelegramChannel sends messages through the server's Telegram bot. Users
+// start a chat with the bot (or add it to a group) and register the chat id.
+type TelegramChannel struct {
+	baseURL    string
+	botToken   string
+	httpClient *http.Client
+}
+
+// NormalizeTarget implements Channel.
+func (c *TelegramChannel) NormalizeTarget(target string) (string, error) {
+	target = strings.TrimSpace(target)
+	if !telegramChatPattern.MatchString(target) {
+		return "", ErrInvalidChannelTarget
+	}
+	return target, nil
+}
+
+// Send implements Channel with the Bot API sendMessage method.
+func (c *TelegramChannel) Send(ctx context.Context, target string, msg ChannelMessage) error {
+	payload, err := json.Marshal(map[string]interface{}{
+		"chat_id":                  target,
+		"text":                     truncateMessage(channelText(msg), 4096),
+		"disable_web_page_preview": true,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to encode message: %w", err)
+	}
+
+	// The token is part of the path; keep it out of returned errors
+	err = postChannelJSON(ctx, c.httpClient, c.baseURL+"/bot"+c.botToken+"/sendMessage", payload)
+	if err != nil {
+		return errors.New(strings.ReplaceAll(err.Error(), c.botToken, "***"))
+	}
+	return nil
+}
+
+// discordWebhookHosts are the hosts Discord serves incoming webhooks from.
+var discordWebhookHosts = map[string]bool{
+	"discord.com":        true,
+	"discordapp.com":     true,
+	"ptb.discord.com":    true,
+	"canary.discord.com": true,
+}
+
+// DiscordChannel posts messages to a Discord incoming webhook the user
+// created for one of their channels.
+type DiscordChannel struct {
+	httpClient *http.Client
+}
+
+// NormalizeTarget implements Channel. Only Discord webhook URLs are accepted,
+// so a channel can't be used to make the server call arbitrary URLs.
+func (c *DiscordChannel) NormalizeTarget(target string) (string, error) {
+	u, err := url.Parse(strings.TrimSpace(target))
+	if err != nil || u.Scheme != "https" || !discordWebhookHosts[strings.ToLower(u.Host)] ||
+		!strings.HasPrefix(u.Path, "/api/webhooks/") {
+		return "", ErrInvalidChannelTarget
+	}
+	u.Host = strings.ToLower(u.Host)
+	u.RawQuery = ""
+	u.Fragment = ""
+	return u.String(), nil
+}
+
+// Send implements Channel.
+func (c *DiscordChannel) Send(ctx context.Context, target string, msg ChannelMessage) error {
+	payload, err := json.Marshal(map[string]interface{}{
+		"username": "Livlog",
+		"content":  truncateMessage(channelText(msg), 2000),
+		// Never ping @everyone or roles from user-written titles
+		"allowed_mentions": map[string]interface{}{"parse": []string{}},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to encode message: %w", err)
+	}
+
+	// The webhook URL is a secret; keep it out of returned errors
+	err = postChannelJSON(ctx, c.httpClient, target, payload)
+	if err != nil {
+		return errors.New(strings.ReplaceAll(err.Error(), target, "discord webhook"))
+	}
+	return nil
+}
+
+// postChannelJSON posts payload and fails on a non-2xx response, with the
+// start of the response body in the error.
+func postChannelJSON(ctx context.Context, client *http.Client, endpoint string, payload []byte) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, bytes.NewReader(payload))
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "Livlog-Notifications/1.0")
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 200))
+		return fmt.Errorf("unexpected status %d: %s", resp.StatusCode, strings.TrimSpace(string(body)))
+	}
+
+	_, _ = io.Copy(io.Discard, resp.Body)
+	return nil
+}
+
+func channelText(msg ChannelMessage) string {
+	if msg.Body == "" {
+		return msg.Title
+	}
+	return msg.Title + "\n\n" + msg.Body
+}
+
+// truncateMessage cuts text to at most max runes, the services' message limit.
+func truncateMessage(text string, max int) string {
+	runes := []rune(text)
+	if len(runes) <= max {
+		return text
+	}
+	return string(runes[:max-1]) + "…"
+}
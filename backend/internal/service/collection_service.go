@@ -4,8 +4,11 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"net/http"
+	"regexp"
 	"strings"
 
+	"github.com/avalarin/livlog/backend/internal/config"
 	"github.com/avalarin/livlog/backend/internal/repository"
 	"github.com/google/uuid"
 )
@@ -13,24 +16,79 @@ import (
 var (
 	ErrInvalidCollectionName = errors.New("collection name must be between 1 and 50 characters")
 	ErrInvalidIcon           = errors.New("icon must be between 1 and 20 characters")
+	ErrIconChoice            = errors.New("collection icon must be either an emoji or an uploaded image")
+	ErrInvalidIconImage      = errors.New("icon image must be a PNG, JPEG, GIF or WebP image of at most 512 KB")
 	ErrCollectionHasEntries  = errors.New("cannot delete collection with entries")
+	ErrCollectionsExist      = errors.New("user already has collections")
+	ErrInvalidAllowedTypes   = errors.New("allowed types must be at most 20 system types or types of your own")
+	ErrInvalidShareSlug      = errors.New("share link must be 3 to 60 characters: lowercase letters and digits, words separated by single hyphens")
+	ErrShareSlugReserved     = errors.New("share link is reserved")
 )
 
+const (
+	// maxIconImageBytes caps uploaded collection icons.
+	maxIconImageBytes = 512 << 10
+	// maxAllowedTypes caps the entry types a collection can be restricted to.
+	maxAllowedTypes = 20
+)
+
+var shareSlugPattern = regexp.MustCompile(`^[a-z0-9]+(-[a-z0-9]+)*$`)
+
+// reservedShareSlugs would read as pages of the app rather than a collection;
+// reserved profile handles are blocked too.
+var reservedShareSlugs = map[string]bool{
+	"collections": true, "edit": true, "entries": true, "new": true, "share": true, "shared": true,
+}
+
+// CollectionIcon is a collection's icon: either an emoji or the ID of an icon
+// image uploaded with UploadIcon. Exactly one of the two is set.
+type CollectionIcon struct {
+	Emoji   string
+	ImageID *uuid.UUID
+}
+
 type CollectionService struct {
 	collectionRepo *repository.CollectionRepository
+	typeRepo       *repository.TypeRepository
+	quotas         config.QuotasConfig
+	ids            IDGenerator
 }
 
-func NewCollectionService(collectionRepo *repository.CollectionRepository) *CollectionService {
+func NewCollectionService(
+	collectionRepo *repository.CollectionRepository,
+	typeRepo *repository.TypeRepository,
+	quotas config.QuotasConfig,
+	ids IDGenerator,
+) *CollectionService {
 	return &CollectionService{
 		collectionRepo: collectionRepo,
+		typeRepo:       typeRepo,
+		quotas:         quotas,
+		ids:            ids,
 	}
 }
 
-// CreateCollection creates a new collection with validation
+// CreateCollection creates a new collection with validation. A non-empty
+// allowedTypeIDs restricts the collection to entries of those types.
 func (s *CollectionService) CreateCollection(
 	ctx context.Context,
 	userID uuid.UUID,
-	name, icon string,
+	name string,
+	icon CollectionIcon,
+	allowedTypeIDs []uuid.UUID,
+) (*repository.Collection, error) {
+	return s.CreateCollectionWithID(ctx, nil, userID, name, icon, allowedTypeIDs)
+}
+
+// CreateCollectionWithID creates a collection with a client-chosen ID
+// (offline-created records), or a generated one if id is nil.
+func (s *CollectionService) CreateCollectionWithID(
+	ctx context.Context,
+	id *uuid.UUID,
+	userID uuid.UUID,
+	name string,
+	icon CollectionIcon,
+	allowedTypeIDs []uuid.UUID,
 ) (*repository.Collection, error) {
 	// Validate name
 	name = strings.TrimSpace(name)
@@ -38,13 +96,32 @@ func (s *CollectionService) CreateCollection(
 		return nil, ErrInvalidCollectionName
 	}
 
-	// Validate icon
-	icon = strings.TrimSpace(icon)
-	if len(icon) < 1 || len(icon) > 20 {
-		return nil, ErrInvalidIcon
+	icon, err := s.checkIcon(ctx, userID, icon)
+	if err != nil {
+		return nil, err
+	}
+
+	allowedTypeIDs, err = s.checkAllowedTypes(ctx, userID, nil, allowedTypeIDs)
+	if err != nil {
+		return nil, err
 	}
 
-	return s.collectionRepo.CreateCollection(ctx, userID, name, icon)
+	// Check quota
+	if s.quotas.MaxCollections > 0 {
+		count, err := s.collectionRepo.CountCollections(ctx, userID)
+		if err != nil {
+			return nil, err
+		}
+		if err := checkQuota(QuotaCollections, s.quotas.MaxCollections, count+1); err != nil {
+			return nil, err
+		}
+	}
+
+	if id == nil {
+		generated := s.ids.NewID()
+		id = &generated
+	}
+	return s.collectionRepo.CreateCollection(ctx, id, userID, name, icon.Emoji, icon.ImageID, allowedTypeIDs)
 }
 
 // GetCollectionsByUserID retrieves all collections for a user
@@ -55,6 +132,27 @@ func (s *CollectionService) GetCollectionsByUserID(
 	return s.collectionRepo.GetCollectionsByUserID(ctx, userID)
 }
 
+// ListCollections returns a page of the user's collections, oldest first,
+// starting after cursor. A zero limit returns all remaining collections.
+func (s *CollectionService) ListCollections(
+	ctx context.Context,
+	userID uuid.UUID,
+	cursor string,
+	limit int,
+) (*Page[*repository.Collection], error) {
+	after, err := decodePageCursor(cursor)
+	if err != nil {
+		return nil, err
+	}
+
+	collections, next, err := s.collectionRepo.ListCollectionsPage(ctx, userID, after, pageLimit(limit))
+	if err != nil {
+		return nil, err
+	}
+
+	return &Page[*repository.Collection]{Items: collections, NextCursor: encodePageCursor(next)}, nil
+}
+
 // GetCollectionByID retrieves a single collection
 func (s *CollectionService) GetCollectionByID(
 	ctx context.Context,
@@ -74,12 +172,16 @@ func (s *CollectionService) GetCollectionByID(
 	return collection, nil
 }
 
-// UpdateCollection updates a collection with validation
+// UpdateCollection updates a collection with validation. Changing the
+// allowed types applies to entries filed from then on; entries already in the
+// collection stay.
 func (s *CollectionService) UpdateCollection(
 	ctx context.Context,
 	id uuid.UUID,
 	userID uuid.UUID,
-	name, icon string,
+	name string,
+	icon CollectionIcon,
+	allowedTypeIDs []uuid.UUID, // nil keeps the current types, empty accepts any
 ) (*repository.Collection, error) {
 	// Check ownership first
 	existing, err := s.GetCollectionByID(ctx, id, userID)
@@ -93,16 +195,148 @@ func (s *CollectionService) UpdateCollection(
 		return nil, ErrInvalidCollectionName
 	}
 
-	// Validate icon
-	icon = strings.TrimSpace(icon)
-	if len(icon) < 1 || len(icon) > 20 {
-		return nil, ErrInvalidIcon
+	icon, err = s.checkIcon(ctx, userID, icon)
+	if err != nil {
+		return nil, err
+	}
+
+	if allowedTypeIDs == nil {
+		allowedTypeIDs = existing.AllowedTypeIDs
+	} else if allowedTypeIDs, err = s.checkAllowedTypes(ctx, userID, existing.WorkspaceID, allowedTypeIDs); err != nil {
+		return nil, err
+	}
+
+	return s.collectionRepo.UpdateCollection(ctx, id, name, icon.Emoji, icon.ImageID, allowedTypeIDs)
+}
+
+// NormalizeShareSlug lowercases a share slug so lookups are case-insensitive.
+func NormalizeShareSlug(slug string) string {
+	return strings.ToLower(strings.TrimSpace(slug))
+}
+
+// ShareCollection shares the user's collection under a slug of their choice,
+// replacing the one it was shared under. Anyone can then see its public
+// entries at GET /public/c/{slug}.
+func (s *CollectionService) ShareCollection(
+	ctx context.Context,
+	id uuid.UUID,
+	userID uuid.UUID,
+	slug string,
+) (*repository.Collection, error) {
+	if _, err := s.GetCollectionByID(ctx, id, userID); err != nil {
+		return nil, err
+	}
+
+	slug = NormalizeShareSlug(slug)
+	if len(slug) < 3 || len(slug) > 60 || !shareSlugPattern.MatchString(slug) {
+		return nil, ErrInvalidShareSlug
 	}
+	if reservedShareSlugs[slug] || reservedHandles[slug] {
+		return nil, ErrShareSlugReserved
+	}
+
+	if err := s.collectionRepo.SetCollectionShareSlug(ctx, id, &slug); err != nil {
+		return nil, err
+	}
+	return s.collectionRepo.GetCollectionByID(ctx, id)
+}
 
-	// Ensure we're updating the right user's collection
-	_ = existing
+// UnshareCollection stops sharing the user's collection; its slug is free
+// for anyone to take.
+func (s *CollectionService) UnshareCollection(
+	ctx context.Context,
+	id uuid.UUID,
+	userID uuid.UUID,
+) (*repository.Collection, error) {
+	if _, err := s.GetCollectionByID(ctx, id, userID); err != nil {
+		return nil, err
+	}
+
+	if err := s.collectionRepo.SetCollectionShareSlug(ctx, id, nil); err != nil {
+		return nil, err
+	}
+	return s.collectionRepo.GetCollectionByID(ctx, id)
+}
+
+// checkAllowedTypes dedupes the types a collection is restricted to and checks
+// each is a system type, one of the user's own, or one of the collection's
+// shared workspace.
+func (s *CollectionService) checkAllowedTypes(
+	ctx context.Context,
+	userID uuid.UUID,
+	workspaceID *uuid.UUID,
+	ids []uuid.UUID,
+) ([]uuid.UUID, error) {
+	ids = dedupeIDs(ids)
+	if len(ids) > maxAllowedTypes {
+		return nil, ErrInvalidAllowedTypes
+	}
+
+	for _, id := range ids {
+		t, err := s.typeRepo.GetTypeByID(ctx, id)
+		if errors.Is(err, repository.ErrTypeNotFound) {
+			return nil, ErrInvalidAllowedTypes
+		}
+		if err != nil {
+			return nil, err
+		}
+		switch {
+		case t.UserID == nil, *t.UserID == userID:
+		case t.WorkspaceID != nil && workspaceID != nil && *t.WorkspaceID == *workspaceID:
+		default:
+			return nil, ErrInvalidAllowedTypes
+		}
+	}
+
+	return ids, nil
+}
+
+// checkIcon validates a collection icon: an emoji of 1-20 characters, or an
+// icon image the user uploaded. Images of other users are reported as missing.
+func (s *CollectionService) checkIcon(ctx context.Context, userID uuid.UUID, icon CollectionIcon) (CollectionIcon, error) {
+	icon.Emoji = strings.TrimSpace(icon.Emoji)
+	if (icon.Emoji == "") == (icon.ImageID == nil) {
+		return icon, ErrIconChoice
+	}
+
+	if icon.ImageID == nil {
+		if len(icon.Emoji) > 20 {
+			return icon, ErrInvalidIcon
+		}
+		return icon, nil
+	}
+
+	image, err := s.collectionRepo.GetCollectionIcon(ctx, *icon.ImageID)
+	if err != nil {
+		return icon, err
+	}
+	if image.UserID != userID {
+		return icon, repository.ErrImageNotFound
+	}
+	return icon, nil
+}
+
+// UploadIcon stores an icon image the user can then set on collections.
+func (s *CollectionService) UploadIcon(
+	ctx context.Context,
+	userID uuid.UUID,
+	imageData []byte,
+) (*repository.CollectionIcon, error) {
+	if len(imageData) == 0 || len(imageData) > maxIconImageBytes {
+		return nil, ErrInvalidIconImage
+	}
+	switch http.DetectContentType(imageData) {
+	case "image/png", "image/jpeg", "image/gif", "image/webp":
+	default:
+		return nil, ErrInvalidIconImage
+	}
+
+	return s.collectionRepo.CreateCollectionIcon(ctx, userID, imageData)
+}
 
-	return s.collectionRepo.UpdateCollection(ctx, id, name, icon)
+// GetIcon retrieves an uploaded icon image
+func (s *CollectionService) GetIcon(ctx context.Context, id uuid.UUID) (*repository.CollectionIcon, error) {
+	return s.collectionRepo.GetCollectionIcon(ctx, id)
 }
 
 // DeleteCollection deletes a collection
@@ -132,7 +366,7 @@ func (s *CollectionService) CreateDefaultCollections(
 	}
 
 	if hasCollections {
-		return nil, errors.New("user already has collections")
+		return nil, ErrCollectionsExist
 	}
 
 	return s.collectionRepo.CreateDefaultCollections(ctx, userID)
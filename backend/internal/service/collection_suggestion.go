@@ -0,0 +1,54 @@
+package service
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/google/uuid"
+)
+
+// suggestionHistory is how many of the user's latest entries of a type the
+// collection suggestion looks at, so it follows how they file entries now.
+const suggestionHistory = 50
+
+// SuggestCollection returns the collection an entry of the type would likely
+// go in, judging by where the user filed their latest ones, or nil when
+// there is no history to go by.
+func (s *EntryService) SuggestCollection(ctx context.Context, userID uuid.UUID, typeID *uuid.UUID) (*uuid.UUID, error) {
+	return s.entryRepo.SuggestCollection(ctx, userID, typeID, suggestionHistory)
+}
+
+// resolveCollection picks the collection for an entry created without one:
+// the suggested collection when the user turned on auto assignment, nil
+// otherwise. Either way the suggestion is reported as a warning.
+func (s *EntryService) resolveCollection(ctx context.Context, userID uuid.UUID, typeID *uuid.UUID) (*uuid.UUID, error) {
+	suggested, err := s.SuggestCollection(ctx, userID, typeID)
+	if err != nil {
+		return nil, err
+	}
+	if suggested == nil {
+		return nil, nil
+	}
+
+	user, err := s.userRepo.GetUserByID(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get user: %w", err)
+	}
+	if user.AutoAssignCollection {
+		warn(ctx, Warning{
+			Code:       WarningCollectionAssigned,
+			Message:    "The entry was added to the collection you usually use for its type",
+			Field:      "collection_id",
+			Suggestion: suggested.String(),
+		})
+		return suggested, nil
+	}
+
+	warn(ctx, Warning{
+		Code:       WarningCollectionSuggested,
+		Message:    "The entry has no collection; it would likely go in the suggested one",
+		Field:      "collection_id",
+		Suggestion: suggested.String(),
+	})
+	return nil, nil
+}
@@ -0,0 +1,29 @@
+package service
+
+import (
+	"time"
+
+	"github.com/avalarin/livlog/backend/internal/repository"
+)
+
+// ConcurrentUpdate is another device's write that an update followed within
+// the concurrent update window. The update was saved; the hint lets the
+// client ask the user before they edit on top of changes they haven't seen.
+type ConcurrentUpdate struct {
+	Device    repository.Device
+	UpdatedAt time.Time
+}
+
+// concurrentUpdate returns the concurrent update an update from device saved
+// at raises, when prev, the entry's write before it, came from another
+// device less than window earlier. Unknown devices are never flagged, and a
+// zero window flags nothing.
+func concurrentUpdate(device *repository.Device, prev *repository.EntryWrite, at time.Time, window time.Duration) *ConcurrentUpdate {
+	if window <= 0 || device == nil || prev == nil || prev.Device == nil || prev.Device.ID == device.ID {
+		return nil
+	}
+	if at.Sub(prev.At) > window {
+		return nil
+	}
+	return &ConcurrentUpdate{Device: *prev.Device, UpdatedAt: prev.At}
+}
@@ -0,0 +1,46 @@
+package service
+
+import (
+	"testing"
+	"time"
+
+	"github.com/avalarin/livlog/backend/internal/repository"
+)
+
+func TestConcurrentUpdate(t *testing.T) {
+	at := time.Date(2025, 1, 20, 16, 45, 40, 0, time.UTC)
+	phone := &repository.Device{ID: "phone-1", Name: "Livlog/2.3 iOS/18.1"}
+	ipad := &repository.Device{ID: "ipad-1", Name: "Livlog/2.3 iPadOS/18.1"}
+	write := func(device *repository.Device, ago time.Duration) *repository.EntryWrite {
+		return &repository.EntryWrite{Device: device, At: at.Add(-ago)}
+	}
+
+	tests := []struct {
+		name   string
+		device *repository.Device
+		prev   *repository.EntryWrite
+		window time.Duration
+		want   bool
+	}{
+		{"other device within window", phone, write(ipad, 30*time.Second), time.Minute, true},
+		{"other device at window", phone, write(ipad, time.Minute), time.Minute, true},
+		{"other device after window", phone, write(ipad, 61*time.Second), time.Minute, false},
+		{"longer window", phone, write(ipad, 5*time.Minute), 10 * time.Minute, true},
+		{"zero window", phone, write(ipad, 0), 0, false},
+		{"same device", phone, write(phone, time.Second), time.Minute, false},
+		{"unknown device", nil, write(ipad, time.Second), time.Minute, false},
+		{"unknown previous device", phone, write(nil, time.Second), time.Minute, false},
+		{"no previous write", phone, nil, time.Minute, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := concurrentUpdate(tt.device, tt.prev, at, tt.window)
+			if (got != nil) != tt.want {
+				t.Fatalf("concurrentUpdate() = %+v, want concurrent %t", got, tt.want)
+			}
+			if got != nil && (got.Device != *tt.prev.Device || !got.UpdatedAt.Equal(tt.prev.At)) {
+				t.Errorf("concurrentUpdate() = %+v, want %+v", got, tt.prev)
+			}
+		})
+	}
+}
@@ -2,17 +2,21 @@ package service
 
 import (
 	"context"
+	"crypto/rand"
 	"errors"
 	"fmt"
+	"math/big"
 	"regexp"
+	"strings"
 	"time"
 
 	"github.com/avalarin/livlog/backend/internal/repository"
+	"github.com/google/uuid"
 )
 
 const (
-	// HardcodedVerificationCode is the verification code used in MVP
-	// In production, this should be replaced with a randomly generated code
+	// HardcodedVerificationCode is the sign-in code used in MVP, while no
+	// mailer is configured
 	HardcodedVerificationCode = "000000"
 
 	// VerificationCodeExpiry is the time window for code verification
@@ -20,21 +24,33 @@ const (
 )
 
 var (
-	ErrInvalidEmail          = errors.New("invalid email format")
-	ErrInvalidCode           = errors.New("invalid verification code")
-	ErrCodeExpired           = errors.New("verification code expired")
-	ErrCodeAlreadyUsed       = errors.New("verification code already used")
-	ErrRateLimitExceeded     = errors.New("too many requests, please wait")
+	ErrInvalidEmail      = errors.New("invalid email format")
+	ErrInvalidCode       = errors.New("invalid verification code")
+	ErrCodeExpired       = errors.New("verification code expired")
+	ErrCodeAlreadyUsed   = errors.New("verification code already used")
+	ErrRateLimitExceeded = errors.New("too many requests, please wait")
+	ErrNoEmail           = errors.New("account has no email")
 
 	// Simple email regex for basic validation
 	emailRegex = regexp.MustCompile(`^[a-zA-Z0-9._%+-]+@[a-zA-Z0-9.-]+\.[a-zA-Z]{2,}$`)
 )
 
+// verificationCodeStore is the part of VerificationCodeRepository email
+// sign-in uses.
+type verificationCodeStore interface {
+	CreateVerificationCode(ctx context.Context, email, code string, expiresAt time.Time) (*repository.VerificationCode, error)
+	FindVerificationCode(ctx context.Context, email, code string) (*repository.VerificationCode, error)
+	MarkCodeAsUsed(ctx context.Context, id uuid.UUID) error
+}
+
 type EmailAuthService struct {
-	userRepo     *repository.UserRepository
-	codeRepo     *repository.VerificationCodeRepository
-	jwtService   *JWTService
-	rateLimiter  *RateLimiter
+	userRepo      *repository.UserRepository
+	codeRepo      verificationCodeStore
+	jwtService    *JWTService
+	rateLimiter   *RateLimiter
+	mailer        Mailer
+	stripPlusTags bool
+	clock         Clock
 }
 
 func NewEmailAuthService(
@@ -42,28 +58,48 @@ func NewEmailAuthService(
 	codeRepo *repository.VerificationCodeRepository,
 	jwtService *JWTService,
 	rateLimiter *RateLimiter,
+	mailer Mailer,
+	stripPlusTags bool,
+	clock Clock,
 ) *EmailAuthService {
 	return &EmailAuthService{
-		userRepo:    userRepo,
-		codeRepo:    codeRepo,
-		jwtService:  jwtService,
-		rateLimiter: rateLimiter,
+		userRepo:      userRepo,
+		codeRepo:      codeRepo,
+		jwtService:    jwtService,
+		rateLimiter:   rateLimiter,
+		mailer:        mailer,
+		stripPlusTags: stripPlusTags,
+		clock:         clock,
 	}
 }
 
-// SendVerificationCode generates and stores a verification code for the email
-// For MVP, always uses hardcoded "000000"
+// SendVerificationCode generates, stores and emails a verification code for
+// the email. Without a mailer it falls back to the MVP's hardcoded "000000";
+// VerifyUserEmail is off then, so such codes never re-verify an account. The
+// code is stored under the normalized address but mailed to the one typed,
+// since not every mail server drops plus tags.
 func (s *EmailAuthService) SendVerificationCode(ctx context.Context, email string) error {
+	address := strings.TrimSpace(email)
+	email = NormalizeEmail(email, s.stripPlusTags)
+
 	// Validate email format
 	if !isValidEmail(email) {
 		return ErrInvalidEmail
 	}
+	if s.mailer != nil && !s.mailer.CanSend(address) {
+		return ErrMailRelayUnavailable
+	}
 
-	// Generate code (hardcoded for MVP)
 	code := HardcodedVerificationCode
+	if s.mailer != nil {
+		var err error
+		if code, err = newVerificationCode(); err != nil {
+			return err
+		}
+	}
 
 	// Calculate expiry time
-	expiresAt := time.Now().Add(VerificationCodeExpiry)
+	expiresAt := s.clock.Now().Add(VerificationCodeExpiry)
 
 	// Create verification code (automatically invalidates previous codes)
 	_, err := s.codeRepo.CreateVerificationCode(ctx, email, code, expiresAt)
@@ -71,32 +107,52 @@ func (s *EmailAuthService) SendVerificationCode(ctx context.Context, email strin
 		return fmt.Errorf("failed to create verification code: %w", err)
 	}
 
-	// In production, send email here
-	// emailService.SendVerificationEmail(email, code)
-
+	if s.mailer != nil {
+		if err := s.mailer.Send(ctx, address, verificationCodeMail(code)); err != nil {
+			return fmt.Errorf("failed to email verification code: %w", err)
+		}
+	}
 	return nil
 }
 
 // ResendVerificationCode resends verification code with rate limiting
 func (s *EmailAuthService) ResendVerificationCode(ctx context.Context, email string) error {
+	normalized := NormalizeEmail(email, s.stripPlusTags)
+
 	// Validate email format
-	if !isValidEmail(email) {
+	if !isValidEmail(normalized) {
 		return ErrInvalidEmail
 	}
 
 	// Check rate limit (1 request per minute per email)
-	rateLimitKey := fmt.Sprintf("resend:%s", email)
-	if !s.rateLimiter.Allow(rateLimitKey) {
-		return ErrRateLimitExceeded
+	rateLimitKey := resendRateLimitKey(normalized)
+	allowed := s.rateLimiter.Allow(rateLimitKey)
+	RecordRateLimitDecision("email_resend", "email", allowed)
+	if !allowed {
+		return &RateLimitError{
+			Err:        ErrRateLimitExceeded,
+			Limit:      1,
+			RetryAfter: time.Duration(s.rateLimiter.GetRetryAfter(rateLimitKey)) * time.Second,
+		}
 	}
 
 	// Send new verification code
 	return s.SendVerificationCode(ctx, email)
 }
 
+// resendRateLimitKey is the rate limiter key of code resends to an email.
+// Plus tags are always stripped here, so the typed address, the normalized
+// one and the account's share one limit: they reach the same inbox.
+func resendRateLimitKey(email string) string {
+	return "resend:" + NormalizeEmail(email, true)
+}
+
 // VerifyCode verifies the code and returns auth response
-// Creates user if doesn't exist
-func (s *EmailAuthService) VerifyCode(ctx context.Context, email, code string) (*AuthResponse, error) {
+// Creates user if doesn't exist; the refresh token is stored with client
+func (s *EmailAuthService) VerifyCode(ctx context.Context, email, code string, client repository.TokenClient) (*AuthResponse, error) {
+	address := strings.TrimSpace(email)
+	email = NormalizeEmail(email, s.stripPlusTags)
+
 	// Validate email format
 	if !isValidEmail(email) {
 		return nil, ErrInvalidEmail
@@ -107,28 +163,12 @@ func (s *EmailAuthService) VerifyCode(ctx context.Context, email, code string) (
 		return nil, ErrInvalidCode
 	}
 
-	// Find and validate code
-	verificationCode, err := s.codeRepo.FindVerificationCode(ctx, email, code)
-	if err != nil {
-		if errors.Is(err, repository.ErrVerificationCodeNotFound) {
-			return nil, ErrInvalidCode
-		}
-		if errors.Is(err, repository.ErrVerificationCodeExpired) {
-			return nil, ErrCodeExpired
-		}
-		return nil, fmt.Errorf("failed to find verification code: %w", err)
-	}
-
-	// Mark code as used
-	if err := s.codeRepo.MarkCodeAsUsed(ctx, verificationCode.ID); err != nil {
-		if errors.Is(err, repository.ErrVerificationCodeUsed) {
-			return nil, ErrCodeAlreadyUsed
-		}
-		return nil, fmt.Errorf("failed to mark code as used: %w", err)
+	if err := s.useCode(ctx, email, code); err != nil {
+		return nil, err
 	}
 
 	// Find or create user
-	user, err := s.findOrCreateEmailUser(ctx, email)
+	user, err := s.findOrCreateEmailUser(ctx, email, address)
 	if err != nil {
 		return nil, fmt.Errorf("failed to find or create user: %w", err)
 	}
@@ -145,8 +185,8 @@ func (s *EmailAuthService) VerifyCode(ctx context.Context, email, code string) (
 	}
 
 	// Save refresh token
-	expiresAt := time.Now().Add(s.jwtService.GetRefreshTokenLifetime())
-	if err := s.userRepo.SaveRefreshToken(ctx, user.ID, refreshToken, expiresAt); err != nil {
+	expiresAt := s.clock.Now().Add(s.jwtService.GetRefreshTokenLifetime())
+	if err := s.userRepo.SaveRefreshToken(ctx, user.ID, refreshToken, expiresAt, client); err != nil {
 		return nil, fmt.Errorf("failed to save refresh token: %w", err)
 	}
 
@@ -164,28 +204,148 @@ func (s *EmailAuthService) VerifyCode(ctx context.Context, email, code string) (
 	}, nil
 }
 
-// GetRetryAfter returns seconds until next resend is allowed
-func (s *EmailAuthService) GetRetryAfter(email string) int {
-	rateLimitKey := fmt.Sprintf("resend:%s", email)
-	return s.rateLimiter.GetRetryAfter(rateLimitKey)
+// SendUserEmailCode emails a random verification code to the signed-in
+// user's current email, to verify it again. Rate limited like resends.
+// Without a mailer it returns ErrMailUnavailable: the code must prove the
+// user reads the inbox, so there is no fallback.
+func (s *EmailAuthService) SendUserEmailCode(ctx context.Context, userID uuid.UUID) error {
+	if s.mailer == nil {
+		return ErrMailUnavailable
+	}
+
+	user, err := s.userRepo.GetUserByID(ctx, userID)
+	if err != nil {
+		return fmt.Errorf("failed to get user: %w", err)
+	}
+	email := getEmailString(user.Email)
+	if email == "" {
+		return ErrNoEmail
+	}
+	if !s.mailer.CanSend(email) {
+		return ErrMailRelayUnavailable
+	}
+
+	rateLimitKey := resendRateLimitKey(email)
+	allowed := s.rateLimiter.Allow(rateLimitKey)
+	RecordRateLimitDecision("email_resend", "email", allowed)
+	if !allowed {
+		return &RateLimitError{
+			Err:        ErrRateLimitExceeded,
+			Limit:      1,
+			RetryAfter: time.Duration(s.rateLimiter.GetRetryAfter(rateLimitKey)) * time.Second,
+		}
+	}
+
+	code, err := newVerificationCode()
+	if err != nil {
+		return err
+	}
+	// Stored hashed under the address as the account has it, without
+	// stripping plus tags, which is what VerifyUserEmail looks the code up by
+	if _, err := s.codeRepo.CreateVerificationCode(ctx, email, code, s.clock.Now().Add(VerificationCodeExpiry)); err != nil {
+		return fmt.Errorf("failed to create verification code: %w", err)
+	}
+
+	if err := s.mailer.Send(ctx, email, verificationCodeMail(code)); err != nil {
+		return fmt.Errorf("failed to email verification code: %w", err)
+	}
+	return nil
+}
+
+// verificationCodeMail is the email a verification code is sent in.
+func verificationCodeMail(code string) MailMessage {
+	minutes := int(VerificationCodeExpiry.Minutes())
+	return MailMessage{
+		Subject: "Your Livlog verification code",
+		Body: fmt.Sprintf("Your verification code is %s\n\n"+
+			"It expires in %d minutes. If you didn't ask for it, you can ignore this email.\n", code, minutes),
+	}
+}
+
+// VerifyUserEmail checks a code from SendUserEmailCode and marks the user's
+// email verified, lifting ErrEmailVerificationRequired.
+func (s *EmailAuthService) VerifyUserEmail(ctx context.Context, userID uuid.UUID, code string) (*User, error) {
+	if s.mailer == nil {
+		return nil, ErrMailUnavailable
+	}
+	if !isValidCode(code) {
+		return nil, ErrInvalidCode
+	}
+
+	user, err := s.userRepo.GetUserByID(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get user: %w", err)
+	}
+	email := getEmailString(user.Email)
+	if email == "" {
+		return nil, ErrNoEmail
+	}
+
+	if err := s.useCode(ctx, email, code); err != nil {
+		return nil, err
+	}
+	if err := s.userRepo.MarkEmailVerified(ctx, userID); err != nil {
+		return nil, fmt.Errorf("failed to mark email verified: %w", err)
+	}
+	user.EmailVerified = true
+
+	providers, err := s.userRepo.GetUserAuthProviders(ctx, user.ID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get auth providers: %w", err)
+	}
+
+	return mapUserToResponse(user, providers), nil
 }
 
 // Helper functions
 
-// findOrCreateEmailUser finds existing user by email or creates new one
-func (s *EmailAuthService) findOrCreateEmailUser(ctx context.Context, email string) (*repository.User, error) {
+// useCode finds the email's code and marks it used, so it works once.
+func (s *EmailAuthService) useCode(ctx context.Context, email, code string) error {
+	verificationCode, err := s.codeRepo.FindVerificationCode(ctx, email, code)
+	if err != nil {
+		if errors.Is(err, repository.ErrVerificationCodeNotFound) {
+			return ErrInvalidCode
+		}
+		if errors.Is(err, repository.ErrVerificationCodeExpired) {
+			return ErrCodeExpired
+		}
+		return fmt.Errorf("failed to find verification code: %w", err)
+	}
+
+	if err := s.codeRepo.MarkCodeAsUsed(ctx, verificationCode.ID); err != nil {
+		if errors.Is(err, repository.ErrVerificationCodeUsed) {
+			return ErrCodeAlreadyUsed
+		}
+		return fmt.Errorf("failed to mark code as used: %w", err)
+	}
+
+	return nil
+}
+
+// findOrCreateEmailUser finds existing user by normalized email or creates
+// new one. A new account's email is the address as typed, which mail is sent
+// to; the normalized one identifies the email provider.
+func (s *EmailAuthService) findOrCreateEmailUser(ctx context.Context, email, address string) (*repository.User, error) {
 	// Try to find user by email provider
 	user, err := s.userRepo.FindUserByProvider(ctx, "email", email)
 	if err != nil {
+		if errors.Is(err, repository.ErrUserDeleted) {
+			return nil, ErrAccountPendingDeletion
+		}
 		if errors.Is(err, repository.ErrUserNotFound) {
+			if err := checkEmailNotPendingDeletion(ctx, s.userRepo, address); err != nil {
+				return nil, err
+			}
+
 			// Create new user with email provider
 			user, err = s.userRepo.CreateUserWithProvider(
 				ctx,
-				email,
-				"",           // No display name initially
-				true,         // Email verified after successful code verification
-				"email",      // Provider type
-				email,        // Provider user ID is the email itself
+				address,
+				"",      // No display name initially
+				true,    // Email verified after successful code verification
+				false,   // Not an Apple private relay address
+				"email", // Provider type
+				email,   // Provider user ID is the email itself
 			)
 			if err != nil {
 				return nil, fmt.Errorf("failed to create user: %w", err)
@@ -198,6 +358,22 @@ func (s *EmailAuthService) findOrCreateEmailUser(ctx context.Context, email stri
 	return user, nil
 }
 
+// NormalizeEmail trims and lowercases an address so that User@x.com and
+// user@x.com sign in to the same account. With stripPlusTags the "+tag" part
+// of the local name is dropped too, so user+news@x.com becomes user@x.com.
+func NormalizeEmail(email string, stripPlusTags bool) string {
+	email = strings.ToLower(strings.TrimSpace(email))
+	if !stripPlusTags {
+		return email
+	}
+
+	at := strings.LastIndex(email, "@")
+	if plus := strings.Index(email, "+"); plus > 0 && plus < at {
+		email = email[:plus] + email[at:]
+	}
+	return email
+}
+
 // isValidEmail validates email format using basic regex
 func isValidEmail(email string) bool {
 	if email == "" {
@@ -209,6 +385,15 @@ func isValidEmail(email string) bool {
 	return emailRegex.MatchString(email)
 }
 
+// newVerificationCode returns a random six-digit code.
+func newVerificationCode() (string, error) {
+	n, err := rand.Int(rand.Reader, big.NewInt(1000000))
+	if err != nil {
+		return "", fmt.Errorf("failed to generate verification code: %w", err)
+	}
+	return fmt.Sprintf("%06d", n.Int64()), nil
+}
+
 // isValidCode validates verification code format (6 digits)
 func isValidCode(code string) bool {
 	if len(code) != 6 {
@@ -0,0 +1,138 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/avalarin/livlog/backend/internal/repository"
+)
+
+func TestNormalizeEmail(t *testing.T) {
+	tests := []struct {
+		name          string
+		email         string
+		stripPlusTags bool
+		want          string
+	}{
+		{"case folding", "User@Example.COM", false, "user@example.com"},
+		{"whitespace", "  user@example.com\t\n", false, "user@example.com"},
+		{"plus tag kept", "user+news@example.com", false, "user+news@example.com"},
+		{"plus tag stripped", "User+News@Example.com", true, "user@example.com"},
+		{"multiple plus signs", "user+a+b@example.com", true, "user@example.com"},
+		{"plus in domain", "user@ex+ample.com", true, "user@ex+ample.com"},
+		{"dots kept", "first.last@gmail.com", true, "first.last@gmail.com"},
+		{"empty", "   ", true, ""},
+		{"no at sign", "user+tag", true, "user+tag"},
+		{"only a tag", "+tag@example.com", true, "+tag@example.com"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := NormalizeEmail(tt.email, tt.stripPlusTags); got != tt.want {
+				t.Errorf("NormalizeEmail(%q, %t) = %q, want %q", tt.email, tt.stripPlusTags, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNewVerificationCode(t *testing.T) {
+	seen := make(map[string]bool)
+	for i := 0; i < 20; i++ {
+		code, err := newVerificationCode()
+		if err != nil {
+			t.Fatalf("newVerificationCode() error = %v", err)
+		}
+		if !isValidCode(code) {
+			t.Fatalf("newVerificationCode() = %q, want six digits", code)
+		}
+		seen[code] = true
+	}
+	if len(seen) < 2 {
+		t.Errorf("newVerificationCode() returned the same code 20 times")
+	}
+}
+
+func TestUserEmailCode_WithoutMailer(t *testing.T) {
+	// Without a mailer the gate must stay closed: no repository is touched,
+	// so no code is stored that could be guessed
+	s := &EmailAuthService{}
+
+	if err := s.SendUserEmailCode(context.Background(), uuid.New()); !errors.Is(err, ErrMailUnavailable) {
+		t.Errorf("SendUserEmailCode() error = %v, want ErrMailUnavailable", err)
+	}
+	if _, err := s.VerifyUserEmail(context.Background(), uuid.New(), HardcodedVerificationCode); !errors.Is(err, ErrMailUnavailable) {
+		t.Errorf("VerifyUserEmail() error = %v, want ErrMailUnavailable", err)
+	}
+}
+
+// fakeCodes keeps the email of the last stored code.
+type fakeCodes struct {
+	email string
+}
+
+func (f *fakeCodes) CreateVerificationCode(_ context.Context, email, _ string, _ time.Time) (*repository.VerificationCode, error) {
+	f.email = email
+	return &repository.VerificationCode{}, nil
+}
+
+func (f *fakeCodes) FindVerificationCode(_ context.Context, _, _ string) (*repository.VerificationCode, error) {
+	return nil, repository.ErrVerificationCodeNotFound
+}
+
+func (f *fakeCodes) MarkCodeAsUsed(_ context.Context, _ uuid.UUID) error {
+	return nil
+}
+
+// fakeMailer records the recipients of sent mail. Like an SMTPMailer
+// without mail.apple_relay, it can't send to private relay addresses.
+type fakeMailer struct {
+	to []string
+}
+
+func (f *fakeMailer) CanSend(to string) bool {
+	return !isAppleRelayAddress(to)
+}
+
+func (f *fakeMailer) Send(_ context.Context, to string, _ MailMessage) error {
+	f.to = append(f.to, to)
+	return nil
+}
+
+func TestSendVerificationCode_MailsTypedAddress(t *testing.T) {
+	codes, mailer := &fakeCodes{}, &fakeMailer{}
+	s := &EmailAuthService{
+		codeRepo:      codes,
+		mailer:        mailer,
+		stripPlusTags: true,
+		clock:         NewManualClock(time.Date(2025, 2, 1, 10, 0, 0, 0, time.UTC)),
+	}
+
+	if err := s.SendVerificationCode(context.Background(), " User+News@Example.com "); err != nil {
+		t.Fatalf("SendVerificationCode() error = %v", err)
+	}
+	// The code is looked up by the normalized address, but only the typed
+	// one is known to reach the user's inbox
+	if codes.email != "user@example.com" {
+		t.Errorf("code stored for %q, want user@example.com", codes.email)
+	}
+	if len(mailer.to) != 1 || mailer.to[0] != "User+News@Example.com" {
+		t.Errorf("code mailed to %v, want [User+News@Example.com]", mailer.to)
+	}
+}
+
+func TestSendVerificationCode_AppleRelay(t *testing.T) {
+	codes, mailer := &fakeCodes{}, &fakeMailer{}
+	s := &EmailAuthService{codeRepo: codes, mailer: mailer, clock: NewManualClock(time.Now())}
+
+	err := s.SendVerificationCode(context.Background(), "abc123@privaterelay.appleid.com")
+	if !errors.Is(err, ErrMailRelayUnavailable) {
+		t.Fatalf("SendVerificationCode() error = %v, want ErrMailRelayUnavailable", err)
+	}
+	if codes.email != "" || len(mailer.to) != 0 {
+		t.Errorf("stored a code for %q and mailed %v, want neither", codes.email, mailer.to)
+	}
+}
@@ -0,0 +1,157 @@
+package service
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/avalarin/livlog/backend/internal/config"
+	"github.com/avalarin/livlog/backend/internal/fieldcrypt"
+	"github.com/avalarin/livlog/backend/internal/repository"
+)
+
+// encryptBatchSize is how many rows a rotation encrypts per query.
+const encryptBatchSize = 500
+
+// EncryptionService manages the keys of application-level encryption, see
+// config.EncryptionConfig. The first KEK is the current one; the others are
+// only used to unwrap keys that haven't been re-wrapped yet.
+type EncryptionService struct {
+	keks      []*fieldcrypt.KEK
+	keyRepo   *repository.EncryptionKeyRepository
+	userRepo  *repository.UserRepository
+	codeRepo  *repository.VerificationCodeRepository
+	entryRepo *repository.EntryRepository
+}
+
+func NewEncryptionService(
+	cfg config.EncryptionConfig,
+	keyRepo *repository.EncryptionKeyRepository,
+	userRepo *repository.UserRepository,
+	codeRepo *repository.VerificationCodeRepository,
+	entryRepo *repository.EntryRepository,
+) (*EncryptionService, error) {
+	keks := make([]*fieldcrypt.KEK, 0, 1+len(cfg.PreviousKeys))
+	for _, encoded := range append([]string{cfg.Key}, cfg.PreviousKeys...) {
+		kek, err := fieldcrypt.ParseKEK(encoded)
+		if err != nil {
+			return nil, err
+		}
+		keks = append(keks, kek)
+	}
+
+	return &EncryptionService{
+		keks:      keks,
+		keyRepo:   keyRepo,
+		userRepo:  userRepo,
+		codeRepo:  codeRepo,
+		entryRepo: entryRepo,
+	}, nil
+}
+
+// Enable opens the keyring, creating the data and index keys on first use,
+// and makes the repositories encrypt with it.
+func (s *EncryptionService) Enable(ctx context.Context) (*fieldcrypt.Keyring, error) {
+	for _, purpose := range []string{fieldcrypt.PurposeData, fieldcrypt.PurposeIndex} {
+		key, err := fieldcrypt.NewKey(purpose, s.keks[0])
+		if err != nil {
+			return nil, err
+		}
+		// Exists after the first start, or when another instance got there first
+		if _, err := s.keyRepo.CreateEncryptionKeyIfMissing(ctx, key); err != nil {
+			return nil, err
+		}
+	}
+
+	keyring, err := fieldcrypt.NewKeyring(ctx, s.keks, s.keyRepo.ListEncryptionKeys)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open encryption keys: %w", err)
+	}
+
+	s.userRepo.UseEncryption(keyring)
+	s.codeRepo.UseEncryption(keyring)
+	s.entryRepo.UseEncryption(keyring)
+	return keyring, nil
+}
+
+// RotationResult counts what a rotation changed.
+type RotationResult struct {
+	RewrappedKeys int
+	NewDataKey    bool
+	Emails        int
+	Identities    int // email sign-in identities
+	DeviceInfo    int
+	IPAddresses   int // of refresh tokens
+	Codes         int // verification codes
+	EntryDevices  int // devices that last wrote entries
+}
+
+// Rotate re-wraps the stored keys that aren't wrapped with the current KEK,
+// replaces the data key when newDataKey is set, then encrypts every value
+// that is plaintext or encrypted with a retired data key. Running servers
+// pick up a new data key the first time they read a value encrypted with it.
+func (s *EncryptionService) Rotate(ctx context.Context, newDataKey bool) (*RotationResult, error) {
+	result := &RotationResult{NewDataKey: newDataKey}
+
+	keys, err := s.keyRepo.ListEncryptionKeys(ctx)
+	if err != nil {
+		return nil, err
+	}
+	current := s.keks[0]
+	for _, key := range keys {
+		if key.KEKFingerprint == current.Fingerprint {
+			continue
+		}
+		raw, err := key.UnwrapWith(s.keks)
+		if err != nil {
+			return nil, err
+		}
+		wrapped, err := current.Wrap(raw)
+		if err != nil {
+			return nil, err
+		}
+		if err := s.keyRepo.RewrapEncryptionKey(ctx, key.ID, wrapped, current.Fingerprint); err != nil {
+			return nil, err
+		}
+		result.RewrappedKeys++
+	}
+
+	if newDataKey {
+		key, err := fieldcrypt.NewKey(fieldcrypt.PurposeData, current)
+		if err != nil {
+			return nil, err
+		}
+		if err := s.keyRepo.RotateDataKey(ctx, key); err != nil {
+			return nil, err
+		}
+	}
+
+	if _, err := s.Enable(ctx); err != nil {
+		return nil, err
+	}
+
+	steps := []struct {
+		count   *int
+		encrypt func(ctx context.Context, limit int) (int, error)
+	}{
+		{&result.Emails, s.userRepo.EncryptEmails},
+		{&result.Identities, s.userRepo.EncryptProviderEmails},
+		{&result.DeviceInfo, s.userRepo.EncryptDeviceInfo},
+		{&result.IPAddresses, s.userRepo.EncryptIPAddresses},
+		{&result.Codes, s.codeRepo.EncryptEmails},
+		{&result.EntryDevices, s.entryRepo.EncryptDevices},
+	}
+	for _, step := range steps {
+		for {
+			n, err := step.encrypt(ctx, encryptBatchSize)
+			*step.count += n
+			if err != nil {
+				return result, err
+			}
+			if n == 0 {
+				break
+			}
+		}
+	}
+
+	return result, nil
+}
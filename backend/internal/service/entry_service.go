@@ -4,53 +4,139 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"regexp"
+	"sort"
 	"strconv"
 	"strings"
 	"time"
 
+	"github.com/avalarin/livlog/backend/internal/config"
 	"github.com/avalarin/livlog/backend/internal/repository"
 	"github.com/google/uuid"
 )
 
 var (
-	ErrInvalidTitle       = errors.New("title must be between 1 and 200 characters")
-	ErrInvalidDescription = errors.New("description must be between 1 and 2000 characters")
-	ErrInvalidScore       = errors.New("score must be between 0 and 3")
-	ErrInvalidFieldValue  = errors.New("additional field has invalid value for its type")
+	ErrInvalidTitle         = errors.New("title must be between 1 and 200 characters")
+	ErrInvalidDescription   = errors.New("description must be between 1 and 2000 characters")
+	ErrInvalidScore         = errors.New("score is out of range")
+	ErrInvalidFieldValue    = errors.New("additional field has invalid value for its type")
+	ErrInvalidSort          = errors.New("sort must be created, manual, priority or title")
+	ErrInvalidPriority      = errors.New("priority must be between 0 and 5")
+	ErrInvalidOriginalTitle = errors.New("original title must be at most 200 characters")
+	ErrInvalidLanguage      = errors.New("language must be a BCP 47 tag such as en or pt-BR")
+	ErrManualSortScope      = errors.New("sort=manual requires collection_id")
+	ErrInvalidEntryOrder    = errors.New("entry order must list each entry of the collection at most once")
+	ErrInvalidVisibility    = errors.New("visibility must be private, shared or public")
+	ErrTypeNotAllowed       = errors.New("the collection does not accept entries of this type")
 )
 
+// MaxPriority is the highest entry priority; 0 means the entry has none.
+const MaxPriority = 5
+
 type EntryService struct {
 	entryRepo      *repository.EntryRepository
 	collectionRepo *repository.CollectionRepository
 	typeRepo       *repository.TypeRepository
+	workspaceRepo  *repository.WorkspaceRepository
+	userRepo       *repository.UserRepository
+	quotas         config.QuotasConfig
+	ids            IDGenerator
+
+	// concurrentWindow is how soon after another device's write an update
+	// is flagged as concurrent; see ConcurrentUpdate
+	concurrentWindow time.Duration
 }
 
 func NewEntryService(
 	entryRepo *repository.EntryRepository,
 	collectionRepo *repository.CollectionRepository,
 	typeRepo *repository.TypeRepository,
+	workspaceRepo *repository.WorkspaceRepository,
+	userRepo *repository.UserRepository,
+	quotas config.QuotasConfig,
+	concurrentWindow time.Duration,
+	ids IDGenerator,
 ) *EntryService {
 	return &EntryService{
-		entryRepo:      entryRepo,
-		collectionRepo: collectionRepo,
-		typeRepo:       typeRepo,
+		entryRepo:        entryRepo,
+		collectionRepo:   collectionRepo,
+		typeRepo:         typeRepo,
+		workspaceRepo:    workspaceRepo,
+		userRepo:         userRepo,
+		quotas:           quotas,
+		ids:              ids,
+		concurrentWindow: concurrentWindow,
+	}
+}
+
+// checkCollectionAccess checks that the user may file entries in the
+// collection: they own it, or it is in a shared workspace they are a member
+// of.
+func (s *EntryService) checkCollectionAccess(ctx context.Context, userID, collectionID uuid.UUID) (*repository.Collection, error) {
+	collection, err := s.collectionRepo.GetCollectionByID(ctx, collectionID)
+	if err != nil {
+		return nil, fmt.Errorf("invalid collection: %w", err)
+	}
+	if collection.UserID == userID {
+		return collection, nil
+	}
+	if collection.WorkspaceID != nil {
+		member, err := s.workspaceRepo.IsMember(ctx, *collection.WorkspaceID, userID)
+		if err != nil {
+			return nil, err
+		}
+		if member {
+			return collection, nil
+		}
 	}
+	return nil, repository.ErrCollectionNotFound
 }
 
-// validateAdditionalFields checks that number-typed fields contain parseable numeric values.
-// Unknown field keys are silently ignored for forward compatibility.
-func (s *EntryService) validateAdditionalFields(
+// checkAllowedType checks that a collection restricted to some entry types
+// accepts the entry's type. Untyped entries only go in unrestricted ones.
+func checkAllowedType(collection *repository.Collection, typeID *uuid.UUID) error {
+	if len(collection.AllowedTypeIDs) == 0 {
+		return nil
+	}
+	if typeID != nil {
+		for _, id := range collection.AllowedTypeIDs {
+			if id == *typeID {
+				return nil
+			}
+		}
+	}
+	return ErrTypeNotAllowed
+}
+
+// sameID reports whether two optional IDs are equal.
+func sameID(a, b *uuid.UUID) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return *a == *b
+}
+
+// validateAgainstType checks the score against the type's score scale (the
+// default scale for untyped entries) and that number-typed fields contain
+// parseable numeric values. Unknown field keys are silently ignored for
+// forward compatibility.
+func (s *EntryService) validateAgainstType(
 	ctx context.Context,
 	typeID *uuid.UUID,
+	score int,
 	additionalFields map[string]string,
 ) error {
-	if typeID == nil || len(additionalFields) == 0 {
-		return nil
+	if typeID == nil {
+		return validateScore(DefaultScoreScale, score)
 	}
 
 	entryType, err := s.typeRepo.GetTypeByID(ctx, *typeID)
 	if err != nil {
-		return fmt.Errorf("failed to fetch type for field validation: %w", err)
+		return fmt.Errorf("failed to fetch type for validation: %w", err)
+	}
+
+	if err := validateScore(entryType.ScoreScale, score); err != nil {
+		return err
 	}
 
 	fieldsByKey := make(map[string]repository.FieldDefinition, len(entryType.Fields))
@@ -58,9 +144,14 @@ func (s *EntryService) validateAdditionalFields(
 		fieldsByKey[f.Key] = f
 	}
 
+	var unknown []string
 	for key, value := range additionalFields {
 		fieldDef, known := fieldsByKey[key]
-		if !known || value == "" {
+		if !known {
+			unknown = append(unknown, key)
+			continue
+		}
+		if value == "" {
 			continue
 		}
 		if fieldDef.Type == "number" {
@@ -70,21 +161,61 @@ func (s *EntryService) validateAdditionalFields(
 		}
 	}
 
+	sort.Strings(unknown)
+	for _, key := range unknown {
+		warn(ctx, Warning{
+			Code:    WarningUnknownField,
+			Message: fmt.Sprintf("type %q has no field %q", entryType.Name, key),
+			Field:   "additional_fields." + key,
+		})
+	}
+
 	return nil
 }
 
-// CreateEntry creates a new entry with validation
+// CreateEntry creates a new entry with validation. Without a collection it
+// is filed in the suggested one when the user turned that on, otherwise the
+// suggestion is returned as a warning; see resolveCollection.
 func (s *EntryService) CreateEntry(
 	ctx context.Context,
 	userID uuid.UUID,
 	collectionID *uuid.UUID,
 	typeID *uuid.UUID,
-	title, description string,
-	score int,
+	title, description, originalTitle, language string,
+	score, priority int,
+	visibility string, // "" for private
+	date time.Time,
+	additionalFields map[string]string,
+	images []repository.EntryImage,
+	seedImageIDs []uuid.UUID,
+	device *repository.Device, // nil when the client didn't say
+) (*repository.Entry, error) {
+	if collectionID == nil {
+		resolved, err := s.resolveCollection(ctx, userID, typeID)
+		if err != nil {
+			return nil, err
+		}
+		collectionID = resolved
+	}
+	return s.CreateEntryWithID(ctx, nil, userID, collectionID, typeID, title, description, originalTitle, language, score, priority, visibility, date, additionalFields, images, seedImageIDs, device)
+}
+
+// CreateEntryWithID creates an entry with a client-chosen ID (offline-created
+// records), or a generated one if id is nil.
+func (s *EntryService) CreateEntryWithID(
+	ctx context.Context,
+	id *uuid.UUID,
+	userID uuid.UUID,
+	collectionID *uuid.UUID,
+	typeID *uuid.UUID,
+	title, description, originalTitle, language string,
+	score, priority int,
+	visibility string, // "" for private
 	date time.Time,
 	additionalFields map[string]string,
 	images []repository.EntryImage,
 	seedImageIDs []uuid.UUID,
+	device *repository.Device, // nil when the client didn't say
 ) (*repository.Entry, error) {
 	// Validate title
 	title = strings.TrimSpace(title)
@@ -98,38 +229,78 @@ func (s *EntryService) CreateEntry(
 		return nil, ErrInvalidDescription
 	}
 
-	// Validate score
-	if score < 0 || score > 3 {
-		return nil, ErrInvalidScore
+	// Validate score and additional field values against the type
+	if err := s.validateAgainstType(ctx, typeID, score, additionalFields); err != nil {
+		return nil, err
+	}
+
+	if err := validatePriority(priority); err != nil {
+		return nil, err
+	}
+
+	if visibility == "" {
+		visibility = repository.VisibilityPrivate
+	}
+	if err := validateVisibility(visibility); err != nil {
+		return nil, err
 	}
 
-	// Validate additional field values against the type's field schema
-	if err := s.validateAdditionalFields(ctx, typeID, additionalFields); err != nil {
+	originalTitle, err := normalizeOriginalTitle(originalTitle)
+	if err != nil {
+		return nil, err
+	}
+	language, err = NormalizeLanguage(language)
+	if err != nil {
 		return nil, err
 	}
 
-	// Validate collection ownership if provided
+	// Validate collection access if provided
 	if collectionID != nil {
-		collection, err := s.collectionRepo.GetCollectionByID(ctx, *collectionID)
+		collection, err := s.checkCollectionAccess(ctx, userID, *collectionID)
+		if err != nil {
+			return nil, err
+		}
+		if err := checkAllowedType(collection, typeID); err != nil {
+			return nil, err
+		}
+	}
+
+	// Check quotas
+	if err := checkQuota(QuotaImagesPerEntry, s.quotas.MaxImagesPerEntry, max(len(images), len(seedImageIDs))); err != nil {
+		return nil, err
+	}
+	warnLargeImages(ctx, images)
+	if s.quotas.MaxEntries > 0 {
+		count, err := s.entryRepo.CountEntries(ctx, userID)
 		if err != nil {
-			return nil, fmt.Errorf("invalid collection: %w", err)
+			return nil, err
 		}
-		if collection.UserID != userID {
-			return nil, repository.ErrCollectionNotFound
+		if err := checkQuota(QuotaEntries, s.quotas.MaxEntries, count+1); err != nil {
+			return nil, err
 		}
 	}
 
 	// Create entry
+	if id == nil {
+		generated := s.ids.NewID()
+		id = &generated
+	}
 	entry, err := s.entryRepo.CreateEntry(
 		ctx,
+		id,
 		userID,
 		collectionID,
 		typeID,
 		title,
 		description,
+		originalTitle,
+		language,
 		score,
+		priority,
+		visibility,
 		date,
 		additionalFields,
+		device,
 	)
 	if err != nil {
 		return nil, err
@@ -145,9 +316,11 @@ func (s *EntryService) CreateEntry(
 			return nil, fmt.Errorf("failed to save images: %w", err)
 		}
 	} else if len(seedImageIDs) > 0 {
-		if err := s.entryRepo.CopySeedImagesToEntry(ctx, entry.ID, seedImageIDs); err != nil {
+		missing, err := s.entryRepo.CopySeedImagesToEntry(ctx, entry.ID, seedImageIDs)
+		if err != nil {
 			return nil, fmt.Errorf("failed to copy seed images: %w", err)
 		}
+		warnMissingSeedImages(ctx, seedImageIDs, missing)
 	}
 
 	return entry, nil
@@ -165,15 +338,170 @@ func (s *EntryService) GetEntriesByUserID(
 	collectionID *uuid.UUID,
 	limit, offset int,
 ) ([]*repository.Entry, error) {
-	// Default pagination
-	if limit <= 0 {
-		limit = 50
+	limit = listLimit(limit)
+
+	return s.entryRepo.GetEntriesByUserID(ctx, userID, collectionID, limit, offset)
+}
+
+// GetEntriesSummary returns the number of entries, optionally in a
+// collection, and when the latest of them changed.
+func (s *EntryService) GetEntriesSummary(
+	ctx context.Context,
+	userID uuid.UUID,
+	collectionID *uuid.UUID,
+) (int, *time.Time, error) {
+	return s.entryRepo.GetEntriesSummary(ctx, userID, collectionID)
+}
+
+// ListEntriesChangedSince returns ids and update times of entries changed after since.
+func (s *EntryService) ListEntriesChangedSince(
+	ctx context.Context,
+	userID uuid.UUID,
+	since time.Time,
+) ([]repository.EntryChange, error) {
+	return s.entryRepo.ListEntriesChangedSince(ctx, userID, since)
+}
+
+// ListEntriesWithImages retrieves entries with their image metadata in a single query
+func (s *EntryService) ListEntriesWithImages(
+	ctx context.Context,
+	userID uuid.UUID,
+	filter repository.EntryFilter,
+	limit, offset int,
+) ([]*repository.EntryWithImages, error) {
+	filter, err := checkEntryFilter(filter)
+	if err != nil {
+		return nil, err
+	}
+
+	limit = listLimit(limit)
+
+	return s.entryRepo.ListEntriesWithImages(ctx, userID, filter, limit, offset)
+}
+
+// RecordEntryView notes that the user opened the entry and returns when, so
+// it leads their recent entries on every device.
+func (s *EntryService) RecordEntryView(ctx context.Context, id, userID uuid.UUID) (time.Time, error) {
+	return s.entryRepo.RecordEntryView(ctx, userID, id)
+}
+
+// ListRecentEntries returns the entries the user opened most recently,
+// optionally only those in one collection.
+func (s *EntryService) ListRecentEntries(
+	ctx context.Context,
+	userID uuid.UUID,
+	collectionID *uuid.UUID,
+	limit int,
+) ([]*repository.RecentEntry, error) {
+	limit = listLimit(limit)
+
+	return s.entryRepo.ListRecentEntries(ctx, userID, collectionID, limit)
+}
+
+// checkEntryFilter validates a list filter and defaults an empty sort to
+// newest first. Positions are per collection, so manual order is only
+// defined within one.
+func checkEntryFilter(filter repository.EntryFilter) (repository.EntryFilter, error) {
+	if err := validatePriority(filter.MinPriority); err != nil {
+		return filter, err
 	}
-	if limit > 100 {
-		limit = 100
+
+	switch filter.Sort {
+	case "":
+		filter.Sort = repository.EntrySortCreated
+	case repository.EntrySortCreated, repository.EntrySortPriority, repository.EntrySortTitle:
+	case repository.EntrySortManual:
+		if filter.CollectionID == nil {
+			return filter, ErrManualSortScope
+		}
+	default:
+		return filter, ErrInvalidSort
 	}
+	return filter, nil
+}
 
-	return s.entryRepo.GetEntriesByUserID(ctx, userID, collectionID, limit, offset)
+// normalizeOriginalTitle trims the original title, which may be empty.
+func normalizeOriginalTitle(originalTitle string) (string, error) {
+	originalTitle = strings.TrimSpace(originalTitle)
+	if len(originalTitle) > 200 {
+		return "", ErrInvalidOriginalTitle
+	}
+	return originalTitle, nil
+}
+
+// languageTagPattern loosely matches a BCP 47 tag: a 2-3 letter language
+// followed by optional subtags.
+var languageTagPattern = regexp.MustCompile(`^[A-Za-z]{2,3}(-[A-Za-z0-9]{1,8})*$`)
+
+// NormalizeLanguage returns a BCP 47 tag in canonical case ("pt_br" becomes
+// "pt-BR"), or "" for an empty tag.
+func NormalizeLanguage(tag string) (string, error) {
+	tag = strings.ReplaceAll(strings.TrimSpace(tag), "_", "-")
+	if tag == "" {
+		return "", nil
+	}
+	if len(tag) > 35 || !languageTagPattern.MatchString(tag) {
+		return "", ErrInvalidLanguage
+	}
+
+	subtags := strings.Split(tag, "-")
+	subtags[0] = strings.ToLower(subtags[0])
+	for i := 1; i < len(subtags); i++ {
+		switch len(subtags[i]) {
+		case 2: // region
+			subtags[i] = strings.ToUpper(subtags[i])
+		case 4: // script
+			subtags[i] = strings.ToUpper(subtags[i][:1]) + strings.ToLower(subtags[i][1:])
+		default:
+			subtags[i] = strings.ToLower(subtags[i])
+		}
+	}
+	return strings.Join(subtags, "-"), nil
+}
+
+func validatePriority(priority int) error {
+	if priority < 0 || priority > MaxPriority {
+		return ErrInvalidPriority
+	}
+	return nil
+}
+
+func validateVisibility(visibility string) error {
+	switch visibility {
+	case repository.VisibilityPrivate, repository.VisibilityShared, repository.VisibilityPublic:
+		return nil
+	}
+	return ErrInvalidVisibility
+}
+
+// SetEntryOrder ranks a collection's entries in the given order. Entries of
+// the collection that aren't listed become unranked.
+func (s *EntryService) SetEntryOrder(
+	ctx context.Context,
+	userID, collectionID uuid.UUID,
+	ids []uuid.UUID,
+) error {
+	collection, err := s.collectionRepo.GetCollectionByID(ctx, collectionID)
+	if err != nil {
+		return err
+	}
+	if collection.UserID != userID {
+		return repository.ErrCollectionNotFound
+	}
+
+	seen := make(map[uuid.UUID]bool, len(ids))
+	for _, id := range ids {
+		if seen[id] {
+			return ErrInvalidEntryOrder
+		}
+		seen[id] = true
+	}
+
+	err = s.entryRepo.SetEntryOrder(ctx, userID, collectionID, ids)
+	if errors.Is(err, repository.ErrEntryNotInCollection) {
+		return fmt.Errorf("%w: %v", ErrInvalidEntryOrder, err)
+	}
+	return err
 }
 
 // GetEntryByID retrieves a single entry
@@ -195,7 +523,9 @@ func (s *EntryService) GetEntryByID(
 	return entry, nil
 }
 
-// UpdateEntry updates an entry with validation
+// UpdateEntry updates an entry with validation. When device follows another
+// device's write too closely it also returns that write, see
+// ConcurrentUpdate.
 func (s *EntryService) UpdateEntry(
 	ctx context.Context,
 	id uuid.UUID,
@@ -203,64 +533,104 @@ func (s *EntryService) UpdateEntry(
 	collectionID *uuid.UUID,
 	typeID *uuid.UUID,
 	title, description string,
+	originalTitle, language *string, // nil keeps the current value
 	score int,
+	priority *int, // nil keeps the current priority
+	visibility *string, // nil keeps the current visibility
 	date time.Time,
 	additionalFields map[string]string,
 	images []repository.EntryImage,
-) (*repository.Entry, error) {
+	device *repository.Device, // nil when the client didn't say
+) (*repository.Entry, *ConcurrentUpdate, error) {
 	// Check ownership
-	_, err := s.GetEntryByID(ctx, id, userID)
+	existing, err := s.GetEntryByID(ctx, id, userID)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 
 	// Validate title
 	title = strings.TrimSpace(title)
 	if len(title) < 1 || len(title) > 200 {
-		return nil, ErrInvalidTitle
+		return nil, nil, ErrInvalidTitle
 	}
 
 	// Validate description
 	description = strings.TrimSpace(description)
 	if len(description) < 1 || len(description) > 2000 {
-		return nil, ErrInvalidDescription
+		return nil, nil, ErrInvalidDescription
 	}
 
-	// Validate score
-	if score < 0 || score > 3 {
-		return nil, ErrInvalidScore
+	// Validate score and additional field values against the type
+	if err := s.validateAgainstType(ctx, typeID, score, additionalFields); err != nil {
+		return nil, nil, err
 	}
 
-	// Validate additional field values against the type's field schema
-	if err := s.validateAdditionalFields(ctx, typeID, additionalFields); err != nil {
-		return nil, err
+	if priority != nil {
+		if err := validatePriority(*priority); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	if visibility != nil {
+		if err := validateVisibility(*visibility); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	if originalTitle != nil {
+		normalized, err := normalizeOriginalTitle(*originalTitle)
+		if err != nil {
+			return nil, nil, err
+		}
+		originalTitle = &normalized
+	}
+	if language != nil {
+		normalized, err := NormalizeLanguage(*language)
+		if err != nil {
+			return nil, nil, err
+		}
+		language = &normalized
 	}
 
-	// Validate collection ownership if provided
+	// Validate collection access if provided; entries already filed keep
+	// their place when the collection's allowed types change later
 	if collectionID != nil {
-		collection, err := s.collectionRepo.GetCollectionByID(ctx, *collectionID)
+		collection, err := s.checkCollectionAccess(ctx, userID, *collectionID)
 		if err != nil {
-			return nil, fmt.Errorf("invalid collection: %w", err)
+			return nil, nil, err
 		}
-		if collection.UserID != userID {
-			return nil, repository.ErrCollectionNotFound
+		if !sameID(existing.CollectionID, collectionID) || !sameID(existing.TypeID, typeID) {
+			if err := checkAllowedType(collection, typeID); err != nil {
+				return nil, nil, err
+			}
 		}
 	}
 
+	// Check quotas
+	if err := checkQuota(QuotaImagesPerEntry, s.quotas.MaxImagesPerEntry, len(images)); err != nil {
+		return nil, nil, err
+	}
+	warnLargeImages(ctx, images)
+
 	// Update entry
-	entry, err := s.entryRepo.UpdateEntry(
+	entry, prev, err := s.entryRepo.UpdateEntry(
 		ctx,
 		id,
 		collectionID,
 		typeID,
 		title,
 		description,
+		originalTitle,
+		language,
 		score,
+		priority,
+		visibility,
 		date,
 		additionalFields,
+		device,
 	)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 
 	// Update images if provided
@@ -270,11 +640,11 @@ func (s *EntryService) UpdateEntry(
 			images[i].EntryID = entry.ID
 		}
 		if err := s.entryRepo.SaveEntryImages(ctx, entry.ID, images); err != nil {
-			return nil, fmt.Errorf("failed to update images: %w", err)
+			return nil, nil, fmt.Errorf("failed to update images: %w", err)
 		}
 	}
 
-	return entry, nil
+	return entry, concurrentUpdate(device, prev, entry.UpdatedAt, s.concurrentWindow), nil
 }
 
 // DeleteEntry deletes an entry
@@ -298,6 +668,73 @@ func (s *EntryService) DeleteEntries(ctx context.Context, ids []uuid.UUID, userI
 	return s.entryRepo.DeleteEntriesByIDs(ctx, ids, userID)
 }
 
+// ScoreUpdate is one score of SetScores.
+type ScoreUpdate struct {
+	EntryID uuid.UUID
+	Score   int
+}
+
+// SetScores rates many of the user's entries at once, e.g. while going
+// through an imported library. It returns one result per update: nil if it
+// was saved, otherwise why it was rejected. Rejected updates don't stop the
+// others, which are saved in one transaction.
+func (s *EntryService) SetScores(ctx context.Context, userID uuid.UUID, updates []ScoreUpdate) ([]error, error) {
+	ids := make([]uuid.UUID, len(updates))
+	for i, u := range updates {
+		ids[i] = u.EntryID
+	}
+	scales, err := s.entryRepo.ListEntryScoreScales(ctx, userID, ids)
+	if err != nil {
+		return nil, err
+	}
+
+	results := make([]error, len(updates))
+	validIDs := make([]uuid.UUID, 0, len(updates))
+	validScores := make([]int, 0, len(updates))
+	for i, u := range updates {
+		typeScale, ok := scales[u.EntryID]
+		if !ok {
+			results[i] = repository.ErrEntryNotFound
+			continue
+		}
+		scale := DefaultScoreScale
+		if typeScale != nil {
+			scale = *typeScale
+		}
+		if err := validateScore(scale, u.Score); err != nil {
+			results[i] = err
+			continue
+		}
+		validIDs = append(validIDs, u.EntryID)
+		validScores = append(validScores, u.Score)
+	}
+
+	if len(validIDs) > 0 {
+		if _, err := s.entryRepo.SetEntryScores(ctx, userID, validIDs, validScores); err != nil {
+			return nil, err
+		}
+	}
+	return results, nil
+}
+
+// SetEntryCover makes one of the entry's images its cover.
+func (s *EntryService) SetEntryCover(
+	ctx context.Context,
+	id uuid.UUID,
+	userID uuid.UUID,
+	imageID uuid.UUID,
+) (*repository.Entry, error) {
+	// Check ownership
+	if _, err := s.GetEntryByID(ctx, id, userID); err != nil {
+		return nil, err
+	}
+
+	if err := s.entryRepo.SetEntryCover(ctx, id, imageID); err != nil {
+		return nil, err
+	}
+	return s.entryRepo.GetEntryByID(ctx, id)
+}
+
 // GetImageByID retrieves a single image by ID without ownership check.
 // Images are served on a public endpoint — access control is by UUID obscurity.
 func (s *EntryService) GetImageByID(
@@ -315,12 +752,40 @@ func (s *EntryService) GetEntryImageMetas(
 	return s.entryRepo.GetEntryImageMetas(ctx, entryID)
 }
 
-// GetImageMetasByEntryIDs returns a map of entry ID -> image metadata for multiple entries
+// GetScoreHistory returns the scores a single entry has had, oldest first
+func (s *EntryService) GetScoreHistory(
+	ctx context.Context,
+	entryID uuid.UUID,
+) ([]repository.ScoreChange, error) {
+	return s.entryRepo.GetScoreHistory(ctx, entryID)
+}
+
+// GetImageMetasByEntryIDs returns a map of entry ID -> image metadata for
+// multiple entries; with coversOnly just each entry's cover.
 func (s *EntryService) GetImageMetasByEntryIDs(
 	ctx context.Context,
 	entryIDs []uuid.UUID,
+	coversOnly bool,
 ) (map[uuid.UUID][]repository.ImageMeta, error) {
-	return s.entryRepo.GetImageMetasByEntryIDs(ctx, entryIDs)
+	return s.entryRepo.GetImageMetasByEntryIDs(ctx, entryIDs, coversOnly)
+}
+
+// ListEntryFields is ListEntriesWithImages reading only the given fields.
+func (s *EntryService) ListEntryFields(
+	ctx context.Context,
+	userID uuid.UUID,
+	filter repository.EntryFilter,
+	fields repository.EntryFields,
+	limit, offset int,
+) ([]*repository.EntryWithImages, error) {
+	filter, err := checkEntryFilter(filter)
+	if err != nil {
+		return nil, err
+	}
+
+	limit = listLimit(limit)
+
+	return s.entryRepo.ListEntryFields(ctx, userID, filter, fields, limit, offset)
 }
 
 // SearchEntries searches entries by query
@@ -330,13 +795,7 @@ func (s *EntryService) SearchEntries(
 	query string,
 	limit, offset int,
 ) ([]*repository.Entry, error) {
-	// Default pagination
-	if limit <= 0 {
-		limit = 50
-	}
-	if limit > 100 {
-		limit = 100
-	}
+	limit = listLimit(limit)
 
 	query = strings.TrimSpace(query)
 	if query == "" {
@@ -345,3 +804,21 @@ func (s *EntryService) SearchEntries(
 
 	return s.entryRepo.SearchEntries(ctx, userID, query, limit, offset)
 }
+
+// SearchEntryFields is SearchEntries reading only the given fields.
+func (s *EntryService) SearchEntryFields(
+	ctx context.Context,
+	userID uuid.UUID,
+	query string,
+	fields repository.EntryFields,
+	limit, offset int,
+) ([]*repository.EntryWithImages, error) {
+	limit = listLimit(limit)
+
+	query = strings.TrimSpace(query)
+	if query == "" {
+		return s.entryRepo.ListEntryFields(ctx, userID, repository.EntryFilter{}, fields, limit, offset)
+	}
+
+	return s.entryRepo.SearchEntryFields(ctx, userID, query, fields, limit, offset)
+}
@@ -0,0 +1,720 @@
+package service
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/avalarin/livlog/backend/internal/pdf"
+	"github.com/avalarin/livlog/backend/internal/repository"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+)
+
+var (
+	ErrInvalidExportScope   = errors.New("exactly one of collection_id or year is required")
+	ErrInvalidExportYear    = errors.New("year must be between 1900 and 2100")
+	ErrInvalidExportFormat  = errors.New("format must be json or csv")
+	ErrTooManyExportEntries = fmt.Errorf("at most %d entries can be selected", exportMaxSelectedEntries)
+	ErrExportTooLarge       = fmt.Errorf("export with images is larger than %d MB", exportMaxArchiveBytes>>20)
+)
+
+const (
+	ExportFormatPDF  = "pdf"
+	ExportFormatJSON = "json"
+	ExportFormatCSV  = "csv"
+
+	// exportMaxEntries caps the size of one PDF export; JSON exports of the
+	// whole account allow more.
+	exportMaxEntries     = 1000
+	exportMaxJSONEntries = 20000
+	// exportMaxSelectedEntries caps entry_ids of one export request.
+	exportMaxSelectedEntries = 1000
+	// exportMaxArchiveBytes caps exports that include images; the file is
+	// kept in the database until it expires.
+	exportMaxArchiveBytes = 512 << 20
+	// exportClaimLease must outlast rendering one export; exports still running
+	// after it are assumed abandoned and picked up again.
+	exportClaimLease   = 10 * time.Minute
+	exportMaxAttempts  = 3
+	exportRetention    = 7 * 24 * time.Hour
+	exportCoverMaxSide = 300 // pixels
+)
+
+// ExportService renders exports in the background. Requests are queued and
+// picked up by ProcessPending; when a file is ready the user gets an inbox
+// notification with its download path.
+type ExportService struct {
+	exportRepo          *repository.ExportRepository
+	entryRepo           *repository.EntryRepository
+	collectionRepo      *repository.CollectionRepository
+	typeRepo            *repository.TypeRepository
+	notificationService *NotificationService
+	clock               Clock
+	logger              *zap.Logger
+}
+
+func NewExportService(
+	exportRepo *repository.ExportRepository,
+	entryRepo *repository.EntryRepository,
+	collectionRepo *repository.CollectionRepository,
+	typeRepo *repository.TypeRepository,
+	notificationService *NotificationService,
+	clock Clock,
+	logger *zap.Logger,
+) *ExportService {
+	return &ExportService{
+		exportRepo:          exportRepo,
+		entryRepo:           entryRepo,
+		collectionRepo:      collectionRepo,
+		typeRepo:            typeRepo,
+		notificationService: notificationService,
+		clock:               clock,
+		logger:              logger,
+	}
+}
+
+// RequestPDFExport queues a PDF of one of the user's collections or of the
+// entries dated in one year.
+func (s *ExportService) RequestPDFExport(
+	ctx context.Context,
+	userID uuid.UUID,
+	collectionID *uuid.UUID,
+	year *int,
+) (*repository.Export, error) {
+	if (collectionID == nil) == (year == nil) {
+		return nil, ErrInvalidExportScope
+	}
+
+	if year != nil && (*year < 1900 || *year > 2100) {
+		return nil, ErrInvalidExportYear
+	}
+
+	if collectionID != nil {
+		collection, err := s.collectionRepo.GetCollectionByID(ctx, *collectionID)
+		if err != nil {
+			return nil, err
+		}
+		if collection.UserID != userID {
+			return nil, repository.ErrCollectionNotFound
+		}
+	}
+
+	return s.exportRepo.CreateExport(ctx, userID, ExportFormatPDF, collectionID, year, nil, false)
+}
+
+// ExportSelection narrows a JSON or CSV export. Nil fields leave that filter
+// out; IncludeImages packs the file with the entries' images into a ZIP.
+type ExportSelection struct {
+	CollectionID  *uuid.UUID
+	Year          *int
+	EntryIDs      []uuid.UUID
+	IncludeImages bool
+}
+
+// RequestExport queues a JSON or CSV export of the selected entries. JSON
+// exports also hold the user's collections and own types; images are listed
+// by id and can be fetched from /images/{id} unless included.
+func (s *ExportService) RequestExport(
+	ctx context.Context,
+	userID uuid.UUID,
+	format string,
+	sel ExportSelection,
+) (*repository.Export, error) {
+	if format != ExportFormatJSON && format != ExportFormatCSV {
+		return nil, ErrInvalidExportFormat
+	}
+
+	if sel.Year != nil && (*sel.Year < 1900 || *sel.Year > 2100) {
+		return nil, ErrInvalidExportYear
+	}
+
+	if sel.CollectionID != nil {
+		collection, err := s.collectionRepo.GetCollectionByID(ctx, *sel.CollectionID)
+		if err != nil {
+			return nil, err
+		}
+		if collection.UserID != userID {
+			return nil, repository.ErrCollectionNotFound
+		}
+	}
+
+	if len(sel.EntryIDs) > 0 {
+		ids := dedupeIDs(sel.EntryIDs)
+		if len(ids) > exportMaxSelectedEntries {
+			return nil, ErrTooManyExportEntries
+		}
+		count, err := s.entryRepo.CountEntriesByIDs(ctx, userID, ids)
+		if err != nil {
+			return nil, err
+		}
+		if count != len(ids) {
+			return nil, repository.ErrEntryNotFound
+		}
+		sel.EntryIDs = ids
+	}
+
+	return s.exportRepo.CreateExport(ctx, userID, format, sel.CollectionID, sel.Year, sel.EntryIDs, sel.IncludeImages)
+}
+
+// ExportFileType returns the content type and file extension of an export's
+// download.
+func ExportFileType(export *repository.Export) (string, string) {
+	switch {
+	case export.IncludeImages:
+		return "application/zip", "zip"
+	case export.Format == ExportFormatJSON:
+		return "application/json", "json"
+	case export.Format == ExportFormatCSV:
+		return "text/csv; charset=utf-8", "csv"
+	default:
+		return "application/pdf", "pdf"
+	}
+}
+
+// GetExport returns a user's export.
+func (s *ExportService) GetExport(ctx context.Context, id, userID uuid.UUID) (*repository.Export, error) {
+	return s.exportRepo.GetExportByID(ctx, id, userID)
+}
+
+// GetExportFile returns the file of a user's export once it is done.
+func (s *ExportService) GetExportFile(ctx context.Context, id, userID uuid.UUID) ([]byte, error) {
+	return s.exportRepo.GetExportFile(ctx, id, userID)
+}
+
+// ProcessPending renders queued exports one at a time until none are left.
+func (s *ExportService) ProcessPending(ctx context.Context) error {
+	for ctx.Err() == nil {
+		export, err := s.exportRepo.ClaimExport(ctx, exportClaimLease)
+		if err != nil {
+			return err
+		}
+		if export == nil {
+			return nil
+		}
+
+		s.process(ctx, export)
+	}
+	return ctx.Err()
+}
+
+// CleanupFinished removes exports finished longer ago than the retention period.
+func (s *ExportService) CleanupFinished(ctx context.Context) (int64, error) {
+	return s.exportRepo.DeleteFinishedExports(ctx, exportRetention)
+}
+
+func (s *ExportService) process(ctx context.Context, export *repository.Export) {
+	fields := []zap.Field{
+		zap.String("export_id", export.ID.String()),
+		zap.String("user_id", export.UserID.String()),
+		zap.Int("attempt", export.Attempts),
+	}
+
+	// Claimed again after a worker died rendering it; don't retry forever
+	if export.Attempts > exportMaxAttempts {
+		s.logger.Warn("export abandoned after repeated attempts", fields...)
+		if err := s.exportRepo.FailExport(ctx, export.ID, "export did not finish"); err != nil {
+			s.logger.Error("failed to record export failure", append(fields, zap.Error(err))...)
+		}
+		return
+	}
+
+	var title string
+	var data []byte
+	var err error
+	switch export.Format {
+	case ExportFormatJSON, ExportFormatCSV:
+		title, data, err = s.renderData(ctx, export)
+	default:
+		title, data, err = s.renderPDF(ctx, export)
+	}
+	if err != nil {
+		s.logger.Warn("export failed", append(fields, zap.Error(err))...)
+		if err := s.exportRepo.FailExport(ctx, export.ID, err.Error()); err != nil {
+			s.logger.Error("failed to record export failure", append(fields, zap.Error(err))...)
+		}
+		return
+	}
+
+	if err := s.exportRepo.CompleteExport(ctx, export.ID, data); err != nil {
+		s.logger.Error("failed to save export", append(fields, zap.Error(err))...)
+		return
+	}
+
+	s.logger.Info("export finished", append(fields, zap.Int("bytes", len(data)))...)
+
+	// The path is relative to the API base (e.g. /api/v1)
+	heading := "Your PDF is ready"
+	if export.Format != ExportFormatPDF {
+		heading = "Your export is ready"
+	}
+	_, err = s.notificationService.Notify(ctx, export.UserID, NotificationExportReady,
+		heading,
+		title+" is ready to download for the next 7 days.",
+		map[string]interface{}{
+			"export_id": export.ID.String(),
+			"job_id":    export.ID.String(),
+			"url":       "/exports/" + export.ID.String() + "/download",
+		},
+	)
+	if err != nil {
+		s.logger.Error("failed to notify about export", append(fields, zap.Error(err))...)
+	}
+}
+
+// renderPDF builds the export file and returns it with the document title.
+func (s *ExportService) renderPDF(ctx context.Context, export *repository.Export) (string, []byte, error) {
+	if export.Year == nil && export.CollectionID == nil {
+		// collection_id is cleared when the collection is deleted
+		return "", nil, repository.ErrCollectionNotFound
+	}
+	title, err := s.exportTitle(ctx, export)
+	if err != nil {
+		return "", nil, err
+	}
+
+	entries, err := s.entryRepo.ListEntriesForExport(ctx, export.UserID, export.CollectionID, export.Year, export.EntryIDs, exportMaxEntries)
+	if err != nil {
+		return "", nil, err
+	}
+
+	types, err := s.typeRepo.GetAllTypes(ctx, export.UserID)
+	if err != nil {
+		return "", nil, err
+	}
+	scales := make(map[uuid.UUID]repository.ScoreScale, len(types))
+	for _, t := range types {
+		scales[t.ID] = t.ScoreScale
+	}
+
+	doc := pdf.New()
+	layout := newPDFLayout(doc)
+	layout.header(title, len(entries))
+
+	progress := newExportProgress(s, export.ID, len(entries))
+	for i, entry := range entries {
+		progress.report(ctx, i)
+
+		var cover *pdf.Image
+		if entry.CoverImageID != nil {
+			cover, err = s.loadCover(ctx, doc, *entry.CoverImageID)
+			if err != nil {
+				// A broken image shouldn't fail the whole export
+				s.logger.Warn("skipping export cover", zap.String("entry_id", entry.ID.String()), zap.Error(err))
+			}
+		}
+		scale := DefaultScoreScale
+		if entry.TypeID != nil {
+			if typeScale, ok := scales[*entry.TypeID]; ok {
+				scale = typeScale
+			}
+		}
+		layout.entry(entry.Entry, cover, ScoreLabel(scale, entry.Score))
+	}
+
+	var buf bytes.Buffer
+	if _, err := doc.WriteTo(&buf); err != nil {
+		return "", nil, fmt.Errorf("failed to write pdf: %w", err)
+	}
+
+	return title, buf.Bytes(), nil
+}
+
+// jsonExport is the document written by JSON exports and read by
+// ImportService. SystemTypes are listed so another server, where system
+// types have other ids, can match them by name.
+type jsonExport struct {
+	ExportedAt  time.Time                `json:"exported_at"`
+	Collections []*repository.Collection `json:"collections"`
+	Types       []*repository.EntryType  `json:"types"`
+	SystemTypes []*repository.EntryType  `json:"system_types"`
+	Entries     []jsonExportEntry        `json:"entries"`
+}
+
+type jsonExportEntry struct {
+	*repository.Entry
+	Images []repository.ImageMeta `json:"images"`
+}
+
+// renderData builds a JSON or CSV export, packed into a ZIP with the
+// entries' images when asked, and returns it with a title for the
+// notification.
+func (s *ExportService) renderData(ctx context.Context, export *repository.Export) (string, []byte, error) {
+	title, err := s.exportTitle(ctx, export)
+	if err != nil {
+		return "", nil, err
+	}
+
+	entries, err := s.entryRepo.ListEntriesForExport(ctx, export.UserID, export.CollectionID, export.Year, export.EntryIDs, exportMaxJSONEntries)
+	if err != nil {
+		return "", nil, err
+	}
+
+	var data []byte
+	if export.Format == ExportFormatCSV {
+		data, err = encodeExportCSV(entries)
+	} else {
+		data, err = s.encodeExportJSON(ctx, export, entries)
+	}
+	if err != nil {
+		return "", nil, err
+	}
+
+	if export.IncludeImages {
+		data, err = s.zipWithImages(ctx, export, "livlog."+export.Format, data, entries)
+		if err != nil {
+			return "", nil, err
+		}
+	}
+
+	return title, data, nil
+}
+
+// encodeExportJSON writes the user's collections, own and system types and
+// the entries.
+func (s *ExportService) encodeExportJSON(
+	ctx context.Context,
+	export *repository.Export,
+	entries []*repository.EntryWithImages,
+) ([]byte, error) {
+	collections, err := s.collectionRepo.GetCollectionsByUserID(ctx, export.UserID)
+	if err != nil {
+		return nil, err
+	}
+	if collections == nil {
+		collections = []*repository.Collection{}
+	}
+	if export.CollectionID != nil {
+		filtered := []*repository.Collection{}
+		for _, c := range collections {
+			if c.ID == *export.CollectionID {
+				filtered = append(filtered, c)
+			}
+		}
+		collections = filtered
+	}
+
+	allTypes, err := s.typeRepo.GetAllTypes(ctx, export.UserID)
+	if err != nil {
+		return nil, err
+	}
+	types, systemTypes := []*repository.EntryType{}, []*repository.EntryType{}
+	for _, t := range allTypes {
+		if t.UserID != nil {
+			types = append(types, t)
+		} else {
+			systemTypes = append(systemTypes, t)
+		}
+	}
+
+	doc := jsonExport{
+		ExportedAt:  s.clock.Now().UTC(),
+		Collections: collections,
+		Types:       types,
+		SystemTypes: systemTypes,
+		Entries:     make([]jsonExportEntry, len(entries)),
+	}
+	for i, e := range entries {
+		images := e.Images
+		if images == nil {
+			images = []repository.ImageMeta{}
+		}
+		doc.Entries[i] = jsonExportEntry{Entry: e.Entry, Images: images}
+	}
+
+	data, err := json.MarshalIndent(doc, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal export: %w", err)
+	}
+	return data, nil
+}
+
+// exportCSVHeader lists the columns of CSV exports, one row per entry.
+var exportCSVHeader = []string{
+	"id", "collection_id", "type_id", "title", "original_title", "language",
+	"description", "score", "priority", "visibility", "date",
+	"additional_fields", "image_ids", "created_at", "updated_at",
+}
+
+// encodeExportCSV writes one row per entry. Additional fields are a JSON
+// object and image ids are separated by spaces, in position order.
+func encodeExportCSV(entries []*repository.EntryWithImages) ([]byte, error) {
+	var buf bytes.Buffer
+	w := csv.NewWriter(&buf)
+	if err := w.Write(exportCSVHeader); err != nil {
+		return nil, fmt.Errorf("failed to write csv: %w", err)
+	}
+
+	for _, e := range entries {
+		fields, err := json.Marshal(e.AdditionalFields)
+		if err != nil {
+			return nil, fmt.Errorf("failed to marshal additional fields: %w", err)
+		}
+		imageIDs := make([]string, len(e.Images))
+		for i, img := range e.Images {
+			imageIDs[i] = img.ID.String()
+		}
+
+		record := []string{
+			e.ID.String(),
+			optionalUUID(e.CollectionID),
+			optionalUUID(e.TypeID),
+			e.Title,
+			e.OriginalTitle,
+			e.Language,
+			e.Description,
+			strconv.Itoa(e.Score),
+			strconv.Itoa(e.Priority),
+			e.Visibility,
+			e.Date.Format("2006-01-02"),
+			string(fields),
+			strings.Join(imageIDs, " "),
+			e.CreatedAt.UTC().Format(time.RFC3339),
+			e.UpdatedAt.UTC().Format(time.RFC3339),
+		}
+		if err := w.Write(record); err != nil {
+			return nil, fmt.Errorf("failed to write csv: %w", err)
+		}
+	}
+
+	w.Flush()
+	if err := w.Error(); err != nil {
+		return nil, fmt.Errorf("failed to write csv: %w", err)
+	}
+	return buf.Bytes(), nil
+}
+
+func optionalUUID(id *uuid.UUID) string {
+	if id == nil {
+		return ""
+	}
+	return id.String()
+}
+
+// zipWithImages packs an export file as name together with the entries'
+// images, stored as images/<id>.jpg so the ids in the file find them.
+func (s *ExportService) zipWithImages(
+	ctx context.Context,
+	export *repository.Export,
+	name string,
+	data []byte,
+	entries []*repository.EntryWithImages,
+) ([]byte, error) {
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+
+	w, err := zw.Create(name)
+	if err != nil {
+		return nil, fmt.Errorf("failed to write zip: %w", err)
+	}
+	if _, err := w.Write(data); err != nil {
+		return nil, fmt.Errorf("failed to write zip: %w", err)
+	}
+
+	progress := newExportProgress(s, export.ID, len(entries))
+	for i, entry := range entries {
+		progress.report(ctx, i)
+
+		for _, meta := range entry.Images {
+			img, err := s.entryRepo.GetImageByID(ctx, meta.ID)
+			if err != nil {
+				// An image deleted meanwhile shouldn't fail the whole export
+				s.logger.Warn("skipping export image", zap.String("image_id", meta.ID.String()), zap.Error(err))
+				continue
+			}
+			if buf.Len()+len(img.ImageData) > exportMaxArchiveBytes {
+				return nil, ErrExportTooLarge
+			}
+
+			// Images are already compressed
+			w, err := zw.CreateHeader(&zip.FileHeader{
+				Name:     "images/" + meta.ID.String() + ".jpg",
+				Method:   zip.Store,
+				Modified: img.CreatedAt,
+			})
+			if err != nil {
+				return nil, fmt.Errorf("failed to write zip: %w", err)
+			}
+			if _, err := w.Write(img.ImageData); err != nil {
+				return nil, fmt.Errorf("failed to write zip: %w", err)
+			}
+		}
+	}
+
+	if err := zw.Close(); err != nil {
+		return nil, fmt.Errorf("failed to write zip: %w", err)
+	}
+	return buf.Bytes(), nil
+}
+
+// exportTitle names what an export covers, e.g. a collection or a year.
+func (s *ExportService) exportTitle(ctx context.Context, export *repository.Export) (string, error) {
+	switch {
+	case export.Year != nil && export.CollectionID == nil:
+		return "Year " + strconv.Itoa(*export.Year), nil
+	case export.CollectionID != nil:
+		collection, err := s.collectionRepo.GetCollectionByID(ctx, *export.CollectionID)
+		if err != nil {
+			return "", err
+		}
+		if export.Year != nil {
+			return collection.Name + ", " + strconv.Itoa(*export.Year), nil
+		}
+		return collection.Name, nil
+	default:
+		return "Your data", nil
+	}
+}
+
+// exportProgress records a running export's progress every 10 percent, so
+// that large exports don't write a row per entry.
+type exportProgress struct {
+	service  *ExportService
+	exportID uuid.UUID
+	total    int
+	reported int
+}
+
+func newExportProgress(s *ExportService, exportID uuid.UUID, total int) *exportProgress {
+	return &exportProgress{service: s, exportID: exportID, total: total}
+}
+
+// report records that done of total items are finished.
+func (p *exportProgress) report(ctx context.Context, done int) {
+	if p.total == 0 {
+		return
+	}
+	percent := done * 100 / p.total
+	if percent-p.reported < 10 {
+		return
+	}
+	p.reported = percent
+
+	if err := p.service.exportRepo.SetExportProgress(ctx, p.exportID, percent); err != nil {
+		// Progress is informational; keep rendering
+		p.service.logger.Warn("failed to record export progress", zap.String("export_id", p.exportID.String()), zap.Error(err))
+	}
+}
+
+func (s *ExportService) loadCover(ctx context.Context, doc *pdf.Document, imageID uuid.UUID) (*pdf.Image, error) {
+	img, err := s.entryRepo.GetImageByID(ctx, imageID)
+	if err != nil {
+		return nil, err
+	}
+	return doc.AddImage(img.ImageData, exportCoverMaxSide)
+}
+
+// PDF layout, in points
+const (
+	pdfMargin      = 50.0
+	pdfCoverWidth  = 70.0
+	pdfCoverHeight = 100.0
+	pdfGap         = 15.0
+	pdfBodySize    = 10.0
+	pdfLineHeight  = 14.0
+)
+
+// pdfLayout places export content top to bottom, starting new pages as needed.
+type pdfLayout struct {
+	doc  *pdf.Document
+	page *pdf.Page
+	y    float64 // top of the free space on the page
+}
+
+func newPDFLayout(doc *pdf.Document) *pdfLayout {
+	l := &pdfLayout{doc: doc}
+	l.newPage()
+	return l
+}
+
+func (l *pdfLayout) newPage() {
+	l.page = l.doc.AddPage()
+	l.y = pdf.PageHeight - pdfMargin
+}
+
+// ensure starts a new page unless height points fit below the current position.
+func (l *pdfLayout) ensure(height float64) {
+	if l.y-height < pdfMargin {
+		l.newPage()
+	}
+}
+
+func (l *pdfLayout) header(title string, entries int) {
+	width := pdf.PageWidth - 2*pdfMargin
+	for _, line := range pdf.Wrap(pdf.Bold, 24, title, width) {
+		l.y -= 28
+		l.page.Text(pdfMargin, l.y, pdf.Bold, 24, line)
+	}
+
+	summary := fmt.Sprintf("%d entries", entries)
+	if entries == 1 {
+		summary = "1 entry"
+	}
+	l.y -= 20
+	l.page.Color(0.4, 0.4, 0.4)
+	l.page.Text(pdfMargin, l.y, pdf.Regular, 12, summary)
+
+	l.y -= 12
+	l.page.Color(0.85, 0.85, 0.85)
+	l.page.Rect(pdfMargin, l.y, width, 0.75)
+	l.page.Color(0, 0, 0)
+	l.y -= pdfGap
+}
+
+// entry draws the cover on the left and the title, date, score label and
+// notes next to it. Long notes continue on the following pages.
+func (l *pdfLayout) entry(entry *repository.Entry, cover *pdf.Image, scoreLabel string) {
+	textX := pdfMargin + pdfCoverWidth + pdfGap
+	textWidth := pdf.PageWidth - pdfMargin - textX
+
+	titleLines := pdf.Wrap(pdf.Bold, 13, entry.Title, textWidth)
+	l.ensure(max(pdfCoverHeight, float64(len(titleLines))*16+2*pdfLineHeight))
+
+	top := l.y
+	if cover != nil {
+		// Fit inside the cover box keeping the aspect ratio
+		scale := min(pdfCoverWidth/float64(cover.Width), pdfCoverHeight/float64(cover.Height))
+		w, h := float64(cover.Width)*scale, float64(cover.Height)*scale
+		l.page.Image(cover, pdfMargin+(pdfCoverWidth-w)/2, top-h, w, h)
+	} else {
+		l.page.Color(0.93, 0.93, 0.93)
+		l.page.Rect(pdfMargin, top-pdfCoverHeight, pdfCoverWidth, pdfCoverHeight)
+		l.page.Color(0, 0, 0)
+	}
+	coverBottom := top - pdfCoverHeight
+
+	for _, line := range titleLines {
+		l.y -= 16
+		l.page.Text(textX, l.y, pdf.Bold, 13, line)
+	}
+
+	score := "  ·  Undecided"
+	if scoreLabel != "" {
+		score = "  ·  " + scoreLabel
+	}
+	l.y -= pdfLineHeight + 2
+	l.page.Color(0.4, 0.4, 0.4)
+	l.page.Text(textX, l.y, pdf.Regular, pdfBodySize, entry.Date.Format("January 2, 2006")+score)
+	l.page.Color(0, 0, 0)
+	l.y -= 4
+
+	if entry.Description != "" {
+		for _, line := range pdf.Wrap(pdf.Regular, pdfBodySize, entry.Description, textWidth) {
+			if l.y-pdfLineHeight < pdfMargin {
+				l.newPage()
+				coverBottom = l.y
+			}
+			l.y -= pdfLineHeight
+			l.page.Text(textX, l.y, pdf.Regular, pdfBodySize, line)
+		}
+	}
+
+	l.y = min(l.y, coverBottom) - pdfGap
+}
@@ -0,0 +1,448 @@
+package service
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"github.com/avalarin/livlog/backend/internal/config"
+	"github.com/avalarin/livlog/backend/internal/repository"
+	"github.com/google/uuid"
+)
+
+var ErrInvalidImportFile = errors.New("file must be a livlog JSON export, or a ZIP of one with images")
+
+// Import conflict codes. Like warning codes they are stable; messages are
+// for people.
+const (
+	// ConflictTypeExists: a type with the name of one you have. Its entries
+	// get your type.
+	ConflictTypeExists = "TYPE_EXISTS"
+	// ConflictCollectionExists: a collection with the name of one you have.
+	// Its entries are added to yours.
+	ConflictCollectionExists = "COLLECTION_EXISTS"
+	// ConflictIconNotImported: a collection with an uploaded icon, which
+	// exports don't carry. It gets importIconFallback.
+	ConflictIconNotImported = "ICON_NOT_IMPORTED"
+	// ConflictUnknownType: an entry of a type that is neither in the file nor
+	// a system type here. It is imported without a type.
+	ConflictUnknownType = "UNKNOWN_TYPE"
+	// ConflictUnknownCollection: an entry of a collection that is not in the
+	// file, e.g. from an export of another collection. It is imported
+	// without a collection.
+	ConflictUnknownCollection = "UNKNOWN_COLLECTION"
+	// ConflictMissingImages: an entry whose images are not in the file, as in
+	// JSON exports without images. It is imported without them.
+	ConflictMissingImages = "MISSING_IMAGES"
+	// ConflictQuotaExceeded: the import would take the account over a quota.
+	// Only dry runs report it; imports fail with the quota error.
+	ConflictQuotaExceeded = "QUOTA_EXCEEDED"
+	// ConflictEntryRejected: an entry that fails validation here. It is
+	// skipped. Dry runs don't check entries, so only imports report it.
+	ConflictEntryRejected = "ENTRY_REJECTED"
+)
+
+const (
+	// importMaxFileBytes caps what an import reads out of a ZIP, the size of
+	// the largest export.
+	importMaxFileBytes = exportMaxArchiveBytes
+	// importIconFallback replaces uploaded collection icons.
+	importIconFallback = "📁"
+)
+
+// ImportConflict is a record of the file that doesn't carry over as it is;
+// SourceID is its id in the file and Message says what happens to it.
+type ImportConflict struct {
+	Code     string
+	SourceID uuid.UUID // uuid.Nil for conflicts of the whole import
+	Message  string
+}
+
+// ImportResult counts what an import created, or would create on a dry run,
+// with the conflicts found on the way.
+type ImportResult struct {
+	DryRun      bool
+	Types       int
+	Collections int
+	Entries     int
+	Images      int
+	Conflicts   []ImportConflict
+}
+
+// ImportService moves an account's data from another livlog server into
+// this one. It reads the file of a JSON export, optionally zipped with its
+// images, and creates everything under new ids.
+type ImportService struct {
+	entryService      *EntryService
+	collectionService *CollectionService
+	typeService       *TypeService
+	entryRepo         *repository.EntryRepository
+	collectionRepo    *repository.CollectionRepository
+	typeRepo          *repository.TypeRepository
+	quotas            config.QuotasConfig
+}
+
+func NewImportService(
+	entryService *EntryService,
+	collectionService *CollectionService,
+	typeService *TypeService,
+	entryRepo *repository.EntryRepository,
+	collectionRepo *repository.CollectionRepository,
+	typeRepo *repository.TypeRepository,
+	quotas config.QuotasConfig,
+) *ImportService {
+	return &ImportService{
+		entryService:      entryService,
+		collectionService: collectionService,
+		typeService:       typeService,
+		entryRepo:         entryRepo,
+		collectionRepo:    collectionRepo,
+		typeRepo:          typeRepo,
+		quotas:            quotas,
+	}
+}
+
+// livlogImport is one run of ImportLivlog. The maps take ids in the file to
+// ids here; on a dry run records that would be created map to themselves.
+type livlogImport struct {
+	userID        uuid.UUID
+	dryRun        bool
+	backup        *jsonExport
+	images        map[uuid.UUID][]byte
+	typeIDs       map[uuid.UUID]uuid.UUID
+	collectionIDs map[uuid.UUID]uuid.UUID
+	result        *ImportResult
+}
+
+func (imp *livlogImport) conflict(code string, sourceID uuid.UUID, format string, args ...interface{}) {
+	imp.result.Conflicts = append(imp.result.Conflicts, ImportConflict{
+		Code:     code,
+		SourceID: sourceID,
+		Message:  fmt.Sprintf(format, args...),
+	})
+}
+
+// ImportLivlog imports the user's data from an export of another server.
+// Types and collections with the names of the user's own are merged into
+// them. A dry run creates nothing and reports what the import would do.
+// Records are created one by one, so an import that fails midway keeps what
+// it created; run it dry first.
+func (s *ImportService) ImportLivlog(ctx context.Context, userID uuid.UUID, file []byte, dryRun bool) (*ImportResult, error) {
+	backup, images, err := readLivlogExport(file)
+	if err != nil {
+		return nil, err
+	}
+
+	imp := &livlogImport{
+		userID:        userID,
+		dryRun:        dryRun,
+		backup:        backup,
+		images:        images,
+		typeIDs:       make(map[uuid.UUID]uuid.UUID),
+		collectionIDs: make(map[uuid.UUID]uuid.UUID),
+		result:        &ImportResult{DryRun: dryRun, Conflicts: []ImportConflict{}},
+	}
+
+	if err := s.importTypes(ctx, imp); err != nil {
+		return nil, err
+	}
+	existingCollections, err := s.collectionRepo.GetCollectionsByUserID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.checkImportQuotas(ctx, imp, existingCollections); err != nil {
+		return nil, err
+	}
+	if err := s.importCollections(ctx, imp, existingCollections); err != nil {
+		return nil, err
+	}
+	if err := s.importEntries(ctx, imp); err != nil {
+		return nil, err
+	}
+
+	return imp.result, nil
+}
+
+// importTypes matches system types by name and creates the user's types,
+// merging those named like one the user has.
+func (s *ImportService) importTypes(ctx context.Context, imp *livlogImport) error {
+	existing, err := s.typeRepo.GetAllTypes(ctx, imp.userID)
+	if err != nil {
+		return err
+	}
+	systemByName := make(map[string]uuid.UUID)
+	ownByName := make(map[string]uuid.UUID)
+	for _, t := range existing {
+		if t.UserID == nil {
+			systemByName[strings.ToLower(t.Name)] = t.ID
+		} else {
+			ownByName[strings.ToLower(t.Name)] = t.ID
+		}
+	}
+
+	for _, t := range imp.backup.SystemTypes {
+		if id, ok := systemByName[strings.ToLower(t.Name)]; ok {
+			imp.typeIDs[t.ID] = id
+		}
+	}
+
+	for _, t := range imp.backup.Types {
+		if id, ok := ownByName[strings.ToLower(strings.TrimSpace(t.Name))]; ok {
+			imp.typeIDs[t.ID] = id
+			imp.conflict(ConflictTypeExists, t.ID, "type %q is merged into your type of the same name", t.Name)
+			continue
+		}
+
+		imp.result.Types++
+		if imp.dryRun {
+			imp.typeIDs[t.ID] = t.ID
+			continue
+		}
+		created, err := s.typeService.CreateType(ctx, imp.userID, t.Name, t.Icon, t.ScoreScale.Kind, t.ScoreScale.Labels)
+		if err != nil {
+			return fmt.Errorf("failed to import type %s: %w", t.ID, err)
+		}
+		imp.typeIDs[t.ID] = created.ID
+		ownByName[strings.ToLower(created.Name)] = created.ID
+	}
+
+	return nil
+}
+
+// checkImportQuotas fails an import that would exceed the entry or
+// collection quota before anything is created; a dry run reports it instead.
+func (s *ImportService) checkImportQuotas(ctx context.Context, imp *livlogImport, existingCollections []*repository.Collection) error {
+	var errs []error
+
+	if s.quotas.MaxCollections > 0 {
+		names := make(map[string]bool, len(existingCollections))
+		for _, c := range existingCollections {
+			names[strings.ToLower(c.Name)] = true
+		}
+		count := len(existingCollections)
+		for _, c := range imp.backup.Collections {
+			if name := strings.ToLower(strings.TrimSpace(c.Name)); !names[name] {
+				names[name] = true
+				count++
+			}
+		}
+		errs = append(errs, checkQuota(QuotaCollections, s.quotas.MaxCollections, count))
+	}
+
+	if s.quotas.MaxEntries > 0 {
+		count, err := s.entryRepo.CountEntries(ctx, imp.userID)
+		if err != nil {
+			return err
+		}
+		errs = append(errs, checkQuota(QuotaEntries, s.quotas.MaxEntries, count+len(imp.backup.Entries)))
+	}
+
+	for _, err := range errs {
+		var qe *QuotaExceededError
+		if !errors.As(err, &qe) {
+			continue
+		}
+		if !imp.dryRun {
+			return err
+		}
+		imp.conflict(ConflictQuotaExceeded, uuid.Nil, "the import would exceed your %s quota of %d", qe.Resource, qe.Limit)
+	}
+	return nil
+}
+
+// importCollections creates the collections, merging those named like one
+// the user has. Workspaces and share links stay behind: imported
+// collections are personal and not shared.
+func (s *ImportService) importCollections(ctx context.Context, imp *livlogImport, existing []*repository.Collection) error {
+	byName := make(map[string]uuid.UUID, len(existing))
+	for _, c := range existing {
+		byName[strings.ToLower(c.Name)] = c.ID
+	}
+
+	for _, c := range imp.backup.Collections {
+		if id, ok := byName[strings.ToLower(strings.TrimSpace(c.Name))]; ok {
+			imp.collectionIDs[c.ID] = id
+			imp.conflict(ConflictCollectionExists, c.ID, "collection %q is merged into your collection of the same name", c.Name)
+			continue
+		}
+
+		icon := c.Icon
+		if icon == "" {
+			icon = importIconFallback
+			imp.conflict(ConflictIconNotImported, c.ID, "collection %q gets the icon %s instead of its uploaded one", c.Name, importIconFallback)
+		}
+		var allowedTypeIDs []uuid.UUID
+		for _, typeID := range c.AllowedTypeIDs {
+			if id, ok := imp.typeIDs[typeID]; ok {
+				allowedTypeIDs = append(allowedTypeIDs, id)
+			}
+		}
+
+		imp.result.Collections++
+		if imp.dryRun {
+			imp.collectionIDs[c.ID] = c.ID
+			continue
+		}
+		created, err := s.collectionService.CreateCollectionWithID(ctx, nil, imp.userID, c.Name, CollectionIcon{Emoji: icon}, allowedTypeIDs)
+		if err != nil {
+			return fmt.Errorf("failed to import collection %s: %w", c.ID, err)
+		}
+		imp.collectionIDs[c.ID] = created.ID
+		byName[strings.ToLower(created.Name)] = created.ID
+	}
+
+	return nil
+}
+
+// importEntries creates the entries with the images found in the file.
+// Entries that fail validation are skipped and reported.
+func (s *ImportService) importEntries(ctx context.Context, imp *livlogImport) error {
+	for _, e := range imp.backup.Entries {
+		if e.Entry == nil {
+			continue
+		}
+
+		var collectionID, typeID *uuid.UUID
+		if e.CollectionID != nil {
+			if id, ok := imp.collectionIDs[*e.CollectionID]; ok {
+				collectionID = &id
+			} else {
+				imp.conflict(ConflictUnknownCollection, e.ID, "entry %q is imported without a collection", e.Title)
+			}
+		}
+		if e.TypeID != nil {
+			if id, ok := imp.typeIDs[*e.TypeID]; ok {
+				typeID = &id
+			} else {
+				imp.conflict(ConflictUnknownType, e.ID, "entry %q is imported without a type", e.Title)
+			}
+		}
+
+		metas := append([]repository.ImageMeta(nil), e.Images...)
+		sort.SliceStable(metas, func(i, j int) bool { return metas[i].Position < metas[j].Position })
+		var images []repository.EntryImage
+		for _, m := range metas {
+			if data, ok := imp.images[m.ID]; ok {
+				images = append(images, repository.EntryImage{ImageData: data, IsCover: m.IsCover, Position: m.Position})
+			}
+		}
+		if missing := len(metas) - len(images); missing > 0 {
+			imp.conflict(ConflictMissingImages, e.ID, "entry %q is imported without %d of its %d images", e.Title, missing, len(metas))
+		}
+
+		if imp.dryRun {
+			imp.result.Entries++
+			imp.result.Images += len(images)
+			continue
+		}
+		_, err := s.entryService.CreateEntryWithID(
+			ctx, nil, imp.userID, collectionID, typeID,
+			e.Title, e.Description, e.OriginalTitle, e.Language,
+			e.Score, e.Priority, e.Visibility, e.Date, e.AdditionalFields,
+			images, nil, nil,
+		)
+		if err != nil {
+			if !isEntryRejection(err) {
+				return fmt.Errorf("failed to import entry %s: %w", e.ID, err)
+			}
+			imp.conflict(ConflictEntryRejected, e.ID, "entry %q is skipped: %v", e.Title, err)
+			continue
+		}
+		imp.result.Entries++
+		imp.result.Images += len(images)
+	}
+
+	return nil
+}
+
+// isEntryRejection reports whether err is about the entry itself rather
+// than a failure to save it.
+func isEntryRejection(err error) bool {
+	for _, target := range []error{
+		ErrInvalidTitle, ErrInvalidDescription, ErrInvalidScore, ErrInvalidFieldValue,
+		ErrInvalidPriority, ErrInvalidVisibility, ErrInvalidOriginalTitle, ErrInvalidLanguage,
+		ErrTypeNotAllowed, ErrQuotaExceeded,
+	} {
+		if errors.Is(err, target) {
+			return true
+		}
+	}
+	return false
+}
+
+// readLivlogExport reads the file of a JSON export, or of a ZIP export
+// holding livlog.json and its images as images/<id>.jpg.
+func readLivlogExport(file []byte) (*jsonExport, map[uuid.UUID][]byte, error) {
+	data := file
+	images := make(map[uuid.UUID][]byte)
+
+	if bytes.HasPrefix(file, []byte("PK\x03\x04")) {
+		zr, err := zip.NewReader(bytes.NewReader(file), int64(len(file)))
+		if err != nil {
+			return nil, nil, fmt.Errorf("%w: %v", ErrInvalidImportFile, err)
+		}
+
+		data = nil
+		budget := int64(importMaxFileBytes)
+		for _, f := range zr.File {
+			var id uuid.UUID
+			if f.Name != "livlog.json" {
+				name, ok := strings.CutPrefix(f.Name, "images/")
+				if !ok {
+					continue
+				}
+				if id, err = uuid.Parse(strings.TrimSuffix(name, ".jpg")); err != nil {
+					continue
+				}
+			}
+
+			content, err := readZipFile(f, budget)
+			if err != nil {
+				return nil, nil, fmt.Errorf("%w: %v", ErrInvalidImportFile, err)
+			}
+			budget -= int64(len(content))
+			if f.Name == "livlog.json" {
+				data = content
+			} else {
+				images[id] = content
+			}
+		}
+		if data == nil {
+			return nil, nil, fmt.Errorf("%w: the ZIP has no livlog.json", ErrInvalidImportFile)
+		}
+	}
+
+	var backup jsonExport
+	if err := json.Unmarshal(data, &backup); err != nil {
+		return nil, nil, fmt.Errorf("%w: %v", ErrInvalidImportFile, err)
+	}
+	if backup.ExportedAt.IsZero() {
+		return nil, nil, fmt.Errorf("%w: exported_at is missing", ErrInvalidImportFile)
+	}
+
+	return &backup, images, nil
+}
+
+// readZipFile reads a file of a ZIP, failing past limit bytes.
+func readZipFile(f *zip.File, limit int64) ([]byte, error) {
+	rc, err := f.Open()
+	if err != nil {
+		return nil, err
+	}
+	defer rc.Close()
+
+	content, err := io.ReadAll(io.LimitReader(rc, limit+1))
+	if err != nil {
+		return nil, err
+	}
+	if int64(len(content)) > limit {
+		return nil, fmt.Errorf("the ZIP unpacks to more than %d MB", importMaxFileBytes>>20)
+	}
+	return content, nil
+}
@@ -15,7 +15,8 @@ import (
 )
 
 var (
-	ErrInvalidAccessToken = errors.New("invalid access token")
+	ErrInvalidAccessToken   = errors.New("invalid access token")
+	ErrInvalidDownloadToken = errors.New("invalid download token")
 )
 
 type JWTService struct {
@@ -25,6 +26,7 @@ type JWTService struct {
 	refreshTokenLifetime time.Duration
 	issuer               string
 	audience             string
+	clock                Clock
 }
 
 type AccessTokenClaims struct {
@@ -33,10 +35,18 @@ type AccessTokenClaims struct {
 	jwt.RegisteredClaims
 }
 
+// DownloadTokenClaims authorize downloading one file without an access
+// token, e.g. from a link opened in a browser. They carry their own audience
+// so that they are never accepted as access tokens.
+type DownloadTokenClaims struct {
+	jwt.RegisteredClaims
+}
+
 func NewJWTService(
 	privateKeyPath, publicKeyPath string,
 	accessTokenLifetime, refreshTokenLifetime int,
 	issuer, audience string,
+	clock Clock,
 ) (*JWTService, error) {
 	// Read private key
 	privateKeyBytes, err := os.ReadFile(privateKeyPath)
@@ -87,11 +97,12 @@ func NewJWTService(
 		refreshTokenLifetime: time.Duration(refreshTokenLifetime) * time.Second,
 		issuer:               issuer,
 		audience:             audience,
+		clock:                clock,
 	}, nil
 }
 
 func (s *JWTService) GenerateAccessToken(userID, email string) (string, error) {
-	now := time.Now()
+	now := s.clock.Now()
 	claims := AccessTokenClaims{
 		UserID: userID,
 		Email:  email,
@@ -114,13 +125,7 @@ func (s *JWTService) GenerateAccessToken(userID, email string) (string, error) {
 }
 
 func (s *JWTService) ValidateAccessToken(tokenString string) (*AccessTokenClaims, error) {
-	token, err := jwt.ParseWithClaims(tokenString, &AccessTokenClaims{}, func(token *jwt.Token) (interface{}, error) {
-		// Validate signing method
-		if _, ok := token.Method.(*jwt.SigningMethodRSA); !ok {
-			return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
-		}
-		return s.publicKey, nil
-	})
+	token, err := jwt.ParseWithClaims(tokenString, &AccessTokenClaims{}, s.keyFunc, jwt.WithAudience(s.audience), jwt.WithTimeFunc(s.clock.Now))
 
 	if err != nil {
 		if errors.Is(err, jwt.ErrTokenExpired) {
@@ -137,6 +142,62 @@ func (s *JWTService) ValidateAccessToken(tokenString string) (*AccessTokenClaims
 	return claims, nil
 }
 
+// GenerateDownloadToken signs a token letting userID download resourceID
+// until it expires after lifetime.
+func (s *JWTService) GenerateDownloadToken(userID, resourceID string, lifetime time.Duration) (string, time.Time, error) {
+	now := s.clock.Now()
+	expiresAt := now.Add(lifetime)
+	claims := DownloadTokenClaims{
+		RegisteredClaims: jwt.RegisteredClaims{
+			ID:        resourceID,
+			Subject:   userID,
+			Issuer:    s.issuer,
+			Audience:  jwt.ClaimStrings{s.downloadAudience()},
+			IssuedAt:  jwt.NewNumericDate(now),
+			ExpiresAt: jwt.NewNumericDate(expiresAt),
+		},
+	}
+
+	token := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
+	tokenString, err := token.SignedString(s.privateKey)
+	if err != nil {
+		return "", time.Time{}, fmt.Errorf("failed to sign token: %w", err)
+	}
+
+	return tokenString, expiresAt, nil
+}
+
+// ValidateDownloadToken checks a download token; the claims' Subject is the
+// user and ID the resource it was issued for.
+func (s *JWTService) ValidateDownloadToken(tokenString string) (*DownloadTokenClaims, error) {
+	token, err := jwt.ParseWithClaims(tokenString, &DownloadTokenClaims{}, s.keyFunc, jwt.WithAudience(s.downloadAudience()), jwt.WithTimeFunc(s.clock.Now))
+	if err != nil {
+		if errors.Is(err, jwt.ErrTokenExpired) {
+			return nil, ErrTokenExpired
+		}
+		return nil, fmt.Errorf("%w: %v", ErrInvalidDownloadToken, err)
+	}
+
+	claims, ok := token.Claims.(*DownloadTokenClaims)
+	if !ok || !token.Valid {
+		return nil, ErrInvalidDownloadToken
+	}
+
+	return claims, nil
+}
+
+func (s *JWTService) downloadAudience() string {
+	return s.audience + "-download"
+}
+
+func (s *JWTService) keyFunc(token *jwt.Token) (interface{}, error) {
+	// Validate signing method
+	if _, ok := token.Method.(*jwt.SigningMethodRSA); !ok {
+		return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
+	}
+	return s.publicKey, nil
+}
+
 func (s *JWTService) GenerateRefreshToken() (string, error) {
 	// Generate 32 random bytes
 	b := make([]byte, 32)
@@ -155,3 +216,33 @@ func (s *JWTService) GetAccessTokenLifetime() time.Duration {
 func (s *JWTService) GetRefreshTokenLifetime() time.Duration {
 	return s.refreshTokenLifetime
 }
+
+// GenerateJWTKeys writes a new RSA key pair for signing tokens in the formats
+// NewJWTService reads: PKCS#8 private key and PKIX public key, both PEM.
+func GenerateJWTKeys(privateKeyPath, publicKeyPath string) error {
+	privateKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		return fmt.Errorf("failed to generate key: %w", err)
+	}
+
+	privateDER, err := x509.MarshalPKCS8PrivateKey(privateKey)
+	if err != nil {
+		return fmt.Errorf("failed to encode private key: %w", err)
+	}
+	publicDER, err := x509.MarshalPKIXPublicKey(&privateKey.PublicKey)
+	if err != nil {
+		return fmt.Errorf("failed to encode public key: %w", err)
+	}
+
+	privatePEM := pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: privateDER})
+	if err := os.WriteFile(privateKeyPath, privatePEM, 0o600); err != nil {
+		return fmt.Errorf("failed to write private key: %w", err)
+	}
+
+	publicPEM := pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: publicDER})
+	if err := os.WriteFile(publicKeyPath, publicPEM, 0o644); err != nil {
+		return fmt.Errorf("failed to write public key: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,169 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"mime"
+	"net"
+	"net/mail"
+	"net/smtp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/avalarin/livlog/backend/internal/config"
+)
+
+var (
+	// ErrMailUnavailable is returned by flows that email the user when the
+	// server has no mail server configured.
+	ErrMailUnavailable = errors.New("email is not configured on this server")
+	// ErrMailRelayUnavailable is returned for Apple private relay addresses
+	// while the From domain isn't declared registered with Apple.
+	ErrMailRelayUnavailable = errors.New("email to Apple private relay addresses is not configured on this server")
+)
+
+// appleRelayDomain is the domain of Apple's Hide My Email addresses.
+const appleRelayDomain = "privaterelay.appleid.com"
+
+// MailMessage is a plain-text email.
+type MailMessage struct {
+	Subject string
+	Body    string
+}
+
+// Mailer sends transactional email.
+type Mailer interface {
+	// CanSend reports whether mail to the address would arrive.
+	CanSend(to string) bool
+	Send(ctx context.Context, to string, msg MailMessage) error
+}
+
+// NewMailer returns an SMTPMailer for cfg, or nil when no mail server is
+// configured.
+func NewMailer(cfg config.MailConfig) (Mailer, error) {
+	if !cfg.Enabled() {
+		return nil, nil
+	}
+	from, err := mail.ParseAddress(cfg.From)
+	if err != nil {
+		return nil, fmt.Errorf("invalid mail.from: %w", err)
+	}
+
+	m := &SMTPMailer{
+		addr:       net.JoinHostPort(cfg.Host, strconv.Itoa(cfg.Port)),
+		host:       cfg.Host,
+		from:       from,
+		timeout:    cfg.Timeout,
+		appleRelay: cfg.AppleRelay,
+	}
+	if cfg.Username != "" {
+		m.auth = smtp.PlainAuth("", cfg.Username, cfg.Password, cfg.Host)
+	}
+	return m, nil
+}
+
+// SMTPMailer sends email through an SMTP server, upgrading the connection
+// with STARTTLS when the server offers it. smtp.PlainAuth refuses to send
+// credentials over a connection that wasn't upgraded, except to localhost.
+type SMTPMailer struct {
+	addr       string
+	host       string
+	from       *mail.Address
+	auth       smtp.Auth
+	timeout    time.Duration
+	appleRelay bool
+}
+
+// CanSend implements Mailer. Apple's private relay only forwards mail from
+// domains registered with Apple, so its addresses need mail.apple_relay.
+func (m *SMTPMailer) CanSend(to string) bool {
+	return m.appleRelay || !isAppleRelayAddress(to)
+}
+
+// Send implements Mailer.
+func (m *SMTPMailer) Send(ctx context.Context, to string, msg MailMessage) error {
+	if !m.CanSend(to) {
+		return ErrMailRelayUnavailable
+	}
+
+	dialer := net.Dialer{Timeout: m.timeout}
+	conn, err := dialer.DialContext(ctx, "tcp", m.addr)
+	if err != nil {
+		return fmt.Errorf("failed to connect to mail server: %w", err)
+	}
+	defer conn.Close()
+
+	deadline := time.Now().Add(m.timeout)
+	if d, ok := ctx.Deadline(); ok && d.Before(deadline) {
+		deadline = d
+	}
+	if err := conn.SetDeadline(deadline); err != nil {
+		return fmt.Errorf("failed to set mail deadline: %w", err)
+	}
+
+	c, err := smtp.NewClient(conn, m.host)
+	if err != nil {
+		return fmt.Errorf("failed to greet mail server: %w", err)
+	}
+	defer c.Close()
+
+	if ok, _ := c.Extension("STARTTLS"); ok {
+		if err := c.StartTLS(&tls.Config{ServerName: m.host}); err != nil {
+			return fmt.Errorf("failed to start TLS: %w", err)
+		}
+	}
+	if m.auth != nil {
+		if err := c.Auth(m.auth); err != nil {
+			return fmt.Errorf("failed to authenticate with mail server: %w", err)
+		}
+	}
+
+	if err := c.Mail(m.from.Address); err != nil {
+		return fmt.Errorf("mail server refused sender: %w", err)
+	}
+	if err := c.Rcpt(to); err != nil {
+		return fmt.Errorf("mail server refused recipient: %w", err)
+	}
+	w, err := c.Data()
+	if err != nil {
+		return fmt.Errorf("failed to start mail data: %w", err)
+	}
+	if _, err := w.Write(composeMail(m.from, to, msg, time.Now())); err != nil {
+		return fmt.Errorf("failed to write mail: %w", err)
+	}
+	if err := w.Close(); err != nil {
+		return fmt.Errorf("mail server rejected message: %w", err)
+	}
+	return c.Quit()
+}
+
+// isAppleRelayAddress reports whether addr is an Apple Hide My Email address.
+func isAppleRelayAddress(addr string) bool {
+	at := strings.LastIndex(addr, "@")
+	return at >= 0 && strings.EqualFold(strings.TrimSpace(addr[at+1:]), appleRelayDomain)
+}
+
+// composeMail renders msg as a UTF-8 plain-text message with CRLF line
+// endings. The recipient is never used unquoted, so it can't add headers.
+func composeMail(from *mail.Address, to string, msg MailMessage, now time.Time) []byte {
+	var b bytes.Buffer
+	header := func(name, value string) {
+		b.WriteString(name + ": " + value + "\r\n")
+	}
+	header("From", from.String())
+	header("To", (&mail.Address{Address: to}).String())
+	header("Subject", mime.QEncoding.Encode("utf-8", msg.Subject))
+	header("Date", now.Format(time.RFC1123Z))
+	header("MIME-Version", "1.0")
+	header("Content-Type", "text/plain; charset=utf-8")
+	header("Content-Transfer-Encoding", "8bit")
+	b.WriteString("\r\n")
+
+	body := strings.ReplaceAll(msg.Body, "\r\n", "\n")
+	b.WriteString(strings.ReplaceAll(body, "\n", "\r\n"))
+	return b.Bytes()
+}
@@ -0,0 +1,69 @@
+package service
+
+import (
+	"net/mail"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/avalarin/livlog/backend/internal/config"
+)
+
+func TestNewMailer_Disabled(t *testing.T) {
+	m, err := NewMailer(config.MailConfig{})
+	if err != nil {
+		t.Fatalf("NewMailer() error = %v", err)
+	}
+	if m != nil {
+		t.Errorf("NewMailer() = %v, want nil without a host", m)
+	}
+}
+
+func TestComposeMail(t *testing.T) {
+	from := &mail.Address{Name: "Livlog", Address: "no-reply@livlog.app"}
+	now := time.Date(2025, 2, 1, 10, 0, 0, 0, time.UTC)
+
+	got := string(composeMail(from, "user@example.com", verificationCodeMail("042917"), now))
+
+	header, body, ok := strings.Cut(got, "\r\n\r\n")
+	if !ok {
+		t.Fatalf("composeMail() has no header/body separator:\n%s", got)
+	}
+	for _, want := range []string{
+		`From: "Livlog" <no-reply@livlog.app>`,
+		"To: <user@example.com>",
+		"Subject: Your Livlog verification code",
+		"Date: Sat, 01 Feb 2025 10:00:00 +0000",
+		"Content-Type: text/plain; charset=utf-8",
+	} {
+		if !strings.Contains(header, want+"\r\n") {
+			t.Errorf("header missing %q:\n%s", want, header)
+		}
+	}
+	if !strings.Contains(body, "042917") {
+		t.Errorf("body doesn't contain the code:\n%s", body)
+	}
+	if strings.Contains(strings.ReplaceAll(got, "\r\n", ""), "\n") {
+		t.Error("composeMail() has bare LF line endings")
+	}
+}
+
+func TestSMTPMailer_CanSend(t *testing.T) {
+	tests := []struct {
+		to         string
+		appleRelay bool
+		want       bool
+	}{
+		{"user@example.com", false, true},
+		{"abc123@privaterelay.appleid.com", false, false},
+		{"abc123@PrivateRelay.AppleID.com", false, false},
+		{"abc123@privaterelay.appleid.com", true, true},
+		{"user@privaterelay.appleid.com.example.com", false, true},
+	}
+	for _, tt := range tests {
+		m := &SMTPMailer{appleRelay: tt.appleRelay}
+		if got := m.CanSend(tt.to); got != tt.want {
+			t.Errorf("CanSend(%q) with apple_relay %v = %v, want %v", tt.to, tt.appleRelay, got, tt.want)
+		}
+	}
+}
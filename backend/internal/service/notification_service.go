@@ -0,0 +1,116 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/avalarin/livlog/backend/internal/repository"
+	"github.com/google/uuid"
+)
+
+var (
+	ErrInvalidNotificationKind  = errors.New("unknown notification kind")
+	ErrInvalidNotificationTitle = errors.New("notification title must be between 1 and 200 characters")
+)
+
+// Notification kinds, one per system event that reports back to the user.
+const (
+	NotificationImportFinished   = "import_finished"
+	NotificationExportReady      = "export_ready"
+	NotificationCollectionInvite = "collection_invite"
+	NotificationAIBudgetExceeded = "ai_budget_exceeded" // to admins, see AISearchService
+)
+
+// NotificationKinds lists every kind a notification can have.
+var NotificationKinds = []string{
+	NotificationImportFinished,
+	NotificationExportReady,
+	NotificationCollectionInvite,
+	NotificationAIBudgetExceeded,
+}
+
+const notificationReadRetention = 90 * 24 * time.Hour
+
+// NotificationService keeps each user's in-app inbox. Background work (imports,
+// exports, sharing) calls Notify when it has a result for the user; the
+// notification is also sent through the user's connected channels.
+type NotificationService struct {
+	notificationRepo *repository.NotificationRepository
+	channelService   *ChannelService
+}
+
+func NewNotificationService(
+	notificationRepo *repository.NotificationRepository,
+	channelService *ChannelService,
+) *NotificationService {
+	return &NotificationService{
+		notificationRepo: notificationRepo,
+		channelService:   channelService,
+	}
+}
+
+// Notify adds a notification to a user's inbox. data carries what the app
+// needs to act on it, e.g. an export download URL or a collection ID.
+func (s *NotificationService) Notify(
+	ctx context.Context,
+	userID uuid.UUID,
+	kind, title, body string,
+	data map[string]interface{},
+) (*repository.Notification, error) {
+	if !isNotificationKind(kind) {
+		return nil, ErrInvalidNotificationKind
+	}
+
+	title = strings.TrimSpace(title)
+	if len(title) < 1 || len(title) > 200 {
+		return nil, ErrInvalidNotificationTitle
+	}
+
+	body = strings.TrimSpace(body)
+	notification, err := s.notificationRepo.CreateNotification(ctx, userID, kind, title, body, data)
+	if err != nil {
+		return nil, err
+	}
+
+	if s.channelService != nil {
+		s.channelService.Deliver(ctx, userID, ChannelMessage{Title: title, Body: body})
+	}
+
+	return notification, nil
+}
+
+// GetNotifications lists a user's notifications, newest first.
+func (s *NotificationService) GetNotifications(
+	ctx context.Context,
+	userID uuid.UUID,
+	unreadOnly bool,
+	limit, offset int,
+) ([]*repository.Notification, error) {
+	limit = listLimit(limit)
+	if offset < 0 {
+		offset = 0
+	}
+
+	return s.notificationRepo.GetNotificationsByUserID(ctx, userID, unreadOnly, limit, offset)
+}
+
+// MarkRead marks a user's notification as read.
+func (s *NotificationService) MarkRead(ctx context.Context, id, userID uuid.UUID) (*repository.Notification, error) {
+	return s.notificationRepo.MarkRead(ctx, id, userID)
+}
+
+// CleanupRead removes notifications read long ago.
+func (s *NotificationService) CleanupRead(ctx context.Context) (int64, error) {
+	return s.notificationRepo.DeleteReadNotifications(ctx, notificationReadRetention)
+}
+
+func isNotificationKind(kind string) bool {
+	for _, k := range NotificationKinds {
+		if k == kind {
+			return true
+		}
+	}
+	return false
+}
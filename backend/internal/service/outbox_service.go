@@ -0,0 +1,85 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/avalarin/livlog/backend/internal/apitime"
+	"github.com/avalarin/livlog/backend/internal/apiv1"
+	"github.com/avalarin/livlog/backend/internal/repository"
+	"github.com/google/uuid"
+)
+
+// outboxBatchSize bounds the events relayed in one transaction.
+const outboxBatchSize = 100
+
+// outboxEntryStore is the part of EntryRepository webhook payloads are built
+// from.
+type outboxEntryStore interface {
+	GetEntryByID(ctx context.Context, id uuid.UUID) (*repository.Entry, error)
+	GetEntryImageMetas(ctx context.Context, entryID uuid.UUID) ([]repository.ImageMeta, error)
+}
+
+// OutboxService publishes events from the transactional outbox to their
+// consumers: the change feed and webhooks.
+type OutboxService struct {
+	outboxRepo *repository.OutboxRepository
+	entryRepo  outboxEntryStore
+}
+
+func NewOutboxService(outboxRepo *repository.OutboxRepository, entryRepo *repository.EntryRepository) *OutboxService {
+	return &OutboxService{
+		outboxRepo: outboxRepo,
+		entryRepo:  entryRepo,
+	}
+}
+
+// Relay publishes pending events in batches until the outbox is drained.
+func (s *OutboxService) Relay(ctx context.Context) error {
+	for {
+		n, err := s.outboxRepo.Relay(ctx, outboxBatchSize, s.webhookFor)
+		if err != nil {
+			return err
+		}
+		if n < outboxBatchSize {
+			return nil
+		}
+	}
+}
+
+// webhookFor builds the webhook delivery of an outbox event, or returns nil
+// if webhooks don't cover it. The entry is mapped like GET /entries/{id}
+// answers v2 clients, so only API fields leave the server. An entry deleted
+// since the event is skipped; its own delete event follows.
+func (s *OutboxService) webhookFor(ctx context.Context, e repository.OutboxEvent) (*repository.OutboxWebhook, error) {
+	event := webhookEventFor(e)
+	if event == "" {
+		return nil, nil
+	}
+
+	var data interface{}
+	if e.Op == "delete" {
+		data = map[string]string{"id": e.EntityID.String()}
+	} else {
+		entry, err := s.entryRepo.GetEntryByID(ctx, e.EntityID)
+		if errors.Is(err, repository.ErrEntryNotFound) {
+			return nil, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		imageMetas, err := s.entryRepo.GetEntryImageMetas(ctx, e.EntityID)
+		if err != nil {
+			return nil, err
+		}
+		data = apiv1.MapEntry(entry, imageMetas, apitime.UTC)
+	}
+
+	body, err := json.Marshal(data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode webhook data: %w", err)
+	}
+	return &repository.OutboxWebhook{Event: event, Data: body}, nil
+}
@@ -0,0 +1,117 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"reflect"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/avalarin/livlog/backend/internal/repository"
+)
+
+// fakeOutboxEntries serves one entry, like GetEntryByID and
+// GetEntryImageMetas do.
+type fakeOutboxEntries struct {
+	entry  *repository.Entry
+	images []repository.ImageMeta
+}
+
+func (f *fakeOutboxEntries) GetEntryByID(_ context.Context, id uuid.UUID) (*repository.Entry, error) {
+	if f.entry == nil || f.entry.ID != id {
+		return nil, repository.ErrEntryNotFound
+	}
+	return f.entry, nil
+}
+
+func (f *fakeOutboxEntries) GetEntryImageMetas(_ context.Context, _ uuid.UUID) ([]repository.ImageMeta, error) {
+	return f.images, nil
+}
+
+func TestOutboxService_WebhookPayload(t *testing.T) {
+	collectionID := uuid.New()
+	entry := &repository.Entry{
+		ID:               uuid.New(),
+		CollectionID:     &collectionID,
+		UserID:           uuid.New(),
+		Title:            "Dune",
+		Score:            3,
+		Visibility:       "private",
+		Date:             time.Date(2025, 2, 1, 0, 0, 0, 0, time.UTC),
+		AdditionalFields: map[string]string{"author": "Frank Herbert"},
+		CreatedAt:        time.Date(2025, 2, 1, 10, 0, 0, 0, time.UTC),
+		UpdatedAt:        time.Date(2025, 2, 1, 10, 0, 0, 0, time.UTC),
+	}
+	s := &OutboxService{entryRepo: &fakeOutboxEntries{
+		entry:  entry,
+		images: []repository.ImageMeta{{ID: uuid.New(), IsCover: true, Hash: "abc"}},
+	}}
+
+	w, err := s.webhookFor(context.Background(), repository.OutboxEvent{
+		UserID: entry.UserID, Entity: "entry", EntityID: entry.ID, Op: "update",
+	})
+	if err != nil {
+		t.Fatalf("webhookFor() error = %v", err)
+	}
+	if w == nil || w.Event != EventEntryUpdated {
+		t.Fatalf("webhookFor() = %+v, want %s", w, EventEntryUpdated)
+	}
+
+	// The data is the API's entry: adding a key here is an API change, and
+	// internal columns such as user_id must never appear
+	var data map[string]interface{}
+	if err := json.Unmarshal(w.Data, &data); err != nil {
+		t.Fatalf("data is not an object: %v", err)
+	}
+	keys := make([]string, 0, len(data))
+	for k := range data {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	want := []string{
+		"additional_fields", "collection_id", "created_at", "date", "description", "id", "images",
+		"language", "original_title", "priority", "score", "title", "updated_at", "visibility",
+	}
+	if !reflect.DeepEqual(keys, want) {
+		t.Errorf("data keys = %v, want %v", keys, want)
+	}
+	if data["created_at"] != "2025-02-01T10:00:00.000000Z" {
+		t.Errorf("created_at = %v, want the v2 layout", data["created_at"])
+	}
+}
+
+func TestOutboxService_WebhookFor(t *testing.T) {
+	id := uuid.New()
+	s := &OutboxService{entryRepo: &fakeOutboxEntries{}}
+
+	tests := []struct {
+		name      string
+		event     repository.OutboxEvent
+		wantEvent string // "" for no delivery
+		wantData  string
+	}{
+		{"delete", repository.OutboxEvent{Entity: "entry", EntityID: id, Op: "delete"}, EventEntryDeleted, `{"id":"` + id.String() + `"}`},
+		{"entry deleted since", repository.OutboxEvent{Entity: "entry", EntityID: id, Op: "insert"}, "", ""},
+		{"collection", repository.OutboxEvent{Entity: "collection", EntityID: id, Op: "insert"}, "", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w, err := s.webhookFor(context.Background(), tt.event)
+			if err != nil {
+				t.Fatalf("webhookFor() error = %v", err)
+			}
+			if tt.wantEvent == "" {
+				if w != nil {
+					t.Errorf("webhookFor() = %+v, want no delivery", w)
+				}
+				return
+			}
+			if w == nil || w.Event != tt.wantEvent || string(w.Data) != tt.wantData {
+				t.Errorf("webhookFor() = %+v, want %s %s", w, tt.wantEvent, tt.wantData)
+			}
+		})
+	}
+}
@@ -0,0 +1,108 @@
+package service
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/avalarin/livlog/backend/internal/config"
+	"github.com/avalarin/livlog/backend/internal/repository"
+	"github.com/google/uuid"
+)
+
+var ErrInvalidPageCursor = errors.New("invalid page cursor")
+
+// Page sizes of every list, see UsePageSizes.
+var (
+	defaultPageSize = 50
+	maxPageSize     = 100
+)
+
+// UsePageSizes sets how many items lists return when the client doesn't ask
+// for a number, and the most they return. Call it once at startup.
+func UsePageSizes(cfg config.APIConfig) {
+	defaultPageSize, maxPageSize = cfg.DefaultPageSize, cfg.MaxPageSize
+}
+
+// DefaultPageSize is the page size of lists when the client doesn't ask for
+// one.
+func DefaultPageSize() int {
+	return defaultPageSize
+}
+
+// Page is one page of a list. NextCursor fetches the following page and is
+// empty on the last one.
+type Page[T any] struct {
+	Items      []T
+	NextCursor string
+}
+
+// pageLimit clamps a requested page size of a cursor-paginated list; zero
+// keeps "no limit".
+func pageLimit(limit int) int {
+	switch {
+	case limit < 0:
+		return defaultPageSize
+	case limit > maxPageSize:
+		return maxPageSize
+	default:
+		return limit
+	}
+}
+
+// listLimit clamps a requested page size of an offset-paginated list; zero
+// or less is the default.
+func listLimit(limit int) int {
+	switch {
+	case limit <= 0:
+		return defaultPageSize
+	case limit > maxPageSize:
+		return maxPageSize
+	default:
+		return limit
+	}
+}
+
+// encodePageCursor turns a page cursor into an opaque string for clients.
+func encodePageCursor(c *repository.PageCursor) string {
+	if c == nil {
+		return ""
+	}
+	raw := fmt.Sprintf("%d:%d:%s", c.Rank, c.CreatedAt.UnixMicro(), c.ID)
+	return base64.RawURLEncoding.EncodeToString([]byte(raw))
+}
+
+// decodePageCursor parses a cursor from encodePageCursor. An empty cursor
+// is the first page.
+func decodePageCursor(cursor string) (*repository.PageCursor, error) {
+	if cursor == "" {
+		return nil, nil
+	}
+
+	raw, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return nil, ErrInvalidPageCursor
+	}
+	parts := strings.Split(string(raw), ":")
+	if len(parts) != 3 {
+		return nil, ErrInvalidPageCursor
+	}
+
+	rank, err := strconv.Atoi(parts[0])
+	if err != nil {
+		return nil, ErrInvalidPageCursor
+	}
+	micros, err := strconv.ParseInt(parts[1], 10, 64)
+	if err != nil {
+		return nil, ErrInvalidPageCursor
+	}
+	id, err := uuid.Parse(parts[2])
+	if err != nil {
+		return nil, ErrInvalidPageCursor
+	}
+
+	return &repository.PageCursor{Rank: rank, CreatedAt: time.UnixMicro(micros), ID: id}, nil
+}
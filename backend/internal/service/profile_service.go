@@ -0,0 +1,226 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"regexp"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/avalarin/livlog/backend/internal/repository"
+	"github.com/google/uuid"
+)
+
+var (
+	ErrInvalidHandle     = errors.New("handle must be 3 to 30 characters: lowercase letters, digits and underscores")
+	ErrHandleReserved    = errors.New("handle is reserved")
+	ErrInvalidBio        = errors.New("bio must be at most 300 characters")
+	ErrTooManyFavorites  = errors.New("at most 12 favorite entries are allowed")
+	ErrInvalidFavorite   = errors.New("favorite entries must be your public entries in a profile collection")
+	ErrInvalidCollection = errors.New("collections must be your collections")
+)
+
+const (
+	maxProfileBio       = 300
+	maxProfileFavorites = 12
+
+	// sharedCollectionEntryLimit caps the entries shown on a shared collection.
+	sharedCollectionEntryLimit = 500
+
+	// releasedHandleHold is how long a changed handle stays reserved for its
+	// previous owner, so links to the old profile are not taken over right away.
+	releasedHandleHold = 30 * 24 * time.Hour
+)
+
+var handlePattern = regexp.MustCompile(`^[a-z0-9_]{3,30}$`)
+
+// reservedHandles would read as the app itself rather than a user.
+var reservedHandles = map[string]bool{
+	"admin": true, "api": true, "app": true, "help": true, "livlog": true, "me": true,
+	"public": true, "root": true, "settings": true, "support": true, "system": true, "www": true,
+}
+
+// PublicProfile is a profile as shown to anyone: only the chosen collections
+// and the favorites still inside them.
+type PublicProfile struct {
+	Profile     *repository.Profile
+	Stats       *repository.ProfileStats
+	Collections []*repository.Collection
+	Favorites   []*repository.EntryWithImages
+}
+
+// SharedCollection is a collection shared under a slug as shown to anyone:
+// its public entries, most recent first.
+type SharedCollection struct {
+	Collection *repository.Collection
+	Entries    []*repository.EntryWithImages
+}
+
+type ProfileService struct {
+	profileRepo    *repository.ProfileRepository
+	collectionRepo *repository.CollectionRepository
+	entryRepo      *repository.EntryRepository
+}
+
+func NewProfileService(
+	profileRepo *repository.ProfileRepository,
+	collectionRepo *repository.CollectionRepository,
+	entryRepo *repository.EntryRepository,
+) *ProfileService {
+	return &ProfileService{
+		profileRepo:    profileRepo,
+		collectionRepo: collectionRepo,
+		entryRepo:      entryRepo,
+	}
+}
+
+// NormalizeHandle lowercases a handle so lookups are case-insensitive.
+func NormalizeHandle(handle string) string {
+	return strings.ToLower(strings.TrimSpace(handle))
+}
+
+// GetProfile retrieves the user's own profile
+func (s *ProfileService) GetProfile(ctx context.Context, userID uuid.UUID) (*repository.Profile, error) {
+	return s.profileRepo.GetProfile(ctx, userID)
+}
+
+// SaveProfile validates and stores the user's profile
+func (s *ProfileService) SaveProfile(
+	ctx context.Context,
+	userID uuid.UUID,
+	handle string,
+	public bool,
+	bio string,
+	collectionIDs, favoriteEntryIDs []uuid.UUID,
+) (*repository.Profile, error) {
+	handle = NormalizeHandle(handle)
+	if !handlePattern.MatchString(handle) {
+		return nil, ErrInvalidHandle
+	}
+	if reservedHandles[handle] {
+		return nil, ErrHandleReserved
+	}
+
+	bio = strings.TrimSpace(bio)
+	if utf8.RuneCountInString(bio) > maxProfileBio {
+		return nil, ErrInvalidBio
+	}
+
+	collectionIDs = dedupeIDs(collectionIDs)
+	favoriteEntryIDs = dedupeIDs(favoriteEntryIDs)
+	if len(favoriteEntryIDs) > maxProfileFavorites {
+		return nil, ErrTooManyFavorites
+	}
+
+	collections, err := s.collectionRepo.GetCollectionsByUserID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	owned := make(map[uuid.UUID]bool, len(collections))
+	for _, c := range collections {
+		owned[c.ID] = true
+	}
+	for _, id := range collectionIDs {
+		if !owned[id] {
+			return nil, ErrInvalidCollection
+		}
+	}
+
+	if len(favoriteEntryIDs) > 0 {
+		favorites, err := s.entryRepo.ListPublicEntriesByIDs(ctx, userID, favoriteEntryIDs, collectionIDs)
+		if err != nil {
+			return nil, err
+		}
+		if len(favorites) != len(favoriteEntryIDs) {
+			return nil, ErrInvalidFavorite
+		}
+	}
+
+	return s.profileRepo.SaveProfile(ctx, userID, handle, public, bio, collectionIDs, favoriteEntryIDs, releasedHandleHold)
+}
+
+// GetPublicProfile retrieves a public profile by handle. Only public entries
+// are shown: collections deleted since the profile was saved, and favorites
+// moved out of the profile collections or made private, are left out.
+func (s *ProfileService) GetPublicProfile(ctx context.Context, handle string) (*PublicProfile, error) {
+	profile, err := s.profileRepo.GetPublicProfileByHandle(ctx, NormalizeHandle(handle))
+	if err != nil {
+		return nil, err
+	}
+
+	all, err := s.collectionRepo.GetCollectionsByUserID(ctx, profile.UserID)
+	if err != nil {
+		return nil, err
+	}
+	byID := make(map[uuid.UUID]*repository.Collection, len(all))
+	for _, c := range all {
+		byID[c.ID] = c
+	}
+	collections := make([]*repository.Collection, 0, len(profile.CollectionIDs))
+	collectionIDs := make([]uuid.UUID, 0, len(profile.CollectionIDs))
+	for _, id := range profile.CollectionIDs {
+		if c, ok := byID[id]; ok {
+			collections = append(collections, c)
+			collectionIDs = append(collectionIDs, id)
+		}
+	}
+
+	// Entry counts only include what visitors can see
+	counts, err := s.profileRepo.CountPublicEntries(ctx, profile.UserID, collectionIDs)
+	if err != nil {
+		return nil, err
+	}
+	for _, c := range collections {
+		c.EntryCount = counts[c.ID]
+	}
+
+	stats, err := s.profileRepo.GetProfileStats(ctx, profile.UserID, collectionIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	favorites := []*repository.EntryWithImages{}
+	if len(profile.FavoriteEntryIDs) > 0 && len(collectionIDs) > 0 {
+		favorites, err = s.entryRepo.ListPublicEntriesByIDs(ctx, profile.UserID, profile.FavoriteEntryIDs, collectionIDs)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return &PublicProfile{
+		Profile:     profile,
+		Stats:       stats,
+		Collections: collections,
+		Favorites:   favorites,
+	}, nil
+}
+
+// GetSharedCollection retrieves the collection shared under slug with its
+// public entries.
+func (s *ProfileService) GetSharedCollection(ctx context.Context, slug string) (*SharedCollection, error) {
+	collection, err := s.collectionRepo.GetSharedCollection(ctx, NormalizeShareSlug(slug))
+	if err != nil {
+		return nil, err
+	}
+
+	entries, err := s.entryRepo.ListPublicCollectionEntries(ctx, collection.ID, sharedCollectionEntryLimit)
+	if err != nil {
+		return nil, err
+	}
+
+	return &SharedCollection{Collection: collection, Entries: entries}, nil
+}
+
+// dedupeIDs drops repeated ids, keeping the first occurrence's position.
+func dedupeIDs(ids []uuid.UUID) []uuid.UUID {
+	seen := make(map[uuid.UUID]bool, len(ids))
+	result := make([]uuid.UUID, 0, len(ids))
+	for _, id := range ids {
+		if !seen[id] {
+			seen[id] = true
+			result = append(result, id)
+		}
+	}
+	return result
+}
@@ -0,0 +1,40 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+)
+
+// Quota resources, reported in QuotaExceededError.Resource.
+const (
+	QuotaEntries        = "entries"
+	QuotaCollections    = "collections"
+	QuotaImagesPerEntry = "images_per_entry"
+)
+
+// ErrQuotaExceeded matches every *QuotaExceededError with errors.Is.
+var ErrQuotaExceeded = errors.New("quota exceeded")
+
+// QuotaExceededError reports which per-user limit, see config.QuotasConfig, a
+// request would exceed.
+type QuotaExceededError struct {
+	Resource string
+	Limit    int
+}
+
+func (e *QuotaExceededError) Error() string {
+	return fmt.Sprintf("%s quota of %d exceeded", e.Resource, e.Limit)
+}
+
+func (e *QuotaExceededError) Is(target error) bool {
+	return target == ErrQuotaExceeded
+}
+
+// checkQuota returns a *QuotaExceededError if a total of count items is more
+// than limit. A zero limit is unlimited.
+func checkQuota(resource string, limit, count int) error {
+	if limit > 0 && count > limit {
+		return &QuotaExceededError{Resource: resource, Limit: limit}
+	}
+	return nil
+}
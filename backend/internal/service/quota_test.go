@@ -0,0 +1,37 @@
+package service
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestCheckQuota(t *testing.T) {
+	tests := []struct {
+		name  string
+		limit int
+		count int
+		want  bool // exceeded
+	}{
+		{"under limit", 10, 9, false},
+		{"at limit", 10, 10, false},
+		{"over limit", 10, 11, true},
+		{"unlimited", 0, 1000, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := checkQuota(QuotaEntries, tt.limit, tt.count)
+			if got := errors.Is(err, ErrQuotaExceeded); got != tt.want {
+				t.Fatalf("checkQuota(%d, %d) = %v, want exceeded %t", tt.limit, tt.count, err, tt.want)
+			}
+			if !tt.want {
+				return
+			}
+
+			var quotaErr *QuotaExceededError
+			if !errors.As(err, &quotaErr) || quotaErr.Resource != QuotaEntries || quotaErr.Limit != tt.limit {
+				t.Errorf("checkQuota() = %#v, want resource %q limit %d", err, QuotaEntries, tt.limit)
+			}
+		})
+	}
+}
@@ -0,0 +1,126 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/avalarin/livlog/backend/internal/repository"
+	"github.com/google/uuid"
+)
+
+// UserRateLimit is the state of one of a user's rate limits. Limit is 0 for
+// limits that don't apply to the user; ResetsAt is nil while the full budget
+// is available.
+type UserRateLimit struct {
+	Name      string
+	Limit     int
+	Remaining int
+	ResetsAt  *time.Time
+}
+
+// RateLimitService lets admins see and reset a user's rate limits when
+// support needs to unblock someone: the per-route request budgets, AI
+// searches and email code resends.
+type RateLimitService struct {
+	userRepo        *repository.UserRepository
+	usageRepo       *repository.AISearchUsageRepository
+	aiSearchService *AISearchService // nil when AI search is disabled
+	emailLimiter    *RateLimiter
+	routeLimiters   map[string]*WindowLimiter // by the name they are counted under
+	clock           Clock
+}
+
+func NewRateLimitService(
+	userRepo *repository.UserRepository,
+	usageRepo *repository.AISearchUsageRepository,
+	aiSearchService *AISearchService,
+	emailLimiter *RateLimiter,
+	routeLimiters map[string]*WindowLimiter,
+	clock Clock,
+) *RateLimitService {
+	return &RateLimitService{
+		userRepo:        userRepo,
+		usageRepo:       usageRepo,
+		aiSearchService: aiSearchService,
+		emailLimiter:    emailLimiter,
+		routeLimiters:   routeLimiters,
+		clock:           clock,
+	}
+}
+
+// GetUserRateLimits returns the state of every rate limit of the user.
+func (s *RateLimitService) GetUserRateLimits(ctx context.Context, userID uuid.UUID) ([]UserRateLimit, error) {
+	user, err := s.userRepo.GetUserByID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	now := s.clock.Now()
+	var limits []UserRateLimit
+
+	names := make([]string, 0, len(s.routeLimiters))
+	for name := range s.routeLimiters {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		status := s.routeLimiters[name].Status(userID.String())
+		limit := UserRateLimit{Name: name, Limit: status.Limit, Remaining: status.Remaining}
+		if status.Reset > 0 {
+			resetsAt := now.Add(status.Reset)
+			limit.ResetsAt = &resetsAt
+		}
+		limits = append(limits, limit)
+	}
+
+	if s.aiSearchService != nil {
+		usage, err := s.usageRepo.GetUsage(ctx, userID)
+		if err != nil {
+			return nil, err
+		}
+		used, periodEnd := 0, (*time.Time)(nil)
+		if usage != nil && usage.PeriodEnd.After(now) {
+			used, periodEnd = usage.SearchCount, &usage.PeriodEnd
+		}
+
+		allowance := s.aiSearchService.Allowance(user.AIUsagePolicy, used, periodEnd)
+		limit := UserRateLimit{Name: "ai_search", ResetsAt: allowance.ResetsAt}
+		if allowance.Limit != nil {
+			limit.Limit, limit.Remaining = *allowance.Limit, *allowance.Remaining
+		}
+		limits = append(limits, limit)
+	}
+
+	if user.Email != nil {
+		limit := UserRateLimit{Name: "email_resend", Limit: 1, Remaining: 1}
+		if retryAfter := s.emailLimiter.GetRetryAfter(resendRateLimitKey(*user.Email)); retryAfter > 0 {
+			resetsAt := now.Add(time.Duration(retryAfter) * time.Second)
+			limit.Remaining, limit.ResetsAt = 0, &resetsAt
+		}
+		limits = append(limits, limit)
+	}
+
+	return limits, nil
+}
+
+// ResetUserRateLimits refills every rate limit of the user.
+func (s *RateLimitService) ResetUserRateLimits(ctx context.Context, userID uuid.UUID) error {
+	user, err := s.userRepo.GetUserByID(ctx, userID)
+	if err != nil {
+		return err
+	}
+
+	for _, limiter := range s.routeLimiters {
+		limiter.Reset(userID.String())
+	}
+	if user.Email != nil {
+		s.emailLimiter.Reset(resendRateLimitKey(*user.Email))
+	}
+	if err := s.usageRepo.ResetUsage(ctx, userID); err != nil {
+		return fmt.Errorf("failed to reset AI search usage: %w", err)
+	}
+
+	return nil
+}
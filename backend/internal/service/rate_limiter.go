@@ -3,21 +3,61 @@ package service
 import (
 	"sync"
 	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+var rateLimitDecisionsTotal = promauto.NewCounterVec(
+	prometheus.CounterOpts{
+		Name: "rate_limit_decisions_total",
+		Help: "Rate limit checks by limiter, key class (user, ip or email) and decision (allowed or blocked)",
+	},
+	[]string{"limiter", "key_class", "decision"},
 )
 
+// RecordRateLimitDecision counts one check of a rate limit, e.g. ("search",
+// "ip", false) for an anonymous search that was turned away.
+func RecordRateLimitDecision(limiter, keyClass string, allowed bool) {
+	decision := "allowed"
+	if !allowed {
+		decision = "blocked"
+	}
+	rateLimitDecisionsTotal.WithLabelValues(limiter, keyClass, decision).Inc()
+}
+
+// RateLimitError reports a used-up request budget and when it is refilled.
+// It matches Err (ErrRateLimitExceeded or ErrAISearchRateLimitExceeded) with
+// errors.Is.
+type RateLimitError struct {
+	Err        error
+	Limit      int
+	RetryAfter time.Duration
+}
+
+func (e *RateLimitError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *RateLimitError) Unwrap() error {
+	return e.Err
+}
+
 // RateLimiter provides in-memory rate limiting
 // Thread-safe using RWMutex
 type RateLimiter struct {
 	mu       sync.RWMutex
 	attempts map[string]time.Time
 	window   time.Duration
+	clock    Clock
 }
 
 // NewRateLimiter creates a new rate limiter with the specified time window
-func NewRateLimiter(window time.Duration) *RateLimiter {
+func NewRateLimiter(window time.Duration, clock Clock) *RateLimiter {
 	return &RateLimiter{
 		attempts: make(map[string]time.Time),
 		window:   window,
+		clock:    clock,
 	}
 }
 
@@ -28,7 +68,7 @@ func (r *RateLimiter) Allow(key string) bool {
 	defer r.mu.Unlock()
 
 	lastAttempt, exists := r.attempts[key]
-	now := time.Now()
+	now := r.clock.Now()
 
 	if !exists || now.Sub(lastAttempt) >= r.window {
 		r.attempts[key] = now
@@ -51,7 +91,7 @@ func (r *RateLimiter) Cleanup() {
 	r.mu.Lock()
 	defer r.mu.Unlock()
 
-	now := time.Now()
+	now := r.clock.Now()
 	for key, lastAttempt := range r.attempts {
 		if now.Sub(lastAttempt) >= r.window {
 			delete(r.attempts, key)
@@ -70,7 +110,7 @@ func (r *RateLimiter) GetRetryAfter(key string) int {
 		return 0
 	}
 
-	elapsed := time.Since(lastAttempt)
+	elapsed := r.clock.Now().Sub(lastAttempt)
 	if elapsed >= r.window {
 		return 0
 	}
@@ -0,0 +1,110 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/avalarin/livlog/backend/internal/config"
+	"github.com/avalarin/livlog/backend/internal/repository"
+	"github.com/google/uuid"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"go.uber.org/zap"
+)
+
+var (
+	purgedUsersTotal = promauto.NewCounter(
+		prometheus.CounterOpts{
+			Name: "retention_purged_users_total",
+			Help: "Total number of deleted accounts purged after the retention period",
+		},
+	)
+
+	purgedRowsTotal = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "retention_purged_rows_total",
+			Help: "Total number of rows purged with deleted accounts by table",
+		},
+		[]string{"table"},
+	)
+
+	purgedBytesTotal = promauto.NewCounter(
+		prometheus.CounterOpts{
+			Name: "retention_purged_image_bytes_total",
+			Help: "Total size of image data purged with deleted accounts",
+		},
+	)
+)
+
+// deletedUserStore is the part of repository.UserRepository that
+// RetentionService uses.
+type deletedUserStore interface {
+	ListPurgeableUsers(ctx context.Context, deletedBefore time.Time, limit int) ([]uuid.UUID, error)
+	PurgeUser(ctx context.Context, id uuid.UUID, dryRun bool) (*repository.PurgeStats, error)
+}
+
+// RetentionService hard-deletes soft-deleted accounts once the retention
+// period in config.RetentionConfig has passed.
+type RetentionService struct {
+	userRepo deletedUserStore
+	cfg      config.RetentionConfig
+	clock    Clock
+	logger   *zap.Logger
+}
+
+func NewRetentionService(userRepo *repository.UserRepository, cfg config.RetentionConfig, clock Clock, logger *zap.Logger) *RetentionService {
+	return &RetentionService{
+		userRepo: userRepo,
+		cfg:      cfg,
+		clock:    clock,
+		logger:   logger,
+	}
+}
+
+// PurgeDeletedUsers purges up to one batch of accounts deleted before the
+// retention period, each in its own transaction. In dry-run mode it only logs
+// what would be purged, so every run reports the same oldest batch.
+func (s *RetentionService) PurgeDeletedUsers(ctx context.Context) error {
+	if s.cfg.DeletedUsers <= 0 {
+		return nil
+	}
+
+	ids, err := s.userRepo.ListPurgeableUsers(ctx, s.clock.Now().Add(-s.cfg.DeletedUsers), s.cfg.BatchSize)
+	if err != nil {
+		return err
+	}
+
+	for _, id := range ids {
+		stats, err := s.userRepo.PurgeUser(ctx, id, s.cfg.DryRun)
+		if errors.Is(err, repository.ErrUserNotFound) {
+			continue // restored or purged by another instance
+		}
+		if err != nil {
+			return err
+		}
+
+		fields := []zap.Field{
+			zap.String("user_id", id.String()),
+			zap.Int64("collections", stats.Collections),
+			zap.Int64("entries", stats.Entries),
+			zap.Int64("images", stats.Images),
+			zap.Int64("types", stats.Types),
+			zap.Int64("image_bytes", stats.ImageBytes),
+		}
+		if s.cfg.DryRun {
+			s.logger.Info("would purge deleted user", fields...)
+			continue
+		}
+		s.logger.Info("purged deleted user", fields...)
+
+		purgedUsersTotal.Inc()
+		purgedRowsTotal.WithLabelValues("collections").Add(float64(stats.Collections))
+		purgedRowsTotal.WithLabelValues("entries").Add(float64(stats.Entries))
+		purgedRowsTotal.WithLabelValues("entry_images").Add(float64(stats.Images))
+		purgedRowsTotal.WithLabelValues("entry_types").Add(float64(stats.Types))
+		purgedBytesTotal.Add(float64(stats.ImageBytes))
+	}
+
+	return nil
+}
@@ -0,0 +1,104 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"go.uber.org/zap"
+
+	"github.com/avalarin/livlog/backend/internal/config"
+	"github.com/avalarin/livlog/backend/internal/repository"
+)
+
+// fakeDeletedUsers returns users deleted at the given times, like
+// ListPurgeableUsers does in SQL, and records the purges.
+type fakeDeletedUsers struct {
+	deletedAt map[uuid.UUID]time.Time
+	cutoff    time.Time
+	purged    []uuid.UUID
+	dryRuns   []bool
+}
+
+func (f *fakeDeletedUsers) ListPurgeableUsers(_ context.Context, deletedBefore time.Time, limit int) ([]uuid.UUID, error) {
+	f.cutoff = deletedBefore
+	var ids []uuid.UUID
+	for id, at := range f.deletedAt {
+		if at.Before(deletedBefore) && len(ids) < limit {
+			ids = append(ids, id)
+		}
+	}
+	return ids, nil
+}
+
+func (f *fakeDeletedUsers) PurgeUser(_ context.Context, id uuid.UUID, dryRun bool) (*repository.PurgeStats, error) {
+	f.purged = append(f.purged, id)
+	f.dryRuns = append(f.dryRuns, dryRun)
+	return &repository.PurgeStats{Entries: 3}, nil
+}
+
+func newRetentionTest(dryRun bool) (*RetentionService, *fakeDeletedUsers, uuid.UUID) {
+	now := time.Date(2025, 3, 1, 12, 0, 0, 0, time.UTC)
+	expired := uuid.New()
+	store := &fakeDeletedUsers{deletedAt: map[uuid.UUID]time.Time{
+		expired:    now.Add(-31 * 24 * time.Hour),
+		uuid.New(): now.Add(-29 * 24 * time.Hour), // still in the grace period
+	}}
+
+	s := &RetentionService{
+		userRepo: store,
+		cfg:      config.RetentionConfig{DeletedUsers: 30 * 24 * time.Hour, BatchSize: 10, DryRun: dryRun},
+		clock:    NewManualClock(now),
+		logger:   zap.NewNop(),
+	}
+	return s, store, expired
+}
+
+func TestPurgeDeletedUsers_GracePeriod(t *testing.T) {
+	s, store, expired := newRetentionTest(false)
+	before := testutil.ToFloat64(purgedUsersTotal)
+
+	if err := s.PurgeDeletedUsers(context.Background()); err != nil {
+		t.Fatalf("PurgeDeletedUsers() error = %v", err)
+	}
+
+	if want := s.clock.Now().Add(-30 * 24 * time.Hour); !store.cutoff.Equal(want) {
+		t.Errorf("cutoff = %s, want %s", store.cutoff, want)
+	}
+	if len(store.purged) != 1 || store.purged[0] != expired || store.dryRuns[0] {
+		t.Errorf("purged %v (dry runs %v), want only %s for real", store.purged, store.dryRuns, expired)
+	}
+	if got := testutil.ToFloat64(purgedUsersTotal) - before; got != 1 {
+		t.Errorf("purged users counted %v, want 1", got)
+	}
+}
+
+func TestPurgeDeletedUsers_DryRun(t *testing.T) {
+	s, store, expired := newRetentionTest(true)
+	before := testutil.ToFloat64(purgedUsersTotal)
+
+	if err := s.PurgeDeletedUsers(context.Background()); err != nil {
+		t.Fatalf("PurgeDeletedUsers() error = %v", err)
+	}
+
+	if len(store.purged) != 1 || store.purged[0] != expired || !store.dryRuns[0] {
+		t.Errorf("purged %v (dry runs %v), want a dry run of %s", store.purged, store.dryRuns, expired)
+	}
+	if got := testutil.ToFloat64(purgedUsersTotal) - before; got != 0 {
+		t.Errorf("dry run counted %v purged users, want 0", got)
+	}
+}
+
+func TestPurgeDeletedUsers_Disabled(t *testing.T) {
+	s, store, _ := newRetentionTest(false)
+	s.cfg.DeletedUsers = 0
+
+	if err := s.PurgeDeletedUsers(context.Background()); err != nil {
+		t.Fatalf("PurgeDeletedUsers() error = %v", err)
+	}
+	if !store.cutoff.IsZero() || len(store.purged) != 0 {
+		t.Errorf("expected no purge with retention disabled, purged %v", store.purged)
+	}
+}
@@ -0,0 +1,114 @@
+package service
+
+import (
+	"context"
+	"time"
+
+	"github.com/avalarin/livlog/backend/internal/repository"
+	"github.com/google/uuid"
+)
+
+// MonthSummary is the recap of one calendar month of entries.
+type MonthSummary struct {
+	Month           time.Time // first day of the month, UTC
+	Entries         int
+	PreviousEntries int
+	AverageScore    *float64
+	TopEntry        *repository.Entry
+	TopEntryImages  []repository.ImageMeta
+	Types           []repository.TypeCount
+}
+
+// AdminStats is the service-wide usage overview for the admin dashboard.
+// Daily series cover the last Days UTC days, oldest first, including today.
+type AdminStats struct {
+	Days             int
+	TotalUsers       int64
+	DailyActiveUsers []repository.DailyCount
+	EntriesCreated   []repository.DailyCount
+	AISearches       []repository.DailyCount
+	Storage          *repository.StorageUsage
+}
+
+type StatsService struct {
+	statsRepo *repository.StatsRepository
+	entryRepo *repository.EntryRepository
+	clock     Clock
+}
+
+func NewStatsService(statsRepo *repository.StatsRepository, entryRepo *repository.EntryRepository, clock Clock) *StatsService {
+	return &StatsService{
+		statsRepo: statsRepo,
+		entryRepo: entryRepo,
+		clock:     clock,
+	}
+}
+
+// MonthSummary summarizes the entries a user dated in the month containing
+// month, compared with the month before.
+func (s *StatsService) MonthSummary(ctx context.Context, userID uuid.UUID, month time.Time) (*MonthSummary, error) {
+	from := time.Date(month.Year(), month.Month(), 1, 0, 0, 0, 0, time.UTC)
+	to := from.AddDate(0, 1, 0)
+
+	stats, err := s.statsRepo.GetPeriodStats(ctx, userID, from, to)
+	if err != nil {
+		return nil, err
+	}
+
+	previous, err := s.statsRepo.CountEntriesInPeriod(ctx, userID, from.AddDate(0, -1, 0), from)
+	if err != nil {
+		return nil, err
+	}
+
+	summary := &MonthSummary{
+		Month:           from,
+		Entries:         stats.Entries,
+		PreviousEntries: previous,
+		AverageScore:    stats.AverageScore,
+		Types:           stats.Types,
+	}
+
+	if stats.TopEntryID != nil {
+		summary.TopEntry, err = s.entryRepo.GetEntryByID(ctx, *stats.TopEntryID)
+		if err != nil {
+			return nil, err
+		}
+		summary.TopEntryImages, err = s.entryRepo.GetEntryImageMetas(ctx, *stats.TopEntryID)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return summary, nil
+}
+
+// AdminStats aggregates usage over the last days days.
+func (s *StatsService) AdminStats(ctx context.Context, days int) (*AdminStats, error) {
+	if days <= 0 || days > 365 {
+		days = 30
+	}
+
+	now := s.clock.Now().UTC()
+	from := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC).AddDate(0, 0, -(days - 1))
+
+	stats := &AdminStats{Days: days}
+	var err error
+
+	if stats.TotalUsers, err = s.statsRepo.CountUsers(ctx); err != nil {
+		return nil, err
+	}
+	if stats.DailyActiveUsers, err = s.statsRepo.DailyActiveUsers(ctx, from); err != nil {
+		return nil, err
+	}
+	if stats.EntriesCreated, err = s.statsRepo.EntriesPerDay(ctx, from); err != nil {
+		return nil, err
+	}
+	if stats.AISearches, err = s.statsRepo.AISearchesPerDay(ctx, from); err != nil {
+		return nil, err
+	}
+	if stats.Storage, err = s.statsRepo.GetStorageUsage(ctx); err != nil {
+		return nil, err
+	}
+
+	return stats, nil
+}
@@ -0,0 +1,211 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/avalarin/livlog/backend/internal/repository"
+	"github.com/google/uuid"
+)
+
+var (
+	ErrInvalidSyncCursor = errors.New("invalid sync cursor")
+	ErrInvalidChangeSeq  = errors.New("invalid change sequence")
+	ErrInvalidMutation   = errors.New("invalid sync mutation")
+)
+
+const (
+	SyncOpUpsert = "upsert"
+	SyncOpDelete = "delete"
+
+	SyncEntityEntry      = "entry"
+	SyncEntityCollection = "collection"
+)
+
+// EntryInput holds the fields of an entry written through sync.
+type EntryInput struct {
+	CollectionID     *uuid.UUID
+	TypeID           *uuid.UUID
+	Title            string
+	OriginalTitle    *string // nil keeps the current value, "" on create
+	Language         *string // nil keeps the current value, "" on create
+	Description      string
+	Score            int
+	Priority         *int    // nil keeps the current priority, 0 on create
+	Visibility       *string // nil keeps the current visibility, private on create
+	Date             time.Time
+	AdditionalFields map[string]string
+	Images           []repository.EntryImage // nil keeps existing images
+}
+
+// CollectionInput holds the fields of a collection written through sync.
+type CollectionInput struct {
+	Name string
+	Icon CollectionIcon
+}
+
+// SyncMutation is a single change made on a client while offline. IDs are
+// chosen by the client, so an upsert of an unknown ID creates the record.
+type SyncMutation struct {
+	Op         string
+	Entity     string
+	ID         uuid.UUID
+	Entry      *EntryInput      // set for entry upserts
+	Collection *CollectionInput // set for collection upserts
+}
+
+type SyncService struct {
+	syncRepo          *repository.SyncRepository
+	entryRepo         *repository.EntryRepository
+	collectionRepo    *repository.CollectionRepository
+	entryService      *EntryService
+	collectionService *CollectionService
+}
+
+func NewSyncService(
+	syncRepo *repository.SyncRepository,
+	entryRepo *repository.EntryRepository,
+	collectionRepo *repository.CollectionRepository,
+	entryService *EntryService,
+	collectionService *CollectionService,
+) *SyncService {
+	return &SyncService{
+		syncRepo:          syncRepo,
+		entryRepo:         entryRepo,
+		collectionRepo:    collectionRepo,
+		entryService:      entryService,
+		collectionService: collectionService,
+	}
+}
+
+// Changes returns everything changed for the user since cursor. An empty
+// cursor requests a full sync. minSeq is the latest change sequence the
+// client got back from a write, or 0; the result includes that write even
+// when read from a lagging replica.
+func (s *SyncService) Changes(
+	ctx context.Context,
+	userID uuid.UUID,
+	cursor string,
+	minSeq int64,
+) (*repository.ChangeSet, error) {
+	if cursor == "" {
+		cursor = "0"
+	}
+	if _, err := strconv.ParseUint(cursor, 10, 64); err != nil {
+		return nil, ErrInvalidSyncCursor
+	}
+	if minSeq < 0 {
+		return nil, ErrInvalidChangeSeq
+	}
+
+	return s.syncRepo.GetChanges(ctx, userID, cursor, minSeq)
+}
+
+// ChangeSeq returns the change sequence to hand back after a successful
+// write. It only grows; clients echo the latest one on their next pull.
+func (s *SyncService) ChangeSeq(ctx context.Context) (int64, error) {
+	return s.syncRepo.ChangeSeq(ctx)
+}
+
+// Push applies mutations in order and returns one result per mutation: nil if
+// it was applied, otherwise the reason it was rejected. A failed mutation does
+// not stop the ones after it. Entry writes are recorded as device's, but
+// their concurrent update hints are dropped: by the time offline edits are
+// pushed the user has moved on.
+func (s *SyncService) Push(
+	ctx context.Context,
+	userID uuid.UUID,
+	device *repository.Device, // nil when the client didn't say
+	mutations []SyncMutation,
+) []error {
+	results := make([]error, len(mutations))
+	for i, m := range mutations {
+		results[i] = s.apply(ctx, userID, device, m)
+	}
+	return results
+}
+
+func (s *SyncService) apply(ctx context.Context, userID uuid.UUID, device *repository.Device, m SyncMutation) error {
+	switch {
+	case m.Entity == SyncEntityEntry && m.Op == SyncOpUpsert && m.Entry != nil:
+		return s.upsertEntry(ctx, userID, device, m.ID, m.Entry)
+	case m.Entity == SyncEntityEntry && m.Op == SyncOpDelete:
+		return ignoreNotFound(s.entryService.DeleteEntry(ctx, m.ID, userID), repository.ErrEntryNotFound)
+	case m.Entity == SyncEntityCollection && m.Op == SyncOpUpsert && m.Collection != nil:
+		return s.upsertCollection(ctx, userID, m.ID, m.Collection)
+	case m.Entity == SyncEntityCollection && m.Op == SyncOpDelete:
+		return ignoreNotFound(s.collectionService.DeleteCollection(ctx, m.ID, userID), repository.ErrCollectionNotFound)
+	default:
+		return fmt.Errorf("%w: %s %s", ErrInvalidMutation, m.Op, m.Entity)
+	}
+}
+
+func (s *SyncService) upsertEntry(ctx context.Context, userID uuid.UUID, device *repository.Device, id uuid.UUID, in *EntryInput) error {
+	existing, err := s.entryRepo.GetEntryByID(ctx, id)
+	if errors.Is(err, repository.ErrEntryNotFound) {
+		var priority int
+		if in.Priority != nil {
+			priority = *in.Priority
+		}
+		var originalTitle, language string
+		if in.OriginalTitle != nil {
+			originalTitle = *in.OriginalTitle
+		}
+		if in.Language != nil {
+			language = *in.Language
+		}
+		var visibility string
+		if in.Visibility != nil {
+			visibility = *in.Visibility
+		}
+		_, err = s.entryService.CreateEntryWithID(
+			ctx, &id, userID, in.CollectionID, in.TypeID, in.Title, in.Description, originalTitle, language,
+			in.Score, priority, visibility, in.Date, in.AdditionalFields, in.Images, nil, device,
+		)
+		return err
+	}
+	if err != nil {
+		return err
+	}
+
+	// The ID belongs to another user: report it as missing rather than leak it
+	if existing.UserID != userID {
+		return repository.ErrEntryNotFound
+	}
+
+	_, _, err = s.entryService.UpdateEntry(
+		ctx, id, userID, in.CollectionID, in.TypeID, in.Title, in.Description, in.OriginalTitle, in.Language,
+		in.Score, in.Priority, in.Visibility, in.Date, in.AdditionalFields, in.Images, device,
+	)
+	return err
+}
+
+func (s *SyncService) upsertCollection(ctx context.Context, userID, id uuid.UUID, in *CollectionInput) error {
+	existing, err := s.collectionRepo.GetCollectionByID(ctx, id)
+	if errors.Is(err, repository.ErrCollectionNotFound) {
+		_, err = s.collectionService.CreateCollectionWithID(ctx, &id, userID, in.Name, in.Icon, nil)
+		return err
+	}
+	if err != nil {
+		return err
+	}
+
+	if existing.UserID != userID {
+		return repository.ErrCollectionNotFound
+	}
+
+	_, err = s.collectionService.UpdateCollection(ctx, id, userID, in.Name, in.Icon, nil)
+	return err
+}
+
+// ignoreNotFound treats deleting an already-deleted record as success, so
+// retried pushes are idempotent.
+func ignoreNotFound(err, notFound error) error {
+	if errors.Is(err, notFound) {
+		return nil
+	}
+	return err
+}
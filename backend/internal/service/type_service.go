@@ -3,6 +3,7 @@ package service
 import (
 	"context"
 	"errors"
+	"fmt"
 	"strings"
 
 	"github.com/avalarin/livlog/backend/internal/repository"
@@ -12,8 +13,68 @@ import (
 var (
 	ErrInvalidTypeName = errors.New("type name must be between 1 and 50 characters")
 	ErrInvalidTypeIcon = errors.New("icon must be between 1 and 20 characters")
+	ErrInvalidScale    = errors.New("invalid score scale")
 )
 
+// scoreScales are the supported score scales with their default labels.
+var scoreScales = map[string][]string{
+	repository.ScoreScaleReaction: {"Bad", "Okay", "Great"},
+	repository.ScoreScaleThumbs:   {"Thumbs down", "Thumbs up"},
+	repository.ScoreScaleStars:    {"1 star", "2 stars", "3 stars", "4 stars", "5 stars"},
+	repository.ScoreScalePoints:   {"1", "2", "3", "4", "5", "6", "7", "8", "9", "10"},
+}
+
+// DefaultScoreScale applies to entries without a type and to types created
+// without an explicit scale.
+var DefaultScoreScale = repository.ScoreScale{
+	Kind:   repository.ScoreScaleReaction,
+	Max:    3,
+	Labels: scoreScales[repository.ScoreScaleReaction],
+}
+
+// newScoreScale builds a scale of the given kind, optionally with custom
+// labels, one per rated value.
+func newScoreScale(kind string, labels []string) (repository.ScoreScale, error) {
+	defaults, ok := scoreScales[kind]
+	if !ok {
+		return repository.ScoreScale{}, fmt.Errorf("%w: unknown kind %q", ErrInvalidScale, kind)
+	}
+
+	if len(labels) == 0 {
+		labels = defaults
+	}
+	if len(labels) != len(defaults) {
+		return repository.ScoreScale{}, fmt.Errorf("%w: %s scale needs %d labels", ErrInvalidScale, kind, len(defaults))
+	}
+
+	trimmed := make([]string, len(labels))
+	for i, label := range labels {
+		trimmed[i] = strings.TrimSpace(label)
+		if len(trimmed[i]) < 1 || len(trimmed[i]) > 30 {
+			return repository.ScoreScale{}, fmt.Errorf("%w: labels must be between 1 and 30 characters", ErrInvalidScale)
+		}
+	}
+
+	return repository.ScoreScale{Kind: kind, Max: len(trimmed), Labels: trimmed}, nil
+}
+
+// ScoreLabel returns the label of a score on the scale, or "" for unrated
+// and out-of-range scores.
+func ScoreLabel(scale repository.ScoreScale, score int) string {
+	if score < 1 || score > len(scale.Labels) {
+		return ""
+	}
+	return scale.Labels[score-1]
+}
+
+// validateScore checks a score against the scale; 0 (not rated) is always valid.
+func validateScore(scale repository.ScoreScale, score int) error {
+	if score < 0 || score > scale.Max {
+		return fmt.Errorf("%w: %s scale allows 0 to %d", ErrInvalidScore, scale.Kind, scale.Max)
+	}
+	return nil
+}
+
 type TypeService struct {
 	typeRepo *repository.TypeRepository
 }
@@ -30,6 +91,27 @@ func (s *TypeService) GetAllTypes(
 	return s.typeRepo.GetAllTypes(ctx, userID)
 }
 
+// ListTypes returns a page of system types plus the user's own types,
+// starting after cursor. A zero limit returns all remaining types.
+func (s *TypeService) ListTypes(
+	ctx context.Context,
+	userID uuid.UUID,
+	cursor string,
+	limit int,
+) (*Page[*repository.EntryType], error) {
+	after, err := decodePageCursor(cursor)
+	if err != nil {
+		return nil, err
+	}
+
+	types, next, err := s.typeRepo.ListTypesPage(ctx, userID, after, pageLimit(limit))
+	if err != nil {
+		return nil, err
+	}
+
+	return &Page[*repository.EntryType]{Items: types, NextCursor: encodePageCursor(next)}, nil
+}
+
 // GetTypeByID returns a type if it is a system type or owned by the given user.
 func (s *TypeService) GetTypeByID(
 	ctx context.Context,
@@ -49,11 +131,15 @@ func (s *TypeService) GetTypeByID(
 	return t, nil
 }
 
-// CreateType creates a new user-owned entry type with validation.
+// CreateType creates a new user-owned entry type with validation. An empty
+// scaleKind uses the default reaction scale; labels optionally rename the
+// scale's values.
 func (s *TypeService) CreateType(
 	ctx context.Context,
 	userID uuid.UUID,
 	name, icon string,
+	scaleKind string,
+	scaleLabels []string,
 ) (*repository.EntryType, error) {
 	name = strings.TrimSpace(name)
 	if len(name) < 1 || len(name) > 50 {
@@ -65,5 +151,13 @@ func (s *TypeService) CreateType(
 		return nil, ErrInvalidTypeIcon
 	}
 
-	return s.typeRepo.CreateType(ctx, &userID, name, icon)
+	if scaleKind == "" {
+		scaleKind = repository.ScoreScaleReaction
+	}
+	scale, err := newScoreScale(scaleKind, scaleLabels)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.typeRepo.CreateType(ctx, &userID, name, icon, scale)
 }
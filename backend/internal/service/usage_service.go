@@ -0,0 +1,156 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/avalarin/livlog/backend/internal/repository"
+	"github.com/google/uuid"
+)
+
+const (
+	// usageWindow is how far back GET /auth/me/usage looks, in days.
+	usageWindow = 30
+	// usageRetention is how long daily usage is kept, long enough to bill a
+	// year back.
+	usageRetention = 400 * 24 * time.Hour
+)
+
+// Usage is what a user used over the last usageWindow days, today included,
+// and what they store now.
+type Usage struct {
+	From         time.Time // first day, UTC
+	To           time.Time // today, UTC
+	APICalls     int64
+	AISearches   int
+	Exports      int
+	Images       int64
+	StorageBytes int64
+	Days         []repository.DailyUsage // every day from From to To
+}
+
+type usageKey struct {
+	userID uuid.UUID
+	day    time.Time
+}
+
+// UsageService counts API calls per user and reports usage. Calls are counted
+// in memory, since writing each one would double the database load, and
+// written by Flush; AI searches and exports are recorded where they happen.
+type UsageService struct {
+	usageRepo *repository.UsageRepository
+	userRepo  *repository.UserRepository
+	clock     Clock
+
+	mu      sync.Mutex
+	pending map[usageKey]int64
+}
+
+func NewUsageService(usageRepo *repository.UsageRepository, userRepo *repository.UserRepository, clock Clock) *UsageService {
+	return &UsageService{
+		usageRepo: usageRepo,
+		userRepo:  userRepo,
+		clock:     clock,
+		pending:   make(map[usageKey]int64),
+	}
+}
+
+// RecordCall counts an API call by the user.
+func (s *UsageService) RecordCall(userID uuid.UUID) {
+	key := usageKey{userID: userID, day: utcDay(s.clock.Now())}
+	s.mu.Lock()
+	s.pending[key]++
+	s.mu.Unlock()
+}
+
+// Flush writes the calls counted since the last flush. Calls that fail to
+// write are kept for the next one.
+func (s *UsageService) Flush(ctx context.Context) error {
+	s.mu.Lock()
+	pending := s.pending
+	s.pending = make(map[usageKey]int64)
+	s.mu.Unlock()
+
+	byDay := make(map[time.Time]map[uuid.UUID]int64)
+	for key, n := range pending {
+		if byDay[key.day] == nil {
+			byDay[key.day] = make(map[uuid.UUID]int64)
+		}
+		byDay[key.day][key.userID] = n
+	}
+
+	var errs []error
+	for day, calls := range byDay {
+		if err := s.usageRepo.AddAPICalls(ctx, day, calls); err != nil {
+			errs = append(errs, err)
+			s.mu.Lock()
+			for userID, n := range calls {
+				s.pending[usageKey{userID: userID, day: day}] += n
+			}
+			s.mu.Unlock()
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// GetUsage returns the user's usage. API calls include those this instance
+// hasn't flushed yet; other instances' show up within a flush interval.
+func (s *UsageService) GetUsage(ctx context.Context, userID uuid.UUID) (*Usage, error) {
+	to := utcDay(s.clock.Now())
+	from := to.AddDate(0, 0, -(usageWindow - 1))
+
+	overview, err := s.userRepo.GetUserOverview(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	stored, err := s.usageRepo.ListDailyUsage(ctx, userID, from)
+	if err != nil {
+		return nil, err
+	}
+
+	usage := &Usage{
+		From:         from,
+		To:           to,
+		Images:       overview.Images,
+		StorageBytes: overview.ImageBytes,
+		Days:         make([]repository.DailyUsage, usageWindow),
+	}
+	for i := range usage.Days {
+		usage.Days[i].Day = from.AddDate(0, 0, i)
+	}
+	for _, d := range stored {
+		if i := int(d.Day.Sub(from).Hours() / 24); i >= 0 && i < usageWindow {
+			usage.Days[i].APICalls += d.APICalls
+			usage.Days[i].AISearches += d.AISearches
+			usage.Days[i].Exports += d.Exports
+		}
+	}
+
+	s.mu.Lock()
+	for key, n := range s.pending {
+		if i := int(key.day.Sub(from).Hours() / 24); key.userID == userID && i >= 0 && i < usageWindow {
+			usage.Days[i].APICalls += n
+		}
+	}
+	s.mu.Unlock()
+
+	for _, d := range usage.Days {
+		usage.APICalls += d.APICalls
+		usage.AISearches += d.AISearches
+		usage.Exports += d.Exports
+	}
+	return usage, nil
+}
+
+// CleanupOld removes daily usage older than the retention period.
+func (s *UsageService) CleanupOld(ctx context.Context) (int64, error) {
+	return s.usageRepo.DeleteUsageOlderThan(ctx, usageRetention)
+}
+
+// utcDay returns the start of t's day in UTC.
+func utcDay(t time.Time) time.Time {
+	y, m, d := t.UTC().Date()
+	return time.Date(y, m, d, 0, 0, 0, 0, time.UTC)
+}
@@ -0,0 +1,107 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/avalarin/livlog/backend/internal/repository"
+	"github.com/google/uuid"
+)
+
+// Warning codes. Like error codes they are stable; messages are for people.
+const (
+	// WarningUnknownField: an additional field the entry's type doesn't
+	// define. It is stored, but the app won't show it.
+	WarningUnknownField = "UNKNOWN_FIELD"
+	// WarningLargeImage: an image above recommendedImageBytes. It is stored,
+	// but slows down every screen that shows it.
+	WarningLargeImage = "LARGE_IMAGE"
+	// WarningSeedImageNotFound: a seed_image_ids id that is not a seed image.
+	// It is skipped; the other seed images are still copied.
+	WarningSeedImageNotFound = "SEED_IMAGE_NOT_FOUND"
+	// WarningCollectionSuggested: an entry created without a collection;
+	// Suggestion holds the collection it would likely go in.
+	WarningCollectionSuggested = "COLLECTION_SUGGESTED"
+	// WarningCollectionAssigned: an entry created without a collection was
+	// filed in the suggested one, as the user's settings ask.
+	WarningCollectionAssigned = "COLLECTION_ASSIGNED"
+)
+
+// recommendedImageBytes is the image size above which writes warn. Entry
+// images are shown in lists, where anything bigger is wasted.
+const recommendedImageBytes = 1 << 20
+
+// Warning is a non-fatal issue with a write that was accepted anyway. Field
+// points at the offending part of the request, e.g. "additional_fields.Year";
+// Suggestion is a value the client can offer to put there.
+type Warning struct {
+	Code       string `json:"code"`
+	Message    string `json:"message"`
+	Field      string `json:"field,omitempty"`
+	Suggestion string `json:"suggestion,omitempty"`
+}
+
+type warningsKey struct{}
+
+type warningList struct {
+	mu    sync.Mutex
+	items []Warning
+}
+
+// WithWarnings returns a context that collects the warnings raised by service
+// calls made with it. Without one, warnings are dropped.
+func WithWarnings(ctx context.Context) context.Context {
+	return context.WithValue(ctx, warningsKey{}, &warningList{})
+}
+
+// Warnings returns the warnings collected on a context from WithWarnings.
+func Warnings(ctx context.Context) []Warning {
+	list, ok := ctx.Value(warningsKey{}).(*warningList)
+	if !ok {
+		return nil
+	}
+	list.mu.Lock()
+	defer list.mu.Unlock()
+	return append([]Warning(nil), list.items...)
+}
+
+func warn(ctx context.Context, w Warning) {
+	list, ok := ctx.Value(warningsKey{}).(*warningList)
+	if !ok {
+		return
+	}
+	list.mu.Lock()
+	list.items = append(list.items, w)
+	list.mu.Unlock()
+}
+
+// warnLargeImages warns about each image above recommendedImageBytes.
+func warnLargeImages(ctx context.Context, images []repository.EntryImage) {
+	for i, img := range images {
+		if len(img.ImageData) > recommendedImageBytes {
+			warn(ctx, Warning{
+				Code:    WarningLargeImage,
+				Message: fmt.Sprintf("image is %d KB, more than the recommended %d KB", len(img.ImageData)>>10, recommendedImageBytes>>10),
+				Field:   fmt.Sprintf("images[%d]", i),
+			})
+		}
+	}
+}
+
+// warnMissingSeedImages warns about each requested seed image that wasn't
+// found, pointing at its place in seed_image_ids.
+func warnMissingSeedImages(ctx context.Context, requested, missing []uuid.UUID) {
+	for _, id := range missing {
+		for i, r := range requested {
+			if r == id {
+				warn(ctx, Warning{
+					Code:    WarningSeedImageNotFound,
+					Message: fmt.Sprintf("seed image %s not found", id),
+					Field:   fmt.Sprintf("seed_image_ids[%d]", i),
+				})
+				break
+			}
+		}
+	}
+}
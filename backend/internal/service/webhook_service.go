@@ -0,0 +1,285 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/avalarin/livlog/backend/internal/repository"
+	"github.com/google/uuid"
+	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
+	"go.uber.org/zap"
+)
+
+var (
+	ErrInvalidWebhookURL   = errors.New("webhook url must be an absolute https url")
+	ErrInvalidWebhookEvent = errors.New("unknown webhook event")
+	ErrWebhookLimitReached = errors.New("webhook limit reached")
+)
+
+// Webhook event names.
+const (
+	EventEntryCreated = "entry.created"
+	EventEntryUpdated = "entry.updated"
+	EventEntryDeleted = "entry.deleted"
+)
+
+// WebhookEvents lists every event a webhook can subscribe to.
+var WebhookEvents = []string{EventEntryCreated, EventEntryUpdated, EventEntryDeleted}
+
+const (
+	maxWebhooksPerUser = 10
+
+	webhookBatchSize      = 20
+	webhookRequestTimeout = 10 * time.Second
+	// webhookClaimLease must outlast a batch; unresolved claims are retried after it.
+	webhookClaimLease = 2 * time.Minute
+
+	// Retries back off exponentially from webhookRetryBase: 1m, 2m, 4m, ... ~2h in total.
+	webhookMaxAttempts = 8
+	webhookRetryBase   = time.Minute
+
+	webhookDeliveryRetention = 7 * 24 * time.Hour
+)
+
+type WebhookService struct {
+	webhookRepo *repository.WebhookRepository
+	httpClient  *http.Client
+	resolver    *net.Resolver
+	clock       Clock
+	logger      *zap.Logger
+}
+
+func NewWebhookService(webhookRepo *repository.WebhookRepository, clock Clock, logger *zap.Logger) *WebhookService {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	// Connections go straight to the receiver so webhookDialControl sees its
+	// address, not a proxy's
+	transport.Proxy = nil
+	transport.DialContext = (&net.Dialer{
+		Timeout:   webhookRequestTimeout,
+		KeepAlive: 30 * time.Second,
+		Control:   webhookDialControl,
+	}).DialContext
+
+	return &WebhookService{
+		webhookRepo: webhookRepo,
+		resolver:    net.DefaultResolver,
+		httpClient: &http.Client{
+			Timeout:   webhookRequestTimeout,
+			Transport: otelhttp.NewTransport(transport),
+			// A redirect would resend the signed body to a URL the user never registered
+			CheckRedirect: func(*http.Request, []*http.Request) error {
+				return http.ErrUseLastResponse
+			},
+		},
+		clock:  clock,
+		logger: logger,
+	}
+}
+
+// CreateWebhook registers a webhook and generates its signing secret. An empty
+// events list subscribes to all events. URLs on the server's own network are
+// rejected with ErrWebhookAddressBlocked.
+func (s *WebhookService) CreateWebhook(
+	ctx context.Context,
+	userID uuid.UUID,
+	rawURL string,
+	events []string,
+) (*repository.Webhook, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil || u.Scheme != "https" || u.Host == "" {
+		return nil, ErrInvalidWebhookURL
+	}
+	if err := checkWebhookHost(ctx, s.resolver, u.Hostname()); err != nil {
+		return nil, err
+	}
+
+	if len(events) == 0 {
+		events = WebhookEvents
+	}
+	for _, e := range events {
+		if !isWebhookEvent(e) {
+			return nil, fmt.Errorf("%w: %q", ErrInvalidWebhookEvent, e)
+		}
+	}
+
+	count, err := s.webhookRepo.CountWebhooks(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if count >= maxWebhooksPerUser {
+		return nil, ErrWebhookLimitReached
+	}
+
+	secret, err := generateWebhookSecret()
+	if err != nil {
+		return nil, err
+	}
+
+	return s.webhookRepo.CreateWebhook(ctx, userID, u.String(), secret, events)
+}
+
+// GetWebhooksByUserID retrieves all webhooks for a user
+func (s *WebhookService) GetWebhooksByUserID(ctx context.Context, userID uuid.UUID) ([]*repository.Webhook, error) {
+	return s.webhookRepo.GetWebhooksByUserID(ctx, userID)
+}
+
+// DeleteWebhook deletes a webhook
+func (s *WebhookService) DeleteWebhook(ctx context.Context, id, userID uuid.UUID) error {
+	return s.webhookRepo.DeleteWebhook(ctx, id, userID)
+}
+
+// DispatchDue sends batches of due deliveries until the queue is drained.
+// Failed deliveries are rescheduled rather than reported as errors.
+func (s *WebhookService) DispatchDue(ctx context.Context) error {
+	for ctx.Err() == nil {
+		deliveries, err := s.webhookRepo.ClaimDueDeliveries(ctx, webhookBatchSize, webhookClaimLease)
+		if err != nil {
+			return err
+		}
+
+		var wg sync.WaitGroup
+		for _, d := range deliveries {
+			wg.Add(1)
+			go func(d *repository.WebhookDelivery) {
+				defer wg.Done()
+				s.deliver(ctx, d)
+			}(d)
+		}
+		wg.Wait()
+
+		if len(deliveries) < webhookBatchSize {
+			return nil
+		}
+	}
+	return ctx.Err()
+}
+
+// CleanupDeliveries removes delivered and permanently failed deliveries older
+// than the retention period.
+func (s *WebhookService) CleanupDeliveries(ctx context.Context) (int64, error) {
+	return s.webhookRepo.DeleteFinishedDeliveries(ctx, webhookDeliveryRetention)
+}
+
+func (s *WebhookService) deliver(ctx context.Context, d *repository.WebhookDelivery) {
+	err := s.send(ctx, d)
+	if err == nil {
+		if err := s.webhookRepo.MarkDelivered(ctx, d.ID); err != nil {
+			s.logger.Error("failed to mark webhook delivered", zap.String("delivery_id", d.ID.String()), zap.Error(err))
+		}
+		return
+	}
+
+	fields := []zap.Field{
+		zap.String("delivery_id", d.ID.String()),
+		zap.String("webhook_id", d.WebhookID.String()),
+		zap.Int("attempt", d.Attempts),
+		zap.Error(err),
+	}
+
+	if d.Attempts >= webhookMaxAttempts {
+		s.logger.Warn("webhook delivery failed permanently", fields...)
+		if err := s.webhookRepo.FailDelivery(ctx, d.ID, err.Error()); err != nil {
+			s.logger.Error("failed to record webhook failure", zap.String("delivery_id", d.ID.String()), zap.Error(err))
+		}
+		return
+	}
+
+	s.logger.Info("webhook delivery failed, will retry", fields...)
+	next := s.clock.Now().Add(webhookRetryDelay(d.Attempts))
+	if err := s.webhookRepo.RescheduleDelivery(ctx, d.ID, err.Error(), next); err != nil {
+		s.logger.Error("failed to reschedule webhook delivery", zap.String("delivery_id", d.ID.String()), zap.Error(err))
+	}
+}
+
+// webhookRetryDelay returns how long to wait after the given failed attempt,
+// counting from 1.
+func webhookRetryDelay(attempt int) time.Duration {
+	return webhookRetryBase << (attempt - 1)
+}
+
+func (s *WebhookService) send(ctx context.Context, d *repository.WebhookDelivery) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, d.URL, bytes.NewReader(d.Payload))
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+
+	timestamp := strconv.FormatInt(s.clock.Now().Unix(), 10)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "Livlog-Webhooks/1.0")
+	req.Header.Set("X-Livlog-Event", d.Event)
+	req.Header.Set("X-Livlog-Delivery", d.ID.String())
+	req.Header.Set("X-Livlog-Timestamp", timestamp)
+	req.Header.Set("X-Livlog-Signature", "sha256="+SignWebhookPayload(d.Secret, timestamp, d.Payload))
+
+	resp, err := s.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("request failed: %w", err)
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("unexpected status %d", resp.StatusCode)
+	}
+
+	return nil
+}
+
+// SignWebhookPayload returns the hex HMAC-SHA256 of "<timestamp>.<body>" keyed
+// with the webhook secret, as sent in X-Livlog-Signature. Receivers recompute
+// it to verify the request and reject stale timestamps to prevent replays.
+func SignWebhookPayload(secret, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+func generateWebhookSecret() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("failed to generate webhook secret: %w", err)
+	}
+	return "whsec_" + hex.EncodeToString(b), nil
+}
+
+// webhookEventFor maps an outbox event to the webhook event it triggers, or ""
+// if webhooks do not cover it.
+func webhookEventFor(e repository.OutboxEvent) string {
+	if e.Entity != "entry" {
+		return ""
+	}
+	switch e.Op {
+	case "insert":
+		return EventEntryCreated
+	case "update":
+		return EventEntryUpdated
+	case "delete":
+		return EventEntryDeleted
+	default:
+		return ""
+	}
+}
+
+func isWebhookEvent(event string) bool {
+	for _, e := range WebhookEvents {
+		if e == event {
+			return true
+		}
+	}
+	return false
+}
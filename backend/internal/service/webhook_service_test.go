@@ -0,0 +1,49 @@
+package service
+
+import (
+	"testing"
+	"time"
+)
+
+func TestSignWebhookPayload(t *testing.T) {
+	// HMAC-SHA256 of `1738404000.{"id":"1"}` keyed with "whsec_test"
+	const want = "332a34351405b29bb765df13845549eac811bd1f0af09d57656339f36b05789c"
+
+	if got := SignWebhookPayload("whsec_test", "1738404000", []byte(`{"id":"1"}`)); got != want {
+		t.Errorf("SignWebhookPayload() = %s, want %s", got, want)
+	}
+
+	// The timestamp is signed too, so a replayed body with a fresh timestamp fails
+	if got := SignWebhookPayload("whsec_test", "1738404001", []byte(`{"id":"1"}`)); got == want {
+		t.Error("expected a different signature for another timestamp")
+	}
+}
+
+func TestWebhookRetryDelay(t *testing.T) {
+	want := []time.Duration{
+		time.Minute,
+		2 * time.Minute,
+		4 * time.Minute,
+		8 * time.Minute,
+		16 * time.Minute,
+		32 * time.Minute,
+		64 * time.Minute,
+	}
+
+	var total time.Duration
+	for i, delay := range want {
+		attempt := i + 1
+		if got := webhookRetryDelay(attempt); got != delay {
+			t.Errorf("webhookRetryDelay(%d) = %s, want %s", attempt, got, delay)
+		}
+		total += webhookRetryDelay(attempt)
+	}
+
+	// The last attempt isn't retried; the ones before span about two hours
+	if len(want) != webhookMaxAttempts-1 {
+		t.Errorf("expected %d retries, got %d", webhookMaxAttempts-1, len(want))
+	}
+	if total != 127*time.Minute {
+		t.Errorf("total backoff = %s, want 2h7m", total)
+	}
+}
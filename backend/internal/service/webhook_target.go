@@ -0,0 +1,81 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/netip"
+	"syscall"
+)
+
+// ErrWebhookAddressBlocked is returned for webhook URLs that point at, or
+// resolve to, an address on the server's own network.
+var ErrWebhookAddressBlocked = errors.New("webhook url must point to a public address")
+
+// blockedWebhookNets are non-public ranges the net.IP predicates don't cover.
+var blockedWebhookNets = []netip.Prefix{
+	netip.MustParsePrefix("0.0.0.0/8"),     // "this network"
+	netip.MustParsePrefix("100.64.0.0/10"), // carrier-grade NAT, also cloud metadata (100.100.100.200)
+	netip.MustParsePrefix("192.0.0.0/24"),  // IETF protocol assignments
+	netip.MustParsePrefix("198.18.0.0/15"), // benchmarking
+	netip.MustParsePrefix("64:ff9b::/96"),  // NAT64, which embeds IPv4 addresses
+}
+
+// blockedWebhookIP reports whether deliveries must not go to ip: loopback,
+// private, link-local (including the 169.254.169.254 metadata endpoint),
+// unspecified, multicast and the blockedWebhookNets.
+func blockedWebhookIP(ip net.IP) bool {
+	if ip.IsLoopback() || ip.IsPrivate() || ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() ||
+		ip.IsUnspecified() || ip.IsMulticast() || ip.IsInterfaceLocalMulticast() {
+		return true
+	}
+	addr, ok := netip.AddrFromSlice(ip)
+	if !ok {
+		return true
+	}
+	for _, prefix := range blockedWebhookNets {
+		if prefix.Contains(addr.Unmap()) {
+			return true
+		}
+	}
+	return false
+}
+
+// checkWebhookHost rejects a host that is, or resolves to, a blocked address.
+// Resolution can change later, so webhookDialControl checks again on every
+// delivery.
+func checkWebhookHost(ctx context.Context, resolver *net.Resolver, host string) error {
+	if ip := net.ParseIP(host); ip != nil {
+		if blockedWebhookIP(ip) {
+			return ErrWebhookAddressBlocked
+		}
+		return nil
+	}
+
+	addrs, err := resolver.LookupIPAddr(ctx, host)
+	if err != nil {
+		return fmt.Errorf("%w: %s does not resolve", ErrInvalidWebhookURL, host)
+	}
+	for _, addr := range addrs {
+		if blockedWebhookIP(addr.IP) {
+			return ErrWebhookAddressBlocked
+		}
+	}
+	return nil
+}
+
+// webhookDialControl refuses connections to blocked addresses. It runs after
+// DNS resolution, so a host that resolved to a public address when the
+// webhook was registered can't be pointed at an internal one later.
+func webhookDialControl(_, address string, _ syscall.RawConn) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+	ip := net.ParseIP(host)
+	if ip == nil || blockedWebhookIP(ip) {
+		return fmt.Errorf("%w: %s", ErrWebhookAddressBlocked, host)
+	}
+	return nil
+}
@@ -0,0 +1,91 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+
+	"github.com/avalarin/livlog/backend/internal/repository"
+)
+
+func TestBlockedWebhookIP(t *testing.T) {
+	tests := []struct {
+		ip   string
+		want bool
+	}{
+		{"127.0.0.1", true},
+		{"::1", true},
+		{"10.1.2.3", true},
+		{"172.16.0.1", true},
+		{"192.168.1.1", true},
+		{"169.254.169.254", true},
+		{"100.100.100.200", true},
+		{"0.0.0.0", true},
+		{"::", true},
+		{"fe80::1", true},
+		{"fd00:ec2::254", true},
+		{"::ffff:127.0.0.1", true},
+		{"224.0.0.1", true},
+		{"93.184.216.34", false},
+		{"2606:2800:220:1:248:1893:25c8:1946", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.ip, func(t *testing.T) {
+			if got := blockedWebhookIP(net.ParseIP(tt.ip)); got != tt.want {
+				t.Errorf("blockedWebhookIP(%s) = %t, want %t", tt.ip, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCreateWebhook_RejectsInternalAddresses(t *testing.T) {
+	s := NewWebhookService(nil, SystemClock, zap.NewNop())
+
+	for _, rawURL := range []string{
+		"https://127.0.0.1/hook",
+		"https://10.0.0.5:8443/hook",
+		"https://169.254.169.254/latest/meta-data/",
+		"https://[::1]/hook",
+		"https://localhost/hook",
+	} {
+		t.Run(rawURL, func(t *testing.T) {
+			_, err := s.CreateWebhook(context.Background(), uuid.New(), rawURL, nil)
+			if !errors.Is(err, ErrWebhookAddressBlocked) {
+				t.Errorf("CreateWebhook(%s) error = %v, want ErrWebhookAddressBlocked", rawURL, err)
+			}
+		})
+	}
+}
+
+func TestWebhookDelivery_RefusesInternalAddresses(t *testing.T) {
+	// Stands in for a host that resolved to a public address at registration
+	// and to a loopback one now
+	hit := false
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hit = true
+	}))
+	defer server.Close()
+
+	s := NewWebhookService(nil, SystemClock, zap.NewNop())
+	err := s.send(context.Background(), &repository.WebhookDelivery{
+		ID:      uuid.New(),
+		URL:     server.URL,
+		Event:   EventEntryCreated,
+		Secret:  "whsec_test",
+		Payload: []byte(`{}`),
+	})
+
+	if !errors.Is(err, ErrWebhookAddressBlocked) {
+		t.Errorf("send() error = %v, want ErrWebhookAddressBlocked", err)
+	}
+	if hit {
+		t.Error("expected the request not to reach the server")
+	}
+}
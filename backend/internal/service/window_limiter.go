@@ -0,0 +1,126 @@
+package service
+
+import (
+	"sync"
+	"time"
+)
+
+// Limiter is a per-key request budget used by rate-limited routes.
+// Implementations must be safe for concurrent use.
+type Limiter interface {
+	// Take counts one request for key and reports whether it is allowed.
+	Take(key string) LimitStatus
+}
+
+// LimitStatus is the outcome of Limiter.Take. A zero Limit means the limiter
+// is disabled and every request is allowed.
+type LimitStatus struct {
+	Allowed   bool
+	Limit     int
+	Remaining int
+	Reset     time.Duration // until the budget is refilled
+}
+
+// WindowLimiter allows up to a fixed number of requests per key in each
+// window. Windows start at a key's first request.
+type WindowLimiter struct {
+	mu       sync.Mutex
+	limit    int
+	window   time.Duration
+	counters map[string]*windowCounter
+	clock    Clock
+}
+
+type windowCounter struct {
+	start time.Time
+	count int
+}
+
+// NewWindowLimiter creates a limiter allowing limit requests per window.
+// A limit of 0 disables it.
+func NewWindowLimiter(limit int, window time.Duration, clock Clock) *WindowLimiter {
+	return &WindowLimiter{
+		limit:    limit,
+		window:   window,
+		counters: make(map[string]*windowCounter),
+		clock:    clock,
+	}
+}
+
+// Take implements Limiter.
+func (l *WindowLimiter) Take(key string) LimitStatus {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.limit <= 0 {
+		return LimitStatus{Allowed: true}
+	}
+
+	now := l.clock.Now()
+	c, ok := l.counters[key]
+	if !ok || now.Sub(c.start) >= l.window {
+		c = &windowCounter{start: now}
+		l.counters[key] = c
+	}
+
+	status := LimitStatus{
+		Limit: l.limit,
+		Reset: c.start.Add(l.window).Sub(now),
+	}
+	if c.count < l.limit {
+		c.count++
+		status.Allowed = true
+	}
+	status.Remaining = l.limit - c.count
+	return status
+}
+
+// Status reports key's budget without counting a request. Reset is zero
+// when no window is running.
+func (l *WindowLimiter) Status(key string) LimitStatus {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.limit <= 0 {
+		return LimitStatus{Allowed: true}
+	}
+
+	status := LimitStatus{Allowed: true, Limit: l.limit, Remaining: l.limit}
+	now := l.clock.Now()
+	if c, ok := l.counters[key]; ok && now.Sub(c.start) < l.window {
+		status.Remaining = max(l.limit-c.count, 0)
+		status.Allowed = status.Remaining > 0
+		status.Reset = c.start.Add(l.window).Sub(now)
+	}
+	return status
+}
+
+// Reset forgets key's window, refilling its budget.
+func (l *WindowLimiter) Reset(key string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	delete(l.counters, key)
+}
+
+// SetLimit changes the budget at runtime. Running windows keep their counts.
+func (l *WindowLimiter) SetLimit(limit int, window time.Duration) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.limit = limit
+	l.window = window
+}
+
+// Cleanup removes expired windows
+// Should be called periodically to prevent memory leaks
+func (l *WindowLimiter) Cleanup() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := l.clock.Now()
+	for key, c := range l.counters {
+		if now.Sub(c.start) >= l.window {
+			delete(l.counters, key)
+		}
+	}
+}
@@ -0,0 +1,335 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/avalarin/livlog/backend/internal/config"
+	"github.com/avalarin/livlog/backend/internal/repository"
+	"github.com/google/uuid"
+)
+
+var (
+	ErrInvalidWorkspaceName = errors.New("workspace name must be between 1 and 50 characters")
+	ErrPersonalWorkspace    = errors.New("the personal workspace cannot be shared or deleted")
+	ErrNotWorkspaceOwner    = errors.New("only the workspace owner can do this")
+	ErrOwnerCannotLeave     = errors.New("the owner cannot leave the workspace, delete it instead")
+)
+
+// WorkspaceService manages shared workspaces. Collections and types are
+// personal until their owner moves them into a shared workspace; members
+// can then see them and file their own entries in the shared collections.
+type WorkspaceService struct {
+	workspaceRepo  *repository.WorkspaceRepository
+	collectionRepo *repository.CollectionRepository
+	typeRepo       *repository.TypeRepository
+	entryRepo      *repository.EntryRepository
+	cfg            config.WorkspacesConfig
+	clock          Clock
+}
+
+func NewWorkspaceService(
+	workspaceRepo *repository.WorkspaceRepository,
+	collectionRepo *repository.CollectionRepository,
+	typeRepo *repository.TypeRepository,
+	entryRepo *repository.EntryRepository,
+	cfg config.WorkspacesConfig,
+	clock Clock,
+) *WorkspaceService {
+	return &WorkspaceService{
+		workspaceRepo:  workspaceRepo,
+		collectionRepo: collectionRepo,
+		typeRepo:       typeRepo,
+		entryRepo:      entryRepo,
+		cfg:            cfg,
+		clock:          clock,
+	}
+}
+
+// ListWorkspaces returns the user's workspaces, creating their personal
+// workspace on first use.
+func (s *WorkspaceService) ListWorkspaces(ctx context.Context, userID uuid.UUID) ([]*repository.Workspace, error) {
+	if err := s.workspaceRepo.EnsurePersonalWorkspace(ctx, userID); err != nil {
+		return nil, err
+	}
+	return s.workspaceRepo.ListWorkspaces(ctx, userID)
+}
+
+// CreateWorkspace creates a shared workspace owned by the user.
+func (s *WorkspaceService) CreateWorkspace(ctx context.Context, userID uuid.UUID, name string) (*repository.Workspace, error) {
+	name = strings.TrimSpace(name)
+	if len(name) < 1 || len(name) > 50 {
+		return nil, ErrInvalidWorkspaceName
+	}
+	return s.workspaceRepo.CreateWorkspace(ctx, userID, name)
+}
+
+// GetWorkspace returns a workspace the user is a member of.
+func (s *WorkspaceService) GetWorkspace(ctx context.Context, userID, id uuid.UUID) (*repository.Workspace, error) {
+	return s.workspaceRepo.GetWorkspace(ctx, id, userID)
+}
+
+// ownedSharedWorkspace returns the workspace if it is shared and the user
+// owns it.
+func (s *WorkspaceService) ownedSharedWorkspace(ctx context.Context, userID, id uuid.UUID) (*repository.Workspace, error) {
+	workspace, err := s.workspaceRepo.GetWorkspace(ctx, id, userID)
+	if err != nil {
+		return nil, err
+	}
+	if workspace.Personal {
+		return nil, ErrPersonalWorkspace
+	}
+	if workspace.Role != repository.WorkspaceRoleOwner {
+		return nil, ErrNotWorkspaceOwner
+	}
+	return workspace, nil
+}
+
+// DeleteWorkspace deletes a shared workspace owned by the user. Its
+// collections and types go back to their owners' personal workspaces.
+func (s *WorkspaceService) DeleteWorkspace(ctx context.Context, userID, id uuid.UUID) error {
+	if _, err := s.ownedSharedWorkspace(ctx, userID, id); err != nil {
+		return err
+	}
+	return s.workspaceRepo.DeleteWorkspace(ctx, id)
+}
+
+// ListMembers returns the members of a workspace the user is a member of.
+func (s *WorkspaceService) ListMembers(ctx context.Context, userID, id uuid.UUID) ([]*repository.WorkspaceMember, error) {
+	if _, err := s.workspaceRepo.GetWorkspace(ctx, id, userID); err != nil {
+		return nil, err
+	}
+	return s.workspaceRepo.ListMembers(ctx, id)
+}
+
+// RemoveMember removes memberID from a shared workspace. The owner can
+// remove anyone else; members can only remove themselves (leave).
+func (s *WorkspaceService) RemoveMember(ctx context.Context, userID, id, memberID uuid.UUID) error {
+	workspace, err := s.workspaceRepo.GetWorkspace(ctx, id, userID)
+	if err != nil {
+		return err
+	}
+	if workspace.Personal {
+		return ErrPersonalWorkspace
+	}
+	if memberID == userID {
+		if workspace.Role == repository.WorkspaceRoleOwner {
+			return ErrOwnerCannotLeave
+		}
+	} else if workspace.Role != repository.WorkspaceRoleOwner {
+		return ErrNotWorkspaceOwner
+	}
+	return s.workspaceRepo.RemoveMember(ctx, id, memberID)
+}
+
+// CreateInvitation creates an invitation to a shared workspace owned by the
+// user. The returned token is not stored and can't be retrieved later.
+func (s *WorkspaceService) CreateInvitation(
+	ctx context.Context,
+	userID, id uuid.UUID,
+) (*repository.WorkspaceInvitation, string, error) {
+	workspace, err := s.ownedSharedWorkspace(ctx, userID, id)
+	if err != nil {
+		return nil, "", err
+	}
+	if workspace.MemberCount >= s.cfg.MaxMembers {
+		return nil, "", repository.ErrWorkspaceFull
+	}
+
+	token, err := generateInvitationToken()
+	if err != nil {
+		return nil, "", err
+	}
+
+	invitation, err := s.workspaceRepo.CreateInvitation(ctx, id, userID, token, s.clock.Now().Add(s.cfg.InvitationTTL))
+	if err != nil {
+		return nil, "", err
+	}
+
+	return invitation, token, nil
+}
+
+// ListInvitations returns the pending invitations of a shared workspace
+// owned by the user.
+func (s *WorkspaceService) ListInvitations(ctx context.Context, userID, id uuid.UUID) ([]*repository.WorkspaceInvitation, error) {
+	if _, err := s.ownedSharedWorkspace(ctx, userID, id); err != nil {
+		return nil, err
+	}
+	return s.workspaceRepo.ListInvitations(ctx, id)
+}
+
+// RevokeInvitation deletes a pending invitation of a shared workspace owned
+// by the user.
+func (s *WorkspaceService) RevokeInvitation(ctx context.Context, userID, id, invitationID uuid.UUID) error {
+	if _, err := s.ownedSharedWorkspace(ctx, userID, id); err != nil {
+		return err
+	}
+	return s.workspaceRepo.DeleteInvitation(ctx, id, invitationID)
+}
+
+// AcceptInvitation makes the user a member of the workspace the token
+// invites to and returns that workspace.
+func (s *WorkspaceService) AcceptInvitation(ctx context.Context, userID uuid.UUID, token string) (*repository.Workspace, error) {
+	workspaceID, err := s.workspaceRepo.AcceptInvitation(ctx, token, userID, s.cfg.MaxMembers)
+	if err != nil {
+		return nil, err
+	}
+	return s.workspaceRepo.GetWorkspace(ctx, workspaceID, userID)
+}
+
+// ListCollections returns the collections in a workspace the user is a
+// member of. The personal workspace holds the user's collections that are
+// not in a shared one.
+func (s *WorkspaceService) ListCollections(ctx context.Context, userID, id uuid.UUID) ([]*repository.Collection, error) {
+	workspace, err := s.workspaceRepo.GetWorkspace(ctx, id, userID)
+	if err != nil {
+		return nil, err
+	}
+	if !workspace.Personal {
+		return s.collectionRepo.GetCollectionsByWorkspace(ctx, id)
+	}
+
+	collections, err := s.collectionRepo.GetCollectionsByUserID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	personal := make([]*repository.Collection, 0, len(collections))
+	for _, c := range collections {
+		if c.WorkspaceID == nil {
+			personal = append(personal, c)
+		}
+	}
+	return personal, nil
+}
+
+// ListTypes returns the types in a workspace the user is a member of. The
+// personal workspace holds the system types and the user's types that are
+// not in a shared one.
+func (s *WorkspaceService) ListTypes(ctx context.Context, userID, id uuid.UUID) ([]*repository.EntryType, error) {
+	workspace, err := s.workspaceRepo.GetWorkspace(ctx, id, userID)
+	if err != nil {
+		return nil, err
+	}
+	if !workspace.Personal {
+		return s.typeRepo.GetTypesByWorkspace(ctx, id)
+	}
+
+	types, err := s.typeRepo.GetAllTypes(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	personal := make([]*repository.EntryType, 0, len(types))
+	for _, t := range types {
+		if t.WorkspaceID == nil {
+			personal = append(personal, t)
+		}
+	}
+	return personal, nil
+}
+
+// ListEntries returns the entries filed in the collections of a shared
+// workspace: the user's own and the other members' shared or public ones.
+// For the personal workspace it lists the user's own entries, like
+// EntryService.ListEntriesWithImages.
+func (s *WorkspaceService) ListEntries(
+	ctx context.Context,
+	userID, id uuid.UUID,
+	filter repository.EntryFilter,
+	limit, offset int,
+) ([]*repository.EntryWithImages, error) {
+	filter, err := checkEntryFilter(filter)
+	if err != nil {
+		return nil, err
+	}
+	limit = listLimit(limit)
+
+	workspace, err := s.workspaceRepo.GetWorkspace(ctx, id, userID)
+	if err != nil {
+		return nil, err
+	}
+	if workspace.Personal {
+		return s.entryRepo.ListEntriesWithImages(ctx, userID, filter, limit, offset)
+	}
+	return s.entryRepo.ListWorkspaceEntries(ctx, id, userID, filter, limit, offset)
+}
+
+// targetWorkspace resolves where to move a record: nil or the user's
+// personal workspace mean personal (nil), anything else must be a shared
+// workspace the user is a member of.
+func (s *WorkspaceService) targetWorkspace(ctx context.Context, userID uuid.UUID, workspaceID *uuid.UUID) (*uuid.UUID, error) {
+	if workspaceID == nil {
+		return nil, nil
+	}
+	workspace, err := s.workspaceRepo.GetWorkspace(ctx, *workspaceID, userID)
+	if err != nil {
+		return nil, err
+	}
+	if workspace.Personal {
+		return nil, nil
+	}
+	return workspaceID, nil
+}
+
+// MoveCollection moves a collection the user owns into a shared workspace,
+// or back to their personal workspace when workspaceID is nil.
+func (s *WorkspaceService) MoveCollection(
+	ctx context.Context,
+	userID, collectionID uuid.UUID,
+	workspaceID *uuid.UUID,
+) (*repository.Collection, error) {
+	collection, err := s.collectionRepo.GetCollectionByID(ctx, collectionID)
+	if err != nil {
+		return nil, err
+	}
+	if collection.UserID != userID {
+		return nil, repository.ErrCollectionNotFound
+	}
+
+	target, err := s.targetWorkspace(ctx, userID, workspaceID)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.collectionRepo.SetCollectionWorkspace(ctx, collectionID, target); err != nil {
+		return nil, err
+	}
+
+	return s.collectionRepo.GetCollectionByID(ctx, collectionID)
+}
+
+// MoveType moves a type the user owns into a shared workspace, or back to
+// their personal workspace when workspaceID is nil.
+func (s *WorkspaceService) MoveType(
+	ctx context.Context,
+	userID, typeID uuid.UUID,
+	workspaceID *uuid.UUID,
+) (*repository.EntryType, error) {
+	entryType, err := s.typeRepo.GetTypeByID(ctx, typeID)
+	if err != nil {
+		return nil, err
+	}
+	if entryType.UserID == nil || *entryType.UserID != userID {
+		return nil, repository.ErrTypeNotFound
+	}
+
+	target, err := s.targetWorkspace(ctx, userID, workspaceID)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.typeRepo.SetTypeWorkspace(ctx, typeID, target); err != nil {
+		return nil, err
+	}
+
+	return s.typeRepo.GetTypeByID(ctx, typeID)
+}
+
+func generateInvitationToken() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("failed to generate invitation token: %w", err)
+	}
+	return "wsinv_" + hex.EncodeToString(b), nil
+}
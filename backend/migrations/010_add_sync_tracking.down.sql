@@ -0,0 +1,22 @@
+DROP TRIGGER IF EXISTS trg_entry_types_tombstone ON entry_types;
+DROP TRIGGER IF EXISTS trg_entries_tombstone ON entries;
+DROP TRIGGER IF EXISTS trg_collections_tombstone ON collections;
+DROP FUNCTION IF EXISTS record_sync_tombstone();
+DROP TABLE IF EXISTS sync_tombstones;
+
+DROP TRIGGER IF EXISTS trg_entry_images_touch_entry ON entry_images;
+DROP FUNCTION IF EXISTS touch_entry_for_image();
+
+DROP TRIGGER IF EXISTS trg_entry_types_change_xid ON entry_types;
+DROP TRIGGER IF EXISTS trg_entries_change_xid ON entries;
+DROP TRIGGER IF EXISTS trg_collections_change_xid ON collections;
+
+DROP INDEX IF EXISTS idx_entry_types_user_change;
+DROP INDEX IF EXISTS idx_entries_user_change;
+DROP INDEX IF EXISTS idx_collections_user_change;
+
+ALTER TABLE entry_types DROP COLUMN IF EXISTS change_xid;
+ALTER TABLE entries DROP COLUMN IF EXISTS change_xid;
+ALTER TABLE collections DROP COLUMN IF EXISTS change_xid;
+
+DROP FUNCTION IF EXISTS touch_change_xid();
@@ -0,0 +1,69 @@
+-- Delta sync: every syncable row records the transaction that last changed it.
+-- Clients sync with a cursor that is the xmin of the server snapshot, so rows
+-- written by transactions still in flight are never skipped.
+
+CREATE FUNCTION touch_change_xid() RETURNS trigger AS $$
+BEGIN
+    NEW.change_xid := pg_current_xact_id();
+    RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+
+ALTER TABLE collections ADD COLUMN change_xid xid8 NOT NULL DEFAULT pg_current_xact_id();
+ALTER TABLE entries ADD COLUMN change_xid xid8 NOT NULL DEFAULT pg_current_xact_id();
+ALTER TABLE entry_types ADD COLUMN change_xid xid8 NOT NULL DEFAULT pg_current_xact_id();
+
+CREATE INDEX idx_collections_user_change ON collections(user_id, change_xid);
+CREATE INDEX idx_entries_user_change ON entries(user_id, change_xid);
+CREATE INDEX idx_entry_types_user_change ON entry_types(user_id, change_xid);
+
+CREATE TRIGGER trg_collections_change_xid BEFORE UPDATE ON collections
+    FOR EACH ROW EXECUTE FUNCTION touch_change_xid();
+CREATE TRIGGER trg_entries_change_xid BEFORE UPDATE ON entries
+    FOR EACH ROW EXECUTE FUNCTION touch_change_xid();
+CREATE TRIGGER trg_entry_types_change_xid BEFORE UPDATE ON entry_types
+    FOR EACH ROW EXECUTE FUNCTION touch_change_xid();
+
+-- Image changes are part of the entry: bump the parent so it is re-synced.
+CREATE FUNCTION touch_entry_for_image() RETURNS trigger AS $$
+BEGIN
+    UPDATE entries SET change_xid = pg_current_xact_id()
+    WHERE id = COALESCE(NEW.entry_id, OLD.entry_id)
+      AND change_xid <> pg_current_xact_id();
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER trg_entry_images_touch_entry AFTER INSERT OR UPDATE OR DELETE ON entry_images
+    FOR EACH ROW EXECUTE FUNCTION touch_entry_for_image();
+
+-- Tombstones for deleted rows. No FK to users: rows are inserted while a user
+-- delete cascades, and must not block it.
+CREATE TABLE sync_tombstones (
+    id BIGSERIAL PRIMARY KEY,
+    user_id UUID NOT NULL,
+    entity_type VARCHAR(20) NOT NULL,
+    entity_id UUID NOT NULL,
+    change_xid xid8 NOT NULL DEFAULT pg_current_xact_id(),
+    deleted_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX idx_sync_tombstones_user_change ON sync_tombstones(user_id, change_xid);
+
+CREATE FUNCTION record_sync_tombstone() RETURNS trigger AS $$
+BEGIN
+    -- System rows (e.g. built-in entry types) have no owner to notify
+    IF OLD.user_id IS NOT NULL THEN
+        INSERT INTO sync_tombstones (user_id, entity_type, entity_id)
+        VALUES (OLD.user_id, TG_ARGV[0], OLD.id);
+    END IF;
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER trg_collections_tombstone AFTER DELETE ON collections
+    FOR EACH ROW EXECUTE FUNCTION record_sync_tombstone('collection');
+CREATE TRIGGER trg_entries_tombstone AFTER DELETE ON entries
+    FOR EACH ROW EXECUTE FUNCTION record_sync_tombstone('entry');
+CREATE TRIGGER trg_entry_types_tombstone AFTER DELETE ON entry_types
+    FOR EACH ROW EXECUTE FUNCTION record_sync_tombstone('type');
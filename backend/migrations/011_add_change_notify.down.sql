@@ -0,0 +1,3 @@
+DROP TRIGGER IF EXISTS trg_entries_notify ON entries;
+DROP TRIGGER IF EXISTS trg_collections_notify ON collections;
+DROP FUNCTION IF EXISTS notify_sync_change();
@@ -0,0 +1,28 @@
+-- Real-time change feed: announce entry and collection changes on the
+-- sync_changes channel. Payloads are delivered on commit, so listeners only see
+-- changes they can already read via /sync/changes.
+
+CREATE FUNCTION notify_sync_change() RETURNS trigger AS $$
+DECLARE
+    rec RECORD;
+BEGIN
+    IF TG_OP = 'DELETE' THEN
+        rec := OLD;
+    ELSE
+        rec := NEW;
+    END IF;
+
+    PERFORM pg_notify('sync_changes', json_build_object(
+        'user_id', rec.user_id,
+        'entity', TG_ARGV[0],
+        'id', rec.id,
+        'op', lower(TG_OP)
+    )::text);
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER trg_collections_notify AFTER INSERT OR UPDATE OR DELETE ON collections
+    FOR EACH ROW EXECUTE FUNCTION notify_sync_change('collection');
+CREATE TRIGGER trg_entries_notify AFTER INSERT OR UPDATE OR DELETE ON entries
+    FOR EACH ROW EXECUTE FUNCTION notify_sync_change('entry');
@@ -0,0 +1,30 @@
+DROP TRIGGER IF EXISTS trg_entries_outbox ON entries;
+DROP TRIGGER IF EXISTS trg_collections_outbox ON collections;
+DROP FUNCTION IF EXISTS write_outbox();
+DROP TABLE IF EXISTS outbox;
+
+-- Restore direct change feed notifications from 011
+CREATE FUNCTION notify_sync_change() RETURNS trigger AS $$
+DECLARE
+    rec RECORD;
+BEGIN
+    IF TG_OP = 'DELETE' THEN
+        rec := OLD;
+    ELSE
+        rec := NEW;
+    END IF;
+
+    PERFORM pg_notify('sync_changes', json_build_object(
+        'user_id', rec.user_id,
+        'entity', TG_ARGV[0],
+        'id', rec.id,
+        'op', lower(TG_OP)
+    )::text);
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER trg_collections_notify AFTER INSERT OR UPDATE OR DELETE ON collections
+    FOR EACH ROW EXECUTE FUNCTION notify_sync_change('collection');
+CREATE TRIGGER trg_entries_notify AFTER INSERT OR UPDATE OR DELETE ON entries
+    FOR EACH ROW EXECUTE FUNCTION notify_sync_change('entry');
@@ -0,0 +1,36 @@
+-- Transactional outbox: entry and collection changes are recorded by trigger
+-- in the same transaction as the change itself, so an event exists if and
+-- only if the change committed. The relay job publishes and deletes them.
+-- Events only name the change: the relay loads the entity and maps it like
+-- the API does, so internal columns never reach webhook payloads.
+CREATE TABLE outbox (
+    id BIGSERIAL PRIMARY KEY,
+    user_id UUID NOT NULL,
+    entity VARCHAR(20) NOT NULL,
+    entity_id UUID NOT NULL,
+    op VARCHAR(10) NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
+
+CREATE FUNCTION write_outbox() RETURNS trigger AS $$
+BEGIN
+    IF TG_OP = 'DELETE' THEN
+        INSERT INTO outbox (user_id, entity, entity_id, op)
+        VALUES (OLD.user_id, TG_ARGV[0], OLD.id, 'delete');
+    ELSE
+        INSERT INTO outbox (user_id, entity, entity_id, op)
+        VALUES (NEW.user_id, TG_ARGV[0], NEW.id, lower(TG_OP));
+    END IF;
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER trg_collections_outbox AFTER INSERT OR UPDATE OR DELETE ON collections
+    FOR EACH ROW EXECUTE FUNCTION write_outbox('collection');
+CREATE TRIGGER trg_entries_outbox AFTER INSERT OR UPDATE OR DELETE ON entries
+    FOR EACH ROW EXECUTE FUNCTION write_outbox('entry');
+
+-- Change feed notifications are now sent by the relay
+DROP TRIGGER IF EXISTS trg_entries_notify ON entries;
+DROP TRIGGER IF EXISTS trg_collections_notify ON collections;
+DROP FUNCTION IF EXISTS notify_sync_change();
@@ -0,0 +1,4 @@
+-- Operator role: admins are created with livlogctl create-admin
+CREATE TYPE user_role AS ENUM ('user', 'admin');
+
+ALTER TABLE users ADD COLUMN role user_role NOT NULL DEFAULT 'user';
@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS idx_user_tokens_created_at;
+DROP TABLE IF EXISTS ai_search_daily;
@@ -0,0 +1,9 @@
+-- Daily AI search totals for the admin dashboard; ai_search_usage only keeps
+-- each user's current period.
+CREATE TABLE ai_search_daily (
+    day DATE PRIMARY KEY,
+    searches BIGINT NOT NULL DEFAULT 0
+);
+
+-- Daily active users are counted from token issues and refreshes
+CREATE INDEX idx_user_tokens_created_at ON user_tokens(created_at);
@@ -0,0 +1,22 @@
+-- Generated export files (e.g. PDF of a collection or year). Rows are queued
+-- as pending and rendered by the export worker; the file is kept until cleanup.
+CREATE TABLE exports (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    format VARCHAR(20) NOT NULL,
+    collection_id UUID REFERENCES collections(id) ON DELETE SET NULL,
+    year INT,
+    status VARCHAR(20) NOT NULL DEFAULT 'pending',
+    attempts INT NOT NULL DEFAULT 0,
+    file_data BYTEA,
+    error TEXT,
+    claimed_at TIMESTAMP WITH TIME ZONE,
+    finished_at TIMESTAMP WITH TIME ZONE,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX idx_exports_user_created ON exports(user_id, created_at DESC);
+
+-- Worker queue: only exports not finished yet
+CREATE INDEX idx_exports_unfinished ON exports(created_at)
+    WHERE status IN ('pending', 'running');
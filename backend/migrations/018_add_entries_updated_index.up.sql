@@ -0,0 +1,2 @@
+-- Client cache checks: latest change per user and entries changed since a time
+CREATE INDEX idx_entries_user_updated ON entries(user_id, updated_at);
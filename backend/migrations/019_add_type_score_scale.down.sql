@@ -0,0 +1,5 @@
+UPDATE entries SET score = 3 WHERE score > 3;
+ALTER TABLE entries DROP CONSTRAINT entries_score_check;
+ALTER TABLE entries ADD CONSTRAINT entries_score_check CHECK (score >= 0 AND score <= 3);
+
+ALTER TABLE entry_types DROP COLUMN IF EXISTS score_scale;
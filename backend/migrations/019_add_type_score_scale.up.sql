@@ -0,0 +1,8 @@
+-- Per-type score scale: what a type's score means and how each value is labelled.
+-- Existing types keep the original Bad/Okay/Great reaction scale.
+ALTER TABLE entry_types ADD COLUMN score_scale JSONB NOT NULL
+    DEFAULT '{"kind": "reaction", "max": 3, "labels": ["Bad", "Okay", "Great"]}';
+
+-- Scores are validated against the entry's type; the column only bounds the largest scale
+ALTER TABLE entries DROP CONSTRAINT entries_score_check;
+ALTER TABLE entries ADD CONSTRAINT entries_score_check CHECK (score >= 0 AND score <= 10);
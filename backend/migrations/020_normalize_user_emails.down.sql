@@ -0,0 +1,8 @@
+-- Normalized addresses are kept; only the index and the duplicate flags go
+DROP INDEX IF EXISTS idx_users_duplicate_of;
+DROP INDEX IF EXISTS idx_users_email;
+CREATE UNIQUE INDEX idx_users_email
+    ON users(email)
+    WHERE email IS NOT NULL AND deleted_at IS NULL;
+
+ALTER TABLE users DROP COLUMN IF EXISTS duplicate_of;
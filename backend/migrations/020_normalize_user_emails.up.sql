@@ -0,0 +1,43 @@
+-- Emails are compared case-insensitively. Accounts whose address only differs
+-- from an older account's by case or surrounding spaces are flagged with the
+-- account they duplicate and keep their raw address; operators merge or
+-- delete them by hand (docs/operations.md).
+ALTER TABLE users ADD COLUMN duplicate_of UUID REFERENCES users(id) ON DELETE SET NULL;
+
+UPDATE users u
+SET duplicate_of = first.id
+FROM (
+    SELECT DISTINCT ON (LOWER(TRIM(email))) id, LOWER(TRIM(email)) AS normalized
+    FROM users
+    WHERE email IS NOT NULL AND deleted_at IS NULL
+    ORDER BY LOWER(TRIM(email)), created_at ASC, id ASC
+) first
+WHERE u.email IS NOT NULL AND u.deleted_at IS NULL
+    AND LOWER(TRIM(u.email)) = first.normalized
+    AND u.id <> first.id;
+
+UPDATE users
+SET email = LOWER(TRIM(email))
+WHERE email IS NOT NULL AND deleted_at IS NULL AND duplicate_of IS NULL
+    AND email <> LOWER(TRIM(email));
+
+-- Email sign-in looks accounts up by the normalized address
+UPDATE user_auth_providers p
+SET provider_user_id = LOWER(TRIM(p.provider_user_id))
+FROM users u
+WHERE p.user_id = u.id AND p.provider = 'email'
+    AND u.deleted_at IS NULL AND u.duplicate_of IS NULL
+    AND p.provider_user_id <> LOWER(TRIM(p.provider_user_id))
+    AND NOT EXISTS (
+        SELECT 1 FROM user_auth_providers o
+        WHERE o.provider = 'email' AND o.provider_user_id = LOWER(TRIM(p.provider_user_id))
+    );
+
+DROP INDEX IF EXISTS idx_users_email;
+CREATE UNIQUE INDEX idx_users_email
+    ON users(LOWER(email))
+    WHERE email IS NOT NULL AND deleted_at IS NULL AND duplicate_of IS NULL;
+
+CREATE INDEX idx_users_duplicate_of
+    ON users(duplicate_of)
+    WHERE duplicate_of IS NOT NULL;
@@ -0,0 +1,2 @@
+-- Percent done of a running export, reported by GET /jobs/{id}
+ALTER TABLE exports ADD COLUMN progress SMALLINT NOT NULL DEFAULT 0;
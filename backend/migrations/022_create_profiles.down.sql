@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS released_handles;
+DROP INDEX IF EXISTS idx_profiles_handle;
+DROP TABLE IF EXISTS profiles;
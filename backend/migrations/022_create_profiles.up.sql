@@ -0,0 +1,24 @@
+-- Opt-in public profiles served at GET /public/users/{handle}. Only the
+-- collections listed in collection_ids are shown, and favorites only while
+-- they are in one of them.
+CREATE TABLE profiles (
+    user_id UUID PRIMARY KEY REFERENCES users(id) ON DELETE CASCADE,
+    handle VARCHAR(30) NOT NULL,
+    public BOOLEAN NOT NULL DEFAULT FALSE,
+    bio VARCHAR(300) NOT NULL DEFAULT '',
+    collection_ids UUID[] NOT NULL DEFAULT '{}',
+    favorite_entry_ids UUID[] NOT NULL DEFAULT '{}',
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
+
+-- Handles are stored lowercased
+CREATE UNIQUE INDEX idx_profiles_handle ON profiles(handle);
+
+-- A handle given up by a user stays reserved for them for a while, so links to
+-- the old profile don't start pointing at someone else right away
+CREATE TABLE released_handles (
+    handle VARCHAR(30) PRIMARY KEY,
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    released_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
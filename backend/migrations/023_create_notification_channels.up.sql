@@ -0,0 +1,15 @@
+-- External services a user's notifications are also sent to (Telegram, Discord)
+CREATE TABLE notification_channels (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    kind VARCHAR(20) NOT NULL,
+    -- Telegram chat id or Discord webhook URL
+    target TEXT NOT NULL,
+    last_error TEXT,
+    last_delivered_at TIMESTAMP WITH TIME ZONE,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    CONSTRAINT notification_channels_kind_check CHECK (kind IN ('telegram', 'discord'))
+);
+
+CREATE INDEX idx_notification_channels_user_id ON notification_channels(user_id);
@@ -0,0 +1,4 @@
+DROP TRIGGER IF EXISTS trg_entries_activity ON entries;
+DROP TRIGGER IF EXISTS trg_collections_activity ON collections;
+DROP FUNCTION IF EXISTS write_activity();
+DROP TABLE IF EXISTS activity_log;
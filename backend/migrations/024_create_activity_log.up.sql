@@ -0,0 +1,67 @@
+-- Who changed what: one row per entry or collection write, with the changed
+-- fields as {"field": {"old": ..., "new": ...}}. Written by trigger in the
+-- same transaction as the change, like the outbox. No foreign key to users,
+-- so rows written while purging a user don't fail; PurgeUser deletes them.
+CREATE TABLE activity_log (
+    id BIGSERIAL PRIMARY KEY,
+    user_id UUID NOT NULL,
+    actor_id UUID NOT NULL,
+    entity VARCHAR(20) NOT NULL,
+    entity_id UUID NOT NULL,
+    op VARCHAR(10) NOT NULL,
+    changes JSONB NOT NULL DEFAULT '{}'::jsonb,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX idx_activity_log_user_id ON activity_log(user_id, id DESC);
+CREATE INDEX idx_activity_log_entity_id ON activity_log(entity_id, id DESC);
+CREATE INDEX idx_activity_log_created_at ON activity_log(created_at);
+
+-- The actor is the owner unless the writing transaction names someone else
+-- with SET LOCAL livlog.actor_id (e.g. a collaborator on a shared collection).
+CREATE FUNCTION write_activity() RETURNS trigger AS $$
+DECLARE
+    old_row JSONB := '{}'::jsonb;
+    new_row JSONB := '{}'::jsonb;
+    owner_id UUID;
+    row_id UUID;
+    diff JSONB;
+BEGIN
+    IF TG_OP <> 'INSERT' THEN
+        old_row := to_jsonb(OLD) - 'id' - 'user_id' - 'change_xid' - 'created_at' - 'updated_at';
+        owner_id := OLD.user_id;
+        row_id := OLD.id;
+    END IF;
+    IF TG_OP <> 'DELETE' THEN
+        new_row := to_jsonb(NEW) - 'id' - 'user_id' - 'change_xid' - 'created_at' - 'updated_at';
+        owner_id := NEW.user_id;
+        row_id := NEW.id;
+    END IF;
+
+    SELECT COALESCE(jsonb_object_agg(k, jsonb_build_object('old', old_row -> k, 'new', new_row -> k)), '{}'::jsonb)
+    INTO diff
+    FROM jsonb_object_keys(old_row || new_row) AS k
+    WHERE COALESCE(old_row -> k, 'null'::jsonb) IS DISTINCT FROM COALESCE(new_row -> k, 'null'::jsonb);
+
+    -- Touches that change nothing visible (e.g. only updated_at) are not activity
+    IF TG_OP = 'UPDATE' AND diff = '{}'::jsonb THEN
+        RETURN NULL;
+    END IF;
+
+    INSERT INTO activity_log (user_id, actor_id, entity, entity_id, op, changes)
+    VALUES (
+        owner_id,
+        COALESCE(NULLIF(current_setting('livlog.actor_id', true), '')::uuid, owner_id),
+        TG_ARGV[0],
+        row_id,
+        lower(TG_OP),
+        diff
+    );
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER trg_collections_activity AFTER INSERT OR UPDATE OR DELETE ON collections
+    FOR EACH ROW EXECUTE FUNCTION write_activity('collection');
+CREATE TRIGGER trg_entries_activity AFTER INSERT OR UPDATE OR DELETE ON entries
+    FOR EACH ROW EXECUTE FUNCTION write_activity('entry');
@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS idx_entries_collection_position;
+ALTER TABLE entries DROP COLUMN IF EXISTS position;
@@ -0,0 +1,6 @@
+-- Manual rank of an entry within its collection, set by
+-- PUT /collections/{id}/entries/order. NULL means unranked; unranked entries
+-- sort after ranked ones.
+ALTER TABLE entries ADD COLUMN position INT;
+
+CREATE INDEX idx_entries_collection_position ON entries(collection_id, position);
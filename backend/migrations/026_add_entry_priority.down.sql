@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS idx_entries_user_priority;
+ALTER TABLE entries DROP COLUMN IF EXISTS priority;
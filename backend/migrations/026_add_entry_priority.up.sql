@@ -0,0 +1,6 @@
+-- How soon a planned entry should be picked up, for "watch next" lists:
+-- 0 is no priority, 1 to 5 rank from low to high.
+ALTER TABLE entries ADD COLUMN priority SMALLINT NOT NULL DEFAULT 0
+    CHECK (priority BETWEEN 0 AND 5);
+
+CREATE INDEX idx_entries_user_priority ON entries(user_id, priority DESC) WHERE priority > 0;
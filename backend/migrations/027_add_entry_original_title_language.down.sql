@@ -0,0 +1,2 @@
+ALTER TABLE entries DROP COLUMN IF EXISTS language;
+ALTER TABLE entries DROP COLUMN IF EXISTS original_title;
@@ -0,0 +1,4 @@
+-- For foreign films and translated books: the title in the original language
+-- and that language as a BCP 47 tag (e.g. "ja", "pt-BR"). Empty when unknown.
+ALTER TABLE entries ADD COLUMN original_title VARCHAR(200) NOT NULL DEFAULT '';
+ALTER TABLE entries ADD COLUMN language VARCHAR(35) NOT NULL DEFAULT '';
@@ -0,0 +1,7 @@
+DROP INDEX IF EXISTS idx_entry_types_workspace_id;
+DROP INDEX IF EXISTS idx_collections_workspace_id;
+ALTER TABLE entry_types DROP COLUMN IF EXISTS workspace_id;
+ALTER TABLE collections DROP COLUMN IF EXISTS workspace_id;
+DROP TABLE IF EXISTS workspace_invitations;
+DROP TABLE IF EXISTS workspace_members;
+DROP TABLE IF EXISTS workspaces;
@@ -0,0 +1,45 @@
+-- Workspaces scope collections and types. Every user has one personal
+-- workspace (created lazily); shared workspaces have members and
+-- invitations. Collections and types with a NULL workspace_id belong to
+-- their owner's personal workspace.
+CREATE TABLE workspaces (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    owner_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    name VARCHAR(50) NOT NULL,
+    personal BOOLEAN NOT NULL DEFAULT FALSE,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX idx_workspaces_owner_id ON workspaces(owner_id);
+CREATE UNIQUE INDEX uq_workspaces_personal ON workspaces(owner_id) WHERE personal;
+
+CREATE TABLE workspace_members (
+    workspace_id UUID NOT NULL REFERENCES workspaces(id) ON DELETE CASCADE,
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    role VARCHAR(20) NOT NULL CHECK (role IN ('owner', 'member')),
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+
+    PRIMARY KEY (workspace_id, user_id)
+);
+
+CREATE INDEX idx_workspace_members_user_id ON workspace_members(user_id);
+
+CREATE TABLE workspace_invitations (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    workspace_id UUID NOT NULL REFERENCES workspaces(id) ON DELETE CASCADE,
+    invited_by UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    token_hash VARCHAR(64) NOT NULL UNIQUE,
+    expires_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    accepted_by UUID REFERENCES users(id) ON DELETE SET NULL,
+    accepted_at TIMESTAMP WITH TIME ZONE,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX idx_workspace_invitations_workspace_id ON workspace_invitations(workspace_id);
+
+ALTER TABLE collections ADD COLUMN workspace_id UUID REFERENCES workspaces(id) ON DELETE SET NULL;
+ALTER TABLE entry_types ADD COLUMN workspace_id UUID REFERENCES workspaces(id) ON DELETE SET NULL;
+
+CREATE INDEX idx_collections_workspace_id ON collections(workspace_id);
+CREATE INDEX idx_entry_types_workspace_id ON entry_types(workspace_id);
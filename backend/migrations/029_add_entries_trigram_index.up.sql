@@ -0,0 +1,28 @@
+-- Trigram index for the ILIKE fallback search, used when the entry
+-- repository runs with trigram search enabled (database.trigram_search).
+-- It indexes the same expression the repository matches against, so
+-- '%term%' patterns of three or more characters use a bitmap index scan
+-- instead of reading every entry of the user.
+--
+-- pg_trgm ships with PostgreSQL's contrib package and needs the CREATE
+-- privilege on the database. The switch is off by default, so a server
+-- without it skips the index instead of failing the migration; searches
+-- stay correct without it, only slower.
+DO $$
+BEGIN
+    IF NOT EXISTS (SELECT 1 FROM pg_available_extensions WHERE name = 'pg_trgm') THEN
+        RAISE NOTICE 'pg_trgm is not available, skipping idx_entries_search_trgm';
+        RETURN;
+    END IF;
+
+    BEGIN
+        CREATE EXTENSION IF NOT EXISTS pg_trgm;
+    EXCEPTION WHEN insufficient_privilege THEN
+        RAISE NOTICE 'not allowed to create pg_trgm, skipping idx_entries_search_trgm';
+        RETURN;
+    END;
+
+    CREATE INDEX idx_entries_search_trgm ON entries
+        USING GIN ((title || ' ' || original_title || ' ' || description) gin_trgm_ops);
+END;
+$$;
@@ -0,0 +1 @@
+DROP INDEX IF EXISTS idx_entry_images_one_cover;
@@ -0,0 +1,18 @@
+-- Every entry with images has exactly one cover. Keep the first flagged
+-- image where several are flagged, flag the first image where none is,
+-- then let the index reject a second cover.
+UPDATE entry_images SET is_cover = false
+WHERE is_cover AND id NOT IN (
+    SELECT DISTINCT ON (entry_id) id FROM entry_images
+    WHERE is_cover
+    ORDER BY entry_id, position, created_at
+);
+
+UPDATE entry_images SET is_cover = true
+WHERE id IN (
+    SELECT DISTINCT ON (i.entry_id) i.id FROM entry_images i
+    WHERE NOT EXISTS (SELECT 1 FROM entry_images c WHERE c.entry_id = i.entry_id AND c.is_cover)
+    ORDER BY i.entry_id, i.position, i.created_at
+);
+
+CREATE UNIQUE INDEX idx_entry_images_one_cover ON entry_images(entry_id) WHERE is_cover;
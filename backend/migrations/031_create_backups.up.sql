@@ -0,0 +1,17 @@
+-- Database backups. Rows are queued as pending (by the schedule or an admin)
+-- and taken by the backup job; the archive itself lives in the configured
+-- store under key.
+CREATE TABLE backups (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    requested_by UUID REFERENCES users(id) ON DELETE SET NULL, -- NULL for scheduled backups
+    status VARCHAR(20) NOT NULL DEFAULT 'pending',
+    key TEXT,
+    size_bytes BIGINT NOT NULL DEFAULT 0,
+    sha256 VARCHAR(64),
+    error TEXT,
+    claimed_at TIMESTAMP WITH TIME ZONE,
+    finished_at TIMESTAMP WITH TIME ZONE,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX idx_backups_created ON backups(created_at DESC);
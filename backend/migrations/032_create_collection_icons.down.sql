@@ -0,0 +1,8 @@
+UPDATE collections SET icon = '📋' WHERE icon IS NULL;
+
+ALTER TABLE collections
+    DROP CONSTRAINT IF EXISTS chk_collections_one_icon,
+    DROP COLUMN IF EXISTS icon_image_id,
+    ALTER COLUMN icon SET NOT NULL;
+
+DROP TABLE IF EXISTS collection_icons;
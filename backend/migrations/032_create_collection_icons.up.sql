@@ -0,0 +1,15 @@
+-- Uploaded collection icons. A collection shows either its emoji icon or an
+-- uploaded image, never both.
+CREATE TABLE collection_icons (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    image_data BYTEA NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX idx_collection_icons_user_id ON collection_icons(user_id);
+
+ALTER TABLE collections
+    ALTER COLUMN icon DROP NOT NULL,
+    ADD COLUMN icon_image_id UUID REFERENCES collection_icons(id) ON DELETE SET NULL,
+    ADD CONSTRAINT chk_collections_one_icon CHECK (icon IS NULL OR icon_image_id IS NULL);
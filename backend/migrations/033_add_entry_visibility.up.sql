@@ -0,0 +1,21 @@
+-- Who can see an entry besides its owner: nobody (private), members of the
+-- shared workspace its collection is in (shared), or also visitors of the
+-- owner's public profile (public).
+ALTER TABLE entries ADD COLUMN visibility VARCHAR(10) NOT NULL DEFAULT 'private'
+    CHECK (visibility IN ('private', 'shared', 'public'));
+
+-- Keep what was visible before: entries in shared workspaces were shown to
+-- members, entries in profile collections on the profile. The backfill is not
+-- user activity, so the activity and outbox triggers are skipped; change_xid
+-- is still bumped so clients pull the new field.
+ALTER TABLE entries DISABLE TRIGGER USER;
+
+UPDATE entries e SET visibility = 'shared', change_xid = pg_current_xact_id()
+FROM collections c
+WHERE c.id = e.collection_id AND c.workspace_id IS NOT NULL;
+
+UPDATE entries e SET visibility = 'public', change_xid = pg_current_xact_id()
+FROM profiles p
+WHERE p.user_id = e.user_id AND e.collection_id = ANY(p.collection_ids);
+
+ALTER TABLE entries ENABLE TRIGGER USER;
@@ -0,0 +1,2 @@
+ALTER TABLE exports DROP COLUMN IF EXISTS include_images;
+ALTER TABLE exports DROP COLUMN IF EXISTS entry_ids;
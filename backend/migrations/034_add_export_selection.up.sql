@@ -0,0 +1,4 @@
+-- Exports limited to chosen entries, and whether the file is a ZIP that
+-- also holds the entries' images
+ALTER TABLE exports ADD COLUMN entry_ids UUID[];
+ALTER TABLE exports ADD COLUMN include_images BOOLEAN NOT NULL DEFAULT FALSE;
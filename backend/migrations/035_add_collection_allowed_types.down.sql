@@ -0,0 +1 @@
+ALTER TABLE collections DROP COLUMN IF EXISTS allowed_type_ids;
@@ -0,0 +1,2 @@
+-- Entry types a collection accepts; NULL accepts any type
+ALTER TABLE collections ADD COLUMN allowed_type_ids UUID[];
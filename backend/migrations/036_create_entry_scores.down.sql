@@ -0,0 +1,3 @@
+DROP TRIGGER IF EXISTS trg_entries_score ON entries;
+DROP FUNCTION IF EXISTS write_entry_score();
+DROP TABLE IF EXISTS entry_scores;
@@ -0,0 +1,28 @@
+-- Every score an entry has had, so a re-rating keeps the earlier one: a row
+-- for the score an entry is created with and one per change. Written by
+-- trigger, like the activity log, but kept as long as the entry.
+CREATE TABLE entry_scores (
+    id BIGSERIAL PRIMARY KEY,
+    entry_id UUID NOT NULL REFERENCES entries(id) ON DELETE CASCADE,
+    score SMALLINT NOT NULL,
+    rated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX idx_entry_scores_entry_id ON entry_scores(entry_id, rated_at, id);
+
+-- When existing entries got their current score is unknown; creation is the
+-- earliest it can have been
+INSERT INTO entry_scores (entry_id, score, rated_at)
+SELECT id, score, created_at FROM entries;
+
+CREATE FUNCTION write_entry_score() RETURNS trigger AS $$
+BEGIN
+    IF TG_OP = 'INSERT' OR NEW.score IS DISTINCT FROM OLD.score THEN
+        INSERT INTO entry_scores (entry_id, score) VALUES (NEW.id, NEW.score);
+    END IF;
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER trg_entries_score AFTER INSERT OR UPDATE OF score ON entries
+    FOR EACH ROW EXECUTE FUNCTION write_entry_score();
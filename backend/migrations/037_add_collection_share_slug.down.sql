@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS idx_collections_share_slug;
+ALTER TABLE collections DROP COLUMN IF EXISTS share_slug;
@@ -0,0 +1,5 @@
+-- Human-readable link a collection is shared under, GET /public/c/{slug}.
+-- Slugs are stored lowercased; NULL when the collection isn't shared.
+ALTER TABLE collections ADD COLUMN share_slug VARCHAR(60);
+
+CREATE UNIQUE INDEX idx_collections_share_slug ON collections(share_slug) WHERE share_slug IS NOT NULL;
@@ -0,0 +1,3 @@
+ALTER TABLE collection_icons DROP COLUMN IF EXISTS content_hash;
+
+ALTER TABLE entry_images DROP COLUMN IF EXISTS content_hash;
@@ -0,0 +1,6 @@
+-- Content hashes version image URLs (?v=), so a CDN can cache them for good.
+ALTER TABLE entry_images
+    ADD COLUMN content_hash TEXT GENERATED ALWAYS AS (md5(image_data)) STORED;
+
+ALTER TABLE collection_icons
+    ADD COLUMN content_hash TEXT GENERATED ALWAYS AS (md5(image_data)) STORED;
@@ -0,0 +1,3 @@
+DROP TRIGGER IF EXISTS trg_exports_usage ON exports;
+DROP FUNCTION IF EXISTS count_export_usage();
+DROP TABLE IF EXISTS user_usage_daily;
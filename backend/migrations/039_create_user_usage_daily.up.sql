@@ -0,0 +1,26 @@
+-- Daily per-user usage for GET /auth/me/usage. API calls are counted in
+-- memory and flushed every minute; AI searches are added with the admin
+-- daily total; exports by trigger, since exports themselves are deleted a
+-- week after they finish.
+CREATE TABLE user_usage_daily (
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    day DATE NOT NULL,
+    api_calls BIGINT NOT NULL DEFAULT 0,
+    ai_searches INT NOT NULL DEFAULT 0,
+    exports INT NOT NULL DEFAULT 0,
+    PRIMARY KEY (user_id, day)
+);
+
+CREATE INDEX idx_user_usage_daily_day ON user_usage_daily(day);
+
+CREATE FUNCTION count_export_usage() RETURNS trigger AS $$
+BEGIN
+    INSERT INTO user_usage_daily (user_id, day, exports)
+    VALUES (NEW.user_id, (NEW.created_at AT TIME ZONE 'UTC')::date, 1)
+    ON CONFLICT (user_id, day) DO UPDATE SET exports = user_usage_daily.exports + 1;
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER trg_exports_usage AFTER INSERT ON exports
+    FOR EACH ROW EXECUTE FUNCTION count_export_usage();
@@ -0,0 +1,7 @@
+-- Apple private relay addresses (user@privaterelay.appleid.com) only forward
+-- mail from domains registered with Apple. Set from the is_private_email
+-- claim on Sign in with Apple; existing relay addresses are backfilled.
+ALTER TABLE users ADD COLUMN is_private_email BOOLEAN NOT NULL DEFAULT FALSE;
+
+UPDATE users SET is_private_email = TRUE
+WHERE LOWER(email) LIKE '%@privaterelay.appleid.com';
@@ -0,0 +1 @@
+DROP TABLE IF EXISTS entry_views;
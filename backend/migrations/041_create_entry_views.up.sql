@@ -0,0 +1,10 @@
+-- When each user last opened each of their entries, for GET /entries/recent.
+-- One row per entry, so the table never outgrows entries.
+CREATE TABLE entry_views (
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    entry_id UUID NOT NULL REFERENCES entries(id) ON DELETE CASCADE,
+    viewed_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (user_id, entry_id)
+);
+
+CREATE INDEX idx_entry_views_user_viewed_at ON entry_views(user_id, viewed_at DESC);
@@ -0,0 +1,3 @@
+ALTER TABLE users
+    DROP COLUMN IF EXISTS ai_enrichment_consent_at,
+    DROP COLUMN IF EXISTS ai_query_consent_at;
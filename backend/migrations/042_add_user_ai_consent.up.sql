@@ -0,0 +1,6 @@
+-- When the user allowed AI search queries to be sent to the model provider,
+-- and AI enrichment of their entries. NULL until given; AI search refuses
+-- to run without the query consent.
+ALTER TABLE users
+    ADD COLUMN ai_query_consent_at TIMESTAMPTZ,
+    ADD COLUMN ai_enrichment_consent_at TIMESTAMPTZ;
@@ -0,0 +1,9 @@
+-- users.email stays TEXT: encrypted values don't fit the old VARCHAR(255),
+-- and without the keys they can't be decrypted, so roll back only while
+-- encryption has never been enabled.
+DROP INDEX IF EXISTS idx_users_email_hash_deleted;
+DROP INDEX IF EXISTS idx_users_email_hash;
+
+ALTER TABLE users DROP COLUMN IF EXISTS email_hash;
+
+DROP TABLE IF EXISTS encryption_keys;
@@ -0,0 +1,37 @@
+-- Keys for application-level encryption of sensitive columns, see
+-- encryption in config.example.yaml. Each key is wrapped (AES-GCM) with the
+-- configured key-encryption key identified by kek_fingerprint. There is one
+-- index key, for blind indexes, and one active data key; retired data keys
+-- stay to decrypt values written before a rotation.
+CREATE TABLE encryption_keys (
+    id UUID PRIMARY KEY,
+    purpose VARCHAR(16) NOT NULL CHECK (purpose IN ('data', 'index')),
+    wrapped_key BYTEA NOT NULL,
+    kek_fingerprint VARCHAR(64) NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    retired_at TIMESTAMPTZ
+);
+
+CREATE UNIQUE INDEX idx_encryption_keys_index
+    ON encryption_keys(purpose)
+    WHERE purpose = 'index';
+
+CREATE UNIQUE INDEX idx_encryption_keys_active_data
+    ON encryption_keys(purpose)
+    WHERE purpose = 'data' AND retired_at IS NULL;
+
+-- Encrypted emails are longer than the plaintext and can't be compared, so
+-- lookups go through email_hash, an HMAC of the normalized address. Rows
+-- written before encryption was enabled keep a NULL hash until livlogctl
+-- rotate-encryption-key encrypts them.
+ALTER TABLE users
+    ALTER COLUMN email TYPE TEXT,
+    ADD COLUMN email_hash VARCHAR(64);
+
+CREATE UNIQUE INDEX idx_users_email_hash
+    ON users(email_hash)
+    WHERE email_hash IS NOT NULL AND deleted_at IS NULL AND duplicate_of IS NULL;
+
+CREATE INDEX idx_users_email_hash_deleted
+    ON users(email_hash)
+    WHERE email_hash IS NOT NULL AND deleted_at IS NOT NULL;
@@ -0,0 +1,48 @@
+-- Restore write_activity from 024
+CREATE OR REPLACE FUNCTION write_activity() RETURNS trigger AS $$
+DECLARE
+    old_row JSONB := '{}'::jsonb;
+    new_row JSONB := '{}'::jsonb;
+    owner_id UUID;
+    row_id UUID;
+    diff JSONB;
+BEGIN
+    IF TG_OP <> 'INSERT' THEN
+        old_row := to_jsonb(OLD) - 'id' - 'user_id' - 'change_xid' - 'created_at' - 'updated_at';
+        owner_id := OLD.user_id;
+        row_id := OLD.id;
+    END IF;
+    IF TG_OP <> 'DELETE' THEN
+        new_row := to_jsonb(NEW) - 'id' - 'user_id' - 'change_xid' - 'created_at' - 'updated_at';
+        owner_id := NEW.user_id;
+        row_id := NEW.id;
+    END IF;
+
+    SELECT COALESCE(jsonb_object_agg(k, jsonb_build_object('old', old_row -> k, 'new', new_row -> k)), '{}'::jsonb)
+    INTO diff
+    FROM jsonb_object_keys(old_row || new_row) AS k
+    WHERE COALESCE(old_row -> k, 'null'::jsonb) IS DISTINCT FROM COALESCE(new_row -> k, 'null'::jsonb);
+
+    -- Touches that change nothing visible (e.g. only updated_at) are not activity
+    IF TG_OP = 'UPDATE' AND diff = '{}'::jsonb THEN
+        RETURN NULL;
+    END IF;
+
+    INSERT INTO activity_log (user_id, actor_id, entity, entity_id, op, changes)
+    VALUES (
+        owner_id,
+        COALESCE(NULLIF(current_setting('livlog.actor_id', true), '')::uuid, owner_id),
+        TG_ARGV[0],
+        row_id,
+        lower(TG_OP),
+        diff
+    );
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+DROP INDEX IF EXISTS idx_entries_user_title_sort;
+
+ALTER TABLE entries DROP COLUMN IF EXISTS title_sort;
+
+DROP FUNCTION IF EXISTS title_sort_key(TEXT);
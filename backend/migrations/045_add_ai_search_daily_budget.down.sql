@@ -0,0 +1,4 @@
+ALTER TABLE ai_search_daily
+    DROP COLUMN IF EXISTS budget_alerted_at,
+    DROP COLUMN IF EXISTS provider_cost,
+    DROP COLUMN IF EXISTS provider_requests;
@@ -0,0 +1,7 @@
+-- Requests AI search sent to OpenRouter and what they cost, per UTC day, for
+-- the global budget (openrouter.budget). budget_alerted_at is set when admins
+-- were told the day's budget ran out, so they are told once.
+ALTER TABLE ai_search_daily
+    ADD COLUMN provider_requests BIGINT NOT NULL DEFAULT 0,
+    ADD COLUMN provider_cost NUMERIC(14, 6) NOT NULL DEFAULT 0,
+    ADD COLUMN budget_alerted_at TIMESTAMP WITH TIME ZONE;
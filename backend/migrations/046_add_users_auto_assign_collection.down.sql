@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS idx_entries_user_type_created;
+ALTER TABLE users DROP COLUMN IF EXISTS auto_assign_collection;
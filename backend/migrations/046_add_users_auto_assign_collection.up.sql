@@ -0,0 +1,6 @@
+-- Whether entries the user creates without a collection are filed in the
+-- suggested one instead of only getting the suggestion back.
+ALTER TABLE users ADD COLUMN auto_assign_collection BOOLEAN NOT NULL DEFAULT FALSE;
+
+-- The user's latest entries of a type, for the suggestion
+CREATE INDEX idx_entries_user_type_created ON entries(user_id, type_id, created_at DESC);
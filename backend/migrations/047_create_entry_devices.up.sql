@@ -0,0 +1,11 @@
+-- The app install that last created or edited an entry (the X-Device-ID
+-- header and User-Agent), for concurrent update hints. Kept out of entries:
+-- row snapshots, like the activity log's changes, would copy it along with
+-- the entry, and with field encryption on, re-encrypting it on key rotation
+-- would rewrite every entry and send it to clients and webhooks again. No
+-- row when the device is unknown.
+CREATE TABLE entry_devices (
+    entry_id UUID PRIMARY KEY REFERENCES entries(id) ON DELETE CASCADE,
+    device_id TEXT NOT NULL,
+    device_name TEXT NOT NULL DEFAULT ''
+);
@@ -0,0 +1,16 @@
+-- Transitions of users' emails and their verification: a provider reporting
+-- a new email (source 'apple'), and the user verifying theirs with a code
+-- (source 'verification'). Addresses aren't kept. While a user's latest
+-- event left the email unverified, security-sensitive actions such as
+-- deleting the account need it verified again.
+CREATE TABLE user_email_events (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    source VARCHAR(20) NOT NULL CHECK (source IN ('apple', 'verification')),
+    email_changed BOOLEAN NOT NULL,
+    was_verified BOOLEAN NOT NULL,
+    verified BOOLEAN NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX idx_user_email_events_user ON user_email_events(user_id, created_at DESC);
@@ -0,0 +1,5 @@
+DROP INDEX IF EXISTS idx_user_tokens_last_used;
+
+ALTER TABLE user_tokens
+    DROP COLUMN IF EXISTS last_used_at,
+    DROP COLUMN IF EXISTS ip_address;
@@ -0,0 +1,17 @@
+-- Where a refresh token was issued to and when it was last presented, for
+-- session listings and stale-session cleanup. ip_address is encrypted like
+-- device_info with field encryption on.
+ALTER TABLE user_tokens
+    ADD COLUMN ip_address TEXT,
+    ADD COLUMN last_used_at TIMESTAMP WITH TIME ZONE;
+
+UPDATE user_tokens SET last_used_at = COALESCE(revoked_at, created_at);
+
+ALTER TABLE user_tokens
+    ALTER COLUMN last_used_at SET DEFAULT NOW(),
+    ALTER COLUMN last_used_at SET NOT NULL;
+
+-- Find sessions that haven't been used for a while
+CREATE INDEX idx_user_tokens_last_used
+    ON user_tokens(last_used_at)
+    WHERE revoked_at IS NULL;
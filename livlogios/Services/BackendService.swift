@@ -124,7 +124,7 @@ actor BackendService {
 
         if httpResponse.statusCode >= 400 {
             if let errorResponse = try? decoder.decode(ErrorResponse.self, from: data) {
-                throw AuthError.serverError(errorResponse.message)
+                throw AuthError.serverError(errorResponse.error.message)
             }
             throw AuthError.serverError("Server error: \(httpResponse.statusCode)")
         }
@@ -280,7 +280,11 @@ private struct LogoutRequest: Codable {
 }
 
 private struct ErrorResponse: Codable {
-    let error: String
+    let error: ErrorBody
+}
+
+private struct ErrorBody: Codable {
+    let code: String
     let message: String
 }
 